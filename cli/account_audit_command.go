@@ -0,0 +1,353 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	auditNoteUnknown = "limit not visible to this user"
+
+	auditStatusOK   = "OK"
+	auditStatusWarn = "WARN"
+	auditStatusFail = "FAIL"
+)
+
+// accountAuditMetric is a single usage measurement compared to its limit, a
+// Limit of -1 means the metric is unlimited or the limit is not visible
+type accountAuditMetric struct {
+	Name     string  `json:"name"`
+	Used     int64   `json:"used"`
+	Limit    int64   `json:"limit"`
+	Percent  float64 `json:"percent"`
+	Status   string  `json:"status"`
+	Headroom string  `json:"headroom,omitempty"`
+	Bytes    bool    `json:"bytes,omitempty"`
+	Note     string  `json:"note,omitempty"`
+}
+
+type accountAuditReport struct {
+	Account   string                `json:"account,omitempty"`
+	Threshold float64               `json:"threshold"`
+	FailAbove float64               `json:"fail_above,omitempty"`
+	Metrics   []*accountAuditMetric `json:"metrics"`
+	Warnings  int                   `json:"warnings"`
+	Failures  int                   `json:"failures"`
+}
+
+func (c *actCmd) auditAction(_ *fisk.ParseContext) error {
+	if c.auditThreshold <= 0 {
+		return fmt.Errorf("threshold must be greater than 0")
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	report := &accountAuditReport{
+		Threshold: c.auditThreshold,
+		FailAbove: c.auditFailAbove,
+	}
+
+	ui := c.userInfo(nc)
+	if ui != nil {
+		report.Account = ui.Account
+	}
+
+	info, err := mgr.JetStreamAccountInfo()
+	if err != nil {
+		logWarnf("Could not retrieve JetStream account information: %v", err)
+	} else {
+		report.Metrics = append(report.Metrics, c.auditJetStream(info)...)
+	}
+
+	claimLimits, limitNote := c.auditAccountClaimLimits(nc, report.Account)
+	report.Metrics = append(report.Metrics, c.auditConnections(nc, claimLimits, limitNote)...)
+
+	for _, m := range report.Metrics {
+		c.auditEvaluate(m)
+
+		switch m.Status {
+		case auditStatusWarn:
+			report.Warnings++
+		case auditStatusFail:
+			report.Failures++
+		}
+	}
+
+	if c.json {
+		err = iu.PrintJSON(report)
+		if err != nil {
+			return err
+		}
+	} else {
+		c.renderAudit(report)
+	}
+
+	if report.Failures > 0 {
+		return fmt.Errorf("%d metrics exceed %.0f%% of their limit", report.Failures, c.auditFailAbove)
+	}
+
+	return nil
+}
+
+// auditJetStream produces metrics for the account or each of its tiers when tiered limits are in use
+func (c *actCmd) auditJetStream(info *api.JetStreamAccountStats) []*accountAuditMetric {
+	if len(info.Tiers) == 0 {
+		return c.auditJetStreamTier("", info.JetStreamTier)
+	}
+
+	var names []string
+	for name := range info.Tiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var metrics []*accountAuditMetric
+	for _, name := range names {
+		metrics = append(metrics, c.auditJetStreamTier(fmt.Sprintf("Tier %s ", name), info.Tiers[name])...)
+	}
+
+	return metrics
+}
+
+func (c *actCmd) auditJetStreamTier(prefix string, tier api.JetStreamTier) []*accountAuditMetric {
+	memory := &accountAuditMetric{Name: prefix + "JetStream Memory", Used: int64(tier.Memory), Limit: tier.Limits.MaxMemory, Bytes: true}
+	store := &accountAuditMetric{Name: prefix + "JetStream Storage", Used: int64(tier.Store), Limit: tier.Limits.MaxStore, Bytes: true}
+	streams := &accountAuditMetric{Name: prefix + "Streams", Used: int64(tier.Streams), Limit: int64(tier.Limits.MaxStreams)}
+	consumers := &accountAuditMetric{Name: prefix + "Consumers", Used: int64(tier.Consumers), Limit: int64(tier.Limits.MaxConsumers)}
+
+	streams.Headroom = auditStreamHeadroom(tier)
+
+	return []*accountAuditMetric{memory, store, streams, consumers}
+}
+
+// auditStreamHeadroom estimates how many more streams of the current average size fit within the stream count and storage limits
+func auditStreamHeadroom(tier api.JetStreamTier) string {
+	remaining := int64(-1)
+	if tier.Limits.MaxStreams >= 0 {
+		remaining = max(int64(tier.Limits.MaxStreams-tier.Streams), 0)
+	}
+
+	if tier.Streams > 0 {
+		avg := int64(tier.Memory+tier.Store) / int64(tier.Streams)
+		if avg > 0 {
+			for _, free := range []int64{tier.Limits.MaxStore - int64(tier.Store), tier.Limits.MaxMemory - int64(tier.Memory)} {
+				if free < 0 {
+					continue
+				}
+				if fit := free / avg; remaining == -1 || fit < remaining {
+					remaining = fit
+				}
+			}
+		}
+
+		switch {
+		case remaining == -1:
+			return "unlimited"
+		case avg > 0:
+			return fmt.Sprintf("~%s more streams of the current average size %s", f(remaining), fiBytes(uint64(avg)))
+		}
+	}
+
+	if remaining == -1 {
+		return "unlimited"
+	}
+
+	return fmt.Sprintf("%s more streams", f(remaining))
+}
+
+// auditAccountClaimLimits retrieves the account JWT limits, only available to system account users
+func (c *actCmd) auditAccountClaimLimits(nc *nats.Conn, account string) (*server.AccountInfo, string) {
+	if account == "" {
+		return nil, auditNoteUnknown
+	}
+
+	subj := fmt.Sprintf("$SYS.REQ.ACCOUNT.%s.INFO", account)
+	if opts().Trace {
+		log.Printf(">>> %s: {}\n", subj)
+	}
+
+	resp, err := nc.Request(subj, nil, opts().Timeout)
+	if err != nil {
+		return nil, auditNoteUnknown
+	}
+	if opts().Trace {
+		log.Printf("<<< %s", string(resp.Data))
+	}
+
+	var res = struct {
+		Data  *server.AccountInfo `json:"data"`
+		Error *server.ApiError    `json:"error"`
+	}{}
+
+	err = json.Unmarshal(resp.Data, &res)
+	if err != nil || res.Error != nil || res.Data == nil {
+		return nil, auditNoteUnknown
+	}
+
+	if res.Data.Claim == nil {
+		return nil, "account has no JWT limits"
+	}
+
+	return res.Data, ""
+}
+
+// auditConnections produces metrics for connections, subscriptions and payload sizes
+func (c *actCmd) auditConnections(nc *nats.Conn, acct *server.AccountInfo, limitNote string) []*accountAuditMetric {
+	conns := &accountAuditMetric{Name: "Connections", Limit: -1, Note: limitNote}
+	leafs := &accountAuditMetric{Name: "Leafnode Connections", Limit: -1, Note: limitNote}
+	subs := &accountAuditMetric{Name: "Subscriptions", Limit: -1, Note: limitNote}
+	payload := &accountAuditMetric{Name: "Maximum Payload", Used: nc.MaxPayload(), Limit: nc.MaxPayload(), Bytes: true, Note: "server maximum"}
+
+	if acct != nil {
+		limits := acct.Claim.Limits
+		conns.Limit = limits.Conn
+		leafs.Limit = limits.LeafNodeConn
+		subs.Limit = limits.Subs
+		conns.Note, leafs.Note, subs.Note = "", "", ""
+
+		if limits.Payload >= 0 && limits.Payload < payload.Limit {
+			payload.Used = limits.Payload
+			payload.Limit = limits.Payload
+			payload.Note = "account maximum"
+		}
+	}
+
+	res, err := doReq(nil, "$SYS.REQ.ACCOUNT.PING.STATZ", 0, nc)
+	if err != nil || len(res) == 0 {
+		logWarnf("Could not retrieve account statistics from any servers: %v", err)
+		return []*accountAuditMetric{payload}
+	}
+
+	for _, r := range res {
+		sz, err := c.parseAccountStatResp(r)
+		if err != nil {
+			logWarnf("Invalid account statistics received: %v", err)
+			continue
+		}
+
+		for _, stats := range sz.Stats.Accounts {
+			conns.Used += int64(stats.Conns)
+			leafs.Used += int64(stats.LeafNodes)
+			subs.Used += int64(stats.NumSubs)
+		}
+	}
+
+	return []*accountAuditMetric{conns, leafs, subs, payload}
+}
+
+// auditEvaluate calculates usage percentage, status and headroom for a metric
+func (c *actCmd) auditEvaluate(m *accountAuditMetric) {
+	m.Status = auditStatusOK
+
+	// the payload limit is informational, it is not consumed by usage
+	if m.Name == "Maximum Payload" || m.Limit < 0 {
+		switch {
+		case m.Headroom != "" || m.Limit >= 0:
+		case m.Note == auditNoteUnknown:
+			m.Headroom = "unknown"
+		default:
+			m.Headroom = "unlimited"
+		}
+		return
+	}
+
+	switch {
+	case m.Limit > 0:
+		m.Percent = float64(m.Used) * 100 / float64(m.Limit)
+	case m.Used > 0:
+		m.Percent = 100
+	}
+
+	if m.Headroom == "" {
+		free := max(m.Limit-m.Used, 0)
+		if m.Bytes {
+			m.Headroom = fmt.Sprintf("%s free", fiBytes(uint64(free)))
+		} else {
+			m.Headroom = fmt.Sprintf("%s more", f(free))
+		}
+	}
+
+	switch {
+	case c.auditFailAbove > 0 && m.Percent > c.auditFailAbove:
+		m.Status = auditStatusFail
+	case m.Percent >= c.auditThreshold:
+		m.Status = auditStatusWarn
+	}
+}
+
+func (c *actCmd) renderAudit(report *accountAuditReport) {
+	title := "Account Limits Audit"
+	if report.Account != "" {
+		title = fmt.Sprintf("Account Limits Audit for %s", report.Account)
+	}
+
+	table := newTableWriter(title)
+	table.AddHeaders("Metric", "Used", "Limit", "Used %", "Status", "Headroom")
+
+	for _, m := range report.Metrics {
+		used := f(m.Used)
+		limit := f(m.Limit)
+		if m.Bytes {
+			used = fiBytes(uint64(m.Used))
+			limit = fiBytes(uint64(m.Limit))
+		}
+
+		pct := fmt.Sprintf("%.1f%%", m.Percent)
+
+		switch {
+		case m.Name == "Maximum Payload":
+			used = ""
+			pct = ""
+		case m.Limit < 0 && m.Note == auditNoteUnknown:
+			limit = "Unknown"
+			pct = ""
+		case m.Limit < 0:
+			limit = "Unlimited"
+			pct = ""
+		}
+
+		headroom := m.Headroom
+		switch {
+		case m.Note != "" && headroom != "" && m.Note != auditNoteUnknown:
+			headroom = fmt.Sprintf("%s (%s)", headroom, m.Note)
+		case m.Note != "":
+			headroom = m.Note
+		}
+
+		table.AddRow(m.Name, used, limit, pct, m.Status, headroom)
+	}
+
+	fmt.Println(table.Render())
+
+	switch {
+	case report.Failures > 0:
+		fmt.Printf("%d metrics exceed %.0f%% and %d metrics are above %.0f%% of their limit\n", report.Failures, report.FailAbove, report.Warnings, report.Threshold)
+	case report.Warnings > 0:
+		fmt.Printf("%d metrics are above %.0f%% of their limit\n", report.Warnings, report.Threshold)
+	default:
+		fmt.Printf("All metrics are below %.0f%% of their limit\n", report.Threshold)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestAccountAuditEvaluate(t *testing.T) {
+	c := &actCmd{auditThreshold: 80, auditFailAbove: 95}
+
+	cases := []struct {
+		metric   accountAuditMetric
+		status   string
+		percent  float64
+		headroom string
+	}{
+		{accountAuditMetric{Name: "Consumers", Used: 10, Limit: 100}, auditStatusOK, 10, "90 more"},
+		{accountAuditMetric{Name: "Consumers", Used: 85, Limit: 100}, auditStatusWarn, 85, "15 more"},
+		{accountAuditMetric{Name: "Consumers", Used: 100, Limit: 100}, auditStatusFail, 100, "0 more"},
+		{accountAuditMetric{Name: "JetStream Storage", Used: 512, Limit: 1024, Bytes: true}, auditStatusOK, 50, "512 B free"},
+		{accountAuditMetric{Name: "Connections", Used: 1000, Limit: -1}, auditStatusOK, 0, "unlimited"},
+		{accountAuditMetric{Name: "Connections", Used: 1000, Limit: -1, Note: auditNoteUnknown}, auditStatusOK, 0, "unknown"},
+		{accountAuditMetric{Name: "Maximum Payload", Used: 1024, Limit: 1024}, auditStatusOK, 0, ""},
+	}
+
+	for _, tc := range cases {
+		m := tc.metric
+		c.auditEvaluate(&m)
+
+		if m.Status != tc.status || m.Percent != tc.percent || m.Headroom != tc.headroom {
+			t.Fatalf("unexpected evaluation of %+v: %+v", tc.metric, m)
+		}
+	}
+}
+
+func TestAuditStreamHeadroom(t *testing.T) {
+	tier := api.JetStreamTier{
+		Store:   3000,
+		Streams: 3,
+		Limits:  api.JetStreamAccountLimits{MaxStreams: -1, MaxConsumers: -1, MaxMemory: -1, MaxStore: 15000},
+	}
+
+	res := auditStreamHeadroom(tier)
+	if res != "~12 more streams of the current average size 1000 B" {
+		t.Fatalf("unexpected headroom: %s", res)
+	}
+
+	tier.Limits.MaxStreams = 5
+	res = auditStreamHeadroom(tier)
+	if res != "~2 more streams of the current average size 1000 B" {
+		t.Fatalf("unexpected headroom: %s", res)
+	}
+
+	tier = api.JetStreamTier{Limits: api.JetStreamAccountLimits{MaxStreams: -1, MaxMemory: -1, MaxStore: -1}}
+	res = auditStreamHeadroom(tier)
+	if res != "unlimited" {
+		t.Fatalf("unexpected headroom: %s", res)
+	}
+
+	tier.Limits.MaxStreams = 10
+	res = auditStreamHeadroom(tier)
+	if res != "10 more streams" {
+		t.Fatalf("unexpected headroom: %s", res)
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+const (
+	accountBackupManifestFile  = "manifest.json"
+	accountBackupConsumersFile = "consumers.json"
+)
+
+// accountBackupManifest describes the streams in an account backup
+type accountBackupManifest struct {
+	Time    time.Time             `json:"time"`
+	Streams []*accountBackupEntry `json:"streams"`
+}
+
+// accountBackupEntry is a single stream in an account backup along with checksums of all its files
+type accountBackupEntry struct {
+	Name      string            `json:"name"`
+	Directory string            `json:"directory"`
+	Consumers int               `json:"consumers"`
+	Files     map[string]string `json:"files,omitempty"`
+
+	cfg *api.StreamConfig
+}
+
+// backupConsumerConfigs saves the configuration of all durable consumers on a stream
+func backupConsumerConfigs(stream *jsm.Stream, dir string) (int, error) {
+	var configs []api.ConsumerConfig
+
+	missing, err := stream.EachConsumer(func(consumer *jsm.Consumer) {
+		if consumer.IsDurable() {
+			configs = append(configs, consumer.Configuration())
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("could not obtain information for %d consumers", len(missing))
+	}
+
+	sort.Slice(configs, func(i, j int) bool {
+		return configs[i].Durable < configs[j].Durable
+	})
+
+	j, err := json.MarshalIndent(configs, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	return len(configs), os.WriteFile(filepath.Join(dir, accountBackupConsumersFile), j, 0600)
+}
+
+func checksumFile(path string) (string, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	h := sha256.New()
+	_, err = io.Copy(h, fh)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksumBackupFiles calculates the checksums of all the files in a stream backup directory
+func checksumBackupFiles(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		sums[entry.Name()], err = checksumFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sums, nil
+}
+
+func writeAccountBackupManifest(dir string, manifest *accountBackupManifest) error {
+	j, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, accountBackupManifestFile), j, 0600)
+}
+
+// readAccountBackupManifest loads the manifest of an account backup, backups made before manifests were written are
+// discovered from their stream directories and can not be verified
+func readAccountBackupManifest(dir string) (*accountBackupManifest, error) {
+	manifest := &accountBackupManifest{}
+
+	j, err := os.ReadFile(filepath.Join(dir, accountBackupManifestFile))
+	switch {
+	case err == nil:
+		err = json.Unmarshal(j, manifest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid manifest: %w", err)
+		}
+
+	case errors.Is(err, os.ErrNotExist):
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+
+			_, err = os.Stat(filepath.Join(dir, entry.Name(), "backup.json"))
+			if err != nil {
+				continue
+			}
+
+			manifest.Streams = append(manifest.Streams, &accountBackupEntry{Name: entry.Name(), Directory: entry.Name()})
+		}
+
+	default:
+		return nil, err
+	}
+
+	for _, entry := range manifest.Streams {
+		var bm api.JSApiStreamRestoreRequest
+		bmj, err := os.ReadFile(filepath.Join(dir, entry.Directory, "backup.json"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name, err)
+		}
+
+		err = json.Unmarshal(bmj, &bm)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid backup.json: %w", entry.Name, err)
+		}
+
+		entry.cfg = &bm.Config
+	}
+
+	return manifest, nil
+}
+
+// verify checks the files of the stream backup against the checksums recorded at backup time
+func (e *accountBackupEntry) verify(dir string) error {
+	for file, sum := range e.Files {
+		actual, err := checksumFile(filepath.Join(dir, e.Directory, file))
+		if err != nil {
+			return err
+		}
+
+		if actual != sum {
+			return fmt.Errorf("checksum mismatch for %s", file)
+		}
+	}
+
+	return nil
+}
+
+// dependencies are the streams in the same backup this stream mirrors or sources from
+func (e *accountBackupEntry) dependencies(known map[string]*accountBackupEntry) []string {
+	var deps []string
+
+	if e.cfg.Mirror != nil {
+		deps = append(deps, e.cfg.Mirror.Name)
+	}
+	for _, source := range e.cfg.Sources {
+		deps = append(deps, source.Name)
+	}
+
+	var res []string
+	for _, dep := range deps {
+		if _, ok := known[dep]; ok && dep != e.Name {
+			res = append(res, dep)
+		}
+	}
+
+	return res
+}
+
+func (e *accountBackupEntry) consumerConfigs(dir string) ([]api.ConsumerConfig, error) {
+	j, err := os.ReadFile(filepath.Join(dir, e.Directory, accountBackupConsumersFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []api.ConsumerConfig
+	err = json.Unmarshal(j, &configs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", accountBackupConsumersFile, err)
+	}
+
+	return configs, nil
+}
+
+// accountRestoreOrder sorts streams so that streams are restored before any mirrors or sources of them
+func accountRestoreOrder(entries []*accountBackupEntry) []*accountBackupEntry {
+	known := map[string]*accountBackupEntry{}
+	for _, entry := range entries {
+		known[entry.Name] = entry
+	}
+
+	sorted := make([]*accountBackupEntry, len(entries))
+	copy(sorted, entries)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var order []*accountBackupEntry
+	done := map[string]bool{}
+	visiting := map[string]bool{}
+
+	var visit func(entry *accountBackupEntry)
+	visit = func(entry *accountBackupEntry) {
+		// cycles are broken by restoring in name order
+		if done[entry.Name] || visiting[entry.Name] {
+			return
+		}
+
+		visiting[entry.Name] = true
+		for _, dep := range entry.dependencies(known) {
+			visit(known[dep])
+		}
+		visiting[entry.Name] = false
+
+		done[entry.Name] = true
+		order = append(order, entry)
+	}
+
+	// plain streams first so sources and mirrors are all restored at the end
+	for _, entry := range sorted {
+		if len(entry.dependencies(known)) == 0 {
+			visit(entry)
+		}
+	}
+	for _, entry := range sorted {
+		visit(entry)
+	}
+
+	return order
+}
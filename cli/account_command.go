@@ -21,11 +21,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats-server/v2/server"
@@ -43,10 +44,17 @@ type actCmd struct {
 	snapShotConsumers bool
 	force             bool
 	failOnWarn        bool
+	restoreInclude    string
+	restoreExclude    string
+	dryRun            bool
 
 	placementCluster string
 	placementTags    []string
 	reverse          bool
+
+	auditThreshold float64
+	auditFailAbove float64
+	json           bool
 }
 
 func configureActCommand(app commandHost) {
@@ -76,6 +84,15 @@ func configureActCommand(app commandHost) {
 	restore.Arg("directory", "The directory holding the account backup to restore").Required().ExistingDirVar(&c.backupDirectory)
 	restore.Flag("cluster", "Place the stream in a specific cluster").StringVar(&c.placementCluster)
 	restore.Flag("tag", "Place the stream on servers that has specific tags (pass multiple times)").StringsVar(&c.placementTags)
+	restore.Flag("include", "Only restore streams with names matching a regular expression").PlaceHolder("REGEX").StringVar(&c.restoreInclude)
+	restore.Flag("exclude", "Do not restore streams with names matching a regular expression").PlaceHolder("REGEX").StringVar(&c.restoreExclude)
+	restore.Flag("dry-run", "Show what would be restored without making any changes").UnNegatableBoolVar(&c.dryRun)
+	restore.Flag("force", "Replace streams that already exist").Short('f').UnNegatableBoolVar(&c.force)
+
+	audit := act.Command("audit", "Compares account usage against its limits").Action(c.auditAction)
+	audit.Flag("threshold", "Flag metrics using more than this percentage of their limit").Default("80").PlaceHolder("PERCENT").Float64Var(&c.auditThreshold)
+	audit.Flag("fail-above", "Exit with an error when any metric uses more than this percentage of its limit").PlaceHolder("PERCENT").Float64Var(&c.auditFailAbove)
+	audit.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 
 	configureAccountTLSCommand(act)
 }
@@ -114,7 +131,7 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 
 	cols := newColumns("Performing backup of all streams to %s", c.backupDirectory)
 	cols.AddRow("Streams", len(streams))
-	cols.AddRow("Size", humanize.IBytes(totalSize))
+	cols.AddRow("Size", fiBytes(totalSize))
 	cols.AddRow("Consumers:", totalConsumers)
 	cols.Println()
 	cols.Frender(os.Stdout)
@@ -138,18 +155,27 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 	var errs []error
 	var warns []error
 
+	manifest := &accountBackupManifest{Time: time.Now().UTC()}
+
 	for _, s := range streams {
-		err = backupStream(s, false, c.snapShotConsumers, c.healthCheck, filepath.Join(c.backupDirectory, s.Name()), 128*1024)
+		entry, err := c.backupAccountStream(s)
 		if errors.Is(err, jsm.ErrMemoryStreamNotSupported) {
 			fmt.Printf("Backup of %s failed: %v\n", s.Name(), err)
 			warns = append(warns, fmt.Errorf("%s: %w", s.Name(), err))
 		} else if err != nil {
 			fmt.Printf("Backup of %s failed: %s\n", s.Name(), err)
 			errs = append(errs, fmt.Errorf("%s: %s", s.Name(), err))
+		} else {
+			manifest.Streams = append(manifest.Streams, entry)
 		}
 		fmt.Println()
 	}
 
+	err = writeAccountBackupManifest(c.backupDirectory, manifest)
+	if err != nil {
+		return fmt.Errorf("could not write the backup manifest: %w", err)
+	}
+
 	if len(warns) > 0 {
 		fmt.Printf("Backup Warnings: \n")
 		for _, err := range warns {
@@ -173,9 +199,52 @@ func (c *actCmd) backupAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
-func (c *actCmd) restoreAction(kp *fisk.ParseContext) error {
+func (c *actCmd) backupAccountStream(stream *jsm.Stream) (*accountBackupEntry, error) {
+	dir := filepath.Join(c.backupDirectory, stream.Name())
+
+	err := backupStream(stream, false, c.snapShotConsumers, c.healthCheck, dir, 128*1024)
+	if err != nil {
+		return nil, err
+	}
+
+	consumers, err := backupConsumerConfigs(stream, dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not save consumer configurations: %w", err)
+	}
+
+	files, err := checksumBackupFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &accountBackupEntry{Name: stream.Name(), Directory: stream.Name(), Consumers: consumers, Files: files}, nil
+}
+
+func (c *actCmd) restoreAction(_ *fisk.ParseContext) error {
+	var include, exclude *regexp.Regexp
+	var err error
+
+	if c.restoreInclude != "" {
+		include, err = regexp.Compile(c.restoreInclude)
+		if err != nil {
+			return fmt.Errorf("invalid include expression: %w", err)
+		}
+	}
+	if c.restoreExclude != "" {
+		exclude, err = regexp.Compile(c.restoreExclude)
+		if err != nil {
+			return fmt.Errorf("invalid exclude expression: %w", err)
+		}
+	}
+
+	manifest, err := readAccountBackupManifest(c.backupDirectory)
+	if err != nil {
+		return err
+	}
+
 	_, mgr, err := prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "setup failed")
+
 	streams, err := mgr.StreamNames(nil)
 	if err != nil {
 		return err
@@ -184,25 +253,146 @@ func (c *actCmd) restoreAction(kp *fisk.ParseContext) error {
 	for _, n := range streams {
 		existingStreams[n] = struct{}{}
 	}
-	de, err := os.ReadDir(c.backupDirectory)
-	fisk.FatalIfError(err, "setup failed")
-	for _, d := range de {
-		if !d.IsDir() {
-			fisk.FatalIfError(err, "expected a directory")
+
+	var selected []*accountBackupEntry
+	for _, entry := range manifest.Streams {
+		if include != nil && !include.MatchString(entry.Name) {
+			continue
 		}
-		if _, ok := existingStreams[d.Name()]; ok {
-			fisk.Fatalf("stream %q exists already", d.Name())
+		if exclude != nil && exclude.MatchString(entry.Name) {
+			continue
 		}
-		_, err := os.Stat(filepath.Join(c.backupDirectory, d.Name(), "backup.json"))
-		fisk.FatalIfError(err, "expected backup.json")
+		selected = append(selected, entry)
 	}
-	fmt.Printf("Restoring backup of all %d streams in directory %q\n\n", len(de), c.backupDirectory)
-	s := &streamCmd{msgID: -1, showProgress: false, placementCluster: c.placementCluster, placementTags: c.placementTags}
-	for _, d := range de {
-		s.backupDirectory = filepath.Join(c.backupDirectory, d.Name())
-		err := s.restoreAction(kp)
-		fisk.FatalIfError(err, "restore for %s failed", d.Name())
+
+	if len(selected) == 0 {
+		return fmt.Errorf("no streams to restore found in %q", c.backupDirectory)
+	}
+
+	if c.dryRun {
+		fmt.Printf("Would restore %d of %d streams in directory %q:\n\n", len(selected), len(manifest.Streams), c.backupDirectory)
+	} else {
+		fmt.Printf("Restoring backup of %d of %d streams in directory %q\n\n", len(selected), len(manifest.Streams), c.backupDirectory)
 	}
+
+	var errs []error
+	var restored, skipped int
+
+	for _, entry := range accountRestoreOrder(selected) {
+		_, exists := existingStreams[entry.Name]
+
+		if c.dryRun {
+			switch {
+			case exists && !c.force:
+				fmt.Printf("  %s: skip, the stream already exists\n", entry.Name)
+			case exists:
+				fmt.Printf("  %s: replace the existing stream and restore %d consumers\n", entry.Name, entry.Consumers)
+			default:
+				fmt.Printf("  %s: restore with %d consumers\n", entry.Name, entry.Consumers)
+			}
+			continue
+		}
+
+		if exists && !c.force {
+			fmt.Printf("Skipping stream %q that already exists\n\n", entry.Name)
+			skipped++
+			continue
+		}
+
+		err = c.restoreAccountStream(mgr, entry, exists)
+		if err != nil {
+			fmt.Printf("Restore of %s failed: %s\n\n", entry.Name, err)
+			errs = append(errs, fmt.Errorf("%s: %s", entry.Name, err))
+			continue
+		}
+
+		restored++
+	}
+
+	if c.dryRun {
+		return nil
+	}
+
+	fmt.Printf("Restored %d streams, skipped %d existing streams\n", restored, skipped)
+
+	if len(errs) > 0 {
+		fmt.Println()
+		fmt.Printf("Restore failures: \n")
+		for _, err := range errs {
+			fmt.Printf("  %s\n", err)
+		}
+		fmt.Println()
+
+		return fmt.Errorf("restore failed")
+	}
+
+	return nil
+}
+
+func (c *actCmd) restoreAccountStream(mgr *jsm.Manager, entry *accountBackupEntry, exists bool) error {
+	err := entry.verify(c.backupDirectory)
+	if err != nil {
+		return err
+	}
+
+	consumers, err := entry.consumerConfigs(c.backupDirectory)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		stream, err := mgr.LoadStream(entry.Name)
+		if err != nil {
+			return err
+		}
+
+		err = stream.Delete()
+		if err != nil {
+			return fmt.Errorf("could not remove the existing stream: %w", err)
+		}
+	}
+
+	cfg := *entry.cfg
+	if c.placementCluster != "" || len(c.placementTags) > 0 {
+		cfg.Placement = &api.Placement{
+			Cluster: c.placementCluster,
+			Tags:    c.placementTags,
+		}
+	}
+
+	fmt.Printf("Starting restore of Stream %q\n", entry.Name)
+
+	fp, _, err := mgr.RestoreSnapshotFromDirectory(ctx, entry.Name, filepath.Join(c.backupDirectory, entry.Directory), jsm.RestoreConfiguration(cfg))
+	if err != nil {
+		return err
+	}
+
+	stream, err := mgr.LoadStream(entry.Name)
+	if err != nil {
+		return err
+	}
+
+	names, err := stream.ConsumerNames()
+	if err != nil {
+		return err
+	}
+
+	// consumers are part of the snapshot unless the backup excluded them
+	created := 0
+	for _, cfg := range consumers {
+		if slices.Contains(names, cfg.Durable) {
+			continue
+		}
+
+		_, err = mgr.NewConsumerFromDefault(entry.Name, cfg)
+		if err != nil {
+			return fmt.Errorf("could not create consumer %s: %w", cfg.Durable, err)
+		}
+		created++
+	}
+
+	fmt.Printf("Restored stream %q in %v, created %d consumers from their configuration\n\n", entry.Name, fp.EndTime().Sub(fp.StartTime()).Round(time.Millisecond), created)
+
 	return nil
 }
 
@@ -267,14 +457,14 @@ func (c *actCmd) reportServerStats(_ *fisk.ParseContext) error {
 			f(sz.ServerInfo.Tags),
 			f(stats.Conns),
 			f(stats.LeafNodes),
-			humanize.IBytes(uint64(stats.Sent.Bytes)),
+			fiBytes(uint64(stats.Sent.Bytes)),
 			f(stats.Sent.Msgs),
-			humanize.IBytes(uint64(stats.Received.Bytes)),
+			fiBytes(uint64(stats.Received.Bytes)),
 			f(stats.Received.Msgs),
 			f(stats.SlowConsumers),
 		)
 	}
-	table.AddFooter(len(res), "", "", "", f(conn), f(ln), humanize.IBytes(uint64(sb)), f(sm), humanize.IBytes(uint64(rb)), f(rm), f(sc))
+	table.AddFooter(len(res), "", "", "", f(conn), f(ln), fiBytes(uint64(sb)), f(sm), fiBytes(uint64(rb)), f(rm), f(sc))
 	fmt.Print(table.Render())
 	fmt.Println()
 
@@ -349,35 +539,35 @@ func (c *actCmd) renderTier(cols *columns.Writer, name string, tier api.JetStrea
 
 	reservedMem := ""
 	if tier.ReservedMemory > 0 {
-		reservedMem = fmt.Sprintf("(%s reserved)", humanize.IBytes(tier.ReservedMemory))
+		reservedMem = fmt.Sprintf("(%s reserved)", fiBytes(tier.ReservedMemory))
 	}
 	if tier.Limits.MaxMemory == -1 {
-		cols.AddRowf("Memory", "%s of Unlimited %s", humanize.IBytes(tier.Memory), reservedMem)
+		cols.AddRowf("Memory", "%s of Unlimited %s", fiBytes(tier.Memory), reservedMem)
 	} else {
-		cols.AddRowf("Memory", "%s of %s %s", humanize.IBytes(tier.Memory), humanize.IBytes(uint64(tier.Limits.MaxMemory)), reservedMem)
+		cols.AddRowf("Memory", "%s of %s %s", fiBytes(tier.Memory), fiBytes(uint64(tier.Limits.MaxMemory)), reservedMem)
 	}
 
 	if tier.Limits.MemoryMaxStreamBytes <= 0 {
 		cols.AddRow("Memory Per Stream", "Unlimited")
 	} else {
-		cols.AddRow("Memory Per Stream", humanize.IBytes(uint64(tier.Limits.MemoryMaxStreamBytes)))
+		cols.AddRow("Memory Per Stream", fiBytes(uint64(tier.Limits.MemoryMaxStreamBytes)))
 	}
 
 	reservedStore := ""
 	if tier.ReservedStore > 0 {
-		reservedStore = fmt.Sprintf("(%s reserved)", humanize.IBytes(tier.ReservedStore))
+		reservedStore = fmt.Sprintf("(%s reserved)", fiBytes(tier.ReservedStore))
 	}
 
 	if tier.Limits.MaxStore == -1 {
-		cols.AddRowf("Storage", "%s of Unlimited %s", humanize.IBytes(tier.Store), reservedStore)
+		cols.AddRowf("Storage", "%s of Unlimited %s", fiBytes(tier.Store), reservedStore)
 	} else {
-		cols.AddRowf("Storage", "%s of %s %s", humanize.IBytes(tier.Store), humanize.IBytes(uint64(tier.Limits.MaxStore)), reservedStore)
+		cols.AddRowf("Storage", "%s of %s %s", fiBytes(tier.Store), fiBytes(uint64(tier.Limits.MaxStore)), reservedStore)
 	}
 
 	if tier.Limits.StoreMaxStreamBytes <= 0 {
 		cols.AddRow("Storage Per Stream", "Unlimited")
 	} else {
-		cols.AddRow("Storage Per Stream", humanize.IBytes(uint64(tier.Limits.StoreMaxStreamBytes)))
+		cols.AddRow("Storage Per Stream", fiBytes(uint64(tier.Limits.StoreMaxStreamBytes)))
 	}
 
 	if tier.Limits.MaxStreams == -1 {
@@ -393,6 +583,38 @@ func (c *actCmd) renderTier(cols *columns.Writer, name string, tier api.JetStrea
 	}
 }
 
+// userInfo retrieves information about the connected user, nil when not supported by the server
+func (c *actCmd) userInfo(nc *nats.Conn) *server.UserInfo {
+	if !serverMinVersion(nc.ConnectedServerVersion(), 2, 10, 0) {
+		return nil
+	}
+
+	subj := "$SYS.REQ.USER.INFO"
+	if opts().Trace {
+		log.Printf(">>> %s: {}\n", subj)
+	}
+	resp, err := nc.Request(subj, nil, time.Second)
+	if err != nil {
+		return nil
+	}
+	if opts().Trace {
+		log.Printf("<<< %s", string(resp.Data))
+	}
+
+	var res = struct {
+		Data   *server.UserInfo  `json:"data"`
+		Server server.ServerInfo `json:"server"`
+		Error  *server.ApiError  `json:"error"`
+	}{}
+
+	err = json.Unmarshal(resp.Data, &res)
+	if err != nil || res.Error != nil {
+		return nil
+	}
+
+	return res.Data
+}
+
 func (c *actCmd) infoAction(_ *fisk.ParseContext) error {
 	nc, mgr, err := prepareHelper("", natsOpts()...)
 	fisk.FatalIfError(err, "setup failed")
@@ -402,29 +624,7 @@ func (c *actCmd) infoAction(_ *fisk.ParseContext) error {
 	rtt, _ := nc.RTT()
 	tlsc, _ := nc.TLSConnectionState()
 
-	var ui *server.UserInfo
-	if serverMinVersion(nc.ConnectedServerVersion(), 2, 10, 0) {
-		subj := "$SYS.REQ.USER.INFO"
-		if opts().Trace {
-			log.Printf(">>> %s: {}\n", subj)
-		}
-		resp, err := nc.Request("$SYS.REQ.USER.INFO", nil, time.Second)
-		if err == nil {
-			if opts().Trace {
-				log.Printf("<<< %s", string(resp.Data))
-			}
-			var res = struct {
-				Data   *server.UserInfo  `json:"data"`
-				Server server.ServerInfo `json:"server"`
-				Error  *server.ApiError  `json:"error"`
-			}{}
-
-			err = json.Unmarshal(resp.Data, &res)
-			if err == nil && res.Error == nil {
-				ui = res.Data
-			}
-		}
-	}
+	ui := c.userInfo(nc)
 
 	cols := newColumns("Account Information")
 	defer cols.Frender(os.Stdout)
@@ -442,7 +642,7 @@ func (c *actCmd) infoAction(_ *fisk.ParseContext) error {
 	cols.AddRow("Client IP", ip)
 	cols.AddRow("RTT", rtt)
 	cols.AddRow("Headers Supported", nc.HeadersSupported())
-	cols.AddRow("Maximum Payload", humanize.IBytes(uint64(nc.MaxPayload())))
+	cols.AddRow("Maximum Payload", fiBytes(uint64(nc.MaxPayload())))
 	cols.AddRowIfNotEmpty("Connected Cluster", nc.ConnectedClusterName())
 	cols.AddRow("Connected URL", nc.ConnectedUrl())
 	cols.AddRow("Connected Address", nc.ConnectedAddr())
@@ -509,26 +709,33 @@ func (c *actCmd) infoAction(_ *fisk.ParseContext) error {
 		cols.Println()
 	}
 
+	domain, prefix := jsTarget()
+
 	info, err := mgr.JetStreamAccountInfo()
 	if info != nil {
-		if info.Domain == "" {
+		if info.Domain != "" {
+			domain = info.Domain
+		}
+
+		if domain == "" {
 			cols.AddSectionTitle("JetStream Account Information")
 		} else {
-			cols.AddSectionTitle("JetStream Account Information for domain %s", info.Domain)
+			cols.AddSectionTitle("JetStream Account Information for domain %s", domain)
 		}
 	}
 
 	switch err {
 	case nil:
 		cols.AddSectionTitle("Account Usage")
-		cols.AddRowIfNotEmpty("Domain", info.Domain)
-		cols.AddRow("Storage", humanize.IBytes(info.Store))
-		cols.AddRow("Memory", humanize.IBytes(info.Memory))
+		cols.AddRowIfNotEmpty("Domain", domain)
+		cols.AddRowIfNotEmpty("API Prefix", prefix)
+		cols.AddRow("Storage", fiBytes(info.Store))
+		cols.AddRow("Memory", fiBytes(info.Memory))
 		cols.AddRow("Streams", info.Streams)
 		cols.AddRow("Consumers", info.Consumers)
 
 		cols.AddSectionTitle("Account Limits")
-		cols.AddRow("Max Message Payload", humanize.IBytes(uint64(nc.MaxPayload())))
+		cols.AddRow("Max Message Payload", fiBytes(uint64(nc.MaxPayload())))
 
 		if len(info.Tiers) > 0 {
 			var tiers []string
@@ -0,0 +1,199 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/jsm.go/schemas"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// Exit statuses used when a command fails, allowing scripts to act on the class of failure
+const (
+	exitStatusError      = 1
+	exitStatusNotFound   = 2
+	exitStatusPermission = 3
+	exitStatusTimeout    = 4
+)
+
+// apiErrorHints are one line suggestions shown along with common JetStream API errors
+var apiErrorHints = map[uint16]string{
+	10002: "the account has reached one of its resource limits, see 'nats account info'",
+	10008: "the JetStream cluster has no leader, see 'nats server report jetstream'",
+	10014: "see 'nats consumer ls' for a list of known consumers",
+	10023: "the server does not have enough storage or memory for this request, see 'nats server report jetstream'",
+	10026: "the stream or account has reached its consumer limit, see 'nats account info'",
+	10027: "the account has reached its stream limit, see 'nats account info'",
+	10037: "the message was deleted, purged or never existed, see 'nats stream info' for the sequence range",
+	10039: "is JetStream enabled for this account?",
+	10047: "the server does not have enough storage resources, see 'nats server report jetstream'",
+	10058: "a stream with this name but a different configuration exists, use 'nats stream edit' to change it",
+	10059: "see 'nats stream ls' for a list of known streams",
+	10065: "another stream already listens on these subjects, see 'nats stream find --subject'",
+	10071: "the subject was updated by another publisher, retrieve the latest and try again",
+	10076: "is JetStream enabled on this server?",
+}
+
+// natsGoAPIErrors are errors returned by nats.go without their NATS error code in the message
+var natsGoAPIErrors = []nats.JetStreamError{
+	nats.ErrJetStreamNotEnabled,
+	nats.ErrJetStreamNotEnabledForAccount,
+	nats.ErrStreamNotFound,
+	nats.ErrStreamNameAlreadyInUse,
+	nats.ErrConsumerNotFound,
+	nats.ErrMsgNotFound,
+}
+
+var (
+	apiErrorCodeRe = regexp.MustCompile(`\((1\d{4})\)`)
+
+	httpCodesMu sync.Mutex
+	httpCodes   map[uint16]int
+)
+
+// apiErrorFromResponse parses a JetStream API response and returns the error it holds, nil when it's not an error response
+func apiErrorFromResponse(data []byte) error {
+	var resp api.JSApiResponse
+	err := json.Unmarshal(data, &resp)
+	if err != nil || !resp.IsError() {
+		return nil
+	}
+
+	return resp.ToError()
+}
+
+// apiErrorHTTPCode looks up the HTTP like status code the server uses for a NATS error code
+func apiErrorHTTPCode(code uint16) int {
+	httpCodesMu.Lock()
+	defer httpCodesMu.Unlock()
+
+	if httpCodes == nil {
+		httpCodes = map[uint16]int{}
+
+		ej, err := schemas.Load("server/errors.json")
+		if err == nil {
+			var errs []*server.ErrorsData
+			if json.Unmarshal(ej, &errs) == nil {
+				for _, e := range errs {
+					httpCodes[e.ErrCode] = e.Code
+				}
+			}
+		}
+	}
+
+	return httpCodes[code]
+}
+
+// renderCLIError renders the failure message of a command including the NATS error code and a hint where known,
+// and determines the exit status matching the class of failure
+func renderCLIError(msg string) (string, int) {
+	var code uint16
+
+	for _, e := range natsGoAPIErrors {
+		if strings.Contains(msg, e.Error()) {
+			code = uint16(e.APIError().ErrorCode)
+			msg = strings.Replace(msg, e.Error(), fmt.Sprintf("%s (%d)", e.Error(), code), 1)
+			break
+		}
+	}
+
+	if code == 0 {
+		matches := apiErrorCodeRe.FindAllStringSubmatch(msg, -1)
+		if len(matches) > 0 {
+			c, _ := strconv.Atoi(matches[len(matches)-1][1])
+			code = uint16(c)
+		}
+	}
+
+	lmsg := strings.ToLower(msg)
+	status := exitStatusError
+	hint := apiErrorHints[code]
+
+	switch {
+	case code > 0 && apiErrorHTTPCode(code) == 404:
+		status = exitStatusNotFound
+	case code > 0 && (apiErrorHTTPCode(code) == 401 || apiErrorHTTPCode(code) == 403):
+		status = exitStatusPermission
+	case strings.Contains(lmsg, "permissions violation"), strings.Contains(lmsg, "authorization violation"), strings.Contains(lmsg, "authentication"):
+		status = exitStatusPermission
+	case strings.Contains(lmsg, nats.ErrNoResponders.Error()):
+		status = exitStatusTimeout
+		if hint == "" {
+			hint = "no responders available, is the service running or JetStream enabled on this server/account?"
+		}
+	case strings.Contains(lmsg, "timeout"), strings.Contains(lmsg, "deadline exceeded"):
+		status = exitStatusTimeout
+	case strings.Contains(lmsg, "not found"):
+		status = exitStatusNotFound
+	}
+
+	if hint != "" {
+		name, _, _ := strings.Cut(msg, ": error: ")
+		msg = fmt.Sprintf("%s\n%s: hint: %s", msg, name, hint)
+	}
+
+	return msg, status
+}
+
+// cliErrorWriter renders errors reported by fisk and records the exit status to use for them
+type cliErrorWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	status int
+}
+
+func (e *cliErrorWriter) Write(p []byte) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	msg := string(p)
+	if !strings.Contains(msg, ": error: ") {
+		return e.w.Write(p)
+	}
+
+	msg, e.status = renderCLIError(strings.TrimSuffix(msg, "\n"))
+	_, err := fmt.Fprintln(e.w, msg)
+
+	return len(p), err
+}
+
+func (e *cliErrorWriter) terminate(status int) {
+	e.mu.Lock()
+	if status == exitStatusError && e.status > 0 {
+		status = e.status
+	}
+	e.mu.Unlock()
+
+	os.Exit(status)
+}
+
+// ConfigureErrorRendering renders errors from app, and fisk.FatalIfError, with NATS error codes and hints and exits
+// with a status indicating the class of failure: 2 for not found, 3 for permission errors and 4 for timeouts
+func ConfigureErrorRendering(app *fisk.Application) {
+	w := &cliErrorWriter{w: os.Stderr}
+
+	app.ErrorWriter(w).Terminate(w.terminate)
+	fisk.CommandLine.ErrorWriter(w).Terminate(w.terminate)
+}
@@ -0,0 +1,63 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+func TestRenderCLIError(t *testing.T) {
+	cases := []struct {
+		msg    string
+		status int
+		code   string
+		hint   bool
+	}{
+		{msg: "nats: error: could not load stream: " + api.ApiError{Code: 404, ErrCode: 10059, Description: "stream not found"}.Error(), status: exitStatusNotFound, code: "(10059)", hint: true},
+		{msg: "nats: error: " + nats.ErrConsumerNotFound.Error(), status: exitStatusNotFound, code: "(10014)", hint: true},
+		{msg: "nats: error: setup failed: " + nats.ErrNoResponders.Error(), status: exitStatusTimeout, hint: true},
+		{msg: "nats: error: context deadline exceeded", status: exitStatusTimeout},
+		{msg: "nats: error: nats: permissions violation for publish to \"x\"", status: exitStatusPermission},
+		{msg: "nats: error: " + api.ApiError{Code: 400, ErrCode: 10058, Description: "stream name already in use"}.Error(), status: exitStatusError, code: "(10058)", hint: true},
+		{msg: "nats: error: something went wrong", status: exitStatusError},
+	}
+
+	for _, tc := range cases {
+		rendered, status := renderCLIError(tc.msg)
+		if status != tc.status {
+			t.Fatalf("expected status %d for %q got %d", tc.status, tc.msg, status)
+		}
+		if tc.code != "" && !strings.Contains(rendered, tc.code) {
+			t.Fatalf("expected %q to include %s", rendered, tc.code)
+		}
+		if tc.hint != strings.Contains(rendered, "\nnats: hint: ") {
+			t.Fatalf("unexpected hint rendering in %q", rendered)
+		}
+	}
+}
+
+func TestAPIErrorFromResponse(t *testing.T) {
+	err := apiErrorFromResponse([]byte(`{"type":"io.nats.jetstream.api.v1.stream_info_response","error":{"code":404,"err_code":10059,"description":"stream not found"}}`))
+	if err == nil || err.Error() != "stream not found (10059)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if apiErrorFromResponse([]byte(`{"type":"io.nats.jetstream.api.v1.stream_info_response"}`)) != nil {
+		t.Fatalf("expected no error")
+	}
+}
@@ -24,7 +24,6 @@ type auditAnalyzeCmd struct {
 	archivePath   string
 	examplesLimit uint
 	verbose       bool
-	quiet         bool
 	checks        []audit2.Check
 }
 
@@ -36,14 +35,13 @@ func configureAuditAnalyzeCommand(srv *fisk.CmdClause) {
 	analyze := srv.Command("analyze", "perform checks against an archive created by the 'gather' subcommand").Action(c.analyze)
 	analyze.Arg("archive", "path to input archive to analyze").Required().ExistingFileVar(&c.archivePath)
 	analyze.Flag("max-examples", "How many example issues to display for each failed check (0 for unlimited)").Default("5").UintVar(&c.examplesLimit)
-	analyze.Flag("quiet", "Disable info and warning messages during analysis").BoolVar(&c.quiet)
 	// Hidden flags
 	analyze.Flag("verbose", "Enable debug console messages during analysis").Hidden().BoolVar(&c.verbose)
 }
 
 func (cmd *auditAnalyzeCmd) analyze(_ *fisk.ParseContext) error {
 	// Adjust log levels
-	if cmd.quiet {
+	if opts().Quiet {
 		audit2.LogQuiet()
 	} else if cmd.verbose {
 		audit2.LogVerbose()
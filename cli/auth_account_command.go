@@ -27,7 +27,6 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/nats-io/nats-server/v2/server"
 	ab "github.com/synadia-io/jwt-auth-builder.go"
@@ -1010,7 +1009,7 @@ func (c *authAccountCommand) showAccount(operator ab.Operator, acct ab.Account)
 	cols.AddRow("Bearer Tokens Allowed", !limits.DisallowBearerTokens())
 	cols.AddRowUnlimited("Subscriptions", limits.MaxSubscriptions(), -1)
 	cols.AddRowUnlimited("Connections", limits.MaxConnections(), -1)
-	cols.AddRowUnlimitedIf("Maximum Payload", humanize.IBytes(uint64(limits.MaxPayload())), limits.MaxPayload() <= 0)
+	cols.AddRowUnlimitedIf("Maximum Payload", fiBytes(uint64(limits.MaxPayload())), limits.MaxPayload() <= 0)
 	if limits.MaxData() > 0 {
 		cols.AddRow("Data", limits.MaxData()) // only showing when set as afaik its a ngs thing
 	}
@@ -1057,10 +1056,10 @@ func (c *authAccountCommand) showAccount(operator ab.Operator, acct ab.Account)
 			cols.AddRowUnlimited("Maximum Streams", streams, -1)
 			cols.AddRowUnlimited("Max Consumers", maxConns, -1)
 			cols.AddRow("Max Stream Size Required", streamSizeRequired)
-			cols.AddRow("Max File Storage", humanize.IBytes(uint64(maxDisk)))
-			cols.AddRowIf("Max File Storage Stream Size", humanize.IBytes(uint64(maxDiskStream)), maxDiskStream > 0)
-			cols.AddRow("Max Memory Storage", humanize.IBytes(uint64(maxMem)))
-			cols.AddRowIf("Max Memory Storage Stream Size", humanize.IBytes(uint64(maxMemStream)), maxMemStream > 0)
+			cols.AddRow("Max File Storage", fiBytes(uint64(maxDisk)))
+			cols.AddRowIf("Max File Storage Stream Size", fiBytes(uint64(maxDiskStream)), maxDiskStream > 0)
+			cols.AddRow("Max Memory Storage", fiBytes(uint64(maxMem)))
+			cols.AddRowIf("Max Memory Storage Stream Size", fiBytes(uint64(maxMemStream)), maxMemStream > 0)
 		}
 
 		cols.Indent(0)
@@ -25,6 +25,7 @@ func configureAuthCommand(app commandHost) {
 	configureAuthAccountCommand(auth)
 	configureAuthUserCommand(auth)
 	configureAuthNkeyCommand(auth)
+	configureAuthProbeCommand(auth)
 }
 
 func init() {
@@ -0,0 +1,449 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	authProbeAllowed = "allowed"
+	authProbeDenied  = "denied"
+	authProbeNA      = "n/a"
+)
+
+var authProbeViolationRe = regexp.MustCompile(`(?i)permissions violation for (publish|subscription|publish with reply) (?:to|of) "([^"]+)"`)
+
+type authProbeCommand struct {
+	subjects []string
+	matrix   string
+	json     bool
+}
+
+type authProbeResult struct {
+	Subject   string `json:"subject"`
+	Publish   string `json:"publish"`
+	Subscribe string `json:"subscribe"`
+	Request   string `json:"request,omitempty"`
+	Response  string `json:"response,omitempty"`
+}
+
+// authProbeSession is a dedicated connection that records the permission violations reported for it
+type authProbeSession struct {
+	nc         *nats.Conn
+	closed     chan struct{}
+	violations chan string
+
+	mu       sync.Mutex
+	violated map[string]bool
+	errs     []error
+}
+
+func configureAuthProbeCommand(auth commandHost) {
+	c := &authProbeCommand{}
+
+	help := `Probes the permissions of the current connection
+
+Every subject is published to with an empty message and subscribed to, the
+server reports violations of the connection permissions for each. When probing
+specific subjects a request is also sent to each to check an inbox can be used
+for responses.
+
+Publishing sends real messages that subscribers on the subjects will receive.
+`
+
+	probe := auth.Command("probe", help).Action(c.probeAction)
+	probe.Flag("subject", "Subject to probe (pass multiple times)").PlaceHolder("SUBJECT").StringsVar(&c.subjects)
+	probe.Flag("matrix", "File holding subjects to probe, one per line").PlaceHolder("FILE").ExistingFileVar(&c.matrix)
+	probe.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func (c *authProbeCommand) probeAction(_ *fisk.ParseContext) error {
+	subjects, err := c.probeSubjects()
+	if err != nil {
+		return err
+	}
+
+	results := make([]*authProbeResult, len(subjects))
+	for i, subject := range subjects {
+		results[i] = &authProbeResult{Subject: subject, Publish: authProbeAllowed, Subscribe: authProbeAllowed}
+	}
+
+	err = c.probePublish(results)
+	if err != nil {
+		return err
+	}
+
+	err = c.probeSubscribe(results)
+	if err != nil {
+		return err
+	}
+
+	if c.matrix == "" {
+		err = c.probeRequest(results)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		iu.PrintJSON(results)
+		return nil
+	}
+
+	c.renderResults(results)
+
+	return nil
+}
+
+func (c *authProbeCommand) probeSubjects() ([]string, error) {
+	subjects := append([]string{}, c.subjects...)
+
+	if c.matrix != "" {
+		fh, err := os.Open(c.matrix)
+		if err != nil {
+			return nil, err
+		}
+		defer fh.Close()
+
+		scanner := bufio.NewScanner(fh)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			subjects = append(subjects, line)
+		}
+
+		err = scanner.Err()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("subjects to probe are required, use --subject or --matrix")
+	}
+
+	// violations are matched to probes by subject so every subject can only be probed once
+	seen := map[string]bool{}
+	var unique []string
+	for _, subject := range subjects {
+		if strings.ContainsAny(subject, " \t") {
+			return nil, fmt.Errorf("invalid subject %q", subject)
+		}
+
+		if !seen[subject] {
+			seen[subject] = true
+			unique = append(unique, subject)
+		}
+	}
+
+	return unique, nil
+}
+
+func (c *authProbeCommand) probePublish(results []*authProbeResult) error {
+	session, err := newAuthProbeSession()
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		if authProbeIsWildcard(res.Subject) {
+			res.Publish = authProbeNA
+			continue
+		}
+
+		err = session.nc.Publish(res.Subject, nil)
+		if err != nil {
+			session.close()
+			return err
+		}
+	}
+
+	err = session.finish()
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		if session.isViolated("publish", res.Subject) {
+			res.Publish = authProbeDenied
+		}
+	}
+
+	return nil
+}
+
+func (c *authProbeCommand) probeSubscribe(results []*authProbeResult) error {
+	session, err := newAuthProbeSession()
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		sub, err := session.nc.SubscribeSync(res.Subject)
+		if err != nil {
+			session.close()
+			return err
+		}
+
+		// denied subscriptions are only reported asynchronously so round trip before removing it again
+		err = session.nc.Flush()
+		if err == nil {
+			err = sub.Unsubscribe()
+		}
+		if err != nil {
+			session.close()
+			return err
+		}
+	}
+
+	err = session.finish()
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		if session.isViolated("subscription", res.Subject) {
+			res.Subscribe = authProbeDenied
+		}
+	}
+
+	return nil
+}
+
+func (c *authProbeCommand) probeRequest(results []*authProbeResult) error {
+	session, err := newAuthProbeSession()
+	if err != nil {
+		return err
+	}
+
+	inboxes := map[string]string{}
+
+	for _, res := range results {
+		if authProbeIsWildcard(res.Subject) {
+			res.Request = authProbeNA
+			continue
+		}
+
+		inbox := session.nc.NewInbox()
+		inboxes[res.Subject] = inbox
+
+		res.Response, err = session.request(res.Subject, inbox)
+		if err != nil {
+			session.close()
+			return err
+		}
+	}
+
+	err = session.finish()
+	if err != nil {
+		return err
+	}
+
+	for _, res := range results {
+		inbox, ok := inboxes[res.Subject]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case session.isViolated("publish", res.Subject):
+			res.Request = authProbeDenied
+			res.Response = "publish denied"
+		case session.isViolated("subscription", inbox):
+			res.Request = authProbeDenied
+			res.Response = "inbox subscription denied"
+		case session.isViolated("publish with reply", inbox):
+			res.Request = authProbeDenied
+			res.Response = "reply subject denied"
+		default:
+			res.Request = authProbeAllowed
+		}
+	}
+
+	return nil
+}
+
+func (c *authProbeCommand) renderResults(results []*authProbeResult) {
+	table := newTableWriter("Permissions of the current connection")
+
+	if c.matrix == "" {
+		table.AddHeaders("Subject", "Publish", "Subscribe", "Request", "Response")
+	} else {
+		table.AddHeaders("Subject", "Publish", "Subscribe")
+	}
+
+	for _, res := range results {
+		if c.matrix == "" {
+			table.AddRow(res.Subject, res.Publish, res.Subscribe, res.Request, res.Response)
+		} else {
+			table.AddRow(res.Subject, res.Publish, res.Subscribe)
+		}
+	}
+
+	fmt.Println(table.Render())
+}
+
+func authProbeIsWildcard(subject string) bool {
+	for _, token := range strings.Split(subject, ".") {
+		if token == "*" || token == ">" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newAuthProbeSession() (*authProbeSession, error) {
+	s := &authProbeSession{
+		closed:     make(chan struct{}),
+		violations: make(chan string, 100),
+		violated:   map[string]bool{},
+	}
+
+	mu.Lock()
+	if opts().Config == nil {
+		err := loadContext(false)
+		if err != nil {
+			mu.Unlock()
+			return nil, err
+		}
+	}
+	mu.Unlock()
+
+	probeOpts := append(natsOpts(),
+		nats.NoReconnect(),
+		nats.ErrorHandler(s.errorHandler),
+		nats.ClosedHandler(func(_ *nats.Conn) { close(s.closed) }),
+	)
+
+	nc, err := nats.Connect(opts().Config.ServerURL(), probeOpts...)
+	if err != nil {
+		return nil, err
+	}
+	s.nc = nc
+
+	return s, nil
+}
+
+func (s *authProbeSession) errorHandler(_ *nats.Conn, _ *nats.Subscription, err error) {
+	matches := authProbeViolationRe.FindStringSubmatch(err.Error())
+	if matches == nil {
+		s.mu.Lock()
+		s.errs = append(s.errs, err)
+		s.mu.Unlock()
+		return
+	}
+
+	key := strings.ToLower(matches[1]) + " " + matches[2]
+
+	s.mu.Lock()
+	s.violated[key] = true
+	s.mu.Unlock()
+
+	select {
+	case s.violations <- key:
+	default:
+	}
+}
+
+func (s *authProbeSession) isViolated(op string, subject string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.violated[op+" "+subject]
+}
+
+// request sends a request to subject with replies to inbox, waiting only until it is clear whether a response will arrive
+func (s *authProbeSession) request(subject string, inbox string) (string, error) {
+	replies := make(chan *nats.Msg, 1)
+	sub, err := s.nc.ChanSubscribe(inbox, replies)
+	if err != nil {
+		return "", err
+	}
+	defer sub.Unsubscribe()
+
+	err = s.nc.PublishRequest(subject, inbox, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.nc.Flush()
+	if err != nil {
+		return "", err
+	}
+
+	timeout := time.NewTimer(opts().Timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case msg := <-replies:
+			if msg.Header.Get("Status") == "503" {
+				return "no responders", nil
+			}
+			return "responded", nil
+
+		case key := <-s.violations:
+			if key == "publish "+subject || key == "subscription "+inbox || key == "publish with reply "+inbox {
+				return "", nil
+			}
+
+		case <-timeout.C:
+			if s.isViolated("publish", subject) || s.isViolated("subscription", inbox) || s.isViolated("publish with reply", inbox) {
+				return "", nil
+			}
+			return "timeout", nil
+		}
+	}
+}
+
+// finish closes the connection and waits for all violations reported before the close to be handled
+func (s *authProbeSession) finish() error {
+	err := s.nc.Flush()
+	if err != nil {
+		s.close()
+		return err
+	}
+
+	s.close()
+
+	select {
+	case <-s.closed:
+	case <-time.After(opts().Timeout):
+		return fmt.Errorf("timeout waiting for the probe connection to close")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return errors.Join(s.errs...)
+}
+
+func (s *authProbeSession) close() {
+	s.nc.Close()
+}
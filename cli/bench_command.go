@@ -23,10 +23,10 @@ import (
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/gosuri/uiprogress"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/bench"
+	iu "github.com/nats-io/natscli/internal/util"
 )
 
 type benchCmd struct {
@@ -66,6 +66,10 @@ type benchCmd struct {
 	deDuplicationWindow  time.Duration
 	retries              int
 	retriesUsed          bool
+	warmup               string
+	measure              time.Duration
+	window               benchWindowReport
+	json                 bool
 }
 
 const (
@@ -108,46 +112,67 @@ JetStream KV put and get:
 
   nats bench benchsubject --kv --sub 10
 
+JetStream KV throughput and latency using a temporary bucket:
+
+  nats bench kv benchbucket --put 4 --get 4 --keys 10000 --value-size 256
+
+Object Store put and get throughput using a temporary bucket:
+
+  nats bench obj benchbucket --size 10MB --count 100 --put-workers 4 --get-workers 4
+
+Core NATS round trip latency compared to JetStream publish latency:
+
+  nats bench compare-latency benchsubject --js-stream benchstream --msgs 50000 --size 256
+
 Remember to use --no-progress to measure performance more accurately
 `
-	bench := app.Command("bench", "Benchmark utility").Action(c.bench)
+	bench := app.Command("bench", "Benchmark utility")
 	if !opts().NoCheats {
 		bench.CheatFile(fs, "bench", "cheats/bench.md")
 	}
 	bench.HelpLong(benchHelp)
-	bench.Arg("subject", "Subject to use for the benchmark").Required().StringVar(&c.subject)
-	bench.Flag("pub", "Number of concurrent publishers").Default("0").IntVar(&c.numPubs)
-	bench.Flag("sub", "Number of concurrent subscribers").Default("0").IntVar(&c.numSubs)
-	bench.Flag("js", "Use JetStream").UnNegatableBoolVar(&c.js)
-	bench.Flag("request", "Request-Reply mode: publishers send requests waits for a reply").UnNegatableBoolVar(&c.request)
-	bench.Flag("reply", "Request-Reply mode: subscribers send replies").UnNegatableBoolVar(&c.reply)
-	bench.Flag("kv", "KV mode, subscribers get from the bucket and publishers put in the bucket").UnNegatableBoolVar(&c.kv)
-	bench.Flag("msgs", "Number of messages to publish").Default("100000").IntVar(&c.numMsg)
-	bench.Flag("size", "Size of the test messages").Default("128").StringVar(&c.msgSizeString)
-	bench.Flag("no-progress", "Disable progress bar while publishing").UnNegatableBoolVar(&c.noProgress)
-	bench.Flag("csv", "Save benchmark data to CSV file").StringVar(&c.csvFile)
-	bench.Flag("purge", "Purge the stream before running").UnNegatableBoolVar(&c.purge)
-	bench.Flag("storage", "JetStream storage (memory/file) for the \"benchstream\" stream").Default("file").EnumVar(&c.storage, "memory", "file")
-	bench.Flag("replicas", "Number of stream replicas for the \"benchstream\" stream").Default("1").IntVar(&c.replicas)
-	bench.Flag("maxbytes", "The maximum size of the stream or KV bucket in bytes").Default("1GB").StringVar(&c.streamMaxBytesString)
-	bench.Flag("stream", "When set to something else than \"benchstream\": use (and do not attempt to define) the specified stream when creating durable subscribers. Otherwise define and use the \"benchstream\" stream").Default(DefaultStreamName).StringVar(&c.streamName)
-	bench.Flag("bucket", "When set to something else than \"benchbucket\": use (and do not attempt to define) the specified bucket when in KV mode. Otherwise define and use the \"benchbucket\" bucket").Default(DefaultBucketName).StringVar(&c.bucketName)
-	bench.Flag("consumer", "Specify the durable consumer name to use").Default(DefaultDurableConsumerName).StringVar(&c.consumerName)
-	bench.Flag("jstimeout", "Timeout for JS operations").Default("30s").DurationVar(&c.jsTimeout)
-	bench.Flag("syncpub", "Synchronously publish to the stream").UnNegatableBoolVar(&c.syncPub)
-	bench.Flag("pubbatch", "Sets the batch size for JS asynchronous publishing").Default("100").IntVar(&c.pubBatch)
-	bench.Flag("pull", "Use a shared durable explicitly acknowledged JS pull consumer rather than individual ephemeral consumers").UnNegatableBoolVar(&c.pull)
-	bench.Flag("push", "Use a shared durable explicitly acknowledged JS push consumer with a queue group rather than individual ephemeral consumers").UnNegatableBoolVar(&c.pushDurable)
-	bench.Flag("consumerbatch", "Sets the batch size for the JS durable pull consumer, or the max ack pending value for the JS durable push consumer").Default("100").IntVar(&c.consumerBatch)
-	bench.Flag("pullbatch", "Sets the batch size for the JS durable pull consumer, or the max ack pending value for the JS durable push consumer").Hidden().Default("100").IntVar(&c.consumerBatch)
-	bench.Flag("subsleep", "Sleep for the specified interval before sending the subscriber acknowledgement back in --js mode, or sending the reply back in --reply mode,  or doing the next get in --kv mode").Default("0s").DurationVar(&c.subSleep)
-	bench.Flag("pubsleep", "Sleep for the specified interval after publishing each message").Default("0s").DurationVar(&c.pubSleep)
-	bench.Flag("history", "History depth for the bucket in KV mode").Default("1").Uint8Var(&c.history)
-	bench.Flag("multisubject", "Multi-subject mode, each message is published on a subject that includes the publisher's message sequence number as a token").UnNegatableBoolVar(&c.multiSubject)
-	bench.Flag("multisubjectmax", "The maximum number of subjects to use in multi-subject mode (0 means no max)").Default("100000").IntVar(&c.multiSubjectMax)
-	bench.Flag("retries", "The maximum number of retries in JS operations").Default("3").IntVar(&c.retries)
-	bench.Flag("dedup", "Sets a message id in the header to use JS Publish de-duplication").Default("false").UnNegatableBoolVar(&c.deDuplication)
-	bench.Flag("dedupwindow", "Sets the duration of the stream's deduplication functionality").Default("2m").DurationVar(&c.deDuplicationWindow)
+
+	run := bench.Command("run", "Benchmark Core NATS, JetStream or KV using a subject").Default().Action(c.bench)
+	run.Arg("subject", "Subject to use for the benchmark").Required().StringVar(&c.subject)
+	run.Flag("pub", "Number of concurrent publishers").Default("0").IntVar(&c.numPubs)
+	run.Flag("sub", "Number of concurrent subscribers").Default("0").IntVar(&c.numSubs)
+	run.Flag("js", "Use JetStream").UnNegatableBoolVar(&c.js)
+	run.Flag("request", "Request-Reply mode: publishers send requests waits for a reply").UnNegatableBoolVar(&c.request)
+	run.Flag("reply", "Request-Reply mode: subscribers send replies").UnNegatableBoolVar(&c.reply)
+	run.Flag("kv", "KV mode, subscribers get from the bucket and publishers put in the bucket").UnNegatableBoolVar(&c.kv)
+	run.Flag("msgs", "Number of messages to publish").Default("100000").IntVar(&c.numMsg)
+	run.Flag("size", "Size of the test messages").Default("128").StringVar(&c.msgSizeString)
+	run.Flag("no-progress", "Disable progress bar while publishing").UnNegatableBoolVar(&c.noProgress)
+	run.Flag("csv", "Save benchmark data to CSV file").StringVar(&c.csvFile)
+	run.Flag("purge", "Purge the stream before running").UnNegatableBoolVar(&c.purge)
+	run.Flag("storage", "JetStream storage (memory/file) for the \"benchstream\" stream").Default("file").EnumVar(&c.storage, "memory", "file")
+	run.Flag("replicas", "Number of stream replicas for the \"benchstream\" stream").Default("1").IntVar(&c.replicas)
+	run.Flag("maxbytes", "The maximum size of the stream or KV bucket in bytes").Default("1GB").StringVar(&c.streamMaxBytesString)
+	run.Flag("stream", "When set to something else than \"benchstream\": use (and do not attempt to define) the specified stream when creating durable subscribers. Otherwise define and use the \"benchstream\" stream").Default(DefaultStreamName).StringVar(&c.streamName)
+	run.Flag("bucket", "When set to something else than \"benchbucket\": use (and do not attempt to define) the specified bucket when in KV mode. Otherwise define and use the \"benchbucket\" bucket").Default(DefaultBucketName).StringVar(&c.bucketName)
+	run.Flag("consumer", "Specify the durable consumer name to use").Default(DefaultDurableConsumerName).StringVar(&c.consumerName)
+	run.Flag("jstimeout", "Timeout for JS operations").Default("30s").DurationVar(&c.jsTimeout)
+	run.Flag("syncpub", "Synchronously publish to the stream").UnNegatableBoolVar(&c.syncPub)
+	run.Flag("pubbatch", "Sets the batch size for JS asynchronous publishing").Default("100").IntVar(&c.pubBatch)
+	run.Flag("pull", "Use a shared durable explicitly acknowledged JS pull consumer rather than individual ephemeral consumers").UnNegatableBoolVar(&c.pull)
+	run.Flag("push", "Use a shared durable explicitly acknowledged JS push consumer with a queue group rather than individual ephemeral consumers").UnNegatableBoolVar(&c.pushDurable)
+	run.Flag("consumerbatch", "Sets the batch size for the JS durable pull consumer, or the max ack pending value for the JS durable push consumer").Default("100").IntVar(&c.consumerBatch)
+	run.Flag("pullbatch", "Sets the batch size for the JS durable pull consumer, or the max ack pending value for the JS durable push consumer").Hidden().Default("100").IntVar(&c.consumerBatch)
+	run.Flag("subsleep", "Sleep for the specified interval before sending the subscriber acknowledgement back in --js mode, or sending the reply back in --reply mode,  or doing the next get in --kv mode").Default("0s").DurationVar(&c.subSleep)
+	run.Flag("pubsleep", "Sleep for the specified interval after publishing each message").Default("0s").DurationVar(&c.pubSleep)
+	run.Flag("history", "History depth for the bucket in KV mode").Default("1").Uint8Var(&c.history)
+	run.Flag("multisubject", "Multi-subject mode, each message is published on a subject that includes the publisher's message sequence number as a token").UnNegatableBoolVar(&c.multiSubject)
+	run.Flag("multisubjectmax", "The maximum number of subjects to use in multi-subject mode (0 means no max)").Default("100000").IntVar(&c.multiSubjectMax)
+	run.Flag("retries", "The maximum number of retries in JS operations").Default("3").IntVar(&c.retries)
+	run.Flag("dedup", "Sets a message id in the header to use JS Publish de-duplication").Default("false").UnNegatableBoolVar(&c.deDuplication)
+	run.Flag("dedupwindow", "Sets the duration of the stream's deduplication functionality").Default("2m").DurationVar(&c.deDuplicationWindow)
+	run.Flag("warmup", "Run at full load for this duration or number of messages before measuring, excluded from all statistics").PlaceHolder("DURATION|MSGS").StringVar(&c.warmup)
+	run.Flag("measure", "Only measure for this duration after the warmup, stopping before all messages are handled").PlaceHolder("DURATION").DurationVar(&c.measure)
+	run.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	configureBenchKVCommand(bench)
+	configureBenchObjCommand(bench)
+	configureBenchLatencyCommand(bench)
 }
 
 func init() {
@@ -164,6 +189,22 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 		log.Fatal("Can not parse or invalid the value specified for the message size: %s", c.msgSizeString)
 	}
 	c.msgSize = int(msgSize)
+
+	c.window.WarmupDuration, c.window.WarmupMsgs, err = parseBenchWarmup(c.warmup)
+	if err != nil {
+		return err
+	}
+	if c.window.WarmupMsgs >= c.numMsg {
+		return fmt.Errorf("the warmup of %s messages leaves no messages to measure, increase --msgs", f(c.window.WarmupMsgs))
+	}
+	if c.measure < 0 {
+		return fmt.Errorf("measure can not be negative")
+	}
+	c.window.Measure = c.measure
+	if c.json {
+		c.noProgress = true
+	}
+
 	if c.js && c.numSubs > 0 && c.pull {
 		log.Print("JetStream durable pull consumer mode, subscriber(s) will explicitly acknowledge the consumption of messages")
 	}
@@ -214,20 +255,24 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 	// Print the banner to repeat the arguments being used
 	if c.js {
 		if c.streamName == DefaultStreamName {
-			log.Printf("Starting JetStream benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, js=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, stream=%s, maxbytes=%s, storage=%s, syncpub=%v, pubbatch=%s, jstimeout=%v, pull=%v, consumerbatch=%s, push=%v, consumername=%s, replicas=%d, purge=%v, pubsleep=%v, subsleep=%v, dedup=%v, dedupwindow=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.js, f(c.numMsg), humanize.IBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.streamName, humanize.IBytes(uint64(c.streamMaxBytes)), c.storage, c.syncPub, f(c.pubBatch), c.jsTimeout, c.pull, f(c.consumerBatch), c.pushDurable, c.consumerName, c.replicas, c.purge, c.pubSleep, c.subSleep, c.deDuplication, c.deDuplicationWindow)
+			log.Printf("Starting JetStream benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, js=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, stream=%s, maxbytes=%s, storage=%s, syncpub=%v, pubbatch=%s, jstimeout=%v, pull=%v, consumerbatch=%s, push=%v, consumername=%s, replicas=%d, purge=%v, pubsleep=%v, subsleep=%v, dedup=%v, dedupwindow=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.js, f(c.numMsg), fiBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.streamName, fiBytes(uint64(c.streamMaxBytes)), c.storage, c.syncPub, f(c.pubBatch), c.jsTimeout, c.pull, f(c.consumerBatch), c.pushDurable, c.consumerName, c.replicas, c.purge, c.pubSleep, c.subSleep, c.deDuplication, c.deDuplicationWindow)
 		} else {
-			log.Printf("Starting JetStream benchmark [subject=%s,  multisubject=%v, multisubjectmax=%d, js=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, stream=%s, maxbytes=%s, syncpub=%v, pubbatch=%s, jstimeout=%v, pull=%v, consumerbatch=%s, push=%v, consumername=%s, purge=%v, pubsleep=%v, subsleep=%v, deduplication=%v, dedupwindow=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.js, f(c.numMsg), humanize.IBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.streamName, humanize.IBytes(uint64(c.streamMaxBytes)), c.syncPub, f(c.pubBatch), c.jsTimeout, c.pull, f(c.consumerBatch), c.pushDurable, c.consumerName, c.purge, c.pubSleep, c.subSleep, c.deDuplication, c.deDuplicationWindow)
+			log.Printf("Starting JetStream benchmark [subject=%s,  multisubject=%v, multisubjectmax=%d, js=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, stream=%s, maxbytes=%s, syncpub=%v, pubbatch=%s, jstimeout=%v, pull=%v, consumerbatch=%s, push=%v, consumername=%s, purge=%v, pubsleep=%v, subsleep=%v, deduplication=%v, dedupwindow=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.js, f(c.numMsg), fiBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.streamName, fiBytes(uint64(c.streamMaxBytes)), c.syncPub, f(c.pubBatch), c.jsTimeout, c.pull, f(c.consumerBatch), c.pushDurable, c.consumerName, c.purge, c.pubSleep, c.subSleep, c.deDuplication, c.deDuplicationWindow)
 		}
 	} else if c.kv {
-		log.Printf("Starting KV benchmark [bucket=%s, kv=%v, msgs=%s, msgsize=%s, maxbytes=%s, pubs=%d, sub=%d, storage=%s, replicas=%d, pubsleep=%v, subsleep=%v]", c.bucketName, c.kv, f(c.numMsg), humanize.IBytes(uint64(c.msgSize)), humanize.IBytes(uint64(c.streamMaxBytes)), c.numPubs, c.numSubs, c.storage, c.replicas, c.pubSleep, c.subSleep)
+		log.Printf("Starting KV benchmark [bucket=%s, kv=%v, msgs=%s, msgsize=%s, maxbytes=%s, pubs=%d, sub=%d, storage=%s, replicas=%d, pubsleep=%v, subsleep=%v]", c.bucketName, c.kv, f(c.numMsg), fiBytes(uint64(c.msgSize)), fiBytes(uint64(c.streamMaxBytes)), c.numPubs, c.numSubs, c.storage, c.replicas, c.pubSleep, c.subSleep)
 	} else {
 		if c.request || c.reply {
-			log.Printf("Starting request-reply benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, request=%v, reply=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, pubsleep=%v, subsleep=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.request, c.reply, f(c.numMsg), humanize.IBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.pubSleep, c.subSleep)
+			log.Printf("Starting request-reply benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, request=%v, reply=%v, msgs=%s, msgsize=%s, pubs=%d, subs=%d, pubsleep=%v, subsleep=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, c.request, c.reply, f(c.numMsg), fiBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.pubSleep, c.subSleep)
 		} else {
-			log.Printf("Starting Core NATS pub/sub benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, msgs=%s, msgsize=%s, pubs=%d, subs=%d, pubsleep=%v, subsleep=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, f(c.numMsg), humanize.IBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.pubSleep, c.subSleep)
+			log.Printf("Starting Core NATS pub/sub benchmark [subject=%s, multisubject=%v, multisubjectmax=%d, msgs=%s, msgsize=%s, pubs=%d, subs=%d, pubsleep=%v, subsleep=%v]", getSubscribeSubject(c), c.multiSubject, c.multiSubjectMax, f(c.numMsg), fiBytes(uint64(c.msgSize)), c.numPubs, c.numSubs, c.pubSleep, c.subSleep)
 		}
 	}
 
+	if c.window.windowed() {
+		log.Printf("Warming up for %s per client then measuring %s", c.window.warmupString(), c.window.measureString())
+	}
+
 	bm := bench.NewBenchmark("NATS", c.numSubs, c.numPubs)
 
 	benchId := strconv.FormatInt(time.Now().UnixMilli(), 16)
@@ -321,7 +366,7 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 					defer func() {
 						err := js.DeleteConsumer(c.streamName, c.consumerName)
 						if err != nil {
-							log.Printf("Error deleting the pull consumer on stream %s: %v", c.streamName, err)
+							logErrorf("Error deleting the pull consumer on stream %s: %v", c.streamName, err)
 						}
 						log.Printf("Deleted durable consumer: %s\n", c.consumerName)
 					}()
@@ -382,7 +427,7 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 			}
 		}()
 
-		go c.runSubscriber(bm, nc, startwg, donewg, numMsg, offset(i, subCounts))
+		go c.runSubscriber(bm, nc, startwg, donewg, numMsg, offset(i, subCounts), c.newBenchWindow(numMsg))
 	}
 	startwg.Wait()
 
@@ -398,7 +443,7 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 		startwg.Add(1)
 		donewg.Add(1)
 
-		go c.runPublisher(bm, nc, startwg, donewg, trigger, pubCounts[i], offset(i, pubCounts), benchId, strconv.Itoa(i))
+		go c.runPublisher(bm, nc, startwg, donewg, trigger, pubCounts[i], offset(i, pubCounts), benchId, strconv.Itoa(i), c.newBenchWindow(pubCounts[i]))
 	}
 
 	if !c.noProgress {
@@ -423,18 +468,33 @@ func (c *benchCmd) bench(_ *fisk.ParseContext) error {
 		log.Print("WARNING: at least one of the JS publish operations had to be retried. These results are not optimal!")
 	}
 
-	fmt.Println()
-	fmt.Println(bm.Report())
+	if c.window.windowed() && !bm.Pubs.HasSamples() && !bm.Subs.HasSamples() {
+		return fmt.Errorf("all clients finished during the warmup, increase --msgs or reduce --warmup")
+	}
 
 	if c.csvFile != "" {
-		csv := bm.CSV()
-		err := os.WriteFile(c.csvFile, []byte(csv), 0600)
+		csv, err := benchCSV(bm, c.window)
+		if err != nil {
+			return err
+		}
+		err = os.WriteFile(c.csvFile, []byte(csv), 0600)
 		if err != nil {
 			log.Printf("error writing file %s: %v", c.csvFile, err)
+		} else if !c.json {
+			fmt.Printf("Saved metric data in csv file %s\n", c.csvFile)
 		}
-		fmt.Printf("Saved metric data in csv file %s\n", c.csvFile)
 	}
 
+	if c.json {
+		return iu.PrintJSON(newBenchJSONReport(bm, c.window))
+	}
+
+	fmt.Println()
+	if c.window.windowed() {
+		renderBenchWindow(bm, c.window)
+	}
+	fmt.Println(bm.Report())
+
 	return nil
 }
 
@@ -465,7 +525,7 @@ func getPublishSubject(c *benchCmd, number int) string {
 	}
 }
 
-func coreNATSPublisher(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, offset int) {
+func coreNATSPublisher(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, offset int, window *benchWindow) {
 
 	var m *nats.Msg
 	var err error
@@ -501,12 +561,15 @@ func coreNATSPublisher(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg
 				log.Fatalf("Publish Request did not receive a positive ACK: %q", m.Data)
 			}
 		}
+		if !window.observe(time.Now()) {
+			break
+		}
 		time.Sleep(c.pubSleep)
 	}
 	state = "Finished  "
 }
 
-func jsPublisher(c *benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, idPrefix string, pubNumber string, offset int) {
+func jsPublisher(c *benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, idPrefix string, pubNumber string, offset int, window *benchWindow) {
 	js, err := nc.JetStream(jsOpts()...)
 	if err != nil {
 		log.Fatalf("Couldn't get the JetStream context: %v", err)
@@ -521,7 +584,8 @@ func jsPublisher(c *benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byt
 	}
 
 	if !c.syncPub {
-		for i := 0; i < numMsg; {
+		measuring := true
+		for i := 0; i < numMsg && measuring; {
 			state = "Publishing"
 			futures := make([]nats.PubAckFuture, min(c.pubBatch, numMsg-i))
 			for j := 0; j < c.pubBatch && (i+j) < numMsg; j++ {
@@ -551,6 +615,9 @@ func jsPublisher(c *benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byt
 					select {
 					case <-futures[future].Ok():
 						i++
+						if measuring && !window.observe(time.Now()) {
+							measuring = false
+						}
 					case err := <-futures[future].Err():
 						if err.Error() == "nats: maximum bytes exceeded" {
 							log.Fatalf("Stream maximum bytes exceeded, can not publish any more messages")
@@ -590,13 +657,15 @@ func jsPublisher(c *benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byt
 				log.Printf("Publish error: %v (retrying)", err)
 				c.retriesUsed = true
 				i--
+			} else if !window.observe(time.Now()) {
+				break
 			}
 			time.Sleep(c.pubSleep)
 		}
 	}
 }
 
-func kvPutter(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, offset int) {
+func kvPutter(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, numMsg int, offset int, window *benchWindow) {
 	js, err := nc.JetStream(jsOpts()...)
 	if err != nil {
 		log.Fatalf("Couldn't get the JetStream context: %v", err)
@@ -623,11 +692,14 @@ func kvPutter(c benchCmd, nc *nats.Conn, progress *uiprogress.Bar, msg []byte, n
 		if err != nil {
 			log.Fatalf("Put: %s", err)
 		}
+		if !window.observe(time.Now()) {
+			break
+		}
 		time.Sleep(c.pubSleep)
 	}
 }
 
-func (c *benchCmd) runPublisher(bm *bench.Benchmark, nc *nats.Conn, startwg *sync.WaitGroup, donewg *sync.WaitGroup, trigger chan struct{}, numMsg int, offset int, idPrefix string, pubNumber string) {
+func (c *benchCmd) runPublisher(bm *bench.Benchmark, nc *nats.Conn, startwg *sync.WaitGroup, donewg *sync.WaitGroup, trigger chan struct{}, numMsg int, offset int, idPrefix string, pubNumber string, window *benchWindow) {
 	startwg.Done()
 
 	var progress *uiprogress.Bar
@@ -657,13 +729,14 @@ func (c *benchCmd) runPublisher(bm *bench.Benchmark, nc *nats.Conn, startwg *syn
 	}
 
 	start := time.Now()
+	window.begin(start)
 
 	if !c.js && !c.kv {
-		coreNATSPublisher(*c, nc, progress, msg, numMsg, offset)
+		coreNATSPublisher(*c, nc, progress, msg, numMsg, offset, window)
 	} else if c.kv {
-		kvPutter(*c, nc, progress, msg, numMsg, offset)
+		kvPutter(*c, nc, progress, msg, numMsg, offset, window)
 	} else if c.js {
-		jsPublisher(c, nc, progress, msg, numMsg, idPrefix, pubNumber, offset)
+		jsPublisher(c, nc, progress, msg, numMsg, idPrefix, pubNumber, offset, window)
 	}
 
 	err := nc.Flush()
@@ -671,13 +744,14 @@ func (c *benchCmd) runPublisher(bm *bench.Benchmark, nc *nats.Conn, startwg *syn
 		log.Fatalf("Could not flush the connection: %v", err)
 	}
 
-	bm.AddPubSample(bench.NewSample(numMsg, c.msgSize, start, time.Now(), nc))
+	c.addSample(bm.AddPubSample, window, numMsg, start, time.Now(), nc, "publisher "+pubNumber)
 
 	donewg.Done()
 }
 
-func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sync.WaitGroup, donewg *sync.WaitGroup, numMsg int, offset int) {
+func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sync.WaitGroup, donewg *sync.WaitGroup, numMsg int, offset int, window *benchWindow) {
 	received := 0
+	finished := false
 
 	ch := make(chan time.Time, 2)
 
@@ -718,11 +792,14 @@ func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sy
 			}
 		}
 
+		now := time.Now()
 		if !c.js && received == 1 {
-			ch <- time.Now()
+			window.begin(now)
+			ch <- now
 		}
-		if received >= numMsg && !c.reply {
-			ch <- time.Now()
+		if !c.reply && !finished && (!window.observe(now) || received >= numMsg) {
+			finished = true
+			ch <- now
 		}
 		if progress != nil {
 			progress.Incr()
@@ -744,6 +821,7 @@ func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sy
 			// start the timer now rather than when the first message is received in JS mode
 
 			startTime := time.Now()
+			window.begin(startTime)
 			ch <- startTime
 			if progress != nil {
 				progress.TimeStarted = startTime
@@ -812,6 +890,7 @@ func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sy
 
 		// start the timer now rather than when the first message is received in JS mode
 		startTime := time.Now()
+		window.begin(startTime)
 		ch <- startTime
 		if progress != nil {
 			progress.TimeStarted = startTime
@@ -824,17 +903,20 @@ func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sy
 				log.Fatalf("Error getting key %d: %v", offset+i, err)
 			}
 			if entry.Value() == nil {
-				log.Printf("Warning: got no value for key %d", offset+i)
+				logWarnf("Got no value for key %d", offset+i)
 			}
 
 			if progress != nil {
 				progress.Incr()
 			}
+			if !window.observe(time.Now()) {
+				break
+			}
 			time.Sleep(c.subSleep)
 		}
 		ch <- time.Now()
 	} else if c.js && c.pull {
-		for i := 0; i < numMsg; {
+		for i := 0; i < numMsg && !finished; {
 			batchSize := func() int {
 				if c.consumerBatch <= (numMsg - i) {
 					return c.consumerBatch
@@ -879,7 +961,7 @@ func (c *benchCmd) runSubscriber(bm *bench.Benchmark, nc *nats.Conn, startwg *sy
 
 	state = "Finished  "
 
-	bm.AddSubSample(bench.NewSample(numMsg, c.msgSize, start, end, nc))
+	c.addSample(bm.AddSubSample, window, numMsg, start, end, nc, "subscriber")
 
 	donewg.Done()
 }
@@ -0,0 +1,115 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestParseBenchWarmup(t *testing.T) {
+	d, msgs, err := parseBenchWarmup("10s")
+	if err != nil || d != 10*time.Second || msgs != 0 {
+		t.Fatalf("unexpected duration warmup: %v %v %v", d, msgs, err)
+	}
+
+	d, msgs, err = parseBenchWarmup("5000")
+	if err != nil || d != 0 || msgs != 5000 {
+		t.Fatalf("unexpected message warmup: %v %v %v", d, msgs, err)
+	}
+
+	for _, invalid := range []string{"-1", "-1s", "10x"} {
+		_, _, err = parseBenchWarmup(invalid)
+		if err == nil {
+			t.Fatalf("expected %q to fail", invalid)
+		}
+	}
+}
+
+func TestBenchWindow(t *testing.T) {
+	start := time.Now()
+
+	c := &benchCmd{numMsg: 100, window: benchWindowReport{WarmupMsgs: 20}}
+	w := c.newBenchWindow(50)
+	if w.warmupMsgs != 10 {
+		t.Fatalf("expected a warmup share of 10 messages got %d", w.warmupMsgs)
+	}
+
+	w.begin(start)
+	for i := 1; i <= 50; i++ {
+		if !w.observe(start.Add(time.Duration(i) * time.Millisecond)) {
+			t.Fatalf("message %d stopped the client", i)
+		}
+	}
+
+	s := w.sample(10, start.Add(time.Second))
+	if s.JobMsgCnt != 40 || s.MsgBytes != 400 || !s.Start.Equal(start.Add(10*time.Millisecond)) || !s.End.Equal(start.Add(time.Second)) {
+		t.Fatalf("unexpected sample: %+v", s)
+	}
+
+	c = &benchCmd{numMsg: 100, window: benchWindowReport{WarmupDuration: 10 * time.Millisecond, Measure: 20 * time.Millisecond}}
+	w = c.newBenchWindow(100)
+	w.begin(start)
+
+	stopped := 0
+	for i := 1; i <= 100; i++ {
+		if !w.observe(start.Add(time.Duration(i) * time.Millisecond)) {
+			stopped = i
+			break
+		}
+	}
+	if stopped != 30 {
+		t.Fatalf("expected the measurement window to close at message 30 got %d", stopped)
+	}
+
+	s = w.sample(10, start.Add(time.Second))
+	if s.JobMsgCnt != 20 || !s.Start.Equal(start.Add(10*time.Millisecond)) || !s.End.Equal(start.Add(30*time.Millisecond)) {
+		t.Fatalf("unexpected sample: %+v", s)
+	}
+
+	w = c.newBenchWindow(100)
+	w.begin(start)
+	w.observe(start.Add(time.Millisecond))
+	if w.sample(10, start.Add(2*time.Millisecond)) != nil {
+		t.Fatalf("expected no sample for a client finishing during warmup")
+	}
+
+	if (&benchCmd{numMsg: 100}).newBenchWindow(100) != nil {
+		t.Fatalf("expected no window without warmup or measure")
+	}
+}
+
+func TestBenchLatencyComparison(t *testing.T) {
+	c := &benchLatencyCmd{subject: "bench", msgs: 4, noProgress: true}
+
+	calls := 0
+	res := c.measure("Core NATS", func() error {
+		calls++
+		if calls == 2 {
+			return fmt.Errorf("timeout")
+		}
+		return nil
+	})
+	if calls != 4 || len(res.latencies) != 3 || res.errors != 1 {
+		t.Fatalf("unexpected result calls %d latencies %d errors %d", calls, len(res.latencies), res.errors)
+	}
+
+	if o := benchLatencyOverhead(100*time.Microsecond, 250*time.Microsecond); o != "150µs (2.5x)" {
+		t.Fatalf("unexpected overhead %q", o)
+	}
+	if o := benchLatencyOverhead(0, 250*time.Microsecond); o != "" {
+		t.Fatalf("expected no overhead without core latencies got %q", o)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/nats.go"
+)
+
+type benchKVCmd struct {
+	bucket          string
+	puts            int
+	gets            int
+	keys            int
+	valueSizeString string
+	iterations      int
+	storage         string
+	replicas        int
+	noProgress      bool
+}
+
+// benchKVResult holds the outcome of all workers doing one kind of operation
+type benchKVResult struct {
+	kind      string
+	workers   int
+	latencies []time.Duration
+	errors    int
+	elapsed   time.Duration
+}
+
+func configureBenchKVCommand(bench *fisk.CmdClause) {
+	c := &benchKVCmd{}
+
+	kv := bench.Command("kv", "Benchmark KV put and get throughput using a temporary bucket").Action(c.kvAction)
+	kv.Arg("bucket", "The bucket to create for the benchmark").Required().StringVar(&c.bucket)
+	kv.Flag("put", "Number of concurrent put workers").Default("1").IntVar(&c.puts)
+	kv.Flag("get", "Number of concurrent get workers").Default("1").IntVar(&c.gets)
+	kv.Flag("keys", "Number of distinct keys to operate on").Default("10000").IntVar(&c.keys)
+	kv.Flag("value-size", "Size of the values to put").Default("128").StringVar(&c.valueSizeString)
+	kv.Flag("iterations", "Number of operations to perform for each of put and get").Default("100000").IntVar(&c.iterations)
+	kv.Flag("storage", "Storage backend for the bucket (memory, file)").Default("file").EnumVar(&c.storage, "memory", "file")
+	kv.Flag("replicas", "Number of replicas for the bucket").Default("1").IntVar(&c.replicas)
+	kv.Flag("no-progress", "Disable progress bars while running").UnNegatableBoolVar(&c.noProgress)
+}
+
+func (c *benchKVCmd) kvAction(_ *fisk.ParseContext) error {
+	if c.puts < 0 || c.gets < 0 || c.puts+c.gets == 0 {
+		return fmt.Errorf("at least one put or get worker is required")
+	}
+	if c.keys <= 0 {
+		return fmt.Errorf("number of keys should be greater than 0")
+	}
+	if c.iterations <= 0 {
+		return fmt.Errorf("number of iterations should be greater than 0")
+	}
+
+	valueSize, err := parseStringAsBytes(c.valueSizeString)
+	if err != nil || valueSize <= 0 {
+		return fmt.Errorf("invalid value size %q", c.valueSizeString)
+	}
+
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	_, err = js.KeyValue(c.bucket)
+	if err == nil {
+		return fmt.Errorf("bucket %s already exists, the benchmark requires a bucket it can create and remove", c.bucket)
+	}
+	if !errors.Is(err, nats.ErrBucketNotFound) {
+		return err
+	}
+
+	storage := nats.FileStorage
+	if c.storage == "memory" {
+		storage = nats.MemoryStorage
+	}
+
+	store, err := js.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:   c.bucket,
+		Storage:  storage,
+		Replicas: c.replicas,
+		History:  1,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create bucket %s: %w", c.bucket, err)
+	}
+	defer func() {
+		err := js.DeleteKeyValue(c.bucket)
+		if err != nil {
+			logErrorf("Could not remove bucket %s: %v", c.bucket, err)
+		}
+	}()
+
+	log.Printf("Starting KV benchmark [bucket=%s, puts=%d, gets=%d, keys=%s, valuesize=%s, iterations=%s, storage=%s, replicas=%d]", c.bucket, c.puts, c.gets, f(c.keys), fiBytes(uint64(valueSize)), f(c.iterations), c.storage, c.replicas)
+
+	value := make([]byte, valueSize)
+	rand.Read(value)
+
+	// gets should find data, so seed every key before timing anything
+	log.Printf("Seeding %s keys", f(c.keys))
+	for i := 0; i < c.keys; i++ {
+		_, err = store.Put(c.keyName(i), value)
+		if err != nil {
+			return fmt.Errorf("could not seed bucket: %w", err)
+		}
+	}
+
+	var progress *uiprogress.Progress
+	if !c.noProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		progress.Start()
+	}
+
+	trigger := make(chan struct{})
+	wg := &sync.WaitGroup{}
+
+	puts := &benchKVResult{kind: "Put", workers: c.puts}
+	gets := &benchKVResult{kind: "Get", workers: c.gets}
+
+	c.startWorkers(puts, progress, wg, trigger, func(key string) error {
+		_, err := store.Put(key, value)
+		return err
+	})
+	c.startWorkers(gets, progress, wg, trigger, func(key string) error {
+		_, err := store.Get(key)
+		return err
+	})
+
+	close(trigger)
+	wg.Wait()
+
+	if progress != nil {
+		progress.Stop()
+	}
+
+	table := newTableWriter(fmt.Sprintf("KV benchmark using bucket %s", c.bucket))
+	table.AddHeaders("Operation", "Workers", "Operations", "Errors", "Ops/sec", "Average", "p50", "p99")
+	for _, res := range []*benchKVResult{puts, gets} {
+		if res.workers == 0 {
+			continue
+		}
+
+		var rate float64
+		if res.elapsed > 0 {
+			rate = float64(len(res.latencies)) / res.elapsed.Seconds()
+		}
+		table.AddRow(res.kind, res.workers, f(len(res.latencies)), f(res.errors), f(int64(rate)), f(benchKVAverage(res.latencies)), f(benchKVPercentile(res.latencies, 50)), f(benchKVPercentile(res.latencies, 99)))
+	}
+
+	fmt.Println()
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *benchKVCmd) keyName(i int) string {
+	return fmt.Sprintf("key%d", i)
+}
+
+// startWorkers starts res.workers goroutines that share c.iterations calls to op
+// once trigger is closed, latencies of successful operations are gathered into res
+func (c *benchKVCmd) startWorkers(res *benchKVResult, progress *uiprogress.Progress, wg *sync.WaitGroup, trigger chan struct{}, op func(key string) error) {
+	if res.workers == 0 {
+		return
+	}
+
+	var bar *uiprogress.Bar
+	if progress != nil {
+		bar = progress.AddBar(c.iterations).AppendCompleted().PrependElapsed()
+		bar.Width = progressWidth()
+		state := fmt.Sprintf("%-5s", res.kind+"s")
+		bar.PrependFunc(func(b *uiprogress.Bar) string { return state })
+	}
+
+	mu := sync.Mutex{}
+	kindwg := &sync.WaitGroup{}
+
+	for i := 0; i < res.workers; i++ {
+		count := c.iterations / res.workers
+		if i < c.iterations%res.workers {
+			count++
+		}
+
+		wg.Add(1)
+		kindwg.Add(1)
+		go func(count int) {
+			defer wg.Done()
+			defer kindwg.Done()
+
+			latencies := make([]time.Duration, 0, count)
+			errs := 0
+
+			<-trigger
+
+			for j := 0; j < count; j++ {
+				key := c.keyName(rand.Intn(c.keys))
+				opStart := time.Now()
+				err := op(key)
+				if err != nil {
+					errs++
+				} else {
+					latencies = append(latencies, time.Since(opStart))
+				}
+
+				if bar != nil {
+					bar.Incr()
+				}
+			}
+
+			mu.Lock()
+			res.latencies = append(res.latencies, latencies...)
+			res.errors += errs
+			mu.Unlock()
+		}(count)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-trigger
+		start := time.Now()
+		kindwg.Wait()
+		res.elapsed = time.Since(start)
+	}()
+}
+
+func benchKVAverage(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+
+	var total time.Duration
+	for _, l := range latencies {
+		total += l
+	}
+
+	return total / time.Duration(len(latencies))
+}
+
+// benchKVPercentile calculates the nearest rank percentile, sorting latencies in place
+func benchKVPercentile(latencies []time.Duration, pct float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	rank := int(float64(len(latencies))*pct/100+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(latencies) {
+		rank = len(latencies) - 1
+	}
+
+	return latencies[rank]
+}
@@ -0,0 +1,217 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+)
+
+type benchLatencyCmd struct {
+	subject    string
+	stream     string
+	msgs       int
+	sizeString string
+	storage    string
+	replicas   int
+	noProgress bool
+}
+
+// benchLatencyResult holds the latencies of one of the compared publish modes
+type benchLatencyResult struct {
+	kind      string
+	latencies []time.Duration
+	errors    int
+}
+
+func configureBenchLatencyCommand(bench *fisk.CmdClause) {
+	c := &benchLatencyCmd{}
+
+	latency := bench.Command("compare-latency", "Compare Core NATS round trip latency with JetStream publish latency").Action(c.compareAction)
+	latency.Arg("subject", "Subject to use for the benchmark").Required().StringVar(&c.subject)
+	latency.Flag("js-stream", "The Stream to create for the JetStream benchmark").Default(DefaultStreamName).StringVar(&c.stream)
+	latency.Flag("msgs", "Number of messages to publish in each benchmark").Default("10000").IntVar(&c.msgs)
+	latency.Flag("size", "Size of the test messages").Default("128").StringVar(&c.sizeString)
+	latency.Flag("storage", "Storage backend for the Stream (memory, file)").Default("file").EnumVar(&c.storage, "memory", "file")
+	latency.Flag("replicas", "Number of replicas for the Stream").Default("1").IntVar(&c.replicas)
+	latency.Flag("no-progress", "Disable progress bars while running").UnNegatableBoolVar(&c.noProgress)
+}
+
+func (c *benchLatencyCmd) compareAction(_ *fisk.ParseContext) error {
+	if c.msgs <= 0 {
+		return fmt.Errorf("number of messages should be greater than 0")
+	}
+
+	size, err := parseStringAsBytes(c.sizeString)
+	if err != nil || size <= 0 {
+		return fmt.Errorf("invalid message size %q", c.sizeString)
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	known, err := mgr.IsKnownStream(c.stream)
+	if err != nil {
+		return err
+	}
+	if known {
+		return fmt.Errorf("stream %s already exists, the benchmark requires a stream it can create and remove", c.stream)
+	}
+
+	// messages stored by another stream would make the Core NATS results meaningless
+	names, err := mgr.StreamNames(&jsm.StreamNamesFilter{Subject: c.subject})
+	if err != nil {
+		return err
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("subject %s is stored by stream %s, the benchmark requires a subject no stream listens on", c.subject, strings.Join(names, ", "))
+	}
+
+	log.Printf("Starting latency comparison [subject=%s, stream=%s, msgs=%s, size=%s, storage=%s, replicas=%d]", c.subject, c.stream, f(c.msgs), fiBytes(uint64(size)), c.storage, c.replicas)
+
+	body := make([]byte, size)
+	rand.Read(body)
+
+	timeout := opts().Timeout
+
+	sub, err := nc.SubscribeSync(c.subject)
+	if err != nil {
+		return err
+	}
+	err = nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	core := c.measure("Core NATS", func() error {
+		err := nc.Publish(c.subject, body)
+		if err != nil {
+			return err
+		}
+
+		_, err = sub.NextMsg(timeout)
+		return err
+	})
+
+	err = sub.Unsubscribe()
+	if err != nil {
+		return err
+	}
+
+	storage := jsm.FileStorage()
+	if c.storage == "memory" {
+		storage = jsm.MemoryStorage()
+	}
+
+	str, err := mgr.NewStream(c.stream, jsm.Subjects(c.subject), storage, jsm.Replicas(c.replicas))
+	if err != nil {
+		return fmt.Errorf("could not create stream %s: %w", c.stream, err)
+	}
+	defer func() {
+		err := str.Delete()
+		if err != nil {
+			logErrorf("Could not remove stream %s: %v", c.stream, err)
+		}
+	}()
+
+	js := c.measure("JetStream", func() error {
+		msg, err := nc.Request(c.subject, body, timeout)
+		if err != nil {
+			return err
+		}
+
+		_, err = jsm.ParsePubAck(msg)
+		return err
+	})
+
+	fmt.Println()
+	fmt.Println(c.renderComparison(core, js))
+
+	return nil
+}
+
+// measure calls op c.msgs times one after the other, timing every successful call
+func (c *benchLatencyCmd) measure(kind string, op func() error) *benchLatencyResult {
+	res := &benchLatencyResult{kind: kind, latencies: make([]time.Duration, 0, c.msgs)}
+
+	var progress *uiprogress.Progress
+	var bar *uiprogress.Bar
+	if !c.noProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(c.msgs).AppendCompleted().PrependElapsed()
+		bar.Width = progressWidth()
+		state := fmt.Sprintf("%-9s", kind)
+		bar.PrependFunc(func(b *uiprogress.Bar) string { return state })
+		progress.Start()
+	}
+
+	for i := 0; i < c.msgs; i++ {
+		start := time.Now()
+		err := op()
+		if err != nil {
+			res.errors++
+		} else {
+			res.latencies = append(res.latencies, time.Since(start))
+		}
+
+		if bar != nil {
+			bar.Incr()
+		}
+	}
+
+	if progress != nil {
+		progress.Stop()
+	}
+
+	return res
+}
+
+func (c *benchLatencyCmd) renderComparison(core *benchLatencyResult, js *benchLatencyResult) string {
+	table := newTableWriter(fmt.Sprintf("Publish latency comparison using %s", c.subject))
+	table.AddHeaders("", core.kind, js.kind, "JetStream Overhead")
+	table.AddRow("Messages", f(len(core.latencies)), f(len(js.latencies)), "")
+	table.AddRow("Errors", f(core.errors), f(js.errors), "")
+
+	row := func(name string, stat func([]time.Duration) time.Duration) {
+		cl := stat(core.latencies)
+		jl := stat(js.latencies)
+		table.AddRow(name, f(cl), f(jl), benchLatencyOverhead(cl, jl))
+	}
+
+	row("Average", benchKVAverage)
+	row("p50", func(l []time.Duration) time.Duration { return benchKVPercentile(l, 50) })
+	row("p95", func(l []time.Duration) time.Duration { return benchKVPercentile(l, 95) })
+	row("p99", func(l []time.Duration) time.Duration { return benchKVPercentile(l, 99) })
+
+	return table.Render()
+}
+
+// benchLatencyOverhead describes how much slower js is than core
+func benchLatencyOverhead(core time.Duration, js time.Duration) string {
+	if core <= 0 || js <= 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (%.1fx)", f(js-core), float64(js)/float64(core))
+}
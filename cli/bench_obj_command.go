@@ -0,0 +1,212 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/nats.go"
+)
+
+type benchObjCmd struct {
+	bucket     string
+	sizeString string
+	count      int
+	putWorkers int
+	getWorkers int
+	storage    string
+	replicas   int
+	noProgress bool
+}
+
+func configureBenchObjCommand(bench *fisk.CmdClause) {
+	c := &benchObjCmd{}
+
+	obj := bench.Command("obj", "Benchmark Object Store put and get throughput using a temporary bucket").Action(c.objAction)
+	obj.Arg("bucket", "The bucket to create for the benchmark").Required().StringVar(&c.bucket)
+	obj.Flag("size", "Size of the objects to put").Default("1MB").StringVar(&c.sizeString)
+	obj.Flag("count", "Number of objects to put and get").Default("100").IntVar(&c.count)
+	obj.Flag("put-workers", "Number of concurrent put workers").Default("1").IntVar(&c.putWorkers)
+	obj.Flag("get-workers", "Number of concurrent get workers").Default("1").IntVar(&c.getWorkers)
+	obj.Flag("storage", "Storage backend for the bucket (memory, file)").Default("file").EnumVar(&c.storage, "memory", "file")
+	obj.Flag("replicas", "Number of replicas for the bucket").Default("1").IntVar(&c.replicas)
+	obj.Flag("no-progress", "Disable progress bars while running").UnNegatableBoolVar(&c.noProgress)
+}
+
+func (c *benchObjCmd) objAction(_ *fisk.ParseContext) error {
+	if c.putWorkers <= 0 || c.getWorkers <= 0 {
+		return fmt.Errorf("at least one put and one get worker is required")
+	}
+	if c.count <= 0 {
+		return fmt.Errorf("number of objects should be greater than 0")
+	}
+
+	size, err := parseStringAsBytes(c.sizeString)
+	if err != nil || size <= 0 {
+		return fmt.Errorf("invalid object size %q", c.sizeString)
+	}
+
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	_, err = js.ObjectStore(c.bucket)
+	if err == nil {
+		return fmt.Errorf("bucket %s already exists, the benchmark requires a bucket it can create and remove", c.bucket)
+	}
+	if !errors.Is(err, nats.ErrStreamNotFound) {
+		return err
+	}
+
+	storage := nats.FileStorage
+	if c.storage == "memory" {
+		storage = nats.MemoryStorage
+	}
+
+	store, err := js.CreateObjectStore(&nats.ObjectStoreConfig{
+		Bucket:   c.bucket,
+		Storage:  storage,
+		Replicas: c.replicas,
+	})
+	if err != nil {
+		return fmt.Errorf("could not create bucket %s: %w", c.bucket, err)
+	}
+	defer func() {
+		err := js.DeleteObjectStore(c.bucket)
+		if err != nil {
+			logErrorf("Could not remove bucket %s: %v", c.bucket, err)
+		}
+	}()
+
+	log.Printf("Starting Object Store benchmark [bucket=%s, size=%s, count=%s, put-workers=%d, get-workers=%d, storage=%s, replicas=%d]", c.bucket, fiBytes(uint64(size)), f(c.count), c.putWorkers, c.getWorkers, c.storage, c.replicas)
+
+	data := make([]byte, size)
+	rand.Read(data)
+
+	var progress *uiprogress.Progress
+	if !c.noProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		progress.Start()
+	}
+
+	// gets should find every object, so all puts complete before gets start
+	puts := &benchKVResult{kind: "Put", workers: c.putWorkers}
+	c.runPhase(puts, progress, func(name string) error {
+		_, err := store.PutBytes(name, data)
+		return err
+	})
+
+	gets := &benchKVResult{kind: "Get", workers: c.getWorkers}
+	c.runPhase(gets, progress, func(name string) error {
+		body, err := store.GetBytes(name)
+		if err != nil {
+			return err
+		}
+		if int64(len(body)) != size {
+			return fmt.Errorf("object %s has %d bytes, expected %d", name, len(body), size)
+		}
+		return nil
+	})
+
+	if progress != nil {
+		progress.Stop()
+	}
+
+	table := newTableWriter(fmt.Sprintf("Object Store benchmark using bucket %s", c.bucket))
+	table.AddHeaders("Operation", "Workers", "Objects", "Errors", "Throughput", "Objects/sec", "Average", "p99")
+	for _, res := range []*benchKVResult{puts, gets} {
+		var rate float64
+		if res.elapsed > 0 {
+			rate = float64(len(res.latencies)) / res.elapsed.Seconds()
+		}
+		throughput := fmt.Sprintf("%s/sec", fiBytes(uint64(rate*float64(size))))
+		table.AddRow(res.kind, res.workers, f(len(res.latencies)), f(res.errors), throughput, f(rate), f(benchKVAverage(res.latencies)), f(benchKVPercentile(res.latencies, 99)))
+	}
+
+	fmt.Println()
+	fmt.Println(table.Render())
+
+	if puts.errors > 0 {
+		logWarnf("%s puts failed, gets of those objects are counted as errors", f(puts.errors))
+	}
+
+	return nil
+}
+
+func (c *benchObjCmd) objectName(i int) string {
+	return fmt.Sprintf("object%d", i)
+}
+
+// runPhase performs op on all c.count objects using res.workers goroutines and waits for them to finish, latencies
+// of successful operations are gathered into res
+func (c *benchObjCmd) runPhase(res *benchKVResult, progress *uiprogress.Progress, op func(name string) error) {
+	var bar *uiprogress.Bar
+	if progress != nil {
+		bar = progress.AddBar(c.count).AppendCompleted().PrependElapsed()
+		bar.Width = progressWidth()
+		state := fmt.Sprintf("%-5s", res.kind+"s")
+		bar.PrependFunc(func(b *uiprogress.Bar) string { return state })
+	}
+
+	objects := make(chan int, c.count)
+	for i := 0; i < c.count; i++ {
+		objects <- i
+	}
+	close(objects)
+
+	mu := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	start := time.Now()
+
+	for i := 0; i < res.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var latencies []time.Duration
+			errs := 0
+
+			for obj := range objects {
+				opStart := time.Now()
+				err := op(c.objectName(obj))
+				if err != nil {
+					errs++
+				} else {
+					latencies = append(latencies, time.Since(opStart))
+				}
+
+				if bar != nil {
+					bar.Incr()
+				}
+			}
+
+			mu.Lock()
+			res.latencies = append(res.latencies, latencies...)
+			res.errors += errs
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	res.elapsed = time.Since(start)
+}
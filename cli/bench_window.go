@@ -0,0 +1,321 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/bench"
+)
+
+// benchWindow tracks the warmup phase and measurement window of a single benchmark client, messages handled during
+// warmup or after the measurement window closed are excluded from the sample. A nil window measures everything
+type benchWindow struct {
+	warmup     time.Duration
+	warmupMsgs int
+	measure    time.Duration
+
+	mu           sync.Mutex
+	started      time.Time
+	count        int
+	warmedUp     bool
+	measureStart time.Time
+	measureCount int
+	measureEnd   time.Time
+}
+
+// benchWindowReport describes the warmup and measurement settings of a benchmark
+type benchWindowReport struct {
+	WarmupDuration time.Duration `json:"warmup_duration,omitempty"`
+	WarmupMsgs     int           `json:"warmup_msgs,omitempty"`
+	Measure        time.Duration `json:"measure,omitempty"`
+}
+
+type benchJSONSample struct {
+	Client      string    `json:"client,omitempty"`
+	Messages    int       `json:"messages"`
+	Bytes       uint64    `json:"bytes"`
+	MsgsPerSec  int64     `json:"msgs_per_sec"`
+	BytesPerSec float64   `json:"bytes_per_sec"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Seconds     float64   `json:"duration_secs"`
+}
+
+type benchJSONGroup struct {
+	benchJSONSample
+	MinRate int64              `json:"min_rate"`
+	AvgRate int64              `json:"avg_rate"`
+	MaxRate int64              `json:"max_rate"`
+	StdDev  float64            `json:"stddev"`
+	Clients []*benchJSONSample `json:"clients"`
+}
+
+type benchJSONReport struct {
+	RunID string `json:"run_id"`
+	benchWindowReport
+	benchJSONSample
+	Publishers  *benchJSONGroup `json:"publishers,omitempty"`
+	Subscribers *benchJSONGroup `json:"subscribers,omitempty"`
+}
+
+// parseBenchWarmup parses a warmup given as a duration like 10s or a number of messages like 5000
+func parseBenchWarmup(warmup string) (time.Duration, int, error) {
+	if warmup == "" {
+		return 0, 0, nil
+	}
+
+	msgs, err := strconv.Atoi(warmup)
+	if err == nil {
+		if msgs < 0 {
+			return 0, 0, fmt.Errorf("warmup can not be negative")
+		}
+		return 0, msgs, nil
+	}
+
+	d, err := time.ParseDuration(warmup)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid warmup %q, expected a duration like 10s or a number of messages", warmup)
+	}
+	if d < 0 {
+		return 0, 0, fmt.Errorf("warmup can not be negative")
+	}
+
+	return d, 0, nil
+}
+
+// windowed is true when a warmup or measurement window is set
+func (r benchWindowReport) windowed() bool {
+	return r.WarmupDuration > 0 || r.WarmupMsgs > 0 || r.Measure > 0
+}
+
+func (r benchWindowReport) warmupString() string {
+	switch {
+	case r.WarmupMsgs > 0:
+		return fmt.Sprintf("%s msgs", f(r.WarmupMsgs))
+	case r.WarmupDuration > 0:
+		return r.WarmupDuration.String()
+	default:
+		return "none"
+	}
+}
+
+func (r benchWindowReport) measureString() string {
+	if r.Measure > 0 {
+		return r.Measure.String()
+	}
+
+	return "all messages"
+}
+
+// newBenchWindow creates the window for a client handling numMsg of all messages, message count warmups are shared
+// between clients in proportion to the messages they handle
+func (c *benchCmd) newBenchWindow(numMsg int) *benchWindow {
+	if !c.window.windowed() {
+		return nil
+	}
+
+	w := &benchWindow{warmup: c.window.WarmupDuration, measure: c.window.Measure}
+	if c.window.WarmupMsgs > 0 {
+		w.warmupMsgs = int(int64(c.window.WarmupMsgs) * int64(numMsg) / int64(c.numMsg))
+	}
+
+	return w
+}
+
+// begin marks the time the client started, messages are only measured once warmup passed
+func (w *benchWindow) begin(now time.Time) {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.started = now
+	if w.warmup <= 0 && w.warmupMsgs <= 0 {
+		w.warmedUp = true
+		w.measureStart = now
+	}
+}
+
+// observe records a handled message, it is false once the measurement window closed and the client should stop
+func (w *benchWindow) observe(now time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.measureEnd.IsZero() {
+		return false
+	}
+
+	w.count++
+
+	if !w.warmedUp && ((w.warmupMsgs > 0 && w.count >= w.warmupMsgs) || (w.warmup > 0 && now.Sub(w.started) >= w.warmup)) {
+		w.warmedUp = true
+		w.measureStart = now
+		w.measureCount = w.count
+		return true
+	}
+
+	if w.warmedUp && w.measure > 0 && now.Sub(w.measureStart) >= w.measure {
+		w.measureEnd = now
+		return false
+	}
+
+	return true
+}
+
+// sample is the sample for the measured window, nil when the client finished during warmup
+func (w *benchWindow) sample(msgSize int, end time.Time) *bench.Sample {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n := w.count - w.measureCount
+	if !w.warmedUp || n <= 0 {
+		return nil
+	}
+
+	if !w.measureEnd.IsZero() {
+		end = w.measureEnd
+	}
+
+	return &bench.Sample{
+		JobMsgCnt: n,
+		MsgCnt:    uint64(n),
+		MsgBytes:  uint64(n * msgSize),
+		IOBytes:   uint64(n * msgSize),
+		Start:     w.measureStart,
+		End:       end,
+	}
+}
+
+// addSample adds the sample of a client, when warming up or measuring for a duration it only covers the measured window
+func (c *benchCmd) addSample(add func(*bench.Sample), window *benchWindow, numMsg int, start time.Time, end time.Time, nc *nats.Conn, client string) {
+	if window == nil {
+		add(bench.NewSample(numMsg, c.msgSize, start, end, nc))
+		return
+	}
+
+	s := window.sample(c.msgSize, end)
+	if s == nil {
+		log.Printf("A %s finished during the warmup and is excluded from the statistics", client)
+		return
+	}
+
+	add(s)
+}
+
+// benchCSV is the CSV produced by the bench package with the warmup and measurement window of each client added
+func benchCSV(bm *bench.Benchmark, window benchWindowReport) (string, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	err := writer.Write([]string{"#RunID", "ClientID", "MsgCount", "MsgBytes", "MsgsPerSec", "BytesPerSec", "DurationSecs", "Warmup", "Measure", "MeasureStart", "MeasureEnd"})
+	if err != nil {
+		return "", err
+	}
+
+	for _, g := range []struct {
+		prefix string
+		group  *bench.SampleGroup
+	}{{"S", bm.Subs}, {"P", bm.Pubs}} {
+		for j, s := range g.group.Samples {
+			err = writer.Write([]string{
+				bm.RunID,
+				fmt.Sprintf("%s%d", g.prefix, j),
+				fmt.Sprintf("%d", s.MsgCnt),
+				fmt.Sprintf("%d", s.MsgBytes),
+				fmt.Sprintf("%d", s.Rate()),
+				fmt.Sprintf("%f", s.Throughput()),
+				fmt.Sprintf("%f", s.Duration().Seconds()),
+				window.warmupString(),
+				window.measureString(),
+				s.Start.UTC().Format(time.RFC3339Nano),
+				s.End.UTC().Format(time.RFC3339Nano),
+			})
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return buffer.String(), writer.Error()
+}
+
+func newBenchJSONSample(client string, s *bench.Sample) benchJSONSample {
+	return benchJSONSample{
+		Client:      client,
+		Messages:    s.JobMsgCnt,
+		Bytes:       s.MsgBytes,
+		MsgsPerSec:  s.Rate(),
+		BytesPerSec: s.Throughput(),
+		Start:       s.Start,
+		End:         s.End,
+		Seconds:     s.Duration().Seconds(),
+	}
+}
+
+func newBenchJSONGroup(prefix string, g *bench.SampleGroup) *benchJSONGroup {
+	if !g.HasSamples() {
+		return nil
+	}
+
+	res := &benchJSONGroup{
+		benchJSONSample: newBenchJSONSample("", &g.Sample),
+		MinRate:         g.MinRate(),
+		AvgRate:         g.AvgRate(),
+		MaxRate:         g.MaxRate(),
+		StdDev:          g.StdDev(),
+	}
+
+	for i, s := range g.Samples {
+		cs := newBenchJSONSample(fmt.Sprintf("%s%d", prefix, i), s)
+		res.Clients = append(res.Clients, &cs)
+	}
+
+	return res
+}
+
+func newBenchJSONReport(bm *bench.Benchmark, window benchWindowReport) *benchJSONReport {
+	return &benchJSONReport{
+		RunID:             bm.RunID,
+		benchWindowReport: window,
+		benchJSONSample:   newBenchJSONSample("", &bm.Sample),
+		Publishers:        newBenchJSONGroup("P", bm.Pubs),
+		Subscribers:       newBenchJSONGroup("S", bm.Subs),
+	}
+}
+
+// renderBenchWindow states the warmup and measurement windows the statistics cover
+func renderBenchWindow(bm *bench.Benchmark, window benchWindowReport) {
+	fmt.Printf("Warmup: %s per client, excluded from the statistics\n", window.warmupString())
+	fmt.Printf("Measurement window: %s per client", window.measureString())
+	if bm.Pubs.HasSamples() || bm.Subs.HasSamples() {
+		fmt.Printf(", measured from %s to %s (%s)", bm.Start.Format(time.StampMilli), bm.End.Format(time.StampMilli), f(bm.Duration().Round(time.Millisecond)))
+	}
+	fmt.Println()
+	fmt.Println()
+}
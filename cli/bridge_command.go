@@ -0,0 +1,509 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/internal/subjectmap"
+)
+
+const (
+	bridgeSourceSubjectHeader  = "X-Bridge-Source-Subject"
+	bridgeSourceServerHeader   = "X-Bridge-Source-Server"
+	bridgeSourceStreamHeader   = "X-Bridge-Source-Stream"
+	bridgeSourceSequenceHeader = "X-Bridge-Source-Sequence"
+
+	// bridgeFetchExpires is how long a pull request waits for messages before the server ends it
+	bridgeFetchExpires = time.Second
+)
+
+type bridgeCmd struct {
+	fromContext    string
+	toContext      string
+	subject        string
+	stream         string
+	consumer       string
+	subjectMaps    []string
+	subjectMapTest string
+	batch          int
+	reportInterval time.Duration
+}
+
+// bridge republishes messages received on the source connection to the destination connection, in JetStream mode
+// messages are pulled from consumer and only acknowledged once the destination received them
+type bridge struct {
+	src      *nats.Conn
+	dst      *nats.Conn
+	subject  string
+	maps     *subjectmap.Mapper
+	consumer *jsm.Consumer
+	batch    int
+	interval time.Duration
+	timeout  time.Duration
+
+	received  atomic.Uint64
+	published atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// bridgeAck is a message pulled from the Consumer along with the acknowledgement to send for it
+type bridgeAck struct {
+	msg   *nats.Msg
+	reply []byte
+}
+
+func configureBridgeCommand(app commandHost) {
+	c := &bridgeCmd{}
+
+	help := `Republishes messages between two NATS deployments
+
+Messages received from the source are published to the destination with their
+headers preserved and X-Bridge-Source-Subject and X-Bridge-Source-Server
+headers added, replies are not bridged.
+
+By default a core NATS subscription is used. Messages published while the
+destination is unavailable are buffered by the client and dropped once the
+buffer is full.
+
+With --stream and --consumer messages are pulled from a durable Consumer, that
+is created when missing, and only acknowledged once the destination received
+them. Fetching pauses while the destination is unavailable and messages that
+could not be published are redelivered. These messages also get
+X-Bridge-Source-Stream and X-Bridge-Source-Sequence headers and a Nats-Msg-Id
+when they had none so a destination Stream can discard duplicates.
+
+Subjects are rewritten using --subject-map, the first matching map is used.
+A * captures a single token and a trailing > the remaining tokens, patterns
+starting with ~ are regular expressions matching the entire subject:
+
+   --subject-map 'orders.*.created=archive.orders.$1.created'
+   --subject-map 'orders.>=neworders.$1'
+   --subject-map '~orders\.(eu|us)\.(.+)=regional.$1.$2'
+
+Use --subject-map-test to show how a subject would be mapped without bridging.
+`
+
+	bridge := app.Command("bridge", help).Action(c.bridgeAction)
+	bridge.Flag("from-context", "Context to connect to the source deployment, defaults to the selected context").PlaceHolder("NAME").StringVar(&c.fromContext)
+	bridge.Flag("to-context", "Context to connect to the destination deployment").Required().PlaceHolder("NAME").StringVar(&c.toContext)
+	bridge.Flag("subject", "Subject to bridge, filters the Consumer when bridging from a Stream").PlaceHolder("SUBJECT").StringVar(&c.subject)
+	bridge.Flag("stream", "Stream to bridge messages from using a durable Consumer").PlaceHolder("STREAM").StringVar(&c.stream)
+	bridge.Flag("consumer", "Durable pull Consumer to bridge messages from, created when missing").PlaceHolder("CONSUMER").StringVar(&c.consumer)
+	bridge.Flag("subject-map", "Rewrites subjects matching a pattern, can be repeated").PlaceHolder("PATTERN=REPLACEMENT").StringsVar(&c.subjectMaps)
+	bridge.Flag("subject-map-test", "Shows what a subject maps to without bridging any messages").PlaceHolder("SUBJECT").StringVar(&c.subjectMapTest)
+	bridge.Flag("batch", "How many messages to pull from the Consumer at a time").Default("100").IntVar(&c.batch)
+	bridge.Flag("report-interval", "How often to report message rates").Default("10s").DurationVar(&c.reportInterval)
+
+	addCheat("bridge", bridge)
+}
+
+func init() {
+	registerCommand("bridge", 2, configureBridgeCommand)
+}
+
+func (c *bridgeCmd) bridgeAction(_ *fisk.ParseContext) error {
+	maps, err := subjectmap.New(c.subjectMaps)
+	if err != nil {
+		return err
+	}
+
+	if c.subjectMapTest != "" {
+		showSubjectMapTest(maps, c.subjectMapTest)
+		return nil
+	}
+
+	switch {
+	case c.subject == "" && c.stream == "":
+		return fmt.Errorf("a subject is required unless bridging from a Stream")
+	case (c.stream == "") != (c.consumer == ""):
+		return fmt.Errorf("stream and consumer have to be used together")
+	case c.batch < 1:
+		return fmt.Errorf("batch must be at least 1")
+	case c.reportInterval <= 0:
+		return fmt.Errorf("report-interval must be positive")
+	}
+
+	var src *nats.Conn
+	var mgr *jsm.Manager
+	if c.fromContext != "" {
+		src, mgr, err = prepareHelperForContext(c.fromContext)
+		if err != nil {
+			return fmt.Errorf("could not connect to the source: %w", err)
+		}
+		defer src.Close()
+	} else {
+		src, mgr, err = prepareHelper("", natsOpts()...)
+		if err != nil {
+			return fmt.Errorf("could not connect to the source: %w", err)
+		}
+	}
+
+	dstOpts := []nats.Option{
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
+			if nc.IsClosed() {
+				return
+			}
+			logWarnf("Disconnected from the destination: %v", err)
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			log.Printf("Reconnected to the destination %s", nc.ConnectedUrlRedacted())
+		}),
+	}
+	// without a reconnect buffer publishing fails right away during an outage so the message is redelivered later
+	if c.stream != "" {
+		dstOpts = append(dstOpts, nats.ReconnectBufSize(-1))
+	}
+
+	dst, _, err := prepareHelperForContext(c.toContext, dstOpts...)
+	if err != nil {
+		return fmt.Errorf("could not connect to the destination: %w", err)
+	}
+	defer dst.Close()
+
+	b := &bridge{
+		src:      src,
+		dst:      dst,
+		subject:  c.subject,
+		maps:     maps,
+		batch:    c.batch,
+		interval: c.reportInterval,
+		timeout:  opts().Timeout,
+	}
+
+	if c.stream != "" {
+		b.consumer, err = c.bridgeConsumer(mgr)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if b.consumer != nil {
+		log.Printf("Bridging messages from Consumer %s > %s on %s to %s", c.stream, c.consumer, src.ConnectedUrlRedacted(), dst.ConnectedUrlRedacted())
+	} else {
+		log.Printf("Bridging messages on %q from %s to %s", c.subject, src.ConnectedUrlRedacted(), dst.ConnectedUrlRedacted())
+	}
+
+	err = b.run(ctx)
+
+	log.Printf("Bridged %s messages of %s received, %s could not be bridged", f(b.published.Load()), f(b.received.Load()), f(b.failed.Load()))
+
+	return err
+}
+
+// bridgeConsumer loads or creates the durable Consumer to bridge messages from
+func (c *bridgeCmd) bridgeConsumer(mgr *jsm.Manager) (*jsm.Consumer, error) {
+	copts := []jsm.ConsumerOption{
+		jsm.DurableName(c.consumer),
+		jsm.AcknowledgeExplicit(),
+		jsm.ConsumerDescription("NATS CLI bridge"),
+	}
+	if c.subject != "" {
+		copts = append(copts, jsm.FilterStreamBySubject(c.subject))
+	}
+
+	cons, err := mgr.LoadOrNewConsumer(c.stream, c.consumer, copts...)
+	if err != nil {
+		return nil, fmt.Errorf("could not load Consumer %s > %s: %w", c.stream, c.consumer, err)
+	}
+
+	if cons.IsPushMode() {
+		return nil, fmt.Errorf("consumer %s > %s is a push Consumer, bridging requires a pull Consumer", c.stream, c.consumer)
+	}
+	if cons.AckPolicy() != api.AckExplicit {
+		return nil, fmt.Errorf("consumer %s > %s does not use the explicit acknowledgement policy", c.stream, c.consumer)
+	}
+
+	return cons, nil
+}
+
+// mapSubject rewrites subject using the first matching map, subjects not matching any are kept
+func (b *bridge) mapSubject(subject string) string {
+	mapped, _ := b.maps.Map(subject)
+	return mapped
+}
+
+// bridgeMsg creates the message to publish to the destination, meta is nil for messages not received from a Consumer
+func (b *bridge) bridgeMsg(msg *nats.Msg, meta *jsm.MsgInfo) *nats.Msg {
+	out := nats.NewMsg(b.mapSubject(msg.Subject))
+	out.Data = msg.Data
+
+	for k, vals := range msg.Header {
+		out.Header[k] = append([]string{}, vals...)
+	}
+
+	out.Header.Set(bridgeSourceSubjectHeader, msg.Subject)
+	out.Header.Set(bridgeSourceServerHeader, b.src.ConnectedServerName())
+
+	if meta != nil {
+		seq := strconv.FormatUint(meta.StreamSequence(), 10)
+		out.Header.Set(bridgeSourceStreamHeader, meta.Stream())
+		out.Header.Set(bridgeSourceSequenceHeader, seq)
+
+		if out.Header.Get(api.JSMsgId) == "" {
+			out.Header.Set(api.JSMsgId, meta.Stream()+"-"+seq)
+		}
+	}
+
+	return out
+}
+
+// run bridges messages until ctx is canceled, messages already received are bridged before returning
+func (b *bridge) run(ctx context.Context) error {
+	rctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go b.reportRates(rctx)
+
+	if b.consumer != nil {
+		return b.runConsumer(ctx)
+	}
+
+	return b.runCore(ctx)
+}
+
+func (b *bridge) runCore(ctx context.Context) error {
+	sub, err := b.src.SubscribeSync(b.subject)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	err = b.src.Flush()
+	if err != nil {
+		return err
+	}
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		b.forwardCore(msg)
+	}
+
+	// messages already delivered to the subscription are bridged before stopping
+	if sub.Drain() == nil {
+		for {
+			msg, err := sub.NextMsg(100 * time.Millisecond)
+			if err != nil {
+				break
+			}
+
+			b.forwardCore(msg)
+		}
+	}
+
+	err = b.dst.FlushTimeout(b.timeout)
+	if err != nil {
+		return fmt.Errorf("could not flush messages to the destination: %w", err)
+	}
+
+	return nil
+}
+
+func (b *bridge) forwardCore(msg *nats.Msg) {
+	b.received.Add(1)
+
+	err := b.dst.PublishMsg(b.bridgeMsg(msg, nil))
+	if err != nil {
+		b.failed.Add(1)
+		return
+	}
+
+	b.published.Add(1)
+}
+
+func (b *bridge) runConsumer(ctx context.Context) error {
+	inbox := b.src.NewRespInbox()
+	sub, err := b.src.SubscribeSync(inbox)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	for ctx.Err() == nil {
+		if !b.dst.IsConnected() {
+			b.waitForDestination(ctx)
+			continue
+		}
+
+		err = b.consumer.NextMsgRequest(inbox, &api.JSApiConsumerGetNextRequest{Batch: b.batch, Expires: bridgeFetchExpires})
+		if err != nil {
+			return err
+		}
+
+		ok, err := b.forwardBatch(sub)
+		if err != nil {
+			return err
+		}
+
+		// the destination is connected but did not confirm receiving the messages, back off before trying again
+		if !ok && b.dst.IsConnected() {
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	// makes sure the acknowledgements reached the source
+	return b.src.FlushTimeout(b.timeout)
+}
+
+// forwardBatch publishes the messages of a single pull request, they are acknowledged once the destination confirmed
+// receiving them and negatively acknowledged otherwise so the Consumer redelivers them. It is false when the
+// destination did not confirm the messages
+func (b *bridge) forwardBatch(sub *nats.Subscription) (bool, error) {
+	var acks []*bridgeAck
+	failed := false
+
+	for len(acks) < b.batch {
+		msg, err := sub.NextMsg(bridgeFetchExpires + time.Second)
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+
+		// status messages end the pull request, for example once it expired without messages
+		if status := msg.Header.Get("Status"); status != "" {
+			if status == "409" && strings.Contains(msg.Header.Get("Description"), "Deleted") {
+				return false, fmt.Errorf("the Consumer was deleted")
+			}
+			break
+		}
+
+		b.received.Add(1)
+		ack := &bridgeAck{msg: msg, reply: api.AckAck}
+		acks = append(acks, ack)
+
+		// once publishing failed the rest of the batch is redelivered to keep messages in order
+		if failed {
+			continue
+		}
+
+		meta, err := jsm.ParseJSMsgMetadata(msg)
+		if err != nil {
+			return false, err
+		}
+
+		err = b.dst.PublishMsg(b.bridgeMsg(msg, meta))
+		switch {
+		case errors.Is(err, nats.ErrBadSubject):
+			// the mapped subject can never be published to so the message is terminated instead of redelivered forever
+			log.Printf("Terminating message %d on subject %q, it maps to the invalid subject %q", meta.StreamSequence(), msg.Subject, b.mapSubject(msg.Subject))
+			ack.reply = api.AckTerm
+		case err != nil:
+			logWarnf("Could not publish to the destination, pausing: %v", err)
+			failed = true
+		}
+	}
+
+	if len(acks) == 0 {
+		return true, nil
+	}
+
+	if !failed {
+		err := b.dst.FlushTimeout(b.timeout)
+		if err != nil {
+			logWarnf("Could not flush messages to the destination, pausing: %v", err)
+			failed = true
+		}
+	}
+
+	for _, ack := range acks {
+		reply := ack.reply
+		if failed && bytes.Equal(reply, api.AckAck) {
+			reply = api.AckNak
+		}
+
+		if bytes.Equal(reply, api.AckAck) {
+			b.published.Add(1)
+		} else {
+			b.failed.Add(1)
+		}
+
+		err := ack.msg.Respond(reply)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return !failed, nil
+}
+
+// waitForDestination pauses fetching from the Consumer until the destination is connected again
+func (b *bridge) waitForDestination(ctx context.Context) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+
+	for !b.dst.IsConnected() {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// reportRates logs the rates messages are received from the source and published to the destination every interval
+func (b *bridge) reportRates(ctx context.Context) {
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	var lastReceived, lastPublished uint64
+	last := time.Now()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			received, published := b.received.Load(), b.published.Load()
+			secs := now.Sub(last).Seconds()
+
+			log.Printf("Source: %s messages received at %s msg/s, Destination: %s messages published at %s msg/s, %s could not be bridged",
+				f(received), f(uint64(float64(received-lastReceived)/secs)),
+				f(published), f(uint64(float64(published-lastPublished)/secs)),
+				f(b.failed.Load()))
+
+			lastReceived, lastPublished, last = received, published, now
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/internal/subjectmap"
+)
+
+func startBridgeTestServer(t *testing.T, port int, js bool) *server.Server {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{Port: port, JetStream: js, StoreDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("server start failed: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatalf("server did not start")
+	}
+
+	return srv
+}
+
+func runTestBridge(t *testing.T, b *bridge) (cancel func()) {
+	t.Helper()
+
+	ctx, cancelCtx := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() { errs <- b.run(ctx) }()
+
+	return func() {
+		cancelCtx()
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("bridge failed: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("bridge did not stop")
+		}
+	}
+}
+
+func TestBridgeCore(t *testing.T) {
+	srcSrv := startBridgeTestServer(t, -1, false)
+	defer srcSrv.Shutdown()
+	dstSrv := startBridgeTestServer(t, -1, false)
+	defer dstSrv.Shutdown()
+
+	src, err := nats.Connect(srcSrv.ClientURL())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer src.Close()
+	dst, err := nats.Connect(dstSrv.ClientURL())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer dst.Close()
+
+	maps, _ := subjectmap.New([]string{"orders.>=neworders.$1"})
+	b := &bridge{src: src, dst: dst, subject: "orders.>", maps: maps, interval: time.Hour, timeout: time.Second}
+
+	sub, err := dst.SubscribeSync("neworders.>")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	subs := srcSrv.NumSubscriptions()
+	stop := runTestBridge(t, b)
+	for start := time.Now(); srcSrv.NumSubscriptions() == subs; time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("bridge did not subscribe")
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		msg := nats.NewMsg(fmt.Sprintf("orders.%d", i))
+		msg.Data = []byte(fmt.Sprintf("order %d", i))
+		msg.Header.Set("Tenant", "acme")
+		err = src.PublishMsg(msg)
+		if err != nil {
+			t.Fatalf("publish failed: %v", err)
+		}
+	}
+	src.Flush()
+
+	for i := 0; i < 10; i++ {
+		msg, err := sub.NextMsg(time.Second)
+		if err != nil {
+			t.Fatalf("message %d was not bridged: %v", i, err)
+		}
+
+		if msg.Subject != fmt.Sprintf("neworders.%d", i) || string(msg.Data) != fmt.Sprintf("order %d", i) {
+			t.Fatalf("unexpected message %s: %s", msg.Subject, msg.Data)
+		}
+		if msg.Header.Get("Tenant") != "acme" {
+			t.Fatalf("headers were not preserved: %v", msg.Header)
+		}
+		if msg.Header.Get(bridgeSourceSubjectHeader) != fmt.Sprintf("orders.%d", i) || msg.Header.Get(bridgeSourceServerHeader) != srcSrv.Name() {
+			t.Fatalf("provenance headers were not added: %v", msg.Header)
+		}
+	}
+
+	stop()
+
+	if b.received.Load() != 10 || b.published.Load() != 10 || b.failed.Load() != 0 {
+		t.Fatalf("unexpected counts received %d published %d failed %d", b.received.Load(), b.published.Load(), b.failed.Load())
+	}
+}
+
+func TestBridgeConsumer(t *testing.T) {
+	SetLogger(goLogger{})
+
+	srcSrv := startBridgeTestServer(t, -1, true)
+	defer srcSrv.Shutdown()
+	dstSrv := startBridgeTestServer(t, -1, false)
+	port := dstSrv.Addr().(*net.TCPAddr).Port
+
+	src, err := nats.Connect(srcSrv.ClientURL())
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer src.Close()
+
+	dst, err := nats.Connect(dstSrv.ClientURL(), nats.MaxReconnects(-1), nats.ReconnectWait(50*time.Millisecond), nats.ReconnectBufSize(-1))
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer dst.Close()
+
+	mgr, err := jsm.New(src)
+	if err != nil {
+		t.Fatalf("manager failed: %v", err)
+	}
+
+	_, err = mgr.NewStream("ORDERS", jsm.Subjects("orders.>"), jsm.MemoryStorage())
+	if err != nil {
+		t.Fatalf("stream create failed: %v", err)
+	}
+
+	publish := func(from int, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			_, err := src.Request(fmt.Sprintf("orders.%d", i), []byte(fmt.Sprintf("order %d", i)), time.Second)
+			if err != nil {
+				t.Fatalf("publish failed: %v", err)
+			}
+		}
+	}
+	publish(0, 10)
+
+	cmd := &bridgeCmd{stream: "ORDERS", consumer: "BRIDGE", subject: "orders.>"}
+	cons, err := cmd.bridgeConsumer(mgr)
+	if err != nil {
+		t.Fatalf("consumer failed: %v", err)
+	}
+
+	b := &bridge{src: src, dst: dst, consumer: cons, batch: 5, interval: time.Hour, timeout: time.Second}
+
+	sub, err := dst.SubscribeSync("orders.>")
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	dst.Flush()
+
+	stop := runTestBridge(t, b)
+
+	receive := func(from int, to int) {
+		t.Helper()
+		for i := from; i < to; i++ {
+			msg, err := sub.NextMsg(5 * time.Second)
+			if err != nil {
+				t.Fatalf("message %d was not bridged: %v", i, err)
+			}
+			if msg.Subject != fmt.Sprintf("orders.%d", i) {
+				t.Fatalf("unexpected message %s", msg.Subject)
+			}
+			if msg.Header.Get(bridgeSourceStreamHeader) != "ORDERS" || msg.Header.Get(bridgeSourceSequenceHeader) != fmt.Sprint(i+1) || msg.Header.Get("Nats-Msg-Id") != fmt.Sprintf("ORDERS-%d", i+1) {
+				t.Fatalf("provenance headers were not added: %v", msg.Header)
+			}
+		}
+	}
+	receive(0, 10)
+
+	waitForAcks := func(floor uint64) {
+		t.Helper()
+		for start := time.Now(); ; time.Sleep(50 * time.Millisecond) {
+			state, err := cons.State()
+			if err != nil {
+				t.Fatalf("state failed: %v", err)
+			}
+			if state.AckFloor.Stream == floor {
+				return
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Fatalf("expected ack floor %d got %d", floor, state.AckFloor.Stream)
+			}
+		}
+	}
+	waitForAcks(10)
+
+	// messages stay in the stream while the destination is unavailable
+	dstSrv.Shutdown()
+	for start := time.Now(); dst.IsConnected(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("destination did not disconnect")
+		}
+	}
+
+	publish(10, 15)
+	time.Sleep(1500 * time.Millisecond)
+
+	state, err := cons.State()
+	if err != nil {
+		t.Fatalf("state failed: %v", err)
+	}
+	if state.AckFloor.Stream != 10 || state.NumPending+uint64(state.NumAckPending) != 5 {
+		t.Fatalf("expected 5 messages to wait for the destination: %+v", state)
+	}
+
+	dstSrv = startBridgeTestServer(t, port, false)
+	defer dstSrv.Shutdown()
+
+	for start := time.Now(); !dst.IsConnected(); time.Sleep(10 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("destination did not reconnect")
+		}
+	}
+
+	receive(10, 15)
+	waitForAcks(15)
+
+	stop()
+
+	if b.published.Load() != 15 {
+		t.Fatalf("expected 15 messages published got %d", b.published.Load())
+	}
+}
@@ -18,11 +18,14 @@ import (
 	"embed"
 	"github.com/nats-io/natscli/options"
 	glog "log"
+	"os"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/choria-io/fisk"
+	"github.com/nats-io/natscli/columns"
 )
 
 type command struct {
@@ -44,6 +47,14 @@ type Logger interface {
 	Println(a ...any)
 }
 
+// LevelLogger is a Logger that supports log levels, Printf and friends log at the info level
+type LevelLogger interface {
+	Logger
+	Debugf(format string, a ...any)
+	Warnf(format string, a ...any)
+	Errorf(format string, a ...any)
+}
+
 var (
 	commands = []*command{}
 	mu       sync.Mutex
@@ -107,6 +118,13 @@ func commonConfigure(cmd commandHost, cliOpts *options.Options, disable ...strin
 	ctx = context.Background()
 	log = goLogger{}
 
+	configureCommands(cmd, disable...)
+
+	return nil
+}
+
+// configureCommands attaches all registered commands, except those named in disable, to cmd
+func configureCommands(cmd commandHost, disable ...string) {
 	sort.Slice(commands, func(i int, j int) bool {
 		return commands[i].Name < commands[j].Name
 	})
@@ -126,8 +144,6 @@ func commonConfigure(cmd commandHost, cliOpts *options.Options, disable ...strin
 			c.Command(cmd)
 		}
 	}
-
-	return nil
 }
 
 // ConfigureInCommand attaches the cli commands to cmd, prepare will load the context on demand and should be true unless override nats,
@@ -160,18 +176,36 @@ func ConfigureInApp(app *fisk.Application, cliOpts *options.Options, prepare boo
 	return options.DefaultOptions, nil
 }
 
-func preAction(_ *fisk.ParseContext) (err error) {
+func preAction(pc *fisk.ParseContext) (err error) {
+	// custom loggers set using SetLogger are kept as is
+	if _, ok := log.(goLogger); ok {
+		log, err = newGoLogger(opts().LogLevel, opts().LogJSON, os.Stderr)
+		if err != nil {
+			return err
+		}
+
+		// the JetStream API traces needed for debug logging are written to the standard logger by jsm.go
+		if logDebugEnabled() && !opts().Trace {
+			glog.SetFlags(0)
+			glog.SetOutput(apiTraceWriter{})
+		}
+	}
+
+	if opts().Quiet {
+		log = quietLogger{log}
+	}
+
+	columns.SetRawNumbers(opts().RawNumbers)
+
 	loadContext(true)
-	return nil
-}
 
-type goLogger struct{}
+	// context management stays usable so a context holding both a domain and an api prefix can be fixed
+	if opts().Config != nil && (pc.SelectedCommand == nil || !strings.HasPrefix(pc.SelectedCommand.FullCommand(), "context")) {
+		return validateJSTarget(opts().Config.JSDomain(), opts().Config.JSAPIPrefix())
+	}
 
-func (goLogger) Fatalf(format string, a ...any) { glog.Fatalf(format, a...) }
-func (goLogger) Printf(format string, a ...any) { glog.Printf(format, a...) }
-func (goLogger) Print(a ...any)                 { glog.Print(a...) }
-func (goLogger) Println(a ...any)               { glog.Println(a...) }
-func (goLogger) Fatal(a ...any)                 { glog.Fatal(a...) }
+	return nil
+}
 
 func opts() *options.Options {
 	return options.DefaultOptions
@@ -14,7 +14,8 @@
 package cli
 
 import (
-	"github.com/dustin/go-humanize"
+	"time"
+
 	"github.com/nats-io/natscli/columns"
 )
 
@@ -27,9 +28,19 @@ func newColumns(heading string, a ...any) *columns.Writer {
 }
 
 func fiBytes(v uint64) string {
-	return humanize.IBytes(v)
+	return columns.IBytes(v)
 }
 
 func f(v any) string {
 	return columns.F(v)
 }
+
+// fCount formats counts compactly for tables where scanning matters more than exact values
+func fCount(v any) string {
+	return columns.Count(v)
+}
+
+// fAgo formats the time since something happened like 3m ago
+func fAgo(d time.Duration) string {
+	return columns.Ago(d)
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// connManager holds the connections made by a command by label so they can be reused, reported on and shut down
+// together
+type connManager struct {
+	conns []*managedConn
+	mu    sync.Mutex
+}
+
+type managedConn struct {
+	label string
+	nc    *nats.Conn
+}
+
+// connManagerStats are the statistics of a connection at the time they were gathered
+type connManagerStats struct {
+	Label  string
+	Server string
+	nats.Statistics
+}
+
+func newConnManager() *connManager {
+	return &connManager{}
+}
+
+// shared returns the connection configured for the CLI, the same one every other part of the command uses
+func (m *connManager) shared(label string) (*nats.Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if nc := m.lookup(label); nc != nil {
+		return nc, nil
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.conns = append(m.conns, &managedConn{label: label, nc: nc})
+
+	return nc, nil
+}
+
+// connect returns a dedicated connection to servers, or the servers of the context when empty, reusing it when one
+// with the same label was made before
+func (m *connManager) connect(label string, servers string, extra ...nats.Option) (*nats.Conn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if nc := m.lookup(label); nc != nil {
+		return nc, nil
+	}
+
+	mu.Lock()
+	if opts().Config == nil {
+		err := loadContext(false)
+		if err != nil {
+			mu.Unlock()
+			return nil, err
+		}
+	}
+	mu.Unlock()
+
+	if servers == "" {
+		servers = opts().Config.ServerURL()
+	}
+
+	nc, err := nats.Connect(servers, append(natsOpts(), extra...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to %s: %w", servers, err)
+	}
+
+	m.conns = append(m.conns, &managedConn{label: label, nc: nc})
+
+	return nc, nil
+}
+
+func (m *connManager) lookup(label string) *nats.Conn {
+	for _, c := range m.conns {
+		if c.label == label {
+			return c.nc
+		}
+	}
+
+	return nil
+}
+
+// stats gathers the statistics of all connections in the order they were made
+func (m *connManager) stats() []*connManagerStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var res []*connManagerStats
+	for _, c := range m.conns {
+		res = append(res, &connManagerStats{Label: c.label, Server: c.nc.ConnectedUrlRedacted(), Statistics: c.nc.Stats()})
+	}
+
+	return res
+}
+
+func (m *connManager) renderReport() string {
+	table := newTableWriter("Connection Statistics")
+	table.AddHeaders("Connection", "Server", "Msgs Out", "Bytes Out", "Msgs In", "Bytes In", "Reconnects")
+	for _, s := range m.stats() {
+		table.AddRow(s.Label, s.Server, f(s.OutMsgs), fiBytes(s.OutBytes), f(s.InMsgs), fiBytes(s.InBytes), f(s.Reconnects))
+	}
+
+	return table.Render()
+}
+
+// shutdown closes every connection, draining them first when drain is set, the statistics are shown before closing
+// when report is set or when tracing
+func (m *connManager) shutdown(drain bool, report bool) {
+	if report || opts().Trace {
+		fmt.Println(m.renderReport())
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, c := range m.conns {
+		if drain && c.nc.IsConnected() && c.nc.Drain() == nil {
+			deadline := time.Now().Add(opts().Timeout)
+			for !c.nc.IsClosed() && time.Now().Before(deadline) {
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+
+		c.nc.Close()
+	}
+
+	m.conns = nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/natscli/options"
+)
+
+func TestConnManager(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv, err := server.NewServer(&server.Options{Port: -1})
+	checkErr(t, err, "could not start server: %v", err)
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatalf("nats server did not start")
+	}
+	defer srv.Shutdown()
+
+	options.DefaultOptions = &options.Options{Servers: srv.ClientURL(), Timeout: time.Second}
+
+	m := newConnManager()
+
+	shared, err := m.shared("default")
+	checkErr(t, err, "shared failed: %v", err)
+	if shared != opts().Conn {
+		t.Fatalf("expected the shared connection to be the CLI connection")
+	}
+
+	pub, err := m.connect("publisher", "")
+	checkErr(t, err, "connect failed: %v", err)
+	again, err := m.connect("publisher", "")
+	checkErr(t, err, "connect failed: %v", err)
+	if pub != again || pub == shared {
+		t.Fatalf("expected the labeled connection to be reused and separate from the shared one")
+	}
+
+	sub, err := shared.SubscribeSync("test")
+	checkErr(t, err, "subscribe failed: %v", err)
+	err = shared.Flush()
+	checkErr(t, err, "flush failed: %v", err)
+
+	for i := 0; i < 3; i++ {
+		err = pub.Publish("test", []byte("hello"))
+		checkErr(t, err, "publish failed: %v", err)
+	}
+	err = pub.Flush()
+	checkErr(t, err, "flush failed: %v", err)
+
+	for i := 0; i < 3; i++ {
+		_, err = sub.NextMsg(time.Second)
+		checkErr(t, err, "next failed: %v", err)
+	}
+
+	stats := m.stats()
+	if len(stats) != 2 || stats[0].Label != "default" || stats[1].Label != "publisher" {
+		t.Fatalf("expected stats for both connections in order got %v", stats)
+	}
+	if stats[0].InMsgs != 3 || stats[1].OutMsgs != 3 || stats[1].OutBytes != 15 {
+		t.Fatalf("unexpected statistics: %+v %+v", stats[0].Statistics, stats[1].Statistics)
+	}
+
+	report := m.renderReport()
+	if !strings.Contains(report, "publisher") || !strings.Contains(report, srv.ClientURL()) {
+		t.Fatalf("unexpected report:\n%s", report)
+	}
+
+	m.shutdown(true, false)
+	opts().Conn = nil
+
+	if !shared.IsClosed() || !pub.IsClosed() {
+		t.Fatalf("expected all connections to be closed")
+	}
+	if len(m.stats()) != 0 {
+		t.Fatalf("expected no connections after shutdown")
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// connectTraceMaxInfo is how much of the first data received is kept looking for the server INFO
+	connectTraceMaxInfo = 16 * 1024
+
+	// TLS record types used to follow the handshake on the wire
+	tlsRecordHandshake       = 0x16
+	tlsRecordApplicationData = 0x17
+)
+
+// connectTrace records the steps taken while establishing a connection, with live set every step is logged as it
+// happens otherwise the attempts and the outcome are only shown when connecting fails
+type connectTrace struct {
+	live     bool
+	start    time.Time
+	attempts int
+	done     bool
+	events   []*connectTraceEvent
+	mu       sync.Mutex
+}
+
+type connectTraceEvent struct {
+	since   time.Duration
+	detail  bool
+	message string
+}
+
+// connectTraceInfo is the part of the server INFO shown in a trace
+type connectTraceInfo struct {
+	ServerID     string `json:"server_id"`
+	ServerName   string `json:"server_name"`
+	Version      string `json:"version"`
+	Cluster      string `json:"cluster"`
+	MaxPayload   int64  `json:"max_payload"`
+	Headers      bool   `json:"headers"`
+	AuthRequired bool   `json:"auth_required"`
+	TLSRequired  bool   `json:"tls_required"`
+	TLSAvailable bool   `json:"tls_available"`
+	JetStream    bool   `json:"jetstream"`
+}
+
+// connectTraceDialer dials using the configured dialer recording every attempt
+type connectTraceDialer struct {
+	trace  *connectTrace
+	dialer nats.CustomDialer
+}
+
+// connectTraceConn follows the server INFO and the TLS handshake of a connection
+type connectTraceConn struct {
+	net.Conn
+
+	trace   *connectTrace
+	attempt int
+	info    []byte
+	infoSet bool
+
+	tlsStart time.Time
+	tlsDone  bool
+}
+
+func newConnectTrace(live bool) *connectTrace {
+	return &connectTrace{live: live, start: time.Now()}
+}
+
+func (t *connectTrace) record(detail bool, format string, a ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// once connected only live traces keep going, covering reconnects
+	if t.done && !t.live {
+		return
+	}
+
+	ev := &connectTraceEvent{since: time.Since(t.start), detail: detail, message: fmt.Sprintf(format, a...)}
+
+	if t.live {
+		log.Printf("Connect +%s: %s", f(ev.since), ev.message)
+		return
+	}
+
+	t.events = append(t.events, ev)
+}
+
+func (t *connectTrace) nextAttempt() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.attempts++
+
+	return t.attempts
+}
+
+// options adds a dialer recording every attempt to copts, wrapping any dialer already set in copts
+func (t *connectTrace) options(servers string, copts []nats.Option) []nats.Option {
+	o := nats.GetDefaultOptions()
+	for _, opt := range copts {
+		opt(&o)
+	}
+
+	t.record(true, "Connecting to %s authenticating with %s", redactServers(servers), connectAuthMethod(&o, servers))
+
+	for _, s := range strings.Split(servers, ",") {
+		u, err := url.Parse(strings.TrimSpace(s))
+		if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+			continue
+		}
+
+		addrs, err := net.LookupHost(u.Hostname())
+		if err != nil {
+			t.record(false, "Could not resolve %s: %v", u.Hostname(), err)
+			continue
+		}
+
+		t.record(true, "Resolved %s to %s", u.Hostname(), strings.Join(addrs, ", "))
+	}
+
+	dialer := o.CustomDialer
+	if dialer == nil {
+		dialer = &net.Dialer{Timeout: o.Timeout}
+	}
+
+	return append(copts, nats.SetCustomDialer(&connectTraceDialer{trace: t, dialer: dialer}))
+}
+
+// finish records the outcome of connecting, failures are shown when the trace is not live
+func (t *connectTrace) finish(nc *nats.Conn, err error) {
+	if err != nil {
+		t.record(false, "Connecting failed after %s: %v", f(time.Since(t.start)), err)
+	} else {
+		if state, err := nc.TLSConnectionState(); err == nil {
+			t.record(true, "Using %s with cipher %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+		}
+
+		t.record(false, "Connected to %s server %s version %s in %s", redactServers(nc.ConnectedUrl()), nc.ConnectedServerName(), nc.ConnectedServerVersion(), f(time.Since(t.start)))
+	}
+
+	t.mu.Lock()
+	t.done = true
+	events := t.events
+	t.events = nil
+	t.mu.Unlock()
+
+	if err == nil || t.live {
+		return
+	}
+
+	for _, ev := range events {
+		if !ev.detail {
+			log.Printf("Connect +%s: %s", f(ev.since), ev.message)
+		}
+	}
+	log.Printf("Re-run with --connect-debug for details")
+}
+
+func (d *connectTraceDialer) Dial(network, address string) (net.Conn, error) {
+	attempt := d.trace.nextAttempt()
+	d.trace.record(false, "Attempt %d connecting to %s", attempt, address)
+
+	start := time.Now()
+	conn, err := d.dialer.Dial(network, address)
+	if err != nil {
+		d.trace.record(false, "Attempt %d failed to connect to %s after %s: %v", attempt, address, f(time.Since(start)), err)
+		return nil, err
+	}
+
+	d.trace.record(true, "Attempt %d connected to %s from %s in %s", attempt, conn.RemoteAddr(), conn.LocalAddr(), f(time.Since(start)))
+
+	return &connectTraceConn{Conn: conn, trace: d.trace, attempt: attempt}, nil
+}
+
+// SkipTLSHandshake passes the choice of a wrapped dialer that handles TLS itself on to the nats client
+func (d *connectTraceDialer) SkipTLSHandshake() bool {
+	sd, ok := d.dialer.(interface{ SkipTLSHandshake() bool })
+
+	return ok && sd.SkipTLSHandshake()
+}
+
+func (c *connectTraceConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 && !c.infoSet {
+		c.observeInfo(b[:n])
+	}
+
+	return n, err
+}
+
+func (c *connectTraceConn) Write(b []byte) (int, error) {
+	if len(b) > 0 && !c.tlsDone {
+		switch {
+		case b[0] == tlsRecordHandshake && c.tlsStart.IsZero():
+			c.tlsStart = time.Now()
+			c.trace.record(true, "Attempt %d starting the TLS handshake", c.attempt)
+		case b[0] == tlsRecordApplicationData && !c.tlsStart.IsZero():
+			// the client sends application data only once the handshake completed
+			c.tlsDone = true
+			c.trace.record(true, "Attempt %d completed the TLS handshake in %s", c.attempt, f(time.Since(c.tlsStart)))
+		}
+	}
+
+	return c.Conn.Write(b)
+}
+
+// observeInfo collects the first protocol line received, recording it when it is the server INFO
+func (c *connectTraceConn) observeInfo(data []byte) {
+	c.info = append(c.info, data...)
+
+	idx := bytes.Index(c.info, []byte("\r\n"))
+	if idx == -1 && len(c.info) < connectTraceMaxInfo {
+		return
+	}
+
+	c.infoSet = true
+	if idx == -1 {
+		return
+	}
+
+	info, err := parseConnectTraceInfo(c.info[:idx])
+	c.info = nil
+	if err != nil {
+		return
+	}
+
+	c.trace.record(true, "Attempt %d received INFO from %s", c.attempt, info)
+}
+
+// parseConnectTraceInfo parses an INFO protocol line, other lines like encrypted data give an error
+func parseConnectTraceInfo(line []byte) (*connectTraceInfo, error) {
+	proto, body, ok := bytes.Cut(bytes.TrimSpace(line), []byte(" "))
+	if !ok || !strings.EqualFold(string(proto), "INFO") {
+		return nil, fmt.Errorf("not an INFO line")
+	}
+
+	info := &connectTraceInfo{}
+	err := json.Unmarshal(body, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (i *connectTraceInfo) String() string {
+	name := i.ServerName
+	if name == "" {
+		name = i.ServerID
+	}
+
+	parts := []string{
+		fmt.Sprintf("server %s", name),
+		fmt.Sprintf("version %s", i.Version),
+		fmt.Sprintf("max_payload %s", fiBytes(uint64(i.MaxPayload))),
+		fmt.Sprintf("auth_required %t", i.AuthRequired),
+		fmt.Sprintf("tls_required %t", i.TLSRequired),
+	}
+	if i.Cluster != "" {
+		parts = append(parts, fmt.Sprintf("cluster %s", i.Cluster))
+	}
+	if i.TLSAvailable {
+		parts = append(parts, "tls_available true")
+	}
+	parts = append(parts, fmt.Sprintf("headers %t", i.Headers), fmt.Sprintf("jetstream %t", i.JetStream))
+
+	return strings.Join(parts, ", ")
+}
+
+// connectAuthMethod describes how a connection with options o authenticates, credentials in the server urls are used
+// in preference to those in the options like the nats client does
+func connectAuthMethod(o *nats.Options, servers string) string {
+	method := ""
+
+	for _, s := range strings.Split(servers, ",") {
+		u, err := url.Parse(strings.TrimSpace(s))
+		if err != nil || u.User == nil {
+			continue
+		}
+
+		if _, ok := u.User.Password(); ok {
+			method = "user and password from the server url"
+		} else {
+			method = "token from the server url"
+		}
+		break
+	}
+
+	if method == "" {
+		switch {
+		case o.UserJWT != nil:
+			method = "JWT credentials"
+		case o.Nkey != "":
+			method = "NKey"
+		case o.User != "":
+			method = "user and password"
+		case o.Token != "" || o.TokenHandler != nil:
+			method = "token"
+		default:
+			method = "no credentials"
+		}
+	}
+
+	if o.TLSCertCB != nil || (o.TLSConfig != nil && len(o.TLSConfig.Certificates) > 0) {
+		method += " and TLS client certificate"
+	}
+
+	return method
+}
+
+// redactServers removes passwords and tokens from a list of server urls
+func redactServers(servers string) string {
+	var redacted []string
+	for _, s := range strings.Split(servers, ",") {
+		s = strings.TrimSpace(s)
+
+		u, err := url.Parse(s)
+		if err != nil || u.User == nil {
+			redacted = append(redacted, s)
+			continue
+		}
+
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		} else {
+			u.User = url.User("xxxxx")
+		}
+		redacted = append(redacted, u.String())
+	}
+
+	return strings.Join(redacted, ", ")
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"crypto/tls"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestParseConnectTraceInfo(t *testing.T) {
+	info, err := parseConnectTraceInfo([]byte(`INFO {"server_id":"NABC","server_name":"n1","version":"2.10.0","max_payload":1048576,"headers":true,"auth_required":true,"jetstream":true}` + "\r\n"))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	expected := "server n1, version 2.10.0, max_payload 1.0 MiB, auth_required true, tls_required false, headers true, jetstream true"
+	if info.String() != expected {
+		t.Fatalf("expected %q got %q", expected, info.String())
+	}
+
+	info, err = parseConnectTraceInfo([]byte(`INFO {"server_id":"NABC","cluster":"c1","tls_available":true}`))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if info.String() != "server NABC, version , max_payload 0 B, auth_required false, tls_required false, cluster c1, tls_available true, headers false, jetstream false" {
+		t.Fatalf("unexpected info: %q", info.String())
+	}
+
+	for _, line := range []string{"", "PING", "+OK", "INFO", "INFO {"} {
+		_, err = parseConnectTraceInfo([]byte(line))
+		if err == nil {
+			t.Fatalf("expected %q to fail", line)
+		}
+	}
+}
+
+func TestConnectAuthMethod(t *testing.T) {
+	cert := &tls.Config{Certificates: []tls.Certificate{{}}}
+
+	for _, tc := range []struct {
+		servers  string
+		opts     []nats.Option
+		expected string
+	}{
+		{"nats://localhost:4222", nil, "no credentials"},
+		{"nats://localhost:4222", []nats.Option{nats.UserInfo("u", "p")}, "user and password"},
+		{"nats://localhost:4222", []nats.Option{nats.Token("t")}, "token"},
+		{"nats://localhost:4222", []nats.Option{nats.UserJWTAndSeed("jwt", "seed")}, "JWT credentials"},
+		{"nats://localhost:4222", []nats.Option{nats.Nkey("UABC", func([]byte) ([]byte, error) { return nil, nil })}, "NKey"},
+		{"nats://localhost:4222, nats://u:p@other:4222", []nats.Option{nats.Token("t")}, "user and password from the server url"},
+		{"nats://t@localhost:4222", nil, "token from the server url"},
+		{"nats://localhost:4222", []nats.Option{nats.Secure(cert)}, "no credentials and TLS client certificate"},
+	} {
+		o := nats.GetDefaultOptions()
+		for _, opt := range tc.opts {
+			opt(&o)
+		}
+
+		if got := connectAuthMethod(&o, tc.servers); got != tc.expected {
+			t.Fatalf("expected %q for %s got %q", tc.expected, tc.servers, got)
+		}
+	}
+}
+
+func TestRedactServers(t *testing.T) {
+	got := redactServers("nats://u:p@a:4222,nats://t@b:4222, nats://c:4222,d:4222")
+	expected := "nats://u:xxxxx@a:4222, nats://xxxxx@b:4222, nats://c:4222, d:4222"
+	if got != expected {
+		t.Fatalf("expected %q got %q", expected, got)
+	}
+}
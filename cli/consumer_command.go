@@ -31,7 +31,6 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats.go"
@@ -94,6 +93,28 @@ type consumerCmd struct {
 	metadata            map[string]string
 	pauseUntil          string
 
+	dlAdvisoryStream string
+	dlSince          time.Duration
+	dlWait           time.Duration
+	dlRequeue        string
+	dlSeqs           []uint64
+
+	stuckOlderThan  time.Duration
+	stuckMaxInspect int
+
+	drainRate    int
+	drainFilter  string
+	showProgress bool
+
+	resetPolicy    string
+	resetStartSeq  uint64
+	resetStartTime string
+
+	ratesInterval time.Duration
+	ratesDuration time.Duration
+
+	nextExec string
+
 	dryRun bool
 	mgr    *jsm.Manager
 	nc     *nats.Conn
@@ -197,6 +218,14 @@ func configureConsumerCommand(app commandHost) {
 	addCreateFlags(consCp, false)
 
 	consNext := cons.Command("next", "Retrieves messages from Pull Consumers without interactive prompts").Action(c.nextAction)
+	consNext.HelpLong(`Messages can be processed by a command that is passed the path to a file
+holding the payload, the message is acknowledged when the command exits 0,
+terminated when it exits 2 and negatively acknowledged for any other exit
+code so it is redelivered:
+
+   nats consumer next ORDERS NEW --exec "./process-message.sh" --count 10
+
+Messages are not acknowledged when the command can not be run.`)
 	consNext.Arg("stream", "Stream name").Required().StringVar(&c.stream)
 	consNext.Arg("consumer", "Consumer name").Required().StringVar(&c.consumer)
 	consNext.Flag("ack", "Acknowledge received message").Default("true").IsSetByUser(&c.ackSetByUser).BoolVar(&c.ack)
@@ -205,6 +234,7 @@ func configureConsumerCommand(app commandHost) {
 	consNext.Flag("raw", "Show only the message").Short('r').UnNegatableBoolVar(&c.raw)
 	consNext.Flag("wait", "Wait up to this period to acknowledge messages").DurationVar(&c.ackWait)
 	consNext.Flag("count", "Number of messages to try to fetch from the pull consumer").Default("1").IntVar(&c.pullCount)
+	consNext.Flag("exec", "Runs a command with the path to a file holding the payload, acknowledging the message based on its exit code").PlaceHolder("COMMAND").StringVar(&c.nextExec)
 
 	consSub := cons.Command("sub", "Retrieves messages from Consumers").Action(c.subAction)
 	consSub.Arg("stream", "Stream name").StringVar(&c.stream)
@@ -224,11 +254,144 @@ func configureConsumerCommand(app commandHost) {
 	conResume.Arg("consumer", "Consumer name").StringVar(&c.consumer)
 	conResume.Flag("force", "Force resume without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
+	alignHelp := `Moves a Consumer to the end of its Stream, skipping all pending messages
+
+JetStream can not move the acknowledgement floor of an existing Consumer so
+the Consumer is removed and created again with the same configuration, starting
+after the last message currently in the Stream. Redelivery counts, pending
+acknowledgements and any pull requests waiting on the Consumer are lost.
+
+Consumers on interest and work queue Streams can not be aligned.
+`
+	conAlign := cons.Command("align", alignHelp).Action(c.alignAction)
+	conAlign.Arg("stream", "Stream name").StringVar(&c.stream)
+	conAlign.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conAlign.Flag("force", "Force alignment without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	resetHelp := `Resets the delivery position of a Consumer, delivering messages again
+
+JetStream can not change the deliver policy of an existing Consumer so the
+Consumer is removed and created again with the same configuration and the new
+delivery position. Redelivery counts, pending acknowledgements and any pull
+requests waiting on the Consumer are lost.
+
+Consumers on interest Streams can not be reset as the server removes messages
+once no Consumer is interested in them. Work queue Streams only hold messages
+not yet acknowledged, those are the only messages that can be delivered again
+and only using the all deliver policy.
+
+Start times can be given in RFC3339 format, as a date like "2023-04-01 02:00"
+optionally followed by a time zone or as a duration like 1h meaning that long ago.
+`
+	conReset := cons.Command("reset", resetHelp).Action(c.resetAction)
+	conReset.Arg("stream", "Stream name").StringVar(&c.stream)
+	conReset.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conReset.Flag("deliver-policy", "Where to start delivering messages (all, last, new, last_per_subject)").PlaceHolder("POLICY").EnumVar(&c.resetPolicy, "all", "last", "new", "last_per_subject")
+	conReset.Flag("start-seq", "Start delivering messages at this Stream sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.resetStartSeq)
+	conReset.Flag("start-time", "Start delivering messages stored at or after this time").PlaceHolder("TIME").StringVar(&c.resetStartTime)
+	conReset.Flag("force", "Force reset without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	drainHelp := `Acknowledges all pending messages of a Consumer without processing them
+
+When a filter subject is given only matching messages are acknowledged, other
+messages are released to be redelivered after the Consumer acknowledgement wait
+time. This requires the explicit acknowledgement policy.
+`
+	conDrain := cons.Command("drain", drainHelp).Action(c.drainAction)
+	conDrain.Arg("stream", "Stream name").StringVar(&c.stream)
+	conDrain.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conDrain.Flag("rate", "Acknowledge at most this many messages per second").PlaceHolder("MSGS").IntVar(&c.drainRate)
+	conDrain.Flag("filter-subject", "Only acknowledge messages matching this subject").PlaceHolder("SUBJECT").StringVar(&c.drainFilter)
+	conDrain.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	conDrain.Flag("force", "Drain without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
 	conReport := cons.Command("report", "Reports on Consumer statistics").Action(c.reportAction)
 	conReport.Arg("stream", "Stream name").StringVar(&c.stream)
 	conReport.Flag("raw", "Show un-formatted numbers").Short('r').UnNegatableBoolVar(&c.raw)
 	conReport.Flag("leaders", "Show details about the leaders").Short('l').UnNegatableBoolVar(&c.reportLeaderDistrib)
 
+	reportRatesHelp := `Shows the message rates of all Consumers of a Stream
+
+The Consumers are polled every interval and the rates they delivered and
+acknowledged messages at since the previous poll are shown in a table that
+refreshes in place, along with the average delivery rate since starting.
+`
+	conReportRates := cons.Command("report-rates", reportRatesHelp).Action(c.reportRatesAction)
+	conReportRates.Arg("stream", "Stream name").StringVar(&c.stream)
+	conReportRates.Flag("interval", "How often to poll the Consumers").Default("5s").DurationVar(&c.ratesInterval)
+	conReportRates.Flag("duration", "How long to measure for, until interrupted when 0").Default("1m").DurationVar(&c.ratesDuration)
+
+	deadLettersHelp := `Shows messages that exceeded the maximum deliveries of a Consumer
+
+Max delivery advisories are read from a Stream storing them, when no
+Stream stores them new advisories are shown until interrupted.
+`
+
+	conDL := cons.Command("dead-letters", deadLettersHelp).Alias("dlq").Action(c.deadLettersAction)
+	conDL.Arg("stream", "Stream name").StringVar(&c.stream)
+	conDL.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conDL.Flag("advisory-stream", "Stream holding the max delivery advisories, detected when not given").PlaceHolder("STREAM").StringVar(&c.dlAdvisoryStream)
+	conDL.Flag("since", "Only show advisories received within this duration").PlaceHolder("DURATION").DurationVar(&c.dlSince)
+	conDL.Flag("wait", "Stop waiting for new advisories after this duration").PlaceHolder("DURATION").DurationVar(&c.dlWait)
+	conDL.Flag("seq", "Only act on specific Stream sequences (pass multiple times)").PlaceHolder("SEQUENCE").Uint64ListVar(&c.dlSeqs)
+	conDL.Flag("requeue", "Republish the messages to this subject").PlaceHolder("SUBJECT").StringVar(&c.dlRequeue)
+	conDL.Flag("term", "Remove the messages from the Stream").UnNegatableBoolVar(&c.term)
+	conDL.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	conDL.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	stuckHelp := `Finds messages that are awaiting acknowledgement for a long time
+
+Messages between the acknowledgement floor and the last delivered message
+are read from the Stream, the first of these is known to be pending while
+later ones might have been acknowledged already. JetStream does not report
+delivery counts or times of individual messages, the time a message was
+stored is the earliest it could have been delivered.
+
+Stuck messages can be terminated or redelivered, this requires the explicit
+acknowledgement policy.
+`
+	conStuck := cons.Command("stuck", stuckHelp).Action(c.stuckAction)
+	conStuck.Arg("stream", "Stream name").StringVar(&c.stream)
+	conStuck.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conStuck.Flag("older-than", "Only show messages stored longer ago than this").Default("5m").DurationVar(&c.stuckOlderThan)
+	conStuck.Flag("max-inspect", "Maximum number of messages past the acknowledgement floor to read").Default("10000").IntVar(&c.stuckMaxInspect)
+	conStuck.Flag("term", "Terminate the stuck messages, preventing further redelivery").UnNegatableBoolVar(&c.term)
+	conStuck.Flag("nak", "Negatively acknowledge the stuck messages, redelivering them").UnNegatableBoolVar(&c.nak)
+	conStuck.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	conStuck.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	peekHelp := `Shows the next messages a Consumer will deliver without consuming them
+
+Messages are read directly from the Stream, the Consumer is not used so
+delivery counts, acknowledgements and its position are not affected.
+Messages awaiting acknowledgement might be redelivered before those shown.
+`
+	conPeek := cons.Command("peek", peekHelp).Action(c.peekAction)
+	conPeek.Arg("stream", "Stream name").StringVar(&c.stream)
+	conPeek.Arg("consumer", "Consumer name").StringVar(&c.consumer)
+	conPeek.Flag("count", "Number of messages to show").Default("5").IntVar(&c.pullCount)
+	conPeek.Flag("raw", "Show only the message bodies").Short('r').UnNegatableBoolVar(&c.raw)
+	conPeek.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	exportAllHelp := `Exports the configuration and state of all Consumers of a Stream to a JSON file
+
+The export can be restored using import-all, possibly into a different Stream
+or cluster.
+`
+	conExportAll := cons.Command("export-all", exportAllHelp).Action(c.exportAllAction)
+	conExportAll.Arg("stream", "Stream name").StringVar(&c.stream)
+	conExportAll.Flag("output", "File to write the Consumers to, prints them when not set").Short('O').PlaceHolder("FILE").StringVar(&c.outFile)
+	conExportAll.Flag("force", "Replace the output file without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	importAllHelp := `Creates the Consumers found in a file written by export-all
+
+Only the configuration is used, Consumers start delivering according to their
+deliver policy. Ephemeral Consumers and Consumers that already exist are skipped.
+`
+	conImportAll := cons.Command("import-all", importAllHelp).Action(c.importAllAction)
+	conImportAll.Arg("stream", "Stream name").StringVar(&c.stream)
+	conImportAll.Flag("from", "File holding the exported Consumers").Required().PlaceHolder("FILE").ExistingFileVar(&c.inputFile)
+
 	conCluster := cons.Command("cluster", "Manages a clustered Consumer").Alias("c")
 	conClusterDown := conCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown)
 	conClusterDown.Arg("stream", "Stream to act on").StringVar(&c.stream)
@@ -280,12 +443,12 @@ func (c *consumerCmd) leaderStandDown(_ *fisk.ParseContext) error {
 
 		info, err = consumer.State()
 		if err != nil {
-			log.Printf("Failed to retrieve Consumer State: %s", err)
+			logErrorf("Failed to retrieve Consumer State: %s", err)
 			continue
 		}
 
 		if info.Cluster == nil {
-			log.Printf("Failed to retrieve Consumer State: no cluster information received")
+			logErrorf("Failed to retrieve Consumer State: no cluster information received")
 			continue
 		}
 
@@ -398,6 +561,11 @@ func (c *consumerCmd) editAction(pc *fisk.ParseContext) error {
 			ncfg.HeadersOnly = c.hdrsOnly
 		}
 
+		err = validateFilterSubjects(c.filterSubjects)
+		if err != nil {
+			return err
+		}
+
 		if len(c.filterSubjects) == 1 {
 			ncfg.FilterSubject = c.filterSubjects[0]
 			ncfg.FilterSubjects = nil
@@ -633,7 +801,7 @@ func (c *consumerCmd) showInfo(config api.ConsumerConfig, state api.ConsumerInfo
 	cols.AddRow("Replay Policy", config.ReplayPolicy.String())
 	cols.AddRowIf("Maximum Deliveries", config.MaxDeliver, config.MaxDeliver != -1)
 	cols.AddRowIfNotEmpty("Sampling Rate", config.SampleFrequency)
-	cols.AddRowIf("Rate Limit", fmt.Sprintf("%s / second", humanize.IBytes(config.RateLimit/8)), config.RateLimit > 0)
+	cols.AddRowIf("Rate Limit", fmt.Sprintf("%s / second", fiBytes(config.RateLimit/8)), config.RateLimit > 0)
 	cols.AddRowIf("Max Ack Pending", config.MaxAckPending, config.MaxAckPending > 0)
 	cols.AddRowIf("Max Waiting Pulls", int64(config.MaxWaiting), config.MaxWaiting > 0)
 	cols.AddRowIf("Idle Heartbeat", config.Heartbeat, config.Heartbeat > 0)
@@ -670,7 +838,7 @@ func (c *consumerCmd) showInfo(config api.ConsumerConfig, state api.ConsumerInfo
 		cols.AddRowIfNotEmpty("Raft Group", state.Cluster.RaftGroup)
 		cols.AddRow("Leader", state.Cluster.Leader)
 		for _, r := range state.Cluster.Replicas {
-			since := fmt.Sprintf("seen %s ago", f(r.Active))
+			since := "seen " + fAgo(r.Active)
 			if r.Active == 0 || r.Active == math.MaxInt64 {
 				since = "not seen"
 			}
@@ -688,14 +856,14 @@ func (c *consumerCmd) showInfo(config api.ConsumerConfig, state api.ConsumerInfo
 	if state.Delivered.Last == nil {
 		cols.AddRowf("Last Delivered Message", "Consumer sequence: %s Stream sequence: %s", f(state.Delivered.Consumer), f(state.Delivered.Stream))
 	} else {
-		cols.AddRowf("Last Delivered Message", "Consumer sequence: %s Stream sequence: %s Last delivery: %s ago", f(state.Delivered.Consumer), f(state.Delivered.Stream), f(sinceRefOrNow(state.TimeStamp, *state.Delivered.Last)))
+		cols.AddRowf("Last Delivered Message", "Consumer sequence: %s Stream sequence: %s Last delivery: %s", f(state.Delivered.Consumer), f(state.Delivered.Stream), fAgo(sinceRefOrNow(state.TimeStamp, *state.Delivered.Last)))
 	}
 
 	if config.AckPolicy != api.AckNone {
 		if state.AckFloor.Last == nil {
 			cols.AddRowf("Acknowledgment Floor", "Consumer sequence: %s Stream sequence: %s", f(state.AckFloor.Consumer), f(state.AckFloor.Stream))
 		} else {
-			cols.AddRowf("Acknowledgment Floor", "Consumer sequence: %s Stream sequence: %s Last Ack: %s ago", f(state.AckFloor.Consumer), f(state.AckFloor.Stream), f(sinceRefOrNow(state.TimeStamp, *state.AckFloor.Last)))
+			cols.AddRowf("Acknowledgment Floor", "Consumer sequence: %s Stream sequence: %s Last Ack: %s", f(state.AckFloor.Consumer), f(state.AckFloor.Stream), fAgo(sinceRefOrNow(state.TimeStamp, *state.AckFloor.Last)))
 		}
 		if config.MaxAckPending > 0 {
 			cols.AddRowf("Outstanding Acks", "%s out of maximum %s", f(state.NumAckPending), f(config.MaxAckPending))
@@ -858,6 +1026,11 @@ func (c *consumerCmd) cpAction(pc *fisk.ParseContext) (err error) {
 		cfg.AckPolicy = c.ackPolicyFromString(c.ackPolicy)
 	}
 
+	err = validateFilterSubjects(c.filterSubjects)
+	if err != nil {
+		return err
+	}
+
 	if len(c.filterSubjects) == 1 {
 		cfg.FilterSubject = c.filterSubjects[0]
 	} else if len(c.filterSubjects) > 1 {
@@ -1172,6 +1345,11 @@ func (c *consumerCmd) prepareConfig(pc *fisk.ParseContext) (cfg *api.ConsumerCon
 		c.filterSubjects = splitString(sub)
 	}
 
+	err = validateFilterSubjects(c.filterSubjects)
+	if err != nil {
+		return nil, err
+	}
+
 	switch {
 	case len(c.filterSubjects) == 1:
 		cfg.FilterSubject = c.filterSubjects[0]
@@ -1356,6 +1534,71 @@ func (c *consumerCmd) resumeAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
+func (c *consumerCmd) alignAction(_ *fisk.ParseContext) error {
+	c.connectAndSetup(true, true)
+
+	if c.selectedConsumer.IsEphemeral() {
+		return fmt.Errorf("only durable consumers can be aligned")
+	}
+
+	retention, err := c.recreateRetention("aligned")
+	if err != nil {
+		return err
+	}
+	if retention == api.WorkQueuePolicy {
+		return fmt.Errorf("consumers on work queue Streams have to deliver all messages, they can not be aligned")
+	}
+
+	stream, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	info, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	state, err := c.selectedConsumer.State()
+	if err != nil {
+		return err
+	}
+
+	skipped := state.NumPending + uint64(state.NumAckPending)
+	if skipped == 0 {
+		fmt.Printf("Consumer %s > %s has no pending messages\n", c.stream, c.consumer)
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really skip %s pending messages on Consumer %s > %s", f(skipped), c.stream, c.consumer), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	cfg := c.selectedConsumer.Configuration()
+	cfg.DeliverPolicy = api.DeliverByStartSequence
+	cfg.OptStartSeq = info.State.LastSeq + 1
+	cfg.OptStartTime = nil
+
+	err = c.selectedConsumer.Delete()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.mgr.NewConsumerFromDefault(c.stream, cfg)
+	if err != nil {
+		return fmt.Errorf("consumer %s was removed but could not be created again: %w", c.consumer, err)
+	}
+
+	fmt.Printf("Consumer %s > %s skipped %s messages and will continue after Stream sequence %s\n", c.stream, c.consumer, f(skipped), f(info.State.LastSeq))
+
+	return nil
+}
+
 func (c *consumerCmd) pauseAction(_ *fisk.ParseContext) error {
 	c.connectAndSetup(true, true)
 
@@ -1556,9 +1799,12 @@ func (c *consumerCmd) getNextMsgDirect(stream string, consumer string) error {
 	}
 	fisk.FatalIfError(err, "no message received")
 
-	if msg.Header != nil && msg.Header.Get("Status") == "503" {
+	// the server answers pull requests it can not satisfy with status messages like 404 No Messages or 409 Consumer Deleted
+	err = statusMsgError(msg, fmt.Sprintf(api.JSApiRequestNextT, stream, consumer))
+	if err != nil {
 		fatalIfNotPull()
 	}
+	fisk.FatalIfError(err, "no message received")
 
 	if !c.raw {
 		info, err := jsm.ParseJSMsgMetadata(msg)
@@ -1594,11 +1840,17 @@ func (c *consumerCmd) getNextMsgDirect(stream string, consumer string) error {
 		fmt.Println(string(msg.Data))
 	}
 
+	if c.nextExec != "" {
+		return c.execNextMsg(msg)
+	}
+
 	if c.term {
 		err = msg.Term()
 		fisk.FatalIfError(err, "could not Terminate message")
 		c.nc.Flush()
-		fmt.Println("\nTerminated message")
+		if !c.raw {
+			log.Printf("Terminated message")
+		}
 	}
 
 	if c.ack || c.nak {
@@ -1631,11 +1883,10 @@ func (c *consumerCmd) getNextMsgDirect(stream string, consumer string) error {
 				neg = "Negative "
 			}
 			if stime > 0 {
-				fmt.Printf("\n%sAcknowledged message after %s delay\n", neg, stime)
+				log.Printf("%sAcknowledged message after %s delay", neg, stime)
 			} else {
-				fmt.Printf("\n%sAcknowledged message\n", neg)
+				log.Printf("%sAcknowledged message", neg)
 			}
-			fmt.Println()
 		}
 	}
 
@@ -1644,13 +1895,12 @@ func (c *consumerCmd) getNextMsgDirect(stream string, consumer string) error {
 
 func (c *consumerCmd) subscribeConsumer(consumer *jsm.Consumer) (err error) {
 	if !c.raw {
-		fmt.Printf("Subscribing to topic %s auto acknowledgment: %v\n\n", consumer.DeliverySubject(), c.ack)
-		fmt.Println("Consumer Info:")
-		fmt.Printf("  Ack Policy: %s\n", consumer.AckPolicy().String())
+		log.Printf("Subscribing to topic %s auto acknowledgment: %v", consumer.DeliverySubject(), c.ack)
 		if consumer.AckPolicy() != api.AckNone {
-			fmt.Printf("    Ack Wait: %v\n", consumer.AckWait())
+			log.Printf("Consumer Ack Policy: %s Ack Wait: %v", consumer.AckPolicy().String(), consumer.AckWait())
+		} else {
+			log.Printf("Consumer Ack Policy: %s", consumer.AckPolicy().String())
 		}
-		fmt.Println()
 	}
 
 	handler := func(m *nats.Msg) {
@@ -1714,7 +1964,7 @@ func (c *consumerCmd) subscribeConsumer(consumer *jsm.Consumer) (err error) {
 		if c.ack {
 			err = m.Respond(nil)
 			if err != nil {
-				fmt.Printf("Acknowledging message via subject %s failed: %s\n", m.Reply, err)
+				logErrorf("Acknowledging message via subject %s failed: %s", m.Reply, err)
 			}
 		}
 	}
@@ -1753,6 +2003,10 @@ func (c *consumerCmd) subAction(_ *fisk.ParseContext) error {
 }
 
 func (c *consumerCmd) nextAction(_ *fisk.ParseContext) error {
+	if c.nextExec != "" && (c.ackSetByUser || c.nak || c.term) {
+		return fmt.Errorf("ack, nak and term can not be used with exec")
+	}
+
 	c.connectAndSetup(false, false, nats.UseOldRequestStyle())
 
 	var err error
@@ -1810,7 +2064,7 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 	missing, err := s.EachConsumer(func(cons *jsm.Consumer) {
 		cs, err := cons.LatestState()
 		if err != nil {
-			log.Printf("Could not obtain consumer state for %s: %s", cons.Name(), err)
+			logErrorf("Could not obtain consumer state for %s: %s", cons.Name(), err)
 			return
 		}
 
@@ -1838,7 +2092,7 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 				if upct > 100 {
 					upct = 100
 				}
-				unprocessed = fmt.Sprintf("%s / %0.0f%%", f(cs.NumPending), upct)
+				unprocessed = fmt.Sprintf("%s / %0.0f%%", fCount(cs.NumPending), upct)
 			}
 
 			table.AddRow(cons.Name(), mode, cons.AckPolicy().String(), f(cons.AckWait()), f(cs.NumAckPending), f(cs.NumRedelivered), unprocessed, f(cs.AckFloor.Stream), renderCluster(cs.Cluster))
@@ -1848,14 +2102,17 @@ func (c *consumerCmd) reportAction(_ *fisk.ParseContext) error {
 		return err
 	}
 
-	fmt.Println(table.Render())
+	out := newPagedWriter()
+	defer out.Close()
+
+	fmt.Fprintln(out, table.Render())
 
 	if c.reportLeaderDistrib && len(leaders) > 0 {
-		renderRaftLeaders(leaders, "Consumers")
+		renderRaftLeaders(out, leaders, "Consumers")
 	}
 
 	if len(missing) > 0 {
-		c.renderMissing(os.Stdout, missing)
+		c.renderMissing(out, missing)
 	}
 
 	return nil
@@ -0,0 +1,393 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	jsadvisory "github.com/nats-io/jsm.go/api/jetstream/advisory"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// deadLetter is a message that exceeded the maximum deliveries of a consumer
+type deadLetter struct {
+	Stream     string              `json:"stream"`
+	Consumer   string              `json:"consumer"`
+	Sequence   uint64              `json:"sequence"`
+	Deliveries uint64              `json:"deliveries"`
+	Advised    time.Time           `json:"advisory_time"`
+	Subject    string              `json:"subject,omitempty"`
+	Headers    map[string][]string `json:"headers,omitempty"`
+	Data       []byte              `json:"data,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+type deadLetterCollector struct {
+	mu      sync.Mutex
+	letters map[uint64]*deadLetter
+}
+
+// add records an advisory, keeping only the latest advisory for every stream sequence
+func (d *deadLetterCollector) add(data []byte) (*deadLetter, error) {
+	var adv jsadvisory.ConsumerDeliveryExceededAdvisoryV1
+	err := json.Unmarshal(data, &adv)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	letter, ok := d.letters[adv.StreamSeq]
+	if ok && letter.Advised.After(adv.Time) {
+		return letter, nil
+	}
+
+	letter = &deadLetter{
+		Stream:     adv.Stream,
+		Consumer:   adv.Consumer,
+		Sequence:   adv.StreamSeq,
+		Deliveries: adv.Deliveries,
+		Advised:    adv.Time,
+	}
+	d.letters[adv.StreamSeq] = letter
+
+	return letter, nil
+}
+
+// sorted returns the letters in stream sequence order, limited to seqs when any are given
+func (d *deadLetterCollector) sorted(seqs []uint64) []*deadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var res []*deadLetter
+	for _, letter := range d.letters {
+		if len(seqs) > 0 && !containsUint64(seqs, letter.Sequence) {
+			continue
+		}
+		res = append(res, letter)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Sequence < res[j].Sequence
+	})
+
+	return res
+}
+
+func containsUint64(list []uint64, v uint64) bool {
+	for _, i := range list {
+		if i == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *consumerCmd) deadLettersAction(_ *fisk.ParseContext) error {
+	c.connectAndSetup(true, true)
+
+	if c.dlRequeue != "" && strings.ContainsAny(c.dlRequeue, "*>") {
+		return fmt.Errorf("requeue subject can not contain wildcards")
+	}
+
+	stream, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	subject := jsm.EventSubject(fmt.Sprintf("%s.%s.%s", api.JSAdvisoryConsumerMaxDeliveryExceedPre, c.stream, c.consumer), opts().Config.JSEventPrefix())
+	collector := &deadLetterCollector{letters: map[uint64]*deadLetter{}}
+
+	if c.dlAdvisoryStream == "" {
+		c.dlAdvisoryStream, err = c.findAdvisoryStream(subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.dlAdvisoryStream != "" {
+		err = c.readStoredDeadLetters(subject, collector)
+	} else {
+		err = c.watchDeadLetters(subject, collector)
+	}
+	if err != nil {
+		return err
+	}
+
+	letters := collector.sorted(c.dlSeqs)
+	for _, letter := range letters {
+		c.resolveDeadLetter(stream, letter)
+	}
+
+	if c.json {
+		err = iu.PrintJSON(letters)
+	} else {
+		c.renderDeadLetters(letters)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(letters) == 0 || (c.dlRequeue == "" && !c.term) {
+		return nil
+	}
+
+	return c.actOnDeadLetters(stream, letters)
+}
+
+// findAdvisoryStream finds a stream that stores the max delivery advisories, empty when none do
+func (c *consumerCmd) findAdvisoryStream(subject string) (string, error) {
+	var found string
+
+	_, err := c.mgr.EachStream(nil, func(s *jsm.Stream) {
+		if found != "" {
+			return
+		}
+
+		for _, subj := range s.Subjects() {
+			if server.SubjectsCollide(subj, subject) {
+				found = s.Name()
+				return
+			}
+		}
+	})
+
+	return found, err
+}
+
+func (c *consumerCmd) readStoredDeadLetters(subject string, collector *deadLetterCollector) error {
+	js, err := c.nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	sopts := []nats.SubOpt{nats.BindStream(c.dlAdvisoryStream), nats.OrderedConsumer()}
+	if c.dlSince > 0 {
+		sopts = append(sopts, nats.StartTime(time.Now().Add(-c.dlSince)))
+	} else {
+		sopts = append(sopts, nats.DeliverAll())
+	}
+
+	sub, err := js.SubscribeSync(subject, sopts...)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	if !c.json {
+		log.Printf("Reading max delivery advisories from Stream %s", c.dlAdvisoryStream)
+	}
+
+	nfo, err := sub.ConsumerInfo()
+	if err != nil {
+		return err
+	}
+	// messages might already be on their way to us, only stop when nothing was delivered either
+	if nfo.NumPending == 0 && nfo.Delivered.Consumer == 0 {
+		return nil
+	}
+
+	for {
+		msg, err := sub.NextMsg(opts().Timeout)
+		if errors.Is(err, nats.ErrTimeout) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = collector.add(msg.Data)
+		if err != nil {
+			log.Printf("Invalid advisory received: %v", err)
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil || meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
+
+func (c *consumerCmd) watchDeadLetters(subject string, collector *deadLetterCollector) error {
+	if c.dlSince > 0 {
+		return fmt.Errorf("since requires a Stream storing advisories, none were found for %s", subject)
+	}
+
+	sub, err := c.nc.Subscribe(subject, func(m *nats.Msg) {
+		letter, err := collector.add(m.Data)
+		if err != nil {
+			log.Printf("Invalid advisory received: %v", err)
+			return
+		}
+
+		if !c.json {
+			log.Printf("Stream sequence %d exceeded %d deliveries", letter.Sequence, letter.Deliveries)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	err = c.nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if c.dlWait > 0 {
+		waitCtx, cancel = context.WithTimeout(waitCtx, c.dlWait)
+		defer cancel()
+	}
+
+	if !c.json {
+		log.Printf("No Stream stores advisories for %s > %s, waiting for new max delivery advisories, interrupt to stop", c.stream, c.consumer)
+	}
+
+	<-waitCtx.Done()
+
+	return nil
+}
+
+// resolveDeadLetter loads the referenced message from the stream
+func (c *consumerCmd) resolveDeadLetter(stream *jsm.Stream, letter *deadLetter) {
+	msg, err := stream.ReadMessage(letter.Sequence)
+	if err != nil {
+		letter.Error = err.Error()
+		return
+	}
+
+	letter.Subject = msg.Subject
+	letter.Data = msg.Data
+
+	if len(msg.Header) > 0 {
+		hdrs, err := decodeHeadersMsg(msg.Header)
+		if err == nil {
+			letter.Headers = hdrs
+		}
+	}
+}
+
+func (c *consumerCmd) renderDeadLetters(letters []*deadLetter) {
+	if len(letters) == 0 {
+		fmt.Printf("No messages exceeded the maximum deliveries of %s > %s\n", c.stream, c.consumer)
+		return
+	}
+
+	table := newTableWriter(fmt.Sprintf("Dead letters for %s > %s", c.stream, c.consumer))
+	table.AddHeaders("Sequence", "Deliveries", "Advised", "Subject", "Size", "Data")
+
+	for _, letter := range letters {
+		if letter.Error != "" {
+			table.AddRow(letter.Sequence, f(letter.Deliveries), f(letter.Advised), "", "", letter.Error)
+			continue
+		}
+
+		table.AddRow(letter.Sequence, f(letter.Deliveries), f(letter.Advised), letter.Subject, fiBytes(uint64(len(letter.Data))), deadLetterPreview(letter.Data, 40))
+	}
+
+	fmt.Println(table.Render())
+}
+
+// deadLetterPreview shows the first length bytes of data on a single line
+func deadLetterPreview(data []byte, length int) string {
+	preview := strings.Join(strings.Fields(string(data)), " ")
+	if len(preview) > length {
+		preview = preview[:length-3] + "..."
+	}
+
+	return preview
+}
+
+func (c *consumerCmd) actOnDeadLetters(stream *jsm.Stream, letters []*deadLetter) error {
+	var actions []string
+	if c.dlRequeue != "" {
+		actions = append(actions, fmt.Sprintf("requeue to %s", c.dlRequeue))
+	}
+	if c.term {
+		actions = append(actions, fmt.Sprintf("remove from Stream %s", c.stream))
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really %s %d messages", strings.Join(actions, " and "), len(letters)), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	var requeued, removed int
+	for _, letter := range letters {
+		if letter.Error != "" {
+			continue
+		}
+
+		if c.dlRequeue != "" {
+			msg := nats.NewMsg(c.dlRequeue)
+			msg.Header = letter.Headers
+			msg.Data = letter.Data
+
+			err := c.nc.PublishMsg(msg)
+			if err != nil {
+				return fmt.Errorf("could not requeue sequence %d: %w", letter.Sequence, err)
+			}
+			requeued++
+		}
+
+		if c.term {
+			err := stream.DeleteMessage(letter.Sequence)
+			if err != nil {
+				return fmt.Errorf("could not remove sequence %d: %w", letter.Sequence, err)
+			}
+			removed++
+		}
+	}
+
+	err := c.nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	if !c.json {
+		switch {
+		case requeued > 0 && removed > 0:
+			fmt.Printf("Requeued %d messages to %s and removed them from %s\n", requeued, c.dlRequeue, c.stream)
+		case requeued > 0:
+			fmt.Printf("Requeued %d messages to %s\n", requeued, c.dlRequeue)
+		case removed > 0:
+			fmt.Printf("Removed %d messages from %s\n", removed, c.stream)
+		}
+	}
+
+	return nil
+}
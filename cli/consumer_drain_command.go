@@ -0,0 +1,306 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+const consumerDrainBatch = 256
+
+// consumerDrainStats tracks the progress of a drain
+type consumerDrainStats struct {
+	start   time.Time
+	acked   uint64
+	skipped uint64
+	pending uint64
+}
+
+func (s *consumerDrainStats) handled() uint64 {
+	return s.acked + s.skipped
+}
+
+// eta estimates the time left to handle the remaining messages based on the rate so far
+func (s *consumerDrainStats) eta() time.Duration {
+	handled := s.handled()
+	if handled == 0 || s.pending == 0 {
+		return 0
+	}
+
+	return time.Duration(float64(time.Since(s.start)) / float64(handled) * float64(s.pending)).Round(time.Second)
+}
+
+func (c *consumerCmd) drainAction(_ *fisk.ParseContext) error {
+	c.connectAndSetup(true, true, nats.UseOldRequestStyle())
+
+	cons := c.selectedConsumer
+
+	if c.drainRate < 0 {
+		return fmt.Errorf("rate can not be negative")
+	}
+
+	if c.drainFilter != "" {
+		err := c.checkDrainFilter(cons)
+		if err != nil {
+			return err
+		}
+	}
+
+	state, err := cons.State()
+	if err != nil {
+		return err
+	}
+
+	if state.NumPending == 0 {
+		fmt.Printf("Consumer %s > %s has no pending messages\n", c.stream, c.consumer)
+		return nil
+	}
+
+	if !c.force {
+		msg := fmt.Sprintf("Really acknowledge %s pending messages on Consumer %s > %s without processing them", f(state.NumPending), c.stream, c.consumer)
+		if c.drainFilter != "" {
+			msg = fmt.Sprintf("Really acknowledge pending messages matching %s on Consumer %s > %s without processing them", c.drainFilter, c.stream, c.consumer)
+		}
+
+		ok, err := askConfirmation(msg, false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	dctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	stats := &consumerDrainStats{start: time.Now(), pending: state.NumPending}
+
+	var progress *uiprogress.Progress
+	var bar *uiprogress.Bar
+	if c.showProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(int(state.NumPending)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		}).AppendFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("ETA %v", stats.eta())
+		})
+		progress.Start()
+	}
+
+	err = c.drainConsumer(dctx, cons, stats, func() {
+		if bar != nil {
+			bar.Set(bar.Total - int(stats.pending))
+		}
+	})
+
+	if progress != nil {
+		time.Sleep(250 * time.Millisecond) // let it draw
+		progress.Stop()
+		fmt.Println()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	took := time.Since(stats.start)
+	rate := float64(stats.handled()) / took.Seconds()
+
+	if c.drainFilter != "" {
+		fmt.Printf("Acknowledged %s messages matching %s and released %s other messages from Consumer %s > %s in %v, %s messages/sec\n", f(stats.acked), c.drainFilter, f(stats.skipped), c.stream, c.consumer, took.Round(time.Millisecond), f(int64(rate)))
+	} else {
+		fmt.Printf("Acknowledged %s messages from Consumer %s > %s in %v, %s messages/sec\n", f(stats.acked), c.stream, c.consumer, took.Round(time.Millisecond), f(int64(rate)))
+	}
+
+	if stats.pending > 0 {
+		fmt.Printf("Drain was interrupted with %s messages still pending\n", f(stats.pending))
+	}
+
+	return nil
+}
+
+// checkDrainFilter ensures only matching messages can be acknowledged, other messages are released back to the Consumer
+func (c *consumerCmd) checkDrainFilter(cons *jsm.Consumer) error {
+	if cons.AckPolicy() != api.AckExplicit {
+		return fmt.Errorf("draining messages matching a subject requires a Consumer with the explicit acknowledgement policy")
+	}
+
+	filters := cons.FilterSubjects()
+	if cons.FilterSubject() != "" {
+		filters = append(filters, cons.FilterSubject())
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	for _, filter := range filters {
+		if server.SubjectsCollide(filter, c.drainFilter) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("subject %s does not match any of the Consumer filter subjects", c.drainFilter)
+}
+
+// drainConsumer receives and acknowledges messages until the Consumer has no more pending messages
+func (c *consumerCmd) drainConsumer(dctx context.Context, cons *jsm.Consumer, stats *consumerDrainStats, progress func()) error {
+	msgs := make(chan *nats.Msg, consumerDrainBatch*4)
+
+	// the handler blocks rather than using a channel subscription so a rate limited drain never drops flow control messages
+	handler := func(m *nats.Msg) {
+		select {
+		case msgs <- m:
+		case <-dctx.Done():
+		}
+	}
+
+	var sub *nats.Subscription
+	var err error
+
+	switch {
+	case cons.IsPullMode():
+		sub, err = c.nc.Subscribe(c.nc.NewRespInbox(), handler)
+	case cons.DeliverGroup() != "":
+		sub, err = c.nc.QueueSubscribe(cons.DeliverySubject(), cons.DeliverGroup(), handler)
+	case cons.IsPushMode():
+		sub, err = c.nc.Subscribe(cons.DeliverySubject(), handler)
+	default:
+		return fmt.Errorf("consumer %s > %s is in an unknown state", c.stream, c.consumer)
+	}
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// pull consumers need requests for every batch while push consumers deliver on their own
+	outstanding := 0
+	requestMore := func() error {
+		if !cons.IsPullMode() || outstanding > 0 {
+			return nil
+		}
+
+		outstanding = consumerDrainBatch
+		return c.mgr.NextMsgRequest(c.stream, c.consumer, sub.Subject, &api.JSApiConsumerGetNextRequest{Batch: consumerDrainBatch, Expires: opts().Timeout})
+	}
+
+	nak := []byte(fmt.Sprintf(`%s {"delay": %d}`, api.AckNak, cons.AckWait()))
+	idle := time.NewTimer(opts().Timeout)
+	defer idle.Stop()
+
+	for {
+		err = requestMore()
+		if err != nil {
+			return err
+		}
+
+		idle.Reset(opts().Timeout)
+
+		select {
+		case <-dctx.Done():
+			return c.nc.Flush()
+
+		case <-idle.C:
+			state, err := cons.State()
+			if err != nil {
+				return err
+			}
+
+			stats.pending = state.NumPending
+			progress()
+			if stats.pending == 0 {
+				return c.nc.Flush()
+			}
+
+			// released messages count towards the max ack pending limit until they are acknowledged by another client
+			if c.drainFilter != "" && cons.MaxAckPending() > 0 && state.NumAckPending >= cons.MaxAckPending() {
+				return fmt.Errorf("consumer %s > %s reached its maximum of %s pending acknowledgements with messages not matching %s", c.stream, c.consumer, f(cons.MaxAckPending()), c.drainFilter)
+			}
+
+			outstanding = 0
+
+		case msg := <-msgs:
+			if len(msg.Data) == 0 && msg.Header.Get("Status") != "" {
+				switch msg.Header.Get("Status") {
+				case "100":
+					stalled := msg.Header.Get("Nats-Consumer-Stalled")
+					if stalled != "" {
+						c.nc.Publish(stalled, nil)
+					} else if msg.Reply != "" {
+						msg.Respond(nil)
+					}
+				default:
+					// no messages, request timeout or other terminal status for the current pull request
+					outstanding = 0
+				}
+
+				continue
+			}
+
+			if outstanding > 0 {
+				outstanding--
+			}
+
+			meta, err := jsm.ParseJSMsgMetadata(msg)
+			if err != nil {
+				return fmt.Errorf("could not parse JetStream metadata: %w", err)
+			}
+
+			if c.drainRate > 0 {
+				next := stats.start.Add(time.Duration(stats.handled()) * time.Second / time.Duration(c.drainRate))
+				select {
+				case <-time.After(time.Until(next)):
+				case <-dctx.Done():
+					return c.nc.Flush()
+				}
+			}
+
+			switch {
+			case c.drainFilter != "" && !server.SubjectsCollide(msg.Subject, c.drainFilter):
+				err = msg.Respond(nak)
+				if meta.Delivered() == 1 {
+					stats.skipped++
+				}
+			case cons.AckPolicy() == api.AckNone:
+				stats.acked++
+			default:
+				err = msg.Respond(nil)
+				stats.acked++
+			}
+			if err != nil {
+				return fmt.Errorf("acknowledging message via subject %s failed: %w", msg.Reply, err)
+			}
+
+			stats.pending = meta.Pending()
+			progress()
+
+			if stats.pending == 0 {
+				return c.nc.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+func (c *consumerCmd) exportAllAction(_ *fisk.ParseContext) error {
+	c.connectAndSetup(true, false)
+
+	consumers, missing, err := c.mgr.Consumers(c.stream)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range missing {
+		logErrorf("Could not retrieve information for Consumer %s, it is not included in the export", name)
+	}
+
+	infos := []api.ConsumerInfo{}
+	for _, cons := range consumers {
+		nfo, err := cons.LatestState()
+		if err != nil {
+			return fmt.Errorf("could not retrieve information for Consumer %s: %w", cons.Name(), err)
+		}
+		infos = append(infos, nfo)
+	}
+
+	if c.outFile == "" {
+		return iu.PrintJSON(infos)
+	}
+
+	if !c.force {
+		_, err = os.Stat(c.outFile)
+		if !os.IsNotExist(err) {
+			ok, err := askConfirmation(fmt.Sprintf("Replace existing file %s", c.outFile), false)
+			fisk.FatalIfError(err, "could not obtain confirmation")
+
+			if !ok {
+				return nil
+			}
+		}
+	}
+
+	j, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(c.outFile, j, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %s Consumers of Stream %s to %s\n", f(len(infos)), c.stream, c.outFile)
+
+	return nil
+}
+
+func (c *consumerCmd) importAllAction(_ *fisk.ParseContext) error {
+	j, err := os.ReadFile(c.inputFile)
+	if err != nil {
+		return err
+	}
+
+	var infos []api.ConsumerInfo
+	err = json.Unmarshal(j, &infos)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", c.inputFile, err)
+	}
+
+	c.connectAndSetup(true, false)
+
+	var created, skipped, failed int
+	for _, nfo := range infos {
+		cfg := nfo.Config
+
+		if cfg.Durable == "" {
+			log.Printf("Skipping ephemeral Consumer %s", nfo.Name)
+			skipped++
+			continue
+		}
+
+		known, err := c.mgr.IsKnownConsumer(c.stream, cfg.Durable)
+		if err != nil {
+			return err
+		}
+		if known {
+			log.Printf("Skipping Consumer %s, it already exists on Stream %s", cfg.Durable, c.stream)
+			skipped++
+			continue
+		}
+
+		_, err = c.mgr.NewConsumerFromDefault(c.stream, cfg)
+		if err != nil {
+			logErrorf("Could not create Consumer %s: %v", cfg.Durable, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("Created Consumer %s\n", cfg.Durable)
+		created++
+	}
+
+	fmt.Printf("Imported %s Consumers into Stream %s, %s skipped\n", f(created), c.stream, f(skipped))
+
+	if failed > 0 {
+		return fmt.Errorf("%d Consumers could not be created", failed)
+	}
+
+	return nil
+}
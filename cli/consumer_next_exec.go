@@ -0,0 +1,103 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+// nextExecTermCode is the exit code of --exec commands that terminates the message rather than redelivering it
+const nextExecTermCode = 2
+
+// nextExecAck is the acknowledgement sent for messages processed by a command exiting with code
+func nextExecAck(code int) ([]byte, string) {
+	switch code {
+	case 0:
+		return api.AckAck, "Acknowledged"
+	case nextExecTermCode:
+		return api.AckTerm, "Terminated"
+	default:
+		return api.AckNak, "Negatively Acknowledged"
+	}
+}
+
+// execNextMsg runs the --exec command with the path to a file holding the message payload and acknowledges the
+// message based on its exit code, messages are not acknowledged when the command can not be run
+func (c *consumerCmd) execNextMsg(msg *nats.Msg) error {
+	parts, err := shellquote.Split(c.nextExec)
+	if err != nil {
+		return fmt.Errorf("could not parse command: %w", err)
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("command to run is required")
+	}
+
+	tf, err := os.CreateTemp("", "nats-msg-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tf.Name())
+
+	_, err = tf.Write(msg.Data)
+	tf.Close()
+	if err != nil {
+		return fmt.Errorf("could not write message payload: %w", err)
+	}
+
+	args := append(parts[1:], tf.Name())
+	if opts().Trace {
+		log.Printf("Executing: %s %s", parts[0], strings.Join(args, " "))
+	}
+
+	cmd := exec.Command(parts[0], args...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("NATS_MSG_SUBJECT=%s", msg.Subject))
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	code := 0
+	err = cmd.Run()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return fmt.Errorf("could not run %s: %w", parts[0], err)
+		}
+		code = exitErr.ExitCode()
+	}
+
+	ack, action := nextExecAck(code)
+	if opts().Trace {
+		log.Printf(">>> %s: %s", msg.Reply, string(ack))
+	}
+
+	err = msg.Respond(ack)
+	if err != nil {
+		return fmt.Errorf("could not acknowledge message: %w", err)
+	}
+	c.nc.Flush()
+
+	if !c.raw {
+		fmt.Printf("\n%s message after %s exited with code %d\n\n", action, parts[0], code)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// consumerPeekMsg is a message a consumer has not delivered yet
+type consumerPeekMsg struct {
+	Sequence uint64      `json:"stream_seq"`
+	Subject  string      `json:"subject"`
+	Time     time.Time   `json:"time"`
+	Header   nats.Header `json:"headers,omitempty"`
+	Data     []byte      `json:"data,omitempty"`
+}
+
+type consumerPeekReport struct {
+	Stream     string             `json:"stream"`
+	Consumer   string             `json:"consumer"`
+	NumPending uint64             `json:"num_pending"`
+	AckPending int                `json:"num_ack_pending"`
+	Messages   []*consumerPeekMsg `json:"messages"`
+}
+
+func (c *consumerCmd) peekAction(_ *fisk.ParseContext) error {
+	if c.pullCount < 1 {
+		return fmt.Errorf("count must be at least 1")
+	}
+
+	c.connectAndSetup(true, true)
+
+	state, err := c.selectedConsumer.LatestState()
+	if err != nil {
+		return err
+	}
+
+	stream, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	report, err := peekConsumer(stream, &state, c.pullCount)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return iu.PrintJSON(report)
+	}
+
+	c.renderConsumerPeek(report)
+
+	return nil
+}
+
+// peekConsumer reads the next messages a consumer will deliver from the stream without interacting with the consumer,
+// delivery counts, acknowledgements and the position of the consumer are not affected
+func peekConsumer(stream *jsm.Stream, state *api.ConsumerInfo, count int) (*consumerPeekReport, error) {
+	report := &consumerPeekReport{
+		Stream:     state.Stream,
+		Consumer:   state.Name,
+		NumPending: state.NumPending,
+		AckPending: state.NumAckPending,
+		Messages:   []*consumerPeekMsg{},
+	}
+
+	if state.NumPending == 0 {
+		return report, nil
+	}
+
+	filters := state.Config.FilterSubjects
+	if state.Config.FilterSubject != "" {
+		filters = append(filters, state.Config.FilterSubject)
+	}
+
+	sstate, err := stream.State()
+	if err != nil {
+		return nil, err
+	}
+
+	first := state.Delivered.Stream + 1
+	if sstate.FirstSeq > first {
+		first = sstate.FirstSeq
+	}
+
+	// once all pending messages are found the rest of the stream can not hold any matching ones
+	for seq := first; seq <= sstate.LastSeq && len(report.Messages) < count && uint64(len(report.Messages)) < state.NumPending; seq++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		msg, err := stream.ReadMessage(seq)
+		if jsm.IsNatsError(err, 10037) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read message %d: %w", seq, err)
+		}
+
+		if !stuckMatchesFilter(msg.Subject, filters) {
+			continue
+		}
+
+		pm := &consumerPeekMsg{Sequence: msg.Sequence, Subject: msg.Subject, Time: msg.Time, Data: msg.Data}
+		if len(msg.Header) > 0 {
+			pm.Header, err = decodeHeadersMsg(msg.Header)
+			if err != nil {
+				return nil, fmt.Errorf("could not decode headers of message %d: %w", seq, err)
+			}
+		}
+
+		report.Messages = append(report.Messages, pm)
+	}
+
+	return report, nil
+}
+
+func (c *consumerCmd) renderConsumerPeek(report *consumerPeekReport) {
+	if c.raw {
+		for _, m := range report.Messages {
+			fmt.Println(string(m.Data))
+		}
+		return
+	}
+
+	if len(report.Messages) == 0 {
+		fmt.Printf("Consumer %s > %s has no messages left to deliver\n", report.Stream, report.Consumer)
+		return
+	}
+
+	fmt.Printf("Next %s of %s messages Consumer %s > %s will deliver:\n\n", f(len(report.Messages)), f(report.NumPending), report.Stream, report.Consumer)
+
+	for i, m := range report.Messages {
+		fmt.Printf("[#%d] Stream sequence %d received %v on Subject %s\n\n", i+1, m.Sequence, m.Time, m.Subject)
+
+		if len(m.Header) > 0 {
+			fmt.Println("Headers:")
+			for k, vals := range m.Header {
+				for _, val := range vals {
+					fmt.Printf("  %s: %s\n", k, val)
+				}
+			}
+			fmt.Println()
+		}
+
+		outPutMSGBody(m.Data, nil, m.Subject, report.Stream)
+	}
+
+	if report.AckPending > 0 {
+		fmt.Printf("%s messages awaiting acknowledgement might be redelivered before these\n", f(report.AckPending))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+// consumerRateSample is the delivered and acknowledged Consumer sequences of a Consumer at a point in time
+type consumerRateSample struct {
+	delivered uint64
+	acked     uint64
+	pending   uint64
+	taken     time.Time
+}
+
+// consumerRate is the rate a sequence moved at between two samples, sequences going backwards as when a Consumer
+// was recreated have no rate
+func consumerRate(prev uint64, cur uint64, elapsed time.Duration) float64 {
+	if cur < prev || elapsed <= 0 {
+		return 0
+	}
+
+	return float64(cur-prev) / elapsed.Seconds()
+}
+
+func (c *consumerCmd) reportRatesAction(_ *fisk.ParseContext) error {
+	if c.ratesInterval <= 0 {
+		return fmt.Errorf("interval has to be greater than 0")
+	}
+	if c.ratesDuration < 0 {
+		return fmt.Errorf("duration can not be negative")
+	}
+
+	c.connectAndSetup(true, false)
+
+	// every consumer is compared with its previous sample and with its first for the average over the whole run
+	first, err := c.rateSamples()
+	if err != nil {
+		return err
+	}
+	previous := first
+
+	var deadline <-chan time.Time
+	if c.ratesDuration > 0 {
+		deadline = time.After(c.ratesDuration)
+	}
+
+	ticker := time.NewTicker(c.ratesInterval)
+	defer ticker.Stop()
+
+	fmt.Printf("Measuring the rates of %s Consumers on Stream %s every %v\n", f(len(first)), c.stream, c.ratesInterval)
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+
+		current, err := c.rateSamples()
+		if err != nil {
+			return err
+		}
+
+		clearScreen()
+		c.renderRates(first, previous, current)
+
+		previous = current
+		for name, sample := range current {
+			if _, ok := first[name]; !ok {
+				first[name] = sample
+			}
+		}
+	}
+}
+
+func (c *consumerCmd) rateSamples() (map[string]*consumerRateSample, error) {
+	consumers, _, err := c.mgr.Consumers(c.stream)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := map[string]*consumerRateSample{}
+	for _, cons := range consumers {
+		state, err := cons.LatestState()
+		if err != nil {
+			logErrorf("Could not obtain consumer state for %s: %s", cons.Name(), err)
+			continue
+		}
+
+		samples[cons.Name()] = &consumerRateSample{
+			delivered: state.Delivered.Consumer,
+			acked:     state.AckFloor.Consumer,
+			pending:   state.NumPending,
+			taken:     time.Now(),
+		}
+	}
+
+	return samples, nil
+}
+
+func (c *consumerCmd) renderRates(first map[string]*consumerRateSample, previous map[string]*consumerRateSample, current map[string]*consumerRateSample) {
+	names := mapKeys(current)
+	sort.Strings(names)
+
+	table := newTableWriter(fmt.Sprintf("Consumer rates for Stream %s at %s", c.stream, time.Now().Format(time.TimeOnly)))
+	table.AddHeaders("Consumer", "Delivered", "Delivered/sec", "Acknowledged/sec", "Average Delivered/sec", "Unprocessed")
+	for _, name := range names {
+		cur := current[name]
+
+		prev, ok := previous[name]
+		if !ok {
+			table.AddRow(name, f(cur.delivered), "new", "new", "new", f(cur.pending))
+			continue
+		}
+
+		start := first[name]
+		table.AddRow(name,
+			f(cur.delivered),
+			f(consumerRate(prev.delivered, cur.delivered, cur.taken.Sub(prev.taken))),
+			f(consumerRate(prev.acked, cur.acked, cur.taken.Sub(prev.taken))),
+			f(consumerRate(start.delivered, cur.delivered, cur.taken.Sub(start.taken))),
+			f(cur.pending),
+		)
+	}
+
+	fmt.Println(table.Render())
+}
@@ -0,0 +1,175 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// consumerResetConfig sets the delivery position of cfg from a deliver policy, a start sequence or a start time
+func consumerResetConfig(cfg api.ConsumerConfig, policy string, startSeq uint64, startTime *time.Time) (api.ConsumerConfig, error) {
+	given := 0
+	for _, set := range []bool{policy != "", startSeq > 0, startTime != nil} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return cfg, fmt.Errorf("one of deliver-policy, start-seq or start-time is required")
+	}
+
+	cfg.OptStartSeq = 0
+	cfg.OptStartTime = nil
+
+	switch {
+	case startSeq > 0:
+		cfg.DeliverPolicy = api.DeliverByStartSequence
+		cfg.OptStartSeq = startSeq
+	case startTime != nil:
+		cfg.DeliverPolicy = api.DeliverByStartTime
+		cfg.OptStartTime = startTime
+	case policy == "all":
+		cfg.DeliverPolicy = api.DeliverAll
+	case policy == "last":
+		cfg.DeliverPolicy = api.DeliverLast
+	case policy == "new":
+		cfg.DeliverPolicy = api.DeliverNew
+	case policy == "last_per_subject":
+		cfg.DeliverPolicy = api.DeliverLastPerSubject
+	default:
+		return cfg, fmt.Errorf("invalid deliver policy %q", policy)
+	}
+
+	return cfg, nil
+}
+
+// consumerResetPreview is an ephemeral pull Consumer with the same filters and delivery position as cfg, used to
+// find how many messages cfg would deliver
+func consumerResetPreview(cfg api.ConsumerConfig) api.ConsumerConfig {
+	return api.ConsumerConfig{
+		AckPolicy:         api.AckExplicit,
+		DeliverPolicy:     cfg.DeliverPolicy,
+		OptStartSeq:       cfg.OptStartSeq,
+		OptStartTime:      cfg.OptStartTime,
+		FilterSubject:     cfg.FilterSubject,
+		FilterSubjects:    cfg.FilterSubjects,
+		ReplayPolicy:      api.ReplayInstant,
+		InactiveThreshold: time.Minute,
+		MemoryStorage:     true,
+	}
+}
+
+// recreateRetention is the retention policy of the stream of the selected consumer, consumers on interest streams
+// can not be deleted and created again as the server removes the messages only they have not acknowledged meanwhile
+func (c *consumerCmd) recreateRetention(action string) (api.RetentionPolicy, error) {
+	stream, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return api.LimitsPolicy, err
+	}
+
+	retention := stream.Retention()
+	if retention == api.InterestPolicy {
+		return retention, fmt.Errorf("consumers on interest Streams can not be %s, messages not acknowledged by %s are removed once it is deleted", action, c.consumer)
+	}
+
+	return retention, nil
+}
+
+func (c *consumerCmd) resetAction(_ *fisk.ParseContext) error {
+	var startTime *time.Time
+	if c.resetStartTime != "" {
+		ts, err := parseTimeString(c.resetStartTime, time.Now())
+		if err != nil {
+			return err
+		}
+		startTime = &ts
+	}
+
+	c.connectAndSetup(true, true)
+
+	if c.selectedConsumer.IsEphemeral() {
+		return fmt.Errorf("only durable consumers can be reset")
+	}
+
+	cfg, err := consumerResetConfig(c.selectedConsumer.Configuration(), c.resetPolicy, c.resetStartSeq, startTime)
+	if err != nil {
+		return err
+	}
+
+	retention, err := c.recreateRetention("reset")
+	if err != nil {
+		return err
+	}
+	if retention == api.WorkQueuePolicy && cfg.DeliverPolicy != api.DeliverAll {
+		return fmt.Errorf("consumers on work queue Streams have to deliver all messages, only --deliver-policy all can be used")
+	}
+
+	state, err := c.selectedConsumer.State()
+	if err != nil {
+		return err
+	}
+
+	prompt := fmt.Sprintf("Really reset Consumer %s > %s", c.stream, c.consumer)
+
+	// work queue streams do not allow a second consumer with overlapping filters so no preview can be made
+	if retention == api.WorkQueuePolicy {
+		fmt.Printf("Consumer %s > %s has %s unprocessed messages, acknowledged messages are removed from work queue Streams so only messages still held can be delivered again\n", c.stream, c.consumer, f(state.NumPending+uint64(state.NumAckPending)))
+	} else {
+		preview, err := c.mgr.NewConsumerFromDefault(c.stream, consumerResetPreview(cfg))
+		if err != nil {
+			return fmt.Errorf("could not determine the messages to deliver: %w", err)
+		}
+		pstate, err := preview.State()
+		preview.Delete()
+		if err != nil {
+			return fmt.Errorf("could not determine the messages to deliver: %w", err)
+		}
+
+		fmt.Printf("Consumer %s > %s has %s unprocessed messages, after resetting it will deliver %s messages\n", c.stream, c.consumer, f(state.NumPending+uint64(state.NumAckPending)), f(pstate.NumPending))
+		prompt = fmt.Sprintf("%s delivering %s messages again", prompt, f(pstate.NumPending))
+	}
+	fmt.Println()
+
+	if !c.force {
+		ok, err := askConfirmation(prompt, false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	err = c.selectedConsumer.Delete()
+	if err != nil {
+		return err
+	}
+
+	cons, err := c.mgr.NewConsumerFromDefault(c.stream, cfg)
+	if err != nil {
+		return fmt.Errorf("consumer %s was removed but could not be created again: %w", c.consumer, err)
+	}
+
+	state, err = cons.State()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Consumer %s > %s was reset and will deliver %s messages\n", c.stream, c.consumer, f(state.NumPending))
+
+	return nil
+}
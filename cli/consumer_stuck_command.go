@@ -0,0 +1,292 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	stuckStatusNothingPending = "nothing pending"
+	stuckStatusYoung          = "pending but young"
+	stuckStatusStuck          = "stuck"
+)
+
+// stuckMessage is a message past the acknowledgement floor of a consumer, Certain is false when it might have been
+// acknowledged individually already
+type stuckMessage struct {
+	Sequence uint64        `json:"stream_seq"`
+	Subject  string        `json:"subject"`
+	Stored   time.Time     `json:"stored"`
+	Age      time.Duration `json:"age"`
+	Certain  bool          `json:"certain"`
+	Action   string        `json:"action,omitempty"`
+}
+
+type stuckReport struct {
+	Stream      string          `json:"stream"`
+	Consumer    string          `json:"consumer"`
+	Status      string          `json:"status"`
+	OlderThan   time.Duration   `json:"older_than"`
+	AckPending  int             `json:"num_ack_pending"`
+	Redelivered int             `json:"num_redelivered"`
+	AckFloor    uint64          `json:"ack_floor_stream_seq"`
+	Delivered   uint64          `json:"delivered_stream_seq"`
+	Inspected   uint64          `json:"inspected"`
+	Complete    bool            `json:"complete"`
+	Candidates  int             `json:"candidates"`
+	Stuck       []*stuckMessage `json:"stuck"`
+}
+
+func (c *consumerCmd) stuckAction(_ *fisk.ParseContext) error {
+	switch {
+	case c.stuckOlderThan <= 0:
+		return fmt.Errorf("older-than must be positive")
+	case c.stuckMaxInspect < 1:
+		return fmt.Errorf("max-inspect must be at least 1")
+	case c.term && c.nak:
+		return fmt.Errorf("only one of term or nak can be used")
+	case (c.term || c.nak) && c.json && !c.force:
+		return fmt.Errorf("acting on messages with --json requires --force")
+	}
+
+	c.connectAndSetup(true, true)
+
+	state, err := c.selectedConsumer.LatestState()
+	if err != nil {
+		return err
+	}
+
+	if (c.term || c.nak) && state.Config.AckPolicy != api.AckExplicit {
+		return fmt.Errorf("term and nak require a Consumer using the explicit acknowledgement policy")
+	}
+
+	stream, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.findStuckMessages(stream, &state)
+	if err != nil {
+		return err
+	}
+
+	if !c.json {
+		c.renderStuckReport(report)
+	}
+
+	if (c.term || c.nak) && len(report.Stuck) > 0 {
+		err = c.actOnStuckMessages(report, &state)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		return iu.PrintJSON(report)
+	}
+
+	return nil
+}
+
+// findStuckMessages inspects the messages between the acknowledgement floor and the last delivered message, JetStream
+// does not expose the pending set so messages past the first might already be acknowledged unless the number of
+// matching messages equals the number pending
+func (c *consumerCmd) findStuckMessages(stream *jsm.Stream, state *api.ConsumerInfo) (*stuckReport, error) {
+	report := &stuckReport{
+		Stream:      state.Stream,
+		Consumer:    state.Name,
+		OlderThan:   c.stuckOlderThan,
+		AckPending:  state.NumAckPending,
+		Redelivered: state.NumRedelivered,
+		AckFloor:    state.AckFloor.Stream,
+		Delivered:   state.Delivered.Stream,
+		Complete:    true,
+		Stuck:       []*stuckMessage{},
+	}
+
+	if state.Config.AckPolicy == api.AckNone || state.NumAckPending == 0 || report.Delivered <= report.AckFloor {
+		report.Status = stuckStatusNothingPending
+		return report, nil
+	}
+
+	filters := state.Config.FilterSubjects
+	if state.Config.FilterSubject != "" {
+		filters = append(filters, state.Config.FilterSubject)
+	}
+
+	now := state.TimeStamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	// messages removed from the stream, for example by a purge, can not be pending
+	first := report.AckFloor + 1
+	sstate, err := stream.State()
+	if err != nil {
+		return nil, err
+	}
+	if sstate.FirstSeq > first {
+		first = sstate.FirstSeq
+	}
+
+	last := report.Delivered
+	if last >= first && last-first >= uint64(c.stuckMaxInspect) {
+		last = first + uint64(c.stuckMaxInspect) - 1
+		report.Complete = false
+	}
+
+	var found []*stuckMessage
+	for seq := first; seq <= last; seq++ {
+		if ctx.Err() != nil {
+			report.Complete = false
+			break
+		}
+
+		msg, err := stream.ReadMessage(seq)
+		report.Inspected++
+		if jsm.IsNatsError(err, 10037) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read message %d: %w", seq, err)
+		}
+
+		if !stuckMatchesFilter(msg.Subject, filters) {
+			continue
+		}
+
+		found = append(found, &stuckMessage{
+			Sequence: seq,
+			Subject:  msg.Subject,
+			Stored:   msg.Time,
+			Age:      now.Sub(msg.Time),
+			Certain:  len(found) == 0,
+		})
+	}
+
+	report.Candidates = len(found)
+	allPending := report.Complete && report.Candidates == report.AckPending
+
+	for _, m := range found {
+		if allPending {
+			m.Certain = true
+		}
+		if m.Age > c.stuckOlderThan {
+			report.Stuck = append(report.Stuck, m)
+		}
+	}
+
+	if len(report.Stuck) == 0 {
+		report.Status = stuckStatusYoung
+	} else {
+		report.Status = stuckStatusStuck
+	}
+
+	return report, nil
+}
+
+func stuckMatchesFilter(subject string, filters []string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+
+	for _, filter := range filters {
+		if api.SubjectIsSubsetMatch(subject, filter) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// actOnStuckMessages terminates or negatively acknowledges the stuck messages, the server ignores acknowledgements
+// for messages that are not pending so uncertain messages are safe to act on
+func (c *consumerCmd) actOnStuckMessages(report *stuckReport, state *api.ConsumerInfo) error {
+	action, body := "Terminate", "+TERM"
+	if c.nak {
+		action, body = "Redeliver", "-NAK"
+	}
+
+	for _, m := range report.Stuck {
+		if !c.force {
+			ok, err := askConfirmation(fmt.Sprintf("%s message %d on subject %s", action, m.Sequence, m.Subject), false)
+			fisk.FatalIfError(err, "could not obtain confirmation")
+
+			if !ok {
+				continue
+			}
+		}
+
+		// the server uses the pending record for explicitly acknowledged consumers, the delivery sequence only needs
+		// to be within the delivered range
+		subj := fmt.Sprintf("$JS.ACK.%s.%s.1.%d.%d.%d.0", state.Stream, state.Name, m.Sequence, state.Delivered.Consumer, time.Now().UnixNano())
+		_, err := c.nc.Request(subj, []byte(body), opts().Timeout)
+		if err != nil {
+			return fmt.Errorf("could not %s message %d: %w", strings.ToLower(action), m.Sequence, err)
+		}
+
+		if c.nak {
+			m.Action = "nak"
+		} else {
+			m.Action = "term"
+		}
+
+		if !c.json {
+			fmt.Printf("Sent %s for message %d\n", body, m.Sequence)
+		}
+	}
+
+	return nil
+}
+
+func (c *consumerCmd) renderStuckReport(report *stuckReport) {
+	if report.Status == stuckStatusNothingPending {
+		fmt.Printf("Consumer %s > %s has no messages awaiting acknowledgement\n", report.Stream, report.Consumer)
+		return
+	}
+
+	fmt.Printf("Consumer %s > %s has %s messages awaiting acknowledgement between Stream sequence %s and %s, %s were redelivered\n", report.Stream, report.Consumer, f(report.AckPending), f(report.AckFloor+1), f(report.Delivered), f(report.Redelivered))
+	if !report.Complete {
+		fmt.Printf("Only the first %s messages past the acknowledgement floor were inspected\n", f(report.Inspected))
+	}
+	fmt.Println()
+
+	if report.Status == stuckStatusYoung {
+		fmt.Printf("None of the %s messages past the acknowledgement floor were stored more than %s ago\n", f(report.Candidates), f(report.OlderThan))
+		return
+	}
+
+	table := newTableWriter(fmt.Sprintf("Messages awaiting acknowledgement stored more than %s ago", f(report.OlderThan)))
+	table.AddHeaders("Stream Sequence", "Subject", "Stored", "Age", "Pending")
+	for _, m := range report.Stuck {
+		pending := "possibly"
+		if m.Certain {
+			pending = "yes"
+		}
+		table.AddRow(f(m.Sequence), m.Subject, f(m.Stored), f(m.Age.Round(time.Second)), pending)
+	}
+	fmt.Println(table.Render())
+
+	fmt.Println("JetStream does not report delivery counts or times of individual messages, a message can not have been")
+	fmt.Println("delivered before it was stored. Messages marked possibly pending might have been acknowledged already.")
+	fmt.Println()
+}
@@ -23,6 +23,7 @@ import (
 	"sort"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
@@ -43,6 +44,8 @@ type ctxCommand struct {
 	nsc              string
 	force            bool
 	validateErrors   int
+	all              bool
+	reveal           bool
 }
 
 func configureCtxCommand(app commandHost) {
@@ -85,10 +88,12 @@ func configureCtxCommand(app commandHost) {
 	info.Arg("name", "The context name to show").StringVar(&c.name)
 	info.Flag("json", "Show the context in JSON format").Short('j').UnNegatableBoolVar(&c.json)
 	info.Flag("connect", "Attempts to connect to NATS using the context while validating").UnNegatableBoolVar(&c.activate)
+	info.Flag("reveal-password", "Shows passwords and tokens instead of masking them").UnNegatableBoolVar(&c.reveal)
+	info.Flag("force", "Reveal passwords without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
-	validate := context.Command("validate", "Validate one or all contexts").Action(c.validateCommand)
+	validate := context.Command("validate", "Verify that one or all contexts are valid and can reach NATS").Action(c.validateCommand)
 	validate.Arg("name", "Validate a specific context, validates all when not supplied").StringVar(&c.name)
-	validate.Flag("connect", "Attempts to connect to NATS using the context while validating").UnNegatableBoolVar(&c.activate)
+	validate.Flag("all", "Validate all known contexts, the default when no name is supplied").UnNegatableBoolVar(&c.all)
 
 	context.Command("previous", "switch to the previous context").Alias("-").Action(c.switchPreviousCtx)
 }
@@ -111,26 +116,109 @@ func (c *ctxCommand) overrideVars() []string {
 
 	return list
 }
-func (c *ctxCommand) validateCommand(pc *fisk.ParseContext) error {
+func (c *ctxCommand) validateCommand(_ *fisk.ParseContext) error {
 	var contexts []string
-	if c.name == "" {
-		contexts = natscontext.KnownContexts()
-	} else {
+	switch {
+	case c.all && c.name != "":
+		return fmt.Errorf("a context name can not be combined with --all")
+	case c.name != "":
 		contexts = append(contexts, c.name)
+	default:
+		contexts = natscontext.KnownContexts()
+	}
+
+	if len(contexts) == 0 {
+		return fmt.Errorf("no contexts found")
 	}
 
+	failed := 0
 	for _, name := range contexts {
-		c.name = name
-		err := c.showCommand(pc)
+		rtt, err := c.validateContext(name)
 		if err != nil {
-			fmt.Printf("Could not load %s: %s\n\n", name, color.RedString(err.Error()))
+			failed++
+			fmt.Printf("%s %s: %s\n", color.RedString("FAIL"), name, err)
+			continue
 		}
 
+		fmt.Printf("%s   %s: %s\n", color.GreenString("OK"), name, f(rtt))
+	}
+
+	if c.hasOverrides() {
+		fmt.Println()
+		fmt.Printf("%s: Shell environment overrides in place using %v", color.HiRedString("WARNING"), f(c.overrideVars()))
 		fmt.Println()
 	}
 
-	if c.validateErrors > 0 {
-		return fmt.Errorf("validation failed")
+	if failed > 0 {
+		return fmt.Errorf("validation failed for %d of %d contexts", failed, len(contexts))
+	}
+
+	return nil
+}
+
+// validateContext checks the files and settings of the named context, connects using it and performs a publish and
+// subscribe round trip
+func (c *ctxCommand) validateContext(name string) (time.Duration, error) {
+	cfg, err := natscontext.New(name, true)
+	if err != nil {
+		return 0, err
+	}
+
+	err = validateContextSettings(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	copts, err := cfg.NATSOptions()
+	if err != nil {
+		return 0, err
+	}
+	copts = append(copts, nats.MaxReconnects(1), nats.Timeout(opts().Timeout))
+
+	nc, err := nats.Connect(cfg.ServerURL(), copts...)
+	if err != nil {
+		return 0, err
+	}
+	defer nc.Close()
+
+	start := time.Now()
+	err = pubSubRoundTrip(nc, nc.NewRespInbox(), opts().Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("publish and subscribe round trip failed: %w", err)
+	}
+
+	return time.Since(start), nil
+}
+
+// validateContextSettings checks that the files a context refers to can be read and that its JetStream settings are valid
+func validateContextSettings(cfg *natscontext.Context) error {
+	files := [][2]string{{"credentials", cfg.Creds()}, {"nkey", cfg.NKey()}, {"CA", cfg.CA()}}
+	if cfg.WindowsCertStore() == "" {
+		files = append(files, [2]string{"certificate", cfg.Certificate()}, [2]string{"key", cfg.Key()})
+	}
+
+	var problems []string
+	for _, file := range files {
+		if file[1] == "" {
+			continue
+		}
+
+		ok, err := fileAccessible(file[1])
+		switch {
+		case err != nil:
+			problems = append(problems, fmt.Sprintf("%s file %s: %s", file[0], file[1], err))
+		case !ok:
+			problems = append(problems, fmt.Sprintf("%s file %s is not accessible", file[0], file[1]))
+		}
+	}
+
+	err := validateJSTarget(cfg.JSDomain(), cfg.JSAPIPrefix())
+	if err != nil {
+		problems = append(problems, "only one of JS Domain or JS API Prefix may be set")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, ", "))
 	}
 
 	return nil
@@ -405,7 +493,7 @@ func (c *ctxCommand) listCommand(_ *fisk.ParseContext) error {
 		cfg, err := natscontext.New(name, true)
 		if err != nil {
 			if !c.completionFormat {
-				log.Printf("Could not load context %s: %s", name, err)
+				logErrorf("Could not load context %s: %s", name, err)
 			}
 			continue
 		}
@@ -441,7 +529,41 @@ func (c *ctxCommand) showCommand(_ *fisk.ParseContext) error {
 		return err
 	}
 
+	if c.reveal && !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really show the passwords and tokens of context %q", c.name), false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			c.reveal = false
+		}
+	}
+
+	secret := func(s string) string {
+		if c.reveal {
+			return s
+		}
+		return strings.Repeat("*", len(s))
+	}
+
+	servers := cfg.ServerURL()
+	if !c.reveal {
+		servers = redactServers(servers)
+	}
+
 	if c.json {
+		if !c.reveal {
+			masked := []natscontext.Option{natscontext.WithPassword(secret(cfg.Password())), natscontext.WithToken(secret(cfg.Token()))}
+			if servers != cfg.ServerURL() {
+				masked = append(masked, natscontext.WithServerURL(servers))
+			}
+
+			cfg, err = natscontext.New(c.name, true, masked...)
+			if err != nil {
+				return err
+			}
+		}
+
 		iu.PrintJSON(cfg)
 		return nil
 	}
@@ -462,11 +584,11 @@ func (c *ctxCommand) showCommand(_ *fisk.ParseContext) error {
 
 	cols := newColumns(fmt.Sprintf("NATS Configuration Context %q", c.name))
 	cols.AddRowIfNotEmpty("Description", cfg.Description())
-	cols.AddRowIfNotEmpty("Server URLs", cfg.ServerURL())
+	cols.AddRowIfNotEmpty("Server URLs", servers)
 	cols.AddRowIfNotEmpty("SOCKS5 Proxy", cfg.SocksProxy())
 	cols.AddRowIfNotEmpty("Username", cfg.User())
-	cols.AddRowIfNotEmpty("Password", strings.Repeat("*", len(cfg.Password())))
-	cols.AddRowIfNotEmpty("Token", cfg.Token())
+	cols.AddRowIfNotEmpty("Password", secret(cfg.Password()))
+	cols.AddRowIfNotEmpty("Token", secret(cfg.Token()))
 	cols.AddRowIf("Credentials", fmt.Sprintf("%s (%s)", cfg.Creds(), checkFile(cfg.Creds())), cfg.Creds() != "")
 	cols.AddRowIf("NKey", fmt.Sprintf("%s (%s)", cfg.NKey(), checkFile(cfg.NKey())), cfg.NKey() != "")
 	if cfg.WindowsCertStore() == "" {
@@ -484,6 +606,10 @@ func (c *ctxCommand) showCommand(_ *fisk.ParseContext) error {
 	cols.AddRowIfNotEmpty("JS API Prefix", cfg.JSAPIPrefix())
 	cols.AddRowIfNotEmpty("JS Event Prefix", cfg.JSEventPrefix())
 	cols.AddRowIfNotEmpty("JS Domain", cfg.JSDomain())
+	if validateJSTarget(cfg.JSDomain(), cfg.JSAPIPrefix()) != nil {
+		c.validateErrors++
+		cols.AddRow("JS Target", color.RedString("ERROR: only one of JS Domain or JS API Prefix may be set"))
+	}
 	cols.AddRowIfNotEmpty("Inbox Prefix", cfg.InboxPrefix())
 	cols.AddRowIfNotEmpty("Path", cfg.Path())
 	cols.AddRowIfNotEmpty("Color Scheme", cfg.ColorScheme())
@@ -576,6 +702,11 @@ func (c *ctxCommand) createCommand(pc *fisk.ParseContext) error {
 		return err
 	}
 
+	err = validateJSTarget(config.JSDomain(), config.JSAPIPrefix())
+	if err != nil {
+		return err
+	}
+
 	err = config.Save(c.name)
 	if err != nil {
 		return err
@@ -0,0 +1,441 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	doctorPass = "PASS"
+	doctorWarn = "WARN"
+	doctorFail = "FAIL"
+	doctorSkip = "SKIP"
+
+	// certificates expiring sooner than this are reported as warnings
+	doctorCertExpiryWarning = 30 * 24 * time.Hour
+)
+
+type doctorCmd struct {
+	json bool
+}
+
+type doctorCheck struct {
+	Name     string        `json:"name"`
+	Target   string        `json:"target,omitempty"`
+	Status   string        `json:"status"`
+	Detail   string        `json:"detail,omitempty"`
+	Hint     string        `json:"hint,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+type doctorReport struct {
+	Time    time.Time      `json:"time"`
+	Context string         `json:"context,omitempty"`
+	Servers string         `json:"servers"`
+	Checks  []*doctorCheck `json:"checks"`
+	Failed  int            `json:"failed"`
+}
+
+func configureDoctorCommand(app commandHost) {
+	c := &doctorCmd{}
+
+	help := `Diagnoses connectivity and configuration problems
+
+Performs a series of checks against the selected context covering
+name resolution, TCP connectivity, TLS, authentication, round trip
+times, publish and subscribe permissions and JetStream availability.
+
+Exits with a non zero status if any check fails`
+
+	doctor := app.Command("doctor", help).Action(c.doctorAction)
+	doctor.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+	addCheat("doctor", doctor)
+}
+
+func init() {
+	registerCommand("doctor", 6, configureDoctorCommand)
+}
+
+func (c *doctorCmd) doctorAction(_ *fisk.ParseContext) error {
+	report := &doctorReport{Time: time.Now().UTC()}
+
+	if opts().Config == nil {
+		err := loadContext(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	report.Context = opts().Config.Name
+	report.Servers = opts().Config.ServerURL()
+
+	if !c.json {
+		ctxName := report.Context
+		if ctxName == "" {
+			ctxName = "none"
+		}
+		fmt.Printf("Diagnosing connectivity to %s using context %s\n\n", report.Servers, ctxName)
+	}
+
+	for _, s := range strings.Split(report.Servers, ",") {
+		c.checkServer(report, strings.TrimSpace(s))
+	}
+
+	nc := c.checkConnect(report)
+	if nc != nil {
+		c.checkRTT(report, nc)
+		c.checkPubSub(report, nc)
+		c.checkJetStream(report)
+	} else {
+		for _, name := range []string{"Round trip time", "Publish and Subscribe", "JetStream"} {
+			c.record(report, &doctorCheck{Name: name, Status: doctorSkip, Detail: "not connected"})
+		}
+	}
+
+	if c.json {
+		iu.PrintJSON(report)
+	} else {
+		fmt.Println()
+		if report.Failed == 0 {
+			fmt.Printf("All %d checks passed\n", len(report.Checks))
+		}
+	}
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", report.Failed, len(report.Checks))
+	}
+
+	return nil
+}
+
+// record adds a check to the report and shows it when not producing JSON
+func (c *doctorCmd) record(report *doctorReport, check *doctorCheck) {
+	report.Checks = append(report.Checks, check)
+	if check.Status == doctorFail {
+		report.Failed++
+	}
+
+	if c.json {
+		return
+	}
+
+	var status string
+	switch check.Status {
+	case doctorPass:
+		status = color.GreenString(check.Status)
+	case doctorWarn:
+		status = color.YellowString(check.Status)
+	case doctorFail:
+		status = color.RedString(check.Status)
+	default:
+		status = check.Status
+	}
+
+	name := check.Name
+	if check.Target != "" {
+		name = fmt.Sprintf("%s %s", check.Name, check.Target)
+	}
+
+	if check.Detail != "" {
+		fmt.Printf("%s %s: %s\n", status, name, check.Detail)
+	} else {
+		fmt.Printf("%s %s\n", status, name)
+	}
+
+	if check.Hint != "" {
+		fmt.Printf("     hint: %s\n", check.Hint)
+	}
+}
+
+// checkServer resolves a server url and checks TCP and TLS connectivity to each of its addresses
+func (c *doctorCmd) checkServer(report *doctorReport, server string) {
+	if !strings.Contains(server, "://") {
+		server = fmt.Sprintf("nats://%s", server)
+	}
+
+	u, err := url.Parse(server)
+	if err != nil {
+		c.record(report, &doctorCheck{Name: "Server URL", Target: server, Status: doctorFail, Detail: err.Error(), Hint: "server URLs should be in the form nats://host:port"})
+		return
+	}
+
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "ws":
+			port = "80"
+		case "wss":
+			port = "443"
+		default:
+			port = "4222"
+		}
+	}
+
+	addrs := []string{u.Hostname()}
+	if net.ParseIP(u.Hostname()) == nil {
+		start := time.Now()
+		addrs, err = net.LookupHost(u.Hostname())
+		check := &doctorCheck{Name: "DNS lookup", Target: u.Hostname(), Duration: time.Since(start)}
+		if err != nil || len(addrs) == 0 {
+			check.Status = doctorFail
+			check.Detail = fmt.Sprintf("could not resolve: %v", err)
+			check.Hint = "check the server name in the context and the DNS configuration of this host"
+			c.record(report, check)
+			return
+		}
+
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("resolved to %s", strings.Join(addrs, ", "))
+		c.record(report, check)
+	}
+
+	for _, addr := range addrs {
+		c.checkAddress(report, u, net.JoinHostPort(addr, port))
+	}
+}
+
+func (c *doctorCmd) checkAddress(report *doctorReport, u *url.URL, addr string) {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", addr, opts().Timeout)
+	check := &doctorCheck{Name: "TCP connect", Target: addr, Duration: time.Since(start)}
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Hint = "ensure the server is running and that no firewall blocks the port"
+		c.record(report, check)
+		return
+	}
+	defer conn.Close()
+
+	check.Status = doctorPass
+	check.Detail = fmt.Sprintf("connected in %v", check.Duration.Round(time.Millisecond))
+	c.record(report, check)
+
+	if strings.HasPrefix(u.Scheme, "ws") {
+		return
+	}
+
+	tlsFirst := opts().TlsFirst || opts().Config.TLSHandshakeFirst()
+	tlsRequired := tlsFirst || u.Scheme == "tls"
+
+	if !tlsFirst {
+		conn.SetReadDeadline(time.Now().Add(opts().Timeout))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err == nil && !strings.HasPrefix(line, "INFO ") {
+			err = fmt.Errorf("unexpected greeting %q", strings.TrimSpace(line))
+		}
+		if err != nil {
+			c.record(report, &doctorCheck{Name: "Server greeting", Target: addr, Status: doctorFail, Detail: fmt.Sprintf("did not receive INFO from the server: %v", err), Hint: "ensure the port belongs to a NATS server client listener"})
+			return
+		}
+
+		var info struct {
+			TLSRequired bool `json:"tls_required"`
+		}
+		json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info)
+		tlsRequired = tlsRequired || info.TLSRequired
+	}
+
+	if tlsRequired {
+		c.checkTLS(report, conn, u.Hostname(), addr)
+	}
+}
+
+func (c *doctorCmd) checkTLS(report *doctorReport, conn net.Conn, host string, addr string) {
+	check := &doctorCheck{Name: "TLS handshake", Target: addr}
+
+	tlsc, err := c.tlsConfig(host)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Hint = "check the TLS certificate, key and CA files configured in the context"
+		c.record(report, check)
+		return
+	}
+
+	start := time.Now()
+	tconn := tls.Client(conn, tlsc)
+	tconn.SetDeadline(time.Now().Add(opts().Timeout))
+	err = tconn.Handshake()
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Hint = "ensure the CA trusts the server certificate and that the certificate is valid for the server name"
+		c.record(report, check)
+		return
+	}
+
+	state := tconn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		check.Status = doctorPass
+		c.record(report, check)
+		return
+	}
+
+	cert := state.PeerCertificates[0]
+	remaining := time.Until(cert.NotAfter)
+	check.Detail = fmt.Sprintf("certificate %s expires %s (%s)", cert.Subject.CommonName, cert.NotAfter.Format(time.RFC3339), f(remaining))
+
+	if remaining < doctorCertExpiryWarning {
+		check.Status = doctorWarn
+		check.Hint = "renew the server certificate before it expires"
+	} else {
+		check.Status = doctorPass
+	}
+
+	c.record(report, check)
+}
+
+func (c *doctorCmd) tlsConfig(host string) (*tls.Config, error) {
+	tlsc := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: host}
+
+	cfg := opts().Config
+	if cfg.CA() != "" {
+		pem, err := os.ReadFile(cfg.CA())
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("could not parse CA %s", cfg.CA())
+		}
+		tlsc.RootCAs = pool
+	}
+
+	if cfg.Certificate() != "" && cfg.Key() != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.Certificate(), cfg.Key())
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsc.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsc, nil
+}
+
+func (c *doctorCmd) checkConnect(report *doctorReport) *nats.Conn {
+	start := time.Now()
+	nc, err := newNatsConn("", natsOpts()...)
+	check := &doctorCheck{Name: "Authentication", Duration: time.Since(start)}
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+
+		switch {
+		case errors.Is(err, nats.ErrAuthorization), errors.Is(err, nats.ErrAuthExpired), errors.Is(err, nats.ErrAuthRevoked):
+			check.Hint = "check the user, password, token, nkey or credentials configured in the context"
+		case errors.Is(err, nats.ErrNoServers):
+			check.Hint = "no server could be reached, see the connectivity checks above"
+		default:
+			check.Hint = "verify the context settings using 'nats context info'"
+		}
+
+		c.record(report, check)
+		return nil
+	}
+
+	check.Status = doctorPass
+	check.Target = nc.ConnectedUrlRedacted()
+	check.Detail = fmt.Sprintf("connected to %s version %s", nc.ConnectedServerName(), nc.ConnectedServerVersion())
+	c.record(report, check)
+
+	return nc
+}
+
+func (c *doctorCmd) checkRTT(report *doctorReport, nc *nats.Conn) {
+	rtt, err := nc.RTT()
+	check := &doctorCheck{Name: "Round trip time", Duration: rtt}
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Hint = "the connection is unstable, check network latency and packet loss"
+	} else {
+		check.Status = doctorPass
+		check.Detail = rtt.String()
+	}
+
+	c.record(report, check)
+}
+
+func (c *doctorCmd) checkPubSub(report *doctorReport, nc *nats.Conn) {
+	subj := nc.NewRespInbox()
+	check := &doctorCheck{Name: "Publish and Subscribe", Target: subj}
+
+	start := time.Now()
+	err := pubSubRoundTrip(nc, subj, opts().Timeout)
+	check.Duration = time.Since(start)
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+		check.Hint = "ensure the user may publish and subscribe to the inbox subjects, by default _INBOX.>"
+	} else {
+		check.Status = doctorPass
+		check.Detail = fmt.Sprintf("message received in %v", check.Duration.Round(time.Microsecond))
+	}
+
+	c.record(report, check)
+}
+
+func (c *doctorCmd) checkJetStream(report *doctorReport) {
+	check := &doctorCheck{Name: "JetStream"}
+
+	start := time.Now()
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err == nil {
+		var info *api.JetStreamAccountStats
+		info, err = mgr.JetStreamAccountInfo()
+		if err == nil {
+			check.Status = doctorPass
+			check.Detail = fmt.Sprintf("%s streams and %s consumers using %s memory and %s file storage", f(info.Streams), f(info.Consumers), fiBytes(info.Memory), fiBytes(info.Store))
+		}
+	}
+	check.Duration = time.Since(start)
+
+	if err != nil {
+		check.Status = doctorFail
+		check.Detail = err.Error()
+
+		switch {
+		case errors.Is(err, nats.ErrNoResponders), errors.Is(err, nats.ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+			check.Hint = "JetStream is not enabled on the server or not available in this domain"
+		case jsm.IsNatsError(err, 10039):
+			check.Hint = "JetStream is not enabled for this account"
+		default:
+			check.Hint = "check the JetStream domain and API prefix settings in the context"
+		}
+	}
+
+	c.record(report, check)
+}
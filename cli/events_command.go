@@ -74,22 +74,17 @@ func (c *eventsCmd) handleNATSEvent(m *nats.Msg) {
 	}
 
 	handle := func() error {
-		kind, event, err := api.ParseMessage(m.Data)
+		kind, ne, err := parseRegisteredEvent(m.Data)
 		if err != nil {
-			return fmt.Errorf("parsing failed: %s", err)
+			return err
 		}
 
 		if opts().Trace {
 			log.Printf("Received %s event on subject %s", kind, m.Subject)
 		}
 
-		if kind == "io.nats.unknown_message" {
-			return fmt.Errorf("unknown event schema on subject %s", m.Subject)
-		}
-
-		ne, ok := event.(api.Event)
-		if !ok {
-			return fmt.Errorf("event %q does not implement the Event interface", kind)
+		if ne == nil {
+			return fmt.Errorf("unknown event schema %s on subject %s", kind, m.Subject)
 		}
 
 		var format api.RenderFormat
@@ -0,0 +1,145 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// gapHistogramBuckets are the upper bounds of the inter-arrival gap buckets
+var gapHistogramBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// gapStats records the time between consecutive messages
+type gapStats struct {
+	last     time.Time
+	gaps     []time.Duration
+	messages uint64
+	mu       sync.Mutex
+}
+
+// gapHistogramBucket is a bucket in a gap report, UpTo is 0 for the overflow bucket
+type gapHistogramBucket struct {
+	Label string        `json:"label"`
+	UpTo  time.Duration `json:"up_to,omitempty"`
+	Count int           `json:"count"`
+}
+
+type gapStatsReport struct {
+	Messages uint64                `json:"messages"`
+	Gaps     int                   `json:"gaps"`
+	Min      time.Duration         `json:"min"`
+	Mean     time.Duration         `json:"mean"`
+	Max      time.Duration         `json:"max"`
+	P95      time.Duration         `json:"p95"`
+	P99      time.Duration         `json:"p99"`
+	Buckets  []*gapHistogramBucket `json:"buckets,omitempty"`
+}
+
+func newGapStats() *gapStats {
+	return &gapStats{}
+}
+
+// observe records a message received at now, gaps are measured from the previous message
+func (g *gapStats) observe(now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.messages++
+
+	if !g.last.IsZero() {
+		gap := now.Sub(g.last)
+		if gap < 0 {
+			gap = 0
+		}
+		g.gaps = append(g.gaps, gap)
+	}
+
+	g.last = now
+}
+
+// report calculates the statistics of all gaps seen so far
+func (g *gapStats) report() *gapStatsReport {
+	g.mu.Lock()
+	gaps := append([]time.Duration{}, g.gaps...)
+	report := &gapStatsReport{Messages: g.messages, Gaps: len(gaps)}
+	g.mu.Unlock()
+
+	if len(gaps) == 0 {
+		return report
+	}
+
+	report.Mean = benchKVAverage(gaps)
+	report.P95 = benchKVPercentile(gaps, 95)
+	report.P99 = benchKVPercentile(gaps, 99)
+	// calculating percentiles sorted the gaps in place
+	report.Min = gaps[0]
+	report.Max = gaps[len(gaps)-1]
+
+	counts := make([]int, len(gapHistogramBuckets)+1)
+	for _, gap := range gaps {
+		i := 0
+		for i < len(gapHistogramBuckets) && gap > gapHistogramBuckets[i] {
+			i++
+		}
+		counts[i]++
+	}
+
+	for i, count := range counts {
+		bucket := &gapHistogramBucket{Count: count}
+		if i < len(gapHistogramBuckets) {
+			bucket.UpTo = gapHistogramBuckets[i]
+			bucket.Label = fmt.Sprintf("<= %s", f(gapHistogramBuckets[i]))
+		} else {
+			bucket.Label = fmt.Sprintf("> %s", f(gapHistogramBuckets[len(gapHistogramBuckets)-1]))
+		}
+
+		report.Buckets = append(report.Buckets, bucket)
+	}
+
+	return report
+}
+
+func (r *gapStatsReport) render() string {
+	if r.Gaps == 0 {
+		return fmt.Sprintf("Received %s messages, at least 2 are needed to measure the gaps between them", f(r.Messages))
+	}
+
+	summary := newTableWriter(fmt.Sprintf("Gaps between %s messages", f(r.Messages)))
+	summary.AddHeaders("Minimum", "Mean", "Maximum", "p95", "p99")
+	summary.AddRow(f(r.Min), f(r.Mean), f(r.Max), f(r.P95), f(r.P99))
+
+	table := newTableWriter("Gap distribution")
+	table.AddHeaders("Gap", "Count", "Percent", "Cumulative")
+
+	cumulative := 0
+	for _, b := range r.Buckets {
+		cumulative += b.Count
+		pct := float64(b.Count) / float64(r.Gaps) * 100
+		cpct := float64(cumulative) / float64(r.Gaps) * 100
+
+		table.AddRow(b.Label, f(b.Count), fmt.Sprintf("%.1f%%", pct), fmt.Sprintf("%.1f%%", cpct))
+	}
+	table.AddFooter("Total", f(r.Gaps), "", "")
+
+	return summary.Render() + "\n" + table.Render()
+}
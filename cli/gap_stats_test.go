@@ -0,0 +1,82 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGapStats(t *testing.T) {
+	t.Run("no gaps", func(t *testing.T) {
+		g := newGapStats()
+		g.observe(time.Now())
+
+		report := g.report()
+		if report.Messages != 1 || report.Gaps != 0 || len(report.Buckets) != 0 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+
+		if !strings.Contains(report.render(), "at least 2 are needed") {
+			t.Fatalf("unexpected render: %s", report.render())
+		}
+	})
+
+	t.Run("gaps", func(t *testing.T) {
+		g := newGapStats()
+
+		now := time.Now()
+		g.observe(now)
+
+		// 100 gaps of 1ms to 100ms in a shuffled order
+		for i := 0; i < 100; i++ {
+			now = now.Add(time.Duration((i*37)%100+1) * time.Millisecond)
+			g.observe(now)
+		}
+
+		report := g.report()
+		if report.Messages != 101 || report.Gaps != 100 {
+			t.Fatalf("unexpected totals: %+v", report)
+		}
+
+		if report.Min != time.Millisecond || report.Max != 100*time.Millisecond {
+			t.Fatalf("unexpected min %v max %v", report.Min, report.Max)
+		}
+
+		if report.Mean != 50500*time.Microsecond || report.P95 != 95*time.Millisecond || report.P99 != 99*time.Millisecond {
+			t.Fatalf("unexpected mean %v p95 %v p99 %v", report.Mean, report.P95, report.P99)
+		}
+
+		if len(report.Buckets) != len(gapHistogramBuckets)+1 {
+			t.Fatalf("expected %d buckets got %d", len(gapHistogramBuckets)+1, len(report.Buckets))
+		}
+
+		expect := []int{0, 1, 9, 90, 0, 0, 0}
+		for i, b := range report.Buckets {
+			if b.Count != expect[i] {
+				t.Fatalf("bucket %s expected %d got %d", b.Label, expect[i], b.Count)
+			}
+		}
+
+		if report.Buckets[2].Label != "<= 10ms" || report.Buckets[6].Label != "> 10s" || report.Buckets[6].UpTo != 0 {
+			t.Fatalf("unexpected buckets: %+v %+v", report.Buckets[2], report.Buckets[6])
+		}
+
+		out := report.render()
+		if !strings.Contains(out, "Gaps between 101 messages") || !strings.Contains(out, "90.0%") || !strings.Contains(out, "99ms") {
+			t.Fatalf("unexpected render:\n%s", out)
+		}
+	})
+}
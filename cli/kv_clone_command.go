@@ -0,0 +1,176 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/nats.go"
+)
+
+type kvCloneStats struct {
+	start   time.Time
+	seen    int
+	copied  int
+	skipped int
+}
+
+func (s *kvCloneStats) rate() float64 {
+	return float64(s.copied) / time.Since(s.start).Seconds()
+}
+
+// kvCloneConfig creates the configuration for a new bucket matching the stream of the source bucket, republishing,
+// mirrors and sources are not copied as the clone is a standalone copy of the data
+func kvCloneConfig(bucket string, nfo *nats.StreamInfo) *nats.KeyValueConfig {
+	cfg := nfo.Config
+
+	history := cfg.MaxMsgsPerSubject
+	if history < 1 {
+		history = 1
+	}
+
+	return &nats.KeyValueConfig{
+		Bucket:       bucket,
+		Description:  cfg.Description,
+		MaxValueSize: cfg.MaxMsgSize,
+		History:      uint8(history),
+		TTL:          cfg.MaxAge,
+		MaxBytes:     cfg.MaxBytes,
+		Storage:      cfg.Storage,
+		Replicas:     cfg.Replicas,
+		Placement:    cfg.Placement,
+		Compression:  cfg.Compression != nats.NoCompression,
+	}
+}
+
+func (c *kvCommand) cloneAction(_ *fisk.ParseContext) error {
+	if c.bucket == c.cloneBucket {
+		return fmt.Errorf("can not clone bucket %s into itself", c.bucket)
+	}
+
+	_, js, source, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	status, err := source.Status()
+	if err != nil {
+		return err
+	}
+
+	nfo := status.(*nats.KeyValueBucketStatus).StreamInfo()
+
+	dest, err := js.KeyValue(c.cloneBucket)
+	switch {
+	case errors.Is(err, nats.ErrBucketNotFound):
+		dest, err = js.CreateKeyValue(kvCloneConfig(c.cloneBucket, nfo))
+		if err != nil {
+			return fmt.Errorf("could not create bucket %s: %w", c.cloneBucket, err)
+		}
+		fmt.Printf("Created bucket %s using the configuration of %s\n", c.cloneBucket, c.bucket)
+	case err != nil:
+		return fmt.Errorf("could not load bucket %s: %w", c.cloneBucket, err)
+	}
+
+	stats := &kvCloneStats{start: time.Now()}
+
+	var progress *uiprogress.Progress
+	var bar *uiprogress.Bar
+	if c.showProgress && nfo.State.NumSubjects > 0 {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(int(nfo.State.NumSubjects)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		}).AppendFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s keys/sec", f(int64(stats.rate())))
+		})
+		progress.Start()
+	}
+
+	err = c.cloneKeys(source, dest, stats, func() {
+		if bar != nil {
+			bar.Set(min(stats.seen, bar.Total))
+		}
+	})
+
+	if progress != nil {
+		time.Sleep(250 * time.Millisecond) // let it draw
+		progress.Stop()
+		fmt.Println()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Copied %s keys from %s to %s in %v, %s keys/sec\n", f(stats.copied), c.bucket, c.cloneBucket, time.Since(stats.start).Round(time.Millisecond), f(int64(stats.rate())))
+	if stats.skipped > 0 {
+		fmt.Printf("Skipped %s keys already in %s, use --overwrite to update them\n", f(stats.skipped), c.cloneBucket)
+	}
+
+	return nil
+}
+
+// cloneKeys copies the latest value of every key in source to dest, deleted keys are not copied and keys already in dest
+// are only updated when overwriting
+func (c *kvCommand) cloneKeys(source nats.KeyValue, dest nats.KeyValue, stats *kvCloneStats, progress func()) error {
+	watcher, err := source.WatchAll()
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	timeout := time.NewTimer(opts().Timeout)
+	defer timeout.Stop()
+
+	for {
+		select {
+		case entry := <-watcher.Updates():
+			// a nil entry marks the end of the current values
+			if entry == nil {
+				return nil
+			}
+
+			timeout.Reset(opts().Timeout)
+			stats.seen++
+
+			if entry.Operation() == nats.KeyValuePut {
+				if c.cloneOverwrite {
+					_, err = dest.Put(entry.Key(), entry.Value())
+				} else {
+					_, err = dest.Create(entry.Key(), entry.Value())
+				}
+
+				switch {
+				case errors.Is(err, nats.ErrKeyExists):
+					stats.skipped++
+				case err != nil:
+					return fmt.Errorf("could not copy %s > %s: %w", source.Bucket(), entry.Key(), err)
+				default:
+					stats.copied++
+				}
+			}
+
+			progress()
+
+		case <-timeout.C:
+			return fmt.Errorf("timeout reading keys from %s after copying %s keys", source.Bucket(), f(stats.copied))
+		}
+	}
+}
@@ -14,12 +14,15 @@
 package cli
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"path"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,9 +30,10 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
+	"github.com/itchyny/gojq"
 	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/natscli/columns"
 	"golang.org/x/term"
@@ -49,6 +53,7 @@ type kvCommand struct {
 	maxBucketSize         int64
 	maxBucketSizeString   string
 	revision              uint64
+	revisionBefore        string
 	description           string
 	listNames             bool
 	lsVerbose             bool
@@ -63,6 +68,29 @@ type kvCommand struct {
 	mirrorDomain          string
 	sources               []string
 	compression           bool
+	watchField            string
+	keysPattern           string
+	keysCount             bool
+	keysValueGt           float64
+	keysValueGtIsSet      bool
+	keysValueLt           float64
+	keysValueLtIsSet      bool
+	diffKey               string
+	diffBucket            string
+	diffDetails           bool
+	lockTTL               time.Duration
+	lockTimeout           time.Duration
+	lockNoWait            bool
+	lockCommand           []string
+	expireTTL             time.Duration
+	cloneBucket           string
+	cloneOverwrite        bool
+	copyBucket            string
+	copyKey               string
+	copyMove              bool
+	copyRecursive         bool
+	showProgress          bool
+	json                  bool
 }
 
 func configureKVCommand(app commandHost) {
@@ -109,6 +137,17 @@ for an indefinite period or a per-bucket configured TTL.
 	get.Flag("revision", "Gets a specific revision").Uint64Var(&c.revision)
 	get.Flag("raw", "Show only the value string").UnNegatableBoolVar(&c.raw)
 
+	getRevision := kv.Command("get-revision", "Gets a historic revision of a key").Action(c.getRevisionAction)
+	getRevision.HelpLong(`With --before the latest revision created before a time is found in the history of the key,
+times can be given in RFC3339 format, as a date like "2023-04-01 02:00" optionally followed
+by a time zone like CET, +02:00 or Europe/Berlin, or as a duration like 1h30m meaning that long
+ago. Times without a zone are in local time.`)
+	getRevision.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	getRevision.Arg("key", "The key to act on").Required().StringVar(&c.key)
+	getRevision.Arg("revision", "The revision to get").Uint64Var(&c.revision)
+	getRevision.Flag("before", "Gets the latest revision created before a time").PlaceHolder("TIME").StringVar(&c.revisionBefore)
+	getRevision.Flag("raw", "Show only the value string").UnNegatableBoolVar(&c.raw)
+
 	create := kv.Command("create", "Puts a value into a key only if the key is new or it's last operation was a delete").Action(c.createAction)
 	create.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
 	create.Arg("key", "The key to act on").Required().StringVar(&c.key)
@@ -147,12 +186,113 @@ for an indefinite period or a per-bucket configured TTL.
 	watch.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
 	watch.Arg("key", "The key to act on").Default(">").StringVar(&c.key)
 
+	watchFields := kv.Command("watch-fields", "Watch a key holding JSON data for changes to a specific field").Action(c.watchFieldsAction)
+	watchFields.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	watchFields.Arg("key", "The key to act on").Required().StringVar(&c.key)
+	watchFields.Flag("field", "jq path of the field to watch for changes").Required().PlaceHolder("PATH").StringVar(&c.watchField)
+
 	ls := kv.Command("ls", "List available buckets or the keys in a bucket").Alias("list").Action(c.lsAction)
 	ls.Arg("bucket", "The bucket to list the keys").StringVar(&c.bucket)
 	ls.Flag("names", "Show just the bucket names").Short('n').UnNegatableBoolVar(&c.listNames)
 	ls.Flag("verbose", "Show detailed info about the key").Short('v').UnNegatableBoolVar(&c.lsVerbose)
 	ls.Flag("display-value", "Display value in verbose output (has no effect without 'verbose')").UnNegatableBoolVar(&c.lsVerboseDisplayValue)
 
+	keys := kv.Command("keys", "List the keys in a bucket, optionally matching a pattern").Action(c.keysAction)
+	keys.Arg("bucket", "The bucket to list the keys").StringVar(&c.bucket)
+	keys.Flag("pattern", "Only show keys matching a NATS wildcard like 'user.*.profile' or a glob like 'user.a?c.*'").PlaceHolder("PATTERN").StringVar(&c.keysPattern)
+	keys.Flag("count", "Only show the number of matching keys").UnNegatableBoolVar(&c.keysCount)
+	keys.Flag("value-gt", "Only show keys with numeric values greater than this").PlaceHolder("NUMBER").IsSetByUser(&c.keysValueGtIsSet).Float64Var(&c.keysValueGt)
+	keys.Flag("value-lt", "Only show keys with numeric values less than this").PlaceHolder("NUMBER").IsSetByUser(&c.keysValueLtIsSet).Float64Var(&c.keysValueLt)
+
+	diff := kv.Command("diff", "Compares the JSON values of two keys").Action(c.diffAction)
+	diff.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	diff.Arg("key", "The first key to compare").Required().StringVar(&c.key)
+	diff.Arg("other", "The key to compare it with").Required().StringVar(&c.diffKey)
+	diff.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	diffBuckets := kv.Command("diff-buckets", "Compares all keys in two buckets").Action(c.diffBucketsAction)
+	diffBuckets.Arg("bucket", "The first bucket to compare").Required().StringVar(&c.bucket)
+	diffBuckets.Arg("other", "The bucket to compare it with").Required().StringVar(&c.diffBucket)
+	diffBuckets.Flag("details", "Show the differences in JSON values of keys that are not the same").UnNegatableBoolVar(&c.diffDetails)
+	diffBuckets.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	cloneHelp := `Copies the current value of every key into another bucket
+
+The destination bucket is created using the configuration of the source
+bucket when it does not exist. Only the latest value of each key is copied,
+history and deleted keys are not. Keys that already exist in the destination
+are skipped unless --overwrite is given.
+
+  nats kv clone CONFIG CONFIG_BACKUP
+`
+
+	clone := kv.Command("clone", "Copies all keys in a bucket into another bucket").Action(c.cloneAction)
+	clone.HelpLong(cloneHelp)
+	clone.Arg("bucket", "The bucket to copy the keys from").Required().StringVar(&c.bucket)
+	clone.Arg("destination", "The bucket to copy the keys to, created when it does not exist").Required().StringVar(&c.cloneBucket)
+	clone.Flag("overwrite", "Update keys that already exist in the destination bucket").UnNegatableBoolVar(&c.cloneOverwrite)
+	clone.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+
+	copyHelp := `Copies the value of a key into a key of another bucket
+
+The destination key is created or updated with the current value of the
+source key. When copying recursively the source key has to end in .> and
+every matching key is copied below the destination key keeping the tokens
+after the source prefix.
+
+  nats kv copy CONFIG app.db CONFIG_BACKUP app.db
+  nats kv copy CONFIG 'users.>' ARCHIVE users.2024 --recursive --move
+`
+
+	copyKey := kv.Command("copy", "Copies a key, or keys matching a prefix, into another bucket").Alias("cp").Action(c.copyAction)
+	copyKey.HelpLong(copyHelp)
+	copyKey.Arg("bucket", "The bucket to copy the key from").Required().StringVar(&c.bucket)
+	copyKey.Arg("key", "The key to copy").Required().StringVar(&c.key)
+	copyKey.Arg("destination", "The bucket to copy the key to").Required().StringVar(&c.copyBucket)
+	copyKey.Arg("destination-key", "The key to copy to, or the prefix to copy below when recursive").Required().StringVar(&c.copyKey)
+	copyKey.Flag("move", "Delete the source keys after copying them").UnNegatableBoolVar(&c.copyMove)
+	copyKey.Flag("recursive", "Copy all keys matching a source key ending in .>").Short('r').UnNegatableBoolVar(&c.copyRecursive)
+
+	lockHelp := `Runs a command while holding a lock stored in a key
+
+The lock is acquired by creating the key, it is refreshed while the command
+runs and released when the command exits or the process is signalled. A
+lock that is not refreshed within its TTL, for example because its holder
+crashed, is considered stale and taken over.
+
+  nats kv lock LOCKS backup -- /usr/local/bin/backup.sh --full
+`
+
+	lock := kv.Command("lock", "Runs a command while holding a lock stored in a key").Action(c.lockAction)
+	lock.HelpLong(lockHelp)
+	lock.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	lock.Arg("key", "The key holding the lock").Required().StringVar(&c.key)
+	lock.Arg("command", "The command to run while holding the lock").Required().StringsVar(&c.lockCommand)
+	lock.Flag("ttl", "How long the lock is valid without being refreshed").Default("30s").DurationVar(&c.lockTTL)
+	lock.Flag("wait", "How long to wait for the lock, waits until it is available by default").PlaceHolder("DURATION").DurationVar(&c.lockTimeout)
+	lock.Flag("no-wait", "Exit with an error when the lock is held").UnNegatableBoolVar(&c.lockNoWait)
+
+	lockStatus := kv.Command("lock-status", "Shows the holder of a lock").Action(c.lockStatusAction)
+	lockStatus.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	lockStatus.Arg("key", "The key holding the lock").Required().StringVar(&c.key)
+	lockStatus.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	expireHelp := `Sets a TTL on an existing key
+
+The key is put again with its current value and a per-key TTL after which
+the server removes it. Per-key TTLs require NATS Server 2.11.0 or newer and
+a bucket whose stream allows message TTLs, the bucket wide TTL set using
+kv add --ttl is not changed.
+
+  nats kv expire SESSIONS user1 --ttl 1h
+`
+
+	expire := kv.Command("expire", "Sets a TTL on an existing key").Action(c.expireAction)
+	expire.HelpLong(expireHelp)
+	expire.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
+	expire.Arg("key", "The key to act on").Required().StringVar(&c.key)
+	expire.Flag("ttl", "How long to keep the key for").Required().DurationVar(&c.expireTTL)
+
 	rmHistory := kv.Command("compact", "Reclaim space used by deleted keys").Action(c.compactAction)
 	rmHistory.Arg("bucket", "The bucket to act on").Required().StringVar(&c.bucket)
 	rmHistory.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
@@ -240,6 +380,122 @@ func (c *kvCommand) lsBucketKeys() error {
 	return nil
 }
 
+func (c *kvCommand) keysAction(_ *fisk.ParseContext) error {
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	subject, match, err := kvKeyMatcher(c.keysPattern)
+	if err != nil {
+		return err
+	}
+
+	filterValues := c.keysValueGtIsSet || c.keysValueLtIsSet
+
+	wopts := []nats.WatchOpt{nats.IgnoreDeletes()}
+	if !filterValues {
+		wopts = append(wopts, nats.MetaOnly())
+	}
+
+	watch, err := store.Watch(subject, wopts...)
+	if err != nil {
+		return err
+	}
+	defer watch.Stop()
+
+	var keys []string
+	for entry := range watch.Updates() {
+		// nil marks the end of the initial values
+		if entry == nil {
+			break
+		}
+
+		if !match(entry.Key()) {
+			continue
+		}
+
+		if filterValues && !c.keysValueMatches(entry.Value()) {
+			continue
+		}
+
+		keys = append(keys, entry.Key())
+	}
+
+	if c.keysCount {
+		fmt.Println(len(keys))
+		return nil
+	}
+
+	if len(keys) == 0 {
+		if c.keysPattern != "" || filterValues {
+			fmt.Println("No matching keys found in bucket")
+		} else {
+			fmt.Println("No keys found in bucket")
+		}
+		return nil
+	}
+
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Println(k)
+	}
+
+	return nil
+}
+
+// keysValueMatches checks numeric values against the value filters, values that are not numbers never match
+func (c *kvCommand) keysValueMatches(value []byte) bool {
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(value)), 64)
+	if err != nil {
+		return false
+	}
+
+	if c.keysValueGtIsSet && v <= c.keysValueGt {
+		return false
+	}
+	if c.keysValueLtIsSet && v >= c.keysValueLt {
+		return false
+	}
+
+	return true
+}
+
+// kvKeyMatcher determines the subject to watch and a matcher for keys, NATS wildcards are matched by the server
+// while glob patterns need all keys to be checked locally
+func kvKeyMatcher(pattern string) (string, func(string) bool, error) {
+	all := func(string) bool { return true }
+
+	if pattern == "" {
+		return ">", all, nil
+	}
+
+	isGlob := strings.ContainsAny(pattern, "?[")
+	for _, token := range strings.Split(pattern, ".") {
+		if strings.Contains(token, "*") && token != "*" {
+			isGlob = true
+		}
+	}
+
+	if !isGlob {
+		if !server.IsValidSubject(pattern) {
+			return "", nil, fmt.Errorf("invalid key pattern %q", pattern)
+		}
+
+		return pattern, all, nil
+	}
+
+	_, err := path.Match(pattern, "")
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid key pattern %q: %w", pattern, err)
+	}
+
+	return ">", func(key string) bool {
+		ok, _ := path.Match(pattern, key)
+		return ok
+	}, nil
+}
+
 func (c *kvCommand) displayKeyInfo(kv nats.KeyValue, keys nats.KeyLister) (bool, error) {
 	var found bool
 
@@ -322,7 +578,7 @@ func (c *kvCommand) lsBuckets() error {
 	for _, s := range found {
 		nfo, _ := s.LatestInformation()
 
-		table.AddRow(strings.TrimPrefix(s.Name(), "KV_"), s.Description(), f(nfo.Created), humanize.IBytes(nfo.State.Bytes), f(nfo.State.Msgs), f(time.Since(nfo.State.LastTime)))
+		table.AddRow(strings.TrimPrefix(s.Name(), "KV_"), s.Description(), f(nfo.Created), fiBytes(nfo.State.Bytes), f(nfo.State.Msgs), f(time.Since(nfo.State.LastTime)))
 	}
 
 	fmt.Println(table.Render())
@@ -515,6 +771,11 @@ func (c *kvCommand) getAction(_ *fisk.ParseContext) error {
 		return nil
 	}
 
+	if opts().Quiet {
+		fmt.Println(base64IfNotPrintable(res.Value()))
+		return nil
+	}
+
 	fmt.Printf("%s > %s revision: %d created @ %s\n", res.Bucket(), res.Key(), res.Revision(), res.Created().Format(time.RFC822))
 	fmt.Println()
 	pv := base64IfNotPrintable(res.Value())
@@ -694,6 +955,107 @@ func (c *kvCommand) watchAction(_ *fisk.ParseContext) error {
 	return nil
 }
 
+func (c *kvCommand) watchFieldsAction(_ *fisk.ParseContext) error {
+	query, err := gojq.Parse(c.watchField)
+	if err != nil {
+		return fmt.Errorf("invalid field path %q: %w", c.watchField, err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return fmt.Errorf("invalid field path %q: %w", c.watchField, err)
+	}
+
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	watch, err := store.Watch(c.key)
+	if err != nil {
+		return err
+	}
+	defer watch.Stop()
+
+	// the last seen field value keyed by kv key, initial values only set the baseline
+	seen := map[string]string{}
+	initial := true
+
+	for res := range watch.Updates() {
+		if res == nil {
+			initial = false
+			continue
+		}
+
+		prev, known := seen[res.Key()]
+
+		switch res.Operation() {
+		case nats.KeyValueDelete, nats.KeyValuePurge:
+			delete(seen, res.Key())
+			if known && !initial {
+				fmt.Printf("[%s] %s %s > %s %s: %s\n", f(res.Created()), color.RedString(c.strForOp(res.Operation())), res.Bucket(), res.Key(), c.watchField, prev)
+			}
+
+		case nats.KeyValuePut:
+			val, err := kvFieldValue(code, res.Value())
+			if err != nil {
+				logErrorf("Could not extract %s from %s > %s revision %d: %v", c.watchField, res.Bucket(), res.Key(), res.Revision(), err)
+				continue
+			}
+
+			seen[res.Key()] = val
+			if initial || (known && prev == val) {
+				continue
+			}
+
+			if known {
+				fmt.Printf("[%s] %s %s > %s %s: %s -> %s\n", f(res.Created()), color.GreenString("CHANGED"), res.Bucket(), res.Key(), c.watchField, prev, val)
+			} else {
+				fmt.Printf("[%s] %s %s > %s %s: %s\n", f(res.Created()), color.GreenString(c.strForOp(res.Operation())), res.Bucket(), res.Key(), c.watchField, val)
+			}
+		}
+	}
+
+	return nil
+}
+
+// kvFieldValue evaluates code against the JSON document in data and returns the
+// results JSON encoded so that values can be compared between revisions
+func kvFieldValue(code *gojq.Code, data []byte) (string, error) {
+	var doc any
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return "", fmt.Errorf("value is not JSON: %w", err)
+	}
+
+	var results []string
+	iter := code.Run(doc)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+
+		err, ok := v.(error)
+		if ok {
+			return "", err
+		}
+
+		j, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		results = append(results, string(j))
+	}
+
+	if len(results) == 0 {
+		return "null", nil
+	}
+
+	return strings.Join(results, ", "), nil
+}
+
 func (c *kvCommand) purgeAction(_ *fisk.ParseContext) error {
 	_, _, store, err := c.loadBucket()
 	if err != nil {
@@ -760,6 +1122,10 @@ func (c *kvCommand) showStatus(store nats.KeyValue) error {
 
 	cols.AddRow("Bucket Name", status.Bucket())
 	cols.AddRow("History Kept", status.History())
+	if nfo != nil {
+		// subjects holding only a delete or purge marker are counted too, counting live keys means reading them all
+		cols.AddRow("Subjects incl. deleted", nfo.State.NumSubjects)
+	}
 	cols.AddRow("Values Stored", status.Values())
 	cols.AddRow("Compressed", status.IsCompressed())
 	cols.AddRow("Backing Store Kind", status.BackingStore())
@@ -767,16 +1133,16 @@ func (c *kvCommand) showStatus(store nats.KeyValue) error {
 	if nfo != nil {
 		cols.AddRowIfNotEmpty("Description", nfo.Config.Description)
 
-		cols.AddRow("Bucket Size", humanize.IBytes(nfo.State.Bytes))
+		cols.AddRow("Bucket Size", fiBytes(nfo.State.Bytes))
 		if nfo.Config.MaxBytes == -1 {
 			cols.AddRow("Maximum Bucket Size", "unlimited")
 		} else {
-			cols.AddRow("Maximum Bucket Size", humanize.IBytes(uint64(nfo.Config.MaxBytes)))
+			cols.AddRow("Maximum Bucket Size", fiBytes(uint64(nfo.Config.MaxBytes)))
 		}
 		if nfo.Config.MaxMsgSize == -1 {
 			cols.AddRow("Maximum Value Size", "unlimited")
 		} else {
-			cols.AddRow("Maximum Value Size", humanize.IBytes(uint64(nfo.Config.MaxMsgSize)))
+			cols.AddRow("Maximum Value Size", fiBytes(uint64(nfo.Config.MaxMsgSize)))
 		}
 		if nfo.Config.MaxAge <= 0 {
 			cols.AddRow("Maximum Age", "unlimited")
@@ -826,6 +1192,7 @@ func (c *kvCommand) showStatus(store nats.KeyValue) error {
 
 		if nfo.Cluster != nil {
 			cols.AddSectionTitle("Cluster Information")
+			cols.AddRow("Health", kvReplicationHealth(nfo))
 			renderNatsGoClusterInfo(cols, nfo)
 		}
 	}
@@ -833,6 +1200,47 @@ func (c *kvCommand) showStatus(store nats.KeyValue) error {
 	return nil
 }
 
+// kvReplicationHealth summarizes the state of the replicas of the stream backing a bucket, offline peers are listed
+// by name as they need attention first
+func kvReplicationHealth(nfo *nats.StreamInfo) string {
+	if nfo.Cluster == nil {
+		return "not clustered"
+	}
+
+	var offline []string
+	outdated := 0
+	for _, r := range nfo.Cluster.Replicas {
+		switch {
+		case r.Offline:
+			offline = append(offline, r.Name)
+		case !r.Current:
+			outdated++
+		}
+	}
+
+	peers := len(nfo.Cluster.Replicas) + 1
+
+	var problems []string
+	if nfo.Cluster.Leader == "" {
+		problems = append(problems, "no leader elected")
+	}
+	if len(offline) > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d peers offline: %s", len(offline), peers, strings.Join(offline, ", ")))
+	}
+	if outdated > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d peers outdated", outdated, peers))
+	}
+
+	switch {
+	case len(problems) > 0:
+		return strings.Join(problems, ", ")
+	case peers == 1:
+		return "healthy"
+	default:
+		return fmt.Sprintf("healthy, all %d peers are current", peers)
+	}
+}
+
 func renderNatsGoClusterInfo(cols *columns.Writer, info *nats.StreamInfo) {
 	cols.AddRow("Name", info.Cluster.Name)
 	cols.AddRow("Leader", info.Cluster.Leader)
@@ -850,7 +1258,7 @@ func renderNatsGoClusterInfo(cols *columns.Writer, info *nats.StreamInfo) {
 		}
 
 		if r.Active > 0 && r.Active < math.MaxInt64 {
-			state = append(state, fmt.Sprintf("seen %s ago", f(r.Active)))
+			state = append(state, "seen "+fAgo(r.Active))
 		} else {
 			state = append(state, "not seen")
 		}
@@ -0,0 +1,307 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"github.com/nats-io/nats.go"
+)
+
+func TestKVFieldValue(t *testing.T) {
+	query, err := gojq.Parse(".config.timeout")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	code, err := gojq.Compile(query)
+	if err != nil {
+		t.Fatalf("compile failed: %v", err)
+	}
+
+	cases := []struct {
+		data   string
+		expect string
+	}{
+		{`{"config":{"timeout":10,"other":1}}`, "10"},
+		{`{"config":{"timeout":"10s"}}`, `"10s"`},
+		{`{"config":{"timeout":{"b":1,"a":2}}}`, `{"a":2,"b":1}`},
+		{`{"config":{}}`, "null"},
+	}
+
+	for _, tc := range cases {
+		val, err := kvFieldValue(code, []byte(tc.data))
+		if err != nil {
+			t.Fatalf("extract from %s failed: %v", tc.data, err)
+		}
+		if val != tc.expect {
+			t.Fatalf("expected %s from %s got %s", tc.expect, tc.data, val)
+		}
+	}
+
+	_, err = kvFieldValue(code, []byte("not json"))
+	if err == nil {
+		t.Fatalf("expected an error for non JSON values")
+	}
+
+	_, err = kvFieldValue(code, []byte(`{"config":[1]}`))
+	if err == nil {
+		t.Fatalf("expected an error when indexing an array with a string")
+	}
+}
+
+func TestKVKeyMatcher(t *testing.T) {
+	cases := []struct {
+		pattern string
+		subject string
+		matches []string
+		misses  []string
+	}{
+		{"", ">", []string{"a", "a.b"}, nil},
+		{"user.*.profile", "user.*.profile", []string{"user.x.profile"}, nil},
+		{"user.>", "user.>", []string{"user.x"}, nil},
+		{"user.a*", ">", []string{"user.a", "user.abc", "user.a.b"}, []string{"user.b", "other.a"}},
+		{"user.?.profile", ">", []string{"user.x.profile"}, []string{"user.xy.profile"}},
+		{"user.[ab].profile", ">", []string{"user.a.profile"}, []string{"user.c.profile"}},
+	}
+
+	for _, tc := range cases {
+		subject, match, err := kvKeyMatcher(tc.pattern)
+		if err != nil {
+			t.Fatalf("pattern %q failed: %v", tc.pattern, err)
+		}
+		if subject != tc.subject {
+			t.Fatalf("expected pattern %q to watch %q got %q", tc.pattern, tc.subject, subject)
+		}
+		for _, k := range tc.matches {
+			if !match(k) {
+				t.Fatalf("expected pattern %q to match %q", tc.pattern, k)
+			}
+		}
+		for _, k := range tc.misses {
+			if match(k) {
+				t.Fatalf("expected pattern %q to not match %q", tc.pattern, k)
+			}
+		}
+	}
+
+	for _, pattern := range []string{"user..x", "user.[a"} {
+		_, _, err := kvKeyMatcher(pattern)
+		if err == nil {
+			t.Fatalf("expected pattern %q to be invalid", pattern)
+		}
+	}
+}
+
+func TestKVKeysValueMatches(t *testing.T) {
+	c := &kvCommand{keysValueGt: 0, keysValueGtIsSet: true, keysValueLt: 10, keysValueLtIsSet: true}
+
+	for _, v := range []string{"1", " 9.5\n"} {
+		if !c.keysValueMatches([]byte(v)) {
+			t.Fatalf("expected %q to match", v)
+		}
+	}
+
+	for _, v := range []string{"0", "10", "-1", "abc", ""} {
+		if c.keysValueMatches([]byte(v)) {
+			t.Fatalf("expected %q to not match", v)
+		}
+	}
+}
+
+func TestJSONDiff(t *testing.T) {
+	parse := func(s string) any {
+		t.Helper()
+		var v any
+		err := json.Unmarshal([]byte(s), &v)
+		if err != nil {
+			t.Fatalf("invalid json %s: %v", s, err)
+		}
+		return v
+	}
+
+	diffs := jsonDiff(parse(`{"a":1,"b":{"c":[1,2]},"d e":true,"gone":null}`), parse(`{"a":1,"b":{"c":[1,3,4]},"d e":false,"new":"x"}`))
+
+	var res []string
+	for _, d := range diffs {
+		res = append(res, fmt.Sprintf("%s %s %v %v", d.Kind, d.Path, d.Old, d.New))
+	}
+
+	expect := []string{
+		"changed .b.c[1] 2 3",
+		"added .b.c[2] <nil> 4",
+		`changed .["d e"] true false`,
+		"removed .gone <nil> <nil>",
+		"added .new <nil> x",
+	}
+	if !reflect.DeepEqual(res, expect) {
+		t.Fatalf("expected %v got %v", expect, res)
+	}
+
+	if len(jsonDiff(parse(`{"a":[1,{"b":2}]}`), parse(`{ "a": [1, {"b": 2}] }`))) != 0 {
+		t.Fatalf("expected no differences")
+	}
+
+	diffs = jsonDiff(parse(`{"a":1}`), parse(`[1]`))
+	if len(diffs) != 1 || diffs[0].Path != "." || diffs[0].Kind != jsonDiffChanged {
+		t.Fatalf("expected the document to be changed got %+v", diffs)
+	}
+}
+
+type testKVEntry struct {
+	nats.KeyValueEntry
+	value   []byte
+	created time.Time
+}
+
+func (e *testKVEntry) Value() []byte      { return e.value }
+func (e *testKVEntry) Created() time.Time { return e.created }
+
+func TestKVLockState(t *testing.T) {
+	now := time.Now()
+	holder := `{"host":"h1","pid":10,"ttl":10000000000}`
+
+	h, stale := kvLockState(&testKVEntry{value: []byte(holder), created: now.Add(-5 * time.Second)}, now, time.Second)
+	if h == nil || h.Host != "h1" || h.PID != 10 || stale {
+		t.Fatalf("expected a held lock using the holder TTL: %+v %v", h, stale)
+	}
+
+	_, stale = kvLockState(&testKVEntry{value: []byte(holder), created: now.Add(-11 * time.Second)}, now, time.Minute)
+	if !stale {
+		t.Fatalf("expected the lock to be stale past the holder TTL")
+	}
+
+	h, stale = kvLockState(&testKVEntry{value: []byte("other"), created: now.Add(-5 * time.Second)}, now, time.Minute)
+	if h != nil || stale {
+		t.Fatalf("expected an unknown holder using the default TTL: %+v %v", h, stale)
+	}
+
+	_, stale = kvLockState(&testKVEntry{value: []byte("null"), created: now.Add(-2 * time.Minute)}, now, time.Minute)
+	if !stale {
+		t.Fatalf("expected an unknown holder to be stale past the default TTL")
+	}
+}
+
+func TestKVExpireMsg(t *testing.T) {
+	msg := kvExpireMsg("SESSIONS", "user1", []byte("data"), 10, 90*time.Minute)
+
+	if msg.Subject != "$KV.SESSIONS.user1" || string(msg.Data) != "data" {
+		t.Fatalf("unexpected message: %s %q", msg.Subject, msg.Data)
+	}
+
+	if ttl := msg.Header.Get("Nats-TTL"); ttl != "1h30m0s" {
+		t.Fatalf("unexpected ttl header %q", ttl)
+	}
+
+	if rev := msg.Header.Get("Nats-Expected-Last-Subject-Sequence"); rev != "10" {
+		t.Fatalf("unexpected expected revision header %q", rev)
+	}
+}
+
+func TestKVCloneConfig(t *testing.T) {
+	nfo := &nats.StreamInfo{Config: nats.StreamConfig{
+		Name:              "KV_CONFIG",
+		Description:       "configuration",
+		MaxMsgsPerSubject: 10,
+		MaxAge:            time.Hour,
+		MaxBytes:          1024,
+		MaxMsgSize:        128,
+		Storage:           nats.MemoryStorage,
+		Replicas:          3,
+		Compression:       nats.S2Compression,
+		RePublish:         &nats.RePublish{Source: ">", Destination: "cfg.>"},
+	}}
+
+	cfg := kvCloneConfig("BACKUP", nfo)
+	expected := &nats.KeyValueConfig{
+		Bucket:       "BACKUP",
+		Description:  "configuration",
+		MaxValueSize: 128,
+		History:      10,
+		TTL:          time.Hour,
+		MaxBytes:     1024,
+		Storage:      nats.MemoryStorage,
+		Replicas:     3,
+		Compression:  true,
+	}
+
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+}
+
+func TestKVReplicationHealth(t *testing.T) {
+	for _, tc := range []struct {
+		cluster  *nats.ClusterInfo
+		expected string
+	}{
+		{nil, "not clustered"},
+		{&nats.ClusterInfo{Leader: "n1"}, "healthy"},
+		{&nats.ClusterInfo{Leader: "n1", Replicas: []*nats.PeerInfo{{Name: "n2", Current: true}, {Name: "n3", Current: true}}}, "healthy, all 3 peers are current"},
+		{&nats.ClusterInfo{Leader: "n1", Replicas: []*nats.PeerInfo{{Name: "n2", Offline: true}, {Name: "n3"}}}, "1 of 3 peers offline: n2, 1 of 3 peers outdated"},
+		{&nats.ClusterInfo{Replicas: []*nats.PeerInfo{{Name: "n2", Offline: true}, {Name: "n3", Offline: true}}}, "no leader elected, 2 of 3 peers offline: n2, n3"},
+	} {
+		if got := kvReplicationHealth(&nats.StreamInfo{Cluster: tc.cluster}); got != tc.expected {
+			t.Fatalf("expected %q got %q", tc.expected, got)
+		}
+	}
+}
+
+func TestKVCopyDestKey(t *testing.T) {
+	for _, tc := range []struct {
+		key    string
+		dest   string
+		expect string
+	}{
+		{"users.a", "archive", "archive.a"},
+		{"users.a.b", "archive.2024", "archive.2024.a.b"},
+		{"users.a", "archive.>", "archive.a"},
+		{"users.a", "archive.", "archive.a"},
+	} {
+		if key := kvCopyDestKey(tc.key, "users.", tc.dest); key != tc.expect {
+			t.Fatalf("expected %s for %s below %s got %s", tc.expect, tc.key, tc.dest, key)
+		}
+	}
+}
+
+func TestKVRevisionBefore(t *testing.T) {
+	now := time.Now()
+	var history []nats.KeyValueEntry
+	for i := 3; i > 0; i-- {
+		history = append(history, &testKVEntry{value: []byte(fmt.Sprintf("v%d", 4-i)), created: now.Add(-time.Duration(i) * time.Hour)})
+	}
+
+	for _, tc := range []struct {
+		before time.Duration
+		expect string
+	}{
+		{4 * time.Hour, ""},
+		{3 * time.Hour, ""},
+		{150 * time.Minute, "v1"},
+		{time.Hour, "v2"},
+		{0, "v3"},
+	} {
+		entry := kvRevisionBefore(history, now.Add(-tc.before))
+		switch {
+		case tc.expect == "" && entry != nil:
+			t.Fatalf("expected no entry before %v got %s", tc.before, entry.Value())
+		case tc.expect != "" && (entry == nil || string(entry.Value()) != tc.expect):
+			t.Fatalf("expected %s before %v got %v", tc.expect, tc.before, entry)
+		}
+	}
+}
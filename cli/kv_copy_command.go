@@ -0,0 +1,155 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+)
+
+// kvCopyDestKey places key, which is below prefix in the source bucket, below dest keeping the tokens after prefix,
+// dest may end in .> like the source key
+func kvCopyDestKey(key string, prefix string, dest string) string {
+	dest = strings.TrimSuffix(strings.TrimSuffix(dest, ">"), ".")
+
+	return dest + "." + strings.TrimPrefix(key, prefix)
+}
+
+func (c *kvCommand) copyAction(_ *fisk.ParseContext) error {
+	if c.copyRecursive && !strings.HasSuffix(c.key, ".>") {
+		return fmt.Errorf("recursive copies require a source key ending in .>")
+	}
+	if !c.copyRecursive && strings.ContainsAny(c.key, "*>") {
+		return fmt.Errorf("source key %s has wildcards, use --recursive to copy many keys", c.key)
+	}
+	if c.bucket == c.copyBucket && c.key == c.copyKey {
+		return fmt.Errorf("can not copy %s > %s onto itself", c.bucket, c.key)
+	}
+	// every key below the source would map onto itself and a move would delete them
+	if c.copyRecursive && c.bucket == c.copyBucket && kvCopyDestKey(c.key, strings.TrimSuffix(c.key, ">"), c.copyKey) == c.key {
+		return fmt.Errorf("can not copy %s > %s onto itself", c.bucket, c.key)
+	}
+
+	_, js, source, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	dest, err := js.KeyValue(c.copyBucket)
+	if err != nil {
+		return fmt.Errorf("could not load bucket %s: %w", c.copyBucket, err)
+	}
+
+	verb := "Copied"
+	if c.copyMove {
+		verb = "Moved"
+	}
+
+	if !c.copyRecursive {
+		entry, err := source.Get(c.key)
+		if err != nil {
+			return fmt.Errorf("could not get %s > %s: %w", c.bucket, c.key, err)
+		}
+
+		err = c.copyEntry(source, dest, entry, c.copyKey)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s %s > %s to %s > %s\n", verb, c.bucket, c.key, c.copyBucket, c.copyKey)
+
+		return nil
+	}
+
+	entries, err := c.copyEntries(source)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("no keys in %s match %s", c.bucket, c.key)
+	}
+
+	// all entries are read before writing so copies into the same bucket are not copied again
+	prefix := strings.TrimSuffix(c.key, ">")
+	for i, entry := range entries {
+		key := kvCopyDestKey(entry.Key(), prefix, c.copyKey)
+
+		err = c.copyEntry(source, dest, entry, key)
+		if err != nil {
+			return fmt.Errorf("%w after copying %s of %s keys", err, f(i), f(len(entries)))
+		}
+	}
+
+	fmt.Printf("%s %s keys from %s > %s to %s > %s\n", verb, f(len(entries)), c.bucket, c.key, c.copyBucket, c.copyKey)
+
+	return nil
+}
+
+// copyEntry puts the value of entry into key of dest, when moving the source key is deleted only once the copy succeeded
+func (c *kvCommand) copyEntry(source nats.KeyValue, dest nats.KeyValue, entry nats.KeyValueEntry, key string) error {
+	// moving a key onto itself would delete it after the copy
+	if source.Bucket() == dest.Bucket() && entry.Key() == key {
+		return fmt.Errorf("can not copy %s > %s onto itself", source.Bucket(), key)
+	}
+
+	_, err := dest.Put(key, entry.Value())
+	if err != nil {
+		return fmt.Errorf("could not copy %s > %s to %s > %s: %w", source.Bucket(), entry.Key(), dest.Bucket(), key, err)
+	}
+
+	if !c.copyMove {
+		return nil
+	}
+
+	err = source.Delete(entry.Key())
+	if err != nil {
+		return fmt.Errorf("copied %s > %s to %s > %s but could not delete it: %w", source.Bucket(), entry.Key(), dest.Bucket(), key, err)
+	}
+
+	return nil
+}
+
+// copyEntries reads the current value of every key matching the source key, deleted keys are not included
+func (c *kvCommand) copyEntries(source nats.KeyValue) ([]nats.KeyValueEntry, error) {
+	watcher, err := source.Watch(c.key, nats.IgnoreDeletes())
+	if err != nil {
+		return nil, err
+	}
+	defer watcher.Stop()
+
+	timeout := time.NewTimer(opts().Timeout)
+	defer timeout.Stop()
+
+	var entries []nats.KeyValueEntry
+	for {
+		select {
+		case entry := <-watcher.Updates():
+			// a nil entry marks the end of the current values
+			if entry == nil {
+				return entries, nil
+			}
+
+			timeout.Reset(opts().Timeout)
+			entries = append(entries, entry)
+
+		case <-timeout.C:
+			return nil, fmt.Errorf("timeout reading keys matching %s from %s", c.key, source.Bucket())
+		}
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"github.com/choria-io/fisk"
+	"github.com/fatih/color"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	jsonDiffAdded   = "added"
+	jsonDiffRemoved = "removed"
+	jsonDiffChanged = "changed"
+)
+
+// jsonDifference is a single difference between two JSON documents, Path is in jq syntax
+type jsonDifference struct {
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	Old  any    `json:"old,omitempty"`
+	New  any    `json:"new,omitempty"`
+}
+
+// kvBucketDifference is a key that is not the same in two buckets
+type kvBucketDifference struct {
+	Key         string            `json:"key"`
+	Status      string            `json:"status"`
+	Differences []*jsonDifference `json:"differences,omitempty"`
+}
+
+type kvBucketDiffReport struct {
+	BucketA   string                `json:"bucket_a"`
+	BucketB   string                `json:"bucket_b"`
+	Identical int                   `json:"identical"`
+	Different []*kvBucketDifference `json:"different"`
+}
+
+var jsonDiffIdentifier = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+func (c *kvCommand) diffAction(_ *fisk.ParseContext) error {
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	a, err := kvJSONValue(store, c.key)
+	if err != nil {
+		return err
+	}
+
+	b, err := kvJSONValue(store, c.diffKey)
+	if err != nil {
+		return err
+	}
+
+	diffs := jsonDiff(a, b)
+
+	if c.json {
+		return iu.PrintJSON(diffs)
+	}
+
+	if len(diffs) == 0 {
+		fmt.Printf("%s > %s and %s > %s are identical\n", c.bucket, c.key, c.bucket, c.diffKey)
+		return nil
+	}
+
+	fmt.Printf("Differences between %s > %s and %s > %s:\n\n", c.bucket, c.key, c.bucket, c.diffKey)
+	renderJSONDiff(diffs, "")
+
+	return nil
+}
+
+func (c *kvCommand) diffBucketsAction(_ *fisk.ParseContext) error {
+	_, js, err := prepareJSHelper()
+	if err != nil {
+		return err
+	}
+
+	storeA, err := js.KeyValue(c.bucket)
+	if err != nil {
+		return fmt.Errorf("could not load bucket %s: %w", c.bucket, err)
+	}
+
+	storeB, err := js.KeyValue(c.diffBucket)
+	if err != nil {
+		return fmt.Errorf("could not load bucket %s: %w", c.diffBucket, err)
+	}
+
+	report, err := kvDiffBuckets(storeA, storeB, c.diffDetails)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		return iu.PrintJSON(report)
+	}
+
+	if len(report.Different) == 0 {
+		fmt.Printf("All %s keys in %s and %s are identical\n", f(report.Identical), c.bucket, c.diffBucket)
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("Keys that differ between %s and %s", c.bucket, c.diffBucket))
+	table.AddHeaders("Key", "Status")
+	for _, d := range report.Different {
+		table.AddRow(d.Key, d.Status)
+	}
+	fmt.Println(table.Render())
+
+	fmt.Printf("%s keys differ, %s keys are identical\n", f(len(report.Different)), f(report.Identical))
+
+	if c.diffDetails {
+		for _, d := range report.Different {
+			if len(d.Differences) == 0 {
+				continue
+			}
+
+			fmt.Printf("\n%s:\n", d.Key)
+			renderJSONDiff(d.Differences, "  ")
+		}
+	}
+
+	return nil
+}
+
+// kvDiffBuckets compares the values of all keys in two buckets, values holding JSON are equal when they hold the same
+// data regardless of formatting and details includes the JSON differences of values that are not equal
+func kvDiffBuckets(a nats.KeyValue, b nats.KeyValue, details bool) (*kvBucketDiffReport, error) {
+	keysA, err := kvBucketKeys(a)
+	if err != nil {
+		return nil, err
+	}
+
+	keysB, err := kvBucketKeys(b)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &kvBucketDiffReport{BucketA: a.Bucket(), BucketB: b.Bucket(), Different: []*kvBucketDifference{}}
+
+	all := map[string]bool{}
+	for k := range keysA {
+		all[k] = true
+	}
+	for k := range keysB {
+		all[k] = true
+	}
+
+	keys := mapKeys(all)
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch {
+		case !keysA[key]:
+			report.Different = append(report.Different, &kvBucketDifference{Key: key, Status: fmt.Sprintf("only in %s", b.Bucket())})
+			continue
+		case !keysB[key]:
+			report.Different = append(report.Different, &kvBucketDifference{Key: key, Status: fmt.Sprintf("only in %s", a.Bucket())})
+			continue
+		}
+
+		va, err := a.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s > %s: %w", a.Bucket(), key, err)
+		}
+
+		vb, err := b.Get(key)
+		if err != nil {
+			return nil, fmt.Errorf("could not get %s > %s: %w", b.Bucket(), key, err)
+		}
+
+		if bytes.Equal(va.Value(), vb.Value()) {
+			report.Identical++
+			continue
+		}
+
+		var ja, jb any
+		if json.Unmarshal(va.Value(), &ja) != nil || json.Unmarshal(vb.Value(), &jb) != nil {
+			report.Different = append(report.Different, &kvBucketDifference{Key: key, Status: "different"})
+			continue
+		}
+
+		diffs := jsonDiff(ja, jb)
+		if len(diffs) == 0 {
+			report.Identical++
+			continue
+		}
+
+		d := &kvBucketDifference{Key: key, Status: "different"}
+		if details {
+			d.Differences = diffs
+		}
+		report.Different = append(report.Different, d)
+	}
+
+	return report, nil
+}
+
+func kvBucketKeys(store nats.KeyValue) (map[string]bool, error) {
+	keys, err := store.Keys()
+	if errors.Is(err, nats.ErrNoKeysFound) {
+		return map[string]bool{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not list keys in %s: %w", store.Bucket(), err)
+	}
+
+	res := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		res[k] = true
+	}
+
+	return res, nil
+}
+
+func kvJSONValue(store nats.KeyValue, key string) (any, error) {
+	entry, err := store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s > %s: %w", store.Bucket(), key, err)
+	}
+
+	var v any
+	err = json.Unmarshal(entry.Value(), &v)
+	if err != nil {
+		return nil, fmt.Errorf("value of %s > %s is not JSON: %w", store.Bucket(), key, err)
+	}
+
+	return v, nil
+}
+
+// jsonDiff finds the differences between two decoded JSON documents, arrays are compared by index
+func jsonDiff(a any, b any) []*jsonDifference {
+	diffs := []*jsonDifference{}
+	jsonDiffWalk("", a, b, &diffs)
+
+	return diffs
+}
+
+func jsonDiffWalk(path string, a any, b any, diffs *[]*jsonDifference) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+
+		keys := mapKeys(av)
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			kp := jsonDiffKeyPath(path, k)
+			ae, inA := av[k]
+			be, inB := bv[k]
+
+			switch {
+			case !inA:
+				*diffs = append(*diffs, &jsonDifference{Path: kp, Kind: jsonDiffAdded, New: be})
+			case !inB:
+				*diffs = append(*diffs, &jsonDifference{Path: kp, Kind: jsonDiffRemoved, Old: ae})
+			default:
+				jsonDiffWalk(kp, ae, be, diffs)
+			}
+		}
+
+		return
+
+	case []any:
+		bv, ok := b.([]any)
+		if !ok {
+			break
+		}
+
+		for i := 0; i < max(len(av), len(bv)); i++ {
+			ip := fmt.Sprintf("%s[%d]", path, i)
+
+			switch {
+			case i >= len(av):
+				*diffs = append(*diffs, &jsonDifference{Path: ip, Kind: jsonDiffAdded, New: bv[i]})
+			case i >= len(bv):
+				*diffs = append(*diffs, &jsonDifference{Path: ip, Kind: jsonDiffRemoved, Old: av[i]})
+			default:
+				jsonDiffWalk(ip, av[i], bv[i], diffs)
+			}
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		if path == "" {
+			path = "."
+		}
+		*diffs = append(*diffs, &jsonDifference{Path: path, Kind: jsonDiffChanged, Old: a, New: b})
+	}
+}
+
+func jsonDiffKeyPath(path string, key string) string {
+	if jsonDiffIdentifier.MatchString(key) {
+		return fmt.Sprintf("%s.%s", path, key)
+	}
+
+	if path == "" {
+		path = "."
+	}
+
+	return fmt.Sprintf("%s[%q]", path, key)
+}
+
+// renderJSONDiff shows each difference on a line, colored when showing on a terminal
+func renderJSONDiff(diffs []*jsonDifference, indent string) {
+	val := func(v any) string {
+		j, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(j)
+	}
+
+	for _, d := range diffs {
+		var line string
+		switch d.Kind {
+		case jsonDiffAdded:
+			line = color.GreenString("+ %s: %s", d.Path, val(d.New))
+		case jsonDiffRemoved:
+			line = color.RedString("- %s: %s", d.Path, val(d.Old))
+		default:
+			line = color.YellowString("~ %s: %s => %s", d.Path, val(d.Old), val(d.New))
+		}
+
+		fmt.Println(indent + line)
+	}
+}
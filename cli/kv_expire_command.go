@@ -0,0 +1,117 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// kvMsgTTLHeader sets how long the server keeps a message, supported from NATS Server 2.11.0
+	kvMsgTTLHeader = "Nats-TTL"
+
+	// kvMinMsgTTL is the shortest TTL the server accepts for a message
+	kvMinMsgTTL = time.Second
+)
+
+// kvStreamTTLInfo is the part of a stream info response indicating if the stream allows message TTLs
+type kvStreamTTLInfo struct {
+	Config struct {
+		AllowMsgTTL bool `json:"allow_msg_ttl"`
+	} `json:"config"`
+	Error *api.ApiError `json:"error,omitempty"`
+}
+
+// kvExpireMsg creates the message putting value again into the key with a TTL, it only succeeds while the
+// latest revision of the key is revision
+func kvExpireMsg(bucket string, key string, value []byte, revision uint64, ttl time.Duration) *nats.Msg {
+	msg := nats.NewMsg(fmt.Sprintf("$KV.%s.%s", bucket, key))
+	msg.Data = value
+	msg.Header.Set(kvMsgTTLHeader, ttl.String())
+	msg.Header.Set(api.JSExpectedLastSubjSeq, strconv.FormatUint(revision, 10))
+
+	return msg
+}
+
+// kvStreamAllowsMsgTTL checks using the stream info if the stream backing bucket allows message TTLs, servers
+// without support for them do not report the setting
+func kvStreamAllowsMsgTTL(nc *nats.Conn, bucket string) (bool, error) {
+	prefix := "$JS.API"
+	switch domain, apiPrefix := jsTarget(); {
+	case apiPrefix != "":
+		prefix = apiPrefix
+	case domain != "":
+		prefix = fmt.Sprintf("$JS.%s.API", domain)
+	}
+
+	res, err := nc.Request(fmt.Sprintf("%s.STREAM.INFO.KV_%s", prefix, bucket), nil, opts().Timeout)
+	if err != nil {
+		return false, err
+	}
+
+	var info kvStreamTTLInfo
+	err = json.Unmarshal(res.Data, &info)
+	if err != nil {
+		return false, err
+	}
+	if info.Error != nil {
+		return false, info.Error
+	}
+
+	return info.Config.AllowMsgTTL, nil
+}
+
+func (c *kvCommand) expireAction(_ *fisk.ParseContext) error {
+	if c.expireTTL < kvMinMsgTTL {
+		return fmt.Errorf("TTL must be at least %s", f(kvMinMsgTTL))
+	}
+
+	nc, js, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	if !serverMinVersion(nc.ConnectedServerVersion(), 2, 11, 0) {
+		return fmt.Errorf("server %s version %s does not support per-key TTLs, version 2.11.0 or newer is required", nc.ConnectedServerName(), nc.ConnectedServerVersion())
+	}
+
+	allowed, err := kvStreamAllowsMsgTTL(nc, store.Bucket())
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("bucket %s does not support per-key TTLs, its stream KV_%s must allow message TTLs", store.Bucket(), store.Bucket())
+	}
+
+	entry, err := store.Get(c.key)
+	if err != nil {
+		return err
+	}
+
+	ack, err := js.PublishMsg(kvExpireMsg(store.Bucket(), entry.Key(), entry.Value(), entry.Revision(), c.expireTTL))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s > %s revision %d expires in %s at %s\n", store.Bucket(), entry.Key(), ack.Sequence, f(c.expireTTL), f(time.Now().Add(c.expireTTL)))
+
+	return nil
+}
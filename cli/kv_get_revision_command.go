@@ -0,0 +1,122 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+)
+
+// kvRevisionBefore finds the latest entry in history created before ts, history is ordered oldest first
+func kvRevisionBefore(history []nats.KeyValueEntry, ts time.Time) nats.KeyValueEntry {
+	var found nats.KeyValueEntry
+	for _, entry := range history {
+		if !entry.Created().Before(ts) {
+			break
+		}
+		found = entry
+	}
+
+	return found
+}
+
+func (c *kvCommand) getRevisionAction(_ *fisk.ParseContext) error {
+	switch {
+	case c.revision == 0 && c.revisionBefore == "":
+		return fmt.Errorf("a revision or --before is required")
+	case c.revision > 0 && c.revisionBefore != "":
+		return fmt.Errorf("a revision and --before can not be used together")
+	}
+
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	var entry nats.KeyValueEntry
+	if c.revisionBefore != "" {
+		entry, err = c.revisionBeforeTime(store)
+	} else {
+		entry, err = c.findRevision(store)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.raw {
+		os.Stdout.Write(entry.Value())
+		return nil
+	}
+
+	cols := newColumns("%s > %s revision %d", entry.Bucket(), entry.Key(), entry.Revision())
+	defer cols.Frender(os.Stdout)
+
+	cols.AddRow("Operation", c.strForOp(entry.Operation()))
+	cols.AddRow("Created", entry.Created())
+	cols.AddRow("Length", len(entry.Value()))
+	if entry.Operation() == nats.KeyValuePut {
+		cols.AddSectionTitle("Value")
+		cols.Println(base64IfNotPrintable(entry.Value()))
+	}
+
+	return nil
+}
+
+// findRevision gets a specific revision, GetRevision does not return delete and purge markers so they are found in the history
+func (c *kvCommand) findRevision(store nats.KeyValue) (nats.KeyValueEntry, error) {
+	entry, err := store.GetRevision(c.key, c.revision)
+	if err == nil {
+		return entry, nil
+	}
+	if !errors.Is(err, nats.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	history, herr := store.History(c.key)
+	if herr != nil {
+		return nil, err
+	}
+
+	for _, entry := range history {
+		if entry.Revision() == c.revision {
+			return entry, nil
+		}
+	}
+
+	return nil, fmt.Errorf("revision %d of key %s not found, it might be removed from the history", c.revision, c.key)
+}
+
+func (c *kvCommand) revisionBeforeTime(store nats.KeyValue) (nats.KeyValueEntry, error) {
+	ts, err := parseTimeString(c.revisionBefore, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := store.History(c.key)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := kvRevisionBefore(history, ts)
+	if entry == nil {
+		return nil, fmt.Errorf("no revision of key %s was created before %s, the oldest kept revision is %d created %s", c.key, ts.Format(time.RFC3339), history[0].Revision(), history[0].Created().Format(time.RFC3339))
+	}
+
+	return entry, nil
+}
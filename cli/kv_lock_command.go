@@ -0,0 +1,316 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+	"github.com/nats-io/nuid"
+)
+
+// kvLockHolder is the value of a lock key, every refresh writes it again so the entry creation time is the last refresh
+type kvLockHolder struct {
+	ID       string        `json:"id"`
+	Host     string        `json:"host"`
+	PID      int           `json:"pid"`
+	Command  string        `json:"command"`
+	Acquired time.Time     `json:"acquired"`
+	TTL      time.Duration `json:"ttl"`
+}
+
+type kvLockStatus struct {
+	Bucket    string        `json:"bucket"`
+	Key       string        `json:"key"`
+	Held      bool          `json:"held"`
+	Stale     bool          `json:"stale"`
+	Revision  uint64        `json:"revision,omitempty"`
+	Refreshed *time.Time    `json:"refreshed,omitempty"`
+	Holder    *kvLockHolder `json:"holder,omitempty"`
+}
+
+func (h *kvLockHolder) String() string {
+	if h == nil {
+		return "an unknown holder"
+	}
+
+	return fmt.Sprintf("pid %d on %s since %s", h.PID, h.Host, f(h.Acquired))
+}
+
+// kvLockState parses a lock entry, the lock is stale once it was not refreshed within the TTL of its holder or ttl
+// when the holder is unknown. Refreshes are timestamped by the server so clocks should be roughly in sync
+func kvLockState(entry nats.KeyValueEntry, now time.Time, ttl time.Duration) (*kvLockHolder, bool) {
+	var holder *kvLockHolder
+
+	err := json.Unmarshal(entry.Value(), &holder)
+	if err == nil && holder != nil && holder.TTL > 0 {
+		ttl = holder.TTL
+	} else {
+		holder = nil
+	}
+
+	return holder, now.Sub(entry.Created()) > ttl
+}
+
+func (c *kvCommand) lockAction(_ *fisk.ParseContext) error {
+	switch {
+	case c.lockTTL <= 0:
+		return fmt.Errorf("ttl must be positive")
+	case c.lockTimeout < 0:
+		return fmt.Errorf("wait can not be negative")
+	case c.lockNoWait && c.lockTimeout > 0:
+		return fmt.Errorf("only one of wait or no-wait can be used")
+	}
+
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	holder := &kvLockHolder{
+		ID:      nuid.Next(),
+		Host:    host,
+		PID:     os.Getpid(),
+		Command: strings.Join(c.lockCommand, " "),
+		TTL:     c.lockTTL,
+	}
+
+	lctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	val, rev, err := c.acquireLock(lctx, store, holder)
+	if err != nil {
+		return err
+	}
+
+	err = c.runLocked(lctx, store, val, rev)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		os.Exit(exitErr.ExitCode())
+	}
+
+	return err
+}
+
+// acquireLock creates the lock key, held locks are waited for until they are released or become stale. Stale locks are
+// taken over using the revision that was found stale so only one of many waiting processes can succeed
+func (c *kvCommand) acquireLock(ctx context.Context, store nats.KeyValue, holder *kvLockHolder) ([]byte, uint64, error) {
+	poll := c.lockTTL / 10
+	switch {
+	case poll < 100*time.Millisecond:
+		poll = 100 * time.Millisecond
+	case poll > time.Second:
+		poll = time.Second
+	}
+
+	var timeout <-chan time.Time
+	if c.lockTimeout > 0 {
+		timeout = time.After(c.lockTimeout)
+	}
+
+	waiting := false
+
+	for {
+		holder.Acquired = time.Now().UTC()
+		val, err := json.Marshal(holder)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		rev, err := store.Create(c.key, val)
+		if err == nil {
+			return val, rev, nil
+		}
+		if !errors.Is(err, nats.ErrKeyExists) {
+			return nil, 0, err
+		}
+
+		entry, err := store.Get(c.key)
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			// released after the create failed
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		current, stale := kvLockState(entry, time.Now(), c.lockTTL)
+		if stale {
+			rev, err = store.Update(c.key, val, entry.Revision())
+			if err == nil {
+				log.Printf("Took over stale lock %s > %s from %s", c.bucket, c.key, current)
+				return val, rev, nil
+			}
+			if !errors.Is(err, nats.ErrKeyExists) {
+				return nil, 0, err
+			}
+
+			// another process took over first
+			continue
+		}
+
+		if c.lockNoWait {
+			return nil, 0, fmt.Errorf("lock %s > %s is held by %s", c.bucket, c.key, current)
+		}
+
+		if !waiting {
+			log.Printf("Waiting for lock %s > %s held by %s", c.bucket, c.key, current)
+			waiting = true
+		}
+
+		select {
+		case <-time.After(poll):
+		case <-timeout:
+			return nil, 0, fmt.Errorf("timeout waiting for lock %s > %s held by %s", c.bucket, c.key, current)
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		}
+	}
+}
+
+// runLocked runs the command while refreshing the lock, the command is stopped when the lock can not be refreshed
+// before it becomes stale. The lock is released unless it was lost
+func (c *kvCommand) runLocked(ctx context.Context, store nats.KeyValue, val []byte, rev uint64) error {
+	cctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	cmd := exec.CommandContext(cctx, c.lockCommand[0], c.lockCommand[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Cancel = func() error { return cmd.Process.Signal(syscall.SIGTERM) }
+	cmd.WaitDelay = 10 * time.Second
+
+	err := cmd.Start()
+	if err != nil {
+		c.releaseLock(store, rev)
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(c.lockTTL / 3)
+	defer ticker.Stop()
+
+	refreshed := time.Now()
+
+	for {
+		select {
+		case err = <-done:
+			c.releaseLock(store, rev)
+			return err
+
+		case <-ticker.C:
+			nrev, err := store.Update(c.key, val, rev)
+			switch {
+			case err == nil:
+				rev = nrev
+				refreshed = time.Now()
+				continue
+			case !errors.Is(err, nats.ErrKeyExists) && time.Since(refreshed) < c.lockTTL:
+				logErrorf("Could not refresh lock %s > %s: %v", c.bucket, c.key, err)
+				continue
+			}
+
+			log.Printf("Lost lock %s > %s, stopping the command: %v", c.bucket, c.key, err)
+			stop()
+			<-done
+
+			return fmt.Errorf("lost lock %s > %s", c.bucket, c.key)
+		}
+	}
+}
+
+// releaseLock deletes the lock only when it is still at the revision written by this process
+func (c *kvCommand) releaseLock(store nats.KeyValue, rev uint64) {
+	err := store.Delete(c.key, nats.LastRevision(rev))
+	if err != nil {
+		logErrorf("Could not release lock %s > %s: %v", c.bucket, c.key, err)
+	}
+}
+
+func (c *kvCommand) lockStatusAction(_ *fisk.ParseContext) error {
+	_, _, store, err := c.loadBucket()
+	if err != nil {
+		return err
+	}
+
+	status := &kvLockStatus{Bucket: c.bucket, Key: c.key}
+
+	entry, err := store.Get(c.key)
+	switch {
+	case errors.Is(err, nats.ErrKeyNotFound):
+	case err != nil:
+		return err
+	default:
+		created := entry.Created()
+		status.Held = true
+		status.Revision = entry.Revision()
+		status.Refreshed = &created
+		status.Holder, status.Stale = kvLockState(entry, time.Now(), 0)
+	}
+
+	if c.json {
+		return iu.PrintJSON(status)
+	}
+
+	if !status.Held {
+		fmt.Printf("Lock %s > %s is not held\n", c.bucket, c.key)
+		return nil
+	}
+
+	cols := newColumns("Lock %s > %s", c.bucket, c.key)
+	defer cols.Frender(os.Stdout)
+
+	switch {
+	case status.Holder == nil:
+		cols.AddRow("Status", "held by an unknown holder")
+	case status.Stale:
+		cols.AddRow("Status", "stale, the holder did not refresh it in time")
+	default:
+		cols.AddRow("Status", "held")
+	}
+
+	cols.AddRow("Revision", status.Revision)
+	cols.AddRow("Last Refreshed", *status.Refreshed)
+
+	if status.Holder != nil {
+		cols.AddRow("Host", status.Holder.Host)
+		cols.AddRow("PID", strconv.Itoa(status.Holder.PID))
+		cols.AddRowIfNotEmpty("Command", status.Holder.Command)
+		cols.AddRow("Acquired", status.Holder.Acquired)
+		cols.AddRow("TTL", status.Holder.TTL)
+	}
+
+	return nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	glog "log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// goLogger logs diagnostics with timestamps, messages below level are discarded and when json is set every message is
+// logged as a JSON document. The zero value logs everything from the info level using the standard logger
+type goLogger struct {
+	level slog.Level
+	text  *glog.Logger
+	json  *slog.Logger
+}
+
+// newGoLogger creates a logger writing to w for a level of debug, info, warn or error
+func newGoLogger(level string, asJSON bool, w io.Writer) (goLogger, error) {
+	l := goLogger{}
+
+	switch level {
+	case "debug":
+		l.level = slog.LevelDebug
+	case "", "info":
+		l.level = slog.LevelInfo
+	case "warn":
+		l.level = slog.LevelWarn
+	case "error":
+		l.level = slog.LevelError
+	default:
+		return l, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", level)
+	}
+
+	if asJSON {
+		l.json = slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: l.level}))
+	} else {
+		l.text = glog.New(w, "", glog.Flags())
+	}
+
+	return l, nil
+}
+
+func (l goLogger) log(level slog.Level, msg string) {
+	if level < l.level {
+		return
+	}
+
+	if l.json != nil {
+		l.json.Log(context.Background(), level, strings.TrimRight(msg, "\n"))
+		return
+	}
+
+	if level != slog.LevelInfo {
+		msg = fmt.Sprintf("[%s] %s", level, msg)
+	}
+
+	if l.text != nil {
+		l.text.Print(msg)
+	} else {
+		glog.Print(msg)
+	}
+}
+
+func (l goLogger) fatal(msg string) {
+	if l.json == nil && l.text == nil {
+		glog.Fatal(msg)
+	}
+
+	l.log(slog.LevelError, msg)
+	os.Exit(1)
+}
+
+func (l goLogger) Debugf(format string, a ...any) { l.log(slog.LevelDebug, fmt.Sprintf(format, a...)) }
+func (l goLogger) Warnf(format string, a ...any)  { l.log(slog.LevelWarn, fmt.Sprintf(format, a...)) }
+func (l goLogger) Errorf(format string, a ...any) { l.log(slog.LevelError, fmt.Sprintf(format, a...)) }
+func (l goLogger) Printf(format string, a ...any) { l.log(slog.LevelInfo, fmt.Sprintf(format, a...)) }
+func (l goLogger) Print(a ...any)                 { l.log(slog.LevelInfo, fmt.Sprint(a...)) }
+func (l goLogger) Println(a ...any)               { l.log(slog.LevelInfo, fmt.Sprintln(a...)) }
+func (l goLogger) Fatalf(format string, a ...any) { l.fatal(fmt.Sprintf(format, a...)) }
+func (l goLogger) Fatal(a ...any)                 { l.fatal(fmt.Sprint(a...)) }
+
+// quietLogger discards informational output while still showing warnings, errors and failing fatally
+type quietLogger struct {
+	l Logger
+}
+
+func (q quietLogger) Fatalf(format string, a ...any) { q.l.Fatalf(format, a...) }
+func (quietLogger) Printf(string, ...any)            {}
+func (quietLogger) Print(...any)                     {}
+func (quietLogger) Println(...any)                   {}
+func (q quietLogger) Fatal(a ...any)                 { q.l.Fatal(a...) }
+func (quietLogger) Debugf(string, ...any)            {}
+func (q quietLogger) Warnf(format string, a ...any)  { warnf(q.l, format, a...) }
+func (q quietLogger) Errorf(format string, a ...any) { errorf(q.l, format, a...) }
+
+// logDebugf logs at debug level, loggers set using SetLogger that do not support levels do not receive debug messages
+func logDebugf(format string, a ...any) {
+	if l, ok := log.(LevelLogger); ok {
+		l.Debugf(format, a...)
+	}
+}
+
+// logWarnf logs at warning level, loggers that do not support levels receive the message as an informational one
+func logWarnf(format string, a ...any) { warnf(log, format, a...) }
+
+// logErrorf logs at error level, loggers that do not support levels receive the message as an informational one
+func logErrorf(format string, a ...any) { errorf(log, format, a...) }
+
+func warnf(l Logger, format string, a ...any) {
+	if ll, ok := l.(LevelLogger); ok {
+		ll.Warnf(format, a...)
+	} else {
+		l.Printf(format, a...)
+	}
+}
+
+func errorf(l Logger, format string, a ...any) {
+	if ll, ok := l.(LevelLogger); ok {
+		ll.Errorf(format, a...)
+	} else {
+		l.Printf(format, a...)
+	}
+}
+
+// logDebugEnabled is true when the debug log level was selected
+func logDebugEnabled() bool {
+	return opts().LogLevel == "debug"
+}
+
+// apiTraceWriter receives the JetStream API traces jsm.go writes to the standard logger and logs a summary of each
+// request and response at debug level
+type apiTraceWriter struct{}
+
+func (apiTraceWriter) Write(p []byte) (int, error) {
+	logDebugf("%s", summarizeAPITrace(string(p)))
+
+	return len(p), nil
+}
+
+// summarizeAPITrace turns a trace like ">>> $JS.API.STREAM.INFO.X\n{...}" into a one line summary, other messages
+// are returned as is
+func summarizeAPITrace(trace string) string {
+	trace = strings.TrimSpace(trace)
+
+	dir, rest, ok := strings.Cut(trace, " ")
+	if !ok || (dir != ">>>" && dir != "<<<") {
+		return trace
+	}
+
+	subject, body, _ := strings.Cut(rest, "\n")
+	body = strings.TrimSpace(body)
+
+	if dir == ">>>" {
+		return fmt.Sprintf("JetStream API request %s with %d bytes", subject, len(body))
+	}
+
+	if subj, err, failed := strings.Cut(subject, ": "); failed {
+		return fmt.Sprintf("JetStream API request %s failed: %s", subj, err)
+	}
+
+	summary := fmt.Sprintf("JetStream API response on %s with %d bytes", subject, len(body))
+
+	var resp api.JSApiResponse
+	if json.Unmarshal([]byte(body), &resp) == nil && resp.Error != nil {
+		summary += fmt.Sprintf(", error %d: %s", resp.Error.ErrCode, resp.Error.Description)
+	}
+
+	return summary
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGoLogger(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := newGoLogger("info", false, &buf)
+		if err != nil {
+			t.Fatalf("logger failed: %v", err)
+		}
+
+		l.Debugf("debug %d", 1)
+		l.Printf("info %d", 1)
+		l.Warnf("warn %d", 1)
+		l.Errorf("error %d", 1)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 lines got %q", buf.String())
+		}
+		for i, expected := range []string{"info 1", "[WARN] warn 1", "[ERROR] error 1"} {
+			if !strings.HasSuffix(lines[i], expected) {
+				t.Fatalf("expected line %d to end with %q got %q", i, expected, lines[i])
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, err := newGoLogger("warn", true, &buf)
+		if err != nil {
+			t.Fatalf("logger failed: %v", err)
+		}
+
+		l.Printf("info\n")
+		l.Warnf("disconnected from %s", "nats://localhost:4222")
+
+		var doc map[string]any
+		err = json.Unmarshal(buf.Bytes(), &doc)
+		if err != nil {
+			t.Fatalf("expected a single JSON document got %q: %v", buf.String(), err)
+		}
+		if doc["level"] != "WARN" || doc["msg"] != "disconnected from nats://localhost:4222" || doc["time"] == nil {
+			t.Fatalf("unexpected document %v", doc)
+		}
+	})
+
+	t.Run("quiet", func(t *testing.T) {
+		var buf bytes.Buffer
+		l, _ := newGoLogger("debug", false, &buf)
+		q := quietLogger{l}
+
+		q.Printf("info")
+		q.Debugf("debug")
+		q.Warnf("warn")
+
+		if out := strings.TrimSpace(buf.String()); !strings.HasSuffix(out, "[WARN] warn") || strings.Contains(out, "\n") {
+			t.Fatalf("expected only the warning got %q", buf.String())
+		}
+	})
+
+	if _, err := newGoLogger("trace", false, nil); err == nil {
+		t.Fatalf("expected an invalid level to fail")
+	}
+}
+
+func TestSummarizeAPITrace(t *testing.T) {
+	for trace, expected := range map[string]string{
+		">>> $JS.API.STREAM.INFO.X\n{}\n\n":               "JetStream API request $JS.API.STREAM.INFO.X with 2 bytes",
+		"<<< $JS.API.STREAM.INFO.X: nats: timeout\n\n":    "JetStream API request $JS.API.STREAM.INFO.X failed: nats: timeout",
+		"<<< $JS.API.STREAM.INFO.X\n{\"type\":\"x\"}\n\n": `JetStream API response on $JS.API.STREAM.INFO.X with 12 bytes`,
+		"something else\n":                                "something else",
+		`<<< $JS.API.STREAM.INFO.X` + "\n" + `{"error":{"code":404,"err_code":10059,"description":"stream not found"}}`: `JetStream API response on $JS.API.STREAM.INFO.X with 72 bytes, error 10059: stream not found`,
+	} {
+		if actual := summarizeAPITrace(trace); actual != expected {
+			t.Fatalf("expected %q for %q got %q", expected, trace, actual)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// capturedMsg is the JSON format messages are saved in by sub --dump, pub --capture and stream export, the stream
+// and sequence are only known for messages read from JetStream
+type capturedMsg struct {
+	Subject  string      `json:"Subject"`
+	Reply    string      `json:"Reply"`
+	Header   nats.Header `json:"Header"`
+	Data     []byte      `json:"Data"`
+	Time     time.Time   `json:"Time"`
+	Stream   string      `json:"Stream,omitempty"`
+	Sequence uint64      `json:"Sequence,omitempty"`
+}
+
+func newCapturedMsg(msg *nats.Msg, t time.Time) *capturedMsg {
+	return &capturedMsg{
+		Subject: msg.Subject,
+		Reply:   msg.Reply,
+		Header:  msg.Header,
+		Data:    msg.Data,
+		Time:    t,
+	}
+}
+
+const (
+	msgCaptureQueueSize     = 10000
+	msgCaptureFlushInterval = time.Second
+)
+
+// msgCaptureWriter saves messages to a file one JSON document per line, writing and flushing in the background
+type msgCaptureWriter struct {
+	file    *os.File
+	queue   chan *capturedMsg
+	done    chan struct{}
+	written uint64
+	err     error
+	once    sync.Once
+}
+
+func newMsgCaptureWriter(path string) (*msgCaptureWriter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &msgCaptureWriter{
+		file:  file,
+		queue: make(chan *capturedMsg, msgCaptureQueueSize),
+		done:  make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *msgCaptureWriter) run() {
+	defer close(w.done)
+
+	buf := bufio.NewWriterSize(w.file, 64*1024)
+	enc := json.NewEncoder(buf)
+
+	ticker := time.NewTicker(msgCaptureFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-w.queue:
+			if !ok {
+				w.setErr(buf.Flush())
+				w.setErr(w.file.Close())
+				return
+			}
+
+			if w.err != nil {
+				continue
+			}
+
+			err := enc.Encode(msg)
+			if err != nil {
+				w.setErr(err)
+				continue
+			}
+			w.written++
+
+		case <-ticker.C:
+			w.setErr(buf.Flush())
+		}
+	}
+}
+
+func (w *msgCaptureWriter) setErr(err error) {
+	if w.err == nil {
+		w.err = err
+	}
+}
+
+// capture queues msg for writing, it only blocks when the writer falls far behind
+func (w *msgCaptureWriter) capture(msg *nats.Msg) {
+	w.write(newCapturedMsg(msg, time.Now()))
+}
+
+// write queues an already captured message for writing
+func (w *msgCaptureWriter) write(msg *capturedMsg) {
+	w.queue <- msg
+}
+
+// close writes all queued messages and closes the file, returning the number of messages written
+func (w *msgCaptureWriter) close() (uint64, error) {
+	w.once.Do(func() { close(w.queue) })
+	<-w.done
+
+	return w.written, w.err
+}
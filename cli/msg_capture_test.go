@@ -0,0 +1,80 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestMsgCaptureWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.json")
+
+	w, err := newMsgCaptureWriter(path)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	for i := 0; i < 2*msgCaptureQueueSize; i++ {
+		msg := nats.NewMsg(fmt.Sprintf("test.%d", i))
+		msg.Header.Set("Seq", fmt.Sprintf("%d", i))
+		msg.Data = []byte("hello")
+		w.capture(msg)
+	}
+
+	written, err := w.close()
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if written != 2*msgCaptureQueueSize {
+		t.Fatalf("expected %d messages written got %d", 2*msgCaptureQueueSize, written)
+	}
+
+	// closing again is safe and reports the same result
+	again, err := w.close()
+	if err != nil || again != written {
+		t.Fatalf("second close returned %d: %v", again, err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer f.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var msg capturedMsg
+		err = json.Unmarshal(scanner.Bytes(), &msg)
+		if err != nil {
+			t.Fatalf("invalid capture line %d: %v", lines, err)
+		}
+
+		if msg.Subject != fmt.Sprintf("test.%d", lines) || msg.Header.Get("Seq") != fmt.Sprintf("%d", lines) || string(msg.Data) != "hello" || msg.Time.IsZero() {
+			t.Fatalf("unexpected message %d: %+v", lines, msg)
+		}
+		lines++
+	}
+
+	if lines != 2*msgCaptureQueueSize {
+		t.Fatalf("expected %d lines got %d", 2*msgCaptureQueueSize, lines)
+	}
+}
@@ -14,12 +14,15 @@
 package cli
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,7 +30,6 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/fatih/color"
 	"github.com/gosuri/uiprogress"
 	"github.com/nats-io/jsm.go"
@@ -48,6 +50,8 @@ type objCommand struct {
 	maxBucketSize       int64
 	maxBucketSizeString string
 	metadata            map[string]string
+	verify              bool
+	getRange            string
 
 	description string
 	replicas    uint
@@ -105,10 +109,12 @@ NOTE: This is an experimental feature.
 	get.Flag("output", "Override the output file name").Short('O').StringVar(&c.overrideName)
 	get.Flag("progress", "Disable progress bars").Default("true").BoolVar(&c.progress)
 	get.Flag("force", "Act without confirmation").Short('f').UnNegatableBoolVar(&c.force)
+	get.Flag("range", "Only retrieve a byte range like 0-1023, 1024- or -512 for the last 512 bytes").PlaceHolder("RANGE").StringVar(&c.getRange)
 
 	info := obj.Command("info", "Get information about a bucket or object").Alias("show").Alias("i").Action(c.infoAction)
 	info.Arg("bucket", "The bucket to act on").StringVar(&c.bucket)
 	info.Arg("file", "The file to retrieve").StringVar(&c.file)
+	info.Flag("verify", "Downloads the object and verifies its digest").UnNegatableBoolVar(&c.verify)
 
 	ls := obj.Command("ls", "List buckets or contents of a specific bucket").Action(c.lsAction)
 	ls.Arg("bucket", "The bucket to act on").StringVar(&c.bucket)
@@ -157,7 +163,7 @@ func (c *objCommand) watchAction(_ *fisk.ParseContext) error {
 		if i.Deleted {
 			fmt.Printf("[%s] %s %s > %s\n", f(i.ModTime), color.RedString("DEL"), i.Bucket, i.Name)
 		} else {
-			fmt.Printf("[%s] %s %s > %s: %s bytes in %s chunks\n", f(i.ModTime), color.GreenString("PUT"), i.Bucket, i.Name, humanize.IBytes(i.Size), f(i.Chunks))
+			fmt.Printf("[%s] %s %s > %s: %s bytes in %s chunks\n", f(i.ModTime), color.GreenString("PUT"), i.Bucket, i.Name, fiBytes(i.Size), f(i.Chunks))
 		}
 	}
 
@@ -202,7 +208,7 @@ func (c *objCommand) delAction(_ *fisk.ParseContext) error {
 				return err
 			}
 
-			ok, err := askConfirmation(fmt.Sprintf("Delete %s byte file %s > %s?", humanize.IBytes(nfo.Size), c.bucket, c.file), false)
+			ok, err := askConfirmation(fmt.Sprintf("Delete %s byte file %s > %s?", fiBytes(nfo.Size), c.bucket, c.file), false)
 			if err != nil {
 				return err
 			}
@@ -260,9 +266,70 @@ func (c *objCommand) infoAction(_ *fisk.ParseContext) error {
 
 	c.showObjectInfo(nfo)
 
+	if !c.verify {
+		return nil
+	}
+
+	if nfo.Deleted {
+		return fmt.Errorf("cannot verify deleted object %s", nfo.Name)
+	}
+
+	return c.verifyObject(obj, nfo)
+}
+
+// verifyObject downloads the object and compares its SHA-256 digest to the one stored in its metadata
+func (c *objCommand) verifyObject(obj nats.ObjectStore, nfo *nats.ObjectInfo) error {
+	digest := strings.SplitN(nfo.Digest, "=", 2)
+	if len(digest) != 2 || !strings.EqualFold(digest[0], "SHA-256") {
+		return fmt.Errorf("unsupported digest %q", nfo.Digest)
+	}
+
+	expected, err := base64.URLEncoding.DecodeString(digest[1])
+	if err != nil {
+		return fmt.Errorf("invalid digest %q: %w", nfo.Digest, err)
+	}
+
+	res, err := obj.Get(nfo.Name)
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, res)
+	if err != nil {
+		return fmt.Errorf("verification failed: %w", err)
+	}
+
+	if uint64(n) != nfo.Size {
+		return fmt.Errorf("verification failed: read %s, expected %s", fiBytes(uint64(n)), fiBytes(nfo.Size))
+	}
+
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return fmt.Errorf("verification failed: digest mismatch")
+	}
+
+	fmt.Printf("Verified %s digest %x\n", fiBytes(nfo.Size), expected)
+
 	return nil
 }
 
+// objectMimeType finds the MIME type of an object from its headers or metadata
+func objectMimeType(nfo *nats.ObjectInfo) string {
+	if ct := nfo.Headers.Get("Content-Type"); ct != "" {
+		return ct
+	}
+
+	for k, v := range nfo.Metadata {
+		switch strings.ToLower(k) {
+		case "content-type", "mime-type", "mime_type", "mimetype":
+			return v
+		}
+	}
+
+	return ""
+}
+
 func (c *objCommand) showBucketInfo(store nats.ObjectStore) error {
 	status, err := store.Status()
 	if err != nil {
@@ -293,12 +360,12 @@ func (c *objCommand) showBucketInfo(store nats.ObjectStore) error {
 		cols.AddRow("TTL", status.TTL())
 	}
 	cols.AddRow("Sealed", status.Sealed())
-	cols.AddRow("Size", humanize.IBytes(status.Size()))
+	cols.AddRow("Size", fiBytes(status.Size()))
 	if nfo != nil {
 		if nfo.Config.MaxBytes == -1 {
 			cols.AddRow("Maximum Bucket Size", "unlimited")
 		} else {
-			cols.AddRow("Maximum Bucket Size", humanize.IBytes(uint64(nfo.Config.MaxBytes)))
+			cols.AddRow("Maximum Bucket Size", fiBytes(uint64(nfo.Config.MaxBytes)))
 		}
 	}
 	cols.AddRow("Backing Store Kind", status.BackingStore())
@@ -331,8 +398,12 @@ func (c *objCommand) showObjectInfo(nfo *nats.ObjectInfo) {
 	cols.AddRow("Size", fiBytes(nfo.Size))
 	cols.AddRow("Modification Time", nfo.ModTime)
 	cols.AddRow("Chunks", nfo.Chunks)
+	cols.AddRowIfNotEmpty("MIME Type", objectMimeType(nfo))
 	cols.AddRowf("Digest", "%s %x", digest[0], digestBytes)
 	cols.AddRowIf("Deleted", nfo.Deleted, nfo.Deleted)
+	if len(nfo.Metadata) > 0 {
+		cols.AddMapStringsAsValue("Metadata", nfo.Metadata)
+	}
 	if len(nfo.Headers) > 0 {
 		var vals []string
 		for k, v := range nfo.Headers {
@@ -384,7 +455,7 @@ func (c *objCommand) listBuckets() error {
 	for _, s := range found {
 		nfo, _ := s.LatestInformation()
 
-		table.AddRow(strings.TrimPrefix(s.Name(), "OBJ_"), s.Description(), f(nfo.Created), humanize.IBytes(nfo.State.Bytes), f(time.Since(nfo.State.LastTime)))
+		table.AddRow(strings.TrimPrefix(s.Name(), "OBJ_"), s.Description(), f(nfo.Created), fiBytes(nfo.State.Bytes), f(time.Since(nfo.State.LastTime)))
 	}
 
 	fmt.Println(table.Render())
@@ -423,7 +494,7 @@ func (c *objCommand) lsAction(_ *fisk.ParseContext) error {
 	table.AddHeaders("Name", "Size", "Time")
 
 	for _, i := range contents {
-		table.AddRow(i.Name, humanize.IBytes(i.Size), i.ModTime.Format(time.RFC3339))
+		table.AddRow(i.Name, fiBytes(i.Size), i.ModTime.Format(time.RFC3339))
 	}
 
 	fmt.Println(table.Render())
@@ -495,9 +566,9 @@ func (c *objCommand) putAction(_ *fisk.ParseContext) error {
 	stop := func() {}
 
 	if !opts().Trace && c.progress && stat != nil && stat.Size() > 20480 {
-		hs := humanize.IBytes(uint64(stat.Size()))
+		hs := fiBytes(uint64(stat.Size()))
 		progress = uiprogress.AddBar(int(stat.Size())).PrependFunc(func(b *uiprogress.Bar) string {
-			return fmt.Sprintf("%s / %s", humanize.IBytes(uint64(b.Current())), hs)
+			return fmt.Sprintf("%s / %s", fiBytes(uint64(b.Current())), hs)
 		})
 		progress.Width = progressWidth()
 
@@ -519,17 +590,24 @@ func (c *objCommand) putAction(_ *fisk.ParseContext) error {
 }
 
 func (c *objCommand) getAction(_ *fisk.ParseContext) error {
-	_, _, obj, err := c.loadBucket()
+	_, js, obj, err := c.loadBucket()
 	if err != nil {
 		return err
 	}
 
-	res, err := obj.Get(c.file)
-	if err != nil {
-		return err
-	}
+	var res nats.ObjectResult
+	var nfo *nats.ObjectInfo
 
-	nfo, err := res.Info()
+	if c.getRange == "" {
+		res, err = obj.Get(c.file)
+		if err != nil {
+			return err
+		}
+
+		nfo, err = res.Info()
+	} else {
+		nfo, err = obj.GetInfo(c.file)
+	}
 	if err != nil {
 		return err
 	}
@@ -538,6 +616,20 @@ func (c *objCommand) getAction(_ *fisk.ParseContext) error {
 		return fmt.Errorf("file has been deleted")
 	}
 
+	size := nfo.Size
+	var first, last uint64
+	if c.getRange != "" {
+		if nfo.Opts != nil && nfo.Opts.Link != nil {
+			return fmt.Errorf("ranges can not be retrieved from links")
+		}
+
+		first, last, err = parseObjRange(c.getRange, nfo.Size)
+		if err != nil {
+			return err
+		}
+		size = last - first + 1
+	}
+
 	out := filepath.Base(nfo.Name)
 	if c.overrideName != "" {
 		out = c.overrideName
@@ -570,10 +662,10 @@ func (c *objCommand) getAction(_ *fisk.ParseContext) error {
 	pw := io.Writer(of)
 	stop := func() {}
 
-	if !opts().Trace && c.progress && nfo.Size > 20480 {
-		hs := humanize.IBytes(nfo.Size)
-		progress = uiprogress.AddBar(int(nfo.Size)).PrependFunc(func(b *uiprogress.Bar) string {
-			return fmt.Sprintf("%s / %s", humanize.IBytes(uint64(b.Current())), hs)
+	if !opts().Trace && c.progress && size > 20480 {
+		hs := fiBytes(size)
+		progress = uiprogress.AddBar(int(size)).PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", fiBytes(uint64(b.Current())), hs)
 		})
 		progress.Width = progressWidth()
 
@@ -584,7 +676,12 @@ func (c *objCommand) getAction(_ *fisk.ParseContext) error {
 	}
 
 	start := time.Now()
-	wc, err := io.Copy(pw, res)
+	var wc int64
+	if c.getRange == "" {
+		wc, err = io.Copy(pw, res)
+	} else {
+		wc, err = getObjectRange(js, nfo, first, last, pw)
+	}
 	stop()
 	if err != nil {
 		of.Close()
@@ -592,18 +689,18 @@ func (c *objCommand) getAction(_ *fisk.ParseContext) error {
 		return err
 	}
 
-	if wc > 0 && uint64(wc) != nfo.Size {
-		return fmt.Errorf("wrote %s, expected %s", humanize.IBytes(uint64(wc)), humanize.IBytes(nfo.Size))
+	if wc > 0 && uint64(wc) != size {
+		return fmt.Errorf("wrote %s, expected %s", fiBytes(uint64(wc)), fiBytes(size))
 	}
 
 	of.Close()
 
 	elapsed := time.Since(start)
 	if elapsed > 2*time.Second {
-		bps := float64(nfo.Size) / elapsed.Seconds()
-		fmt.Printf("Wrote: %s to %s in %v average %s/s\n", humanize.IBytes(uint64(wc)), of.Name(), f(elapsed), humanize.IBytes(uint64(bps)))
+		bps := float64(size) / elapsed.Seconds()
+		log.Printf("Wrote: %s to %s in %v average %s/s", fiBytes(uint64(wc)), of.Name(), f(elapsed), fiBytes(uint64(bps)))
 	} else {
-		fmt.Printf("Wrote: %s to %s in %v\n", humanize.IBytes(uint64(wc)), of.Name(), f(elapsed))
+		log.Printf("Wrote: %s to %s in %v", fiBytes(uint64(wc)), of.Name(), f(elapsed))
 	}
 
 	return nil
@@ -643,6 +740,121 @@ func (c *objCommand) addAction(_ *fisk.ParseContext) error {
 	return c.showBucketInfo(obj)
 }
 
+// parseObjRange parses an inclusive byte range like 0-1023, an offset to the end like 1024- or the last bytes like -512
+// and limits it to the size of the object
+func parseObjRange(spec string, size uint64) (uint64, uint64, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok || (from == "" && to == "") {
+		return 0, 0, fmt.Errorf("invalid range %q, expected a range like 0-1023, 1024- or -512", spec)
+	}
+
+	if size == 0 {
+		return 0, 0, fmt.Errorf("can not retrieve a range from an empty object")
+	}
+
+	var first, last uint64
+	var err error
+
+	switch {
+	case from == "":
+		n, err := strconv.ParseUint(to, 10, 64)
+		if err != nil || n == 0 {
+			return 0, 0, fmt.Errorf("invalid range %q, expected a positive number of bytes after -", spec)
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, nil
+
+	case to == "":
+		last = size - 1
+		first, err = strconv.ParseUint(from, 10, 64)
+
+	default:
+		first, err = strconv.ParseUint(from, 10, 64)
+		if err == nil {
+			last, err = strconv.ParseUint(to, 10, 64)
+		}
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q: %w", spec, err)
+	}
+
+	if first >= size {
+		return 0, 0, fmt.Errorf("range %q starts beyond the end of the %s object", spec, fiBytes(size))
+	}
+	if last < first {
+		return 0, 0, fmt.Errorf("invalid range %q, the end is before the start", spec)
+	}
+	if last >= size {
+		last = size - 1
+	}
+
+	return first, last, nil
+}
+
+// getObjectRange writes the bytes first to last of an object, a headers only consumer finds the size and sequence of
+// every chunk so only the chunks holding the range are downloaded
+func getObjectRange(js nats.JetStreamContext, nfo *nats.ObjectInfo, first uint64, last uint64, w io.Writer) (int64, error) {
+	stream := fmt.Sprintf("OBJ_%s", nfo.Bucket)
+
+	sub, err := js.SubscribeSync(fmt.Sprintf("$O.%s.C.%s", nfo.Bucket, nfo.NUID), nats.OrderedConsumer(), nats.HeadersOnly(), nats.BindStream(stream))
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	var offset uint64
+	var written int64
+
+	for chunk := uint32(0); chunk < nfo.Chunks && offset <= last; chunk++ {
+		msg, err := sub.NextMsg(opts().Timeout)
+		if err != nil {
+			return written, fmt.Errorf("could not read chunk %d: %w", chunk, err)
+		}
+
+		size, err := strconv.ParseUint(msg.Header.Get(nats.MsgSize), 10, 64)
+		if err != nil {
+			return written, fmt.Errorf("invalid size for chunk %d: %w", chunk, err)
+		}
+
+		end := offset + size
+		if end > first {
+			meta, err := msg.Metadata()
+			if err != nil {
+				return written, err
+			}
+
+			raw, err := js.GetMsg(stream, meta.Sequence.Stream)
+			if err != nil {
+				return written, fmt.Errorf("could not get chunk %d: %w", chunk, err)
+			}
+			if uint64(len(raw.Data)) != size {
+				return written, fmt.Errorf("chunk %d holds %d bytes, expected %d", chunk, len(raw.Data), size)
+			}
+
+			from := uint64(0)
+			if first > offset {
+				from = first - offset
+			}
+			to := size
+			if last+1 < end {
+				to = last + 1 - offset
+			}
+
+			n, err := w.Write(raw.Data[from:to])
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+
+		offset = end
+	}
+
+	return written, nil
+}
+
 func (c *objCommand) loadBucket() (*nats.Conn, nats.JetStreamContext, nats.ObjectStore, error) {
 	nc, js, err := prepareJSHelper()
 	if err != nil {
@@ -0,0 +1,60 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+)
+
+func TestParseObjRange(t *testing.T) {
+	for _, tc := range []struct {
+		spec  string
+		first uint64
+		last  uint64
+		err   bool
+	}{
+		{spec: "0-1023", first: 0, last: 1023},
+		{spec: "10-10", first: 10, last: 10},
+		{spec: "1000-", first: 1000, last: 1999},
+		{spec: "-512", first: 1488, last: 1999},
+		{spec: "-5000", first: 0, last: 1999},
+		{spec: "1500-9999", first: 1500, last: 1999},
+		{spec: "2000-", err: true},
+		{spec: "10-5", err: true},
+		{spec: "-", err: true},
+		{spec: "-0", err: true},
+		{spec: "abc", err: true},
+		{spec: "a-10", err: true},
+	} {
+		first, last, err := parseObjRange(tc.spec, 2000)
+		if tc.err {
+			if err == nil {
+				t.Fatalf("expected an error for %q", tc.spec)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("unexpected error for %q: %v", tc.spec, err)
+		}
+		if first != tc.first || last != tc.last {
+			t.Fatalf("expected %q to be %d-%d got %d-%d", tc.spec, tc.first, tc.last, first, last)
+		}
+	}
+
+	_, _, err := parseObjRange("0-10", 0)
+	if err == nil {
+		t.Fatalf("expected an error for an empty object")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	terminal "golang.org/x/term"
+)
+
+const defaultPager = "less -FRX"
+
+// pagedWriter buffers report output and, when closed, sends it through a pager
+// if stdout is a terminal and the output would not fit on one screen
+type pagedWriter struct {
+	buf    bytes.Buffer
+	out    io.Writer
+	height int
+	pager  string
+}
+
+// newPagedWriter creates a writer for long command output, paging is disabled
+// when stdout is not a terminal or the user passed --no-pager
+func newPagedWriter() *pagedWriter {
+	w := &pagedWriter{out: os.Stdout}
+
+	if opts().NoPager || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return w
+	}
+
+	_, h, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || h <= 0 {
+		return w
+	}
+
+	w.height = h
+	w.pager = pagerCommand()
+
+	return w
+}
+
+// pagerCommand determines the pager to use from NATS_PAGER, PAGER or the default
+func pagerCommand() string {
+	for _, e := range []string{"NATS_PAGER", "PAGER"} {
+		v, ok := os.LookupEnv(e)
+		if ok {
+			return strings.TrimSpace(v)
+		}
+	}
+
+	return defaultPager
+}
+
+func (w *pagedWriter) Write(p []byte) (int, error) {
+	return w.buf.Write(p)
+}
+
+// shouldPage determines if the buffered output is taller than the terminal
+func (w *pagedWriter) shouldPage() bool {
+	if w.pager == "" || w.height <= 0 {
+		return false
+	}
+
+	return bytes.Count(w.buf.Bytes(), []byte("\n")) >= w.height
+}
+
+// Close writes the buffered output to stdout, through the pager when needed
+func (w *pagedWriter) Close() error {
+	if w.shouldPage() {
+		parts := strings.Fields(w.pager)
+		cmd := exec.Command(parts[0], parts[1:]...)
+		cmd.Stdin = bytes.NewReader(w.buf.Bytes())
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+
+		err := cmd.Run()
+		if err == nil {
+			return nil
+		}
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			// the pager ran but exited non zero, likely the user quit early
+			return nil
+		}
+
+		// the pager could not be started, fall back to plain output
+	}
+
+	_, err := w.out.Write(w.buf.Bytes())
+
+	return err
+}
@@ -0,0 +1,61 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPagerCommand(t *testing.T) {
+	t.Setenv("NATS_PAGER", "")
+	t.Setenv("PAGER", "more")
+	if cmd := pagerCommand(); cmd != "" {
+		t.Fatalf("expected empty NATS_PAGER to disable paging, got %q", cmd)
+	}
+
+	t.Setenv("NATS_PAGER", "most -s")
+	if cmd := pagerCommand(); cmd != "most -s" {
+		t.Fatalf("expected NATS_PAGER to be used, got %q", cmd)
+	}
+}
+
+func TestPagedWriter(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	w := &pagedWriter{out: out, height: 5}
+
+	for i := 0; i < 10; i++ {
+		fmt.Fprintf(w, "line %d\n", i)
+	}
+
+	if w.shouldPage() {
+		t.Fatalf("should not page without a pager")
+	}
+
+	w.pager = defaultPager
+	if !w.shouldPage() {
+		t.Fatalf("should page output taller than the terminal")
+	}
+
+	w.pager = ""
+	err := w.Close()
+	if err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	if bytes.Count(out.Bytes(), []byte("\n")) != 10 {
+		t.Fatalf("expected buffered output to be written, got %q", out.String())
+	}
+}
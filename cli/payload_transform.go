@@ -0,0 +1,213 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/s2"
+)
+
+// payloadTransformer changes a message payload, the subject and stream are those of the message being transformed
+type payloadTransformer func(data []byte, subject string, stream string) ([]byte, error)
+
+// payloadTransforms holds the known transforms by name, each creates a transformer from the argument given after a
+// colon like exec:jq
+var payloadTransforms = map[string]func(arg string) (payloadTransformer, error){
+	"base64":        noArgTransform(base64Encode),
+	"base64-decode": noArgTransform(base64Decode),
+	"exec":          execTransform,
+	"gunzip":        noArgTransform(gunzipDecode),
+	"gzip":          noArgTransform(gzipEncode),
+	"hex":           noArgTransform(hexEncode),
+	"hex-decode":    noArgTransform(hexDecode),
+	"hexdump":       noArgTransform(hexDump),
+	"s2":            noArgTransform(s2Encode),
+	"s2-decode":     noArgTransform(s2Decode),
+}
+
+type payloadTransformStage struct {
+	spec        string
+	transformer payloadTransformer
+}
+
+// payloadTransformPipeline applies a chain of transforms to message payloads in order, a nil pipeline leaves them unchanged
+type payloadTransformPipeline struct {
+	stages []*payloadTransformStage
+}
+
+// payloadTransformNames lists the names of all known transforms
+func payloadTransformNames() []string {
+	names := mapKeys(payloadTransforms)
+	sort.Strings(names)
+
+	return names
+}
+
+// payloadTransformHelp is the help text for flags accepting transforms, dir describes what the transforms apply to
+func payloadTransformHelp(dir string) string {
+	return fmt.Sprintf("Transforms %s, applied in order (pass multiple times, %s)", dir, strings.Join(payloadTransformNames(), ", "))
+}
+
+// newPayloadTransformPipeline creates a pipeline from transform specifications in name or name:argument form,
+// translate is a command given using --translate and is run last, nil is returned when there are no transforms
+func newPayloadTransformPipeline(specs []string, translate string) (*payloadTransformPipeline, error) {
+	if translate != "" {
+		specs = append(append([]string{}, specs...), "exec:"+translate)
+	}
+
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	p := &payloadTransformPipeline{}
+	for _, spec := range specs {
+		name, arg, _ := strings.Cut(spec, ":")
+
+		factory, ok := payloadTransforms[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown transform %q, valid transforms are %s", name, strings.Join(payloadTransformNames(), ", "))
+		}
+
+		transformer, err := factory(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid transform %q: %w", spec, err)
+		}
+
+		p.stages = append(p.stages, &payloadTransformStage{spec: spec, transformer: transformer})
+	}
+
+	return p, nil
+}
+
+// apply runs data through every stage, errors identify the stage that failed
+func (p *payloadTransformPipeline) apply(data []byte, subject string, stream string) ([]byte, error) {
+	if p == nil {
+		return data, nil
+	}
+
+	var err error
+	for i, stage := range p.stages {
+		data, err = stage.transformer(data, subject, stream)
+		if err != nil {
+			return nil, fmt.Errorf("transform %d %q failed: %w", i+1, stage.spec, err)
+		}
+	}
+
+	return data, nil
+}
+
+func noArgTransform(fn func([]byte) ([]byte, error)) func(string) (payloadTransformer, error) {
+	return func(arg string) (payloadTransformer, error) {
+		if arg != "" {
+			return nil, fmt.Errorf("does not accept an argument")
+		}
+
+		return func(data []byte, _ string, _ string) ([]byte, error) {
+			return fn(data)
+		}, nil
+	}
+}
+
+func execTransform(arg string) (payloadTransformer, error) {
+	if arg == "" {
+		return nil, fmt.Errorf("a command is required")
+	}
+
+	return func(data []byte, subject string, stream string) ([]byte, error) {
+		return filterDataThroughCmd(data, arg, subject, stream)
+	}, nil
+}
+
+func base64Encode(data []byte) ([]byte, error) {
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(out, data)
+
+	return out, nil
+}
+
+func base64Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(out, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[:n], nil
+}
+
+func hexEncode(data []byte) ([]byte, error) {
+	out := make([]byte, hex.EncodedLen(len(data)))
+	hex.Encode(out, data)
+
+	return out, nil
+}
+
+func hexDecode(data []byte) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	out := make([]byte, hex.DecodedLen(len(data)))
+	n, err := hex.Decode(out, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return out[:n], nil
+}
+
+func hexDump(data []byte) ([]byte, error) {
+	return []byte(hex.Dump(data)), nil
+}
+
+func gzipEncode(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gunzipDecode(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+func s2Encode(data []byte) ([]byte, error) {
+	return s2.Encode(nil, data), nil
+}
+
+func s2Decode(data []byte) ([]byte, error) {
+	return s2.Decode(nil, data)
+}
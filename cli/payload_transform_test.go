@@ -0,0 +1,120 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPayloadTransformPipeline(t *testing.T) {
+	binary := make([]byte, 256)
+	for i := range binary {
+		binary[i] = byte(i)
+	}
+
+	t.Run("nil", func(t *testing.T) {
+		p, err := newPayloadTransformPipeline(nil, "")
+		checkErr(t, err, "pipeline failed: %v", err)
+		if p != nil {
+			t.Fatalf("expected no pipeline without transforms")
+		}
+
+		out, err := p.apply(binary, "", "")
+		checkErr(t, err, "apply failed: %v", err)
+		if !bytes.Equal(out, binary) {
+			t.Fatalf("expected data to be unchanged")
+		}
+	})
+
+	t.Run("chaining", func(t *testing.T) {
+		encode, err := newPayloadTransformPipeline([]string{"gzip", "base64"}, "")
+		checkErr(t, err, "pipeline failed: %v", err)
+		decode, err := newPayloadTransformPipeline([]string{"base64-decode", "gunzip"}, "")
+		checkErr(t, err, "pipeline failed: %v", err)
+
+		encoded, err := encode.apply([]byte("hello world"), "", "")
+		checkErr(t, err, "encode failed: %v", err)
+		if bytes.Contains(encoded, []byte("hello")) {
+			t.Fatalf("expected encoded data got %q", encoded)
+		}
+
+		// a trailing new line like one added by echo is accepted
+		decoded, err := decode.apply(append(encoded, '\n'), "", "")
+		checkErr(t, err, "decode failed: %v", err)
+		if string(decoded) != "hello world" {
+			t.Fatalf("expected hello world got %q", decoded)
+		}
+	})
+
+	t.Run("binary safety", func(t *testing.T) {
+		for _, pair := range [][2]string{{"base64", "base64-decode"}, {"hex", "hex-decode"}, {"gzip", "gunzip"}, {"s2", "s2-decode"}} {
+			p, err := newPayloadTransformPipeline(pair[:], "")
+			checkErr(t, err, "pipeline failed: %v", err)
+
+			out, err := p.apply(binary, "", "")
+			checkErr(t, err, "%s failed: %v", pair, err)
+			if !bytes.Equal(out, binary) {
+				t.Fatalf("%v did not round trip binary data: %v", pair, out)
+			}
+		}
+
+		p, err := newPayloadTransformPipeline([]string{"hexdump"}, "")
+		checkErr(t, err, "pipeline failed: %v", err)
+		out, err := p.apply(binary, "", "")
+		checkErr(t, err, "hexdump failed: %v", err)
+		if !strings.HasPrefix(string(out), "00000000  00 01 02 03") {
+			t.Fatalf("unexpected hexdump: %s", out)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		p, err := newPayloadTransformPipeline([]string{"base64", "gunzip", "hex"}, "")
+		checkErr(t, err, "pipeline failed: %v", err)
+
+		_, err = p.apply([]byte("hello"), "", "")
+		if err == nil || !strings.HasPrefix(err.Error(), `transform 2 "gunzip" failed: `) {
+			t.Fatalf("expected the gunzip stage to fail got %v", err)
+		}
+
+		_, err = newPayloadTransformPipeline([]string{"gzip", "rot13"}, "")
+		if err == nil || !strings.Contains(err.Error(), `unknown transform "rot13"`) {
+			t.Fatalf("expected an unknown transform error got %v", err)
+		}
+
+		_, err = newPayloadTransformPipeline([]string{"gzip:9"}, "")
+		if err == nil || !strings.Contains(err.Error(), "does not accept an argument") {
+			t.Fatalf("expected an argument error got %v", err)
+		}
+
+		_, err = newPayloadTransformPipeline([]string{"exec"}, "")
+		if err == nil || !strings.Contains(err.Error(), "a command is required") {
+			t.Fatalf("expected a command error got %v", err)
+		}
+	})
+
+	t.Run("translate", func(t *testing.T) {
+		specs := []string{"gunzip"}
+		p, err := newPayloadTransformPipeline(specs, "jq .")
+		checkErr(t, err, "pipeline failed: %v", err)
+
+		if len(p.stages) != 2 || p.stages[1].spec != "exec:jq ." {
+			t.Fatalf("expected translate to run last got %v", p.stages)
+		}
+		if len(specs) != 1 {
+			t.Fatalf("expected the specifications to be unchanged")
+		}
+	})
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// adaptiveRateInterval is how often the connection is checked for congestion
+	adaptiveRateInterval = 100 * time.Millisecond
+
+	// adaptiveRateBufferLimit is how many bytes may be pending in the connection buffer before it is considered congested
+	adaptiveRateBufferLimit = 32 * 1024
+
+	// adaptiveRateRTTSlack is how much the round trip time has to grow before it is considered a sign of congestion,
+	// avoiding backing off on the jitter of very fast connections
+	adaptiveRateRTTSlack = 5 * time.Millisecond
+)
+
+// adaptiveRate paces publishing below a maximum rate, it starts at a tenth of the maximum and backs off when the
+// connection shows signs of congestion
+type adaptiveRate struct {
+	max       float64
+	rate      float64
+	peak      float64
+	minRTT    time.Duration
+	buffered  int
+	decreases int
+
+	windowStart time.Time
+	windowSent  uint64
+	lastAdjust  time.Time
+}
+
+func newAdaptiveRate(max int) *adaptiveRate {
+	now := time.Now()
+	rate := math.Max(float64(max)/10, 1)
+
+	return &adaptiveRate{
+		max:         float64(max),
+		rate:        rate,
+		peak:        rate,
+		windowStart: now,
+		lastAdjust:  now,
+	}
+}
+
+// wait blocks until the next message may be published at the current rate, false when ctx is done first
+func (a *adaptiveRate) wait(ctx context.Context) bool {
+	due := a.windowStart.Add(time.Duration(float64(a.windowSent) / a.rate * float64(time.Second)))
+	if d := time.Until(due); d > 0 {
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	a.windowSent++
+
+	return ctx.Err() == nil
+}
+
+// adjustDue indicates if it is time to check the connection for congestion
+func (a *adaptiveRate) adjustDue() bool {
+	return time.Since(a.lastAdjust) >= adaptiveRateInterval
+}
+
+// adjust updates the rate given the bytes pending in the connection buffer and the round trip time to the server.
+// A growing buffer or round trip time halves the rate, else it increases by a tenth of the maximum
+func (a *adaptiveRate) adjust(buffered int, rtt time.Duration) (congested bool) {
+	if a.minRTT == 0 || rtt < a.minRTT {
+		a.minRTT = rtt
+	}
+
+	congested = (buffered > adaptiveRateBufferLimit && buffered >= a.buffered) || (rtt > 2*a.minRTT && rtt-a.minRTT > adaptiveRateRTTSlack)
+	a.buffered = buffered
+
+	if congested {
+		a.rate = math.Max(a.rate/2, 1)
+		a.decreases++
+	} else {
+		a.rate = math.Min(a.rate+a.max/10, a.max)
+	}
+	a.peak = math.Max(a.peak, a.rate)
+
+	now := time.Now()
+	a.windowStart = now
+	a.windowSent = 0
+	a.lastAdjust = now
+
+	return congested
+}
+
+// check measures the pending buffer and round trip time of nc and adjusts the rate
+func (a *adaptiveRate) check(nc *nats.Conn) error {
+	buffered, err := nc.Buffered()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	err = nc.FlushTimeout(opts().Timeout)
+	if err != nil {
+		return err
+	}
+
+	if a.adjust(buffered, time.Since(start)) {
+		logDebugf("Congestion detected with %s buffered and a round trip time of %s, reducing the rate to %s msg/sec", fiBytes(uint64(buffered)), f(time.Since(start)), f(int64(a.rate)))
+	}
+
+	return nil
+}
+
+func (a *adaptiveRate) report(published uint64, elapsed time.Duration) {
+	log.Printf("Published %s messages in %s averaging %s msg/sec, the rate peaked at %s msg/sec and was reduced %s times due to congestion", f(published), f(elapsed), f(int64(float64(published)/elapsed.Seconds())), f(int64(a.peak)), f(a.decreases))
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// pubChaos randomly drops, delays and duplicates published messages to test how subscribers handle faults
+type pubChaos struct {
+	dropRate      float64
+	duplicateRate float64
+	delayMax      time.Duration
+	rand          *rand.Rand
+
+	dropped    uint64
+	delayed    uint64
+	duplicated uint64
+}
+
+func newPubChaos(dropRate float64, duplicateRate float64, delayMax time.Duration) (*pubChaos, error) {
+	for name, rate := range map[string]float64{"drop-rate": dropRate, "duplicate-rate": duplicateRate} {
+		if rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if delayMax < 0 {
+		return nil, fmt.Errorf("delay-max can not be negative")
+	}
+
+	return &pubChaos{
+		dropRate:      dropRate,
+		duplicateRate: duplicateRate,
+		delayMax:      delayMax,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// apply delays msg a random time up to delayMax, reports if msg should be published and publishes an extra copy
+// when duplicating it
+func (p *pubChaos) apply(ctx context.Context, nc *nats.Conn, msg *nats.Msg, seq int) (bool, error) {
+	if p.dropRate > 0 && p.rand.Float64() < p.dropRate {
+		p.dropped++
+		log.Printf("Chaos: dropped message %d to %s", seq, msg.Subject)
+		return false, nil
+	}
+
+	if p.delayMax > 0 {
+		delay := time.Duration(p.rand.Int63n(int64(p.delayMax) + 1))
+		p.delayed++
+		log.Printf("Chaos: delayed message %d to %s by %v", seq, msg.Subject, delay)
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return false, nil
+		}
+	}
+
+	if p.duplicateRate > 0 && p.rand.Float64() < p.duplicateRate {
+		p.duplicated++
+		log.Printf("Chaos: duplicated message %d to %s", seq, msg.Subject)
+		return true, nc.PublishMsg(msg)
+	}
+
+	return true, nil
+}
+
+func (p *pubChaos) report() {
+	log.Printf("Chaos: dropped %s, delayed %s and duplicated %s messages", f(p.dropped), f(p.delayed), f(p.duplicated))
+}
@@ -14,31 +14,92 @@
 package cli
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/choria-io/fisk"
 	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/internal/subjectmap"
 	terminal "golang.org/x/term"
+	"gopkg.in/yaml.v3"
 )
 
 type pubCmd struct {
-	subject      string
-	body         string
-	req          bool
-	replyTo      string
-	raw          bool
-	hdrs         []string
-	cnt          int
-	sleep        time.Duration
-	replyCount   int
-	replyTimeout time.Duration
-	forceStdin   bool
-	translate    string
+	subject          string
+	body             string
+	req              bool
+	replyTo          string
+	raw              bool
+	hdrs             []string
+	headerFile       string
+	headerSets       []map[string]string
+	cnt              int
+	sleep            time.Duration
+	replyCount       int
+	replyTimeout     time.Duration
+	forceStdin       bool
+	translate        string
+	transformIn      []string
+	transformOut     []string
+	inTransform      *payloadTransformPipeline
+	outTransform     *payloadTransformPipeline
+	connectionReport bool
+	alsoPublish      []string
+	templateFile     string
+	varsFiles        []string
+	captureFile      string
+	jsAsync          bool
+	ackWindow        int
+	tail             string
+	checkpoint       string
+	forwardFrom      string
+	forwardTo        string
+	forwardTransform string
+	forwardMaps      []string
+	forwardMapTest   string
+	forwardMapper    *subjectmap.Mapper
+	stripHeaders     bool
+	soak             bool
+	soakDuration     time.Duration
+	soakSubjects     string
+	soakSubjectCount int
+	soakRate         int
+	soakDistribution string
+	soakInterval     time.Duration
+	soakSizeDist     *soakSizes
+	adaptiveRate     bool
+	maxRate          int
+	maxPendingS      string
+	maxPending       int64
+	chaosDropRate    float64
+	chaosDupRate     float64
+	chaosDelayMax    time.Duration
+	onReply          string
+	onReplyTimeout   time.Duration
+	expectNoConsumer bool
+	envelope         bool
+	envelopeTemplate string
+	envelopeBody     string
+	redisSource      string
+	redisSourceURL   *pubRedisSource
+	redisKey         string
+	redisBatch       int
+	redisSubject     string
+
+	templateBody string
+	templateVars [][]map[string]any
 }
 
 func configurePubCommand(app commandHost) {
@@ -56,24 +117,166 @@ Multiple messages with random strings between 10 and 100 long:
 Available template functions are:
 
    Count            the message number
+   Seq              the message number, for use with nats sub --sequence-check
    TimeStamp        RFC3339 format current time
    Unix             seconds since 1970 in UTC
    UnixNano         nano seconds since 1970 in UTC
    Time             the current time
    ID               an unique ID
    Random(min, max) random string at least min long, at most max
+
+The body can also be read from a template file with data from YAML or
+JSON files, the data is available as . in the template:
+
+   nats pub events.order --template order.tmpl --vars order.yaml
+
+Multiple vars files are merged in order, when a file holds many YAML
+documents message N uses document N, repeating from the start as needed.
+
+Lines appended to a file can be published to a Stream as they are written,
+following the file when it is rotated or truncated:
+
+   nats pub logs.app --tail /var/log/app.log --checkpoint app.checkpoint
+
+Every line has File-Name and File-Offset headers and a message ID derived
+from the file and offset so the Stream discards duplicates, use --sleep
+to limit the rate lines are published at.
+
+Messages received on one subject can be republished to another, optionally
+transforming JSON payloads using a jq expression:
+
+   nats pub --forward-from orders.new --to orders.audit --transform '.payload'
+
+Headers and reply subjects are kept unless --strip-headers is given, a jq
+expression producing no output skips the message.
+
+Each message can be forwarded to a subject based on the one it was received
+on using --subject-map, the first matching map is used and messages matching
+none are forwarded to --to, or skipped when it is not given. A * captures a
+single token and a trailing > the remaining tokens, patterns starting with ~
+are regular expressions matching the entire subject:
+
+   nats pub --forward-from 'orders.>' \
+       --subject-map 'orders.*.created=archive.orders.$1.created' \
+       --subject-map '~orders\.(eu|us)\.(.+)=regional.$1.$2'
+
+Use --subject-map-test to show how a subject would be mapped.
+
+Synthetic load can be generated for long running soak tests, publishing
+to random subjects at a steady rate:
+
+   nats pub --soak --duration 24h --subjects "soak.>" --subject-count 100 \
+       --msg-rate 1000 --size-distribution uniform:64:1024
+
+Wildcards in the subjects are replaced by the subject number, a summary is
+shown every --report-interval and any publish error fails the soak test.
+
+Producers can find a safe rate by publishing up to a maximum and backing
+off when the connection is congested, shown by data building up in the
+publish buffer or a growing round trip time to the server:
+
+   nats pub loadtest.subject "data" --count 1000000 --adaptive-rate --max-rate 100000
+
+Messages can have varying headers read from a file holding a JSON object
+per line, message N uses line N, repeating from the start as needed:
+
+   nats pub events.test "data" --count 100 --header-file headers.jsonl
+
+One-off batch publishes to a Stream can be guarded against interfering
+with live consumers, nothing is published when the Stream holding the
+subject has any Consumers:
+
+   nats pub ORDERS.import "data" --count 1000 --expect-no-consumers
+
+The Consumers are checked once before publishing, Consumers added while
+publishing will receive the messages.
+
+Memory limited producers can cap the data buffered on the connection, when
+the limit is reached publishing blocks until the server received it all:
+
+   nats pub loadtest.subject "data" --count 100000 --max-pending 64KB
+
+Faults can be injected to test how subscribers handle lost, late and
+repeated messages, every message dropped, delayed or duplicated is logged:
+
+   nats pub chaos.test "data" --count 10000 --drop-rate 0.01 --delay-max 10ms --duplicate-rate 0.005
+
+A reply can be processed by a command, the message is sent as a request
+and the body of the first reply is passed to the command:
+
+   nats pub events.request payload --on-reply "jq .status" --on-reply-timeout 5s
+
+Message bodies can be wrapped in a standard JSON envelope recording the
+subject, time and source of the event:
+
+   nats pub events.orders '{"id":1}' --envelope
+
+Use --envelope-template to wrap bodies using a Go template instead, the
+template has .Subject, .TimeStamp, .Source, .Seq, .Body and .Payload, the
+body encoded as JSON:
+
+   nats pub events.orders '{"id":1}' --envelope-template event.tmpl
+
+Items pushed onto a Redis list can be moved to NATS, helping migrate away
+from Redis while producers still push to it:
+
+   nats pub --redis-source redis://localhost:6379 --redis-key myqueue \
+       --subject nats.from.redis --redis-batch 100
+
+Items are popped using BLPOP and published in order, the connection to
+Redis is retried until interrupted. Items that could not be published are
+pushed back to the front of the list.
 `
 
 	pub := app.Command("publish", "Generic data publish utility").Alias("pub").Action(c.publish)
 	addCheat("pub", pub)
 	pub.HelpLong(pubHelp)
-	pub.Arg("subject", "Subject to publish to").Required().StringVar(&c.subject)
+	pub.Arg("subject", "Subject to publish to").StringVar(&c.subject)
 	pub.Arg("body", "Message body").Default("!nil!").StringVar(&c.body)
 	pub.Flag("reply", "Sets a custom reply to subject").StringVar(&c.replyTo)
 	pub.Flag("header", "Adds headers to the message using K:V format").Short('H').StringsVar(&c.hdrs)
+	pub.Flag("header-file", "Adds headers from a file holding a JSON object per line, message N uses line N").PlaceHolder("FILE").ExistingFileVar(&c.headerFile)
 	pub.Flag("count", "Publish multiple messages").Default("1").IntVar(&c.cnt)
 	pub.Flag("sleep", "When publishing multiple messages, sleep between publishes").DurationVar(&c.sleep)
 	pub.Flag("force-stdin", "Force reading from stdin").UnNegatableBoolVar(&c.forceStdin)
+	pub.Flag("also-publish", "Also publish each message to these subjects").PlaceHolder("SUBJECT").StringsVar(&c.alsoPublish)
+	pub.Flag("template", "Renders the message body from a Go template file").PlaceHolder("FILE").ExistingFileVar(&c.templateFile)
+	pub.Flag("vars", "YAML or JSON files holding data for the template (pass multiple times)").PlaceHolder("FILE").ExistingFilesVar(&c.varsFiles)
+	pub.Flag("js-async", "Publish to JetStream without waiting for each acknowledgement, collecting them in the background").UnNegatableBoolVar(&c.jsAsync)
+	pub.Flag("ack-window", "Maximum JetStream publishes awaiting acknowledgement when using --js-async").Default("512").IntVar(&c.ackWindow)
+	pub.Flag("tail", "Follow a file like tail -F, publishing every new line to JetStream").PlaceHolder("FILE").ExistingFileVar(&c.tail)
+	pub.Flag("checkpoint", "File to record progress in when using --tail, resuming from it between runs").PlaceHolder("FILE").StringVar(&c.checkpoint)
+	pub.Flag("forward-from", "Republish messages received on this subject until interrupted").PlaceHolder("SUBJECT").StringVar(&c.forwardFrom)
+	pub.Flag("to", "Subject to republish messages to when using --forward-from").PlaceHolder("SUBJECT").StringVar(&c.forwardTo)
+	pub.Flag("transform", "jq expression transforming JSON payloads when using --forward-from").PlaceHolder("JQ").StringVar(&c.forwardTransform)
+	pub.Flag("subject-map", "Rewrites subjects matching a pattern when using --forward-from, can be repeated").PlaceHolder("PATTERN=REPLACEMENT").StringsVar(&c.forwardMaps)
+	pub.Flag("subject-map-test", "Shows what a subject maps to without forwarding any messages").PlaceHolder("SUBJECT").StringVar(&c.forwardMapTest)
+	pub.Flag("strip-headers", "Do not copy headers of messages received when using --forward-from").UnNegatableBoolVar(&c.stripHeaders)
+	pub.Flag("soak", "Publish generated messages at a steady rate for soak testing").UnNegatableBoolVar(&c.soak)
+	pub.Flag("duration", "How long to run the soak test for, runs until interrupted when not set").PlaceHolder("DURATION").DurationVar(&c.soakDuration)
+	pub.Flag("subjects", "Subject pattern to publish soak test messages to").PlaceHolder("SUBJECT").StringVar(&c.soakSubjects)
+	pub.Flag("subject-count", "Number of subjects to publish soak test messages to").Default("10").IntVar(&c.soakSubjectCount)
+	pub.Flag("msg-rate", "Soak test messages to publish per second").Default("100").IntVar(&c.soakRate)
+	pub.Flag("size-distribution", "Soak test payload sizes as fixed:SIZE or uniform:MIN:MAX").Default("fixed:128").StringVar(&c.soakDistribution)
+	pub.Flag("report-interval", "How often to show soak test summaries").Default("1m").DurationVar(&c.soakInterval)
+	pub.Flag("adaptive-rate", "Publish up to --max-rate, slowing down when the connection to the server is congested").UnNegatableBoolVar(&c.adaptiveRate)
+	pub.Flag("max-rate", "Maximum messages to publish per second when using --adaptive-rate").PlaceHolder("MSGS").IntVar(&c.maxRate)
+	pub.Flag("max-pending", "Blocks publishing while this much data is buffered on the connection, waiting for the server to receive it").PlaceHolder("BYTES").StringVar(&c.maxPendingS)
+	pub.Flag("drop-rate", "Fraction of messages to drop without publishing them, between 0 and 1").PlaceHolder("RATE").Float64Var(&c.chaosDropRate)
+	pub.Flag("delay-max", "Delays every message a random time up to this long before publishing it").PlaceHolder("DURATION").DurationVar(&c.chaosDelayMax)
+	pub.Flag("duplicate-rate", "Fraction of messages to publish twice, between 0 and 1").PlaceHolder("RATE").Float64Var(&c.chaosDupRate)
+	pub.Flag("on-reply", "Waits for a reply and passes its body to the STDIN of this command, showing the output").PlaceHolder("COMMAND").StringVar(&c.onReply)
+	pub.Flag("on-reply-timeout", "How long to wait for the reply when using --on-reply, defaults to --timeout").PlaceHolder("DURATION").DurationVar(&c.onReplyTimeout)
+	pub.Flag("expect-no-consumers", "Fail without publishing when the Stream holding the subject has any Consumers").UnNegatableBoolVar(&c.expectNoConsumer)
+	pub.Flag("envelope", "Wraps message bodies in a JSON envelope holding the subject, time and source").UnNegatableBoolVar(&c.envelope)
+	pub.Flag("envelope-template", "Wraps message bodies using a Go template file, implies --envelope").PlaceHolder("FILE").ExistingFileVar(&c.envelopeTemplate)
+	pub.Flag("capture", "Also save every message sent to a file, one JSON document per line").PlaceHolder("FILE").StringVar(&c.captureFile)
+	pub.Flag("transform-out", payloadTransformHelp("message bodies before sending")).PlaceHolder("TRANSFORM").StringsVar(&c.transformOut)
+	pub.Flag("redis-source", "Publish items popped from a Redis list on this server until interrupted").PlaceHolder("URL").StringVar(&c.redisSource)
+	pub.Flag("redis-key", "Redis list to pop items from when using --redis-source").PlaceHolder("KEY").StringVar(&c.redisKey)
+	pub.Flag("redis-batch", "Maximum items to pop from Redis at a time when using --redis-source").Default("1").IntVar(&c.redisBatch)
+	pub.Flag("subject", "Subject to publish items to when using --redis-source").PlaceHolder("SUBJECT").StringVar(&c.redisSubject)
+	pub.Flag("connection-report", "Show statistics about the connections used when exiting").UnNegatableBoolVar(&c.connectionReport)
 
 	requestHelp := `Body and Header values of the messages may use Go templates to 
 create unique messages.
@@ -106,22 +309,131 @@ Available template functions are:
 	req.Flag("replies", "Wait for multiple replies from services. 0 waits until timeout").Default("1").IntVar(&c.replyCount)
 	req.Flag("reply-timeout", "Maximum timeout between incoming replies.").Default("300ms").DurationVar(&c.replyTimeout)
 	req.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.translate)
+	req.Flag("transform-out", payloadTransformHelp("request bodies before sending")).PlaceHolder("TRANSFORM").StringsVar(&c.transformOut)
+	req.Flag("transform-in", payloadTransformHelp("response bodies before output")).PlaceHolder("TRANSFORM").StringsVar(&c.transformIn)
+	req.Flag("connection-report", "Show statistics about the connections used when exiting").UnNegatableBoolVar(&c.connectionReport)
 }
 
 func init() {
 	registerCommand("pub", 11, configurePubCommand)
 }
 
+// validateSubjects checks the syntax of all subjects given, soak test subjects are patterns that may hold wildcards
+func (c *pubCmd) validateSubjects() error {
+	var err error
+
+	switch {
+	case c.soak:
+		err = validateSubjectSyntax(c.subject)
+	case c.subject != "":
+		err = validatePublishSubject(c.subject)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.forwardFrom != "" {
+		err = validateSubscribeSubject(c.forwardFrom, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.replyTo != "" {
+		err = validatePublishSubject(c.replyTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, subject := range splitCLISubjects(c.alsoPublish) {
+		err = validatePublishSubject(subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkNoConsumers ensures the streams holding the subjects being published to have no consumers
+func (c *pubCmd) checkNoConsumers(nc *nats.Conn) error {
+	js, err := nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	domain, prefix := jsTarget()
+	mgr, err := jsm.New(nc, jsm.WithDomain(domain), jsm.WithAPIPrefix(prefix), jsm.WithTimeout(opts().Timeout))
+	if err != nil {
+		return err
+	}
+
+	for _, subject := range append([]string{c.subject}, splitCLISubjects(c.alsoPublish)...) {
+		stream, err := js.StreamNameBySubject(subject)
+		if errors.Is(err, nats.ErrNoMatchingStream) {
+			return fmt.Errorf("no Stream holds subject %s", subject)
+		}
+		if err != nil {
+			return fmt.Errorf("could not find the Stream holding subject %s: %w", subject, err)
+		}
+
+		consumers, err := mgr.ConsumerNames(stream)
+		if err != nil {
+			return fmt.Errorf("could not list the consumers of Stream %s holding subject %s: %w", stream, subject, err)
+		}
+		if len(consumers) > 0 {
+			sort.Strings(consumers)
+			return fmt.Errorf("stream %s holding subject %s has %s consumers: %s", stream, subject, f(len(consumers)), strings.Join(consumers, ", "))
+		}
+	}
+
+	return nil
+}
+
 func (c *pubCmd) prepareMsg(body []byte, seq int) (*nats.Msg, error) {
-	msg := nats.NewMsg(c.subject)
+	return c.prepareMsgTo(c.subject, body, seq)
+}
+
+func (c *pubCmd) prepareMsgTo(subject string, body []byte, seq int) (*nats.Msg, error) {
+	body, err := c.outTransform.apply(body, subject, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if c.envelope || c.envelopeTemplate != "" {
+		body, err = c.wrapEnvelope(subject, body, seq)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	msg := nats.NewMsg(subject)
 	msg.Reply = c.replyTo
 	msg.Data = body
 
-	return msg, parseStringsToMsgHeader(c.hdrs, seq, msg)
+	err = parseStringsToMsgHeader(c.hdrs, seq, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(c.headerSets) > 0 {
+		for k, v := range c.headerSets[(seq-1)%len(c.headerSets)] {
+			val, err := pubReplyBodyTemplate(v, "", seq)
+			if err != nil {
+				logErrorf("Failed to parse Header template for %s: %s", k, err)
+				continue
+			}
+
+			msg.Header.Add(k, string(val))
+		}
+	}
+
+	return msg, nil
 }
 
 func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
-	logOutput := !c.raw && progress == nil
+	logOutput := !c.raw && !opts().Quiet && progress == nil
 
 	for i := 1; i <= c.cnt; i++ {
 		if logOutput {
@@ -130,7 +442,7 @@ func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
 
 		body, err := pubReplyBodyTemplate(c.body, "", i)
 		if err != nil {
-			log.Printf("Could not parse body template: %s", err)
+			logErrorf("Could not parse body template: %s", err)
 		}
 
 		msg, err := c.prepareMsg(body, i)
@@ -160,6 +472,9 @@ func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
 		// Honor the overall timeout for the first response.  No
 		// responders will circuit break.
 		timeout := opts().Timeout
+		if c.onReplyTimeout > 0 {
+			timeout = c.onReplyTimeout
+		}
 
 		// loop until reply count is met, or if zero, until we
 		// timeout receiving messages.
@@ -169,21 +484,35 @@ func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
 			m, err := s.NextMsg(timeout)
 			if err != nil {
 				if err == nats.ErrTimeout {
+					if c.onReply != "" && rc == 0 {
+						return fmt.Errorf("no reply received on %s within %v", c.subject, timeout)
+					}
 					// continue to publish additional messages.
 					break
 				}
 				if err == nats.ErrNoResponders {
-					log.Printf("No responders are available")
-					return nil
+					return fmt.Errorf("no responders available for subject %s: %w", c.subject, err)
 				}
 				return err
 			}
 
+			// the server replies with status messages like 408 Request Timeout on behalf of some services
+			err = statusMsgError(m, c.subject)
+			if err != nil {
+				return err
+			}
+
 			rtt := time.Since(start)
 
 			switch {
-			case c.raw:
-				outPutMSGBody(m.Data, c.translate, m.Subject, "")
+			case c.onReply != "":
+				// only the output of the command is shown
+				_, err = outPutMSGBodyCompact(m.Data, c.inTransform, m.Subject, "")
+				if err != nil {
+					return err
+				}
+			case c.raw || opts().Quiet:
+				outPutMSGBody(m.Data, c.inTransform, m.Subject, "")
 			case logOutput:
 				log.Printf("Received with rtt %v", rtt)
 
@@ -196,7 +525,7 @@ func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
 					fmt.Println()
 				}
 
-				outPutMSGBody(m.Data, c.translate, m.Subject, "")
+				outPutMSGBody(m.Data, c.inTransform, m.Subject, "")
 			}
 
 			rc++
@@ -230,17 +559,183 @@ func (c *pubCmd) doReq(nc *nats.Conn, progress *uiprogress.Bar) error {
 }
 
 func (c *pubCmd) publish(_ *fisk.ParseContext) error {
-	nc, err := newNatsConn("", natsOpts()...)
+	var err error
+	c.outTransform, err = newPayloadTransformPipeline(c.transformOut, "")
+	if err != nil {
+		return err
+	}
+
+	c.inTransform, err = newPayloadTransformPipeline(c.transformIn, c.translate)
+	if err != nil {
+		return err
+	}
+
+	c.forwardMapper, err = subjectmap.New(c.forwardMaps)
+	if err != nil {
+		return err
+	}
+
+	if c.forwardMapTest != "" {
+		showSubjectMapTest(c.forwardMapper, c.forwardMapTest)
+		return nil
+	}
+
+	manager := newConnManager()
+	defer manager.shutdown(true, c.connectionReport)
+
+	nc, err := manager.shared("default")
 	if err != nil {
 		return err
 	}
-	defer nc.Close()
 
 	if c.cnt < 1 {
 		c.cnt = math.MaxInt16
 	}
 
-	if c.body == "!nil!" && (terminal.IsTerminal(int(os.Stdout.Fd())) || c.forceStdin) {
+	if c.templateFile != "" {
+		err = c.loadTemplate()
+		if err != nil {
+			return err
+		}
+	} else if len(c.varsFiles) > 0 {
+		return fmt.Errorf("vars requires a template")
+	}
+
+	if c.headerFile != "" {
+		c.headerSets, err = readHeaderFile(c.headerFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = c.loadEnvelope()
+	if err != nil {
+		return err
+	}
+
+	if c.jsAsync {
+		switch {
+		case c.replyTo != "":
+			return fmt.Errorf("reply subjects can not be used with js-async")
+		case c.ackWindow < 1:
+			return fmt.Errorf("ack-window must be at least 1")
+		}
+	}
+
+	if c.tail != "" {
+		switch {
+		case c.body != "!nil!" || c.templateFile != "":
+			return fmt.Errorf("a message body or template can not be used with tail")
+		case c.replyTo != "" || c.jsAsync || len(c.alsoPublish) > 0:
+			return fmt.Errorf("reply, js-async and also-publish can not be used with tail")
+		}
+	} else if c.checkpoint != "" {
+		return fmt.Errorf("checkpoint requires tail")
+	}
+
+	if c.forwardFrom != "" {
+		err = c.validateForward()
+		if err != nil {
+			return err
+		}
+	} else if c.forwardTo != "" || c.forwardTransform != "" || c.stripHeaders || len(c.forwardMaps) > 0 {
+		return fmt.Errorf("to, transform, subject-map and strip-headers require forward-from")
+	}
+
+	if c.redisSource != "" {
+		err = c.validateRedis()
+		if err != nil {
+			return err
+		}
+	} else if c.redisKey != "" || c.redisSubject != "" {
+		return fmt.Errorf("redis-key and subject require redis-source")
+	}
+
+	if c.soak {
+		err = c.validateSoak()
+		if err != nil {
+			return err
+		}
+	} else if c.soakSubjects != "" || c.soakDuration > 0 {
+		return fmt.Errorf("subjects and duration require soak")
+	}
+
+	if c.adaptiveRate {
+		switch {
+		case c.maxRate < 1:
+			return fmt.Errorf("max-rate must be at least 1 when using adaptive-rate")
+		case c.sleep > 0:
+			return fmt.Errorf("sleep can not be used with adaptive-rate")
+		case c.tail != "" || c.forwardFrom != "" || c.soak:
+			return fmt.Errorf("tail, forward-from and soak can not be used with adaptive-rate")
+		}
+	} else if c.maxRate > 0 {
+		return fmt.Errorf("max-rate requires adaptive-rate")
+	}
+
+	if c.maxPendingS != "" {
+		c.maxPending, err = parseStringAsBytes(c.maxPendingS)
+		switch {
+		case err != nil:
+			return fmt.Errorf("invalid max-pending: %w", err)
+		case c.maxPending < 1:
+			return fmt.Errorf("max-pending must be at least 1 byte")
+		case c.adaptiveRate || c.jsAsync:
+			return fmt.Errorf("adaptive-rate and js-async can not be used with max-pending")
+		case c.tail != "" || c.forwardFrom != "" || c.soak:
+			return fmt.Errorf("tail, forward-from and soak can not be used with max-pending")
+		}
+	}
+
+	if c.chaosEnabled() {
+		switch {
+		case c.jsAsync || c.adaptiveRate:
+			return fmt.Errorf("js-async and adaptive-rate can not be used with drop-rate, delay-max and duplicate-rate")
+		case c.tail != "" || c.forwardFrom != "" || c.soak:
+			return fmt.Errorf("tail, forward-from and soak can not be used with drop-rate, delay-max and duplicate-rate")
+		}
+	}
+
+	if c.onReply != "" {
+		switch {
+		case c.replyTo != "" || c.jsAsync:
+			return fmt.Errorf("reply and js-async can not be used with on-reply")
+		case c.tail != "" || c.forwardFrom != "" || c.soak:
+			return fmt.Errorf("tail, forward-from and soak can not be used with on-reply")
+		case c.adaptiveRate || c.maxPending > 0 || c.chaosEnabled():
+			return fmt.Errorf("adaptive-rate, max-pending and chaos testing can not be used with on-reply")
+		}
+
+		c.inTransform, err = newPayloadTransformPipeline(nil, c.onReply)
+		if err != nil {
+			return err
+		}
+		c.replyCount = 1
+	} else if c.onReplyTimeout > 0 {
+		return fmt.Errorf("on-reply-timeout requires on-reply")
+	}
+
+	if c.subject == "" && c.forwardFrom == "" {
+		return fmt.Errorf("a subject to publish to is required")
+	}
+
+	err = c.validateSubjects()
+	if err != nil {
+		return err
+	}
+
+	if c.expectNoConsumer {
+		if c.forwardFrom != "" || c.soak {
+			return fmt.Errorf("forward-from and soak can not be used with expect-no-consumers")
+		}
+
+		err = c.checkNoConsumers(nc)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.body == "!nil!" && c.templateFile == "" && c.tail == "" && c.forwardFrom == "" && c.redisSource == "" && !c.soak && (terminal.IsTerminal(int(os.Stdout.Fd())) || c.forceStdin) {
 		log.Println("Reading payload from STDIN")
 		body, err := io.ReadAll(os.Stdin)
 		if err != nil {
@@ -250,7 +745,7 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 	}
 
 	var progress *uiprogress.Bar
-	if c.cnt > 20 && !c.raw {
+	if c.cnt > 20 && !c.raw && !opts().Quiet {
 		progressFormat := fmt.Sprintf("%%%dd / %%d", len(fmt.Sprintf("%d", c.cnt)))
 		progress = uiprogress.AddBar(c.cnt).PrependFunc(func(b *uiprogress.Bar) string {
 			return fmt.Sprintf(progressFormat, b.Current(), c.cnt)
@@ -267,38 +762,372 @@ func (c *pubCmd) publish(_ *fisk.ParseContext) error {
 		return c.doReq(nc, progress)
 	}
 
-	for i := 1; i <= c.cnt; i++ {
-		body, err := pubReplyBodyTemplate(c.body, "", i)
+	var capture *msgCaptureWriter
+	if c.captureFile != "" {
+		capture, err = newMsgCaptureWriter(c.captureFile)
 		if err != nil {
-			log.Printf("Could not parse body template: %s", err)
+			return err
 		}
+	}
 
-		msg, err := c.prepareMsg(body, i)
+	var published uint64
+	switch {
+	case c.tail != "":
+		published, err = c.tailPublish(nc, capture)
+	case c.forwardFrom != "":
+		published, err = c.forwardPublish(nc, capture)
+	case c.redisSource != "":
+		published, err = c.redisPublish(nc, capture)
+	case c.soak:
+		published, err = c.soakPublish(nc, capture)
+	default:
+		published, err = c.publishMsgs(nc, progress, capture)
+	}
+
+	if capture != nil {
+		captured, cerr := capture.close()
+		switch {
+		case cerr != nil:
+			logErrorf("Could not write capture file %s: %v", c.captureFile, cerr)
+		case captured != published:
+			log.Printf("Captured %s messages to %s while %s were published", f(captured), c.captureFile, f(published))
+		case progress != nil:
+			log.Printf("Captured %s messages to %s", f(captured), c.captureFile)
+		}
+	}
+
+	return err
+}
+
+// publishMsgs publishes all messages until done or interrupted, returning how many were published
+func (c *pubCmd) publishMsgs(nc *nats.Conn, progress *uiprogress.Bar, capture *msgCaptureWriter) (published uint64, err error) {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	subjects := append([]string{c.subject}, splitCLISubjects(c.alsoPublish)...)
+
+	var js nats.JetStreamContext
+	var futures []nats.PubAckFuture
+	if c.jsAsync {
+		js, err = nc.JetStream(append(jsOpts(), nats.PublishAsyncMaxPending(c.ackWindow))...)
 		if err != nil {
-			return err
+			return 0, err
 		}
 
-		err = nc.PublishMsg(msg)
+		start := time.Now()
+		defer func() {
+			aerr := c.reportAsyncAcks(js, futures, start)
+			if err == nil {
+				err = aerr
+			}
+		}()
+	}
+
+	var limiter *adaptiveRate
+	if c.adaptiveRate {
+		limiter = newAdaptiveRate(c.maxRate)
+
+		start := time.Now()
+		defer func() { limiter.report(published, time.Since(start)) }()
+	}
+
+	var pending *pubPendingLimit
+	if c.maxPending > 0 {
+		pending = newPubPendingLimit(int(c.maxPending))
+		defer pending.report()
+	}
+
+	var chaos *pubChaos
+	if c.chaosEnabled() {
+		chaos, err = newPubChaos(c.chaosDropRate, c.chaosDupRate, c.chaosDelayMax)
 		if err != nil {
-			return err
+			return 0, err
+		}
+		defer chaos.report()
+	}
+
+	for i := 1; i <= c.cnt; i++ {
+		if ctx.Err() != nil {
+			return published, nil
 		}
-		nc.Flush()
 
-		err = nc.LastError()
+		if limiter != nil && !limiter.wait(ctx) {
+			return published, nil
+		}
+
+		var body []byte
+		if c.templateFile != "" {
+			body, err = c.renderTemplate(i)
+			if err != nil {
+				return published, err
+			}
+		} else {
+			body, err = pubReplyBodyTemplate(c.body, "", i)
+			if err != nil {
+				logErrorf("Could not parse body template: %s", err)
+			}
+		}
+
+		msg, err := c.prepareMsg(body, i)
 		if err != nil {
-			return err
+			return published, err
+		}
+
+		for _, subject := range subjects {
+			msg.Subject = subject
+
+			if chaos != nil {
+				publish, err := chaos.apply(ctx, nc, msg, i)
+				if err != nil {
+					return published, err
+				}
+				if !publish {
+					continue
+				}
+			}
+
+			if capture != nil {
+				capture.capture(msg)
+			}
+
+			if js != nil {
+				// the message is kept until acknowledged for retries so each subject needs its own
+				future, err := js.PublishMsgAsync(&nats.Msg{Subject: subject, Header: msg.Header, Data: msg.Data})
+				if err != nil {
+					return published, err
+				}
+				futures = append(futures, future)
+				published++
+
+				continue
+			}
+
+			err = nc.PublishMsg(msg)
+			if err != nil {
+				return published, err
+			}
+			// adaptive rates flush while checking for congestion and pending limits only once the buffer is full
+			switch {
+			case pending != nil:
+				err = pending.wait(nc)
+				if err != nil {
+					return published, err
+				}
+			case limiter == nil:
+				nc.Flush()
+			}
+
+			err = nc.LastError()
+			if err != nil {
+				return published, err
+			}
+			published++
+
+			if progress == nil {
+				log.Printf("Published %d bytes to %q\n", len(msg.Data), subject)
+			}
+		}
+
+		if limiter != nil && (limiter.adjustDue() || i == c.cnt) {
+			err = limiter.check(nc)
+			if err != nil {
+				return published, err
+			}
 		}
 
 		if c.cnt > 1 && c.sleep > 0 {
-			time.Sleep(c.sleep)
+			select {
+			case <-time.After(c.sleep):
+			case <-ctx.Done():
+			}
 		}
 
-		if progress == nil {
-			log.Printf("Published %d bytes to %q\n", len(body), c.subject)
-		} else {
+		if progress != nil {
 			progress.Incr()
 		}
 	}
 
+	return published, nil
+}
+
+// chaosEnabled reports if messages should be randomly dropped, delayed or duplicated
+func (c *pubCmd) chaosEnabled() bool {
+	return c.chaosDropRate != 0 || c.chaosDupRate != 0 || c.chaosDelayMax != 0
+}
+
+// reportAsyncAcks waits for outstanding acknowledgements and reports how many were received and any errors
+func (c *pubCmd) reportAsyncAcks(js nats.JetStreamContext, futures []nats.PubAckFuture, start time.Time) error {
+	select {
+	case <-js.PublishAsyncComplete():
+	case <-time.After(opts().Timeout):
+		log.Printf("Timeout waiting for %s outstanding acknowledgements", f(js.PublishAsyncPending()))
+	}
+
+	elapsed := time.Since(start)
+
+	var acked, pending int
+	errs := map[string]int{}
+
+	for _, future := range futures {
+		select {
+		case <-future.Ok():
+			acked++
+		case err := <-future.Err():
+			errs[err.Error()]++
+		default:
+			pending++
+		}
+	}
+
+	log.Printf("Received %s acknowledgements for %s messages in %v, %s acknowledgements/sec", f(acked), f(len(futures)), f(elapsed), f(int64(float64(acked)/elapsed.Seconds())))
+
+	if pending > 0 {
+		log.Printf("%s messages were not acknowledged", f(pending))
+	}
+
+	errNames := make([]string, 0, len(errs))
+	for e := range errs {
+		errNames = append(errNames, e)
+	}
+	sort.Strings(errNames)
+
+	failed := pending
+	for _, e := range errNames {
+		log.Printf("%s messages failed: %s", f(errs[e]), e)
+		failed += errs[e]
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%s messages were not acknowledged successfully", f(failed))
+	}
+
+	return nil
+}
+
+// loadTemplate reads the template and all documents from every vars file
+func (c *pubCmd) loadTemplate() error {
+	if c.body != "!nil!" {
+		return fmt.Errorf("a message body can not be given with a template")
+	}
+
+	body, err := os.ReadFile(c.templateFile)
+	if err != nil {
+		return err
+	}
+	c.templateBody = string(body)
+
+	c.templateVars = nil
+	for _, file := range c.varsFiles {
+		docs, err := readTemplateVars(file)
+		if err != nil {
+			return err
+		}
+
+		c.templateVars = append(c.templateVars, docs)
+	}
+
 	return nil
 }
+
+// renderTemplate renders the template for message number seq, merging the matching document from each vars file
+func (c *pubCmd) renderTemplate(seq int) ([]byte, error) {
+	data := map[string]any{}
+	for _, docs := range c.templateVars {
+		mergeTemplateVars(data, docs[(seq-1)%len(docs)])
+	}
+
+	body, err := pubReplyBodyTemplateWithData(c.templateBody, "", seq, data)
+	if err != nil {
+		return nil, fmt.Errorf("could not render template %s: %w", c.templateFile, err)
+	}
+
+	return body, nil
+}
+
+// readTemplateVars reads all the YAML documents in file, JSON files are valid single documents
+func readTemplateVars(file string) ([]map[string]any, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var docs []map[string]any
+	dec := yaml.NewDecoder(f)
+	for {
+		doc := map[string]any{}
+		err = dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid vars file %s: %w", file, err)
+		}
+
+		docs = append(docs, doc)
+	}
+
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("vars file %s holds no data", file)
+	}
+
+	return docs, nil
+}
+
+// readHeaderFile reads the header sets in file, each line holds a JSON object mapping header names to values
+func readHeaderFile(file string) ([]map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var sets []map[string]string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		set := map[string]string{}
+		err = json.Unmarshal(scanner.Bytes(), &set)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header file %s line %d: %w", file, line, err)
+		}
+
+		sets = append(sets, set)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("could not read header file %s: %w", file, err)
+	}
+
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("header file %s holds no headers", file)
+	}
+
+	return sets, nil
+}
+
+// mergeTemplateVars copies src into dst, merging nested maps rather than replacing them
+func mergeTemplateVars(dst map[string]any, src map[string]any) {
+	for k, v := range src {
+		sm, ok := v.(map[string]any)
+		if !ok {
+			dst[k] = v
+			continue
+		}
+
+		dm, ok := dst[k].(map[string]any)
+		if !ok {
+			dm = map[string]any{}
+			dst[k] = dm
+		}
+
+		mergeTemplateVars(dm, sm)
+	}
+}
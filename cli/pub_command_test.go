@@ -0,0 +1,283 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestPubTemplate(t *testing.T) {
+	dir := t.TempDir()
+
+	write := func(name string, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		err := os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+		return path
+	}
+
+	c := &pubCmd{
+		body:         "!nil!",
+		templateFile: write("order.tmpl", "{{ Count }} {{ .order.id }} {{ .order.customer }} {{ .region }}"),
+		varsFiles: []string{
+			write("common.json", `{"region": "eu", "order": {"customer": "acme"}}`),
+			write("orders.yaml", "order:\n  id: 1\n---\norder:\n  id: 2\n"),
+		},
+	}
+
+	err := c.loadTemplate()
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	for seq, expected := range map[int]string{1: "1 1 acme eu", 2: "2 2 acme eu", 3: "3 1 acme eu"} {
+		body, err := c.renderTemplate(seq)
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if string(body) != expected {
+			t.Fatalf("expected %q got %q", expected, body)
+		}
+	}
+
+	c.body = "body"
+	err = c.loadTemplate()
+	if err == nil {
+		t.Fatalf("expected an error when giving a body and template")
+	}
+
+	c.body = "!nil!"
+	c.varsFiles = []string{write("empty.yaml", "")}
+	err = c.loadTemplate()
+	if err == nil {
+		t.Fatalf("expected an error for empty vars files")
+	}
+}
+
+func TestPubHeaderFile(t *testing.T) {
+	dir := t.TempDir()
+
+	file := filepath.Join(dir, "headers.jsonl")
+	err := os.WriteFile(file, []byte("{\"X-Trace-Id\":\"abc\",\"X-Tenant\":\"acme\"}\n\n{\"X-Tenant\":\"other\",\"X-Msg\":\"{{ Count }}\"}\n"), 0600)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	sets, err := readHeaderFile(file)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+
+	c := &pubCmd{subject: "test", hdrs: []string{"X-Static:yes"}, headerSets: sets}
+	for seq, expected := range map[int]map[string]string{
+		1: {"X-Static": "yes", "X-Trace-Id": "abc", "X-Tenant": "acme"},
+		2: {"X-Static": "yes", "X-Tenant": "other", "X-Msg": "2"},
+		3: {"X-Static": "yes", "X-Trace-Id": "abc", "X-Tenant": "acme"},
+	} {
+		msg, err := c.prepareMsg([]byte("body"), seq)
+		if err != nil {
+			t.Fatalf("prepare failed: %v", err)
+		}
+
+		got := map[string]string{}
+		for k := range msg.Header {
+			got[k] = msg.Header.Get(k)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Fatalf("message %d expected headers %v got %v", seq, expected, got)
+		}
+	}
+
+	invalid := filepath.Join(dir, "invalid.jsonl")
+	err = os.WriteFile(invalid, []byte("{\"X-Tenant\":\"acme\"}\n{\"X-Count\":1}\n"), 0600)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	_, err = readHeaderFile(invalid)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected an error for line 2 got %v", err)
+	}
+}
+
+func TestPubEnvelope(t *testing.T) {
+	c := &pubCmd{subject: "events.orders", envelope: true}
+
+	for body, expected := range map[string]string{
+		`{"id":1}`: `{"id":1}`,
+		"hello":    `"hello"`,
+	} {
+		msg, err := c.prepareMsg([]byte(body), 1)
+		if err != nil {
+			t.Fatalf("prepare failed: %v", err)
+		}
+
+		var env struct {
+			Meta    map[string]string `json:"meta"`
+			Payload json.RawMessage   `json:"payload"`
+		}
+		err = json.Unmarshal(msg.Data, &env)
+		if err != nil {
+			t.Fatalf("invalid envelope %q: %v", msg.Data, err)
+		}
+		if string(env.Payload) != expected {
+			t.Fatalf("expected payload %s got %s", expected, env.Payload)
+		}
+		if env.Meta["subject"] != "events.orders" || env.Meta["source"] != "nats-cli" {
+			t.Fatalf("unexpected meta %v", env.Meta)
+		}
+		_, err = time.Parse(time.RFC3339Nano, env.Meta["ts"])
+		if err != nil {
+			t.Fatalf("invalid time stamp %q: %v", env.Meta["ts"], err)
+		}
+	}
+
+	c.envelopeBody = `{"event":{{ .Payload }},"subject":"{{ .Subject }}","seq":{{ .Seq }},"raw":"{{ .Body }}"}`
+	msg, err := c.prepareMsg([]byte("hello"), 2)
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	expected := `{"event":"hello","subject":"events.orders","seq":2,"raw":"hello"}`
+	if string(msg.Data) != expected {
+		t.Fatalf("expected %s got %s", expected, msg.Data)
+	}
+
+	c.envelopeBody = "{{ .Missing }}"
+	_, err = c.prepareMsg([]byte("hello"), 1)
+	if err == nil {
+		t.Fatalf("expected an error for an invalid template")
+	}
+}
+
+func TestPubSoak(t *testing.T) {
+	sizes, err := parseSoakSizes("uniform:64:1KB")
+	checkErr(t, err, "parse failed: %v", err)
+	if sizes.min != 64 || sizes.max != 1024 {
+		t.Fatalf("unexpected sizes: %+v", sizes)
+	}
+	for i := 0; i < 1000; i++ {
+		if s := sizes.next(); s < 64 || s > 1024 {
+			t.Fatalf("size %d out of range", s)
+		}
+	}
+
+	sizes, err = parseSoakSizes("fixed:128")
+	checkErr(t, err, "parse failed: %v", err)
+	if sizes.next() != 128 {
+		t.Fatalf("unexpected fixed size: %+v", sizes)
+	}
+
+	for _, dist := range []string{"uniform:1KB:64", "normal:10:2", "fixed", "uniform:1:x"} {
+		_, err = parseSoakSizes(dist)
+		if err == nil {
+			t.Fatalf("expected an error for %q", dist)
+		}
+	}
+
+	cases := map[string][]string{
+		"soak.>":   {"soak.0", "soak.1", "soak.2"},
+		"a.*.b.*":  {"a.0.b.0", "a.1.b.1", "a.2.b.2"},
+		"soak.msg": {"soak.msg.0", "soak.msg.1", "soak.msg.2"},
+	}
+	for pattern, expected := range cases {
+		subjects := soakSubjects(pattern, 3)
+		if !reflect.DeepEqual(subjects, expected) {
+			t.Fatalf("%s: expected %v got %v", pattern, expected, subjects)
+		}
+	}
+}
+
+func TestAdaptiveRate(t *testing.T) {
+	a := newAdaptiveRate(1000)
+	if a.rate != 100 {
+		t.Fatalf("expected to start at a tenth of the maximum got %v", a.rate)
+	}
+
+	// healthy checks increase the rate up to the maximum
+	for i := 0; i < 20; i++ {
+		if a.adjust(0, time.Millisecond) {
+			t.Fatalf("unexpected congestion on check %d", i)
+		}
+	}
+	if a.rate != 1000 || a.peak != 1000 {
+		t.Fatalf("expected the maximum rate got %v peak %v", a.rate, a.peak)
+	}
+
+	// jitter below the slack is not congestion
+	if a.adjust(0, 4*time.Millisecond) {
+		t.Fatalf("unexpected congestion for a small rtt increase")
+	}
+
+	if !a.adjust(0, 10*time.Millisecond) || a.rate != 500 {
+		t.Fatalf("expected a slow rtt to halve the rate got %v", a.rate)
+	}
+
+	if !a.adjust(2*adaptiveRateBufferLimit, time.Millisecond) || a.rate != 250 {
+		t.Fatalf("expected a full buffer to halve the rate got %v", a.rate)
+	}
+
+	// a buffer that is draining is not congestion
+	if a.adjust(adaptiveRateBufferLimit+1, time.Millisecond) || a.adjust(0, time.Millisecond) {
+		t.Fatalf("unexpected congestion for a draining buffer")
+	}
+
+	for i := 0; i < 20; i++ {
+		a.adjust(0, time.Second)
+	}
+	if a.rate != 1 || a.decreases != 22 || a.peak != 1000 {
+		t.Fatalf("expected the rate to stay at least 1 got %v after %d decreases", a.rate, a.decreases)
+	}
+}
+
+func TestPubChaos(t *testing.T) {
+	SetLogger(goLogger{})
+
+	for _, tc := range []struct {
+		drop  float64
+		dup   float64
+		delay time.Duration
+	}{{-0.1, 0, 0}, {1.1, 0, 0}, {0, 2, 0}, {0, 0, -time.Second}} {
+		_, err := newPubChaos(tc.drop, tc.dup, tc.delay)
+		if err == nil {
+			t.Fatalf("expected %+v to be invalid", tc)
+		}
+	}
+
+	chaos, err := newPubChaos(1, 0, time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 1; i <= 10; i++ {
+		publish, err := chaos.apply(context.Background(), nil, &nats.Msg{Subject: "chaos.test"}, i)
+		if publish || err != nil {
+			t.Fatalf("expected message %d to be dropped: %v", i, err)
+		}
+	}
+	if chaos.dropped != 10 || chaos.delayed != 0 || chaos.duplicated != 0 {
+		t.Fatalf("unexpected counts %d %d %d", chaos.dropped, chaos.delayed, chaos.duplicated)
+	}
+}
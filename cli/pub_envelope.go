@@ -0,0 +1,98 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// pubEnvelopeSource is the source recorded in the envelope of published messages
+const pubEnvelopeSource = "nats-cli"
+
+// pubEnvelope is the standard JSON envelope wrapping message bodies
+type pubEnvelope struct {
+	Meta    pubEnvelopeMeta `json:"meta"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+type pubEnvelopeMeta struct {
+	Subject string `json:"subject"`
+	TS      string `json:"ts"`
+	Source  string `json:"source"`
+}
+
+// pubEnvelopeData is the data available to custom envelope templates
+type pubEnvelopeData struct {
+	Subject   string
+	TimeStamp string
+	Source    string
+	Seq       int
+	// Payload is the body as JSON, bodies that are not valid JSON are encoded as strings
+	Payload string
+	// Body is the unmodified body
+	Body string
+}
+
+// loadEnvelope reads the custom envelope template when one is given
+func (c *pubCmd) loadEnvelope() error {
+	if c.envelopeTemplate == "" {
+		return nil
+	}
+
+	body, err := os.ReadFile(c.envelopeTemplate)
+	if err != nil {
+		return err
+	}
+	c.envelopeBody = string(body)
+
+	return nil
+}
+
+// wrapEnvelope wraps body published to subject in the standard envelope or the custom envelope template
+func (c *pubCmd) wrapEnvelope(subject string, body []byte, seq int) ([]byte, error) {
+	payload := json.RawMessage(body)
+	if !json.Valid(body) {
+		var err error
+		payload, err = json.Marshal(string(body))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ts := time.Now().UTC().Format(time.RFC3339Nano)
+
+	if c.envelopeBody == "" {
+		return json.Marshal(pubEnvelope{
+			Meta:    pubEnvelopeMeta{Subject: subject, TS: ts, Source: pubEnvelopeSource},
+			Payload: payload,
+		})
+	}
+
+	wrapped, err := pubReplyBodyTemplateWithData(c.envelopeBody, "", seq, &pubEnvelopeData{
+		Subject:   subject,
+		TimeStamp: ts,
+		Source:    pubEnvelopeSource,
+		Seq:       seq,
+		Payload:   string(payload),
+		Body:      string(body),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not render envelope template %s: %w", c.envelopeTemplate, err)
+	}
+
+	return wrapped, nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+// pubForwardTransform turns a forwarded payload into the payloads to publish, none are returned when the message is
+// filtered out
+type pubForwardTransform func(data []byte) ([][]byte, error)
+
+// newPubForwardTransform compiles a jq expression that is run against JSON payloads, string results are published
+// as is while other results are published as JSON
+func newPubForwardTransform(expr string) (pubForwardTransform, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform: %w", err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform: %w", err)
+	}
+
+	return func(data []byte) ([][]byte, error) {
+		var doc any
+		err := json.Unmarshal(data, &doc)
+		if err != nil {
+			return nil, fmt.Errorf("payload is not JSON: %w", err)
+		}
+
+		var results [][]byte
+		iter := code.Run(doc)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+
+			switch r := v.(type) {
+			case error:
+				return nil, r
+			case string:
+				results = append(results, []byte(r))
+			default:
+				j, err := json.Marshal(r)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, j)
+			}
+		}
+
+		return results, nil
+	}, nil
+}
+
+// forwardPublish republishes every message received on the forward subject to the publish subject until interrupted,
+// returning how many were published
+func (c *pubCmd) forwardPublish(nc *nats.Conn, capture *msgCaptureWriter) (published uint64, err error) {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var transform pubForwardTransform
+	if c.forwardTransform != "" {
+		transform, err = newPubForwardTransform(c.forwardTransform)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	sub, err := nc.SubscribeSync(c.forwardFrom)
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsubscribe()
+
+	err = nc.Flush()
+	if err != nil {
+		return 0, err
+	}
+
+	var received, failed uint64
+
+	target := fmt.Sprintf("%q", c.subject)
+	switch {
+	case c.forwardMapper.Len() > 0 && c.subject != "":
+		target = fmt.Sprintf("subjects mapped using %d subject maps or %q", c.forwardMapper.Len(), c.subject)
+	case c.forwardMapper.Len() > 0:
+		target = fmt.Sprintf("subjects mapped using %d subject maps", c.forwardMapper.Len())
+	}
+
+	log.Printf("Forwarding messages from %q to %s", c.forwardFrom, target)
+	defer func() {
+		log.Printf("Forwarded %s messages to %s from %s received on %q, %s could not be forwarded", f(published), target, f(received), c.forwardFrom, f(failed))
+	}()
+
+	for {
+		msg, err := sub.NextMsgWithContext(ctx)
+		if ctx.Err() != nil {
+			return published, nil
+		}
+		if err != nil {
+			return published, err
+		}
+
+		received++
+
+		subject, ok := c.forwardSubject(msg.Subject)
+		if !ok {
+			failed++
+			continue
+		}
+
+		bodies := [][]byte{msg.Data}
+		if transform != nil {
+			bodies, err = transform(msg.Data)
+			if err != nil {
+				logErrorf("Could not transform message received on %q: %v", msg.Subject, err)
+				failed++
+				continue
+			}
+		}
+
+		for _, body := range bodies {
+			out, err := c.prepareMsgTo(subject, body, int(published)+1)
+			if err != nil {
+				return published, err
+			}
+
+			// replies go straight back to the original requester
+			out.Reply = msg.Reply
+
+			if !c.stripHeaders {
+				for k, vals := range msg.Header {
+					for _, v := range vals {
+						out.Header.Add(k, v)
+					}
+				}
+			}
+
+			if capture != nil {
+				capture.capture(out)
+			}
+
+			err = nc.PublishMsg(out)
+			if err != nil {
+				return published, err
+			}
+
+			published++
+		}
+
+		if c.sleep > 0 {
+			select {
+			case <-time.After(c.sleep):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+// forwardSubject is the subject to forward a message received on subject to, false when it should be skipped
+func (c *pubCmd) forwardSubject(subject string) (string, bool) {
+	mapped, used := c.forwardMapper.Map(subject)
+	switch {
+	case used == nil && c.subject == "":
+		logWarnf("Skipping message received on %q, it does not match any subject map", subject)
+		return "", false
+	case used == nil:
+		return c.subject, true
+	case api.SubjectIsSubsetMatch(mapped, c.forwardFrom):
+		logErrorf("Skipping message received on %q, it maps to %q using %s which would forward it again", subject, mapped, used)
+		return "", false
+	}
+
+	return mapped, true
+}
+
+// validateForward checks that the publish flags make sense when forwarding
+func (c *pubCmd) validateForward() error {
+	if c.forwardTo != "" {
+		if c.subject != "" && c.subject != c.forwardTo {
+			return fmt.Errorf("the subject %q and --to %q can not both be given", c.subject, c.forwardTo)
+		}
+		c.subject = c.forwardTo
+	}
+
+	switch {
+	case c.subject == "" && c.forwardMapper.Len() == 0:
+		return fmt.Errorf("a subject to forward to is required, use --to or --subject-map")
+	case c.body != "!nil!" || c.templateFile != "":
+		return fmt.Errorf("a message body or template can not be used with forward-from")
+	case c.tail != "" || c.replyTo != "" || c.jsAsync || len(c.alsoPublish) > 0:
+		return fmt.Errorf("tail, reply, js-async and also-publish can not be used with forward-from")
+	case c.subject != "" && api.SubjectIsSubsetMatch(c.subject, c.forwardFrom):
+		return fmt.Errorf("forwarding %q to %q would forward its own messages", c.forwardFrom, c.subject)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nats-io/natscli/internal/subjectmap"
+)
+
+func TestPubForwardTransform(t *testing.T) {
+	transform := func(expr string, data string) []string {
+		t.Helper()
+
+		tf, err := newPubForwardTransform(expr)
+		checkErr(t, err, "compile failed: %v", err)
+
+		out, err := tf([]byte(data))
+		checkErr(t, err, "transform failed: %v", err)
+
+		var res []string
+		for _, o := range out {
+			res = append(res, string(o))
+		}
+
+		return res
+	}
+
+	out := transform(".payload", `{"payload":{"id":1}}`)
+	if len(out) != 1 || out[0] != `{"id":1}` {
+		t.Fatalf("expected the payload as JSON got %v", out)
+	}
+
+	out = transform(".name", `{"name":"order"}`)
+	if len(out) != 1 || out[0] != "order" {
+		t.Fatalf("expected the raw string got %v", out)
+	}
+
+	out = transform(".[]", `[1,2,3]`)
+	if strings.Join(out, ",") != "1,2,3" {
+		t.Fatalf("expected a result per element got %v", out)
+	}
+
+	out = transform(`select(.keep)`, `{"keep":false}`)
+	if len(out) != 0 {
+		t.Fatalf("expected the message to be filtered got %v", out)
+	}
+
+	tf, err := newPubForwardTransform(".")
+	checkErr(t, err, "compile failed: %v", err)
+	_, err = tf([]byte("hello"))
+	if err == nil || !strings.Contains(err.Error(), "payload is not JSON") {
+		t.Fatalf("expected a JSON error got %v", err)
+	}
+
+	_, err = newPubForwardTransform(".[")
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid transform") {
+		t.Fatalf("expected a parse error got %v", err)
+	}
+}
+
+func TestPubValidateForward(t *testing.T) {
+	c := &pubCmd{body: "!nil!", forwardFrom: "orders.>", forwardTo: "audit.orders"}
+	err := c.validateForward()
+	checkErr(t, err, "validate failed: %v", err)
+	if c.subject != "audit.orders" {
+		t.Fatalf("expected --to to set the subject got %q", c.subject)
+	}
+
+	c = &pubCmd{body: "!nil!", forwardFrom: "orders.>", subject: "orders.audit"}
+	err = c.validateForward()
+	if err == nil || !strings.Contains(err.Error(), "would forward its own messages") {
+		t.Fatalf("expected a loop error got %v", err)
+	}
+
+	c = &pubCmd{body: "!nil!", forwardFrom: "orders.new", subject: "a", forwardTo: "b"}
+	err = c.validateForward()
+	if err == nil || !strings.Contains(err.Error(), "can not both be given") {
+		t.Fatalf("expected a conflict error got %v", err)
+	}
+
+	c = &pubCmd{body: "hello", forwardFrom: "orders.new", forwardTo: "b"}
+	err = c.validateForward()
+	if err == nil {
+		t.Fatalf("expected a body error")
+	}
+}
+
+func TestPubForwardSubject(t *testing.T) {
+	SetLogger(goLogger{})
+
+	maps, err := subjectmap.New([]string{"orders.*.created=archive.orders.$1.created", "orders.*.loop=orders.$1"})
+	checkErr(t, err, "maps failed: %v", err)
+
+	c := &pubCmd{body: "!nil!", forwardFrom: "orders.>", forwardMapper: maps}
+	err = c.validateForward()
+	checkErr(t, err, "validate failed: %v", err)
+
+	for subject, expected := range map[string]string{
+		"orders.eu.created": "archive.orders.eu.created",
+		"orders.eu.deleted": "",
+		"orders.eu.loop":    "",
+	} {
+		mapped, ok := c.forwardSubject(subject)
+		if mapped != expected || ok != (expected != "") {
+			t.Fatalf("expected %q to forward to %q got %q", subject, expected, mapped)
+		}
+	}
+
+	c = &pubCmd{body: "!nil!", forwardFrom: "orders.>", forwardTo: "audit", forwardMapper: maps}
+	err = c.validateForward()
+	checkErr(t, err, "validate failed: %v", err)
+
+	if mapped, ok := c.forwardSubject("orders.eu.deleted"); !ok || mapped != "audit" {
+		t.Fatalf("expected unmatched subjects to forward to --to got %q", mapped)
+	}
+
+	c = &pubCmd{body: "!nil!", forwardFrom: "orders.>"}
+	err = c.validateForward()
+	if err == nil || !strings.Contains(err.Error(), "use --to or --subject-map") {
+		t.Fatalf("expected a missing subject error got %v", err)
+	}
+}
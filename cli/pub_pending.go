@@ -0,0 +1,51 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// pubPendingLimit blocks publishing while the connection buffers more than a maximum number of bytes, the
+// nats.go client only limits buffering while reconnecting so the buffer is checked after every publish
+type pubPendingLimit struct {
+	max     int
+	blocked uint64
+	waited  time.Duration
+}
+
+func newPubPendingLimit(max int) *pubPendingLimit {
+	return &pubPendingLimit{max: max}
+}
+
+// wait blocks until the server received all buffered data when at least max bytes are buffered
+func (l *pubPendingLimit) wait(nc *nats.Conn) error {
+	buffered, err := nc.Buffered()
+	if err != nil || buffered < l.max {
+		return err
+	}
+
+	l.blocked++
+	start := time.Now()
+	err = nc.FlushTimeout(opts().Timeout)
+	l.waited += time.Since(start)
+
+	return err
+}
+
+func (l *pubPendingLimit) report() {
+	log.Printf("Publishing blocked %s times for %s in total waiting for %s of buffered data to be sent", f(l.blocked), f(l.waited.Round(time.Millisecond)), fiBytes(uint64(l.max)))
+}
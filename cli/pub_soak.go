@@ -0,0 +1,259 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// soakSizes picks payload sizes for soak tests
+type soakSizes struct {
+	min int
+	max int
+}
+
+// soakStats are the totals of a soak test or one of its reporting intervals
+type soakStats struct {
+	published uint64
+	bytes     uint64
+	errors    uint64
+}
+
+// parseSoakSizes parses a size distribution like fixed:128 or uniform:64:1KB
+func parseSoakSizes(dist string) (*soakSizes, error) {
+	parts := strings.Split(dist, ":")
+
+	size := func(s string) (int, error) {
+		v, err := parseStringAsBytes(s)
+		if err != nil {
+			return 0, err
+		}
+		if v < 0 {
+			return 0, fmt.Errorf("invalid size %q", s)
+		}
+
+		return int(v), nil
+	}
+
+	switch {
+	case parts[0] == "fixed" && len(parts) == 2:
+		s, err := size(parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		return &soakSizes{min: s, max: s}, nil
+
+	case parts[0] == "uniform" && len(parts) == 3:
+		low, err := size(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		high, err := size(parts[2])
+		if err != nil {
+			return nil, err
+		}
+		if low > high {
+			return nil, fmt.Errorf("the smallest size %s is larger than the largest size %s", parts[1], parts[2])
+		}
+
+		return &soakSizes{min: low, max: high}, nil
+
+	default:
+		return nil, fmt.Errorf("invalid size distribution %q, expected fixed:SIZE or uniform:MIN:MAX", dist)
+	}
+}
+
+func (s *soakSizes) next() int {
+	if s.max == s.min {
+		return s.min
+	}
+
+	return s.min + rng.Intn(s.max-s.min+1)
+}
+
+// soakSubjects creates count subjects from pattern, wildcards are replaced by the subject number and patterns without
+// wildcards get the number appended as a final token
+func soakSubjects(pattern string, count int) []string {
+	tokens := strings.Split(pattern, ".")
+
+	wild := false
+	for _, t := range tokens {
+		if t == "*" || t == ">" {
+			wild = true
+			break
+		}
+	}
+
+	subjects := make([]string, count)
+	for i := range subjects {
+		n := strconv.Itoa(i)
+		if !wild {
+			subjects[i] = pattern + "." + n
+			continue
+		}
+
+		parts := make([]string, len(tokens))
+		for ti, t := range tokens {
+			if t == "*" || t == ">" {
+				parts[ti] = n
+			} else {
+				parts[ti] = t
+			}
+		}
+		subjects[i] = strings.Join(parts, ".")
+	}
+
+	return subjects
+}
+
+// validateSoak checks the flags used for soak tests and prepares the subject
+func (c *pubCmd) validateSoak() error {
+	if c.soakSubjects != "" {
+		if c.subject != "" && c.subject != c.soakSubjects {
+			return fmt.Errorf("the subject %q and --subjects %q can not both be given", c.subject, c.soakSubjects)
+		}
+		c.subject = c.soakSubjects
+	}
+
+	switch {
+	case c.subject == "":
+		return fmt.Errorf("subjects to publish to are required, use --subjects")
+	case c.body != "!nil!" || c.templateFile != "":
+		return fmt.Errorf("a message body or template can not be used with soak, payloads are generated using --size-distribution")
+	case c.tail != "" || c.forwardFrom != "" || c.replyTo != "" || c.jsAsync || len(c.alsoPublish) > 0:
+		return fmt.Errorf("tail, forward-from, reply, js-async and also-publish can not be used with soak")
+	case c.soakSubjectCount < 1:
+		return fmt.Errorf("subject-count must be at least 1")
+	case c.soakRate < 1:
+		return fmt.Errorf("msg-rate must be at least 1")
+	case c.soakInterval <= 0:
+		return fmt.Errorf("report-interval must be positive")
+	case c.soakDuration < 0:
+		return fmt.Errorf("duration can not be negative")
+	}
+
+	var err error
+	c.soakSizeDist, err = parseSoakSizes(c.soakDistribution)
+
+	return err
+}
+
+// soakPublish publishes generated messages at a steady rate to random subjects until the duration passed or
+// interrupted, any publish error fails the soak test once it completes
+func (c *pubCmd) soakPublish(nc *nats.Conn, capture *msgCaptureWriter) (uint64, error) {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if c.soakDuration > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.soakDuration)
+		defer cancel()
+	}
+
+	subjects := soakSubjects(c.subject, c.soakSubjectCount)
+	payload := []byte(randomString(uint(c.soakSizeDist.max), uint(c.soakSizeDist.max)))
+
+	// publishing in small batches keeps the rate steady without a timer per message
+	tick := 10 * time.Millisecond
+	if interval := time.Second / time.Duration(c.soakRate); interval > tick {
+		tick = interval
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	report := time.NewTicker(c.soakInterval)
+	defer report.Stop()
+
+	var total, interval soakStats
+	start := time.Now()
+	intervalStart := start
+	lastErr := nc.LastError()
+
+	fail := func(err error) {
+		total.errors++
+		interval.errors++
+
+		// only the first few errors are shown, the rest are counted in the summaries
+		if total.errors <= 10 {
+			log.Printf("Publish failed: %v", err)
+		}
+	}
+
+	log.Printf("Soak testing %s subjects matching %q at %s msg/sec with %s payloads", f(len(subjects)), c.subject, f(c.soakRate), c.soakDistribution)
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.soakSummary("Completed", time.Since(start), total)
+			if total.errors > 0 {
+				return total.published, fmt.Errorf("%s publish errors occurred during the soak test", f(total.errors))
+			}
+			return total.published, nil
+
+		case <-report.C:
+			if err := nc.LastError(); err != nil && err != lastErr {
+				lastErr = err
+				fail(err)
+			}
+
+			c.soakSummary("Interval", time.Since(intervalStart), interval)
+			interval = soakStats{}
+			intervalStart = time.Now()
+
+		case <-ticker.C:
+			target := uint64(time.Since(start).Seconds() * float64(c.soakRate))
+
+			for total.published+total.errors < target && ctx.Err() == nil {
+				size := c.soakSizeDist.next()
+				seq := int(total.published + total.errors + 1)
+
+				msg, err := c.prepareMsg(payload[:size], seq)
+				if err != nil {
+					return total.published, err
+				}
+				msg.Subject = subjects[rng.Intn(len(subjects))]
+
+				err = nc.PublishMsg(msg)
+				if err != nil {
+					fail(err)
+					continue
+				}
+
+				if capture != nil {
+					capture.capture(msg)
+				}
+
+				total.published++
+				interval.published++
+				total.bytes += uint64(len(msg.Data))
+				interval.bytes += uint64(len(msg.Data))
+			}
+		}
+	}
+}
+
+func (c *pubCmd) soakSummary(title string, elapsed time.Duration, stats soakStats) {
+	rate := float64(stats.published) / elapsed.Seconds()
+
+	log.Printf("%s %v: published %s messages totaling %s at %s msg/sec, %s errors", title, elapsed.Round(time.Second), f(stats.published), fiBytes(stats.bytes), f(int64(rate)), f(stats.errors))
+}
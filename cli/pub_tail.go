@@ -0,0 +1,451 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	pubTailPollInterval             = 250 * time.Millisecond
+	pubTailCheckpointInterval       = time.Second
+	pubTailFingerprintSize    int64 = 1024
+)
+
+type pubTailRotation int
+
+const (
+	pubTailUnchanged pubTailRotation = iota
+	pubTailReplaced
+	pubTailTruncated
+)
+
+// pubTailCheckpoint records how much of a file was published, the fingerprint of the start of the file detects
+// rotation between runs
+type pubTailCheckpoint struct {
+	File             string `json:"file"`
+	Generation       uint64 `json:"generation"`
+	Offset           int64  `json:"offset"`
+	Fingerprint      string `json:"fingerprint"`
+	FingerprintBytes int64  `json:"fingerprint_bytes"`
+}
+
+// pubTailer reads complete lines from a file that is being written to, following it when truncated or replaced
+type pubTailer struct {
+	path       string
+	file       *os.File
+	info       os.FileInfo
+	reader     *bufio.Reader
+	offset     int64
+	generation uint64
+	partial    []byte
+}
+
+func loadPubTailCheckpoint(path string) (*pubTailCheckpoint, error) {
+	j, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &pubTailCheckpoint{}
+	err = json.Unmarshal(j, cp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+
+	return cp, nil
+}
+
+func (cp *pubTailCheckpoint) save(path string) error {
+	j, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, j, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// newPubTailer opens path, resuming from cp when it was made for the same file and the file was not rotated since
+func newPubTailer(path string, cp *pubTailCheckpoint) (*pubTailer, error) {
+	t := &pubTailer{path: path}
+
+	err := t.open()
+	if err != nil {
+		return nil, err
+	}
+
+	if cp == nil || cp.File != path {
+		return t, nil
+	}
+
+	same, err := t.matches(cp)
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+
+	if !same {
+		t.generation = cp.Generation + 1
+		return t, nil
+	}
+
+	_, err = t.file.Seek(cp.Offset, io.SeekStart)
+	if err != nil {
+		t.close()
+		return nil, err
+	}
+
+	t.reader.Reset(t.file)
+	t.offset = cp.Offset
+	t.generation = cp.Generation
+
+	return t, nil
+}
+
+func (t *pubTailer) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	t.file = file
+	t.info = info
+	t.reader = bufio.NewReader(file)
+	t.offset = 0
+	t.partial = nil
+
+	return nil
+}
+
+func (t *pubTailer) close() {
+	if t.file != nil {
+		t.file.Close()
+	}
+}
+
+// matches determines if the open file is the one the checkpoint was made for
+func (t *pubTailer) matches(cp *pubTailCheckpoint) (bool, error) {
+	if t.info.Size() < cp.Offset || t.info.Size() < cp.FingerprintBytes {
+		return false, nil
+	}
+
+	fp, err := t.fingerprint(cp.FingerprintBytes)
+	if err != nil {
+		return false, err
+	}
+
+	return fp == cp.Fingerprint, nil
+}
+
+func (t *pubTailer) fingerprint(size int64) (string, error) {
+	h := sha256.New()
+	_, err := io.Copy(h, io.NewSectionReader(t.file, 0, size))
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkpoint creates a checkpoint that resumes after the first offset bytes of the current file
+func (t *pubTailer) checkpoint(offset int64) (*pubTailCheckpoint, error) {
+	size := offset
+	if size > pubTailFingerprintSize {
+		size = pubTailFingerprintSize
+	}
+
+	fp, err := t.fingerprint(size)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pubTailCheckpoint{
+		File:             t.path,
+		Generation:       t.generation,
+		Offset:           offset,
+		Fingerprint:      fp,
+		FingerprintBytes: size,
+	}, nil
+}
+
+// next returns the next non-empty line without its line ending and the offset it starts at, io.EOF is returned when
+// no complete line is available yet
+func (t *pubTailer) next() ([]byte, int64, error) {
+	for {
+		data, err := t.reader.ReadBytes('\n')
+		t.partial = append(t.partial, data...)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		line := bytes.TrimRight(t.partial, "\r\n")
+		start := t.offset
+		t.offset += int64(len(t.partial))
+		t.partial = nil
+
+		if len(line) > 0 {
+			return line, start, nil
+		}
+	}
+}
+
+// remainder returns the unterminated last line of the file, only useful once it was replaced and can not grow anymore
+func (t *pubTailer) remainder() ([]byte, int64) {
+	line := bytes.TrimRight(t.partial, "\r\n")
+	start := t.offset
+	t.offset += int64(len(t.partial))
+	t.partial = nil
+
+	if len(line) == 0 {
+		return nil, 0
+	}
+
+	return line, start
+}
+
+// rotation checks if the file at path was replaced by a new file or truncated since it was opened
+func (t *pubTailer) rotation() (pubTailRotation, error) {
+	info, err := os.Stat(t.path)
+	if errors.Is(err, os.ErrNotExist) {
+		// moved away and not yet recreated
+		return pubTailUnchanged, nil
+	}
+	if err != nil {
+		return pubTailUnchanged, err
+	}
+
+	switch {
+	case !os.SameFile(info, t.info):
+		return pubTailReplaced, nil
+	case info.Size() < t.offset+int64(len(t.partial)):
+		return pubTailTruncated, nil
+	default:
+		return pubTailUnchanged, nil
+	}
+}
+
+// restart follows a rotated file from its start, every file followed is a new generation so message ids stay unique
+func (t *pubTailer) restart(rotation pubTailRotation) error {
+	t.generation++
+
+	if rotation == pubTailReplaced {
+		t.file.Close()
+		return t.open()
+	}
+
+	_, err := t.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	t.reader.Reset(t.file)
+	t.offset = 0
+	t.partial = nil
+
+	return nil
+}
+
+// tailPublish follows the tail file publishing every line to JetStream until interrupted, returning how many were published
+func (c *pubCmd) tailPublish(nc *nats.Conn, capture *msgCaptureWriter) (published uint64, err error) {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	path, err := filepath.Abs(c.tail)
+	if err != nil {
+		return 0, err
+	}
+
+	var cp *pubTailCheckpoint
+	if c.checkpoint != "" {
+		cp, err = loadPubTailCheckpoint(c.checkpoint)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	t, err := newPubTailer(path, cp)
+	if err != nil {
+		return 0, err
+	}
+	defer t.close()
+
+	js, err := nc.JetStream(jsOpts()...)
+	if err != nil {
+		return 0, err
+	}
+
+	// the offset after the last line that was acknowledged, checkpoints never cover lines that were not published
+	acked := t.offset
+	dirty := true
+	lastSave := time.Now()
+
+	save := func() error {
+		if c.checkpoint == "" || !dirty {
+			return nil
+		}
+
+		cp, err := t.checkpoint(acked)
+		if err != nil {
+			return err
+		}
+
+		dirty = false
+		lastSave = time.Now()
+
+		return cp.save(c.checkpoint)
+	}
+
+	defer func() {
+		serr := save()
+		if serr != nil {
+			logErrorf("Could not save checkpoint %s: %v", c.checkpoint, serr)
+		}
+
+		if c.checkpoint != "" {
+			log.Printf("Published %s lines from %s, saved checkpoint at offset %d to %s", f(published), path, acked, c.checkpoint)
+		} else {
+			log.Printf("Published %s lines from %s ending at offset %d", f(published), path, acked)
+		}
+	}()
+
+	publish := func(line []byte, offset int64) error {
+		msg, err := c.prepareMsg(line, int(published)+1)
+		if err != nil {
+			return err
+		}
+
+		msg.Header.Set(nats.MsgIdHdr, fmt.Sprintf("%s:%d:%d", t.path, t.generation, offset))
+		msg.Header.Set("File-Name", t.path)
+		msg.Header.Set("File-Offset", strconv.FormatInt(offset, 10))
+
+		if capture != nil {
+			capture.capture(msg)
+		}
+
+		_, err = js.PublishMsg(msg)
+		if err != nil {
+			return err
+		}
+
+		published++
+
+		return nil
+	}
+
+	log.Printf("Publishing lines from %s to %q starting at offset %d", path, c.subject, t.offset)
+
+	poll := time.NewTicker(pubTailPollInterval)
+	defer poll.Stop()
+
+	for ctx.Err() == nil {
+		line, offset, err := t.next()
+		if err == nil {
+			err = publish(line, offset)
+			if err != nil {
+				return published, err
+			}
+			acked = t.offset
+			dirty = true
+
+			if time.Since(lastSave) >= pubTailCheckpointInterval {
+				err = save()
+				if err != nil {
+					return published, err
+				}
+			}
+
+			if c.sleep > 0 {
+				select {
+				case <-time.After(c.sleep):
+				case <-ctx.Done():
+				}
+			}
+
+			continue
+		}
+		if !errors.Is(err, io.EOF) {
+			return published, err
+		}
+
+		// everything written so far was read, save progress while waiting for more
+		err = save()
+		if err != nil {
+			return published, err
+		}
+
+		rotation, err := t.rotation()
+		if err != nil {
+			return published, err
+		}
+
+		if rotation == pubTailReplaced {
+			line, offset := t.remainder()
+			if line != nil {
+				err = publish(line, offset)
+				if err != nil {
+					return published, err
+				}
+			}
+		}
+
+		if rotation != pubTailUnchanged {
+			err = t.restart(rotation)
+			if err != nil {
+				return published, err
+			}
+			acked = 0
+			dirty = true
+
+			log.Printf("%s was rotated, publishing from the start of the new file", path)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-poll.C:
+		}
+	}
+
+	return published, nil
+}
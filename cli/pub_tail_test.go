@@ -0,0 +1,167 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPubTailer(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	write := func(content string, flag int) {
+		t.Helper()
+		fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|flag, 0600)
+		if err != nil {
+			t.Fatalf("open failed: %v", err)
+		}
+		defer fh.Close()
+
+		_, err = fh.WriteString(content)
+		if err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+
+	expectLine := func(tailer *pubTailer, line string, offset int64) {
+		t.Helper()
+		l, o, err := tailer.next()
+		if err != nil {
+			t.Fatalf("next failed: %v", err)
+		}
+		if string(l) != line || o != offset {
+			t.Fatalf("expected %q at %d got %q at %d", line, offset, l, o)
+		}
+	}
+
+	expectEOF := func(tailer *pubTailer) {
+		t.Helper()
+		_, _, err := tailer.next()
+		if !errors.Is(err, io.EOF) {
+			t.Fatalf("expected EOF got %v", err)
+		}
+	}
+
+	write("one\r\n\ntwo\nthr", os.O_TRUNC)
+
+	tailer, err := newPubTailer(path, nil)
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	defer tailer.close()
+
+	expectLine(tailer, "one", 0)
+	expectLine(tailer, "two", 6)
+	expectEOF(tailer)
+
+	// the partial line is completed by a later write
+	write("ee\n", os.O_APPEND)
+	expectLine(tailer, "three", 10)
+	expectEOF(tailer)
+
+	cp, err := tailer.checkpoint(tailer.offset)
+	if err != nil {
+		t.Fatalf("checkpoint failed: %v", err)
+	}
+
+	rotation, err := tailer.rotation()
+	if err != nil || rotation != pubTailUnchanged {
+		t.Fatalf("expected no rotation got %v: %v", rotation, err)
+	}
+
+	t.Run("resume", func(t *testing.T) {
+		write("four\n", os.O_APPEND)
+
+		resumed, err := newPubTailer(path, cp)
+		if err != nil {
+			t.Fatalf("tail failed: %v", err)
+		}
+		defer resumed.close()
+
+		if resumed.generation != cp.Generation {
+			t.Fatalf("expected generation %d got %d", cp.Generation, resumed.generation)
+		}
+		expectLine(resumed, "four", 16)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		write("new\n", os.O_TRUNC)
+
+		rotation, err := tailer.rotation()
+		if err != nil || rotation != pubTailTruncated {
+			t.Fatalf("expected truncation got %v: %v", rotation, err)
+		}
+
+		err = tailer.restart(rotation)
+		if err != nil {
+			t.Fatalf("restart failed: %v", err)
+		}
+		if tailer.generation != 1 {
+			t.Fatalf("expected generation 1 got %d", tailer.generation)
+		}
+		expectLine(tailer, "new", 0)
+
+		// the checkpoint was made before truncation so the file is not resumed
+		resumed, err := newPubTailer(path, cp)
+		if err != nil {
+			t.Fatalf("tail failed: %v", err)
+		}
+		defer resumed.close()
+
+		if resumed.generation != cp.Generation+1 || resumed.offset != 0 {
+			t.Fatalf("expected a new generation at offset 0 got %d at %d", resumed.generation, resumed.offset)
+		}
+	})
+
+	t.Run("replaced", func(t *testing.T) {
+		write("last", os.O_APPEND)
+		expectEOF(tailer)
+
+		err := os.Rename(path, path+".1")
+		if err != nil {
+			t.Fatalf("rename failed: %v", err)
+		}
+
+		rotation, err := tailer.rotation()
+		if err != nil || rotation != pubTailUnchanged {
+			t.Fatalf("expected no rotation while the file is missing got %v: %v", rotation, err)
+		}
+
+		write("replacement\n", os.O_TRUNC)
+
+		rotation, err = tailer.rotation()
+		if err != nil || rotation != pubTailReplaced {
+			t.Fatalf("expected replacement got %v: %v", rotation, err)
+		}
+
+		line, offset := tailer.remainder()
+		if string(line) != "last" || offset != 4 {
+			t.Fatalf("expected remainder last at 4 got %q at %d", line, offset)
+		}
+
+		err = tailer.restart(rotation)
+		if err != nil {
+			t.Fatalf("restart failed: %v", err)
+		}
+		if tailer.generation != 2 {
+			t.Fatalf("expected generation 2 got %d", tailer.generation)
+		}
+		expectLine(tailer, "replacement", 0)
+	})
+}
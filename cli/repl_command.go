@@ -0,0 +1,433 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/choria-io/fisk"
+	"github.com/kballard/go-shellquote"
+	iu "github.com/nats-io/natscli/internal/util"
+	terminal "golang.org/x/term"
+)
+
+// replTerminated is raised by the REPL application instead of exiting the process
+type replTerminated int
+
+type replCmd struct {
+	mu        sync.Mutex
+	streams   []string
+	consumers map[string][]string
+}
+
+func configureReplCommand(app commandHost) {
+	c := &replCmd{}
+
+	help := `Interactive shell running commands over a single connection
+
+Commands are entered without the leading 'nats' and use the connection
+established when the shell starts, global connection flags can not be
+changed from within the shell.
+
+Ctrl-C interrupts a running command and clears the current line at the
+prompt, exit, quit or Ctrl-D ends the session.
+`
+
+	repl := app.Command("repl", help).Action(c.replAction)
+	addCheat("repl", repl)
+}
+
+func init() {
+	registerCommand("repl", 21, configureReplCommand)
+}
+
+func (c *replCmd) replAction(_ *fisk.ParseContext) error {
+	_, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C should only ever interrupt the running command, never the shell itself
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	w := &cliErrorWriter{w: os.Stderr}
+	fisk.CommandLine.Terminate(func(status int) { panic(replTerminated(status)) })
+	defer fisk.CommandLine.Terminate(w.terminate)
+
+	readLine, err := c.lineReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		line, err := readLine()
+		if err == io.EOF {
+			if iu.IsTerminal() {
+				fmt.Println()
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		switch line {
+		case "":
+			continue
+		case "exit", "quit":
+			return c.close()
+		}
+
+		args, err := shellquote.Split(line)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nats: error: %v\n", err)
+			continue
+		}
+
+		c.execute(args)
+	}
+
+	return c.close()
+}
+
+// lineReader reads lines using an editor with history and completion on terminals and plain lines otherwise
+func (c *replCmd) lineReader() (func() (string, error), error) {
+	if !iu.IsTerminal() {
+		scanner := bufio.NewScanner(os.Stdin)
+		return func() (string, error) {
+			if !scanner.Scan() {
+				if scanner.Err() != nil {
+					return "", scanner.Err()
+				}
+				return "", io.EOF
+			}
+			return scanner.Text(), nil
+		}, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	term := terminal.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{&replInterruptReader{os.Stdin}, os.Stdout}, c.prompt())
+
+	completer := &replCompleter{model: c.newApp().Model(), resources: c.resources, term: term}
+	term.AutoCompleteCallback = completer.complete
+
+	read := func() (string, error) {
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return "", err
+		}
+		defer terminal.Restore(fd, state)
+
+		if w, h, err := terminal.GetSize(fd); err == nil && w > 0 {
+			term.SetSize(w, h)
+		}
+
+		term.SetPrompt(c.prompt())
+
+		return term.ReadLine()
+	}
+
+	return read, nil
+}
+
+func (c *replCmd) prompt() string {
+	if opts().Config != nil && opts().Config.Name != "" {
+		return fmt.Sprintf("nats[%s]> ", opts().Config.Name)
+	}
+
+	return "nats> "
+}
+
+// newApp creates a fresh application for every command so flag and argument values never leak between commands
+func (c *replCmd) newApp() *fisk.Application {
+	w := &cliErrorWriter{w: os.Stderr}
+
+	app := fisk.New("nats", "NATS Utility")
+	app.UsageWriter(os.Stdout)
+	app.ErrorWriter(w)
+	app.Terminate(func(status int) { panic(replTerminated(status)) })
+	app.HelpFlag.Short('h')
+	app.WithCheats().CheatCommand.Hidden()
+
+	configureCommands(app, "repl")
+
+	return app
+}
+
+func (c *replCmd) execute(args []string) {
+	parent := ctx
+
+	cmdCtx, cancel := signal.NotifyContext(parent, os.Interrupt)
+	ctx = cmdCtx
+
+	app := c.newApp()
+
+	defer func() {
+		cancel()
+		ctx = parent
+
+		c.mu.Lock()
+		c.streams = nil
+		c.consumers = nil
+		c.mu.Unlock()
+
+		// commands close the connection when they are done with it, the next
+		// command will then make a new one using the same context
+		if nc := opts().Conn; nc != nil && nc.IsClosed() {
+			mu.Lock()
+			opts().Conn, opts().Mgr, opts().JSc = nil, nil, nil
+			mu.Unlock()
+		}
+
+		if r := recover(); r != nil {
+			if _, ok := r.(replTerminated); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	_, err := app.Parse(args)
+	if err != nil {
+		app.Errorf("%s", err)
+	}
+}
+
+func (c *replCmd) close() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nc := opts().Conn
+	if nc == nil || nc.IsClosed() {
+		return nil
+	}
+
+	nc.Close()
+	opts().Conn, opts().Mgr, opts().JSc = nil, nil, nil
+
+	return nil
+}
+
+// resources lists the names that can be completed for an argument, other holds the preceding arguments
+func (c *replCmd) resources(arg string, other []string) []string {
+	switch arg {
+	case "stream":
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.streams == nil {
+			_, mgr, err := prepareHelper("", natsOpts()...)
+			if err != nil {
+				return nil
+			}
+
+			c.streams, _ = mgr.StreamNames(nil)
+		}
+
+		return c.streams
+
+	case "consumer":
+		if len(other) == 0 {
+			return nil
+		}
+		stream := other[len(other)-1]
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.consumers == nil {
+			c.consumers = map[string][]string{}
+		}
+
+		names, ok := c.consumers[stream]
+		if !ok {
+			_, mgr, err := prepareHelper("", natsOpts()...)
+			if err != nil {
+				return nil
+			}
+
+			names, _ = mgr.ConsumerNames(stream)
+			c.consumers[stream] = names
+		}
+
+		return names
+	}
+
+	return nil
+}
+
+// replInterruptReader turns Ctrl-C at the prompt into a line clear rather than ending input
+type replInterruptReader struct {
+	r io.Reader
+}
+
+func (r *replInterruptReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == 3 {
+			p[i] = 21
+		}
+	}
+
+	return n, err
+}
+
+type replCompleter struct {
+	model     *fisk.ApplicationModel
+	resources func(arg string, other []string) []string
+	term      *terminal.Terminal
+}
+
+// complete is a terminal completion callback that completes the word before the cursor on tab
+func (c *replCompleter) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	word, candidates := c.candidates(line[:pos])
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completion := candidates[0]
+	if len(candidates) == 1 {
+		completion += " "
+	} else {
+		for _, cand := range candidates[1:] {
+			for !strings.HasPrefix(cand, completion) {
+				completion = completion[:len(completion)-1]
+			}
+		}
+
+		if completion == word && c.term != nil {
+			fmt.Fprintln(c.term, strings.Join(candidates, "  "))
+		}
+	}
+
+	prefix := line[:pos-len(word)] + completion
+
+	return prefix + line[pos:], len(prefix), true
+}
+
+// candidates finds the word being completed at the end of line and all the values it could be completed to
+func (c *replCompleter) candidates(line string) (string, []string) {
+	words := strings.Fields(line)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		word = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	var cmd *fisk.CmdModel
+	commands := c.model.Commands
+	var positional []string
+	skipValue := false
+
+	for _, w := range words {
+		switch {
+		case skipValue:
+			skipValue = false
+
+		case strings.HasPrefix(w, "-"):
+			flag := c.flag(cmd, w)
+			skipValue = flag != nil && !flag.IsBoolFlag() && !strings.Contains(w, "=")
+
+		case len(positional) == 0 && c.command(commands, w) != nil:
+			cmd = c.command(commands, w)
+			commands = cmd.Commands
+
+		default:
+			positional = append(positional, w)
+		}
+	}
+
+	var candidates []string
+
+	switch {
+	case skipValue:
+
+	case strings.HasPrefix(word, "-"):
+		candidates = append(candidates, "--help")
+		if cmd != nil {
+			for _, f := range cmd.Flags {
+				if !f.Hidden {
+					candidates = append(candidates, "--"+f.Name)
+				}
+			}
+		}
+
+	case len(commands) > 0 && len(positional) == 0:
+		for _, sub := range commands {
+			if !sub.Hidden {
+				candidates = append(candidates, sub.Name)
+			}
+		}
+
+	case cmd != nil && len(positional) < len(cmd.Args):
+		candidates = append(candidates, c.resources(cmd.Args[len(positional)].Name, positional)...)
+	}
+
+	var matched []string
+	for _, cand := range candidates {
+		if strings.HasPrefix(cand, word) {
+			matched = append(matched, cand)
+		}
+	}
+	sort.Strings(matched)
+
+	return word, matched
+}
+
+func (c *replCompleter) command(commands []*fisk.CmdModel, name string) *fisk.CmdModel {
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+
+		for _, alias := range cmd.Aliases {
+			if alias == name {
+				return cmd
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *replCompleter) flag(cmd *fisk.CmdModel, word string) *fisk.FlagModel {
+	if cmd == nil {
+		return nil
+	}
+
+	name, _, _ := strings.Cut(strings.TrimLeft(word, "-"), "=")
+	for _, f := range cmd.Flags {
+		if f.Name == name || (len(name) == 1 && f.Short == rune(name[0]) && !strings.HasPrefix(word, "--")) {
+			return f
+		}
+	}
+
+	return nil
+}
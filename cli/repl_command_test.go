@@ -0,0 +1,88 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/choria-io/fisk"
+)
+
+func TestReplCompleter(t *testing.T) {
+	app := fisk.New("nats", "test")
+	str := app.Command("stream", "").Alias("s")
+	info := str.Command("info", "")
+	info.Arg("stream", "").String()
+	info.Flag("json", "").Bool()
+	str.Command("ls", "")
+	str.Command("hidden", "").Hidden()
+	cons := app.Command("consumer", "")
+	cinfo := cons.Command("info", "")
+	cinfo.Flag("server", "").String()
+	cinfo.Arg("stream", "").String()
+	cinfo.Arg("consumer", "").String()
+
+	c := &replCompleter{model: app.Model(), resources: func(arg string, other []string) []string {
+		switch arg {
+		case "stream":
+			return []string{"ORDERS", "OTHER", "X"}
+		case "consumer":
+			if len(other) == 1 && other[0] == "ORDERS" {
+				return []string{"NEW", "SHIPPED"}
+			}
+		}
+		return nil
+	}}
+
+	cases := []struct {
+		line  string
+		word  string
+		cands []string
+	}{
+		{"", "", []string{"consumer", "stream"}},
+		{"st", "st", []string{"stream"}},
+		{"stream ", "", []string{"info", "ls"}},
+		{"s i", "i", []string{"info"}},
+		{"stream info O", "O", []string{"ORDERS", "OTHER"}},
+		{"stream info --json O", "O", []string{"ORDERS", "OTHER"}},
+		{"stream info ORDERS ", "", nil},
+		{"stream info --", "--", []string{"--help", "--json"}},
+		{"consumer info ORDERS ", "", []string{"NEW", "SHIPPED"}},
+		{"consumer info --server ORDERS ", "", []string{"ORDERS", "OTHER", "X"}},
+		{"consumer info --server ", "", nil},
+	}
+
+	for _, tc := range cases {
+		word, cands := c.candidates(tc.line)
+		if word != tc.word || !reflect.DeepEqual(cands, tc.cands) {
+			t.Fatalf("%q: expected %q %v got %q %v", tc.line, tc.word, tc.cands, word, cands)
+		}
+	}
+
+	line, pos, ok := c.complete("stream info OR x", 14, '\t')
+	if !ok || line != "stream info ORDERS  x" || pos != 19 {
+		t.Fatalf("unexpected completion %q %d %v", line, pos, ok)
+	}
+
+	line, pos, ok = c.complete("stream info O", 13, '\t')
+	if !ok || line != "stream info O" || pos != 13 {
+		t.Fatalf("unexpected completion %q %d %v", line, pos, ok)
+	}
+
+	_, _, ok = c.complete("stream info O", 13, 'a')
+	if ok {
+		t.Fatalf("expected only tab to complete")
+	}
+}
@@ -37,6 +37,11 @@ type replyCmd struct {
 	sleep   time.Duration
 	limit   uint
 	hdrs    []string
+
+	proxy           string
+	cacheTTL        time.Duration
+	cacheMaxEntries int
+	cacheHeaders    []string
 }
 
 func configureReplyCommand(app commandHost) {
@@ -74,6 +79,13 @@ Available template functions are:
    ID               an unique ID
    Request          the request payload
    Random(min, max) random string at least min long, at most max
+
+With --proxy requests are forwarded to an upstream subject, setting --cache-ttl
+serves repeated requests from a cache keyed on the payload and any headers
+given using --cache-header. Replies have an X-Cache header set to HIT or MISS
+and statistics are shown on exit.
+
+   nats reply service.cached --proxy service.requests --cache-ttl 10s
 `
 
 	act := app.Command("reply", "Generic service reply utility").Action(c.reply)
@@ -87,6 +99,10 @@ Available template functions are:
 	act.Flag("sleep", "Inject a random sleep delay between replies up to this duration max").PlaceHolder("MAX").DurationVar(&c.sleep)
 	act.Flag("header", "Adds headers to the message using K:V format").Short('H').StringsVar(&c.hdrs)
 	act.Flag("count", "Quit after receiving this many messages").UintVar(&c.limit)
+	act.Flag("proxy", "Forward requests to an upstream subject and reply with its responses").PlaceHolder("SUBJECT").StringVar(&c.proxy)
+	act.Flag("cache-ttl", "Cache upstream responses for this long when proxying").PlaceHolder("DURATION").DurationVar(&c.cacheTTL)
+	act.Flag("cache-max-entries", "Maximum number of cached responses, the least recently used is evicted first").Default("1000").IntVar(&c.cacheMaxEntries)
+	act.Flag("cache-header", "Headers to include in the cache key in addition to the payload").PlaceHolder("HEADER").StringsVar(&c.cacheHeaders)
 }
 
 func init() {
@@ -94,12 +110,43 @@ func init() {
 }
 
 func (c *replyCmd) reply(_ *fisk.ParseContext) error {
+	// replying to many subjects in a queue group is the norm so only the subject syntax is checked
+	err := validateSubscribeSubject(c.subject, "")
+	if err != nil {
+		return err
+	}
+
+	if c.proxy != "" {
+		err = validatePublishSubject(c.proxy)
+		if err != nil {
+			return err
+		}
+	}
+
 	nc, err := newNatsConn("", natsOpts()...)
 	if err != nil {
 		return err
 	}
 
-	if c.body == "" && c.command == "" && !c.echo {
+	var proxy *replyProxy
+	switch {
+	case c.proxy != "":
+		if c.body != "" || c.command != "" || c.echo {
+			return fmt.Errorf("proxy can not be combined with a body, command or echo")
+		}
+		if c.cacheTTL < 0 {
+			return fmt.Errorf("cache-ttl can not be negative")
+		}
+		if c.cacheMaxEntries < 1 {
+			return fmt.Errorf("cache-max-entries must be at least 1")
+		}
+
+		proxy = newReplyProxy(nc, c.proxy, opts().Timeout, c.cacheTTL, c.cacheMaxEntries, c.cacheHeaders)
+
+	case c.cacheTTL > 0 || len(c.cacheHeaders) > 0:
+		return fmt.Errorf("caching requires --proxy")
+
+	case c.body == "" && c.command == "" && !c.echo:
 		log.Println("No body or command supplied, enabling echo mode")
 		c.echo = true
 	}
@@ -108,6 +155,20 @@ func (c *replyCmd) reply(_ *fisk.ParseContext) error {
 	defer close(ic)
 	i := 0
 	sub, _ := nc.QueueSubscribe(c.subject, c.queue, func(m *nats.Msg) {
+		if proxy != nil {
+			if opts().Trace {
+				log.Printf("[#%d] Proxying request received on subject %q", i, m.Subject)
+			}
+
+			proxy.handle(m)
+
+			i++
+			if c.limit != 0 && uint(i) == c.limit {
+				ic <- os.Interrupt
+			}
+			return
+		}
+
 		log.Printf("[#%d] Received on subject %q:", i, m.Subject)
 		for h, vals := range m.Header {
 			for _, val := range vals {
@@ -151,13 +212,13 @@ func (c *replyCmd) reply(_ *fisk.ParseContext) error {
 
 			parsedCmd, err := pubReplyBodyTemplate(rawCmd, string(m.Data), i)
 			if err != nil {
-				log.Printf("Could not parse command template: %s", err)
+				logErrorf("Could not parse command template: %s", err)
 			}
 			rawCmd = string(parsedCmd)
 
 			cmdParts, err := shellquote.Split(rawCmd)
 			if err != nil {
-				log.Printf("Could not parse command: %s", err)
+				logErrorf("Could not parse command: %s", err)
 				return
 			}
 
@@ -182,7 +243,7 @@ func (c *replyCmd) reply(_ *fisk.ParseContext) error {
 		default:
 			body, err := pubReplyBodyTemplate(c.body, string(m.Data), i)
 			if err != nil {
-				log.Printf("Could not parse body template: %s", err)
+				logErrorf("Could not parse body template: %s", err)
 			}
 
 			msg.Data = body
@@ -190,7 +251,7 @@ func (c *replyCmd) reply(_ *fisk.ParseContext) error {
 
 		err = m.RespondMsg(msg)
 		if err != nil {
-			log.Printf("Could not publish reply: %s", err)
+			logErrorf("Could not publish reply: %s", err)
 			return
 		}
 
@@ -211,11 +272,22 @@ func (c *replyCmd) reply(_ *fisk.ParseContext) error {
 		return err
 	}
 
-	log.Printf("Listening on %q in group %q", c.subject, c.queue)
+	if proxy != nil {
+		log.Printf("Proxying %q in group %q to %q", c.subject, c.queue, c.proxy)
+	} else {
+		log.Printf("Listening on %q in group %q", c.subject, c.queue)
+	}
 
 	signal.Notify(ic, os.Interrupt)
 	<-ic
 
+	if proxy != nil {
+		if sub != nil {
+			sub.Unsubscribe()
+		}
+		proxy.report()
+	}
+
 	log.Printf("\nDraining...")
 	nc.Drain()
 	log.Fatalf("Exiting")
@@ -0,0 +1,257 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/nats-io/nats.go"
+)
+
+const replyProxyCacheHeader = "X-Cache"
+
+// replyProxyCache is a size bounded cache of responses evicting the least recently used entry when full
+type replyProxyCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type replyProxyCacheEntry struct {
+	key     string
+	data    []byte
+	header  nats.Header
+	expires time.Time
+}
+
+// replyProxy forwards requests to an upstream subject, caching the responses when a cache TTL is set
+type replyProxy struct {
+	nc       *nats.Conn
+	upstream string
+	timeout  time.Duration
+	headers  []string
+	cache    *replyProxyCache
+
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+	hits      int
+	misses    int
+	errors    int
+	latencies *hdrhistogram.Histogram
+}
+
+func newReplyProxyCache(ttl time.Duration, maxEntries int) *replyProxyCache {
+	return &replyProxyCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// get finds an unexpired response, expired entries are removed
+func (c *replyProxyCache) get(key string, now time.Time) (*replyProxyCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := e.Value.(*replyProxyCacheEntry)
+	if !now.Before(entry.expires) {
+		c.lru.Remove(e)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(e)
+
+	return entry, true
+}
+
+func (c *replyProxyCache) put(key string, data []byte, header nats.Header, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &replyProxyCacheEntry{key: key, data: data, header: header, expires: now.Add(c.ttl)}
+
+	if e, ok := c.entries[key]; ok {
+		e.Value = entry
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*replyProxyCacheEntry).key)
+	}
+}
+
+func (c *replyProxyCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func newReplyProxy(nc *nats.Conn, upstream string, timeout time.Duration, ttl time.Duration, maxEntries int, headers []string) *replyProxy {
+	highest := time.Minute
+	if timeout > highest {
+		highest = timeout
+	}
+
+	p := &replyProxy{
+		nc:        nc,
+		upstream:  upstream,
+		timeout:   timeout,
+		headers:   headers,
+		latencies: hdrhistogram.New(1, int64(highest), 3),
+	}
+
+	if ttl > 0 {
+		p.cache = newReplyProxyCache(ttl, maxEntries)
+	}
+
+	return p
+}
+
+// cacheKey hashes the request payload and the values of the headers selected to be part of the key
+func (p *replyProxy) cacheKey(m *nats.Msg) string {
+	h := sha256.New()
+	for _, hdr := range p.headers {
+		h.Write([]byte(hdr))
+		h.Write([]byte{0})
+		for _, v := range m.Header.Values(hdr) {
+			h.Write([]byte(v))
+			h.Write([]byte{0})
+		}
+	}
+	h.Write(m.Data)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// handle answers a request from the cache or by forwarding it upstream, requests are handled concurrently so slow
+// upstream responses do not delay cache hits
+func (p *replyProxy) handle(m *nats.Msg) {
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+
+		var key string
+		if p.cache != nil {
+			key = p.cacheKey(m)
+
+			entry, ok := p.cache.get(key, time.Now())
+			if ok {
+				p.respond(m, entry.data, entry.header, "HIT")
+				p.record(true, 0, nil)
+				return
+			}
+		}
+
+		req := nats.NewMsg(p.upstream)
+		req.Data = m.Data
+		for h, vals := range m.Header {
+			for _, v := range vals {
+				req.Header.Add(h, v)
+			}
+		}
+
+		start := time.Now()
+		resp, err := p.nc.RequestMsg(req, p.timeout)
+		latency := time.Since(start)
+		if err != nil {
+			log.Printf("Upstream request to %s failed: %v", p.upstream, err)
+			p.record(false, 0, err)
+			return
+		}
+
+		if p.cache != nil {
+			p.cache.put(key, resp.Data, resp.Header, time.Now())
+		}
+
+		p.respond(m, resp.Data, resp.Header, "MISS")
+		p.record(false, latency, nil)
+	}()
+}
+
+func (p *replyProxy) respond(m *nats.Msg, data []byte, header nats.Header, status string) {
+	msg := nats.NewMsg(m.Reply)
+	msg.Data = data
+	for h, vals := range header {
+		for _, v := range vals {
+			msg.Header.Add(h, v)
+		}
+	}
+	msg.Header.Set(replyProxyCacheHeader, status)
+
+	err := m.RespondMsg(msg)
+	if err != nil {
+		logErrorf("Could not publish reply: %s", err)
+	}
+}
+
+func (p *replyProxy) record(hit bool, latency time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case err != nil:
+		p.errors++
+	case hit:
+		p.hits++
+	default:
+		p.misses++
+		p.latencies.RecordValue(int64(latency))
+	}
+}
+
+// report waits for outstanding requests and logs the cache and upstream statistics
+func (p *replyProxy) report() {
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := p.hits + p.misses
+	ratio := 0.0
+	if total > 0 {
+		ratio = float64(p.hits) / float64(total) * 100
+	}
+
+	log.Printf("Proxied %s requests to %s: %s hits, %s misses (%.1f%% hit ratio), %s errors", f(total+p.errors), p.upstream, f(p.hits), f(p.misses), ratio, f(p.errors))
+	if p.cache != nil {
+		log.Printf("Cache: %s / %s entries, TTL %s", f(p.cache.len()), f(p.cache.maxEntries), f(p.cache.ttl))
+	}
+
+	if p.latencies.TotalCount() > 0 {
+		q := func(pct float64) string { return f(time.Duration(p.latencies.ValueAtQuantile(pct))) }
+		log.Printf("Upstream latency: min %s, p50 %s, p90 %s, p99 %s, max %s", f(time.Duration(p.latencies.Min())), q(50), q(90), q(99), f(time.Duration(p.latencies.Max())))
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+func TestReplyProxyCache(t *testing.T) {
+	now := time.Now()
+	cache := newReplyProxyCache(time.Second, 2)
+
+	cache.put("a", []byte("A"), nil, now)
+	cache.put("b", []byte("B"), nil, now)
+
+	// a becomes the most recently used so b is evicted
+	if _, ok := cache.get("a", now); !ok {
+		t.Fatalf("expected a to be cached")
+	}
+	cache.put("c", []byte("C"), nil, now)
+
+	if _, ok := cache.get("b", now); ok {
+		t.Fatalf("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := cache.get(k, now); !ok {
+			t.Fatalf("expected %s to be cached", k)
+		}
+	}
+	if cache.len() != 2 {
+		t.Fatalf("expected 2 entries got %d", cache.len())
+	}
+
+	cache.put("a", []byte("AA"), nil, now.Add(500*time.Millisecond))
+	entry, ok := cache.get("a", now.Add(1200*time.Millisecond))
+	if !ok || string(entry.data) != "AA" {
+		t.Fatalf("expected the replaced entry to be cached: %v", entry)
+	}
+
+	if _, ok := cache.get("c", now.Add(time.Second)); ok {
+		t.Fatalf("expected c to expire")
+	}
+	if cache.len() != 1 {
+		t.Fatalf("expected expired entries to be removed got %d", cache.len())
+	}
+}
+
+func TestReplyProxyCacheKey(t *testing.T) {
+	p := &replyProxy{headers: []string{"Tenant"}}
+
+	msg := func(body string, tenant string, other string) *nats.Msg {
+		m := nats.NewMsg("x")
+		m.Data = []byte(body)
+		if tenant != "" {
+			m.Header.Set("Tenant", tenant)
+		}
+		m.Header.Set("Other", other)
+		return m
+	}
+
+	if p.cacheKey(msg("a", "t1", "1")) != p.cacheKey(msg("a", "t1", "2")) {
+		t.Fatalf("expected headers not selected to be ignored")
+	}
+	if p.cacheKey(msg("a", "t1", "1")) == p.cacheKey(msg("a", "t2", "1")) {
+		t.Fatalf("expected selected headers to be part of the key")
+	}
+	if p.cacheKey(msg("a", "t1", "1")) == p.cacheKey(msg("b", "t1", "1")) {
+		t.Fatalf("expected the payload to be part of the key")
+	}
+	if p.cacheKey(msg("t1", "", "1")) == p.cacheKey(msg("", "t1", "1")) {
+		t.Fatalf("expected header values and payload to be separated")
+	}
+}
+
+func TestReplyProxy(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{Port: -1})
+	checkErr(t, err, "could not start server: %v", err)
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatalf("nats server did not start")
+	}
+	defer srv.Shutdown()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	checkErr(t, err, "connect failed: %v", err)
+	defer nc.Close()
+
+	var upstream atomic.Int32
+	_, err = nc.Subscribe("upstream", func(m *nats.Msg) {
+		n := upstream.Add(1)
+		resp := nats.NewMsg(m.Reply)
+		resp.Data = []byte(fmt.Sprintf("%s %d", m.Data, n))
+		resp.Header.Set("Upstream", "yes")
+		m.RespondMsg(resp)
+	})
+	checkErr(t, err, "subscribe failed: %v", err)
+
+	proxy := newReplyProxy(nc, "upstream", time.Second, time.Minute, 10, nil)
+	_, err = nc.Subscribe("proxy", proxy.handle)
+	checkErr(t, err, "subscribe failed: %v", err)
+
+	_, err = nc.Subscribe("proxy.nocache", newReplyProxy(nc, "upstream", time.Second, 0, 10, nil).handle)
+	checkErr(t, err, "subscribe failed: %v", err)
+
+	for _, tc := range []struct {
+		subject string
+		body    string
+		reply   string
+		cache   string
+	}{
+		{"proxy", "a", "a 1", "MISS"},
+		{"proxy", "a", "a 1", "HIT"},
+		{"proxy", "b", "b 2", "MISS"},
+		{"proxy.nocache", "a", "a 3", "MISS"},
+		{"proxy.nocache", "a", "a 4", "MISS"},
+	} {
+		resp, err := nc.Request(tc.subject, []byte(tc.body), time.Second)
+		checkErr(t, err, "request failed: %v", err)
+
+		if string(resp.Data) != tc.reply || resp.Header.Get("X-Cache") != tc.cache || resp.Header.Get("Upstream") != "yes" {
+			t.Fatalf("expected %q with %s from %s got %q with %v", tc.reply, tc.cache, tc.subject, resp.Data, resp.Header)
+		}
+	}
+
+	proxy.wg.Wait()
+	if proxy.hits != 1 || proxy.misses != 2 || proxy.errors != 0 || proxy.latencies.TotalCount() != 2 {
+		t.Fatalf("unexpected statistics hits=%d misses=%d errors=%d", proxy.hits, proxy.misses, proxy.errors)
+	}
+}
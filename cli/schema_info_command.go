@@ -18,12 +18,12 @@ import (
 
 	"github.com/choria-io/fisk"
 	"github.com/ghodss/yaml"
-	"github.com/nats-io/jsm.go/api"
 )
 
 type schemaInfoCmd struct {
 	schema string
 	yaml   bool
+	goType bool
 }
 
 func configureSchemaInfoCommand(schema *fisk.CmdClause) {
@@ -31,10 +31,15 @@ func configureSchemaInfoCommand(schema *fisk.CmdClause) {
 	info := schema.Command("info", "Display schema contents").Alias("show").Alias("view").Action(c.info)
 	info.Arg("schema", "Schema ID to show").Required().StringVar(&c.schema)
 	info.Flag("yaml", "Produce YAML format output").UnNegatableBoolVar(&c.yaml)
+	info.Flag("go", "Show the Go structure used to represent the schema").UnNegatableBoolVar(&c.goType)
 }
 
 func (c *schemaInfoCmd) info(_ *fisk.ParseContext) error {
-	schema, err := api.Schema(c.schema)
+	if c.goType {
+		return c.showGoType()
+	}
+
+	schema, err := registeredSchema(c.schema)
 	if err != nil {
 		return fmt.Errorf("could not load schema %q: %s", c.schema, err)
 	}
@@ -50,3 +55,19 @@ func (c *schemaInfoCmd) info(_ *fisk.ParseContext) error {
 
 	return nil
 }
+
+func (c *schemaInfoCmd) showGoType() error {
+	name, fields, err := registeredSchemaStruct(c.schema)
+	if err != nil {
+		return fmt.Errorf("could not load schema %q: %s", c.schema, err)
+	}
+
+	table := newTableWriter("Go structure %s", name)
+	table.AddHeaders("Field", "JSON", "Type")
+	for _, field := range fields {
+		table.AddRow(field.Name, field.JSONName, field.Type)
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
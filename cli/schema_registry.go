@@ -0,0 +1,153 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+// the schema registry is the single place where the CLI resolves NATS schema
+// types, it's shared by the schema and events commands
+
+const unknownSchemaType = "io.nats.unknown_message"
+
+// schemaField describes a single field of the Go structure backing a schema
+type schemaField struct {
+	Name     string `json:"name"`
+	JSONName string `json:"json_name"`
+	Type     string `json:"type"`
+}
+
+// registeredSchemaTypes lists known schema types matching the regular expression pattern and containing the substring filter
+func registeredSchemaTypes(pattern string, filter string) ([]string, error) {
+	found, err := api.SchemaSearch(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	if filter == "" {
+		return found, nil
+	}
+
+	var matched []string
+	for _, s := range found {
+		if strings.Contains(s, filter) {
+			matched = append(matched, s)
+		}
+	}
+
+	return matched, nil
+}
+
+// isRegisteredSchemaType determines if schemaType is known to the registry
+func isRegisteredSchemaType(schemaType string) bool {
+	_, ok := api.NewMessage(schemaType)
+	return ok
+}
+
+// registeredSchema retrieves the JSON schema document for schemaType
+func registeredSchema(schemaType string) ([]byte, error) {
+	if !isRegisteredSchemaType(schemaType) {
+		return nil, fmt.Errorf("unknown schema type %q", schemaType)
+	}
+
+	return api.Schema(schemaType)
+}
+
+// registeredSchemaStruct describes the Go structure used to represent schemaType
+func registeredSchemaStruct(schemaType string) (string, []schemaField, error) {
+	msg, ok := api.NewMessage(schemaType)
+	if !ok {
+		return "", nil, fmt.Errorf("unknown schema type %q", schemaType)
+	}
+
+	t := reflect.TypeOf(msg)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return t.String(), nil, nil
+	}
+
+	return t.String(), schemaStructFields(t), nil
+}
+
+func schemaStructFields(t reflect.Type) []schemaField {
+	var fields []schemaField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		jname, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jname == "-" {
+			continue
+		}
+
+		// embedded structures are flattened by encoding/json so we do the same
+		if field.Anonymous && jname == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				fields = append(fields, schemaStructFields(ft)...)
+				continue
+			}
+		}
+
+		if jname == "" {
+			jname = field.Name
+		}
+
+		fields = append(fields, schemaField{Name: field.Name, JSONName: jname, Type: field.Type.String()})
+	}
+
+	return fields
+}
+
+// validateRegisteredSchema validates data against schemaType returning errors prefixed with the JSON path they relate to
+func validateRegisteredSchema(data any, schemaType string) (bool, []string) {
+	if !isRegisteredSchemaType(schemaType) {
+		return false, []string{fmt.Sprintf("unknown schema type %s", schemaType)}
+	}
+
+	return new(SchemaValidator).ValidateStruct(data, schemaType)
+}
+
+// parseRegisteredEvent parses data as a typed NATS message, returning the event only when the type is known and renderable
+func parseRegisteredEvent(data []byte) (string, api.Event, error) {
+	kind, msg, err := api.ParseMessage(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("parsing failed: %s", err)
+	}
+
+	if kind == unknownSchemaType || !isRegisteredSchemaType(kind) {
+		return kind, nil, nil
+	}
+
+	event, ok := msg.(api.Event)
+	if !ok {
+		return kind, nil, fmt.Errorf("event %q does not implement the Event interface", kind)
+	}
+
+	return kind, event, nil
+}
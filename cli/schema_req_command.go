@@ -63,6 +63,11 @@ func (c *schemaReqCmd) requestAction(_ *fisk.ParseContext) error {
 		fmt.Println()
 	}
 
+	err = apiErrorFromResponse(res.Data)
+	if err != nil {
+		return err
+	}
+
 	schemaType, msg, err := api.ParseMessage(res.Data)
 	if err != nil {
 		return err
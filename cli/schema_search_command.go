@@ -19,23 +19,24 @@ import (
 	"strings"
 
 	"github.com/choria-io/fisk"
-	"github.com/nats-io/jsm.go/api"
 )
 
 type schemaSearchCmd struct {
-	filter string
-	json   bool
+	pattern string
+	filter  string
+	json    bool
 }
 
 func configureSchemaSearchCommand(schema *fisk.CmdClause) {
 	c := &schemaSearchCmd{}
 	search := schema.Command("search", "Search schemas using a pattern").Alias("find").Alias("list").Alias("ls").Action(c.search)
-	search.Arg("pattern", "Regular expression to search for").Default(".").StringVar(&c.filter)
+	search.Arg("pattern", "Regular expression to search for").Default(".").StringVar(&c.pattern)
+	search.Flag("filter", "Only show schemas containing this substring").StringVar(&c.filter)
 	search.Flag("json", "Produce JSON format output").UnNegatableBoolVar(&c.json)
 }
 
 func (c *schemaSearchCmd) search(_ *fisk.ParseContext) error {
-	found, err := api.SchemaSearch(c.filter)
+	found, err := registeredSchemaTypes(c.pattern, c.filter)
 	if err != nil {
 		return fmt.Errorf("search failed: %s", err)
 	}
@@ -46,7 +47,7 @@ func (c *schemaSearchCmd) search(_ *fisk.ParseContext) error {
 	}
 
 	if len(found) == 0 {
-		fmt.Printf("No schemas matched %q\n", c.filter)
+		fmt.Printf("No schemas matched %q\n", c.pattern)
 		return nil
 	}
 
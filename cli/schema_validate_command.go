@@ -64,7 +64,7 @@ func (c *schemaValidateCmd) validate(_ *fisk.ParseContext) error {
 		return fmt.Errorf("could not parse JSON data in %q: %s", c.file, err)
 	}
 
-	ok, errs := new(SchemaValidator).ValidateStruct(data, c.schema)
+	ok, errs := validateRegisteredSchema(data, c.schema)
 	if c.json {
 		if errs == nil {
 			errs = []string{}
@@ -19,6 +19,7 @@ type srvAccountCommand struct {
 	account string
 	server  string
 	force   bool
+	watch   time.Duration
 }
 
 func configureServerAccountCommand(srv *fisk.CmdClause) {
@@ -27,10 +28,16 @@ func configureServerAccountCommand(srv *fisk.CmdClause) {
 	account := srv.Command("account", "Interact with accounts").Alias("acct")
 	account.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 
+	account.Command("ls", "Lists all accounts with their connections and JetStream usage over all servers").Alias("list").Action(c.listAction)
+
 	info := account.Command("info", "Shows information for an account").Alias("i").Action(c.infoAction)
 	info.Arg("account", "The name of the account to view").Required().StringVar(&c.account)
 	info.Flag("host", "Request information from a specific server").StringVar(&c.server)
 
+	stats := account.Command("stats", "Shows connection, subscription and JetStream usage of an account over all servers").Action(c.statsAction)
+	stats.Arg("account", "The name of the account to view").Required().StringVar(&c.account)
+	stats.Flag("watch", "Query the statistics again at this interval, showing changes since the previous query").PlaceHolder("INTERVAL").DurationVar(&c.watch)
+
 	purge := account.Command("purge", "Purge assets from JetStream clusters").Action(c.purgeAccount)
 	purge.Arg("account", "The name of the account to purge").PlaceHolder("NAME").Required().StringVar(&c.account)
 	purge.Flag("force", "Perform the operation without prompting").Short('f').UnNegatableBoolVar(&c.force)
@@ -135,7 +142,7 @@ func (c *srvAccountCommand) infoAction(_ *fisk.ParseContext) error {
 	cols.AddRow("Complete", nfo.Complete)
 	cols.AddRow("Expired", nfo.Expired)
 	cols.AddRow("System Account", nfo.IsSystem)
-	cols.AddRowf("Updated", "%v (%s ago)", f(nfo.LastUpdate), f(time.Since(nfo.LastUpdate)))
+	cols.AddRowf("Updated", "%v (%s)", f(nfo.LastUpdate), fAgo(time.Since(nfo.LastUpdate)))
 	cols.AddRow("JetStream", nfo.JetStream)
 	cols.AddRowIfNotEmpty("Issuer", nfo.IssuerKey)
 	cols.AddRowIfNotEmpty("Tag", nfo.NameTag)
@@ -177,7 +184,7 @@ func (c *srvAccountCommand) infoAction(_ *fisk.ParseContext) error {
 		cols.AddSectionTitle("Revoked Users")
 
 		for r, t := range nfo.RevokedUser {
-			cols.AddRowf(r, "%s (%s ago)", t, f(time.Since(t)))
+			cols.AddRowf(r, "%s (%s)", f(t), fAgo(time.Since(t)))
 		}
 	}
 
@@ -0,0 +1,184 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// srvAccountListEntry is the usage of an account summed over all servers
+type srvAccountListEntry struct {
+	Account     string `json:"account"`
+	Name        string `json:"name,omitempty"`
+	Connections int    `json:"connections"`
+	Leafnodes   int    `json:"leafnodes"`
+	JetStream   bool   `json:"jetstream"`
+	Streams     int    `json:"streams"`
+	Consumers   int    `json:"consumers"`
+	Messages    uint64 `json:"messages"`
+	Bytes       uint64 `json:"bytes"`
+
+	streams accountStreamUsage
+}
+
+func (c *srvAccountCommand) listAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := c.listAccounts(nc)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		iu.PrintJSON(accounts)
+		return nil
+	}
+
+	if len(accounts) == 0 {
+		fmt.Println("No accounts found")
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("%s Accounts", f(len(accounts))))
+	table.AddHeaders("Account", "Connections", "Leafnodes", "JetStream", "Streams", "Consumers", "Messages", "Bytes")
+
+	var conns, leafs, streams, consumers int
+	var msgs, bytes uint64
+	for _, acct := range accounts {
+		name := acct.Account
+		if acct.Name != "" && acct.Name != acct.Account {
+			name = fmt.Sprintf("%s (%s)", acct.Name, acct.Account)
+		}
+
+		if acct.JetStream {
+			table.AddRow(name, f(acct.Connections), f(acct.Leafnodes), "yes", f(acct.Streams), f(acct.Consumers), f(acct.Messages), fiBytes(acct.Bytes))
+		} else {
+			table.AddRow(name, f(acct.Connections), f(acct.Leafnodes), "no", "", "", "", "")
+		}
+
+		conns += acct.Connections
+		leafs += acct.Leafnodes
+		streams += acct.Streams
+		consumers += acct.Consumers
+		msgs += acct.Messages
+		bytes += acct.Bytes
+	}
+	table.AddFooter("Total", f(conns), f(leafs), "", f(streams), f(consumers), f(msgs), fiBytes(bytes))
+
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+// listAccounts finds every account known to any server along with its connections and JetStream usage, sorted by
+// connections with the busiest first
+func (c *srvAccountCommand) listAccounts(nc *nats.Conn) ([]*srvAccountListEntry, error) {
+	accounts := map[string]*srvAccountListEntry{}
+	account := func(id string) *srvAccountListEntry {
+		acct, ok := accounts[id]
+		if !ok {
+			acct = &srvAccountListEntry{Account: id, streams: accountStreamUsage{}}
+			accounts[id] = acct
+		}
+		return acct
+	}
+
+	res, err := doReq(server.AccountStatzOptions{IncludeUnused: true}, "$SYS.REQ.ACCOUNT.PING.STATZ", 0, nc)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no responses received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	for _, r := range res {
+		var resp struct {
+			Data  *server.AccountStatz `json:"data"`
+			Error *server.ApiError     `json:"error"`
+		}
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account statistics received: %w", err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("account statistics request failed: %s", resp.Error.Description)
+		}
+		if resp.Data == nil {
+			continue
+		}
+
+		for _, stat := range resp.Data.Accounts {
+			acct := account(stat.Account)
+			if stat.Name != "" {
+				acct.Name = stat.Name
+			}
+			acct.Connections += stat.Conns
+			acct.Leafnodes += stat.LeafNodes
+		}
+	}
+
+	res, err = doReq(server.JSzOptions{Accounts: true, Streams: true, Limit: 10000}, "$SYS.REQ.SERVER.PING.JSZ", 0, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		var resp struct {
+			Data  *server.JSInfo   `json:"data"`
+			Error *server.ApiError `json:"error"`
+		}
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JetStream statistics received: %w", err)
+		}
+		// servers without JetStream respond with errors
+		if resp.Error != nil || resp.Data == nil {
+			continue
+		}
+
+		for _, detail := range resp.Data.AccountDetails {
+			acct := account(detail.Id)
+			if detail.Name != detail.Id {
+				acct.Name = detail.Name
+			}
+			acct.JetStream = true
+			acct.streams.add(detail.Streams)
+		}
+	}
+
+	var list []*srvAccountListEntry
+	for _, acct := range accounts {
+		acct.Streams, acct.Consumers, acct.Messages, acct.Bytes = acct.streams.totals()
+		list = append(list, acct)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Connections == list[j].Connections {
+			return list[i].Account < list[j].Account
+		}
+		return list[i].Connections > list[j].Connections
+	})
+
+	return list, nil
+}
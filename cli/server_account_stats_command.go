@@ -0,0 +1,318 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jwt/v2"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// srvAccountStats is the usage of an account summed over all servers, JetStream usage counts every stream once
+type srvAccountStats struct {
+	Account          string              `json:"account"`
+	Servers          int                 `json:"servers"`
+	Connections      int                 `json:"connections"`
+	Leafnodes        int                 `json:"leafnodes"`
+	TotalConnections int                 `json:"total_connections"`
+	Subscriptions    uint64              `json:"subscriptions"`
+	SlowConsumers    int64               `json:"slow_consumers"`
+	Sent             server.DataStats    `json:"sent"`
+	Received         server.DataStats    `json:"received"`
+	JetStream        bool                `json:"jetstream"`
+	Streams          int                 `json:"streams"`
+	Consumers        int                 `json:"consumers"`
+	Messages         uint64              `json:"messages"`
+	Bytes            uint64              `json:"bytes"`
+	ReservedMemory   uint64              `json:"reserved_memory"`
+	ReservedStore    uint64              `json:"reserved_storage"`
+	APIRequests      uint64              `json:"api_requests"`
+	APIErrors        uint64              `json:"api_errors"`
+	Limits           *jwt.OperatorLimits `json:"limits,omitempty"`
+}
+
+func (c *srvAccountCommand) statsAction(_ *fisk.ParseContext) error {
+	if c.watch > 0 && c.json {
+		return fmt.Errorf("watch and json can not be used together")
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.accountStats(nc)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		iu.PrintJSON(stats)
+		return nil
+	}
+
+	if c.watch <= 0 {
+		c.renderAccountStats(stats, nil)
+		return nil
+	}
+
+	ticker := time.NewTicker(c.watch)
+	defer ticker.Stop()
+
+	var previous *srvAccountStats
+	for {
+		clearScreen()
+		c.renderAccountStats(stats, previous)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+
+		previous = stats
+		stats, err = c.accountStats(nc)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// accountStats gathers connection statistics from every server the account is used on along with its JetStream usage and limits
+func (c *srvAccountCommand) accountStats(nc *nats.Conn) (*srvAccountStats, error) {
+	stats := &srvAccountStats{Account: c.account}
+
+	res, err := doReq(nil, fmt.Sprintf("$SYS.REQ.ACCOUNT.%s.STATZ", c.account), 0, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range res {
+		var resp struct {
+			Data  *server.AccountStatz `json:"data"`
+			Error *server.ApiError     `json:"error"`
+		}
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account statistics received: %w", err)
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("account statistics request failed: %s", resp.Error.Description)
+		}
+		if resp.Data == nil {
+			continue
+		}
+
+		for _, acct := range resp.Data.Accounts {
+			stats.Servers++
+			stats.Connections += acct.Conns
+			stats.Leafnodes += acct.LeafNodes
+			stats.TotalConnections += acct.TotalConns
+			stats.Subscriptions += uint64(acct.NumSubs)
+			stats.SlowConsumers += acct.SlowConsumers
+			stats.Sent.Msgs += acct.Sent.Msgs
+			stats.Sent.Bytes += acct.Sent.Bytes
+			stats.Received.Msgs += acct.Received.Msgs
+			stats.Received.Bytes += acct.Received.Bytes
+		}
+	}
+
+	err = c.accountJetStreamStats(nc, stats)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err = doReq(nil, fmt.Sprintf("$SYS.REQ.ACCOUNT.%s.INFO", c.account), 1, nc)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no responses received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var info struct {
+		Data  *server.AccountInfo `json:"data"`
+		Error *server.ApiError    `json:"error"`
+	}
+	err = json.Unmarshal(res[0], &info)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account information received: %w", err)
+	}
+	if info.Error != nil {
+		return nil, fmt.Errorf("account information request failed: %s", info.Error.Description)
+	}
+	if info.Data != nil {
+		stats.JetStream = info.Data.JetStream
+		if info.Data.Claim != nil {
+			stats.Limits = &info.Data.Claim.Limits
+		}
+	}
+
+	return stats, nil
+}
+
+// accountJetStreamStats adds the JetStream usage reported by every JetStream server, counting each stream once
+func (c *srvAccountCommand) accountJetStreamStats(nc *nats.Conn, stats *srvAccountStats) error {
+	res, err := doReq(server.JSzOptions{Streams: true}, fmt.Sprintf("$SYS.REQ.ACCOUNT.%s.JSZ", c.account), 0, nc)
+	if err != nil {
+		return err
+	}
+
+	streams := accountStreamUsage{}
+	for _, r := range res {
+		var resp struct {
+			Data  *server.AccountDetail `json:"data"`
+			Error *server.ApiError      `json:"error"`
+		}
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return fmt.Errorf("invalid JetStream statistics received: %w", err)
+		}
+		// servers without JetStream, or where the account does not use it, respond with errors
+		if resp.Error != nil || resp.Data == nil {
+			continue
+		}
+
+		stats.ReservedMemory = max(stats.ReservedMemory, resp.Data.ReservedMemory)
+		stats.ReservedStore = max(stats.ReservedStore, resp.Data.ReservedStore)
+		stats.APIRequests = max(stats.APIRequests, resp.Data.API.Total)
+		stats.APIErrors = max(stats.APIErrors, resp.Data.API.Errors)
+
+		streams.add(resp.Data.Streams)
+	}
+
+	stats.Streams, stats.Consumers, stats.Messages, stats.Bytes = streams.totals()
+
+	return nil
+}
+
+// accountStreamUsage is the state of every stream in an account, replicated streams are reported by every server
+// holding a replica so only the most recent state seen for each is kept
+type accountStreamUsage map[string]server.StreamState
+
+func (u accountStreamUsage) add(streams []server.StreamDetail) {
+	for _, s := range streams {
+		known, ok := u[s.Name]
+		if !ok || s.State.LastSeq > known.LastSeq || (s.State.LastSeq == known.LastSeq && s.State.Consumers > known.Consumers) {
+			u[s.Name] = s.State
+		}
+	}
+}
+
+func (u accountStreamUsage) totals() (streams int, consumers int, messages uint64, bytes uint64) {
+	for _, state := range u {
+		streams++
+		consumers += state.Consumers
+		messages += state.Msgs
+		bytes += state.Bytes
+	}
+
+	return streams, consumers, messages, bytes
+}
+
+func (c *srvAccountCommand) renderAccountStats(stats *srvAccountStats, previous *srvAccountStats) {
+	// delta shows the change since the previous query when watching
+	delta := func(now, before int64) string {
+		if previous == nil {
+			return f(now)
+		}
+
+		switch {
+		case now > before:
+			return fmt.Sprintf("%s (+%s)", f(now), f(now-before))
+		case now < before:
+			return fmt.Sprintf("%s (-%s)", f(now), f(before-now))
+		default:
+			return f(now)
+		}
+	}
+
+	prev := previous
+	if prev == nil {
+		prev = &srvAccountStats{}
+	}
+
+	title := fmt.Sprintf("Account statistics for account %s", stats.Account)
+	if c.watch > 0 {
+		title = fmt.Sprintf("%s at %s", title, time.Now().Format(time.TimeOnly))
+	}
+
+	cols := newColumns(title)
+	defer cols.Frender(os.Stdout)
+
+	cols.AddSectionTitle("Connections")
+	cols.AddRow("Servers", stats.Servers)
+	cols.AddRow("Connections", delta(int64(stats.Connections), int64(prev.Connections)))
+	cols.AddRow("Leafnodes", delta(int64(stats.Leafnodes), int64(prev.Leafnodes)))
+	cols.AddRow("Total Connections", delta(int64(stats.TotalConnections), int64(prev.TotalConnections)))
+	cols.AddRow("Subscriptions", delta(int64(stats.Subscriptions), int64(prev.Subscriptions)))
+	cols.AddRow("Slow Consumers", delta(int64(stats.SlowConsumers), int64(prev.SlowConsumers)))
+	cols.AddRow("Messages Sent", delta(int64(stats.Sent.Msgs), int64(prev.Sent.Msgs)))
+	cols.AddRowf("Bytes Sent", "%s", fiBytes(uint64(stats.Sent.Bytes)))
+	cols.AddRow("Messages Received", delta(int64(stats.Received.Msgs), int64(prev.Received.Msgs)))
+	cols.AddRowf("Bytes Received", "%s", fiBytes(uint64(stats.Received.Bytes)))
+
+	cols.AddSectionTitle("JetStream")
+	if !stats.JetStream {
+		cols.AddRow("Enabled", false)
+	} else {
+		cols.AddRow("Streams", delta(int64(stats.Streams), int64(prev.Streams)))
+		cols.AddRow("Consumers", delta(int64(stats.Consumers), int64(prev.Consumers)))
+		cols.AddRow("Messages", delta(int64(stats.Messages), int64(prev.Messages)))
+		cols.AddRow("Bytes", fiBytes(stats.Bytes))
+		cols.AddRowIf("Reserved Memory", fiBytes(stats.ReservedMemory), stats.ReservedMemory > 0)
+		cols.AddRowIf("Reserved Storage", fiBytes(stats.ReservedStore), stats.ReservedStore > 0)
+		cols.AddRow("API Requests", delta(int64(stats.APIRequests), int64(prev.APIRequests)))
+		cols.AddRow("API Errors", delta(int64(stats.APIErrors), int64(prev.APIErrors)))
+	}
+
+	cols.AddSectionTitle("Limits")
+	if stats.Limits == nil {
+		cols.AddRow("Limits", "none, the account is not configured using a JWT")
+		return
+	}
+
+	limit := func(v int64) string {
+		if v < 0 {
+			return "unlimited"
+		}
+		return f(v)
+	}
+	byteLimit := func(v int64) string {
+		if v < 0 {
+			return "unlimited"
+		}
+		return fiBytes(uint64(v))
+	}
+
+	l := stats.Limits
+	cols.AddRow("Connections", limit(l.Conn))
+	cols.AddRow("Leafnodes", limit(l.LeafNodeConn))
+	cols.AddRow("Subscriptions", limit(l.Subs))
+	cols.AddRow("Data", byteLimit(l.Data))
+	cols.AddRow("Payload", byteLimit(l.Payload))
+	if stats.JetStream {
+		cols.AddRow("Streams", limit(l.Streams))
+		cols.AddRow("Consumers", limit(l.Consumer))
+		cols.AddRow("Memory Storage", byteLimit(l.MemoryStorage))
+		cols.AddRow("File Storage", byteLimit(l.DiskStorage))
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/nats.go"
+)
+
+type SrvBenchmarkCmd struct {
+	ops        int
+	workers    int
+	sizeString string
+	noProgress bool
+}
+
+func configureServerBenchmarkCommand(srv *fisk.CmdClause) {
+	c := &SrvBenchmarkCmd{}
+
+	help := `Measures request-reply round trips through the server
+
+A responder is started on its own connection and every worker sends
+requests to it over a dedicated connection, waiting for each reply before
+sending the next. Nothing is persisted, the results are a baseline of what
+the connection between this machine and the server can do without the
+overhead of JetStream.
+
+Every request passes through the server twice, once to reach the responder
+and once for the reply to return.
+`
+
+	bench := srv.Command("benchmark", help).Action(c.benchmarkAction)
+	bench.Flag("ops", "Number of requests to send").Default("100000").IntVar(&c.ops)
+	bench.Flag("workers", "Number of concurrent workers, each using its own connection").Default("1").IntVar(&c.workers)
+	bench.Flag("size", "Size of the request and reply payloads").Default("128").StringVar(&c.sizeString)
+	bench.Flag("no-progress", "Disable the progress bar while running").UnNegatableBoolVar(&c.noProgress)
+}
+
+func (c *SrvBenchmarkCmd) benchmarkAction(_ *fisk.ParseContext) error {
+	if c.ops <= 0 {
+		return fmt.Errorf("number of ops should be greater than 0")
+	}
+	if c.workers <= 0 {
+		return fmt.Errorf("number of workers should be greater than 0")
+	}
+
+	size, err := parseStringAsBytes(c.sizeString)
+	if err != nil || size < 0 {
+		return fmt.Errorf("invalid size %q", c.sizeString)
+	}
+
+	manager := newConnManager()
+	defer manager.shutdown(false, false)
+
+	responder, err := manager.connect("responder", "")
+	if err != nil {
+		return err
+	}
+
+	subject := nats.NewInbox()
+	_, err = responder.Subscribe(subject, func(msg *nats.Msg) {
+		msg.Respond(msg.Data)
+	})
+	if err != nil {
+		return err
+	}
+	err = responder.Flush()
+	if err != nil {
+		return err
+	}
+
+	rtt, err := responder.RTT()
+	if err != nil {
+		return err
+	}
+
+	conns := make([]*nats.Conn, c.workers)
+	for i := range conns {
+		conns[i], err = manager.connect(fmt.Sprintf("worker %d", i), "")
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Starting server benchmark [server=%s, ops=%s, workers=%d, size=%s, rtt=%v]", responder.ConnectedUrlRedacted(), f(c.ops), c.workers, fiBytes(uint64(size)), rtt)
+
+	payload := make([]byte, size)
+	rand.Read(payload)
+
+	var bar *uiprogress.Bar
+	var progress *uiprogress.Progress
+	if !c.noProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(c.ops).AppendCompleted().PrependElapsed()
+		bar.Width = progressWidth()
+		progress.Start()
+	}
+
+	res := &benchKVResult{kind: "Request", workers: c.workers}
+	mu := sync.Mutex{}
+	wg := &sync.WaitGroup{}
+	trigger := make(chan struct{})
+
+	for i, nc := range conns {
+		count := c.ops / c.workers
+		if i < c.ops%c.workers {
+			count++
+		}
+
+		wg.Add(1)
+		go func(nc *nats.Conn, count int) {
+			defer wg.Done()
+
+			latencies := make([]time.Duration, 0, count)
+			errs := 0
+
+			<-trigger
+
+			for j := 0; j < count; j++ {
+				opStart := time.Now()
+				_, err := nc.Request(subject, payload, opts().Timeout)
+				if err != nil {
+					errs++
+				} else {
+					latencies = append(latencies, time.Since(opStart))
+				}
+
+				if bar != nil {
+					bar.Incr()
+				}
+			}
+
+			mu.Lock()
+			res.latencies = append(res.latencies, latencies...)
+			res.errors += errs
+			mu.Unlock()
+		}(nc, count)
+	}
+
+	start := time.Now()
+	close(trigger)
+	wg.Wait()
+	res.elapsed = time.Since(start)
+
+	if progress != nil {
+		progress.Stop()
+	}
+
+	var rate float64
+	if res.elapsed > 0 {
+		rate = float64(len(res.latencies)) / res.elapsed.Seconds()
+	}
+
+	table := newTableWriter(fmt.Sprintf("Server benchmark using %d workers on %s", c.workers, responder.ConnectedUrlRedacted()))
+	table.AddHeaders("Requests", "Errors", "Requests/sec", "Average", "p50", "p90", "p99", "p99.9", "Max")
+	table.AddRow(f(len(res.latencies)), f(res.errors), f(int64(rate)), f(benchKVAverage(res.latencies)), f(benchKVPercentile(res.latencies, 50)), f(benchKVPercentile(res.latencies, 90)), f(benchKVPercentile(res.latencies, 99)), f(benchKVPercentile(res.latencies, 99.9)), f(benchKVPercentile(res.latencies, 100)))
+
+	fmt.Println()
+	fmt.Println(table.Render())
+	fmt.Printf("Round trip time to the server before the benchmark was %s\n", f(rtt))
+
+	if res.errors > 0 {
+		return fmt.Errorf("%s of %s requests failed", f(res.errors), f(c.ops))
+	}
+
+	return nil
+}
@@ -30,6 +30,7 @@ import (
 	"github.com/nats-io/jwt/v2"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/columns"
 	"github.com/nats-io/nkeys"
 )
 
@@ -123,6 +124,9 @@ type SrvCheckCmd struct {
 	credentialRequiresExpire bool
 	credential               string
 
+	permsPubSubjects []string
+	permsSubSubjects []string
+
 	useMetadata bool
 }
 
@@ -232,6 +236,10 @@ When set these settings will be used, but can be overridden using --waiting-crit
 	cred.Flag("validity-warn", "Warning threshold for time before expiry").DurationVar(&c.credentialValidityWarn)
 	cred.Flag("validity-critical", "Critical threshold for time before expiry").DurationVar(&c.credentialValidityCrit)
 	cred.Flag("require-expiry", "Requires the credential to have expiry set").Default("true").BoolVar(&c.credentialRequiresExpire)
+
+	perms := check.Command("permissions", "Checks that the connection is allowed to publish and subscribe to subjects").Alias("perms").Action(c.checkPermissionsAction)
+	perms.Flag("pub-subject", "Subject that has to allow publishing, an empty message is sent to it (pass multiple times)").PlaceHolder("SUBJECT").StringsVar(&c.permsPubSubjects)
+	perms.Flag("sub-subject", "Subject that has to allow subscribing (pass multiple times)").PlaceHolder("SUBJECT").StringsVar(&c.permsSubSubjects)
 }
 
 var (
@@ -439,11 +447,11 @@ func (c *SrvCheckCmd) checkVarz(check *monitor.Result, vz *server.Varz) error {
 		}
 
 		if up <= c.srvUptimeCrit {
-			check.Critical("Up %s", f(up))
+			check.Critical("Up %s", fCheckDuration(up))
 		} else if up <= c.srvUptimeWarn {
-			check.Warn("Up %s", f(up))
+			check.Warn("Up %s", fCheckDuration(up))
 		} else {
-			check.Ok("Up %s", f(up))
+			check.Ok("Up %s", fCheckDuration(up))
 		}
 	}
 
@@ -1064,9 +1072,9 @@ func (c *SrvCheckCmd) checkCredential(check *monitor.Result) error {
 	case cd.Expires == 0 && c.credentialRequiresExpire:
 		check.Critical("never expires")
 	case c.credentialValidityCrit > 0 && (until <= crit):
-		check.Critical("expires sooner than %s", f(c.credentialValidityCrit))
+		check.Critical("expires sooner than %s", fCheckDuration(c.credentialValidityCrit))
 	case c.credentialValidityWarn > 0 && (until <= warn):
-		check.Warn("expires sooner than %s", f(c.credentialValidityWarn))
+		check.Warn("expires sooner than %s", fCheckDuration(c.credentialValidityWarn))
 	default:
 		check.Ok("expires in %s", time.Unix(cd.Expires, 0).UTC())
 	}
@@ -1080,3 +1088,76 @@ func (c *SrvCheckCmd) checkCredentialAction(_ *fisk.ParseContext) error {
 
 	return c.checkCredential(check)
 }
+
+func (c *SrvCheckCmd) checkPermissionsAction(_ *fisk.ParseContext) error {
+	check := &monitor.Result{Name: "Permissions", Check: "permissions", OutFile: checkRenderOutFile, NameSpace: opts().PrometheusNamespace, RenderFormat: checkRenderFormat}
+	defer check.GenericExit()
+
+	if len(c.permsPubSubjects) == 0 && len(c.permsSubSubjects) == 0 {
+		check.CriticalExit("no subjects to check, use --pub-subject or --sub-subject")
+	}
+
+	probe := &authProbeCommand{}
+
+	var pub []*authProbeResult
+	for _, subject := range c.permsPubSubjects {
+		err := validatePublishSubject(subject)
+		check.CriticalIfErr(err, "invalid publish subject: %s", err)
+		pub = append(pub, &authProbeResult{Subject: subject, Publish: authProbeAllowed})
+	}
+
+	var sub []*authProbeResult
+	for _, subject := range c.permsSubSubjects {
+		err := validateSubjectSyntax(subject)
+		check.CriticalIfErr(err, "invalid subscribe subject: %s", err)
+		sub = append(sub, &authProbeResult{Subject: subject, Subscribe: authProbeAllowed})
+	}
+
+	if len(pub) > 0 {
+		err := probe.probePublish(pub)
+		check.CriticalIfErr(err, "publish check failed: %s", err)
+	}
+
+	if len(sub) > 0 {
+		err := probe.probeSubscribe(sub)
+		check.CriticalIfErr(err, "subscribe check failed: %s", err)
+	}
+
+	c.checkPermissions(check, pub, sub)
+
+	return nil
+}
+
+// checkPermissions reports every probed operation, any that was denied is critical
+func (c *SrvCheckCmd) checkPermissions(check *monitor.Result, pub []*authProbeResult, sub []*authProbeResult) {
+	denied := 0
+
+	for _, res := range pub {
+		if res.Publish == authProbeDenied {
+			denied++
+			check.Critical("publish to %s denied", res.Subject)
+		} else {
+			check.Ok("publish to %s allowed", res.Subject)
+		}
+	}
+
+	for _, res := range sub {
+		if res.Subscribe == authProbeDenied {
+			denied++
+			check.Critical("subscribe to %s denied", res.Subject)
+		} else {
+			check.Ok("subscribe to %s allowed", res.Subject)
+		}
+	}
+
+	check.Pd(
+		&monitor.PerfDataItem{Name: "checks", Value: float64(len(pub) + len(sub)), Help: "Number of publish and subscribe permissions checked"},
+		&monitor.PerfDataItem{Name: "denied", Value: float64(denied), Crit: 1, Help: "Number of publish and subscribe permissions that were denied"},
+	)
+}
+
+// fCheckDuration formats durations in check output the way they always were, alert rules parse this text so it does
+// not follow the humanized or raw durations used elsewhere
+func fCheckDuration(d time.Duration) string {
+	return columns.HumanizeDuration(d)
+}
@@ -753,6 +753,33 @@ SUAKYITMHPMSYUGPNQBLLPGOPFQN44XNCGXHNSHLJJVMD3IKYGBOLAI7TI
 	})
 
 }
+
+func TestCheckPermissions(t *testing.T) {
+	cmd := &SrvCheckCmd{}
+
+	t.Run("allowed", func(t *testing.T) {
+		check := &monitor.Result{}
+		cmd.checkPermissions(check,
+			[]*authProbeResult{{Subject: "events.orders", Publish: authProbeAllowed}},
+			[]*authProbeResult{{Subject: "events.>", Subscribe: authProbeAllowed}})
+
+		assertListIsEmpty(t, check.Criticals)
+		assertListEquals(t, check.OKs, "publish to events.orders allowed", "subscribe to events.> allowed")
+		assertHasPDItem(t, check, "checks=2 denied=0;;1")
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		check := &monitor.Result{}
+		cmd.checkPermissions(check,
+			[]*authProbeResult{{Subject: "events.orders", Publish: authProbeAllowed}, {Subject: "events.billing", Publish: authProbeDenied}},
+			[]*authProbeResult{{Subject: "events.>", Subscribe: authProbeDenied}})
+
+		assertListEquals(t, check.Criticals, "publish to events.billing denied", "subscribe to events.> denied")
+		assertListEquals(t, check.OKs, "publish to events.orders allowed")
+		assertHasPDItem(t, check, "checks=3 denied=2;;1")
+	})
+}
+
 func TestCheckJSZ(t *testing.T) {
 	cmd := &SrvCheckCmd{}
 
@@ -273,7 +273,7 @@ func (c *SrvClusterCmd) metaLeaderStandDownAction(_ *fisk.ParseContext) error {
 
 		resp, err = getJSI()
 		if err != nil {
-			log.Printf("Failed to retrieve Cluster State: %s", err)
+			logErrorf("Failed to retrieve Cluster State: %s", err)
 			continue
 		}
 
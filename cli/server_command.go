@@ -18,18 +18,27 @@ func configureServerCommand(app commandHost) {
 	addCheat("server", srv)
 
 	configureServerAccountCommand(srv)
+	configureServerBenchmarkCommand(srv)
 	configureServerCheckCommand(srv)
 	configureServerClusterCommand(srv)
 	configureServerConfigCommand(srv)
 	configureServerGenerateCommand(srv)
 	configureServerInfoCommand(srv)
+	configureServerLeafnodeCommand(srv)
 	configureServerListCommand(srv)
 	configureServerMappingCommand(srv)
+	configureServerMetaLeaderCommand(srv)
+	configureServerMonitorCommand(srv)
+	configureServerOfflineCheckCommand(srv)
 	configureServerPasswdCommand(srv)
 	configureServerPingCommand(srv)
+	configureServerProfileCommand(srv)
+	configureServerRebalanceCommand(srv)
 	configureServerReportCommand(srv)
 	configureServerRequestCommand(srv)
 	configureServerRunCommand(srv)
+	configureServerSubscriptionsCommand(srv)
+	configureServerVersionCommand(srv)
 	configureServerWatchCommand(srv)
 }
 
@@ -16,14 +16,80 @@ package cli
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/choria-io/fisk"
+	"github.com/nats-io/jwt/v2"
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
 )
 
 type SrvConfigCmd struct {
 	serverID string
 	force    bool
+	all      bool
+	output   string
+	desired  string
+}
+
+// srvConfigExport is a snapshot of the configuration of all servers
+type srvConfigExport struct {
+	Time    time.Time                `json:"time"`
+	Servers []*srvConfigExportServer `json:"servers"`
+}
+
+// srvConfigExportServer is the configuration reported in VARZ by a server without any runtime statistics
+type srvConfigExportServer struct {
+	ID                string                   `json:"server_id"`
+	Name              string                   `json:"server_name"`
+	Version           string                   `json:"version"`
+	GitCommit         string                   `json:"git_commit,omitempty"`
+	ConfigLoadTime    time.Time                `json:"config_load_time"`
+	ConfigDigest      string                   `json:"config_digest,omitempty"`
+	Host              string                   `json:"host"`
+	Port              int                      `json:"port"`
+	AuthRequired      bool                     `json:"auth_required,omitempty"`
+	TLSRequired       bool                     `json:"tls_required,omitempty"`
+	TLSVerify         bool                     `json:"tls_verify,omitempty"`
+	TLSOCSPPeerVerify bool                     `json:"tls_ocsp_peer_verify,omitempty"`
+	TLSTimeout        float64                  `json:"tls_timeout"`
+	MaxConn           int                      `json:"max_connections"`
+	MaxSubs           int                      `json:"max_subscriptions,omitempty"`
+	PingInterval      time.Duration            `json:"ping_interval"`
+	MaxPingsOut       int                      `json:"ping_max"`
+	HTTPHost          string                   `json:"http_host"`
+	HTTPPort          int                      `json:"http_port"`
+	HTTPBasePath      string                   `json:"http_base_path"`
+	HTTPSPort         int                      `json:"https_port"`
+	AuthTimeout       float64                  `json:"auth_timeout"`
+	MaxControlLine    int32                    `json:"max_control_line"`
+	MaxPayload        int                      `json:"max_payload"`
+	MaxPending        int64                    `json:"max_pending"`
+	WriteDeadline     time.Duration            `json:"write_deadline"`
+	Cluster           server.ClusterOptsVarz   `json:"cluster,omitempty"`
+	Gateway           server.GatewayOptsVarz   `json:"gateway,omitempty"`
+	LeafNode          server.LeafNodeOptsVarz  `json:"leaf,omitempty"`
+	MQTT              server.MQTTOptsVarz      `json:"mqtt,omitempty"`
+	Websocket         server.WebsocketOptsVarz `json:"websocket,omitempty"`
+	JetStream         *server.JetStreamConfig  `json:"jetstream,omitempty"`
+	Tags              jwt.TagList              `json:"tags,omitempty"`
+	SystemAccount     string                   `json:"system_account,omitempty"`
+	TrustedOperators  []string                 `json:"trusted_operators,omitempty"`
+}
+
+// srvReloadVarz is the subset of a VARZ response used to confirm a configuration reload
+type srvReloadVarz struct {
+	Server *server.ServerInfo `json:"server"`
+	Data   struct {
+		ConfigLoadTime time.Time `json:"config_load_time"`
+		ConfigDigest   string    `json:"config_digest"`
+	} `json:"data"`
+	Error *server.ApiError `json:"error"`
 }
 
 func configureServerConfigCommand(srv *fisk.CmdClause) {
@@ -34,6 +100,23 @@ func configureServerConfigCommand(srv *fisk.CmdClause) {
 	reload := cfg.Command("reload", "Reloads the runtime configuration").Action(c.reloadAction)
 	reload.Arg("id", "The server ID to trigger a reload for").Required().StringVar(&c.serverID)
 	reload.Flag("force", "Force reload without prompting").Short('f').BoolVar(&c.force)
+
+	srvReload := srv.Command("reload", "Reloads the configuration of running servers").Action(c.reloadServersAction)
+	srvReload.Arg("id", "The server ID to reload, defaults to the connected server").StringVar(&c.serverID)
+	srvReload.Flag("all", "Reload all servers in the cluster one after the other").UnNegatableBoolVar(&c.all)
+	srvReload.Flag("force", "Force reload without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	export := srv.Command("config-export", "Exports the configuration of all servers as JSON").Action(c.exportAction)
+	export.Flag("output", "Write the configuration to a file rather than to STDOUT").Short('o').PlaceHolder("FILE").StringVar(&c.output)
+
+	diff := srv.Command("config-diff", "Compares the configuration of a running server to a configuration file").Action(c.diffAction)
+	diff.HelpLong(`Settings reported by the monitoring API are compared, covering limits, JetStream,
+whether authorization and TLS are required, the system account and the accounts
+defined in the file. Settings not set in the file are not compared.
+
+Exits with code 1 when any setting differs.`)
+	diff.Arg("id", "The server ID to compare, defaults to the connected server").StringVar(&c.serverID)
+	diff.Flag("desired", "The configuration file the server should be running").Required().PlaceHolder("FILE").ExistingFileVar(&c.desired)
 }
 
 func (c *SrvConfigCmd) reloadAction(pc *fisk.ParseContext) error {
@@ -80,3 +163,332 @@ func (c *SrvConfigCmd) reloadAction(pc *fisk.ParseContext) error {
 	nfo := &SrvInfoCmd{id: c.serverID}
 	return nfo.info(pc)
 }
+
+func (c *SrvConfigCmd) reloadServersAction(_ *fisk.ParseContext) error {
+	if c.all && c.serverID != "" {
+		return fmt.Errorf("a server ID can not be combined with --all")
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	var targets []*srvReloadVarz
+	if c.all {
+		targets, err = c.reloadTargetsAll(nc)
+	} else {
+		id := c.serverID
+		if id == "" {
+			id = nc.ConnectedServerId()
+		}
+
+		var vz *srvReloadVarz
+		vz, err = c.reloadVarz(nc, id)
+		targets = append(targets, vz)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !c.force {
+		prompt := fmt.Sprintf("Really reload configuration for %s (%s) on %s", targets[0].Server.Name, targets[0].Server.ID, targets[0].Server.Host)
+		if len(targets) > 1 {
+			prompt = fmt.Sprintf("Really reload configuration for %d servers", len(targets))
+		}
+
+		ok, err := askConfirmation(prompt, false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	failed := 0
+	for _, before := range targets {
+		err = c.reloadServer(nc, before)
+		if err != nil {
+			fmt.Printf("Reload of %s (%s) failed: %v\n", before.Server.Name, before.Server.ID, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("reload failed on %d of %d servers", failed, len(targets))
+	}
+
+	return nil
+}
+
+// reloadServer reloads a single server and compares its configuration state to before
+func (c *SrvConfigCmd) reloadServer(nc *nats.Conn, before *srvReloadVarz) error {
+	resps, err := doReq(nil, fmt.Sprintf("$SYS.REQ.SERVER.%s.RELOAD", before.Server.ID), 1, nc)
+	if err != nil {
+		return err
+	}
+
+	if len(resps) == 0 {
+		return fmt.Errorf("no response received, the server might not support remote reloads")
+	}
+
+	var resp server.ServerAPIResponse
+	err = json.Unmarshal(resps[0], &resp)
+	if err != nil {
+		return err
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("%s", resp.Error.Description)
+	}
+
+	after, err := c.reloadVarz(nc, before.Server.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Reloaded configuration for %s (%s)\n", after.Server.Name, after.Server.ID)
+	if before.Data.ConfigDigest != "" || after.Data.ConfigDigest != "" {
+		fmt.Printf("   Config Digest: %s -> %s\n", before.Data.ConfigDigest, after.Data.ConfigDigest)
+	}
+	fmt.Printf("     Config Load: %s -> %s\n", f(before.Data.ConfigLoadTime), f(after.Data.ConfigLoadTime))
+
+	if !after.Data.ConfigLoadTime.After(before.Data.ConfigLoadTime) {
+		return fmt.Errorf("config load time did not change")
+	}
+
+	return nil
+}
+
+func (c *SrvConfigCmd) reloadVarz(nc *nats.Conn, id string) (*srvReloadVarz, error) {
+	resps, err := doReq(nil, fmt.Sprintf("$SYS.REQ.SERVER.%s.VARZ", id), 1, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resps) != 1 {
+		return nil, fmt.Errorf("invalid response from %d servers", len(resps))
+	}
+
+	vz := &srvReloadVarz{}
+	err = json.Unmarshal(resps[0], vz)
+	if err != nil {
+		return nil, err
+	}
+
+	if vz.Error != nil {
+		return nil, fmt.Errorf("%s", vz.Error.Description)
+	}
+
+	if vz.Server == nil {
+		return nil, fmt.Errorf("invalid VARZ response from %s", id)
+	}
+
+	return vz, nil
+}
+
+func (c *SrvConfigCmd) reloadTargetsAll(nc *nats.Conn) ([]*srvReloadVarz, error) {
+	expect, err := currentActiveServers(nc)
+	if err != nil {
+		return nil, err
+	}
+
+	resps, err := doReq(nil, "$SYS.REQ.SERVER.PING.VARZ", expect, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []*srvReloadVarz
+	for _, resp := range resps {
+		vz := &srvReloadVarz{}
+		err = json.Unmarshal(resp, vz)
+		if err != nil {
+			return nil, err
+		}
+
+		if vz.Error != nil || vz.Server == nil {
+			continue
+		}
+
+		targets = append(targets, vz)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no servers found")
+	}
+
+	sort.Slice(targets, func(i, j int) bool { return targets[i].Server.Name < targets[j].Server.Name })
+
+	return targets, nil
+}
+
+func (c *SrvConfigCmd) exportAction(_ *fisk.ParseContext) error {
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	expect, err := currentActiveServers(nc)
+	if err != nil {
+		return err
+	}
+
+	resps, err := doReq(nil, "$SYS.REQ.SERVER.PING", expect, nc)
+	if err != nil {
+		return err
+	}
+
+	export := &srvConfigExport{Time: time.Now().UTC()}
+	for _, resp := range resps {
+		var ping server.ServerStatsMsg
+		err = json.Unmarshal(resp, &ping)
+		if err != nil {
+			return err
+		}
+
+		cfg, err := c.exportServer(nc, ping.Server.ID)
+		if err != nil {
+			return fmt.Errorf("could not export configuration for %s: %w", ping.Server.Name, err)
+		}
+
+		export.Servers = append(export.Servers, cfg)
+	}
+
+	if len(export.Servers) == 0 {
+		return fmt.Errorf("no servers found")
+	}
+
+	sort.Slice(export.Servers, func(i, j int) bool { return export.Servers[i].Name < export.Servers[j].Name })
+
+	if c.output == "" {
+		return iu.PrintJSON(export)
+	}
+
+	j, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(c.output, j, 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported the configuration of %s servers to %s\n", f(len(export.Servers)), c.output)
+
+	return nil
+}
+
+// exportServer retrieves the configuration of a single server, URLs are redacted as they might hold credentials
+func (c *SrvConfigCmd) exportServer(nc *nats.Conn, id string) (*srvConfigExportServer, error) {
+	resps, err := doReq(nil, fmt.Sprintf("$SYS.REQ.SERVER.%s.VARZ", id), 1, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resps) != 1 {
+		return nil, fmt.Errorf("invalid response from %d servers", len(resps))
+	}
+
+	var resp struct {
+		Data  *server.Varz     `json:"data"`
+		Error *server.ApiError `json:"error"`
+	}
+	err = json.Unmarshal(resps[0], &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Description)
+	}
+
+	if resp.Data == nil {
+		return nil, fmt.Errorf("invalid VARZ response")
+	}
+
+	// VARZ of this server version does not include the digest so it is parsed separately
+	var digest srvReloadVarz
+	err = json.Unmarshal(resps[0], &digest)
+	if err != nil {
+		return nil, err
+	}
+
+	vz := resp.Data
+	cfg := &srvConfigExportServer{
+		ID:                vz.ID,
+		Name:              vz.Name,
+		Version:           vz.Version,
+		GitCommit:         vz.GitCommit,
+		ConfigLoadTime:    vz.ConfigLoadTime,
+		ConfigDigest:      digest.Data.ConfigDigest,
+		Host:              vz.Host,
+		Port:              vz.Port,
+		AuthRequired:      vz.AuthRequired,
+		TLSRequired:       vz.TLSRequired,
+		TLSVerify:         vz.TLSVerify,
+		TLSOCSPPeerVerify: vz.TLSOCSPPeerVerify,
+		TLSTimeout:        vz.TLSTimeout,
+		MaxConn:           vz.MaxConn,
+		MaxSubs:           vz.MaxSubs,
+		PingInterval:      vz.PingInterval,
+		MaxPingsOut:       vz.MaxPingsOut,
+		HTTPHost:          vz.HTTPHost,
+		HTTPPort:          vz.HTTPPort,
+		HTTPBasePath:      vz.HTTPBasePath,
+		HTTPSPort:         vz.HTTPSPort,
+		AuthTimeout:       vz.AuthTimeout,
+		MaxControlLine:    vz.MaxControlLine,
+		MaxPayload:        vz.MaxPayload,
+		MaxPending:        vz.MaxPending,
+		WriteDeadline:     vz.WriteDeadline,
+		Cluster:           vz.Cluster,
+		Gateway:           vz.Gateway,
+		LeafNode:          vz.LeafNode,
+		MQTT:              vz.MQTT,
+		Websocket:         vz.Websocket,
+		JetStream:         vz.JetStream.Config,
+		Tags:              vz.Tags,
+		SystemAccount:     vz.SystemAccount,
+	}
+
+	for _, op := range vz.TrustedOperatorsClaim {
+		cfg.TrustedOperators = append(cfg.TrustedOperators, op.Subject)
+	}
+
+	cfg.Cluster.URLs = redactURLs(cfg.Cluster.URLs)
+	for i := range cfg.Gateway.Gateways {
+		cfg.Gateway.Gateways[i].URLs = redactURLs(cfg.Gateway.Gateways[i].URLs)
+	}
+	for i := range cfg.LeafNode.Remotes {
+		cfg.LeafNode.Remotes[i].URLs = redactURLs(cfg.LeafNode.Remotes[i].URLs)
+	}
+
+	return cfg, nil
+}
+
+// redactURLs removes any user information from urls, servers usually report only the host and port
+func redactURLs(urls []string) []string {
+	var res []string
+	for _, u := range urls {
+		if !strings.Contains(u, "@") {
+			res = append(res, u)
+			continue
+		}
+
+		parsed, err := url.Parse(u)
+		if err != nil || parsed.Host == "" {
+			res = append(res, u[strings.LastIndex(u, "@")+1:])
+			continue
+		}
+
+		parsed.User = nil
+		res = append(res, parsed.String())
+	}
+
+	return res
+}
@@ -0,0 +1,250 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// srvConfigDrift is a setting with a different value in the running server than in the desired configuration
+type srvConfigDrift struct {
+	setting string
+	running string
+	desired string
+}
+
+// srvConfigDriftReport compares the VARZ and accounts of a running server to a configuration file
+type srvConfigDriftReport struct {
+	compared int
+	drift    []*srvConfigDrift
+}
+
+func (r *srvConfigDriftReport) compare(setting string, set bool, running any, desired any) {
+	if !set {
+		return
+	}
+
+	r.compared++
+
+	rs, ds := f(running), f(desired)
+	if rs != ds {
+		r.drift = append(r.drift, &srvConfigDrift{setting: setting, running: rs, desired: ds})
+	}
+}
+
+// newSrvConfigDriftReport compares the settings reported in VARZ and ACCOUNTZ, settings not set in the desired
+// configuration are not compared as the server would use its defaults for them
+func newSrvConfigDriftReport(vz *server.Varz, accounts []string, desired *server.Options) *srvConfigDriftReport {
+	report := &srvConfigDriftReport{}
+
+	report.compare("server_name", desired.ServerName != "", vz.Name, desired.ServerName)
+	report.compare("port", desired.Port > 0, vz.Port, desired.Port)
+	report.compare("http_port", desired.HTTPPort > 0, vz.HTTPPort, desired.HTTPPort)
+	report.compare("max_connections", desired.MaxConn != 0, vz.MaxConn, desired.MaxConn)
+	report.compare("max_subscriptions", desired.MaxSubs != 0, vz.MaxSubs, desired.MaxSubs)
+	report.compare("max_payload", desired.MaxPayload != 0, vz.MaxPayload, int(desired.MaxPayload))
+	report.compare("max_pending", desired.MaxPending != 0, vz.MaxPending, desired.MaxPending)
+	report.compare("max_control_line", desired.MaxControlLine != 0, vz.MaxControlLine, desired.MaxControlLine)
+	report.compare("ping_interval", desired.PingInterval != 0, vz.PingInterval, desired.PingInterval)
+	report.compare("ping_max", desired.MaxPingsOut != 0, vz.MaxPingsOut, desired.MaxPingsOut)
+	report.compare("write_deadline", desired.WriteDeadline != 0, vz.WriteDeadline, desired.WriteDeadline)
+	report.compare("cluster.name", desired.Cluster.Name != "", vz.Cluster.Name, desired.Cluster.Name)
+	report.compare("gateway.name", desired.Gateway.Name != "", vz.Gateway.Name, desired.Gateway.Name)
+	report.compare("leafnodes.port", desired.LeafNode.Port > 0, vz.LeafNode.Port, desired.LeafNode.Port)
+
+	tags := make([]string, len(desired.Tags))
+	copy(tags, desired.Tags)
+	sort.Strings(tags)
+	runningTags := make([]string, len(vz.Tags))
+	copy(runningTags, vz.Tags)
+	sort.Strings(runningTags)
+	report.compare("server_tags", len(tags) > 0, runningTags, tags)
+
+	// JetStream is disabled when the file does not enable it, so it is always compared
+	report.compare("jetstream", true, vz.JetStream.Config != nil, desired.JetStream)
+	if desired.JetStream && vz.JetStream.Config != nil {
+		js := vz.JetStream.Config
+		report.compare("jetstream.max_memory_store", desired.JetStreamMaxMemory > 0, js.MaxMemory, desired.JetStreamMaxMemory)
+		report.compare("jetstream.max_file_store", desired.JetStreamMaxStore > 0, js.MaxStore, desired.JetStreamMaxStore)
+
+		// the server stores data in a jetstream directory below the one configured
+		storeDir := desired.StoreDir
+		if storeDir != "" && filepath.Base(storeDir) != server.JetStreamStoreDir {
+			storeDir = filepath.Join(storeDir, server.JetStreamStoreDir)
+		}
+		report.compare("jetstream.store_dir", storeDir != "", js.StoreDir, storeDir)
+		report.compare("jetstream.domain", desired.JetStreamDomain != "", js.Domain, desired.JetStreamDomain)
+	}
+
+	// authorization details are not exposed by the monitoring API, only whether it is required
+	authRequired := desired.Username != "" || desired.Authorization != "" || len(desired.Users) > 0 || len(desired.Nkeys) > 0 || len(desired.TrustedOperators) > 0
+	report.compare("authorization required", true, vz.AuthRequired, authRequired)
+	report.compare("authorization.timeout", desired.AuthTimeout != 0, vz.AuthTimeout, desired.AuthTimeout)
+	report.compare("tls required", true, vz.TLSRequired, desired.TLSConfig != nil && !desired.AllowNonTLS)
+	report.compare("tls verify", desired.TLSConfig != nil, vz.TLSVerify, desired.TLSVerify)
+	report.compare("system_account", desired.SystemAccount != "", vz.SystemAccount, desired.SystemAccount)
+
+	// accounts from an operator are not in the file, the global and system accounts are created by the server
+	if len(desired.Accounts) > 0 && len(desired.TrustedOperators) == 0 {
+		running := map[string]bool{}
+		for _, acct := range accounts {
+			running[acct] = true
+		}
+
+		wanted := map[string]bool{}
+		for _, acct := range desired.Accounts {
+			wanted[acct.Name] = true
+		}
+
+		for _, acct := range []string{server.DEFAULT_GLOBAL_ACCOUNT, server.DEFAULT_SYSTEM_ACCOUNT} {
+			if !wanted[acct] {
+				delete(running, acct)
+			}
+		}
+
+		names := mapKeys(running)
+		for name := range wanted {
+			if !running[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			report.compare(fmt.Sprintf("accounts.%s", name), true, srvConfigAccountState(running[name]), srvConfigAccountState(wanted[name]))
+		}
+	}
+
+	return report
+}
+
+func srvConfigAccountState(defined bool) string {
+	if defined {
+		return "defined"
+	}
+
+	return "absent"
+}
+
+func (c *SrvConfigCmd) diffAction(_ *fisk.ParseContext) error {
+	desired, err := server.ProcessConfigFile(c.desired)
+	if err != nil {
+		return fmt.Errorf("could not parse %s: %w", c.desired, err)
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	id := c.serverID
+	if id == "" {
+		id = nc.ConnectedServerId()
+	}
+
+	vz, err := c.diffVarz(nc, id)
+	if err != nil {
+		return err
+	}
+
+	accounts, err := c.diffAccounts(nc, id)
+	if err != nil {
+		return err
+	}
+
+	report := newSrvConfigDriftReport(vz, accounts, desired)
+
+	if len(report.drift) == 0 {
+		fmt.Printf("The running configuration of %s matches %s in all %d settings compared\n", vz.Name, c.desired, report.compared)
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("Configuration drift of %s from %s, loaded %s ago", vz.Name, c.desired, f(time.Since(vz.ConfigLoadTime).Round(time.Second))))
+	table.AddHeaders("Setting", "Running", "Desired")
+	for _, d := range report.drift {
+		table.AddRow(d.setting, d.running, d.desired)
+	}
+	fmt.Println(table.Render())
+
+	return fmt.Errorf("%d of %d settings compared differ from %s", len(report.drift), report.compared, c.desired)
+}
+
+func (c *SrvConfigCmd) diffVarz(nc *nats.Conn, id string) (*server.Varz, error) {
+	resps, err := doReq(nil, fmt.Sprintf("$SYS.REQ.SERVER.%s.VARZ", id), 1, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resps) != 1 {
+		return nil, fmt.Errorf("invalid response from %d servers", len(resps))
+	}
+
+	var resp struct {
+		Data  *server.Varz     `json:"data"`
+		Error *server.ApiError `json:"error"`
+	}
+	err = json.Unmarshal(resps[0], &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Description)
+	}
+
+	if resp.Data == nil {
+		return nil, fmt.Errorf("invalid VARZ response from %s", id)
+	}
+
+	return resp.Data, nil
+}
+
+func (c *SrvConfigCmd) diffAccounts(nc *nats.Conn, id string) ([]string, error) {
+	resps, err := doReq(nil, fmt.Sprintf("$SYS.REQ.SERVER.%s.ACCOUNTZ", id), 1, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resps) != 1 {
+		return nil, fmt.Errorf("invalid response from %d servers", len(resps))
+	}
+
+	var resp struct {
+		Data  *server.Accountz `json:"data"`
+		Error *server.ApiError `json:"error"`
+	}
+	err = json.Unmarshal(resps[0], &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s", resp.Error.Description)
+	}
+
+	if resp.Data == nil {
+		return nil, fmt.Errorf("invalid ACCOUNTZ response from %s", id)
+	}
+
+	return resp.Data.Accounts, nil
+}
@@ -22,7 +22,6 @@ import (
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/nats-server/v2/server"
 )
 
@@ -137,11 +136,11 @@ func (c *SrvInfoCmd) info(_ *fisk.ParseContext) error {
 		cols.AddSectionTitle("JetStream")
 		cols.AddRow("Domain", js.Config.Domain)
 		cols.AddRow("Storage Directory", js.Config.StoreDir)
-		cols.AddRow("Max Memory", humanize.IBytes(uint64(js.Config.MaxMemory)))
-		cols.AddRow("Max File", humanize.IBytes(uint64(js.Config.MaxStore)))
+		cols.AddRow("Max Memory", fiBytes(uint64(js.Config.MaxMemory)))
+		cols.AddRow("Max File", fiBytes(uint64(js.Config.MaxStore)))
 		cols.AddRow("Active Accounts", js.Stats.Accounts)
-		cols.AddRow("Memory In Use", humanize.IBytes(js.Stats.Memory))
-		cols.AddRow("File In Use", humanize.IBytes(js.Stats.Store))
+		cols.AddRow("Memory In Use", fiBytes(js.Stats.Memory))
+		cols.AddRow("File In Use", fiBytes(js.Stats.Store))
 		cols.AddRow("API Requests", js.Stats.API.Total)
 		cols.AddRow("API Errors", js.Stats.API.Errors)
 		// would be zero on machines that dont support this setting
@@ -154,17 +153,17 @@ func (c *SrvInfoCmd) info(_ *fisk.ParseContext) error {
 	cols.AddSectionTitle("Limits")
 	cols.AddRow("Max Conn", varz.MaxConn)
 	cols.AddRow("Max Subs", varz.MaxSubs)
-	cols.AddRow("Max Payload", humanize.IBytes(uint64(varz.MaxPayload)))
+	cols.AddRow("Max Payload", fiBytes(uint64(varz.MaxPayload)))
 	cols.AddRow("TLS Timeout", time.Duration(varz.TLSTimeout)*time.Second)
 	cols.AddRow("Write Deadline", varz.WriteDeadline.Round(time.Millisecond))
 
 	cols.AddSectionTitle("Statistics")
 	cols.AddRowf("CPU Cores", "%d %.2f%%", varz.Cores, varz.CPU)
-	cols.AddRow("Memory", humanize.IBytes(uint64(varz.Mem)))
+	cols.AddRow("Memory", fiBytes(uint64(varz.Mem)))
 	cols.AddRow("Connections", varz.Connections)
 	cols.AddRow("Subscriptions", varz.Subscriptions)
 	cols.AddRowf("Messages", "%s in %s out", f(varz.InMsgs), f(varz.OutMsgs))
-	cols.AddRowf("Bytes", "%s in %s out", humanize.IBytes(uint64(varz.InBytes)), humanize.IBytes(uint64(varz.OutBytes)))
+	cols.AddRowf("Bytes", "%s in %s out", fiBytes(uint64(varz.InBytes)), fiBytes(uint64(varz.OutBytes)))
 	cols.AddRow("Slow Consumers", varz.SlowConsumers)
 
 	if len(varz.Cluster.URLs) > 0 {
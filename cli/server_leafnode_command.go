@@ -0,0 +1,188 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const srvLeafWatchInterval = 5 * time.Second
+
+type SrvLeafCmd struct {
+	id      string
+	account string
+	watch   bool
+	json    bool
+}
+
+type srvLeafInfo struct {
+	*server.LeafInfo
+	Server *server.ServerInfo `json:"server"`
+}
+
+type srvLeafzResponse struct {
+	Server *server.ServerInfo `json:"server"`
+	Data   *server.Leafz      `json:"data,omitempty"`
+	Error  *server.ApiError   `json:"error,omitempty"`
+}
+
+func configureServerLeafnodeCommand(srv *fisk.CmdClause) {
+	c := &SrvLeafCmd{}
+
+	leaf := srv.Command("leafnode", "Show leaf node connections").Alias("leafnodes").Alias("leafz").Alias("leaf").Action(c.leafAction)
+	leaf.Arg("server", "Server ID or Name to inspect, all servers when not given").StringVar(&c.id)
+	leaf.Flag("account", "Only show leaf nodes bound to a specific account").StringVar(&c.account)
+	leaf.Flag("watch", "Refresh the leaf node information every 5 seconds").Short('w').UnNegatableBoolVar(&c.watch)
+	leaf.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func (c *SrvLeafCmd) leafAction(_ *fisk.ParseContext) error {
+	if c.watch && c.json {
+		return fmt.Errorf("watch and json can not be used together")
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	if !c.watch {
+		return c.showLeafs(nc)
+	}
+
+	ticker := time.NewTicker(srvLeafWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		clearScreen()
+		err = c.showLeafs(nc)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *SrvLeafCmd) showLeafs(nc *nats.Conn) error {
+	leafs, err := c.leafs(nc)
+	if err != nil {
+		return err
+	}
+
+	if c.json {
+		iu.PrintJSON(leafs)
+		return nil
+	}
+
+	c.renderLeafs(leafs)
+
+	return nil
+}
+
+func (c *SrvLeafCmd) leafs(nc *nats.Conn) ([]*srvLeafInfo, error) {
+	subj := "$SYS.REQ.SERVER.PING.LEAFZ"
+	req := server.LeafzEventOptions{
+		LeafzOptions: server.LeafzOptions{Account: c.account},
+	}
+
+	waitFor := 1
+	switch {
+	case len(c.id) == 56 && strings.ToUpper(c.id) == c.id:
+		subj = fmt.Sprintf("$SYS.REQ.SERVER.%s.LEAFZ", c.id)
+	case c.id != "":
+		req.EventFilterOptions.Name = c.id
+	default:
+		expect, err := currentActiveServers(nc)
+		if err != nil {
+			return nil, err
+		}
+		waitFor = expect
+	}
+
+	results, err := doReq(&req, subj, waitFor, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var leafs []*srvLeafInfo
+	for _, result := range results {
+		var resp srvLeafzResponse
+		err = json.Unmarshal(result, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("invalid response received: %v", resp.Error)
+		}
+		if resp.Data == nil {
+			continue
+		}
+
+		for _, leaf := range resp.Data.Leafs {
+			leafs = append(leafs, &srvLeafInfo{LeafInfo: leaf, Server: resp.Server})
+		}
+	}
+
+	sort.Slice(leafs, func(i, j int) bool {
+		return sortMultiSort(leafs[i].Server.Name, leafs[j].Server.Name, leafs[i].Name, leafs[j].Name)
+	})
+
+	return leafs, nil
+}
+
+func (c *SrvLeafCmd) renderLeafs(leafs []*srvLeafInfo) {
+	if len(leafs) == 0 {
+		fmt.Println("No leaf node connections found")
+		return
+	}
+
+	table := newTableWriter(fmt.Sprintf("%d Leaf Node connections", len(leafs)))
+	table.AddHeaders("Server", "Name", "Account", "Address", "Spoke", "RTT", "Subscriptions", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes")
+
+	for _, leaf := range leafs {
+		table.AddRow(
+			leaf.Server.Name,
+			leaf.Name,
+			leaf.Account,
+			fmt.Sprintf("%s:%d", leaf.IP, leaf.Port),
+			leaf.IsSpoke,
+			leaf.RTT,
+			f(leaf.NumSubs),
+			f(leaf.InMsgs),
+			f(leaf.OutMsgs),
+			fiBytes(uint64(leaf.InBytes)),
+			fiBytes(uint64(leaf.OutBytes)),
+		)
+	}
+
+	fmt.Println(table.Render())
+}
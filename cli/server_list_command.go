@@ -24,7 +24,6 @@ import (
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/nats-server/v2/server"
 )
 
@@ -87,7 +86,7 @@ func (c *SrvLsCmd) list(_ *fisk.ParseContext) error {
 		ssm := &server.ServerStatsMsg{}
 		err = json.Unmarshal(data, ssm)
 		if err != nil {
-			log.Printf("Could not decode response: %s", err)
+			logErrorf("Could not decode response: %s", err)
 			os.Exit(1)
 		}
 
@@ -249,11 +248,11 @@ func (c *SrvLsCmd) list(_ *fisk.ParseContext) error {
 			cHosts[i],
 			ssm.Server.Version,
 			jsEnabled,
-			f(ssm.Stats.Connections),
-			f(ssm.Stats.NumSubs),
+			fCount(ssm.Stats.Connections),
+			fCount(ssm.Stats.NumSubs),
 			len(ssm.Stats.Routes),
 			len(ssm.Stats.Gateways),
-			humanize.IBytes(uint64(ssm.Stats.Mem)),
+			fiBytes(uint64(ssm.Stats.Mem)),
 			fmt.Sprintf("%.0f", ssm.Stats.CPU),
 			ssm.Stats.Cores,
 			ssm.Stats.SlowConsumers,
@@ -267,11 +266,11 @@ func (c *SrvLsCmd) list(_ *fisk.ParseContext) error {
 		servers,
 		versionsOk,
 		js,
-		f(connections),
-		f(subs),
+		fCount(connections),
+		fCount(subs),
 		routesOk,
 		gwaysOk,
-		humanize.IBytes(uint64(memory)),
+		fiBytes(uint64(memory)),
 		"",
 		"",
 		f(slow),
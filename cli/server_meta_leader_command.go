@@ -0,0 +1,272 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+type SrvMetaLeaderCmd struct {
+	expect           int
+	stepdown         bool
+	placementCluster string
+	wait             time.Duration
+	force            bool
+	json             bool
+}
+
+// srvMetaLeaderView is the meta leader as seen by one server
+type srvMetaLeaderView struct {
+	Server   string `json:"server"`
+	ID       string `json:"id"`
+	Cluster  string `json:"cluster,omitempty"`
+	Leader   string `json:"leader"`
+	IsLeader bool   `json:"is_leader"`
+	Size     int    `json:"cluster_size"`
+}
+
+type srvMetaLeaderReport struct {
+	Leader         string               `json:"leader"`
+	PreviousLeader string               `json:"previous_leader,omitempty"`
+	Agreed         bool                 `json:"agreed"`
+	Servers        []*srvMetaLeaderView `json:"servers"`
+}
+
+func configureServerMetaLeaderCommand(srv *fisk.CmdClause) {
+	c := &SrvMetaLeaderCmd{}
+
+	help := `Shows the JetStream meta leader as seen by every server
+
+The meta leader manages the JetStream cluster, every JetStream server is
+asked which server it considers the leader. With --stepdown the leader is
+asked to stand down and the command waits for all servers to agree on a
+new leader.
+`
+
+	ml := srv.Command("meta-leader", help).Alias("ml").Action(c.metaLeaderAction)
+	ml.Flag("expect", "How many JetStream servers to expect").PlaceHolder("SERVERS").IntVar(&c.expect)
+	ml.Flag("stepdown", "Stand down the current meta leader to trigger a new election").UnNegatableBoolVar(&c.stepdown)
+	ml.Flag("cluster", "Request placement of the new leader in a specific cluster when standing down").PlaceHolder("CLUSTER").StringVar(&c.placementCluster)
+	ml.Flag("wait", "How long to wait for a new leader to be elected").Default("10s").DurationVar(&c.wait)
+	ml.Flag("force", "Stand down without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	ml.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func (c *SrvMetaLeaderCmd) metaLeaderAction(_ *fisk.ParseContext) error {
+	if c.placementCluster != "" && !c.stepdown {
+		return fmt.Errorf("cluster requires stepdown")
+	}
+	if c.stepdown && c.json && !c.force {
+		return fmt.Errorf("standing down with --json requires --force")
+	}
+
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	report, err := c.metaLeaderReport(nc)
+	if err != nil {
+		return err
+	}
+
+	if !c.stepdown {
+		if c.json {
+			return iu.PrintJSON(report)
+		}
+
+		c.renderMetaLeader(report)
+		return nil
+	}
+
+	if report.Leader == "" {
+		return fmt.Errorf("the JetStream servers do not agree on a current meta leader")
+	}
+
+	if !c.json {
+		c.renderMetaLeader(report)
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really stand down meta leader %s", report.Leader), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	previous := report.Leader
+
+	if c.placementCluster != "" {
+		err = mgr.MetaLeaderStandDown(&api.Placement{Cluster: c.placementCluster})
+	} else {
+		err = mgr.MetaLeaderStandDown(nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	timeout := time.After(c.wait)
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-timeout:
+			return fmt.Errorf("no new meta leader was agreed on within %s", f(c.wait))
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		report, err = c.metaLeaderReport(nc)
+		if err != nil {
+			logErrorf("Could not retrieve the meta leader: %v", err)
+			continue
+		}
+
+		if !report.Agreed || report.Leader == previous {
+			continue
+		}
+
+		report.PreviousLeader = previous
+
+		if c.json {
+			return iu.PrintJSON(report)
+		}
+
+		fmt.Println()
+		fmt.Printf("Previous leader: %s\n", previous)
+		fmt.Printf("     New leader: %s, agreed by all %s servers after %s\n", report.Leader, f(len(report.Servers)), f(time.Since(start).Round(time.Millisecond)))
+
+		return nil
+	}
+}
+
+// metaLeaderReport asks all JetStream servers which server they consider the meta leader
+func (c *SrvMetaLeaderCmd) metaLeaderReport(nc *nats.Conn) (*srvMetaLeaderReport, error) {
+	res, err := doReq(server.JSzOptions{}, "$SYS.REQ.SERVER.PING.JSZ", c.expect, nc)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no JetStream servers responded, ensure the account used has system privileges")
+	}
+
+	var views []*srvMetaLeaderView
+	for _, r := range res {
+		var resp struct {
+			Server server.ServerInfo `json:"server"`
+			Data   *server.JSInfo    `json:"data"`
+			Error  *server.ApiError  `json:"error"`
+		}
+
+		err = json.Unmarshal(r, &resp)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.Error != nil {
+			return nil, fmt.Errorf("server %s failed: %s", resp.Server.Name, resp.Error.Description)
+		}
+
+		if resp.Data == nil || resp.Data.Disabled {
+			continue
+		}
+
+		view := &srvMetaLeaderView{Server: resp.Server.Name, ID: resp.Server.ID, Cluster: resp.Server.Cluster}
+		if resp.Data.Meta != nil {
+			view.Leader = resp.Data.Meta.Leader
+			view.Size = resp.Data.Meta.Size
+		}
+		view.IsLeader = view.Leader != "" && view.Leader == view.Server
+
+		views = append(views, view)
+	}
+
+	if len(views) == 0 {
+		return nil, fmt.Errorf("no servers with JetStream enabled responded")
+	}
+
+	if c.expect > 0 && len(views) != c.expect {
+		return nil, fmt.Errorf("expected %d JetStream servers but %d responded", c.expect, len(views))
+	}
+
+	return newSrvMetaLeaderReport(views), nil
+}
+
+// newSrvMetaLeaderReport determines the leader most servers agree on, Agreed is only true when all servers agree
+func newSrvMetaLeaderReport(views []*srvMetaLeaderView) *srvMetaLeaderReport {
+	sort.Slice(views, func(i, j int) bool {
+		return sortMultiSort(views[j].Cluster, views[i].Cluster, views[i].Server, views[j].Server)
+	})
+
+	votes := map[string]int{}
+	for _, v := range views {
+		if v.Leader != "" {
+			votes[v.Leader]++
+		}
+	}
+
+	report := &srvMetaLeaderReport{Servers: views}
+	for leader, count := range votes {
+		if count > votes[report.Leader] || (count == votes[report.Leader] && leader < report.Leader) {
+			report.Leader = leader
+		}
+	}
+
+	report.Agreed = report.Leader != "" && votes[report.Leader] == len(views)
+
+	return report
+}
+
+func (c *SrvMetaLeaderCmd) renderMetaLeader(report *srvMetaLeaderReport) {
+	table := newTableWriter("JetStream Meta Leader")
+	table.AddHeaders("Server", "Cluster", "Reported Leader", "Cluster Size")
+	for _, v := range report.Servers {
+		name := v.Server
+		if v.IsLeader {
+			name += "*"
+		}
+
+		leader := v.Leader
+		if leader == "" {
+			leader = "none"
+		}
+
+		table.AddRow(name, v.Cluster, leader, f(v.Size))
+	}
+	fmt.Println(table.Render())
+
+	switch {
+	case report.Agreed:
+		fmt.Printf("All %s servers agree that %s is the meta leader\n", f(len(report.Servers)), report.Leader)
+	case report.Leader == "":
+		fmt.Println("No server reports a meta leader, the JetStream cluster might be electing a leader or not be clustered")
+	default:
+		fmt.Printf("Servers disagree on the meta leader, most report %s\n", report.Leader)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+)
+
+func TestSrvMetaLeaderReport(t *testing.T) {
+	report := newSrvMetaLeaderReport([]*srvMetaLeaderView{
+		{Server: "n3", Cluster: "c1", Leader: "n1"},
+		{Server: "n1", Cluster: "c1", Leader: "n1", IsLeader: true},
+		{Server: "n2", Cluster: "c1", Leader: "n1"},
+	})
+	if report.Leader != "n1" || !report.Agreed {
+		t.Fatalf("expected agreed leader n1: %+v", report)
+	}
+	if report.Servers[0].Server != "n1" || report.Servers[2].Server != "n3" {
+		t.Fatalf("servers were not sorted: %v, %v, %v", report.Servers[0].Server, report.Servers[1].Server, report.Servers[2].Server)
+	}
+
+	report = newSrvMetaLeaderReport([]*srvMetaLeaderView{
+		{Server: "n1", Leader: "n2"},
+		{Server: "n2", Leader: "n2", IsLeader: true},
+		{Server: "n3", Leader: ""},
+	})
+	if report.Leader != "n2" || report.Agreed {
+		t.Fatalf("expected disagreed leader n2: %+v", report)
+	}
+
+	report = newSrvMetaLeaderReport([]*srvMetaLeaderView{
+		{Server: "n1", Leader: "n2"},
+		{Server: "n2", Leader: "n1"},
+	})
+	if report.Leader != "n1" || report.Agreed {
+		t.Fatalf("expected tied leader n1: %+v", report)
+	}
+
+	report = newSrvMetaLeaderReport([]*srvMetaLeaderView{{Server: "n1"}, {Server: "n2"}})
+	if report.Leader != "" || report.Agreed {
+		t.Fatalf("expected no leader: %+v", report)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	jsadvisory "github.com/nats-io/jsm.go/api/jetstream/advisory"
+	srvadvisory "github.com/nats-io/jsm.go/api/server/advisory"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type SrvMonitorCmd struct {
+	advisories []string
+
+	slow map[string]int64
+	mu   sync.Mutex
+}
+
+// srvMonitorKinds are the supported advisory kinds, in display order
+var srvMonitorKinds = []string{"jetstream", "auth", "connect", "slow"}
+
+func configureServerMonitorCommand(srv *fisk.CmdClause) {
+	c := &SrvMonitorCmd{slow: map[string]int64{}}
+
+	help := `Prints short summaries of actionable advisories
+
+Supported advisories are:
+
+  jetstream  Stream and Consumer changes, delivery failures and cluster problems
+  auth       Authentication failures
+  connect    Client connections and disconnections
+  slow       Increases in slow consumer counts reported by servers
+
+Server and connection advisories require system account access.
+`
+
+	monitor := srv.Command("monitor", help).Action(c.monitorAction)
+	monitor.Flag("advisories", fmt.Sprintf("Comma separated list of advisories to show (%s)", strings.Join(srvMonitorKinds, ", "))).Default(strings.Join(srvMonitorKinds, ",")).StringsVar(&c.advisories)
+}
+
+func (c *SrvMonitorCmd) kinds() ([]string, error) {
+	seen := map[string]bool{}
+
+	for _, a := range c.advisories {
+		for _, kind := range strings.Split(a, ",") {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			if kind == "" {
+				continue
+			}
+
+			known := false
+			for _, k := range srvMonitorKinds {
+				if k == kind {
+					known = true
+				}
+			}
+			if !known {
+				return nil, fmt.Errorf("unknown advisory %q, valid advisories are %s", kind, strings.Join(srvMonitorKinds, ", "))
+			}
+
+			seen[kind] = true
+		}
+	}
+
+	var kinds []string
+	for _, k := range srvMonitorKinds {
+		if seen[k] {
+			kinds = append(kinds, k)
+		}
+	}
+
+	if len(kinds) == 0 {
+		return nil, fmt.Errorf("no advisories were chosen")
+	}
+
+	return kinds, nil
+}
+
+func (c *SrvMonitorCmd) subjects(kind string) []string {
+	switch kind {
+	case "jetstream":
+		return []string{fmt.Sprintf("%s.>", jsm.EventSubject(api.JSAdvisoryPrefix, opts().Config.JSEventPrefix()))}
+	case "auth":
+		return []string{"$SYS.SERVER.*.CLIENT.AUTH.ERR"}
+	case "connect":
+		return []string{"$SYS.ACCOUNT.*.CONNECT", "$SYS.ACCOUNT.*.DISCONNECT"}
+	case "slow":
+		return []string{"$SYS.SERVER.*.STATSZ", "$SYS.ACCOUNT.*.SERVER.CONNS"}
+	}
+
+	return nil
+}
+
+func (c *SrvMonitorCmd) monitorAction(_ *fisk.ParseContext) error {
+	kinds, err := c.kinds()
+	if err != nil {
+		return err
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	for _, kind := range kinds {
+		kind := kind
+
+		for _, subj := range c.subjects(kind) {
+			_, err = nc.Subscribe(subj, func(m *nats.Msg) {
+				summary := c.summarize(kind, m)
+				if summary == "" {
+					return
+				}
+
+				c.mu.Lock()
+				fmt.Printf("[%s] %-9s %s\n", time.Now().Format("15:04:05"), strings.ToUpper(kind), summary)
+				c.mu.Unlock()
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = nc.Flush()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Monitoring %s advisories", strings.Join(kinds, ", "))
+
+	<-ctx.Done()
+
+	return nil
+}
+
+// summarize produces a one line description of an advisory, empty when nothing should be shown
+func (c *SrvMonitorCmd) summarize(kind string, m *nats.Msg) string {
+	if kind == "slow" {
+		return c.summarizeSlow(m)
+	}
+
+	_, event, err := api.ParseMessage(m.Data)
+	if err != nil {
+		return fmt.Sprintf("invalid advisory received on %s: %v", m.Subject, err)
+	}
+
+	switch e := event.(type) {
+	case *jsadvisory.JSStreamActionAdvisoryV1:
+		return fmt.Sprintf("Stream %s %s", e.Stream, c.action(e.Action))
+
+	case *jsadvisory.JSConsumerActionAdvisoryV1:
+		return fmt.Sprintf("Consumer %s > %s %s", e.Stream, e.Consumer, c.action(e.Action))
+
+	case *jsadvisory.ConsumerDeliveryExceededAdvisoryV1:
+		return fmt.Sprintf("Consumer %s > %s exceeded maximum deliveries for stream sequence %d after %d deliveries", e.Stream, e.Consumer, e.StreamSeq, e.Deliveries)
+
+	case *jsadvisory.JSConsumerDeliveryTerminatedAdvisoryV1:
+		reason := ""
+		if e.Reason != "" {
+			reason = fmt.Sprintf(": %s", e.Reason)
+		}
+		return fmt.Sprintf("Consumer %s > %s terminated delivery of stream sequence %d%s", e.Stream, e.Consumer, e.StreamSeq, reason)
+
+	case *jsadvisory.JSStreamQuorumLostV1:
+		return fmt.Sprintf("Stream %s lost quorum", e.Stream)
+
+	case *jsadvisory.JSConsumerQuorumLostV1:
+		return fmt.Sprintf("Consumer %s > %s lost quorum", e.Stream, e.Consumer)
+
+	case *jsadvisory.JSServerOutOfSpaceAdvisoryV1:
+		if e.Stream != "" {
+			return fmt.Sprintf("Server %s in cluster %s ran out of space while storing stream %s", e.Server, e.Cluster, e.Stream)
+		}
+		return fmt.Sprintf("Server %s in cluster %s ran out of space", e.Server, e.Cluster)
+
+	case *jsadvisory.JSStreamLeaderElectedV1:
+		return fmt.Sprintf("Stream %s elected new leader %s", e.Stream, e.Leader)
+
+	case *jsadvisory.JSConsumerLeaderElectedV1:
+		return fmt.Sprintf("Consumer %s > %s elected new leader %s", e.Stream, e.Consumer, e.Leader)
+
+	case *srvadvisory.ConnectEventMsgV1:
+		return fmt.Sprintf("Client %s connected to %s", c.client(e.Client), e.Server.Name)
+
+	case *srvadvisory.DisconnectEventMsgV1:
+		if kind == "auth" {
+			return fmt.Sprintf("Client %s failed to authenticate on %s: %s", c.client(e.Client), e.Server.Name, e.Reason)
+		}
+		return fmt.Sprintf("Client %s disconnected from %s: %s", c.client(e.Client), e.Server.Name, e.Reason)
+	}
+
+	// api, snapshot, restore and other housekeeping advisories are not shown, use nats events for those
+	return ""
+}
+
+// summarizeSlow reports when a server or account slow consumer count increases, the first report from each only records a baseline
+func (c *SrvMonitorCmd) summarizeSlow(m *nats.Msg) string {
+	var key, description string
+	var count int64
+
+	if strings.HasPrefix(m.Subject, "$SYS.SERVER.") {
+		var stats server.ServerStatsMsg
+		err := json.Unmarshal(m.Data, &stats)
+		if err != nil {
+			return ""
+		}
+
+		key = "server:" + stats.Server.ID
+		description = fmt.Sprintf("Server %s", stats.Server.Name)
+		count = stats.Stats.SlowConsumers
+	} else {
+		var conns srvadvisory.AccountConnectionsV1
+		err := json.Unmarshal(m.Data, &conns)
+		if err != nil {
+			return ""
+		}
+
+		key = fmt.Sprintf("account:%s:%s", conns.Account, conns.Server.ID)
+		description = fmt.Sprintf("Account %s on %s", conns.Account, conns.Server.Name)
+		count = conns.SlowConsumers
+	}
+
+	c.mu.Lock()
+	prev, seen := c.slow[key]
+	c.slow[key] = count
+	c.mu.Unlock()
+
+	if !seen || count <= prev {
+		return ""
+	}
+
+	return fmt.Sprintf("%s had %s new slow consumers, %s in total", description, f(count-prev), f(count))
+}
+
+func (c *SrvMonitorCmd) action(a jsadvisory.ActionAdvisoryTypeV1) string {
+	switch a {
+	case jsadvisory.CreateEvent:
+		return "created"
+	case jsadvisory.DeleteEvent:
+		return "deleted"
+	case jsadvisory.ModifyEvent:
+		return "modified"
+	default:
+		return string(a)
+	}
+}
+
+func (c *SrvMonitorCmd) client(ci srvadvisory.ClientInfoV1) string {
+	parts := []string{fmt.Sprintf("%d", ci.ID)}
+	if ci.Name != "" {
+		parts = append(parts, fmt.Sprintf("name=%s", ci.Name))
+	}
+	if ci.User != "" {
+		parts = append(parts, fmt.Sprintf("user=%s", ci.User))
+	}
+	if ci.Account != "" {
+		parts = append(parts, fmt.Sprintf("account=%s", ci.Account))
+	}
+	if ci.Host != "" {
+		parts = append(parts, fmt.Sprintf("host=%s", ci.Host))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,66 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSrvMonitorKinds(t *testing.T) {
+	c := &SrvMonitorCmd{advisories: []string{"connect,JetStream", "auth"}}
+	kinds, err := c.kinds()
+	if err != nil {
+		t.Fatalf("kinds failed: %v", err)
+	}
+	if len(kinds) != 3 || kinds[0] != "jetstream" || kinds[1] != "auth" || kinds[2] != "connect" {
+		t.Fatalf("unexpected kinds %v", kinds)
+	}
+
+	c.advisories = []string{"jetstream,other"}
+	_, err = c.kinds()
+	if err == nil {
+		t.Fatalf("expected an error for unknown advisories")
+	}
+}
+
+func TestSrvMonitorSummarize(t *testing.T) {
+	c := &SrvMonitorCmd{slow: map[string]int64{}}
+
+	cases := []struct {
+		kind    string
+		subject string
+		data    string
+		expect  string
+	}{
+		{"jetstream", "$JS.EVENT.ADVISORY.STREAM.CREATED.ORDERS", `{"type":"io.nats.jetstream.advisory.v1.stream_action","stream":"ORDERS","action":"create"}`, "Stream ORDERS created"},
+		{"jetstream", "$JS.EVENT.ADVISORY.CONSUMER.DELETED.ORDERS.NEW", `{"type":"io.nats.jetstream.advisory.v1.consumer_action","stream":"ORDERS","consumer":"NEW","action":"delete"}`, "Consumer ORDERS > NEW deleted"},
+		{"jetstream", "$JS.EVENT.ADVISORY.API", `{"type":"io.nats.jetstream.advisory.v1.api_audit","subject":"$JS.API.INFO"}`, ""},
+		{"auth", "$SYS.SERVER.X.CLIENT.AUTH.ERR", `{"type":"io.nats.server.advisory.v1.client_disconnect","server":{"name":"n1"},"client":{"id":5,"user":"bob"},"reason":"Authentication Failure"}`, "Client 5 user=bob failed to authenticate on n1: Authentication Failure"},
+		{"connect", "$SYS.ACCOUNT.A.CONNECT", `{"type":"io.nats.server.advisory.v1.client_connect","server":{"name":"n1"},"client":{"id":6,"name":"app","acc":"A"}}`, "Client 6 name=app account=A connected to n1"},
+		{"slow", "$SYS.ACCOUNT.A.SERVER.CONNS", `{"type":"io.nats.server.advisory.v1.account_connections","server":{"name":"n1","id":"N1"},"acc":"A","slow_consumers":1}`, ""},
+		{"slow", "$SYS.ACCOUNT.A.SERVER.CONNS", `{"type":"io.nats.server.advisory.v1.account_connections","server":{"name":"n1","id":"N1"},"acc":"A","slow_consumers":1}`, ""},
+		{"slow", "$SYS.ACCOUNT.A.SERVER.CONNS", `{"type":"io.nats.server.advisory.v1.account_connections","server":{"name":"n1","id":"N1"},"acc":"A","slow_consumers":4}`, "Account A on n1 had 3 new slow consumers, 4 in total"},
+		{"slow", "$SYS.SERVER.N1.STATSZ", `{"server":{"name":"n1","id":"N1"},"statsz":{"slow_consumers":2}}`, ""},
+		{"slow", "$SYS.SERVER.N1.STATSZ", `{"server":{"name":"n1","id":"N1"},"statsz":{"slow_consumers":3}}`, "Server n1 had 1 new slow consumers, 3 in total"},
+	}
+
+	for _, tc := range cases {
+		res := c.summarize(tc.kind, &nats.Msg{Subject: tc.subject, Data: []byte(tc.data)})
+		if res != tc.expect {
+			t.Fatalf("expected %q got %q for %s", tc.expect, res, tc.data)
+		}
+	}
+}
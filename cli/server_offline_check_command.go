@@ -0,0 +1,263 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+type SrvOfflineCheckCmd struct {
+	expect int
+}
+
+// srvOfflineServer is a server known to be part of the cluster
+type srvOfflineServer struct {
+	name    string
+	cluster string
+	rtt     time.Duration
+	online  bool
+}
+
+// srvOfflineCheck tracks the servers that responded and the servers known from the JetStream meta cluster
+type srvOfflineCheck struct {
+	servers  map[string]*srvOfflineServer
+	metaSize int
+	mu       sync.Mutex
+}
+
+func configureServerOfflineCheckCommand(srv *fisk.CmdClause) {
+	c := &SrvOfflineCheckCmd{}
+
+	help := `Reports servers in the cluster that did not respond
+
+All servers are asked for their JetStream state, servers that do not respond
+within --timeout are offline. Servers are known from their responses and the
+peers of the JetStream meta cluster, use --expect for clusters without
+JetStream.
+
+Exits with code 0 when all servers responded, 1 when some are offline and 2
+when a majority is offline.
+`
+
+	check := srv.Command("offline-check", help).Action(c.offlineCheckAction)
+	check.Flag("expect", "How many servers to expect").PlaceHolder("SERVERS").IntVar(&c.expect)
+}
+
+// srvOfflineExitCode is 0 when no servers are offline, 1 when some are and 2 when a majority is
+func srvOfflineExitCode(offline int, total int) int {
+	switch {
+	case offline == 0:
+		return 0
+	case offline*2 > total:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func newSrvOfflineCheck() *srvOfflineCheck {
+	return &srvOfflineCheck{servers: map[string]*srvOfflineServer{}}
+}
+
+// known adds a server that belongs to the cluster
+func (o *srvOfflineCheck) known(name string) *srvOfflineServer {
+	s, ok := o.servers[name]
+	if !ok {
+		s = &srvOfflineServer{name: name}
+		o.servers[name] = s
+	}
+
+	return s
+}
+
+// observe records a server response and the meta cluster peers it reports
+func (o *srvOfflineCheck) observe(info server.ServerInfo, jsi *server.JSInfo, rtt time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.known(info.Name)
+	s.cluster = info.Cluster
+	s.rtt = rtt
+	s.online = true
+
+	if jsi == nil || jsi.Meta == nil {
+		return
+	}
+
+	if jsi.Meta.Size > o.metaSize {
+		o.metaSize = jsi.Meta.Size
+	}
+	if jsi.Meta.Leader != "" {
+		o.known(jsi.Meta.Leader)
+	}
+	for _, peer := range jsi.Meta.Replicas {
+		o.known(peer.Name)
+	}
+}
+
+// expected is how many servers are known to be in the cluster
+func (o *srvOfflineCheck) expected(expect int) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return max(expect, o.metaSize, len(o.servers))
+}
+
+// complete reports if all expected servers responded, without knowing how many servers to expect responses are
+// received until the timeout
+func (o *srvOfflineCheck) complete(expect int) bool {
+	o.mu.Lock()
+	known := expect > 0 || o.metaSize > 0
+	o.mu.Unlock()
+
+	return known && o.responded() >= o.expected(expect)
+}
+
+// responded is how many servers responded
+func (o *srvOfflineCheck) responded() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cnt := 0
+	for _, s := range o.servers {
+		if s.online {
+			cnt++
+		}
+	}
+
+	return cnt
+}
+
+func (c *SrvOfflineCheckCmd) offlineCheckAction(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	check := newSrvOfflineCheck()
+
+	ctx, cancel := context.WithTimeout(ctx, opts().Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	sub, err := nc.Subscribe(nc.NewRespInbox(), func(m *nats.Msg) {
+		if m.Header.Get("Status") == "503" {
+			errs <- fmt.Errorf("server request failed, ensure the account used has system privileges and appropriate permissions")
+			return
+		}
+
+		resp := struct {
+			Server server.ServerInfo `json:"server"`
+			Data   *server.JSInfo    `json:"data"`
+		}{}
+		err := json.Unmarshal(m.Data, &resp)
+		if err != nil {
+			logErrorf("Could not decode response: %s", err)
+			return
+		}
+
+		check.observe(resp.Server, resp.Data, time.Since(start))
+		if check.complete(c.expect) {
+			cancel()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	err = nc.PublishRequest("$SYS.REQ.SERVER.PING.JSZ", sub.Subject, []byte("{}"))
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err = <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	sub.Unsubscribe()
+
+	expected := check.expected(c.expect)
+	responded := check.responded()
+	if responded == 0 {
+		return fmt.Errorf("no responses received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	c.renderOfflineCheck(check, expected, responded)
+
+	code := srvOfflineExitCode(expected-responded, expected)
+	if code > 0 {
+		os.Exit(code)
+	}
+
+	return nil
+}
+
+func (c *SrvOfflineCheckCmd) renderOfflineCheck(check *srvOfflineCheck, expected int, responded int) {
+	var servers []*srvOfflineServer
+	var offline []string
+	for _, s := range check.servers {
+		servers = append(servers, s)
+		if !s.online {
+			offline = append(offline, s.name)
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].online != servers[j].online {
+			return !servers[i].online
+		}
+		return servers[i].name < servers[j].name
+	})
+	sort.Strings(offline)
+
+	table := newTableWriter(fmt.Sprintf("%d of %d servers responded within %v", responded, expected, opts().Timeout))
+	table.AddHeaders("Name", "Cluster", "Status", "Response Time")
+	for _, s := range servers {
+		if s.online {
+			table.AddRow(s.name, s.cluster, "online", f(s.rtt))
+		} else {
+			table.AddRow(s.name, s.cluster, "offline", "")
+		}
+	}
+	fmt.Println(table.Render())
+
+	unnamed := expected - len(servers)
+	switch {
+	case responded == expected:
+		fmt.Printf("All %d servers are online\n", expected)
+	case unnamed > 0 && len(offline) > 0:
+		fmt.Printf("%d servers are offline: %s and %d servers without a known name\n", expected-responded, strings.Join(offline, ", "), unnamed)
+	case unnamed > 0:
+		fmt.Printf("%d servers without a known name are offline\n", unnamed)
+	default:
+		fmt.Printf("%d servers are offline: %s\n", expected-responded, strings.Join(offline, ", "))
+	}
+
+	if srvOfflineExitCode(expected-responded, expected) == 2 {
+		fmt.Println("A majority of servers is offline, the cluster has no quorum")
+	}
+}
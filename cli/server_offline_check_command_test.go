@@ -0,0 +1,63 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestSrvOfflineExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		offline int
+		total   int
+		expect  int
+	}{{0, 3, 0}, {1, 3, 1}, {2, 3, 2}, {3, 3, 2}, {2, 4, 1}, {3, 5, 2}} {
+		code := srvOfflineExitCode(tc.offline, tc.total)
+		if code != tc.expect {
+			t.Fatalf("expected %d for %d of %d offline got %d", tc.expect, tc.offline, tc.total, code)
+		}
+	}
+}
+
+func TestSrvOfflineCheck(t *testing.T) {
+	check := newSrvOfflineCheck()
+
+	check.observe(server.ServerInfo{Name: "n2", Cluster: "c1"}, &server.JSInfo{Meta: &server.MetaClusterInfo{Leader: "n1", Size: 3}}, time.Millisecond)
+	if check.complete(0) || check.expected(0) != 3 || check.responded() != 1 {
+		t.Fatalf("expected 1 of 3 servers to have responded got %d of %d", check.responded(), check.expected(0))
+	}
+
+	check.observe(server.ServerInfo{Name: "n1", Cluster: "c1"}, &server.JSInfo{Meta: &server.MetaClusterInfo{Leader: "n1", Size: 3, Replicas: []*server.PeerInfo{{Name: "n2"}, {Name: "n3", Offline: true}}}}, time.Millisecond)
+	if check.complete(0) || check.responded() != 2 || check.servers["n3"] == nil || check.servers["n3"].online {
+		t.Fatalf("expected n3 to be known and offline")
+	}
+
+	check.observe(server.ServerInfo{Name: "n3", Cluster: "c1"}, nil, time.Millisecond)
+	if !check.complete(0) {
+		t.Fatalf("expected all servers to have responded")
+	}
+
+	if check.complete(4) || check.expected(4) != 4 {
+		t.Fatalf("expected to wait for 4 servers")
+	}
+
+	check = newSrvOfflineCheck()
+	check.observe(server.ServerInfo{Name: "n1"}, nil, time.Millisecond)
+	if check.complete(0) {
+		t.Fatalf("expected to wait for the timeout without knowing how many servers to expect")
+	}
+}
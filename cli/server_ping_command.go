@@ -68,7 +68,7 @@ func (c *SrvPingCmd) ping(_ *fisk.ParseContext) error {
 		ssm := &server.ServerStatsMsg{}
 		err = json.Unmarshal(msg.Data, ssm)
 		if err != nil {
-			log.Printf("Could not decode response: %s", err)
+			logErrorf("Could not decode response: %s", err)
 			os.Exit(1)
 		}
 
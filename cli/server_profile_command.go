@@ -0,0 +1,145 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+// srvProfileTypes are the profiles the server can produce over HTTP, cpu is sampled over a duration while the others are snapshots
+var srvProfileTypes = []string{"cpu", "heap", "allocs", "goroutine", "mutex", "block", "threadcreate"}
+
+// srvProfileSlack is how much longer than the profile duration the request may take
+const srvProfileSlack = 30 * time.Second
+
+type SrvProfileCmd struct {
+	url      string
+	kind     string
+	duration time.Duration
+	output   string
+}
+
+func configureServerProfileCommand(srv *fisk.CmdClause) {
+	c := &SrvProfileCmd{}
+
+	help := `Captures a profile from the HTTP profiling endpoint of a server
+
+The profile is fetched from /debug/pprof on the given URL and saved in the
+binary pprof format for use with go tool pprof. Profiling has to be enabled
+on the server, usually by setting prof_port in its configuration.
+
+CPU profiles are sampled over the given duration, other profiles are
+snapshots taken immediately.
+
+To capture profiles using the system account instead of HTTP use
+nats server request profile.
+`
+
+	profile := srv.Command("profile", help).Action(c.profileAction)
+	profile.Flag("url", "URL of the server profiling port").Default("http://localhost:8222").StringVar(&c.url)
+	profile.Flag("type", fmt.Sprintf("The kind of profile to capture (%s)", strings.Join(srvProfileTypes, ", "))).Default("cpu").EnumVar(&c.kind, srvProfileTypes...)
+	profile.Flag("duration", "How long to sample CPU profiles for").Default("30s").DurationVar(&c.duration)
+	profile.Flag("output", "File to write the profile to, defaults to TYPE.pprof").Short('o').PlaceHolder("FILE").StringVar(&c.output)
+}
+
+// profileURL is the address of the profile, CPU profiles take a duration in whole seconds
+func (c *SrvProfileCmd) profileURL() (string, error) {
+	u, err := url.Parse(c.url)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q: %w", c.url, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid url %q: only http and https are supported", c.url)
+	}
+
+	u.RawQuery = ""
+	path := strings.TrimSuffix(u.Path, "/")
+
+	if c.kind == "cpu" {
+		u.Path = path + "/debug/pprof/profile"
+		u.RawQuery = url.Values{"seconds": []string{fmt.Sprintf("%d", int(c.duration.Round(time.Second)/time.Second))}}.Encode()
+	} else {
+		u.Path = path + "/debug/pprof/" + c.kind
+	}
+
+	return u.String(), nil
+}
+
+func (c *SrvProfileCmd) profileAction(_ *fisk.ParseContext) error {
+	if c.kind == "cpu" && c.duration < time.Second {
+		return fmt.Errorf("duration must be at least 1s")
+	}
+
+	if c.output == "" {
+		c.output = c.kind + ".pprof"
+	}
+
+	target, err := c.profileURL()
+	if err != nil {
+		return err
+	}
+
+	timeout := srvProfileSlack
+	if c.kind == "cpu" {
+		timeout += c.duration
+		fmt.Printf("Capturing a %s CPU profile from %s\n", f(c.duration), c.url)
+	}
+
+	profile, err := c.fetch(target, timeout)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(c.output, profile, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+
+	fmt.Printf("Wrote %s %s profile to %s\n", fiBytes(uint64(len(profile))), c.kind, c.output)
+
+	return nil
+}
+
+func (c *SrvProfileCmd) fetch(target string, timeout time.Duration) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the profile, is profiling enabled on the server using prof_port: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("%s was not found, is profiling enabled on the server using prof_port", target)
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s returned %s: %s", target, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,46 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSrvProfileURL(t *testing.T) {
+	for _, tc := range []struct {
+		url      string
+		kind     string
+		expected string
+	}{
+		{"http://localhost:8222", "cpu", "http://localhost:8222/debug/pprof/profile?seconds=30"},
+		{"http://localhost:8222/", "heap", "http://localhost:8222/debug/pprof/heap"},
+		{"https://nats1.example.net:65432/nats?x=1", "goroutine", "https://nats1.example.net:65432/nats/debug/pprof/goroutine"},
+	} {
+		c := &SrvProfileCmd{url: tc.url, kind: tc.kind, duration: 30 * time.Second}
+		got, err := c.profileURL()
+		if err != nil {
+			t.Fatalf("profileURL failed: %v", err)
+		}
+		if got != tc.expected {
+			t.Fatalf("expected %q got %q", tc.expected, got)
+		}
+	}
+
+	c := &SrvProfileCmd{url: "localhost:8222", kind: "heap"}
+	_, err := c.profileURL()
+	if err == nil {
+		t.Fatalf("expected an error for an url without a scheme")
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+)
+
+type SrvRebalanceCmd struct {
+	dryRun bool
+	wait   time.Duration
+	force  bool
+}
+
+// srvRebalanceStream is a clustered Stream with its leader and the peers able to take over leadership
+type srvRebalanceStream struct {
+	name   string
+	leader string
+	peers  []string
+}
+
+// srvRebalanceStep is a leader step down expected to move leadership of a Stream to the target server
+type srvRebalanceStep struct {
+	stream string
+	from   string
+	target string
+}
+
+// srvRebalancePlan is the current distribution of Stream leaders and the step downs expected to even it out
+type srvRebalancePlan struct {
+	leaders map[string]int
+	ideal   int
+	steps   []*srvRebalanceStep
+}
+
+func configureServerRebalanceCommand(srv *fisk.CmdClause) {
+	c := &SrvRebalanceCmd{}
+
+	help := `Redistributes Stream leaders evenly over the servers in the cluster
+
+All clustered Streams of the account used are inspected and leaders are
+stepped down one at a time on servers leading more Streams than their fair
+share, waiting for a new leader to be elected between each.
+
+The server elects a new leader from the current replicas, it might not be
+the server the plan expects, running the command again moves the remaining
+leaders.
+`
+
+	rebalance := srv.Command("rebalance", help).Action(c.rebalanceAction)
+	rebalance.Flag("dry-run", "Show the step downs that would be performed without performing them").UnNegatableBoolVar(&c.dryRun)
+	rebalance.Flag("wait", "How long to wait for a new leader to be elected after each step down").Default("10s").DurationVar(&c.wait)
+	rebalance.Flag("force", "Rebalance without prompting").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+// newSrvRebalancePlan determines the step downs needed so no server leads more than its fair share of streams,
+// leadership can only move to current replicas of a Stream
+func newSrvRebalancePlan(streams []*srvRebalanceStream) *srvRebalancePlan {
+	plan := &srvRebalancePlan{leaders: map[string]int{}}
+
+	for _, s := range streams {
+		plan.leaders[s.leader]++
+		for _, peer := range s.peers {
+			plan.leaders[peer] += 0
+		}
+	}
+
+	if len(plan.leaders) == 0 {
+		return plan
+	}
+
+	plan.ideal = (len(streams) + len(plan.leaders) - 1) / len(plan.leaders)
+
+	sorted := make([]*srvRebalanceStream, len(streams))
+	copy(sorted, streams)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	for _, s := range sorted {
+		if plan.leaders[s.leader] <= plan.ideal {
+			continue
+		}
+
+		target := ""
+		for _, peer := range s.peers {
+			if plan.leaders[peer] >= plan.ideal {
+				continue
+			}
+			if target == "" || plan.leaders[peer] < plan.leaders[target] || (plan.leaders[peer] == plan.leaders[target] && peer < target) {
+				target = peer
+			}
+		}
+		if target == "" {
+			continue
+		}
+
+		plan.leaders[s.leader]--
+		plan.leaders[target]++
+		plan.steps = append(plan.steps, &srvRebalanceStep{stream: s.name, from: s.leader, target: target})
+	}
+
+	// the plan assumed every step succeeds, report the distribution as it is now
+	for _, step := range plan.steps {
+		plan.leaders[step.from]++
+		plan.leaders[step.target]--
+	}
+
+	return plan
+}
+
+func (c *SrvRebalanceCmd) rebalanceAction(_ *fisk.ParseContext) error {
+	_, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	streams, loaded, err := c.clusteredStreams(mgr)
+	if err != nil {
+		return err
+	}
+
+	if len(streams) == 0 {
+		fmt.Println("No clustered Streams with a leader found")
+		return nil
+	}
+
+	plan := newSrvRebalancePlan(streams)
+	c.renderPlan(plan)
+
+	if len(plan.steps) == 0 {
+		fmt.Println("Stream leaders are evenly distributed, no step downs are needed")
+		return nil
+	}
+
+	if c.dryRun {
+		fmt.Printf("Dry run, %d Stream leaders would be stepped down one at a time\n", len(plan.steps))
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really step down the leaders of %d Streams", len(plan.steps)), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	steps := len(plan.steps)
+	failed := 0
+	for _, step := range plan.steps {
+		leader, err := c.stepDown(loaded[step.stream], step.from)
+		if err != nil {
+			logErrorf("Could not step down the leader of Stream %s: %v", step.stream, err)
+			failed++
+			continue
+		}
+
+		fmt.Printf("Stream %s leader moved from %s to %s\n", step.stream, step.from, leader)
+	}
+
+	// elections might have chosen other servers than the plan expected
+	streams, _, err = c.clusteredStreams(mgr)
+	if err != nil {
+		return err
+	}
+
+	plan = newSrvRebalancePlan(streams)
+	fmt.Println()
+	c.renderPlan(plan)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d step downs failed", failed, steps)
+	}
+
+	if len(plan.steps) > 0 {
+		fmt.Printf("%d Streams can still be moved, run the rebalance again to move them\n", len(plan.steps))
+	}
+
+	return nil
+}
+
+// clusteredStreams finds all Streams with a leader and at least one current replica to hand leadership to
+func (c *SrvRebalanceCmd) clusteredStreams(mgr *jsm.Manager) ([]*srvRebalanceStream, map[string]*jsm.Stream, error) {
+	var streams []*srvRebalanceStream
+	loaded := map[string]*jsm.Stream{}
+
+	found, _, err := mgr.Streams(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, stream := range found {
+		nfo, err := stream.LatestInformation()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if nfo.Cluster == nil || nfo.Cluster.Leader == "" || len(nfo.Cluster.Replicas) == 0 {
+			continue
+		}
+
+		s := &srvRebalanceStream{name: stream.Name(), leader: nfo.Cluster.Leader}
+		for _, peer := range nfo.Cluster.Replicas {
+			if peer.Current && !peer.Offline && !peer.Observer {
+				s.peers = append(s.peers, peer.Name)
+			}
+		}
+
+		streams = append(streams, s)
+		loaded[s.name] = stream
+	}
+
+	return streams, loaded, nil
+}
+
+// stepDown asks the leader of stream to step down and waits for another server to be elected
+func (c *SrvRebalanceCmd) stepDown(stream *jsm.Stream, leader string) (string, error) {
+	err := stream.LeaderStepDown()
+	if err != nil {
+		return "", err
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(c.wait)
+
+	for {
+		select {
+		case <-ticker.C:
+			nfo, err := stream.Information()
+			if err != nil {
+				logErrorf("Failed to retrieve Stream State: %s", err)
+				continue
+			}
+
+			if nfo.Cluster != nil && nfo.Cluster.Leader != "" && nfo.Cluster.Leader != leader {
+				return nfo.Cluster.Leader, nil
+			}
+
+		case <-timeout:
+			return "", fmt.Errorf("no new leader elected within %v", c.wait)
+		}
+	}
+}
+
+func (c *SrvRebalanceCmd) renderPlan(plan *srvRebalancePlan) {
+	servers := mapKeys(plan.leaders)
+	sort.Slice(servers, func(i, j int) bool {
+		return sortMultiSort(plan.leaders[servers[i]], plan.leaders[servers[j]], servers[i], servers[j])
+	})
+
+	expected := map[string]int{}
+	for server, cnt := range plan.leaders {
+		expected[server] = cnt
+	}
+	for _, step := range plan.steps {
+		expected[step.from]--
+		expected[step.target]++
+	}
+
+	table := newTableWriter(fmt.Sprintf("At most %d Stream leaders per server", plan.ideal))
+	table.AddHeaders("Server", "Leaders", "After Rebalance")
+	for _, server := range servers {
+		table.AddRow(server, f(plan.leaders[server]), f(expected[server]))
+	}
+	fmt.Println(table.Render())
+
+	if len(plan.steps) == 0 {
+		return
+	}
+
+	table = newTableWriter(fmt.Sprintf("%d leader step downs needed", len(plan.steps)))
+	table.AddHeaders("Stream", "Current Leader", "Expected Leader")
+	for _, step := range plan.steps {
+		table.AddRow(step.stream, step.from, step.target)
+	}
+	fmt.Println(table.Render())
+}
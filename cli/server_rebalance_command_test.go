@@ -0,0 +1,67 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSrvRebalancePlan(t *testing.T) {
+	var streams []*srvRebalanceStream
+	for _, name := range []string{"F", "E", "D", "C", "B", "A"} {
+		streams = append(streams, &srvRebalanceStream{name: name, leader: "n1", peers: []string{"n2", "n3"}})
+	}
+
+	plan := newSrvRebalancePlan(streams)
+	if plan.ideal != 2 {
+		t.Fatalf("expected an ideal of 2 got %d", plan.ideal)
+	}
+	if plan.leaders["n1"] != 6 || plan.leaders["n2"] != 0 || plan.leaders["n3"] != 0 {
+		t.Fatalf("expected the current distribution got %v", plan.leaders)
+	}
+
+	var steps []string
+	for _, step := range plan.steps {
+		steps = append(steps, fmt.Sprintf("%s %s>%s", step.stream, step.from, step.target))
+	}
+	expected := "[A n1>n2 B n1>n3 C n1>n2 D n1>n3]"
+	if fmt.Sprint(steps) != expected {
+		t.Fatalf("expected steps %s got %v", expected, steps)
+	}
+
+	t.Run("balanced", func(t *testing.T) {
+		plan := newSrvRebalancePlan([]*srvRebalanceStream{
+			{name: "A", leader: "n1", peers: []string{"n2", "n3"}},
+			{name: "B", leader: "n2", peers: []string{"n1", "n3"}},
+			{name: "C", leader: "n3", peers: []string{"n1", "n2"}},
+			{name: "D", leader: "n1", peers: []string{"n2", "n3"}},
+		})
+		if len(plan.steps) != 0 {
+			t.Fatalf("expected no steps got %d", len(plan.steps))
+		}
+	})
+
+	t.Run("no current peers", func(t *testing.T) {
+		plan := newSrvRebalancePlan([]*srvRebalanceStream{
+			{name: "A", leader: "n1", peers: []string{"n2"}},
+			{name: "B", leader: "n1"},
+			{name: "C", leader: "n1"},
+			{name: "D", leader: "n1"},
+		})
+		if len(plan.steps) != 1 || plan.steps[0].stream != "A" || plan.steps[0].target != "n2" {
+			t.Fatalf("expected only A to move to n2 got %d steps", len(plan.steps))
+		}
+	})
+}
@@ -17,13 +17,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"sort"
+	"time"
 
 	iu "github.com/nats-io/natscli/internal/util"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/expr-lang/expr"
 	"github.com/expr-lang/expr/vm"
 	"github.com/fatih/color"
@@ -49,6 +50,9 @@ type SrvReportCmd struct {
 	stateFilter             string
 	filterReason            string
 	skipDiscoverClusterSize bool
+	queue                   string
+	queueSubject            string
+	queueInterval           time.Duration
 }
 
 type srvReportAccountInfo struct {
@@ -103,6 +107,20 @@ func configureServerReportCommand(srv *fisk.CmdClause) {
 	jsz.Flag("sort", "Sort by a specific property (name,cluster,streams,consumers,msgs,mbytes,mem,file,api,err").Default("cluster").EnumVar(&c.sort, "name", "cluster", "streams", "consumers", "msgs", "mbytes", "bytes", "mem", "file", "store", "api", "err")
 	jsz.Flag("compact", "Compact server names").Default("true").BoolVar(&c.compact)
 
+	queueHelp := `Reports on the members of a queue group
+
+Shows every subscription in the queue group across all servers along with the
+connection it belongs to. Message counts are sampled twice, interval apart,
+to show which members received the messages published during the interval.
+`
+	queue := report.Command("queue-group", queueHelp).Alias("queue").Alias("qgroup").Action(c.reportQueue)
+	queue.Arg("group", "The queue group to report on").Required().StringVar(&c.queue)
+	addFilterOpts(queue)
+	queue.Flag("account", "Limit the report to a specific account").StringVar(&c.account)
+	queue.Flag("subject", "Only include members that would receive messages published to this subject").StringVar(&c.queueSubject)
+	queue.Flag("interval", "Time between the two samples used to calculate message distribution, 0 disables sampling").Default("5s").DurationVar(&c.queueInterval)
+	queue.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
 	cpu := report.Command("cpu", "Reports on CPU uage").Action(c.reportCPU)
 	addFilterOpts(cpu)
 	cpu.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
@@ -351,10 +369,10 @@ func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
 		row = append(row,
 			f(rStreams),
 			f(rConsumers),
-			f(rMessages),
-			humanize.IBytes(rBytes),
-			humanize.IBytes(jss.Memory),
-			humanize.IBytes(jss.Store),
+			fCount(rMessages),
+			fiBytes(rBytes),
+			fiBytes(jss.Memory),
+			fiBytes(jss.Store),
 			f(jss.API.Total),
 			errCol,
 		)
@@ -366,7 +384,7 @@ func (c *SrvReportCmd) reportJetStream(_ *fisk.ParseContext) error {
 	if renderDomain {
 		row = append(row, "")
 	}
-	row = append(row, f(streams), f(consumers), f(msgs), humanize.IBytes(bytes), humanize.IBytes(memory), humanize.IBytes(store), f(apiTotal), f(apiErr))
+	row = append(row, f(streams), f(consumers), fCount(msgs), fiBytes(bytes), fiBytes(memory), fiBytes(store), f(apiTotal), f(apiErr))
 	table.AddFooter(row...)
 
 	fmt.Print(table.Render())
@@ -459,7 +477,7 @@ func (c *SrvReportCmd) reportAccount(_ *fisk.ParseContext) error {
 
 		if len(account.ConnInfo) > 0 {
 			report := account.ConnInfo
-			c.renderConnections(report)
+			c.renderConnections(os.Stdout, report)
 		}
 		return nil
 	}
@@ -504,7 +522,7 @@ func (c *SrvReportCmd) reportAccount(_ *fisk.ParseContext) error {
 	table.AddHeaders("Account", "Connections", "In Msgs", "Out Msgs", "In Bytes", "Out Bytes", "Subs")
 
 	for _, acct := range accounts {
-		table.AddRow(acct.Account, f(acct.Connections), f(acct.InMsgs), f(acct.OutMsgs), humanize.IBytes(uint64(acct.InBytes)), humanize.IBytes(uint64(acct.OutBytes)), f(acct.Subs))
+		table.AddRow(acct.Account, f(acct.Connections), fCount(acct.InMsgs), fCount(acct.OutMsgs), fiBytes(uint64(acct.InBytes)), fiBytes(uint64(acct.OutBytes)), f(acct.Subs))
 	}
 
 	fmt.Print(table.Render())
@@ -575,7 +593,10 @@ func (c *SrvReportCmd) reportConnections(_ *fisk.ParseContext) error {
 		return nil
 	}
 
-	c.renderConnections(conns)
+	out := newPagedWriter()
+	defer out.Close()
+
+	c.renderConnections(out, conns)
 
 	return nil
 }
@@ -609,7 +630,7 @@ func (c *SrvReportCmd) sortConnections(conns []connInfo) {
 	})
 }
 
-func (c *SrvReportCmd) renderConnections(report []connInfo) {
+func (c *SrvReportCmd) renderConnections(out io.Writer, report []connInfo) {
 	c.sortConnections(report)
 
 	total := len(report)
@@ -677,7 +698,7 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 		}
 
 		if i < limit {
-			values := []any{cid, name, srvName, cluster, fmt.Sprintf("%s:%d", info.IP, info.Port), acc, info.Uptime, f(info.InMsgs), f(info.OutMsgs), humanize.IBytes(uint64(info.InBytes)), humanize.IBytes(uint64(info.OutBytes)), f(len(info.Subs))}
+			values := []any{cid, name, srvName, cluster, fmt.Sprintf("%s:%d", info.IP, info.Port), acc, info.Uptime, fCount(info.InMsgs), fCount(info.OutMsgs), fiBytes(uint64(info.InBytes)), fiBytes(uint64(info.OutBytes)), f(len(info.Subs))}
 			if showReason {
 				values = append(values, info.Reason)
 			}
@@ -686,17 +707,17 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 	}
 
 	if len(report) > 1 {
-		values := []any{"", fmt.Sprintf("Totals for %s connections", humanize.Comma(int64(total))), "", "", "", "", "", f(iMsgs), f(oMsgs), humanize.IBytes(uint64(iBytes)), humanize.IBytes(uint64(oBytes)), f(subs)}
+		values := []any{"", fmt.Sprintf("Totals for %s connections", f(total)), "", "", "", "", "", fCount(iMsgs), fCount(oMsgs), fiBytes(uint64(iBytes)), fiBytes(uint64(oBytes)), f(subs)}
 		if showReason {
 			values = append(values, "")
 		}
 		table.AddFooter(values...)
 	}
 
-	fmt.Print(table.Render())
+	fmt.Fprint(out, table.Render())
 
 	if len(serverNames) > 0 {
-		fmt.Println()
+		fmt.Fprintln(out)
 
 		sort.Slice(serverNames, func(i, j int) bool {
 			return servers[serverNames[i]].conns < servers[serverNames[j]].conns
@@ -711,7 +732,7 @@ func (c *SrvReportCmd) renderConnections(report []connInfo) {
 		for _, n := range serverNames {
 			table.AddRow(n, servers[n].cluster, servers[n].conns)
 		}
-		fmt.Print(table.Render())
+		fmt.Fprint(out, table.Render())
 	}
 }
 
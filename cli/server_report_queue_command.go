@@ -0,0 +1,251 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const srvReportSubszPageSize = 1024
+
+type srvReportQueueMember struct {
+	Server   string `json:"server"`
+	ServerID string `json:"server_id"`
+	Cluster  string `json:"cluster,omitempty"`
+	Account  string `json:"account"`
+	Cid      uint64 `json:"cid"`
+	Name     string `json:"name,omitempty"`
+	Subject  string `json:"subject"`
+	Sid      string `json:"sid"`
+	Msgs     int64  `json:"messages"`
+	Delta    int64  `json:"interval_messages"`
+}
+
+type srvReportQueue struct {
+	Queue    string                  `json:"queue"`
+	Interval time.Duration           `json:"interval"`
+	Accounts []string                `json:"accounts"`
+	Members  []*srvReportQueueMember `json:"members"`
+	Warnings []string                `json:"warnings,omitempty"`
+}
+
+type srvReportSubszResponse struct {
+	Server *server.ServerInfo `json:"server"`
+	Data   *server.Subsz      `json:"data,omitempty"`
+	Error  *server.ApiError   `json:"error,omitempty"`
+}
+
+func (c *SrvReportCmd) reportQueue(_ *fisk.ParseContext) error {
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	if c.waitFor == 0 {
+		c.waitFor, err = currentActiveServers(nc)
+		if err != nil {
+			return err
+		}
+	}
+
+	members, err := c.queueMembers(nc)
+	if err != nil {
+		return err
+	}
+
+	if len(members) > 0 && c.queueInterval > 0 {
+		if !c.json {
+			fmt.Printf("Sampling queue group %s for %v\n\n", c.queue, c.queueInterval)
+		}
+
+		select {
+		case <-time.After(c.queueInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		current, err := c.queueMembers(nc)
+		if err != nil {
+			return err
+		}
+
+		members = queueMemberDeltas(members, current)
+	}
+
+	err = c.addQueueMemberNames(nc, members)
+	if err != nil {
+		return err
+	}
+
+	report := &srvReportQueue{
+		Queue:    c.queue,
+		Interval: c.queueInterval,
+		Accounts: queueMemberAccounts(members),
+		Members:  members,
+	}
+
+	if len(report.Accounts) > 1 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("Members of queue group %s are in multiple accounts (%s), messages are only shared between members in the same account", c.queue, strings.Join(report.Accounts, ", ")))
+	}
+
+	if c.json {
+		iu.PrintJSON(report)
+		return nil
+	}
+
+	c.renderQueue(report)
+
+	return nil
+}
+
+// queueMembers gathers all subscriptions in the queue group from all servers
+func (c *SrvReportCmd) queueMembers(nc *nats.Conn) ([]*srvReportQueueMember, error) {
+	var members []*srvReportQueueMember
+
+	opts := server.SubszOptions{Account: c.account, Test: c.queueSubject}
+	err := subszSubscriptions(nc, "$SYS.REQ.SERVER.PING.SUBSZ", c.waitFor, opts, c.reqFilter(), func(srv *server.ServerInfo, sub *server.SubDetail) {
+		if sub.Queue != c.queue {
+			return
+		}
+
+		members = append(members, &srvReportQueueMember{
+			Server:   srv.Name,
+			ServerID: srv.ID,
+			Cluster:  srv.Cluster,
+			Account:  sub.Account,
+			Cid:      sub.Cid,
+			Subject:  sub.Subject,
+			Sid:      sub.Sid,
+			Msgs:     sub.Msgs,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// addQueueMemberNames looks up the connection names of all members
+func (c *SrvReportCmd) addQueueMemberNames(nc *nats.Conn, members []*srvReportQueueMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	// the report is shown as a whole so connection gathering progress is not needed
+	quiet := c.json
+	c.json = true
+	connz, err := c.getConnz(0, nc)
+	c.json = quiet
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	for _, conn := range connz.flatConnInfo() {
+		names[fmt.Sprintf("%s:%d", conn.Info.ID, conn.Cid)] = conn.Name
+	}
+
+	for _, member := range members {
+		member.Name = names[fmt.Sprintf("%s:%d", member.ServerID, member.Cid)]
+	}
+
+	return nil
+}
+
+// queueMemberDeltas calculates the messages every current member received since the earlier snapshot
+func queueMemberDeltas(earlier []*srvReportQueueMember, current []*srvReportQueueMember) []*srvReportQueueMember {
+	key := func(m *srvReportQueueMember) string {
+		return fmt.Sprintf("%s:%d:%s", m.ServerID, m.Cid, m.Sid)
+	}
+
+	previous := map[string]int64{}
+	for _, m := range earlier {
+		previous[key(m)] = m.Msgs
+	}
+
+	for _, m := range current {
+		m.Delta = m.Msgs - previous[key(m)]
+	}
+
+	return current
+}
+
+func queueMemberAccounts(members []*srvReportQueueMember) []string {
+	seen := map[string]bool{}
+	accounts := []string{}
+
+	for _, m := range members {
+		if !seen[m.Account] {
+			seen[m.Account] = true
+			accounts = append(accounts, m.Account)
+		}
+	}
+
+	sort.Strings(accounts)
+
+	return accounts
+}
+
+func (c *SrvReportCmd) renderQueue(report *srvReportQueue) {
+	if len(report.Members) == 0 {
+		fmt.Printf("No members found for queue group %s\n", report.Queue)
+		return
+	}
+
+	var total int64
+	for _, m := range report.Members {
+		total += m.Delta
+	}
+
+	sort.Slice(report.Members, func(i, j int) bool {
+		if report.Interval > 0 {
+			return c.boolReverse(report.Members[i].Delta > report.Members[j].Delta)
+		}
+		return c.boolReverse(report.Members[i].Msgs > report.Members[j].Msgs)
+	})
+
+	table := newTableWriter(fmt.Sprintf("%d members of queue group %s", len(report.Members), report.Queue))
+	if report.Interval > 0 {
+		table.AddHeaders("Server", "Account", "CID", "Name", "Subject", "Messages", fmt.Sprintf("Last %v", report.Interval), "Share")
+	} else {
+		table.AddHeaders("Server", "Account", "CID", "Name", "Subject", "Messages")
+	}
+
+	for _, m := range report.Members {
+		if report.Interval > 0 {
+			share := 0.0
+			if total > 0 {
+				share = float64(m.Delta) / float64(total) * 100
+			}
+			table.AddRow(m.Server, m.Account, m.Cid, m.Name, m.Subject, f(m.Msgs), f(m.Delta), fmt.Sprintf("%.1f%%", share))
+		} else {
+			table.AddRow(m.Server, m.Account, m.Cid, m.Name, m.Subject, f(m.Msgs))
+		}
+	}
+
+	fmt.Println(table.Render())
+
+	for _, warning := range report.Warnings {
+		fmt.Printf("WARNING: %s\n", warning)
+	}
+}
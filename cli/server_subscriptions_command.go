@@ -0,0 +1,237 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+type SrvSubsCmd struct {
+	id      string
+	account string
+	filter  string
+	top     int
+	json    bool
+}
+
+type srvSubsSubject struct {
+	Subject       string   `json:"subject"`
+	Subscriptions int      `json:"subscriptions"`
+	Queues        int      `json:"queue_subscriptions"`
+	Accounts      []string `json:"accounts"`
+	Servers       []string `json:"servers"`
+}
+
+type srvSubsReport struct {
+	Filter        string            `json:"filter,omitempty"`
+	Total         int               `json:"total"`
+	Matched       int               `json:"matched"`
+	UniqueSubject int               `json:"unique_subjects"`
+	Subjects      []*srvSubsSubject `json:"subjects"`
+}
+
+func configureServerSubscriptionsCommand(srv *fisk.CmdClause) {
+	c := &SrvSubsCmd{}
+
+	subs := srv.Command("subscriptions", "Show the subjects with the most subscriptions").Alias("subs").Alias("subsz").Action(c.subsAction)
+	subs.Arg("server", "Server ID or Name to inspect, all servers when not given").StringVar(&c.id)
+	subs.Flag("top", "Number of subjects to show").Default("20").IntVar(&c.top)
+	subs.Flag("filter", "Only include subscriptions on subjects matching this subject").PlaceHolder("SUBJECT").StringVar(&c.filter)
+	subs.Flag("account", "Only include subscriptions in a specific account").StringVar(&c.account)
+	subs.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+func (c *SrvSubsCmd) subsAction(_ *fisk.ParseContext) error {
+	if c.top < 1 {
+		return fmt.Errorf("top must be at least 1")
+	}
+
+	nc, _, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	subj := "$SYS.REQ.SERVER.PING.SUBSZ"
+	var filter server.EventFilterOptions
+
+	waitFor := 1
+	switch {
+	case len(c.id) == 56 && strings.ToUpper(c.id) == c.id:
+		subj = fmt.Sprintf("$SYS.REQ.SERVER.%s.SUBSZ", c.id)
+	case c.id != "":
+		filter.Name = c.id
+	default:
+		waitFor, err = currentActiveServers(nc)
+		if err != nil {
+			return err
+		}
+	}
+
+	bySubject := map[string]*srvSubsSubject{}
+	accounts := map[string]map[string]bool{}
+	servers := map[string]map[string]bool{}
+	report := &srvSubsReport{Filter: c.filter}
+
+	err = subszSubscriptions(nc, subj, waitFor, server.SubszOptions{Account: c.account}, filter, func(srv *server.ServerInfo, sub *server.SubDetail) {
+		report.Total++
+
+		if c.filter != "" && !api.SubjectIsSubsetMatch(sub.Subject, c.filter) {
+			return
+		}
+
+		report.Matched++
+
+		s, ok := bySubject[sub.Subject]
+		if !ok {
+			s = &srvSubsSubject{Subject: sub.Subject}
+			bySubject[sub.Subject] = s
+			accounts[sub.Subject] = map[string]bool{}
+			servers[sub.Subject] = map[string]bool{}
+		}
+
+		s.Subscriptions++
+		if sub.Queue != "" {
+			s.Queues++
+		}
+		accounts[sub.Subject][sub.Account] = true
+		servers[sub.Subject][srv.Name] = true
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := func(m map[string]bool) []string {
+		res := []string{}
+		for k := range m {
+			res = append(res, k)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	for subject, s := range bySubject {
+		s.Accounts = keys(accounts[subject])
+		s.Servers = keys(servers[subject])
+		report.Subjects = append(report.Subjects, s)
+	}
+
+	sort.Slice(report.Subjects, func(i, j int) bool {
+		if report.Subjects[i].Subscriptions == report.Subjects[j].Subscriptions {
+			return report.Subjects[i].Subject < report.Subjects[j].Subject
+		}
+		return report.Subjects[i].Subscriptions > report.Subjects[j].Subscriptions
+	})
+
+	report.UniqueSubject = len(report.Subjects)
+	if len(report.Subjects) > c.top {
+		report.Subjects = report.Subjects[:c.top]
+	}
+
+	if c.json {
+		iu.PrintJSON(report)
+		return nil
+	}
+
+	c.renderSubs(report)
+
+	return nil
+}
+
+func (c *SrvSubsCmd) renderSubs(report *srvSubsReport) {
+	if report.Matched == 0 {
+		fmt.Printf("No subscriptions found out of %s subscriptions\n", f(report.Total))
+		return
+	}
+
+	table := newTableWriter(fmt.Sprintf("Top %d subjects by subscription count", len(report.Subjects)))
+	table.AddHeaders("Subject", "Subscriptions", "Queue Subscriptions", "Accounts", "Servers")
+
+	for _, s := range report.Subjects {
+		table.AddRow(s.Subject, f(s.Subscriptions), f(s.Queues), f(len(s.Accounts)), f(len(s.Servers)))
+	}
+
+	fmt.Println(table.Render())
+
+	if c.filter != "" {
+		fmt.Printf("%s subscriptions on %s subjects matching %s out of %s subscriptions in total\n", f(report.Matched), f(report.UniqueSubject), c.filter, f(report.Total))
+	} else {
+		fmt.Printf("%s subscriptions on %s subjects in total\n", f(report.Total), f(report.UniqueSubject))
+	}
+}
+
+// subszSubscriptions pages through all subscriptions on the servers responding to subj, calling cb once for every subscription
+func subszSubscriptions(nc *nats.Conn, subj string, waitFor int, opts server.SubszOptions, filter server.EventFilterOptions, cb func(*server.ServerInfo, *server.SubDetail)) error {
+	// servers do not list subscriptions in a stable order so pages might overlap
+	seen := map[string]bool{}
+
+	opts.Subscriptions = true
+	opts.Limit = srvReportSubszPageSize
+
+	for offset := 0; ; offset += srvReportSubszPageSize {
+		opts.Offset = offset
+		req := &server.SubszEventOptions{SubszOptions: opts, EventFilterOptions: filter}
+
+		results, err := doReq(req, subj, waitFor, nc)
+		if err != nil {
+			return err
+		}
+
+		if offset == 0 && len(results) == 0 {
+			return fmt.Errorf("no results received, ensure the account used has system privileges and appropriate permissions")
+		}
+
+		more := false
+		for _, result := range results {
+			var resp srvReportSubszResponse
+			err = json.Unmarshal(result, &resp)
+			if err != nil {
+				return err
+			}
+			if resp.Error != nil {
+				return fmt.Errorf("invalid response received: %v", resp.Error)
+			}
+			if resp.Data == nil || resp.Server == nil {
+				continue
+			}
+
+			for i := range resp.Data.Subs {
+				sub := &resp.Data.Subs[i]
+				key := fmt.Sprintf("%s:%d:%s:%s", resp.Server.ID, sub.Cid, sub.Account, sub.Sid)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				cb(resp.Server, sub)
+			}
+
+			if len(resp.Data.Subs) == srvReportSubszPageSize {
+				more = true
+			}
+		}
+
+		if !more {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+type SrvVersionCmd struct {
+	all    bool
+	expect uint32
+	json   bool
+}
+
+// srvVersionFeature is a server feature along with the version that introduced it
+type srvVersionFeature struct {
+	Feature   string `json:"feature"`
+	Requires  string `json:"requires"`
+	Used      bool   `json:"used"`
+	Supported bool   `json:"supported"`
+
+	major, minor, patch int
+	used                func(*srvFeatureUsage) bool
+}
+
+// srvFeatureUsage is what the account makes use of, found by inspecting its Streams and Consumers
+type srvFeatureUsage struct {
+	jetStream      bool
+	kv             bool
+	objectStore    bool
+	directGet      bool
+	compression    bool
+	transforms     bool
+	metadata       bool
+	filterSubjects bool
+	paused         bool
+}
+
+type srvVersionServer struct {
+	Name    string `json:"name"`
+	Cluster string `json:"cluster,omitempty"`
+	Version string `json:"version"`
+}
+
+type srvVersionReport struct {
+	Server        string               `json:"server"`
+	Version       string               `json:"version"`
+	CLIVersion    string               `json:"cli_version"`
+	ClientVersion string               `json:"client_version"`
+	Servers       []*srvVersionServer  `json:"servers,omitempty"`
+	Versions      []string             `json:"versions,omitempty"`
+	Features      []*srvVersionFeature `json:"features"`
+}
+
+// srvVersionFeatures are the features that are checked for compatibility in the order they were introduced
+var srvVersionFeatures = []srvVersionFeature{
+	{Feature: "JetStream", major: 2, minor: 2, used: func(u *srvFeatureUsage) bool { return u.jetStream }},
+	{Feature: "Key-Value Stores", major: 2, minor: 6, patch: 2, used: func(u *srvFeatureUsage) bool { return u.kv }},
+	{Feature: "Object Stores", major: 2, minor: 6, patch: 3, used: func(u *srvFeatureUsage) bool { return u.objectStore }},
+	{Feature: "Direct Get", major: 2, minor: 9, used: func(u *srvFeatureUsage) bool { return u.directGet }},
+	{Feature: "Stream Compression", major: 2, minor: 10, used: func(u *srvFeatureUsage) bool { return u.compression }},
+	{Feature: "Subject Transforms", major: 2, minor: 10, used: func(u *srvFeatureUsage) bool { return u.transforms }},
+	{Feature: "Metadata", major: 2, minor: 10, used: func(u *srvFeatureUsage) bool { return u.metadata }},
+	{Feature: "Multiple Consumer Filters", major: 2, minor: 10, used: func(u *srvFeatureUsage) bool { return u.filterSubjects }},
+	{Feature: "Consumer Pausing", major: 2, minor: 11, used: func(u *srvFeatureUsage) bool { return u.paused }},
+}
+
+func configureServerVersionCommand(srv *fisk.CmdClause) {
+	c := &SrvVersionCmd{}
+
+	help := `Shows the server version and the features it supports
+
+Features introduced in later server versions are listed along with the
+version they require, features used by Streams and Consumers in the
+account are marked as in use.
+
+With --all every server is asked for its version, servers running
+different versions are flagged and the features are checked against the
+oldest version found, failing when a feature in use is not supported.
+`
+
+	version := srv.Command("version", help).Action(c.versionAction)
+	version.Flag("all", "Checks the versions of all servers").Short('a').UnNegatableBoolVar(&c.all)
+	version.Flag("expect", "How many servers to expect when using --all").PlaceHolder("SERVERS").Uint32Var(&c.expect)
+	version.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+}
+
+// serverVersionCompatibility checks which features the server version supports and which are used
+func serverVersionCompatibility(version string, usage *srvFeatureUsage) []*srvVersionFeature {
+	var features []*srvVersionFeature
+
+	for _, feature := range srvVersionFeatures {
+		feature := feature
+		feature.Requires = fmt.Sprintf("%d.%d.%d", feature.major, feature.minor, feature.patch)
+		feature.Supported = serverMinVersion(version, feature.major, feature.minor, feature.patch)
+		feature.Used = usage != nil && feature.used(usage)
+		features = append(features, &feature)
+	}
+
+	return features
+}
+
+// sortServerVersions sorts versions from oldest to newest
+func sortServerVersions(versions []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		major, minor, patch, err := versionComponents(versions[j])
+		if err != nil {
+			return false
+		}
+		return !serverMinVersion(versions[i], major, minor, patch)
+	})
+}
+
+func (c *SrvVersionCmd) versionAction(_ *fisk.ParseContext) error {
+	nc, mgr, err := prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	report := &srvVersionReport{
+		Server:        nc.ConnectedServerName(),
+		Version:       nc.ConnectedServerVersion(),
+		CLIVersion:    Version,
+		ClientVersion: nats.Version,
+	}
+
+	checked := report.Version
+	if c.all {
+		report.Servers, err = c.serverVersions(nc)
+		if err != nil {
+			return err
+		}
+
+		seen := map[string]bool{}
+		for _, s := range report.Servers {
+			if !seen[s.Version] {
+				seen[s.Version] = true
+				report.Versions = append(report.Versions, s.Version)
+			}
+		}
+		sortServerVersions(report.Versions)
+
+		checked = report.Versions[0]
+	}
+
+	usage, err := c.featureUsage(mgr)
+	if err != nil {
+		return err
+	}
+
+	report.Features = serverVersionCompatibility(checked, usage)
+
+	if c.json {
+		iu.PrintJSON(report)
+	} else {
+		c.renderVersions(report, checked)
+	}
+
+	var unsupported []string
+	for _, feature := range report.Features {
+		if feature.Used && !feature.Supported {
+			unsupported = append(unsupported, feature.Feature)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("server version %s does not support %s used in this account", checked, strings.Join(unsupported, ", "))
+	}
+
+	return nil
+}
+
+// serverVersions asks every server for its version
+func (c *SrvVersionCmd) serverVersions(nc *nats.Conn) ([]*srvVersionServer, error) {
+	res, err := doReq(nil, "$SYS.REQ.SERVER.PING", int(c.expect), nc)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("no responses received, ensure the account used has system privileges and appropriate permissions")
+	}
+
+	var servers []*srvVersionServer
+	for _, r := range res {
+		ssm := &server.ServerStatsMsg{}
+		err = json.Unmarshal(r, ssm)
+		if err != nil {
+			return nil, fmt.Errorf("invalid server statistics received: %w", err)
+		}
+
+		servers = append(servers, &srvVersionServer{Name: ssm.Server.Name, Cluster: ssm.Server.Cluster, Version: ssm.Server.Version})
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		if servers[i].Cluster == servers[j].Cluster {
+			return servers[i].Name < servers[j].Name
+		}
+		return servers[i].Cluster < servers[j].Cluster
+	})
+
+	return servers, nil
+}
+
+// featureUsage inspects the Streams and Consumers in the account for features introduced in later server versions
+func (c *SrvVersionCmd) featureUsage(mgr *jsm.Manager) (*srvFeatureUsage, error) {
+	usage := &srvFeatureUsage{}
+
+	if !mgr.IsJetStreamEnabled() {
+		return usage, nil
+	}
+	usage.jetStream = true
+
+	hasMetadata := func(md map[string]string) bool {
+		for k := range md {
+			// servers add their own metadata using reserved keys
+			if !strings.HasPrefix(k, "_nats") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var streams []api.StreamConfig
+	_, err := mgr.EachStream(nil, func(s *jsm.Stream) {
+		streams = append(streams, s.Configuration())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cfg := range streams {
+		switch {
+		case strings.HasPrefix(cfg.Name, "KV_"):
+			usage.kv = true
+		case strings.HasPrefix(cfg.Name, "OBJ_"):
+			usage.objectStore = true
+		}
+
+		usage.directGet = usage.directGet || cfg.AllowDirect
+		usage.compression = usage.compression || cfg.Compression != api.NoCompression
+		usage.transforms = usage.transforms || cfg.SubjectTransform != nil
+		usage.metadata = usage.metadata || hasMetadata(cfg.Metadata)
+
+		consumers, _, err := mgr.Consumers(cfg.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, cons := range consumers {
+			ccfg := cons.Configuration()
+			usage.filterSubjects = usage.filterSubjects || len(ccfg.FilterSubjects) > 0
+			usage.metadata = usage.metadata || hasMetadata(ccfg.Metadata)
+			usage.paused = usage.paused || !ccfg.PauseUntil.IsZero()
+		}
+	}
+
+	return usage, nil
+}
+
+func (c *SrvVersionCmd) renderVersions(report *srvVersionReport, checked string) {
+	if len(report.Servers) > 0 {
+		table := newTableWriter(fmt.Sprintf("%s Servers", f(len(report.Servers))))
+		table.AddHeaders("Name", "Cluster", "Version", "Outdated")
+		for _, s := range report.Servers {
+			// servers not running the newest version are flagged when versions differ
+			outdated := ""
+			if s.Version != report.Versions[len(report.Versions)-1] {
+				outdated = "X"
+			}
+			table.AddRow(s.Name, s.Cluster, s.Version, outdated)
+		}
+		fmt.Println(table.Render())
+
+		if len(report.Versions) > 1 {
+			logWarnf("Servers run %d different versions: %s, checking features against the oldest version %s", len(report.Versions), strings.Join(report.Versions, ", "), checked)
+			fmt.Println()
+		}
+	}
+
+	cols := newColumns("Compatibility with server version %s", checked)
+	defer cols.Frender(os.Stdout)
+
+	cols.AddRow("Connected Server", report.Server)
+	cols.AddRow("Server Version", report.Version)
+	cols.AddRow("CLI Version", report.CLIVersion)
+	cols.AddRow("Client Library", fmt.Sprintf("nats.go %s", report.ClientVersion))
+
+	cols.AddSectionTitle("Features")
+	for _, feature := range report.Features {
+		var status string
+		switch {
+		case feature.Supported && feature.Used:
+			status = "supported, in use"
+		case feature.Supported:
+			status = "supported"
+		case feature.Used:
+			status = fmt.Sprintf("requires %s, in use", feature.Requires)
+		default:
+			status = fmt.Sprintf("requires %s", feature.Requires)
+		}
+
+		cols.AddRow(feature.Feature, status)
+	}
+}
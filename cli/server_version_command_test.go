@@ -0,0 +1,54 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServerVersionCompatibility(t *testing.T) {
+	features := serverVersionCompatibility("2.9.22", &srvFeatureUsage{jetStream: true, kv: true, compression: true})
+
+	found := map[string]*srvVersionFeature{}
+	for _, feature := range features {
+		found[feature.Feature] = feature
+	}
+
+	for name, expected := range map[string][2]bool{
+		"JetStream":          {true, true},
+		"Key-Value Stores":   {true, true},
+		"Direct Get":         {false, true},
+		"Stream Compression": {true, false},
+		"Consumer Pausing":   {false, false},
+	} {
+		feature, ok := found[name]
+		if !ok {
+			t.Fatalf("feature %s not found", name)
+		}
+		if feature.Used != expected[0] || feature.Supported != expected[1] {
+			t.Fatalf("expected %s used %v supported %v got %v %v", name, expected[0], expected[1], feature.Used, feature.Supported)
+		}
+	}
+
+	if found["Stream Compression"].Requires != "2.10.0" {
+		t.Fatalf("unexpected requirement %s", found["Stream Compression"].Requires)
+	}
+
+	versions := []string{"2.10.14", "2.9.22", "2.11.0-dev", "2.10.2"}
+	sortServerVersions(versions)
+	if !reflect.DeepEqual(versions, []string{"2.9.22", "2.10.2", "2.10.14", "2.11.0-dev"}) {
+		t.Fatalf("unexpected order %v", versions)
+	}
+}
@@ -422,7 +422,7 @@ func (c *serviceCmd) infoAction(_ *fisk.ParseContext) error {
 		cols.AddSectionTitle("%s Endpoint Statistics", e.Name)
 		cols.AddRowf("Requests", "%s in group %q", f(e.NumRequests), e.QueueGroup)
 		cols.AddRowf("Processing Time", "%s (average %s)", f(e.ProcessingTime), f(e.AverageProcessingTime))
-		cols.AddRowf("Started:", "%s (%s ago)", f(stats.Started), f(time.Since(stats.Started)))
+		cols.AddRowf("Started:", "%s (%s)", f(stats.Started), fAgo(time.Since(stats.Started)))
 		cols.AddRow("Errors", e.NumErrors)
 		cols.AddRowIfNotEmpty("Last Error", e.LastError)
 
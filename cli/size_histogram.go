@@ -0,0 +1,144 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sync"
+)
+
+// defaultSizeHistogramBuckets are the upper bounds of the default message size buckets
+var defaultSizeHistogramBuckets = []int64{128, 1024, 4 * 1024, 16 * 1024, 64 * 1024, 256 * 1024, 1024 * 1024, 4 * 1024 * 1024}
+
+// sizeHistogram counts message sizes in buckets, sizes larger than the last bound are counted in an overflow bucket
+type sizeHistogram struct {
+	bounds   []int64
+	counts   []uint64
+	messages uint64
+	bytes    uint64
+	largest  int64
+	mu       sync.Mutex
+}
+
+// sizeHistogramBucket is a bucket in a histogram report, UpTo is 0 for the overflow bucket
+type sizeHistogramBucket struct {
+	Label string `json:"label"`
+	UpTo  int64  `json:"up_to,omitempty"`
+	Count uint64 `json:"count"`
+}
+
+type sizeHistogramReport struct {
+	Messages uint64                 `json:"messages"`
+	Bytes    uint64                 `json:"bytes"`
+	Largest  int64                  `json:"largest"`
+	Buckets  []*sizeHistogramBucket `json:"buckets"`
+}
+
+// newSizeHistogram creates a histogram with buckets up to each of bounds, the default buckets are used when none are given
+func newSizeHistogram(bounds []int64) (*sizeHistogram, error) {
+	if len(bounds) == 0 {
+		bounds = defaultSizeHistogramBuckets
+	}
+
+	for i, b := range bounds {
+		if b <= 0 {
+			return nil, fmt.Errorf("histogram bucket sizes must be positive")
+		}
+		if i > 0 && b <= bounds[i-1] {
+			return nil, fmt.Errorf("histogram bucket sizes must be in increasing order")
+		}
+	}
+
+	return &sizeHistogram{
+		bounds: append([]int64{}, bounds...),
+		counts: make([]uint64, len(bounds)+1),
+	}, nil
+}
+
+// parseSizeHistogramBuckets parses bucket sizes like 1KB or 4MB, values may hold many sizes separated by commas
+func parseSizeHistogramBuckets(values []string) ([]int64, error) {
+	var bounds []int64
+	for _, v := range splitCLISubjects(values) {
+		b, err := parseStringAsBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		if b <= 0 {
+			return nil, fmt.Errorf("invalid histogram bucket size %q", v)
+		}
+
+		bounds = append(bounds, b)
+	}
+
+	return bounds, nil
+}
+
+func (h *sizeHistogram) observe(size int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.bounds) && int64(size) > h.bounds[i] {
+		i++
+	}
+
+	h.counts[i]++
+	h.messages++
+	h.bytes += uint64(size)
+	if int64(size) > h.largest {
+		h.largest = int64(size)
+	}
+}
+
+// report is a copy of the current counts
+func (h *sizeHistogram) report() *sizeHistogramReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	report := &sizeHistogramReport{Messages: h.messages, Bytes: h.bytes, Largest: h.largest}
+	for i, count := range h.counts {
+		bucket := &sizeHistogramBucket{Count: count}
+		if i < len(h.bounds) {
+			bucket.UpTo = h.bounds[i]
+			bucket.Label = fmt.Sprintf("<= %s", fiBytes(uint64(h.bounds[i])))
+		} else {
+			bucket.Label = fmt.Sprintf("> %s", fiBytes(uint64(h.bounds[len(h.bounds)-1])))
+		}
+
+		report.Buckets = append(report.Buckets, bucket)
+	}
+
+	return report
+}
+
+func (r *sizeHistogramReport) render(title string) string {
+	table := newTableWriter(fmt.Sprintf("%s, largest %s", title, fiBytes(uint64(r.Largest))))
+	table.AddHeaders("Size", "Messages", "Percent", "Cumulative")
+
+	var cumulative uint64
+	for _, b := range r.Buckets {
+		cumulative += b.Count
+
+		pct, cpct := 0.0, 0.0
+		if r.Messages > 0 {
+			pct = float64(b.Count) / float64(r.Messages) * 100
+			cpct = float64(cumulative) / float64(r.Messages) * 100
+		}
+
+		table.AddRow(b.Label, f(b.Count), fmt.Sprintf("%.1f%%", pct), fmt.Sprintf("%.1f%%", cpct))
+	}
+	table.AddFooter("Total", f(r.Messages), "", "")
+
+	return table.Render()
+}
@@ -0,0 +1,94 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizeHistogram(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		h, err := newSizeHistogram(nil)
+		checkErr(t, err, "histogram failed: %v", err)
+
+		for _, size := range []int{0, 128, 129, 1024, 5 * 1024 * 1024} {
+			h.observe(size)
+		}
+
+		report := h.report()
+		if report.Messages != 5 || report.Bytes != 128+129+1024+5*1024*1024 || report.Largest != 5*1024*1024 {
+			t.Fatalf("unexpected totals: %+v", report)
+		}
+
+		if len(report.Buckets) != len(defaultSizeHistogramBuckets)+1 {
+			t.Fatalf("expected %d buckets got %d", len(defaultSizeHistogramBuckets)+1, len(report.Buckets))
+		}
+
+		expect := []uint64{2, 2, 0, 0, 0, 0, 0, 0, 1}
+		for i, b := range report.Buckets {
+			if b.Count != expect[i] {
+				t.Fatalf("bucket %s expected %d got %d", b.Label, expect[i], b.Count)
+			}
+		}
+
+		if report.Buckets[0].Label != "<= 128 B" || report.Buckets[0].UpTo != 128 {
+			t.Fatalf("unexpected first bucket: %+v", report.Buckets[0])
+		}
+
+		last := report.Buckets[len(report.Buckets)-1]
+		if last.Label != "> 4.0 MiB" || last.UpTo != 0 {
+			t.Fatalf("unexpected overflow bucket: %+v", last)
+		}
+
+		out := report.render("Message sizes")
+		if !strings.Contains(out, "largest 5.0 MiB") || !strings.Contains(out, "40.0%") {
+			t.Fatalf("unexpected render:\n%s", out)
+		}
+	})
+
+	t.Run("custom buckets", func(t *testing.T) {
+		bounds, err := parseSizeHistogramBuckets([]string{"1KB,64KB", "1MB"})
+		checkErr(t, err, "parse failed: %v", err)
+		if len(bounds) != 3 || bounds[0] != 1024 || bounds[1] != 64*1024 || bounds[2] != 1024*1024 {
+			t.Fatalf("unexpected bounds: %v", bounds)
+		}
+
+		h, err := newSizeHistogram(bounds)
+		checkErr(t, err, "histogram failed: %v", err)
+		h.observe(2000)
+
+		report := h.report()
+		if len(report.Buckets) != 4 || report.Buckets[1].Count != 1 {
+			t.Fatalf("unexpected buckets: %+v", report.Buckets)
+		}
+	})
+
+	t.Run("invalid buckets", func(t *testing.T) {
+		_, err := newSizeHistogram([]int64{1024, 128})
+		if err == nil || !strings.Contains(err.Error(), "increasing order") {
+			t.Fatalf("expected an order error got %v", err)
+		}
+
+		_, err = parseSizeHistogramBuckets([]string{"-1"})
+		if err == nil {
+			t.Fatalf("expected an invalid size error")
+		}
+
+		_, err = parseSizeHistogramBuckets([]string{"10X"})
+		if err == nil {
+			t.Fatalf("expected an invalid size error")
+		}
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// cleanupStream is a Stream eligible for removal by stream cleanup
+type cleanupStream struct {
+	Name    string        `json:"name"`
+	Created time.Time     `json:"created"`
+	Age     time.Duration `json:"age"`
+	Deleted bool          `json:"deleted,omitempty"`
+	Skipped string        `json:"skipped,omitempty"`
+}
+
+func (c *streamCmd) cleanupAction(_ *fisk.ParseContext) (err error) {
+	switch {
+	case !c.cleanupEmpty:
+		return fmt.Errorf("only empty Streams can be cleaned up, pass --empty to confirm")
+	case c.cleanupOlderThan < 0:
+		return fmt.Errorf("older-than can not be negative")
+	case c.json && !c.dryRun && !c.force:
+		return fmt.Errorf("deleting Streams with --json requires --force or --dry-run")
+	}
+
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return fmt.Errorf("setup failed: %v", err)
+	}
+
+	streams, candidates, err := c.findCleanupStreams()
+	if err != nil {
+		return err
+	}
+
+	if !c.json {
+		c.renderCleanupStreams(candidates)
+	}
+
+	if c.dryRun || len(candidates) == 0 {
+		if c.json {
+			return iu.PrintJSON(candidates)
+		}
+
+		if c.dryRun && len(candidates) > 0 {
+			fmt.Println("Dry run, no Streams were deleted")
+		}
+
+		return nil
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really delete %d empty Streams", len(candidates)), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, cand := range candidates {
+		stream := streams[cand.Name]
+
+		// messages might have arrived since the Streams were listed
+		state, err := stream.State()
+		if err != nil {
+			return fmt.Errorf("could not load Stream %s: %w", cand.Name, err)
+		}
+		switch {
+		case state.Msgs > 0:
+			cand.Skipped = fmt.Sprintf("received %s messages", f(state.Msgs))
+		case state.Consumers > 0 && !c.cleanupInUse:
+			cand.Skipped = fmt.Sprintf("has %s Consumers", f(state.Consumers))
+		}
+		if cand.Skipped != "" {
+			if !c.json {
+				fmt.Printf("Skipped Stream %s: %s\n", cand.Name, cand.Skipped)
+			}
+			continue
+		}
+
+		err = stream.Delete()
+		if err != nil {
+			return fmt.Errorf("could not delete Stream %s: %w", cand.Name, err)
+		}
+		cand.Deleted = true
+
+		if !c.json {
+			fmt.Printf("Deleted Stream %s\n", cand.Name)
+		}
+	}
+
+	if c.json {
+		return iu.PrintJSON(candidates)
+	}
+
+	return nil
+}
+
+// findCleanupStreams finds empty Streams created longer ago than the threshold, Streams managed by KV, Object Store and
+// MQTT are excluded as their configuration is meaningful even without messages. Streams with Consumers, a mirror or
+// sources are only included when in-use is set as they are often empty because they are caught up
+func (c *streamCmd) findCleanupStreams() (map[string]*jsm.Stream, []*cleanupStream, error) {
+	opts := []jsm.StreamQueryOpt{jsm.StreamQueryWithoutMessages()}
+	if c.cleanupOlderThan > 0 {
+		opts = append(opts, jsm.StreamQueryOlderThan(c.cleanupOlderThan))
+	}
+
+	found, err := c.mgr.QueryStreams(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	streams := map[string]*jsm.Stream{}
+	candidates := []*cleanupStream{}
+	for _, s := range found {
+		if s.IsInternal() {
+			continue
+		}
+
+		nfo, err := s.LatestInformation()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !c.cleanupInUse && (nfo.State.Consumers > 0 || nfo.Config.Mirror != nil || len(nfo.Config.Sources) > 0) {
+			continue
+		}
+
+		now := nfo.TimeStamp
+		if now.IsZero() {
+			now = time.Now()
+		}
+
+		streams[s.Name()] = s
+		candidates = append(candidates, &cleanupStream{
+			Name:    s.Name(),
+			Created: nfo.Created,
+			Age:     now.Sub(nfo.Created),
+		})
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		return sortMultiSort(candidates[i].Age, candidates[j].Age, candidates[i].Name, candidates[j].Name)
+	})
+
+	return streams, candidates, nil
+}
+
+func (c *streamCmd) renderCleanupStreams(candidates []*cleanupStream) {
+	age := ""
+	if c.cleanupOlderThan > 0 {
+		age = fmt.Sprintf(" created more than %s ago", f(c.cleanupOlderThan))
+	}
+
+	if len(candidates) == 0 {
+		fmt.Printf("No empty Streams%s were found\n", age)
+		return
+	}
+
+	table := newTableWriter("Empty Streams" + age)
+	table.AddHeaders("Stream", "Created", "Age")
+	for _, s := range candidates {
+		table.AddRow(s.Name, f(s.Created), f(s.Age.Round(time.Second)))
+	}
+
+	fmt.Println(table.Render())
+}
@@ -14,6 +14,7 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -35,8 +36,8 @@ import (
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/emicklei/dot"
+	"github.com/fatih/color"
 	"github.com/google/go-cmp/cmp"
 	"github.com/gosuri/uiprogress"
 	"github.com/nats-io/jsm.go"
@@ -79,6 +80,8 @@ type streamCmd struct {
 	reportRaw              bool
 	reportLimitCluster     string
 	reportLeaderDistrib    bool
+	reportPendingWarn      uint64
+	reportSeenCritical     time.Duration
 	discardPolicy          string
 	validateOnly           bool
 	backupDirectory        string
@@ -125,6 +128,42 @@ type streamCmd struct {
 	firstSeq               uint64
 	limitInactiveThreshold time.Duration
 	limitMaxAckPending     int
+	graphWatch             bool
+	verifyMirror           string
+	verifyContext          string
+	verifySamples          int
+	verifyFull             bool
+	sourceName             string
+	sourceFilter           string
+	exportSince            string
+	exportUntil            string
+	exportSubject          string
+	exportOutput           string
+	staleThreshold         time.Duration
+	staleDelete            bool
+	healthThreshold        int
+	simulateSample         int
+	cleanupOlderThan       time.Duration
+	cleanupEmpty           bool
+	cleanupInUse           bool
+	replaySubjectPrefix    string
+	replaySubjectMaps      []string
+	replayRate             int
+	replayStartSeq         uint64
+	replayCheckpoint       string
+	replayResume           bool
+	replayDryRun           bool
+	replayAck              bool
+	storageSnapshot        string
+	storageTarget          string
+	subjectChanges         []string
+	subjectRenameOld       string
+	subjectRenameNew       string
+	storageVerify          bool
+	storageParallel        int
+	subjectCountsTop       int
+	subjectCountsBytes     bool
+	subjectCountsCSV       string
 
 	fServer      string
 	fCluster     string
@@ -146,8 +185,12 @@ type streamCmd struct {
 	vwPageSize   int
 	vwRaw        bool
 	vwTranslate  string
+	vwTransform  *payloadTransformPipeline
 	vwSubject    string
 
+	dumpStartSeq uint64
+	dumpEndSeq   uint64
+
 	dryRun         bool
 	selectedStream *jsm.Stream
 	nc             *nats.Conn
@@ -155,20 +198,30 @@ type streamCmd struct {
 	chunkSize      string
 }
 
+const (
+	streamHealthOK       = "ok"
+	streamHealthWarning  = "warning"
+	streamHealthCritical = "critical"
+)
+
 type streamStat struct {
-	Name      string
-	Consumers int
-	Msgs      int64
-	Bytes     uint64
-	Storage   string
-	Template  string
-	Cluster   *api.ClusterInfo
-	LostBytes uint64
-	LostMsgs  int
-	Deleted   int
-	Mirror    *api.StreamSourceInfo
-	Sources   []*api.StreamSourceInfo
-	Placement *api.Placement
+	Name          string                  `json:"name"`
+	Subjects      []string                `json:"subjects,omitempty"`
+	Consumers     int                     `json:"consumers"`
+	Msgs          int64                   `json:"messages"`
+	Bytes         uint64                  `json:"bytes"`
+	Storage       string                  `json:"storage"`
+	Template      string                  `json:"template,omitempty"`
+	Cluster       *api.ClusterInfo        `json:"cluster,omitempty"`
+	LostBytes     uint64                  `json:"lost_bytes"`
+	LostMsgs      int                     `json:"lost_messages"`
+	Deleted       int                     `json:"deleted"`
+	Mirror        *api.StreamSourceInfo   `json:"mirror,omitempty"`
+	Sources       []*api.StreamSourceInfo `json:"sources,omitempty"`
+	Placement     *api.Placement          `json:"placement,omitempty"`
+	MaxPending    uint64                  `json:"max_consumer_pending"`
+	Health        string                  `json:"health"`
+	HealthReasons []string                `json:"health_reasons,omitempty"`
 }
 
 func configureStreamCommand(app commandHost) {
@@ -256,6 +309,9 @@ func configureStreamCommand(app commandHost) {
 	strReport.Flag("raw", "Show un-formatted numbers").Short('r').UnNegatableBoolVar(&c.reportRaw)
 	strReport.Flag("dot", "Produce a GraphViz graph of replication topology").StringVar(&c.outFile)
 	strReport.Flag("leaders", "Show details about cluster leaders").Short('l').UnNegatableBoolVar(&c.reportLeaderDistrib)
+	strReport.Flag("pending-warn", "Warning threshold for the number of messages pending on any consumer").Default("10000").PlaceHolder("MSGS").Uint64Var(&c.reportPendingWarn)
+	strReport.Flag("seen-critical", "Critical threshold for how long ago a mirror should have been seen").Default("1m").PlaceHolder("DURATION").DurationVar(&c.reportSeenCritical)
+	strReport.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 
 	findHelp := `Expression format:
 
@@ -321,6 +377,20 @@ Finding streams with certain subjects configured:
 	strSubs.Flag("reverse", "Reverse sort servers").Short('R').UnNegatableBoolVar(&c.reportSortReverse)
 	strSubs.Flag("names", "SList only subject names").BoolVar(&c.listNames)
 
+	strSubCounts := str.Command("subject-counts", "Reports the subjects holding the most messages in a stream").Action(c.subjectCountsAction)
+	strSubCounts.HelpLong(`Lists subjects by the number of messages held for each, with the share of all
+messages in the Stream, to find uneven partitioning in partitioned Streams.
+
+The server only reports message counts per subject, with --bytes the headers of
+every matching message are read to add up the payload sizes which can take a
+long time for large Streams.`)
+	strSubCounts.Arg("stream", "Stream name").StringVar(&c.stream)
+	strSubCounts.Arg("filter", "Limit the subjects to those matching a filter").Default(">").StringVar(&c.filterSubject)
+	strSubCounts.Flag("top", "Number of subjects to show, 0 shows all").Default("20").IntVar(&c.subjectCountsTop)
+	strSubCounts.Flag("bytes", "Measure the payload bytes held for every subject").UnNegatableBoolVar(&c.subjectCountsBytes)
+	strSubCounts.Flag("csv", "Save the counts of all subjects to a CSV file").PlaceHolder("FILE").StringVar(&c.subjectCountsCSV)
+	strSubCounts.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
 	strEdit := str.Command("edit", "Edits an existing stream").Alias("update").Action(c.editAction)
 	strEdit.Arg("stream", "Stream to retrieve edit").StringVar(&c.stream)
 	strEdit.Flag("config", "JSON file to read configuration from").ExistingFileVar(&c.inputFile)
@@ -367,6 +437,55 @@ Finding streams with certain subjects configured:
 	strGet.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
 	strGet.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.vwTranslate)
 
+	strDump := str.Command("dump", "Dumps messages in a Stream to a JSON Lines file").Alias("get-all").Action(c.dumpAction)
+	strDump.Arg("stream", "Stream to dump").StringVar(&c.stream)
+	strDump.Flag("output", "File to write messages to, - for STDOUT").Short('o').Default("-").PlaceHolder("FILE").StringVar(&c.outFile)
+	strDump.Flag("filter", "Only dump messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.filterSubject)
+	strDump.Flag("start-seq", "Starts dumping at a specific sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.dumpStartSeq)
+	strDump.Flag("end-seq", "Stops dumping at a specific sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.dumpEndSeq)
+	strDump.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+
+	strExport := str.Command("export", "Exports messages stored in a Stream during a time window to a JSON Lines file").Action(c.exportAction)
+	strExport.HelpLong(`Times can be given in RFC3339 format, as a date like "2023-04-01 02:00" optionally followed
+by a time zone like CET, +02:00 or Europe/Berlin, or as a duration like 1h30m meaning that long
+ago. Times without a zone are in local time.
+
+Messages are written one JSON document per line in the same format as pub --capture.`)
+	strExport.Arg("stream", "Stream to export").Required().StringVar(&c.stream)
+	strExport.Flag("since", "Export messages stored at or after this time").Required().PlaceHolder("TIME").StringVar(&c.exportSince)
+	strExport.Flag("until", "Export messages stored before this time, defaults to now").PlaceHolder("TIME").StringVar(&c.exportUntil)
+	strExport.Flag("subject", "Only export messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.exportSubject)
+	strExport.Flag("output", "File or directory to write messages to").Short('o').Required().PlaceHolder("PATH").StringVar(&c.exportOutput)
+	strExport.Flag("force", "Overwrite the output file if it exists").Short('f').UnNegatableBoolVar(&c.force)
+	strExport.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+
+	strReplay := str.Command("replay", "Republishes messages stored in a Stream to their original subjects").Action(c.replayAction)
+	strReplay.HelpLong(`Messages are read using an ordered Consumer up to the last message in the Stream when
+the replay started and published with their headers and a Nats-Replay-Of-Sequence header
+holding the sequence they were replayed from.
+
+Subjects can be changed using --subject-map and --subject-prefix, maps are applied first:
+
+   nats stream replay ORDERS --subject-map 'orders.*.created=orders.$1.new' --subject-prefix replayed.
+
+The last replayed sequence is written to a checkpoint file, defaulting to STREAM-replay.json,
+so an interrupted replay can be continued using --resume.
+
+By default every message waits for a JetStream acknowledgement, use --no-ack when replaying to
+subjects not stored in a Stream.`)
+	strReplay.Arg("stream", "Stream to replay").StringVar(&c.stream)
+	strReplay.Flag("subject-prefix", "Prefix to add to the subjects messages are replayed to").PlaceHolder("PREFIX").StringVar(&c.replaySubjectPrefix)
+	strReplay.Flag("subject-map", "Rewrites subjects matching a pattern before adding the prefix, can be repeated").PlaceHolder("PATTERN=REPLACEMENT").StringsVar(&c.replaySubjectMaps)
+	strReplay.Flag("filter", "Only replay messages matching a subject").PlaceHolder("SUBJECT").StringVar(&c.filterSubject)
+	strReplay.Flag("rate", "Maximum messages to replay per second, 0 for unlimited").Default("0").IntVar(&c.replayRate)
+	strReplay.Flag("start-seq", "Starts replaying at a specific sequence").PlaceHolder("SEQUENCE").Uint64Var(&c.replayStartSeq)
+	strReplay.Flag("checkpoint", "File to record the last replayed sequence in").PlaceHolder("FILE").StringVar(&c.replayCheckpoint)
+	strReplay.Flag("resume", "Continue after the sequence recorded in the checkpoint").UnNegatableBoolVar(&c.replayResume)
+	strReplay.Flag("dry-run", "Count the messages that would be replayed per subject without publishing").UnNegatableBoolVar(&c.replayDryRun)
+	strReplay.Flag("ack", "Wait for a JetStream acknowledgement for every message").Default("true").BoolVar(&c.replayAck)
+	strReplay.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	strReplay.Flag("force", "Replay without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
 	strBackup := str.Command("backup", "Creates a backup of a Stream over the NATS network").Alias("snapshot").Action(c.backupAction)
 	strBackup.Arg("stream", "Stream to backup").Required().StringVar(&c.stream)
 	strBackup.Arg("target", "Directory to create the backup in").Required().StringVar(&c.backupDirectory)
@@ -387,12 +506,210 @@ Finding streams with certain subjects configured:
 	strSeal.Arg("stream", "The name of the Stream to seal").Required().StringVar(&c.stream)
 	strSeal.Flag("force", "Force sealing without prompting").Short('f').UnNegatableBoolVar(&c.force)
 
+	strRetention := str.Command("retention", "Changes the retention policy of a Stream").Action(c.retentionAction)
+	strRetention.Arg("stream", "The name of the Stream to change").StringVar(&c.stream)
+	strRetention.Flag("policy", "The new retention policy (limits, interest, work-queue)").Required().EnumVar(&c.retentionPolicyS, "limits", "interest", "work-queue", "workq", "work")
+	strRetention.Flag("force", "Change without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	strAddSubject := str.Command("add-subject", "Adds subjects to a Stream without editing the rest of its configuration").Action(c.addSubjectAction)
+	strAddSubject.Arg("stream", "The name of the Stream to change").Required().StringVar(&c.stream)
+	strAddSubject.Arg("subject", "The subjects to add").Required().StringsVar(&c.subjectChanges)
+
+	strRemoveSubject := str.Command("remove-subject", "Removes subjects from a Stream without editing the rest of its configuration").Alias("rm-subject").Action(c.removeSubjectAction)
+	strRemoveSubject.Arg("stream", "The name of the Stream to change").Required().StringVar(&c.stream)
+	strRemoveSubject.Arg("subject", "The subjects to remove").Required().StringsVar(&c.subjectChanges)
+	strRemoveSubject.Flag("force", "Remove without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	strSubjectRename := str.Command("subject-rename", "Renames subjects of a Stream and the filters of its Consumers").Action(c.subjectRenameAction)
+	strSubjectRename.HelpLong(`Stream subjects and Consumer filters equal to the old subject are replaced by the new
+subject. When both end in > subjects below the old subject are renamed too, renaming
+orders.v1.> to orders.v2.> changes a Consumer filtering orders.v1.created to filter
+orders.v2.created.
+
+The Stream is updated before its Consumers. Messages already stored keep their subjects.`)
+	strSubjectRename.Arg("stream", "The name of the Stream to change").Required().StringVar(&c.stream)
+	strSubjectRename.Flag("old-subject", "The subject to rename").Required().PlaceHolder("SUBJECT").StringVar(&c.subjectRenameOld)
+	strSubjectRename.Flag("new-subject", "The new name for the subject").Required().PlaceHolder("SUBJECT").StringVar(&c.subjectRenameNew)
+	strSubjectRename.Flag("force", "Rename without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	strReplicas := str.Command("replicas", "Changes how many replicas of a Stream are kept in the cluster").Action(c.replicasAction)
+	strReplicas.Arg("stream", "The name of the Stream to change").StringVar(&c.stream)
+	strReplicas.Flag("count", "The new number of replicas").Required().Int64Var(&c.replicas)
+	strReplicas.Flag("force", "Change without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	strStorage := str.Command("storage-upgrade", "Moves a Stream from memory to file storage").Action(c.storageUpgradeAction)
+	strStorage.HelpLong(`The storage of a Stream can not be changed in place, instead all messages are saved
+to a snapshot file, the Stream is deleted, created again using file storage and the
+messages are published to it again. Publishers should be stopped while upgrading.
+
+Sequences are kept when the Stream has no gaps between its messages, otherwise later
+messages are renumbered. Messages get new timestamps so their age is reset. Durable
+Consumers are created again from their configuration without their delivery state.
+Only Streams using limits retention can be upgraded.
+
+Use --verify to compare the message and subject counts before and after upgrading.
+
+The snapshot is removed once the upgrade completed and was verified, otherwise it is
+kept and holds all messages one JSON document per line as written by stream export.`)
+	strStorage.Arg("stream", "The name of the Stream to upgrade").StringVar(&c.stream)
+	strStorage.Flag("verify", "Verify the Stream holds the same number of messages and subjects after upgrading").UnNegatableBoolVar(&c.storageVerify)
+	strStorage.Flag("snapshot", "File to save messages to while upgrading, defaults to STREAM-storage-upgrade.jsonl").PlaceHolder("FILE").StringVar(&c.storageSnapshot)
+	strStorage.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	strStorage.Flag("force", "Confirms deleting and creating the Stream again").Short('f').UnNegatableBoolVar(&c.force)
+
+	strConvert := str.Command("convert", "Moves a Stream to a different storage type").Action(c.storageConvertAction)
+	strConvert.HelpLong(`The storage of a Stream can not be changed in place, instead all messages are saved
+to a snapshot file, the Stream is deleted, created again using the new storage and the
+messages are published to it again. Publishers should be stopped while converting.
+
+Sequences are kept when the Stream has no gaps between its messages, otherwise later
+messages are renumbered. Messages get new timestamps so their age is reset. Durable
+Consumers are created again from their configuration without their delivery state.
+
+Use --parallel to have many publishes awaiting acknowledgement, speeding up large
+Streams, and --verify to compare the message counts before and after converting.
+
+The snapshot is removed once converting completed and was verified, otherwise it is
+kept and holds all messages one JSON document per line as written by stream export.`)
+	strConvert.Arg("stream", "The name of the Stream to convert").StringVar(&c.stream)
+	strConvert.Flag("storage", "The storage type to convert to (file, memory)").Required().EnumVar(&c.storageTarget, "file", "memory")
+	strConvert.Flag("verify", "Verify the Stream holds the same number of messages and subjects after converting").UnNegatableBoolVar(&c.storageVerify)
+	strConvert.Flag("parallel", "Number of publishes awaiting acknowledgement while publishing messages again").Default("1").IntVar(&c.storageParallel)
+	strConvert.Flag("snapshot", "File to save messages to while converting, defaults to STREAM-storage-convert.jsonl").PlaceHolder("FILE").StringVar(&c.storageSnapshot)
+	strConvert.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	strConvert.Flag("force", "Confirms deleting and creating the Stream again").Short('f').UnNegatableBoolVar(&c.force)
+
+	strRename := str.Command("rename", "Gives a Stream a new name by moving its messages to a new Stream").Action(c.renameAction)
+	strRename.HelpLong(`The server can not rename Streams, instead all messages are saved to a snapshot file,
+the Stream is deleted, created again using the new name and the messages are published
+to it again. Publishers should be stopped while renaming.
+
+Sequences are kept when the Stream has no gaps between its messages, otherwise later
+messages are renumbered. Messages get new timestamps so their age is reset. Durable
+Consumers are created again from their configuration without their delivery state.
+Only Streams using limits retention can be renamed.
+
+The message counts before and after renaming are compared, should publishing or
+verifying fail the snapshot is kept and holds all messages one JSON document per line
+as written by stream export.`)
+	strRename.Arg("stream", "The name of the Stream to rename").Required().StringVar(&c.stream)
+	strRename.Arg("name", "The new name of the Stream").Required().StringVar(&c.destination)
+	strRename.Flag("parallel", "Number of publishes awaiting acknowledgement while publishing messages again").Default("1").IntVar(&c.storageParallel)
+	strRename.Flag("snapshot", "File to save messages to while renaming, defaults to STREAM-rename.jsonl").PlaceHolder("FILE").StringVar(&c.storageSnapshot)
+	strRename.Flag("progress", "Enables or disables progress reporting using a progress bar").Default("true").BoolVar(&c.showProgress)
+	strRename.Flag("force", "Confirms deleting the Stream and creating it using the new name").Short('f').UnNegatableBoolVar(&c.force)
+
 	gapDetect := str.Command("gaps", "Detect gaps in the Stream content that would be reported as deleted messages").Action(c.detectGaps)
 	gapDetect.Arg("stream", "Stream to act on").StringVar(&c.stream)
 	gapDetect.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
 	gapDetect.Flag("progress", "Enable progress bar").Default("true").BoolVar(&c.showProgress)
 	gapDetect.Flag("json", "Show detected gaps in JSON format").UnNegatableBoolVar(&c.json)
 
+	strGraph := str.Command("consumer-graph", "Shows the progress of all consumers through the Stream").Action(c.consumerGraphAction)
+	strGraph.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strGraph.Flag("watch", "Refresh the graph every 2 seconds").Short('w').UnNegatableBoolVar(&c.graphWatch)
+
+	strFilters := str.Command("consumer-filters", "Shows the filter subjects of all Consumers, warning about Consumers with overlapping filters").Action(c.consumerFiltersAction)
+	strFilters.HelpLong(`Consumers with overlapping filters both receive the messages matching them, on
+a Work Queue Stream this is rejected by the server while on other Streams it
+causes messages to be processed more than once when Consumers are meant to
+partition the Stream. Consumers without filters receive all messages.`)
+	strFilters.Arg("stream", "Stream to act on").StringVar(&c.stream)
+
+	strStale := str.Command("consumers-stale", "Lists Consumers that did not deliver messages recently").Action(c.consumersStaleAction)
+	strStale.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strStale.Flag("idle-threshold", "Consumers that did not deliver a message for longer than this are stale").Default("10m").DurationVar(&c.staleThreshold)
+	strStale.Flag("delete-stale", "Deletes the stale Consumers").UnNegatableBoolVar(&c.staleDelete)
+	strStale.Flag("force", "Delete without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strStale.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strHealth := str.Command("health-score", "Computes a health score from 0 to 100 for a Stream").Action(c.healthScoreAction)
+	strHealth.HelpLong(`The score is lowered by replicas that are not current, mirror and source lag,
+unprocessed messages of Consumers, paused Consumers and the Stream being sealed.
+Each factor is shown with the points it took off the score.`)
+	strHealth.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strHealth.Flag("exit-nonzero-below", "Exit with code 1 when the score is below this, for use in monitoring checks").PlaceHolder("SCORE").IntVar(&c.healthThreshold)
+
+	strConsCleanup := str.Command("consumers-cleanup", "Deletes Consumers with unprocessed messages that did not deliver any recently").Action(c.consumersCleanupAction)
+	strConsCleanup.HelpLong(`Consumers of crashed workers keep unprocessed messages without delivering them,
+Consumers without unprocessed messages are idle rather than stuck and are kept.
+Every Consumer is checked to still not be making progress right before it is deleted.`)
+	strConsCleanup.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strConsCleanup.Flag("no-progress-threshold", "Consumers that did not deliver a message for longer than this are not making progress").Default("10m").DurationVar(&c.staleThreshold)
+	strConsCleanup.Flag("force", "Delete without prompting").Short('f').UnNegatableBoolVar(&c.force)
+
+	strCleanup := str.Command("cleanup", "Deletes empty Streams that were created a while ago").Action(c.cleanupAction)
+	strCleanup.HelpLong(`Streams backing KV buckets, Object Stores and MQTT state are never removed.
+Every Stream is checked to still be empty right before it is deleted.
+
+Streams with Consumers, like work queues kept empty by their Consumers, and
+Streams that mirror or source other Streams are kept unless --in-use is given,
+deleting a Stream also deletes its Consumers.`)
+	strCleanup.Flag("empty", "Delete Streams with no messages").UnNegatableBoolVar(&c.cleanupEmpty)
+	strCleanup.Flag("older-than", "Only delete Streams created longer ago than duration").PlaceHolder("DURATION").DurationVar(&c.cleanupOlderThan)
+	strCleanup.Flag("in-use", "Also delete empty Streams that have Consumers, a mirror or sources").UnNegatableBoolVar(&c.cleanupInUse)
+	strCleanup.Flag("dry-run", "Show the Streams that would be deleted without deleting them").UnNegatableBoolVar(&c.dryRun)
+	strCleanup.Flag("force", "Delete without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strCleanup.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strSimulate := str.Command("simulate", "Estimates what applying new limits to a Stream would remove").Action(c.simulateAction)
+	strSimulate.HelpLong(`Messages are read using the JetStream message get API, the Stream and its
+configuration is not changed. Streams holding more messages than --sample
+are sampled at even sequence intervals and the results are estimates.`)
+	strSimulate.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strSimulate.Flag("max-age", "Maximum age of messages to keep").PlaceHolder("DURATION").StringVar(&c.maxAgeLimit)
+	strSimulate.Flag("max-bytes", "Maximum bytes to keep").PlaceHolder("BYTES").StringVar(&c.maxBytesLimitString)
+	strSimulate.Flag("max-msgs", "Maximum amount of messages to keep").PlaceHolder("MSGS").Int64Var(&c.maxMsgLimit)
+	strSimulate.Flag("sample", "Maximum number of messages to read").Default("10000").IntVar(&c.simulateSample)
+	strSimulate.Flag("progress", "Enable progress bar").Default("true").BoolVar(&c.showProgress)
+	strSimulate.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strSources := str.Command("sources", "Manages the sources of a Stream")
+
+	strSourcesAdd := strSources.Command("add", "Adds a source to a Stream").Action(c.sourcesAddAction)
+	strSourcesAdd.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strSourcesAdd.Flag("source-stream", "The name of the Stream to source messages from").Required().PlaceHolder("STREAM").StringVar(&c.sourceName)
+	strSourcesAdd.Flag("filter-subject", "Only source messages matching this subject").PlaceHolder("SUBJECT").StringVar(&c.sourceFilter)
+	strSourcesAdd.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strSourcesAdd.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strSourcesRm := strSources.Command("remove", "Removes a source from a Stream").Alias("rm").Action(c.sourcesRemoveAction)
+	strSourcesRm.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strSourcesRm.Flag("source-stream", "The name of the source Stream to remove").Required().PlaceHolder("STREAM").StringVar(&c.sourceName)
+	strSourcesRm.Flag("filter-subject", "Only remove the source with this filter subject").PlaceHolder("SUBJECT").StringVar(&c.sourceFilter)
+	strSourcesRm.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strSourcesRm.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strRepubList := str.Command("republish-list", "Shows the republish rules of a Stream").Action(c.republishListAction)
+	strRepubList.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strRepubList.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strRepubAdd := str.Command("republish-add", "Adds a rule republishing stored messages to another subject").Action(c.republishAddAction)
+	strRepubAdd.HelpLong(`Messages stored in the Stream matching the source subject are published
+to the destination subject once stored, the destination may use the
+subject mapping functions of the server. A Stream has at most one rule.`)
+	strRepubAdd.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strRepubAdd.Flag("src", "Republish messages stored on subjects matching this").Required().PlaceHolder("SUBJECT").StringVar(&c.repubSource)
+	strRepubAdd.Flag("dest", "Subject to republish the messages to").Required().PlaceHolder("SUBJECT").StringVar(&c.repubDest)
+	strRepubAdd.Flag("headers-only", "Republish only message headers, no bodies").UnNegatableBoolVar(&c.repubHeadersOnly)
+	strRepubAdd.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strRepubAdd.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strRepubRm := str.Command("republish-remove", "Removes a republish rule from a Stream").Alias("republish-rm").Action(c.republishRemoveAction)
+	strRepubRm.Arg("stream", "Stream to act on").StringVar(&c.stream)
+	strRepubRm.Flag("src", "The source subject of the rule to remove").Required().PlaceHolder("SUBJECT").StringVar(&c.repubSource)
+	strRepubRm.Flag("force", "Act without prompting").Short('f').UnNegatableBoolVar(&c.force)
+	strRepubRm.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
+	strVerify := str.Command("verify-mirror", "Verifies the messages in a mirror match those in the Stream it mirrors").Action(c.verifyMirrorAction)
+	strVerify.Arg("origin", "The Stream being mirrored").Required().StringVar(&c.stream)
+	strVerify.Arg("mirror", "The mirror of the origin Stream").Required().StringVar(&c.verifyMirror)
+	strVerify.Flag("context-b", "Connect to the mirror using a different context").PlaceHolder("CONTEXT").StringVar(&c.verifyContext)
+	strVerify.Flag("samples", "Number of randomly selected messages to compare").Default("100").IntVar(&c.verifySamples)
+	strVerify.Flag("full", "Compare every message rather than a sample").UnNegatableBoolVar(&c.verifyFull)
+	strVerify.Flag("progress", "Enable progress bar").Default("true").BoolVar(&c.showProgress)
+	strVerify.Flag("json", "Produce JSON output").Short('j').UnNegatableBoolVar(&c.json)
+
 	strCluster := str.Command("cluster", "Manages a clustered Stream").Alias("c")
 	strClusterDown := strCluster.Command("step-down", "Force a new leader election by standing down the current leader").Alias("stepdown").Alias("sd").Alias("elect").Alias("down").Alias("d").Action(c.leaderStandDown)
 	strClusterDown.Arg("stream", "Stream to act on").StringVar(&c.stream)
@@ -433,7 +750,7 @@ func (c *streamCmd) detectGaps(_ *fisk.ParseContext) error {
 	if !c.force {
 		fmt.Println("WARNING: Detecting gaps in a stream consumes the entire stream and can be resource intensive on the Server, Client and Network.")
 		fmt.Println()
-		ok, err := askConfirmation(fmt.Sprintf("Really detect gaps in stream %s with %s messages and %s bytes", c.stream, humanize.Comma(int64(info.State.Msgs)), humanize.IBytes(info.State.Bytes)), false)
+		ok, err := askConfirmation(fmt.Sprintf("Really detect gaps in stream %s with %s messages and %s bytes", c.stream, f(info.State.Msgs), fiBytes(info.State.Bytes)), false)
 		fisk.FatalIfError(err, "could not obtain confirmation")
 
 		if !ok {
@@ -729,7 +1046,7 @@ func (c *streamCmd) leaderStandDown(_ *fisk.ParseContext) error {
 
 		info, err = stream.Information()
 		if err != nil {
-			log.Printf("Failed to retrieve Stream State: %s", err)
+			logErrorf("Failed to retrieve Stream State: %s", err)
 			continue
 		}
 
@@ -809,6 +1126,12 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 		c.vwPageSize = 25
 	}
 
+	transform, err := newPayloadTransformPipeline(nil, c.vwTranslate)
+	if err != nil {
+		return err
+	}
+	c.vwTransform = transform
+
 	c.connectAndAskStream()
 
 	str, err := c.loadStream(c.stream)
@@ -892,7 +1215,7 @@ func (c *streamCmd) viewAction(_ *fisk.ParseContext) error {
 				}
 			}
 
-			outPutMSGBody(msg.Data, c.vwTranslate, msg.Subject, meta.Stream())
+			outPutMSGBody(msg.Data, c.vwTransform, msg.Subject, meta.Stream())
 		}
 
 		if shouldTerminate {
@@ -965,7 +1288,7 @@ func (c *streamCmd) restoreAction(_ *fisk.ParseContext) error {
 
 		if progress == nil {
 			progress = uiprogress.AddBar(p.ChunksToSend()).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
-				return humanize.IBytes(bps) + "/s"
+				return fiBytes(bps) + "/s"
 			})
 			progress.Width = progressWidth()
 		}
@@ -1067,13 +1390,13 @@ func backupStream(stream *jsm.Stream, showProgress bool, consumers bool, check b
 				expected = int(p.BytesExpected())
 			}
 			bar = progress.AddBar(expected).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
-				return humanize.IBytes(bps) + "/s"
+				return fiBytes(bps) + "/s"
 			})
 			bar.Width = progressWidth()
 		}
 
 		if first {
-			fmt.Printf("Starting backup of Stream %q with %s\n", stream.Name(), humanize.IBytes(p.BytesExpected()))
+			fmt.Printf("Starting backup of Stream %q with %s\n", stream.Name(), fiBytes(p.BytesExpected()))
 			if showProgress {
 				fmt.Println()
 			}
@@ -1162,7 +1485,7 @@ func backupStream(stream *jsm.Stream, showProgress bool, consumers bool, check b
 		return fmt.Errorf("backup timed out after receiving no data for a long period")
 	}
 
-	fmt.Printf("Received %s compressed data in %s chunks for stream %q in %v, %s uncompressed \n", humanize.IBytes(fp.BytesReceived()), f(fp.ChunksReceived()), stream.Name(), fp.EndTime().Sub(fp.StartTime()).Round(time.Millisecond), humanize.IBytes(fp.UncompressedBytesReceived()))
+	fmt.Printf("Received %s compressed data in %s chunks for stream %q in %v, %s uncompressed \n", fiBytes(fp.BytesReceived()), f(fp.ChunksReceived()), stream.Name(), fp.EndTime().Sub(fp.StartTime()).Round(time.Millisecond), fiBytes(fp.UncompressedBytesReceived()))
 
 	return nil
 }
@@ -1238,6 +1561,7 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 
 		s := streamStat{
 			Name:      info.Config.Name,
+			Subjects:  info.Config.Subjects,
 			Consumers: info.State.Consumers,
 			Msgs:      int64(info.State.Msgs),
 			Bytes:     info.State.Bytes,
@@ -1255,6 +1579,18 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 			s.LostMsgs = len(info.State.Lost.Msgs)
 		}
 
+		if info.State.Consumers > 0 {
+			_, err = stream.EachConsumer(func(cons *jsm.Consumer) {
+				state, err := cons.LatestState()
+				if err == nil && state.NumPending > s.MaxPending {
+					s.MaxPending = state.NumPending
+				}
+			})
+			fisk.FatalIfError(err, "could not get consumers for %s", stream.Name())
+		}
+
+		c.evaluateStreamHealth(&s)
+
 		if len(info.Config.Sources) > 0 {
 			showReplication = true
 			node, ok := dg.FindNodeById(info.Config.Name)
@@ -1318,10 +1654,17 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 		sort.Slice(stats, func(i, j int) bool { return stats[i].Bytes < stats[j].Bytes })
 	}
 
-	c.renderStreams(stats)
+	if c.json {
+		return iu.PrintJSON(stats)
+	}
+
+	out := newPagedWriter()
+	defer out.Close()
+
+	c.renderStreams(out, stats)
 
 	if showReplication {
-		c.renderReplication(stats)
+		c.renderReplication(out, stats)
 
 		if c.outFile != "" {
 			os.WriteFile(c.outFile, []byte(dg.String()), 0600)
@@ -1329,15 +1672,52 @@ func (c *streamCmd) reportAction(_ *fisk.ParseContext) error {
 	}
 
 	if c.reportLeaderDistrib && len(leaders) > 0 {
-		renderRaftLeaders(leaders, "Streams")
+		renderRaftLeaders(out, leaders, "Streams")
 	}
 
-	c.renderMissing(os.Stdout, missing)
+	c.renderMissing(out, missing)
 
 	return nil
 }
 
-func (c *streamCmd) renderReplication(stats []streamStat) {
+// evaluateStreamHealth marks a stream critical when its mirror is failing and warning when consumers fall behind
+func (c *streamCmd) evaluateStreamHealth(s *streamStat) {
+	s.Health = streamHealthOK
+	s.HealthReasons = nil
+
+	if s.Mirror != nil {
+		switch {
+		case s.Mirror.Error != nil:
+			s.HealthReasons = append(s.HealthReasons, fmt.Sprintf("mirror failed: %v", s.Mirror.Error))
+		case s.Mirror.Active < 0 || (c.reportSeenCritical > 0 && s.Mirror.Active > c.reportSeenCritical):
+			s.HealthReasons = append(s.HealthReasons, "mirror is not active")
+		}
+
+		if len(s.HealthReasons) > 0 {
+			s.Health = streamHealthCritical
+		}
+	}
+
+	if c.reportPendingWarn > 0 && s.MaxPending > c.reportPendingWarn {
+		s.HealthReasons = append(s.HealthReasons, fmt.Sprintf("consumer has %s pending messages", f(s.MaxPending)))
+		if s.Health == streamHealthOK {
+			s.Health = streamHealthWarning
+		}
+	}
+}
+
+func streamHealthIcon(health string) string {
+	switch health {
+	case streamHealthCritical:
+		return color.RedString("✗")
+	case streamHealthWarning:
+		return color.YellowString("⚠")
+	default:
+		return color.GreenString("✓")
+	}
+}
+
+func (c *streamCmd) renderReplication(out io.Writer, stats []streamStat) {
 	table := newTableWriter("Replication Report")
 	table.AddHeaders("Stream", "Kind", "API Prefix", "Source Stream", "Filters and Transforms", "Active", "Lag", "Error")
 
@@ -1393,12 +1773,12 @@ func (c *streamCmd) renderReplication(stats []streamStat) {
 
 		}
 	}
-	fmt.Println(table.Render())
+	fmt.Fprintln(out, table.Render())
 }
 
-func (c *streamCmd) renderStreams(stats []streamStat) {
+func (c *streamCmd) renderStreams(out io.Writer, stats []streamStat) {
 	table := newTableWriter("Stream Report")
-	table.AddHeaders("Stream", "Storage", "Placement", "Consumers", "Messages", "Bytes", "Lost", "Deleted", "Replicas")
+	table.AddHeaders("", "Stream", "Subjects", "Storage", "Placement", "Consumers", "Messages", "Bytes", "Lost", "Deleted", "Mirror Lag", "Replicas")
 
 	for _, s := range stats {
 		lost := "0"
@@ -1412,20 +1792,45 @@ func (c *streamCmd) renderStreams(stats []streamStat) {
 			}
 		}
 
+		subjects := strings.Join(s.Subjects, ", ")
+		mirrorLag := ""
+
 		if c.reportRaw {
 			if s.LostMsgs > 0 {
 				lost = fmt.Sprintf("%d (%d)", s.LostMsgs, s.LostBytes)
 			}
-			table.AddRow(s.Name, s.Storage, placement, s.Consumers, s.Msgs, s.Bytes, lost, s.Deleted, renderCluster(s.Cluster))
+			if s.Mirror != nil {
+				mirrorLag = strconv.FormatUint(s.Mirror.Lag, 10)
+			}
+			table.AddRow(streamHealthIcon(s.Health), s.Name, subjects, s.Storage, placement, s.Consumers, s.Msgs, s.Bytes, lost, s.Deleted, mirrorLag, renderCluster(s.Cluster))
 		} else {
 			if s.LostMsgs > 0 {
-				lost = fmt.Sprintf("%s (%s)", f(s.LostMsgs), humanize.IBytes(s.LostBytes))
+				lost = fmt.Sprintf("%s (%s)", f(s.LostMsgs), fiBytes(s.LostBytes))
+			}
+			if s.Mirror != nil {
+				mirrorLag = f(s.Mirror.Lag)
 			}
-			table.AddRow(s.Name, s.Storage, placement, f(s.Consumers), f(s.Msgs), humanize.IBytes(s.Bytes), lost, f(s.Deleted), renderCluster(s.Cluster))
+			table.AddRow(streamHealthIcon(s.Health), s.Name, subjects, s.Storage, placement, f(s.Consumers), fCount(s.Msgs), fiBytes(s.Bytes), lost, fCount(s.Deleted), mirrorLag, renderCluster(s.Cluster))
 		}
 	}
 
-	fmt.Println(table.Render())
+	fmt.Fprintln(out, table.Render())
+
+	var unhealthy []string
+	for _, s := range stats {
+		if s.Health != streamHealthOK {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s %s: %s", streamHealthIcon(s.Health), s.Name, strings.Join(s.HealthReasons, ", ")))
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		fmt.Fprintln(out, "Unhealthy Streams:")
+		fmt.Fprintln(out)
+		for _, u := range unhealthy {
+			fmt.Fprintf(out, "   %s\n", u)
+		}
+		fmt.Fprintln(out)
+	}
 }
 
 func (c *streamCmd) loadConfigFile(file string) (*api.StreamConfig, error) {
@@ -1517,6 +1922,11 @@ func (c *streamCmd) copyAndEditStream(cfg api.StreamConfig, pc *fisk.ParseContex
 
 	if len(c.subjects) > 0 {
 		cfg.Subjects = splitCLISubjects(c.subjects)
+
+		err = validateFilterSubjects(cfg.Subjects)
+		if err != nil {
+			return api.StreamConfig{}, err
+		}
 	}
 
 	if c.storage != "" {
@@ -1885,7 +2295,7 @@ func (c *streamCmd) showStreamConfig(cols *columns.Writer, cfg api.StreamConfig)
 	if cfg.MaxBytes == -1 {
 		cols.AddRow("Maximum Bytes", "unlimited")
 	} else {
-		cols.AddRow("Maximum Bytes", humanize.IBytes(uint64(cfg.MaxBytes)))
+		cols.AddRow("Maximum Bytes", fiBytes(uint64(cfg.MaxBytes)))
 	}
 	if cfg.MaxAge <= 0 {
 		cols.AddRow("Maximum Age", "unlimited")
@@ -1895,7 +2305,7 @@ func (c *streamCmd) showStreamConfig(cols *columns.Writer, cfg api.StreamConfig)
 	if cfg.MaxMsgSize == -1 {
 		cols.AddRow("Maximum Message Size", "unlimited")
 	} else {
-		cols.AddRow("Maximum Message Size", humanize.IBytes(uint64(cfg.MaxMsgSize)))
+		cols.AddRow("Maximum Message Size", fiBytes(uint64(cfg.MaxMsgSize)))
 	}
 	if cfg.MaxConsumers == -1 {
 		cols.AddRow("Maximum Consumers", "unlimited")
@@ -2009,7 +2419,7 @@ func (c *streamCmd) showStreamInfo(info *api.StreamInfo) {
 			}
 
 			if r.Active > 0 && r.Active < math.MaxInt64 {
-				state = append(state, fmt.Sprintf("seen %s ago", f(r.Active)))
+				state = append(state, "seen "+fAgo(r.Active))
 			} else {
 				state = append(state, "not seen")
 			}
@@ -2093,10 +2503,10 @@ func (c *streamCmd) showStreamInfo(info *api.StreamInfo) {
 
 	cols.AddSectionTitle("State")
 	cols.AddRow("Messages", info.State.Msgs)
-	cols.AddRow("Bytes", humanize.IBytes(info.State.Bytes))
+	cols.AddRow("Bytes", fiBytes(info.State.Bytes))
 
 	if info.State.Lost != nil && len(info.State.Lost.Msgs) > 0 {
-		cols.AddRowf("Lost Messages", "%s (%s)", f(len(info.State.Lost.Msgs)), humanize.IBytes(info.State.Lost.Bytes))
+		cols.AddRowf("Lost Messages", "%s (%s)", f(len(info.State.Lost.Msgs)), fiBytes(info.State.Lost.Bytes))
 	}
 
 	if info.State.FirstTime.Equal(time.Unix(0, 0)) || info.State.FirstTime.IsZero() {
@@ -2200,7 +2610,7 @@ func (c *streamCmd) retentionPolicyFromString() api.RetentionPolicy {
 		return api.LimitsPolicy
 	case "interest":
 		return api.InterestPolicy
-	case "work queue", "workq", "work":
+	case "work queue", "work-queue", "workq", "work":
 		return api.WorkQueuePolicy
 	default:
 		fisk.Fatalf("invalid retention policy %s", c.retentionPolicyS)
@@ -2272,6 +2682,9 @@ func (c *streamCmd) prepareConfig(_ *fisk.ParseContext, requireSize bool) api.St
 		}
 
 		c.subjects = splitCLISubjects(c.subjects)
+
+		err = validateFilterSubjects(c.subjects)
+		fisk.FatalIfError(err, "invalid Stream subjects")
 	}
 
 	if c.mirror != "" && len(c.subjects) > 0 {
@@ -3022,7 +3435,11 @@ func (c *streamCmd) renderStreamsAsTable(streams []*jsm.Stream, missing []string
 	table.AddHeaders("Name", "Description", "Created", "Messages", "Size", "Last Message")
 	for _, s := range streams {
 		nfo, _ := s.LatestInformation()
-		table.AddRow(s.Name(), s.Description(), f(nfo.Created.Local()), f(nfo.State.Msgs), humanize.IBytes(nfo.State.Bytes), f(sinceRefOrNow(nfo.TimeStamp, nfo.State.LastTime)))
+		last := "never"
+		if nfo.State.Msgs > 0 && !nfo.State.LastTime.IsZero() {
+			last = fAgo(sinceRefOrNow(nfo.TimeStamp, nfo.State.LastTime))
+		}
+		table.AddRow(s.Name(), s.Description(), f(nfo.Created.Local()), fCount(nfo.State.Msgs), fiBytes(nfo.State.Bytes), last)
 	}
 
 	fmt.Fprintln(&out, table.Render())
@@ -3086,6 +3503,11 @@ func (c *streamCmd) rmMsgAction(_ *fisk.ParseContext) (err error) {
 }
 
 func (c *streamCmd) getAction(_ *fisk.ParseContext) (err error) {
+	c.vwTransform, err = newPayloadTransformPipeline(nil, c.vwTranslate)
+	if err != nil {
+		return err
+	}
+
 	c.connectAndAskStream()
 
 	if c.msgID == -1 && c.filterSubject == "" {
@@ -3141,10 +3563,226 @@ func (c *streamCmd) getAction(_ *fisk.ParseContext) (err error) {
 		}
 		fmt.Println()
 	}
-	outPutMSGBody(item.Data, c.vwTranslate, item.Subject, c.stream)
+	outPutMSGBody(item.Data, c.vwTransform, item.Subject, c.stream)
 	return nil
 }
 
+// streamDumpBatchSize is how many messages are requested at a time when dumping a stream
+const streamDumpBatchSize = 256
+
+// streamDumpMsg is a single line in a stream dump
+type streamDumpMsg struct {
+	Subject  string      `json:"subject"`
+	Sequence uint64      `json:"seq"`
+	Time     time.Time   `json:"time"`
+	Header   nats.Header `json:"headers,omitempty"`
+	Data     []byte      `json:"data,omitempty"`
+}
+
+func (c *streamCmd) dumpAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.LatestInformation()
+	if err != nil {
+		return err
+	}
+
+	start := max(c.dumpStartSeq, nfo.State.FirstSeq)
+	end := nfo.State.LastSeq
+	if c.dumpEndSeq > 0 && c.dumpEndSeq < end {
+		end = c.dumpEndSeq
+	}
+
+	if nfo.State.Msgs == 0 || start > end {
+		log.Printf("No messages to dump in Stream %s", c.stream)
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	if c.outFile != "-" {
+		f, err := os.Create(c.outFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	var bar *uiprogress.Bar
+	if c.showProgress {
+		progress := uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(int(end - start + 1)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		})
+		progress.Start()
+		defer func() {
+			time.Sleep(250 * time.Millisecond) // let it draw
+			progress.Stop()
+		}()
+	}
+
+	cnt := 0
+	write := func(msg *streamDumpMsg) error {
+		j, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintln(w, string(j))
+		if err != nil {
+			return err
+		}
+
+		cnt++
+		if bar != nil {
+			bar.Set(int(msg.Sequence - start + 1))
+		}
+
+		return nil
+	}
+
+	if stream.DirectAllowed() {
+		err = c.dumpDirect(stream, start, end, write)
+	} else {
+		err = c.dumpSequential(stream, start, end, write)
+	}
+	if err != nil {
+		return err
+	}
+
+	if bar != nil {
+		bar.Set(bar.Total)
+	}
+
+	log.Printf("Dumped %s messages from Stream %s", f(cnt), c.stream)
+
+	return nil
+}
+
+// dumpDirect reads messages in batches using the direct get API
+func (c *streamCmd) dumpDirect(stream *jsm.Stream, start uint64, end uint64, write func(*streamDumpMsg) error) error {
+	seq := start
+
+	for seq <= end {
+		var last uint64
+		var werr error
+
+		_, _, _, err := stream.DirectGet(ctx, api.JSApiMsgGetRequest{Seq: seq, NextFor: c.filterSubject, Batch: streamDumpBatchSize}, func(m *nats.Msg) {
+			if werr != nil {
+				return
+			}
+
+			msg, err := streamDumpMsgFromDirect(m)
+			if err != nil {
+				werr = err
+				return
+			}
+
+			last = msg.Sequence
+			if msg.Sequence > end {
+				return
+			}
+
+			werr = write(msg)
+		})
+		if err != nil {
+			if err.Error() == "no messages found matching request" {
+				return nil
+			}
+			return err
+		}
+		if werr != nil {
+			return werr
+		}
+
+		if last == 0 {
+			return nil
+		}
+
+		seq = last + 1
+	}
+
+	return nil
+}
+
+// dumpSequential reads messages one by one for streams that do not allow direct gets
+func (c *streamCmd) dumpSequential(stream *jsm.Stream, start uint64, end uint64, write func(*streamDumpMsg) error) error {
+	for seq := start; seq <= end; seq++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		item, err := stream.ReadMessage(seq)
+		if jsm.IsNatsError(err, 10037) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if c.filterSubject != "" && !api.SubjectIsSubsetMatch(item.Subject, c.filterSubject) {
+			continue
+		}
+
+		msg := &streamDumpMsg{Subject: item.Subject, Sequence: item.Sequence, Time: item.Time, Data: item.Data}
+		if len(item.Header) > 0 {
+			msg.Header, err = decodeHeadersMsg(item.Header)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = write(msg)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func streamDumpMsgFromDirect(m *nats.Msg) (*streamDumpMsg, error) {
+	seq, err := strconv.ParseUint(m.Header.Get("Nats-Sequence"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sequence in direct get response: %w", err)
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, m.Header.Get("Nats-Time-Stamp"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid time stamp in direct get response: %w", err)
+	}
+
+	msg := &streamDumpMsg{
+		Subject:  m.Header.Get("Nats-Subject"),
+		Sequence: seq,
+		Time:     ts,
+		Data:     m.Data,
+	}
+
+	for k, v := range m.Header {
+		switch k {
+		case "Nats-Stream", "Nats-Subject", "Nats-Sequence", "Nats-Time-Stamp", "Nats-Num-Pending", "Nats-Last-Sequence":
+			continue
+		}
+
+		if msg.Header == nil {
+			msg.Header = nats.Header{}
+		}
+		msg.Header[k] = v
+	}
+
+	return msg, nil
+}
+
 func (c *streamCmd) connectAndAskStream() bool {
 	var err error
 
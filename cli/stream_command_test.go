@@ -0,0 +1,293 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/jsm.go/api"
+)
+
+func TestEvaluateStreamHealth(t *testing.T) {
+	c := &streamCmd{reportPendingWarn: 10000, reportSeenCritical: time.Minute}
+
+	cases := []struct {
+		name   string
+		stat   streamStat
+		health string
+	}{
+		{"healthy", streamStat{MaxPending: 10}, streamHealthOK},
+		{"pending", streamStat{MaxPending: 10001}, streamHealthWarning},
+		{"active mirror", streamStat{Mirror: &api.StreamSourceInfo{Active: time.Second}}, streamHealthOK},
+		{"stale mirror", streamStat{Mirror: &api.StreamSourceInfo{Active: time.Hour}}, streamHealthCritical},
+		{"unseen mirror", streamStat{Mirror: &api.StreamSourceInfo{Active: -1}}, streamHealthCritical},
+		{"failed mirror", streamStat{Mirror: &api.StreamSourceInfo{Active: time.Second, Error: &api.ApiError{Code: 500, Description: "failed"}}}, streamHealthCritical},
+		{"stale mirror and pending", streamStat{MaxPending: 20000, Mirror: &api.StreamSourceInfo{Active: time.Hour}}, streamHealthCritical},
+	}
+
+	for _, tc := range cases {
+		s := tc.stat
+		c.evaluateStreamHealth(&s)
+		if s.Health != tc.health {
+			t.Fatalf("%s: expected %s got %s: %v", tc.name, tc.health, s.Health, s.HealthReasons)
+		}
+		if (s.Health == streamHealthOK) != (len(s.HealthReasons) == 0) {
+			t.Fatalf("%s: unexpected reasons %v", tc.name, s.HealthReasons)
+		}
+	}
+}
+
+func TestConsumerGraphBar(t *testing.T) {
+	cases := []struct {
+		first, last, seq uint64
+		bar              string
+	}{
+		{1, 10, 0, "░░░░░░░░░░"},
+		{1, 10, 5, "█████░░░░░"},
+		{1, 10, 10, "██████████"},
+		{11, 20, 5, "░░░░░░░░░░"},
+		{11, 20, 15, "█████░░░░░"},
+		{0, 0, 0, "██████████"},
+	}
+
+	for _, tc := range cases {
+		bar := consumerGraphBar(tc.first, tc.last, tc.seq, 10)
+		if bar != tc.bar {
+			t.Fatalf("%d-%d at %d: expected %q got %q", tc.first, tc.last, tc.seq, tc.bar, bar)
+		}
+	}
+
+	state := &api.ConsumerInfo{Config: api.ConsumerConfig{AckPolicy: api.AckNone}, Delivered: api.SequenceInfo{Stream: 10}, AckFloor: api.SequenceInfo{Stream: 2}}
+	if consumerGraphProgress(state) != 10 {
+		t.Fatalf("expected delivered sequence for ack none consumers")
+	}
+	state.Config.AckPolicy = api.AckExplicit
+	if consumerGraphProgress(state) != 2 {
+		t.Fatalf("expected ack floor for acknowledged consumers")
+	}
+}
+
+func TestSimulateRetention(t *testing.T) {
+	now := time.Now()
+
+	// 10 messages of 100 bytes, one per hour, alternating between two subjects
+	var samples []retentionSample
+	for i := 0; i < 10; i++ {
+		subj := "orders.new"
+		if i%2 == 1 {
+			subj = "orders.shipped"
+		}
+		samples = append(samples, retentionSample{Subject: subj, Time: now.Add(-time.Duration(10-i) * time.Hour), Size: 80})
+	}
+	state := api.StreamState{Msgs: 10, Bytes: 1000}
+
+	sim := simulateRetention(samples, state, 5*time.Hour+time.Minute, 0, 0, now)
+	if sim.RemovedMessages != 5 || sim.RemovedBytes != 500 {
+		t.Fatalf("expected 5 messages and 500 bytes removed by age: %+v", sim)
+	}
+	if len(sim.Subjects) != 2 || sim.Subjects[0].Subject != "orders.new" || sim.Subjects[0].Messages != 3 || sim.Subjects[1].Messages != 2 {
+		t.Fatalf("unexpected subjects: %+v %+v", sim.Subjects[0], sim.Subjects[1])
+	}
+
+	sim = simulateRetention(samples, state, 0, 301, 0, now)
+	if sim.RemovedMessages != 7 || sim.RemovedBytes != 700 {
+		t.Fatalf("expected 7 messages removed by bytes: %+v", sim)
+	}
+
+	sim = simulateRetention(samples, state, 30*time.Minute, 0, 8, now)
+	if sim.RemovedMessages != 10 {
+		t.Fatalf("expected all messages removed: %+v", sim)
+	}
+
+	sim = simulateRetention(samples, state, 24*time.Hour, 0, 8, now)
+	if sim.RemovedMessages != 2 {
+		t.Fatalf("expected 2 messages removed by count: %+v", sim)
+	}
+
+	// every other message sampled from a stream of 1000 messages
+	var sampled []retentionSample
+	for i := 0; i < 10; i += 2 {
+		sampled = append(sampled, samples[i])
+	}
+	sim = simulateRetention(sampled, api.StreamState{Msgs: 1000, Bytes: 100000}, 0, 0, 500, now)
+	if sim.RemovedMessages != 500 || sim.RemovedBytes != 50000 || sim.Sampled != 5 {
+		t.Fatalf("expected half of the stream removed: %+v", sim)
+	}
+}
+
+func TestStreamPeerChanges(t *testing.T) {
+	before := &api.ClusterInfo{Leader: "n1", Replicas: []*api.PeerInfo{{Name: "n2", Current: true}, {Name: "n3", Current: true}}}
+	after := &api.ClusterInfo{Leader: "n1", Replicas: []*api.PeerInfo{{Name: "n3", Current: true}, {Name: "n4"}, {Name: "n5", Offline: true}}}
+
+	added, removed := streamPeerChanges(before, after)
+	if !reflect.DeepEqual(added, []string{"n4", "n5"}) || !reflect.DeepEqual(removed, []string{"n2"}) {
+		t.Fatalf("unexpected changes added %v removed %v", added, removed)
+	}
+
+	if d := describeStreamPeers(after, added); d != "leader n1, replicas n3, n4 (new) outdated, n5 (new) OFFLINE" {
+		t.Fatalf("unexpected description %q", d)
+	}
+
+	if d := describeStreamPeers(&api.ClusterInfo{Leader: "n1"}, nil); d != "leader n1" {
+		t.Fatalf("unexpected description %q", d)
+	}
+
+	if d := describeStreamPeers(nil, nil); d != "no cluster information" {
+		t.Fatalf("unexpected description %q", d)
+	}
+}
+
+func TestStorageConvertVerify(t *testing.T) {
+	before := api.StreamState{Msgs: 10, NumSubjects: 2, FirstSeq: 5, LastSeq: 20}
+
+	for _, tc := range []struct {
+		name       string
+		after      api.StreamState
+		renumbered uint64
+		err        bool
+	}{
+		{"same", before, 0, false},
+		{"renumbered", api.StreamState{Msgs: 10, NumSubjects: 2, FirstSeq: 5, LastSeq: 14}, 6, false},
+		{"missing messages", api.StreamState{Msgs: 9, NumSubjects: 2, FirstSeq: 5, LastSeq: 20}, 0, true},
+		{"missing subjects", api.StreamState{Msgs: 10, NumSubjects: 1, FirstSeq: 5, LastSeq: 20}, 0, true},
+		{"moved", api.StreamState{Msgs: 10, NumSubjects: 2, FirstSeq: 1, LastSeq: 20}, 0, true},
+		{"unexpected last", api.StreamState{Msgs: 10, NumSubjects: 2, FirstSeq: 5, LastSeq: 14}, 0, true},
+	} {
+		err := storageConvertVerify(before, tc.after, tc.renumbered)
+		if (err != nil) != tc.err {
+			t.Fatalf("%s: unexpected result %v", tc.name, err)
+		}
+	}
+}
+
+func TestStreamSubjectsEdit(t *testing.T) {
+	updated, added := streamSubjectsAdd([]string{"orders.>"}, []string{"orders.>", "returns.>"})
+	if !reflect.DeepEqual(updated, []string{"orders.>", "returns.>"}) || !reflect.DeepEqual(added, []string{"returns.>"}) {
+		t.Fatalf("unexpected subjects %v added %v", updated, added)
+	}
+
+	updated, err := streamSubjectsRemove([]string{"orders.>", "returns.>"}, []string{"orders.>"})
+	if err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if !reflect.DeepEqual(updated, []string{"returns.>"}) {
+		t.Fatalf("unexpected subjects %v", updated)
+	}
+
+	_, err = streamSubjectsRemove([]string{"orders.>"}, []string{"returns.>"})
+	if err == nil {
+		t.Fatalf("expected an error removing an unknown subject")
+	}
+
+	_, err = streamSubjectsRemove([]string{"orders.>"}, []string{"orders.>"})
+	if err == nil {
+		t.Fatalf("expected an error removing the last subject")
+	}
+}
+
+func TestStreamSubjectsRename(t *testing.T) {
+	for _, tc := range []struct {
+		subject string
+		old     string
+		new     string
+		expect  string
+		changed bool
+	}{
+		{"orders.v1.>", "orders.v1.>", "orders.v2.>", "orders.v2.>", true},
+		{"orders.v1.created", "orders.v1.>", "orders.v2.>", "orders.v2.created", true},
+		{"orders.v1.*.eu", "orders.v1.>", "orders.v2.>", "orders.v2.*.eu", true},
+		{"orders.v10.created", "orders.v1.>", "orders.v2.>", "orders.v10.created", false},
+		{"orders.v1.created", "orders.v1.>", "orders.new", "orders.v1.created", false},
+		{"orders.v1", "orders.v1", "orders.v2", "orders.v2", true},
+		{"returns.>", "orders.v1.>", "orders.v2.>", "returns.>", false},
+	} {
+		renamed, changed := streamSubjectRename(tc.subject, tc.old, tc.new)
+		if renamed != tc.expect || changed != tc.changed {
+			t.Fatalf("renaming %s from %s to %s expected %s %v got %s %v", tc.subject, tc.old, tc.new, tc.expect, tc.changed, renamed, changed)
+		}
+	}
+
+	renamed, changed := streamSubjectsRename([]string{"orders.v1.>", "orders.v2.>", "returns.>"}, "orders.v1.>", "orders.v2.>")
+	if !changed || !reflect.DeepEqual(renamed, []string{"orders.v2.>", "returns.>"}) {
+		t.Fatalf("unexpected subjects %v %v", renamed, changed)
+	}
+}
+
+func TestStreamHealthScore(t *testing.T) {
+	nfo := &api.StreamInfo{
+		Config: api.StreamConfig{Replicas: 3},
+		State:  api.StreamState{Msgs: 100},
+		Cluster: &api.ClusterInfo{Leader: "n1", Replicas: []*api.PeerInfo{
+			{Name: "n2", Current: true},
+			{Name: "n3", Current: true},
+		}},
+	}
+
+	score, factors := streamHealthScore(nfo, nil)
+	if score != 100 || len(factors) != 5 {
+		t.Fatalf("expected a healthy stream to score 100 got %d", score)
+	}
+
+	nfo.Cluster.Replicas[1].Offline = true
+	nfo.Mirror = &api.StreamSourceInfo{Name: "ORIGIN", Lag: 100}
+	nfo.Config.Sealed = true
+	consumers := []api.ConsumerInfo{{NumPending: 100, Paused: true}, {NumPending: 0}}
+
+	// replicas 30/3, mirror lag 25/2, consumer lag 25/2, paused 10/2 and sealed 10
+	score, factors = streamHealthScore(nfo, consumers)
+	penalties := map[string]int{}
+	for _, factor := range factors {
+		penalties[factor.name] = factor.penalty
+	}
+	expected := map[string]int{"Replication": 10, "Mirror and Source Lag": 13, "Consumer Lag": 13, "Paused Consumers": 5, "Sealed": 10}
+	if !reflect.DeepEqual(penalties, expected) {
+		t.Fatalf("expected penalties %v got %v", expected, penalties)
+	}
+	if score != 49 {
+		t.Fatalf("expected score 49 got %d", score)
+	}
+
+	nfo.Cluster.Leader = ""
+	nfo.Mirror.Error = &api.ApiError{Description: "stream not found"}
+	score, _ = streamHealthScore(nfo, consumers)
+	if score != 100-30-25-13-5-10 {
+		t.Fatalf("expected no leader and a failed mirror to take the full weights got %d", score)
+	}
+}
+
+func TestFindConsumerFilterOverlaps(t *testing.T) {
+	filters := map[string][]string{
+		"EU":     consumerFilterSubjects(api.ConsumerConfig{FilterSubject: "orders.eu.*"}),
+		"US":     consumerFilterSubjects(api.ConsumerConfig{FilterSubjects: []string{"orders.us.new", "orders.us.shipped"}}),
+		"NEW":    consumerFilterSubjects(api.ConsumerConfig{FilterSubjects: []string{"orders.*.new"}}),
+		"REFUND": consumerFilterSubjects(api.ConsumerConfig{FilterSubject: "refunds.>"}),
+	}
+
+	overlaps := findConsumerFilterOverlaps(filters)
+	expect := []*consumerFilterOverlap{
+		{consumer: "EU", filter: "orders.eu.*", otherConsumer: "NEW", otherFilter: "orders.*.new"},
+		{consumer: "NEW", filter: "orders.*.new", otherConsumer: "US", otherFilter: "orders.us.new"},
+	}
+	if !reflect.DeepEqual(overlaps, expect) {
+		t.Fatalf("unexpected overlaps %+v", overlaps)
+	}
+
+	filters["ALL"] = consumerFilterSubjects(api.ConsumerConfig{})
+	overlaps = findConsumerFilterOverlaps(filters)
+	if len(overlaps) != 6 {
+		t.Fatalf("expected an unfiltered consumer to overlap every other got %+v", overlaps)
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// consumerFilterOverlap is a pair of Consumers with filters matching some of the same subjects
+type consumerFilterOverlap struct {
+	consumer      string
+	filter        string
+	otherConsumer string
+	otherFilter   string
+}
+
+// consumerFilterSubjects is the filter subjects of a Consumer, a Consumer without filters receives all messages
+func consumerFilterSubjects(cfg api.ConsumerConfig) []string {
+	switch {
+	case len(cfg.FilterSubjects) > 0:
+		return cfg.FilterSubjects
+	case cfg.FilterSubject != "":
+		return []string{cfg.FilterSubject}
+	default:
+		return []string{">"}
+	}
+}
+
+// findConsumerFilterOverlaps finds every pair of Consumers that would both receive some messages, reporting the first
+// filters found to overlap for each pair
+func findConsumerFilterOverlaps(filters map[string][]string) []*consumerFilterOverlap {
+	names := mapKeys(filters)
+	sort.Strings(names)
+
+	var overlaps []*consumerFilterOverlap
+	for i, name := range names {
+		for _, other := range names[i+1:] {
+		pair:
+			for _, filter := range filters[name] {
+				for _, otherFilter := range filters[other] {
+					if server.SubjectsCollide(filter, otherFilter) {
+						overlaps = append(overlaps, &consumerFilterOverlap{consumer: name, filter: filter, otherConsumer: other, otherFilter: otherFilter})
+						break pair
+					}
+				}
+			}
+		}
+	}
+
+	return overlaps
+}
+
+func (c *streamCmd) consumerFiltersAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	filters := map[string][]string{}
+	_, err = stream.EachConsumer(func(cons *jsm.Consumer) {
+		filters[cons.Name()] = consumerFilterSubjects(cons.Configuration())
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(filters) == 0 {
+		fmt.Printf("Stream %s has no Consumers\n", c.stream)
+		return nil
+	}
+
+	overlaps := findConsumerFilterOverlaps(filters)
+	overlapping := map[string][]string{}
+	for _, o := range overlaps {
+		overlapping[o.consumer] = append(overlapping[o.consumer], o.otherConsumer)
+		overlapping[o.otherConsumer] = append(overlapping[o.otherConsumer], o.consumer)
+	}
+
+	names := mapKeys(filters)
+	sort.Strings(names)
+
+	table := newTableWriter(fmt.Sprintf("Consumer filters for Stream %s", c.stream))
+	table.AddHeaders("Consumer", "Filter Subjects", "Overlaps With")
+	for _, name := range names {
+		others := overlapping[name]
+		sort.Strings(others)
+		table.AddRow(name, strings.Join(filters[name], "\n"), strings.Join(others, ", "))
+	}
+	fmt.Println(table.Render())
+
+	if len(overlaps) == 0 {
+		fmt.Printf("No overlapping filters found for %s Consumers\n", f(len(filters)))
+		return nil
+	}
+
+	for _, o := range overlaps {
+		logWarnf("Consumers %s and %s overlap on %s and %s, messages matching both are delivered to each", o.consumer, o.otherConsumer, o.filter, o.otherFilter)
+	}
+
+	return nil
+}
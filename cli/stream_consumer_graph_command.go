@@ -0,0 +1,131 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+const consumerGraphInterval = 2 * time.Second
+
+func (c *streamCmd) consumerGraphAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	if !c.graphWatch {
+		return c.renderConsumerGraph(stream)
+	}
+
+	ticker := time.NewTicker(consumerGraphInterval)
+	defer ticker.Stop()
+
+	for {
+		clearScreen()
+		err = c.renderConsumerGraph(stream)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *streamCmd) renderConsumerGraph(stream *jsm.Stream) error {
+	info, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	var states []*api.ConsumerInfo
+	_, err = stream.EachConsumer(func(cons *jsm.Consumer) {
+		state, err := cons.LatestState()
+		if err != nil {
+			logErrorf("Could not load state for consumer %s: %v", cons.Name(), err)
+			return
+		}
+		states = append(states, &state)
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(states) == 0 {
+		fmt.Printf("No Consumers defined for Stream %s\n", stream.Name())
+		return nil
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+
+	nameWidth := 0
+	for _, state := range states {
+		nameWidth = max(nameWidth, len(state.Name))
+	}
+	width := max(progressWidth()-nameWidth, 10)
+
+	first := info.State.FirstSeq
+	last := info.State.LastSeq
+
+	fmt.Printf("Consumer progress for Stream %s, sequences %s to %s\n\n", stream.Name(), f(first), f(last))
+
+	for _, state := range states {
+		seq := consumerGraphProgress(state)
+		pending := state.NumPending + uint64(state.NumAckPending)
+
+		fmt.Printf("%*s |%s| %s pending\n", nameWidth, state.Name, consumerGraphBar(first, last, seq, width), f(pending))
+	}
+
+	fmt.Printf("%*s  %-*s%s\n", nameWidth, "", width-len(f(last)), f(first), f(last))
+
+	return nil
+}
+
+// consumerGraphProgress is the stream sequence up to which the consumer has handled all messages
+func consumerGraphProgress(state *api.ConsumerInfo) uint64 {
+	if state.Config.AckPolicy == api.AckNone {
+		return state.Delivered.Stream
+	}
+
+	return state.AckFloor.Stream
+}
+
+// consumerGraphBar renders seq as a bar over the first to last sequence range, filled up to seq
+func consumerGraphBar(first uint64, last uint64, seq uint64, width int) string {
+	filled := 0
+
+	switch {
+	case last == 0 || seq >= last:
+		filled = width
+	case seq >= first && last > first:
+		filled = int(float64(seq-first+1) / float64(last-first+1) * float64(width))
+	}
+
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
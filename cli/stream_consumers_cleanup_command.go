@@ -0,0 +1,94 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/choria-io/fisk"
+)
+
+// stuckConsumers are the stale consumers that have messages waiting, consumers without work are idle but not stuck
+func stuckConsumers(stale []*staleConsumer) []*staleConsumer {
+	stuck := []*staleConsumer{}
+	for _, s := range stale {
+		if s.NumPending > 0 {
+			stuck = append(stuck, s)
+		}
+	}
+
+	return stuck
+}
+
+func (c *streamCmd) consumersCleanupAction(_ *fisk.ParseContext) error {
+	if c.staleThreshold <= 0 {
+		return fmt.Errorf("no progress threshold must be positive")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	stale, err := c.findStaleConsumers(stream)
+	if err != nil {
+		return err
+	}
+
+	stuck := stuckConsumers(stale)
+	if len(stuck) == 0 {
+		fmt.Printf("No Consumers for Stream %s have unprocessed messages without delivering any for more than %s\n", c.stream, f(c.staleThreshold))
+		return nil
+	}
+
+	renderStaleConsumerTable(fmt.Sprintf("Consumers for Stream %s with unprocessed messages that delivered none for more than %s", c.stream, f(c.staleThreshold)), stuck)
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really delete %d Consumers from Stream %s that are not making progress", len(stuck), c.stream), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	for _, s := range stuck {
+		// consumers might have resumed while waiting for confirmation
+		cons, err := c.mgr.LoadConsumer(c.stream, s.Name)
+		if err != nil {
+			return fmt.Errorf("could not load Consumer %s: %w", s.Name, err)
+		}
+
+		state, err := cons.LatestState()
+		if err != nil {
+			return fmt.Errorf("could not load state for Consumer %s: %w", s.Name, err)
+		}
+
+		if current := staleConsumerFromState(&state, c.staleThreshold); current == nil || current.NumPending == 0 {
+			fmt.Printf("Consumer %s made progress, not deleting it\n", s.Name)
+			continue
+		}
+
+		err = cons.Delete()
+		if err != nil {
+			return fmt.Errorf("could not delete Consumer %s: %w", s.Name, err)
+		}
+
+		fmt.Printf("Deleted Consumer %s\n", s.Name)
+	}
+
+	return nil
+}
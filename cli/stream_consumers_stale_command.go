@@ -0,0 +1,201 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// staleConsumer is a consumer that did not deliver a message for longer than the idle threshold
+type staleConsumer struct {
+	Name          string        `json:"name"`
+	LastDelivery  *time.Time    `json:"last_delivery,omitempty"`
+	Idle          time.Duration `json:"idle"`
+	NumAckPending int           `json:"num_ack_pending"`
+	NumPending    uint64        `json:"num_pending"`
+	PushBound     bool          `json:"push_bound,omitempty"`
+	Deleted       bool          `json:"deleted,omitempty"`
+	Skipped       string        `json:"skipped,omitempty"`
+}
+
+func (c *streamCmd) consumersStaleAction(_ *fisk.ParseContext) error {
+	if c.staleThreshold <= 0 {
+		return fmt.Errorf("idle threshold must be positive")
+	}
+
+	if c.staleDelete && c.json && !c.force {
+		return fmt.Errorf("deleting stale consumers with --json requires --force")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	stale, err := c.findStaleConsumers(stream)
+	if err != nil {
+		return err
+	}
+
+	if c.staleDelete && len(stale) > 0 {
+		if !c.json {
+			c.renderStaleConsumers(stale)
+		}
+
+		if !c.force {
+			ok, err := askConfirmation(fmt.Sprintf("Really delete %d stale Consumers from Stream %s", len(stale), c.stream), false)
+			fisk.FatalIfError(err, "could not obtain confirmation")
+
+			if !ok {
+				return nil
+			}
+		}
+
+		for _, s := range stale {
+			// consumers might have resumed while waiting for confirmation
+			cons, err := c.mgr.LoadConsumer(c.stream, s.Name)
+			if err != nil {
+				return fmt.Errorf("could not load Consumer %s: %w", s.Name, err)
+			}
+
+			state, err := cons.LatestState()
+			if err != nil {
+				return fmt.Errorf("could not load state for Consumer %s: %w", s.Name, err)
+			}
+
+			if staleConsumerFromState(&state, c.staleThreshold) == nil {
+				s.Skipped = "delivered messages recently"
+				if !c.json {
+					fmt.Printf("Consumer %s delivered messages recently, not deleting it\n", s.Name)
+				}
+				continue
+			}
+
+			err = cons.Delete()
+			if err != nil {
+				return fmt.Errorf("could not delete Consumer %s: %w", s.Name, err)
+			}
+			s.Deleted = true
+
+			if !c.json {
+				fmt.Printf("Deleted Consumer %s\n", s.Name)
+			}
+		}
+	}
+
+	if c.json {
+		return iu.PrintJSON(stale)
+	}
+
+	if !c.staleDelete || len(stale) == 0 {
+		c.renderStaleConsumers(stale)
+	}
+
+	return nil
+}
+
+// findStaleConsumers finds consumers whose last delivery, or creation when nothing was delivered, is older than the
+// threshold, the server time of the state is used as now so clock differences do not matter. Consumers whose state
+// could not be loaded fail the search rather than being left out of the report
+func (c *streamCmd) findStaleConsumers(stream *jsm.Stream) ([]*staleConsumer, error) {
+	stale := []*staleConsumer{}
+	var errs []error
+
+	missing, err := stream.EachConsumer(func(cons *jsm.Consumer) {
+		state, err := cons.LatestState()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not load state for Consumer %s: %w", cons.Name(), err))
+			return
+		}
+
+		if s := staleConsumerFromState(&state, c.staleThreshold); s != nil {
+			stale = append(stale, s)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		errs = append(errs, fmt.Errorf("could not load Consumers %v", missing))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Idle > stale[j].Idle
+	})
+
+	return stale, nil
+}
+
+func staleConsumerFromState(state *api.ConsumerInfo, threshold time.Duration) *staleConsumer {
+	now := state.TimeStamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	last := state.Created
+	if state.Delivered.Last != nil {
+		last = *state.Delivered.Last
+	}
+
+	idle := now.Sub(last)
+	if idle <= threshold {
+		return nil
+	}
+
+	return &staleConsumer{
+		Name:          state.Name,
+		LastDelivery:  state.Delivered.Last,
+		Idle:          idle,
+		NumAckPending: state.NumAckPending,
+		NumPending:    state.NumPending,
+		PushBound:     state.PushBound,
+	}
+}
+
+func (c *streamCmd) renderStaleConsumers(stale []*staleConsumer) {
+	if len(stale) == 0 {
+		fmt.Printf("No Consumers for Stream %s were idle for more than %s\n", c.stream, f(c.staleThreshold))
+		return
+	}
+
+	renderStaleConsumerTable(fmt.Sprintf("Consumers for Stream %s idle for more than %s", c.stream, f(c.staleThreshold)), stale)
+}
+
+func renderStaleConsumerTable(title string, stale []*staleConsumer) {
+	table := newTableWriter(title)
+	table.AddHeaders("Consumer", "Last Delivery", "Idle", "Ack Pending", "Unprocessed", "Push Bound")
+	for _, s := range stale {
+		last := "never"
+		if s.LastDelivery != nil {
+			last = f(*s.LastDelivery)
+		}
+
+		table.AddRow(s.Name, last, f(s.Idle.Round(time.Second)), f(s.NumAckPending), f(s.NumPending), f(s.PushBound))
+	}
+
+	fmt.Println(table.Render())
+}
@@ -0,0 +1,234 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+const streamExportFileTime = "20060102T150405Z"
+
+func (c *streamCmd) exportAction(_ *fisk.ParseContext) error {
+	now := time.Now()
+
+	since, err := parseTimeString(c.exportSince, now)
+	if err != nil {
+		return fmt.Errorf("invalid since time: %w", err)
+	}
+
+	until := now
+	if c.exportUntil != "" {
+		until, err = parseTimeString(c.exportUntil, now)
+		if err != nil {
+			return fmt.Errorf("invalid until time: %w", err)
+		}
+	}
+
+	if !until.After(since) {
+		return fmt.Errorf("until %s is not after since %s", until.Format(time.RFC3339), since.Format(time.RFC3339))
+	}
+
+	c.connectAndAskStream()
+
+	path, err := c.exportPath(since, until)
+	if err != nil {
+		return err
+	}
+
+	ectx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	estimate, err := c.exportEstimate(since, until)
+	if err != nil {
+		return err
+	}
+
+	if estimate == 0 {
+		log.Printf("No messages found in Stream %s between %s and %s", c.stream, since.Format(time.RFC3339), until.Format(time.RFC3339))
+		return nil
+	}
+
+	js, err := c.nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	// the library deletes the ordered consumer on unsubscribe, the server removes it should we exit uncleanly
+	sub, err := js.SubscribeSync(c.exportSubject, nats.BindStream(c.stream), nats.OrderedConsumer(), nats.StartTime(since))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	writer, err := newMsgCaptureWriter(path)
+	if err != nil {
+		return err
+	}
+
+	var bar *uiprogress.Bar
+	if c.showProgress {
+		progress := uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(int(estimate)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		})
+		progress.Start()
+		defer func() {
+			time.Sleep(250 * time.Millisecond) // let it draw
+			progress.Stop()
+		}()
+	}
+
+	rerr := c.exportMessages(ectx, sub, until, writer, bar)
+
+	cnt, err := writer.close()
+	if err != nil {
+		return fmt.Errorf("could not write %s: %w", path, err)
+	}
+
+	if rerr != nil {
+		return fmt.Errorf("export failed after %s messages, %s is incomplete: %w", f(cnt), path, rerr)
+	}
+
+	if bar != nil {
+		bar.Set(bar.Total)
+	}
+
+	log.Printf("Exported %s messages from Stream %s between %s and %s to %s", f(cnt), c.stream, since.Format(time.RFC3339), until.Format(time.RFC3339), path)
+
+	return nil
+}
+
+// exportMessages writes messages until one is found that was stored after until or the end of the stream is reached
+func (c *streamCmd) exportMessages(ectx context.Context, sub *nats.Subscription, until time.Time, writer *msgCaptureWriter, bar *uiprogress.Bar) error {
+	for {
+		mctx, cancel := context.WithTimeout(ectx, opts().Timeout)
+		msg, err := sub.NextMsgWithContext(mctx)
+		cancel()
+		if ectx.Err() != nil {
+			return ectx.Err()
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+
+		if !meta.Timestamp.Before(until) {
+			return nil
+		}
+
+		writer.write(&capturedMsg{
+			Subject:  msg.Subject,
+			Header:   msg.Header,
+			Data:     msg.Data,
+			Time:     meta.Timestamp,
+			Stream:   meta.Stream,
+			Sequence: meta.Sequence.Stream,
+		})
+
+		if bar != nil {
+			// the estimate can be short when messages are added to the window while exporting
+			if bar.Current() >= bar.Total {
+				bar.Total++
+			}
+			bar.Incr()
+		}
+
+		if meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
+
+// exportEstimate determines how many messages are in the window using the pending counts of consumers starting at either end
+func (c *streamCmd) exportEstimate(since time.Time, until time.Time) (uint64, error) {
+	pendingSince, err := c.exportPendingAt(since)
+	if err != nil {
+		return 0, err
+	}
+
+	pendingUntil, err := c.exportPendingAt(until)
+	if err != nil {
+		return 0, err
+	}
+
+	if pendingUntil > pendingSince {
+		return 0, nil
+	}
+
+	return pendingSince - pendingUntil, nil
+}
+
+func (c *streamCmd) exportPendingAt(t time.Time) (uint64, error) {
+	copts := []jsm.ConsumerOption{jsm.StartAtTime(t), jsm.AcknowledgeNone(), jsm.InactiveThreshold(time.Minute)}
+	if c.exportSubject != "" {
+		copts = append(copts, jsm.FilterStreamBySubject(c.exportSubject))
+	}
+
+	cons, err := c.mgr.NewConsumer(c.stream, copts...)
+	if err != nil {
+		return 0, fmt.Errorf("could not create temporary consumer: %w", err)
+	}
+	defer cons.Delete()
+
+	state, err := cons.LatestState()
+	if err != nil {
+		return 0, err
+	}
+
+	return state.NumPending, nil
+}
+
+// exportPath is the output file, a file named after the stream and window is created when the output is a directory
+func (c *streamCmd) exportPath(since time.Time, until time.Time) (string, error) {
+	path := c.exportOutput
+
+	stat, err := os.Stat(path)
+	switch {
+	case err == nil && stat.IsDir():
+		path = filepath.Join(path, fmt.Sprintf("%s-%s-%s.json", c.stream, since.UTC().Format(streamExportFileTime), until.UTC().Format(streamExportFileTime)))
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return "", err
+	}
+
+	if c.force {
+		return path, nil
+	}
+
+	_, err = os.Stat(path)
+	if err == nil {
+		return "", fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+
+	return path, nil
+}
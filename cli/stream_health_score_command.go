@@ -0,0 +1,183 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// the most each factor can take off the health score, together they add up to 100
+const (
+	streamHealthReplicasWeight  = 30
+	streamHealthSourceLagWeight = 25
+	streamHealthConsumerWeight  = 25
+	streamHealthSealedWeight    = 10
+	streamHealthPausedWeight    = 10
+)
+
+// streamHealthFactor is one signal contributing to the health score of a Stream
+type streamHealthFactor struct {
+	name    string
+	status  string
+	penalty int
+}
+
+// streamHealthPenalty scales weight by ratio, a ratio of 1 or more takes the full weight
+func streamHealthPenalty(weight int, ratio float64) int {
+	return int(math.Round(float64(weight) * math.Min(math.Max(ratio, 0), 1)))
+}
+
+// streamHealthScore computes a score from 0 to 100 for a Stream and the factors that lowered it
+func streamHealthScore(nfo *api.StreamInfo, consumers []api.ConsumerInfo) (int, []*streamHealthFactor) {
+	var factors []*streamHealthFactor
+
+	replicas := &streamHealthFactor{name: "Replication", status: "not clustered"}
+	if nfo.Config.Replicas > 1 {
+		current := 0
+		if nfo.Cluster != nil && nfo.Cluster.Leader != "" {
+			current++
+			for _, peer := range nfo.Cluster.Replicas {
+				if peer.Current && !peer.Offline {
+					current++
+				}
+			}
+		}
+
+		replicas.status = fmt.Sprintf("%d of %d replicas current", current, nfo.Config.Replicas)
+		if current == 0 {
+			replicas.status = "no leader"
+			replicas.penalty = streamHealthReplicasWeight
+		} else {
+			replicas.penalty = streamHealthPenalty(streamHealthReplicasWeight, float64(nfo.Config.Replicas-current)/float64(nfo.Config.Replicas))
+		}
+	}
+	factors = append(factors, replicas)
+
+	// the worst of the mirror and all sources counts
+	sources := nfo.Sources
+	if nfo.Mirror != nil {
+		sources = append([]*api.StreamSourceInfo{nfo.Mirror}, sources...)
+	}
+	lag := &streamHealthFactor{name: "Mirror and Source Lag", status: "no mirror or sources"}
+	for i, source := range sources {
+		var penalty int
+		var status string
+		switch {
+		case source.Error != nil:
+			penalty = streamHealthSourceLagWeight
+			status = fmt.Sprintf("%s failed: %s", source.Name, source.Error.Description)
+		default:
+			penalty = streamHealthPenalty(streamHealthSourceLagWeight, float64(source.Lag)/float64(source.Lag+nfo.State.Msgs))
+			status = fmt.Sprintf("%s is %s messages behind", source.Name, f(source.Lag))
+		}
+
+		if i == 0 || penalty > lag.penalty {
+			lag.penalty = penalty
+			lag.status = status
+		}
+	}
+	factors = append(factors, lag)
+
+	consumerLag := &streamHealthFactor{name: "Consumer Lag", status: "no consumers"}
+	paused := &streamHealthFactor{name: "Paused Consumers", status: "none paused"}
+	if len(consumers) > 0 {
+		var ratios float64
+		pausedCount := 0
+		for _, cons := range consumers {
+			if nfo.State.Msgs > 0 {
+				ratios += math.Min(float64(cons.NumPending)/float64(nfo.State.Msgs), 1)
+			}
+			if cons.Paused {
+				pausedCount++
+			}
+		}
+
+		avg := ratios / float64(len(consumers))
+		consumerLag.penalty = streamHealthPenalty(streamHealthConsumerWeight, avg)
+		consumerLag.status = fmt.Sprintf("%.0f%% of messages unprocessed on average over %d consumers", avg*100, len(consumers))
+
+		if pausedCount > 0 {
+			paused.penalty = streamHealthPenalty(streamHealthPausedWeight, float64(pausedCount)/float64(len(consumers)))
+			paused.status = fmt.Sprintf("%d of %d consumers paused", pausedCount, len(consumers))
+		}
+	}
+	factors = append(factors, consumerLag, paused)
+
+	sealed := &streamHealthFactor{name: "Sealed", status: "accepting messages"}
+	if nfo.Config.Sealed {
+		sealed.status = "sealed, no messages are accepted"
+		sealed.penalty = streamHealthSealedWeight
+	}
+	factors = append(factors, sealed)
+
+	score := 100
+	for _, factor := range factors {
+		score -= factor.penalty
+	}
+
+	return max(score, 0), factors
+}
+
+func (c *streamCmd) healthScoreAction(_ *fisk.ParseContext) error {
+	if c.healthThreshold < 0 || c.healthThreshold > 100 {
+		return fmt.Errorf("exit-nonzero-below must be between 0 and 100")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.LatestInformation()
+	if err != nil {
+		return err
+	}
+
+	var consumers []api.ConsumerInfo
+	_, err = stream.EachConsumer(func(cons *jsm.Consumer) {
+		state, err := cons.LatestState()
+		if err != nil {
+			logErrorf("Could not load state for consumer %s: %v", cons.Name(), err)
+			return
+		}
+		consumers = append(consumers, state)
+	})
+	if err != nil {
+		return err
+	}
+
+	score, factors := streamHealthScore(nfo, consumers)
+
+	table := newTableWriter(fmt.Sprintf("Stream %s health score %d of 100", c.stream, score))
+	table.AddHeaders("Factor", "Status", "Penalty")
+	for _, factor := range factors {
+		table.AddRow(factor.name, factor.status, f(factor.penalty))
+	}
+	fmt.Println(table.Render())
+
+	if c.healthThreshold > 0 && score < c.healthThreshold {
+		fmt.Printf("Stream %s health score %d is below %d\n", c.stream, score, c.healthThreshold)
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// renameAction gives a stream a new name, the server can not rename streams so the messages are saved, the stream
+// deleted and the messages published again to a new stream using the same configuration
+func (c *streamCmd) renameAction(_ *fisk.ParseContext) error {
+	if c.stream == c.destination {
+		return fmt.Errorf("stream %s already has that name", c.stream)
+	}
+	if c.storageParallel < 1 {
+		return fmt.Errorf("parallel must be at least 1")
+	}
+
+	c.connectAndAskStream()
+
+	start := time.Now()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	known, err := c.mgr.IsKnownStream(c.destination)
+	if err != nil {
+		return err
+	}
+	if known {
+		return fmt.Errorf("stream %s already exists", c.destination)
+	}
+
+	nfo, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	cfg := nfo.Config
+
+	switch {
+	case cfg.Mirror != nil || len(cfg.Sources) > 0:
+		return fmt.Errorf("stream %s is a mirror or has sources, it can not be renamed by publishing its messages again", c.stream)
+	case cfg.Retention != api.LimitsPolicy:
+		return fmt.Errorf("stream %s uses %s retention, messages published again would be removed before its Consumers are created", c.stream, strings.ToLower(cfg.Retention.String()))
+	case !c.force:
+		return fmt.Errorf("the server can not rename Streams, renaming deletes Stream %s and publishes its messages again to a new Stream %s, pass --force to continue", c.stream, c.destination)
+	}
+
+	logWarnf("The server can not rename Streams, moving the %s messages in Stream %s to a new Stream %s", f(nfo.State.Msgs), c.stream, c.destination)
+
+	path := c.storageSnapshot
+	if path == "" {
+		path = fmt.Sprintf("%s-rename.jsonl", c.stream)
+	}
+
+	cfg.Name = c.destination
+
+	moved, err := c.moveStream(stream, nfo, cfg, path, true)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		logWarnf("Could not remove snapshot %s: %v", path, err)
+	}
+
+	fmt.Printf("Renamed Stream %s to %s in %s\n", c.stream, c.destination, f(time.Since(start)))
+	fmt.Printf("Stream %s held %s messages, Stream %s holds %s messages\n", c.stream, f(nfo.State.Msgs), c.destination, f(moved.after.State.Msgs))
+	if moved.renumbered > 0 {
+		fmt.Printf("%s messages were renumbered to close gaps between sequences\n", f(moved.renumbered))
+	}
+
+	return nil
+}
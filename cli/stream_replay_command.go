@@ -0,0 +1,421 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/internal/subjectmap"
+)
+
+const (
+	streamReplaySequenceHeader     = "Nats-Replay-Of-Sequence"
+	streamReplayCheckpointInterval = time.Second
+
+	// streamReplayMaxFailures is how many publish failures are listed in the summary
+	streamReplayMaxFailures = 20
+)
+
+// streamReplayCheckpoint records the last sequence of a Stream that was replayed
+type streamReplayCheckpoint struct {
+	Stream   string `json:"stream"`
+	Sequence uint64 `json:"sequence"`
+}
+
+type streamReplayFailure struct {
+	sequence uint64
+	subject  string
+	err      error
+}
+
+// streamReplay republishes messages read from a Stream to their original subjects changed using maps and prefix
+type streamReplay struct {
+	nc      *nats.Conn
+	maps    *subjectmap.Mapper
+	prefix  string
+	ack     bool
+	timeout time.Duration
+
+	published  int
+	duplicates int
+	failed     int
+	failures   []streamReplayFailure
+	subjects   map[string]int
+}
+
+func loadStreamReplayCheckpoint(path string) (*streamReplayCheckpoint, error) {
+	j, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := &streamReplayCheckpoint{}
+	err = json.Unmarshal(j, cp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+
+	return cp, nil
+}
+
+func (cp *streamReplayCheckpoint) save(path string) error {
+	j, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, j, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (c *streamCmd) replayAction(_ *fisk.ParseContext) error {
+	switch {
+	case c.replayRate < 0:
+		return fmt.Errorf("rate can not be negative")
+	case c.replayResume && c.replayStartSeq > 0:
+		return fmt.Errorf("resume and start-seq can not be used together")
+	case c.replayResume && c.replayDryRun:
+		return fmt.Errorf("resume and dry-run can not be used together")
+	}
+
+	maps, err := subjectmap.New(c.replaySubjectMaps)
+	if err != nil {
+		return err
+	}
+
+	c.connectAndAskStream()
+
+	if c.replayCheckpoint == "" {
+		c.replayCheckpoint = fmt.Sprintf("%s-replay.json", c.stream)
+	}
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	start := nfo.State.FirstSeq
+	if c.replayStartSeq > start {
+		start = c.replayStartSeq
+	}
+
+	if c.replayResume {
+		cp, err := loadStreamReplayCheckpoint(c.replayCheckpoint)
+		if err != nil {
+			return fmt.Errorf("could not resume: %w", err)
+		}
+		if cp.Stream != c.stream {
+			return fmt.Errorf("checkpoint %s was made for Stream %s", c.replayCheckpoint, cp.Stream)
+		}
+
+		start = max(start, cp.Sequence+1)
+	}
+
+	// replayed messages can be stored in the same Stream, the end is fixed up front so they are never replayed again
+	end := nfo.State.LastSeq
+	if nfo.State.Msgs == 0 || start > end {
+		log.Printf("No messages to replay from Stream %s", c.stream)
+		return nil
+	}
+
+	if !c.replayDryRun && !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really replay up to %s messages from Stream %s starting at sequence %d", f(end-start+1), c.stream, start), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	rctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	js, err := c.nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	// the library deletes the ordered consumer on unsubscribe, the server removes it should we exit uncleanly
+	sub, err := js.SubscribeSync(c.filterSubject, nats.BindStream(c.stream), nats.OrderedConsumer(), nats.StartSequence(start))
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	var progress *uiprogress.Progress
+	var bar *uiprogress.Bar
+	if c.showProgress {
+		progress = uiprogress.New()
+		progress.SetOut(os.Stderr)
+		bar = progress.AddBar(int(end - start + 1)).AppendCompleted().PrependElapsed()
+		bar.Width = progressWidth()
+		progress.Start()
+	}
+
+	r := &streamReplay{
+		nc:       c.nc,
+		maps:     maps,
+		prefix:   c.replaySubjectPrefix,
+		ack:      c.replayAck,
+		timeout:  opts().Timeout,
+		subjects: map[string]int{},
+	}
+
+	last, rerr := c.replayMessages(rctx, r, sub, start, end, bar)
+
+	if progress != nil {
+		progress.Stop()
+	}
+
+	if c.replayDryRun {
+		fmt.Println(r.renderDryRun(c.stream))
+		return rerr
+	}
+
+	if r.published > 0 || r.failed > 0 {
+		if !r.ack {
+			err = c.nc.FlushTimeout(r.timeout)
+			if err != nil && rerr == nil {
+				rerr = err
+			}
+		}
+
+		err = (&streamReplayCheckpoint{Stream: c.stream, Sequence: last}).save(c.replayCheckpoint)
+		if err != nil {
+			logErrorf("Could not save checkpoint %s: %v", c.replayCheckpoint, err)
+		}
+	}
+
+	fmt.Println(r.renderSummary(c.stream, start, last))
+
+	switch {
+	case rctx.Err() != nil:
+		log.Printf("Replay interrupted after sequence %d, continue using --resume", last)
+		return nil
+	case rerr != nil:
+		return fmt.Errorf("replay failed after sequence %d, continue using --resume: %w", last, rerr)
+	}
+
+	return nil
+}
+
+// replayMessages replays messages up to end at the configured rate, returning the last sequence handled
+func (c *streamCmd) replayMessages(rctx context.Context, r *streamReplay, sub *nats.Subscription, start uint64, end uint64, bar *uiprogress.Bar) (uint64, error) {
+	var interval time.Duration
+	if c.replayRate > 0 {
+		interval = time.Second / time.Duration(c.replayRate)
+	}
+
+	last := start - 1
+	next := time.Now()
+	lastCheckpoint := time.Now()
+
+	for last < end {
+		mctx, cancel := context.WithTimeout(rctx, r.timeout)
+		msg, err := sub.NextMsgWithContext(mctx)
+		cancel()
+		if rctx.Err() != nil {
+			return last, nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			// messages up to end were deleted or do not match the filter
+			return last, nil
+		}
+		if err != nil {
+			return last, err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return last, err
+		}
+
+		if meta.Sequence.Stream > end {
+			return last, nil
+		}
+
+		if interval > 0 && !c.replayDryRun {
+			now := time.Now()
+			if next.After(now) {
+				select {
+				case <-time.After(next.Sub(now)):
+				case <-rctx.Done():
+					return last, nil
+				}
+			} else if now.Sub(next) > interval {
+				// do not burst to catch up after a slow publish
+				next = now
+			}
+			next = next.Add(interval)
+		}
+
+		if c.replayDryRun {
+			r.count(msg.Subject)
+		} else {
+			r.replay(msg, meta.Sequence.Stream)
+		}
+
+		last = meta.Sequence.Stream
+
+		if bar != nil {
+			bar.Set(int(last - start + 1))
+		}
+
+		if !c.replayDryRun && time.Since(lastCheckpoint) >= streamReplayCheckpointInterval {
+			lastCheckpoint = time.Now()
+
+			if !r.ack {
+				err = r.nc.FlushTimeout(r.timeout)
+				if err != nil {
+					return last, err
+				}
+			}
+
+			err = (&streamReplayCheckpoint{Stream: c.stream, Sequence: last}).save(c.replayCheckpoint)
+			if err != nil {
+				return last, fmt.Errorf("could not save checkpoint %s: %w", c.replayCheckpoint, err)
+			}
+		}
+
+		if meta.NumPending == 0 {
+			return last, nil
+		}
+	}
+
+	return last, nil
+}
+
+// target is the subject a message received on subject is replayed to
+func (r *streamReplay) target(subject string) string {
+	mapped, _ := r.maps.Map(subject)
+	return r.prefix + mapped
+}
+
+func (r *streamReplay) count(subject string) {
+	r.subjects[subject]++
+}
+
+// replay publishes msg, failures are recorded rather than stopping the replay
+func (r *streamReplay) replay(msg *nats.Msg, seq uint64) {
+	out := nats.NewMsg(r.target(msg.Subject))
+	out.Data = msg.Data
+	for k, vals := range msg.Header {
+		out.Header[k] = append([]string{}, vals...)
+	}
+	out.Header.Set(streamReplaySequenceHeader, strconv.FormatUint(seq, 10))
+
+	err := validatePublishSubject(out.Subject)
+	if err == nil {
+		err = r.publish(out)
+	}
+
+	if err != nil {
+		r.failed++
+		if len(r.failures) < streamReplayMaxFailures {
+			r.failures = append(r.failures, streamReplayFailure{sequence: seq, subject: out.Subject, err: err})
+		}
+		return
+	}
+
+	r.published++
+}
+
+func (r *streamReplay) publish(msg *nats.Msg) error {
+	if !r.ack {
+		return r.nc.PublishMsg(msg)
+	}
+
+	resp, err := r.nc.RequestMsg(msg, r.timeout)
+	if err != nil {
+		return err
+	}
+
+	ack, err := jsm.ParsePubAck(resp)
+	if err != nil {
+		return err
+	}
+
+	if ack.Duplicate {
+		r.duplicates++
+	}
+
+	return nil
+}
+
+func (r *streamReplay) renderDryRun(stream string) string {
+	table := newTableWriter(fmt.Sprintf("Messages that would be replayed from Stream %s", stream))
+	table.AddHeaders("Subject", "Replayed To", "Messages")
+
+	subjects := make([]string, 0, len(r.subjects))
+	for s := range r.subjects {
+		subjects = append(subjects, s)
+	}
+	sort.Strings(subjects)
+
+	total := 0
+	for _, s := range subjects {
+		table.AddRow(s, r.target(s), f(r.subjects[s]))
+		total += r.subjects[s]
+	}
+	table.AddFooter("", "", f(total))
+
+	return table.Render()
+}
+
+func (r *streamReplay) renderSummary(stream string, start uint64, last uint64) string {
+	cols := newColumns(fmt.Sprintf("Replay of Stream %s", stream))
+	if last >= start {
+		cols.AddRow("Sequences", fmt.Sprintf("%d - %d", start, last))
+	}
+	cols.AddRow("Published", r.published)
+	cols.AddRowIf("Duplicates", r.duplicates, r.duplicates > 0)
+	cols.AddRow("Failed", r.failed)
+
+	if len(r.failures) > 0 {
+		cols.AddSectionTitle("Failures")
+		for _, failure := range r.failures {
+			cols.AddRow(fmt.Sprintf("Sequence %d", failure.sequence), fmt.Sprintf("%s: %v", failure.subject, failure.err))
+		}
+		if r.failed > len(r.failures) {
+			cols.AddRow("More", fmt.Sprintf("%s failures not shown", f(r.failed-len(r.failures))))
+		}
+	}
+
+	out, _ := cols.Render()
+
+	return out
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func (c *streamCmd) replicasAction(_ *fisk.ParseContext) error {
+	if c.replicas < 1 || c.replicas > 5 {
+		return fmt.Errorf("replicas has to be between 1 and 5")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	current := nfo.Config.Replicas
+	target := int(c.replicas)
+
+	if current == target {
+		fmt.Printf("Stream %s already has %d replicas\n", c.stream, current)
+		return nil
+	}
+
+	fmt.Printf("Stream %s has %d replicas: %s\n", c.stream, current, describeStreamPeers(nfo.Cluster, nil))
+	fmt.Println()
+
+	if target > 1 && (nfo.Cluster == nil || nfo.Cluster.Name == "") {
+		logWarnf("Stream %s is not hosted in a cluster, no servers are available to hold additional replicas", c.stream)
+	}
+
+	if target < current {
+		logWarnf("Reducing Stream %s from %d to %d replicas, the data held by the removed replicas will be discarded", c.stream, current, target)
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really change the replicas of Stream %s from %d to %d", c.stream, current, target), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	cfg := nfo.Config
+	cfg.Replicas = target
+
+	err = stream.UpdateConfiguration(cfg)
+	if err != nil {
+		return fmt.Errorf("the replicas of Stream %s can not be changed from %d to %d: %w", c.stream, current, target, err)
+	}
+
+	updated, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	added, removed := streamPeerChanges(nfo.Cluster, updated.Cluster)
+
+	fmt.Printf("Stream %s now has %d replicas: %s\n", c.stream, updated.Config.Replicas, describeStreamPeers(updated.Cluster, added))
+	if len(added) > 0 {
+		fmt.Printf("New replicas on %s are catching up in the background, use 'nats stream info %s' to follow their progress\n", strings.Join(added, ", "), c.stream)
+	}
+	if len(removed) > 0 {
+		fmt.Printf("Removed replicas from %s\n", strings.Join(removed, ", "))
+	}
+
+	return nil
+}
+
+// streamPeers lists the servers holding a Stream with the leader first
+func streamPeers(cluster *api.ClusterInfo) []string {
+	if cluster == nil {
+		return nil
+	}
+
+	var peers []string
+	if cluster.Leader != "" {
+		peers = append(peers, cluster.Leader)
+	}
+
+	for _, r := range cluster.Replicas {
+		peers = append(peers, r.Name)
+	}
+
+	return peers
+}
+
+// streamPeerChanges finds the servers that started and stopped holding a Stream
+func streamPeerChanges(before *api.ClusterInfo, after *api.ClusterInfo) (added []string, removed []string) {
+	old := streamPeers(before)
+	current := streamPeers(after)
+
+	for _, p := range current {
+		if !slices.Contains(old, p) {
+			added = append(added, p)
+		}
+	}
+
+	for _, p := range old {
+		if !slices.Contains(current, p) {
+			removed = append(removed, p)
+		}
+	}
+
+	return added, removed
+}
+
+// describeStreamPeers describes the leader and replicas of a Stream, marking the servers in added as new replicas
+func describeStreamPeers(cluster *api.ClusterInfo, added []string) string {
+	if cluster == nil || (cluster.Leader == "" && len(cluster.Replicas) == 0) {
+		return "no cluster information"
+	}
+
+	mark := func(name string) string {
+		if slices.Contains(added, name) {
+			return name + " (new)"
+		}
+		return name
+	}
+
+	leader := "no leader"
+	if cluster.Leader != "" {
+		leader = "leader " + mark(cluster.Leader)
+	}
+
+	if len(cluster.Replicas) == 0 {
+		return leader
+	}
+
+	var replicas []string
+	for _, r := range cluster.Replicas {
+		state := mark(r.Name)
+
+		switch {
+		case r.Offline:
+			state += " OFFLINE"
+		case !r.Current:
+			state += " outdated"
+		}
+
+		replicas = append(replicas, state)
+	}
+
+	return fmt.Sprintf("%s, replicas %s", leader, strings.Join(replicas, ", "))
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+func (c *streamCmd) republishListAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	return c.renderRepublish(stream)
+}
+
+func (c *streamCmd) republishAddAction(_ *fisk.ParseContext) error {
+	stream, cfg, err := c.loadSourcesConfig()
+	if err != nil {
+		return err
+	}
+
+	// the server supports a single republish rule per Stream
+	if cfg.RePublish != nil {
+		return fmt.Errorf("stream %s already republishes %s, remove it before adding another", c.stream, c.renderRepublishRule(cfg.RePublish))
+	}
+
+	cfg.RePublish = &api.RePublish{Source: c.repubSource, Destination: c.repubDest, HeadersOnly: c.repubHeadersOnly}
+
+	return c.updateRepublish(stream, cfg, fmt.Sprintf("Really republish %s on Stream %s", c.renderRepublishRule(cfg.RePublish), c.stream))
+}
+
+func (c *streamCmd) republishRemoveAction(_ *fisk.ParseContext) error {
+	stream, cfg, err := c.loadSourcesConfig()
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case cfg.RePublish == nil:
+		return fmt.Errorf("stream %s does not republish any messages", c.stream)
+	case cfg.RePublish.Source != c.repubSource:
+		return fmt.Errorf("stream %s does not republish %s, it republishes %s", c.stream, c.repubSource, c.renderRepublishRule(cfg.RePublish))
+	}
+
+	prompt := fmt.Sprintf("Really stop republishing %s on Stream %s", c.renderRepublishRule(cfg.RePublish), c.stream)
+	cfg.RePublish = nil
+
+	return c.updateRepublish(stream, cfg, prompt)
+}
+
+func (c *streamCmd) updateRepublish(stream *jsm.Stream, cfg *api.StreamConfig, prompt string) error {
+	if !c.force {
+		ok, err := askConfirmation(prompt, false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	err := stream.UpdateConfiguration(*cfg)
+	if err != nil {
+		return fmt.Errorf("could not update Stream %s: %w", c.stream, err)
+	}
+
+	return c.renderRepublish(stream)
+}
+
+func (c *streamCmd) renderRepublish(stream *jsm.Stream) error {
+	rules := []*api.RePublish{}
+	if repub := stream.Configuration().RePublish; repub != nil {
+		rules = append(rules, repub)
+	}
+
+	if c.json {
+		return iu.PrintJSON(rules)
+	}
+
+	if len(rules) == 0 {
+		fmt.Printf("Stream %s does not republish any messages\n", c.stream)
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("Republish rules for Stream %s", c.stream))
+	table.AddHeaders("Source", "Destination", "Headers Only")
+	for _, rule := range rules {
+		table.AddRow(rule.Source, rule.Destination, f(rule.HeadersOnly))
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *streamCmd) renderRepublishRule(repub *api.RePublish) string {
+	if repub.HeadersOnly {
+		return fmt.Sprintf("headers of %s to %s", repub.Source, repub.Destination)
+	}
+
+	return fmt.Sprintf("%s to %s", repub.Source, repub.Destination)
+}
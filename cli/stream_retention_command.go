@@ -0,0 +1,67 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+)
+
+func (c *streamCmd) retentionAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	current := nfo.Config.Retention
+	target := c.retentionPolicyFromString()
+
+	if current == target {
+		fmt.Printf("Stream %s already uses the %s retention policy\n", c.stream, current)
+		return nil
+	}
+
+	// interest streams remove messages once all consumers acknowledged them, with limits they stay until a limit is hit
+	if current == api.InterestPolicy && target == api.LimitsPolicy && nfo.State.Consumers > 0 {
+		logWarnf("Stream %s has %s Consumers, with the limits retention policy messages are no longer removed once acknowledged by all Consumers and are kept until the Stream limits are reached", c.stream, f(nfo.State.Consumers))
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really change the retention policy of Stream %s from %s to %s", c.stream, current, target), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	cfg := nfo.Config
+	cfg.Retention = target
+
+	err = stream.UpdateConfiguration(cfg)
+	if err != nil {
+		return fmt.Errorf("the retention policy of Stream %s can not be changed from %s to %s: %w", c.stream, current, target, err)
+	}
+
+	return c.showStream(stream)
+}
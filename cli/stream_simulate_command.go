@@ -0,0 +1,329 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// retentionSample is a message read from the stream, Size excludes the storage overhead
+type retentionSample struct {
+	Subject string
+	Time    time.Time
+	Size    uint64
+}
+
+type retentionSubjectRemoval struct {
+	Subject  string `json:"subject"`
+	Messages uint64 `json:"messages"`
+	Bytes    uint64 `json:"bytes"`
+}
+
+// retentionSimulation is the estimated effect of applying new limits to a stream
+type retentionSimulation struct {
+	Stream          string                     `json:"stream"`
+	MaxAge          time.Duration              `json:"max_age,omitempty"`
+	MaxBytes        int64                      `json:"max_bytes,omitempty"`
+	MaxMsgs         int64                      `json:"max_msgs,omitempty"`
+	Messages        uint64                     `json:"messages"`
+	Bytes           uint64                     `json:"bytes"`
+	RemovedMessages uint64                     `json:"removed_messages"`
+	RemovedBytes    uint64                     `json:"removed_bytes"`
+	Sampled         int                        `json:"sampled"`
+	Estimated       bool                       `json:"estimated"`
+	Subjects        []*retentionSubjectRemoval `json:"subjects,omitempty"`
+}
+
+func (c *streamCmd) simulateAction(_ *fisk.ParseContext) error {
+	var maxAge time.Duration
+	var maxBytes int64
+	var err error
+
+	if c.maxAgeLimit != "" {
+		maxAge, err = fisk.ParseDuration(c.maxAgeLimit)
+		if err != nil {
+			return fmt.Errorf("invalid maximum age: %w", err)
+		}
+	}
+
+	if c.maxBytesLimitString != "" {
+		maxBytes, err = parseStringAsBytes(c.maxBytesLimitString)
+		if err != nil {
+			return fmt.Errorf("invalid maximum bytes: %w", err)
+		}
+	}
+
+	if maxAge <= 0 && maxBytes <= 0 && c.maxMsgLimit <= 0 {
+		return fmt.Errorf("at least one of --max-age, --max-bytes or --max-msgs is required")
+	}
+
+	if c.simulateSample < 1 {
+		return fmt.Errorf("sample must be at least 1")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	state, err := stream.State()
+	if err != nil {
+		return err
+	}
+
+	seqs, estimated := c.simulateSequences(state.FirstSeq, state.LastSeq, state.Msgs)
+
+	if c.json {
+		c.showProgress = false
+	}
+
+	var progress *uiprogress.Bar
+	if c.showProgress && len(seqs) > 0 {
+		progress = uiprogress.AddBar(len(seqs)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		})
+		uiprogress.Start()
+	}
+
+	samples := make([]retentionSample, 0, len(seqs))
+	for _, seq := range seqs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		msg, err := stream.ReadMessage(seq)
+		if progress != nil {
+			progress.Incr()
+		}
+		if jsm.IsNatsError(err, 10037) {
+			continue
+		}
+		if err != nil {
+			if progress != nil {
+				uiprogress.Stop()
+			}
+			return fmt.Errorf("could not read message %d: %w", seq, err)
+		}
+
+		samples = append(samples, retentionSample{
+			Subject: msg.Subject,
+			Time:    msg.Time,
+			Size:    uint64(len(msg.Subject) + len(msg.Header) + len(msg.Data)),
+		})
+	}
+
+	if progress != nil {
+		time.Sleep(250 * time.Millisecond) // let it draw
+		uiprogress.Stop()
+		fmt.Println()
+	}
+
+	sim := simulateRetention(samples, state, maxAge, maxBytes, c.maxMsgLimit, time.Now())
+	sim.Stream = stream.Name()
+	sim.Estimated = estimated
+	if !streamHasWildcardSubjects(stream.Subjects()) {
+		sim.Subjects = nil
+	}
+
+	if c.json {
+		return iu.PrintJSON(sim)
+	}
+
+	c.renderRetentionSimulation(sim)
+
+	return nil
+}
+
+// simulateSequences picks the sequences to read, all of them when there are no more than the sample size otherwise
+// evenly spaced ones between first and last, the boolean is true when sampling
+func (c *streamCmd) simulateSequences(first uint64, last uint64, msgs uint64) ([]uint64, bool) {
+	if msgs == 0 || first == 0 || last < first {
+		return nil, false
+	}
+
+	count := last - first + 1
+	if count <= uint64(c.simulateSample) {
+		seqs := make([]uint64, 0, count)
+		for seq := first; seq <= last; seq++ {
+			seqs = append(seqs, seq)
+		}
+		return seqs, false
+	}
+
+	step := float64(count) / float64(c.simulateSample)
+	seqs := make([]uint64, 0, c.simulateSample)
+	for i := 0; i < c.simulateSample; i++ {
+		seqs = append(seqs, first+uint64(float64(i)*step))
+	}
+
+	return seqs, true
+}
+
+// simulateRetention estimates what applying the limits to a stream would remove, limits remove the oldest messages first
+// so samples must be in stream order. Each sample stands for an equal share of the messages in the stream and the
+// storage overhead not covered by the sample sizes is spread evenly over all messages
+func simulateRetention(samples []retentionSample, state api.StreamState, maxAge time.Duration, maxBytes int64, maxMsgs int64, now time.Time) *retentionSimulation {
+	sim := &retentionSimulation{
+		MaxAge:   maxAge,
+		MaxBytes: maxBytes,
+		MaxMsgs:  maxMsgs,
+		Messages: state.Msgs,
+		Bytes:    state.Bytes,
+		Sampled:  len(samples),
+		Subjects: []*retentionSubjectRemoval{},
+	}
+
+	if len(samples) == 0 || state.Msgs == 0 {
+		return sim
+	}
+
+	weight := float64(state.Msgs) / float64(len(samples))
+
+	var raw float64
+	for _, s := range samples {
+		raw += float64(s.Size)
+	}
+	overhead := math.Max(0, (float64(state.Bytes)-raw*weight)/float64(state.Msgs))
+
+	remainingMsgs := float64(state.Msgs)
+	remainingBytes := float64(state.Bytes)
+	cutoff := now.Add(-maxAge)
+
+	var removedMsgs, removedBytes float64
+	subjects := map[string][2]float64{}
+
+	for _, s := range samples {
+		size := (float64(s.Size) + overhead) * weight
+
+		var portion float64
+		if maxAge > 0 && s.Time.Before(cutoff) {
+			portion = 1
+		}
+		if maxMsgs > 0 && remainingMsgs > float64(maxMsgs) {
+			portion = math.Max(portion, math.Min(1, (remainingMsgs-float64(maxMsgs))/weight))
+		}
+		if maxBytes > 0 && remainingBytes > float64(maxBytes) && size > 0 {
+			portion = math.Max(portion, math.Min(1, (remainingBytes-float64(maxBytes))/size))
+		}
+
+		if portion <= 0 {
+			break
+		}
+
+		// the server removes whole messages
+		portion = math.Min(1, math.Ceil(portion*weight)/weight)
+
+		removedMsgs += portion * weight
+		removedBytes += portion * size
+		remainingMsgs -= portion * weight
+		remainingBytes -= portion * size
+
+		subj := subjects[s.Subject]
+		subj[0] += portion * weight
+		subj[1] += portion * size
+		subjects[s.Subject] = subj
+	}
+
+	sim.RemovedMessages = uint64(math.Min(math.Round(removedMsgs), float64(state.Msgs)))
+	sim.RemovedBytes = uint64(math.Min(math.Round(removedBytes), float64(state.Bytes)))
+
+	for subj, removed := range subjects {
+		sim.Subjects = append(sim.Subjects, &retentionSubjectRemoval{
+			Subject:  subj,
+			Messages: uint64(math.Round(removed[0])),
+			Bytes:    uint64(math.Round(removed[1])),
+		})
+	}
+
+	sort.Slice(sim.Subjects, func(i, j int) bool {
+		return sortMultiSort(sim.Subjects[i].Messages, sim.Subjects[j].Messages, sim.Subjects[i].Subject, sim.Subjects[j].Subject)
+	})
+
+	return sim
+}
+
+func streamHasWildcardSubjects(subjects []string) bool {
+	if len(subjects) > 1 {
+		return true
+	}
+
+	for _, s := range subjects {
+		if strings.ContainsAny(s, "*>") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *streamCmd) renderRetentionSimulation(sim *retentionSimulation) {
+	var limits []string
+	if sim.MaxAge > 0 {
+		limits = append(limits, fmt.Sprintf("max age %s", f(sim.MaxAge)))
+	}
+	if sim.MaxBytes > 0 {
+		limits = append(limits, fmt.Sprintf("max bytes %s", fiBytes(uint64(sim.MaxBytes))))
+	}
+	if sim.MaxMsgs > 0 {
+		limits = append(limits, fmt.Sprintf("max messages %s", f(sim.MaxMsgs)))
+	}
+
+	cols := newColumns("Simulated retention for Stream %s with %s", sim.Stream, strings.Join(limits, ", "))
+	cols.AddRow("Messages", sim.Messages)
+	cols.AddRow("Bytes", fiBytes(sim.Bytes))
+	cols.AddRowf("Removed Messages", "%s (%.1f%%)", f(sim.RemovedMessages), simulatePercent(sim.RemovedMessages, sim.Messages))
+	cols.AddRowf("Removed Bytes", "%s (%.1f%%)", fiBytes(sim.RemovedBytes), simulatePercent(sim.RemovedBytes, sim.Bytes))
+	cols.AddRow("Remaining Messages", sim.Messages-sim.RemovedMessages)
+	cols.AddRow("Remaining Bytes", fiBytes(sim.Bytes-sim.RemovedBytes))
+	cols.Frender(os.Stdout)
+
+	if len(sim.Subjects) > 0 {
+		table := newTableWriter("Removed per Subject")
+		table.AddHeaders("Subject", "Messages", "Bytes")
+		for i, s := range sim.Subjects {
+			if i == 25 {
+				table.AddFooter(fmt.Sprintf("%s more subjects", f(len(sim.Subjects)-i)), "", "")
+				break
+			}
+			table.AddRow(s.Subject, f(s.Messages), fiBytes(s.Bytes))
+		}
+		fmt.Println(table.Render())
+	}
+
+	if sim.Estimated {
+		fmt.Printf("These are estimates based on %s of %s messages sampled at even intervals, each standing for about %s messages.\n", f(sim.Sampled), f(sim.Messages), f(uint64(math.Round(float64(sim.Messages)/float64(max(sim.Sampled, 1))))))
+		fmt.Println("Increase --sample for a more accurate estimate.")
+	}
+}
+
+func simulatePercent(part uint64, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	return float64(part) / float64(total) * 100
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+func (c *streamCmd) sourcesAddAction(_ *fisk.ParseContext) error {
+	stream, cfg, err := c.loadSourcesConfig()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Mirror != nil {
+		return fmt.Errorf("stream %s is a mirror and can not have sources", c.stream)
+	}
+
+	for _, source := range cfg.Sources {
+		if source.Name == c.sourceName && source.FilterSubject == c.sourceFilter {
+			return fmt.Errorf("stream %s already sources from %s", c.stream, c.renderSourceFilter(source))
+		}
+	}
+
+	source := &api.StreamSource{Name: c.sourceName, FilterSubject: c.sourceFilter}
+	cfg.Sources = append(cfg.Sources, source)
+
+	return c.updateSources(stream, cfg, fmt.Sprintf("Really add source %s to Stream %s", c.renderSourceFilter(source), c.stream))
+}
+
+func (c *streamCmd) sourcesRemoveAction(_ *fisk.ParseContext) error {
+	stream, cfg, err := c.loadSourcesConfig()
+	if err != nil {
+		return err
+	}
+
+	var remaining []*api.StreamSource
+	var removed []*api.StreamSource
+	for _, source := range cfg.Sources {
+		if source.Name == c.sourceName && (c.sourceFilter == "" || source.FilterSubject == c.sourceFilter) {
+			removed = append(removed, source)
+		} else {
+			remaining = append(remaining, source)
+		}
+	}
+
+	switch {
+	case len(removed) == 0 && c.sourceFilter != "":
+		return fmt.Errorf("stream %s does not source from %s with filter subject %s", c.stream, c.sourceName, c.sourceFilter)
+	case len(removed) == 0:
+		return fmt.Errorf("stream %s does not source from %s", c.stream, c.sourceName)
+	case len(removed) > 1:
+		return fmt.Errorf("stream %s sources from %s %d times, select one using --filter-subject", c.stream, c.sourceName, len(removed))
+	}
+
+	cfg.Sources = remaining
+
+	return c.updateSources(stream, cfg, fmt.Sprintf("Really remove source %s from Stream %s", c.renderSourceFilter(removed[0]), c.stream))
+}
+
+// loadSourcesConfig loads the stream and a copy of its configuration to modify
+func (c *streamCmd) loadSourcesConfig() (*jsm.Stream, *api.StreamConfig, error) {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// lazy deep copy
+	ij, err := json.Marshal(stream.Configuration())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cfg api.StreamConfig
+	err = json.Unmarshal(ij, &cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stream, &cfg, nil
+}
+
+func (c *streamCmd) updateSources(stream *jsm.Stream, cfg *api.StreamConfig, prompt string) error {
+	if !c.force {
+		ok, err := askConfirmation(prompt, false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	err := stream.UpdateConfiguration(*cfg)
+	if err != nil {
+		return fmt.Errorf("could not update Stream %s: %w", c.stream, err)
+	}
+
+	sources := stream.Sources()
+	if c.json {
+		if sources == nil {
+			sources = []*api.StreamSource{}
+		}
+		return iu.PrintJSON(sources)
+	}
+
+	if len(sources) == 0 {
+		fmt.Printf("Stream %s has no sources\n", c.stream)
+		return nil
+	}
+
+	table := newTableWriter(fmt.Sprintf("Sources for Stream %s", c.stream))
+	table.AddHeaders("Stream", "Filter Subject", "Start Sequence", "Start Time", "API Prefix")
+	for _, source := range sources {
+		var startTime, apiPrefix string
+		if source.OptStartTime != nil {
+			startTime = f(*source.OptStartTime)
+		}
+		if source.External != nil {
+			apiPrefix = source.External.ApiPrefix
+		}
+
+		table.AddRow(source.Name, source.FilterSubject, f(source.OptStartSeq), startTime, apiPrefix)
+	}
+	fmt.Println(table.Render())
+
+	return nil
+}
+
+func (c *streamCmd) renderSourceFilter(source *api.StreamSource) string {
+	if source.FilterSubject == "" {
+		return source.Name
+	}
+
+	return fmt.Sprintf("%s (%s)", source.Name, source.FilterSubject)
+}
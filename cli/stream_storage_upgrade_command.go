@@ -0,0 +1,439 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+)
+
+func (c *streamCmd) storageUpgradeAction(_ *fisk.ParseContext) error {
+	return c.storageConvert(api.FileStorage, "storage-upgrade")
+}
+
+func (c *streamCmd) storageConvertAction(_ *fisk.ParseContext) error {
+	if c.storageParallel < 1 {
+		return fmt.Errorf("parallel must be at least 1")
+	}
+
+	target := api.FileStorage
+	if c.storageTarget == "memory" {
+		target = api.MemoryStorage
+	}
+
+	return c.storageConvert(target, "storage-convert")
+}
+
+// storageConvert moves the stream to target storage by saving its messages, deleting it and publishing them again to
+// a new stream, the snapshot defaults to a file named after the stream and operation
+func (c *streamCmd) storageConvert(target api.StorageType, operation string) error {
+	c.connectAndAskStream()
+
+	start := time.Now()
+	storage := strings.ToLower(target.String())
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	nfo, err := stream.Information()
+	if err != nil {
+		return err
+	}
+
+	cfg := nfo.Config
+
+	switch {
+	case cfg.Storage == target:
+		fmt.Printf("Stream %s already uses %s storage\n", c.stream, storage)
+		return nil
+	case cfg.Mirror != nil || len(cfg.Sources) > 0:
+		return fmt.Errorf("stream %s is a mirror or has sources, it can not be converted by publishing its messages again", c.stream)
+	case cfg.Retention != api.LimitsPolicy:
+		return fmt.Errorf("stream %s uses %s retention, messages published again would be removed before its Consumers are created", c.stream, strings.ToLower(cfg.Retention.String()))
+	case !c.force:
+		return fmt.Errorf("converting deletes Stream %s and creates it again, pass --force to continue", c.stream)
+	}
+
+	path := c.storageSnapshot
+	if path == "" {
+		path = fmt.Sprintf("%s-%s.jsonl", c.stream, operation)
+	}
+
+	cfg.Storage = target
+
+	moved, err := c.moveStream(stream, nfo, cfg, path, c.storageVerify)
+	if err != nil {
+		return err
+	}
+
+	// without verifying only the message count was compared, the snapshot is the only copy should anything else differ
+	if c.storageVerify {
+		err = os.Remove(path)
+		if err != nil {
+			logWarnf("Could not remove snapshot %s: %v", path, err)
+		}
+	} else {
+		log.Printf("Kept snapshot %s, pass --verify to remove it once the Stream was verified", path)
+	}
+
+	fmt.Printf("Stream %s now uses %s storage, %s messages were published again in %s\n", c.stream, storage, f(moved.published), f(time.Since(start)))
+	if moved.renumbered > 0 {
+		fmt.Printf("%s messages were renumbered to close gaps between sequences\n", f(moved.renumbered))
+	}
+	fmt.Printf("Freed %s of %s storage, the Stream uses %s of %s storage\n", fiBytes(nfo.State.Bytes), strings.ToLower(nfo.Config.Storage.String()), fiBytes(moved.after.State.Bytes), storage)
+
+	return nil
+}
+
+// streamMove is the outcome of moving the messages of a Stream to a new Stream
+type streamMove struct {
+	after      *api.StreamInfo
+	published  uint64
+	renumbered uint64
+}
+
+// moveStream saves the messages of the stream described by nfo to path, deletes the stream and publishes the messages
+// again to a new stream created using cfg. Durable consumers are created again on the new stream once the messages
+// are published so only streams using limits retention can be moved, path is kept when anything fails after the
+// messages were saved
+func (c *streamCmd) moveStream(stream *jsm.Stream, nfo *api.StreamInfo, cfg api.StreamConfig, path string, verify bool) (*streamMove, error) {
+	consumers, missing, err := c.mgr.Consumers(nfo.Config.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("could not retrieve the configuration of Consumers %v", missing)
+	}
+
+	var durables []api.ConsumerConfig
+	for _, cons := range consumers {
+		if cons.IsDurable() {
+			durables = append(durables, cons.Configuration())
+		}
+	}
+
+	if len(durables) > 0 {
+		logWarnf("Stream %s has %s durable Consumers, they will be created again without their delivery state", nfo.Config.Name, f(len(durables)))
+	}
+
+	saved, err := c.storageUpgradeSnapshot(path, nfo)
+	if err != nil {
+		return nil, err
+	}
+
+	// messages added after the snapshot was taken would be lost
+	current, err := stream.Information()
+	if err != nil {
+		return nil, err
+	}
+	if current.State.LastSeq != nfo.State.LastSeq || current.State.Msgs != saved {
+		return nil, fmt.Errorf("stream %s changed while saving its messages, stop publishers and try again, %s was not removed", nfo.Config.Name, path)
+	}
+
+	err = stream.Delete()
+	if err != nil {
+		return nil, fmt.Errorf("could not delete Stream %s, %s was not removed: %w", nfo.Config.Name, path, err)
+	}
+
+	// messages can only be published to a sealed stream once it is created, it is sealed again after
+	sealed := cfg.Sealed
+	cfg.Sealed = false
+	cfg.FirstSeq = nfo.State.FirstSeq
+
+	upgraded, err := c.mgr.NewStreamFromDefault(cfg.Name, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("could not create Stream %s, its messages are saved in %s: %w", cfg.Name, path, err)
+	}
+
+	publishStart := time.Now()
+	published, renumbered, err := c.storageUpgradePublish(path, saved)
+	if err != nil {
+		return nil, fmt.Errorf("publishing failed after %s messages, all messages are saved in %s: %w", f(published), path, err)
+	}
+	log.Printf("Published %s messages to Stream %s in %s", f(published), cfg.Name, f(time.Since(publishStart)))
+
+	if sealed {
+		err = upgraded.Seal()
+		if err != nil {
+			return nil, fmt.Errorf("could not seal Stream %s again: %w", cfg.Name, err)
+		}
+	}
+
+	for _, ccfg := range durables {
+		_, err = c.mgr.NewConsumerFromDefault(cfg.Name, ccfg)
+		if err != nil {
+			return nil, fmt.Errorf("could not create Consumer %s again, all messages are saved in %s: %w", ccfg.Durable, path, err)
+		}
+	}
+
+	after, err := upgraded.Information()
+	if err != nil {
+		return nil, fmt.Errorf("could not load Stream %s, all messages are saved in %s: %w", cfg.Name, path, err)
+	}
+
+	if after.State.Msgs != saved {
+		return nil, fmt.Errorf("stream %s holds %s messages but %s were saved, all messages are saved in %s", cfg.Name, f(after.State.Msgs), f(saved), path)
+	}
+
+	if verify {
+		err = storageConvertVerify(nfo.State, after.State, renumbered)
+		if err != nil {
+			return nil, fmt.Errorf("verifying Stream %s failed, all messages are saved in %s: %w", cfg.Name, path, err)
+		}
+		log.Printf("Verified Stream %s holds %s messages on %s subjects as before", cfg.Name, f(after.State.Msgs), f(after.State.NumSubjects))
+	}
+
+	return &streamMove{after: after, published: published, renumbered: renumbered}, nil
+}
+
+// storageConvertVerify checks the stream holds the same messages after converting as before, the last sequence only
+// matches when no messages were renumbered
+func storageConvertVerify(before api.StreamState, after api.StreamState, renumbered uint64) error {
+	switch {
+	case after.Msgs != before.Msgs:
+		return fmt.Errorf("expected %s messages but found %s", f(before.Msgs), f(after.Msgs))
+	case after.NumSubjects != before.NumSubjects:
+		return fmt.Errorf("expected messages on %s subjects but found %s", f(before.NumSubjects), f(after.NumSubjects))
+	case after.FirstSeq != before.FirstSeq:
+		return fmt.Errorf("expected the first sequence to be %d but found %d", before.FirstSeq, after.FirstSeq)
+	case renumbered == 0 && after.LastSeq != before.LastSeq:
+		return fmt.Errorf("expected the last sequence to be %d but found %d", before.LastSeq, after.LastSeq)
+	}
+
+	return nil
+}
+
+// storageUpgradeSnapshot saves every message in the stream described by nfo to path
+func (c *streamCmd) storageUpgradeSnapshot(path string, nfo *api.StreamInfo) (uint64, error) {
+	start := time.Now()
+
+	writer, err := newMsgCaptureWriter(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var serr error
+	if nfo.State.Msgs > 0 {
+		serr = c.storageUpgradeSave(writer, nfo.State.Msgs)
+	}
+
+	cnt, err := writer.close()
+	if err != nil {
+		return cnt, fmt.Errorf("could not write %s: %w", path, err)
+	}
+	if serr != nil {
+		return cnt, fmt.Errorf("saving messages failed after %s messages: %w", f(cnt), serr)
+	}
+
+	log.Printf("Saved %s messages from Stream %s to %s in %s", f(cnt), c.stream, path, f(time.Since(start)))
+
+	return cnt, nil
+}
+
+func (c *streamCmd) storageUpgradeSave(writer *msgCaptureWriter, total uint64) error {
+	js, err := c.nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	sub, err := js.SubscribeSync("", nats.BindStream(c.stream), nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	bar, stop := c.storageUpgradeProgress(total)
+	defer stop()
+
+	for {
+		msg, err := sub.NextMsg(opts().Timeout)
+		if err != nil {
+			return err
+		}
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+
+		writer.write(&capturedMsg{
+			Subject:  msg.Subject,
+			Header:   msg.Header,
+			Data:     msg.Data,
+			Time:     meta.Timestamp,
+			Stream:   meta.Stream,
+			Sequence: meta.Sequence.Stream,
+		})
+
+		if bar != nil {
+			bar.Incr()
+		}
+
+		if meta.NumPending == 0 {
+			return nil
+		}
+	}
+}
+
+// storageUpgradePublish publishes the messages saved in path in order, failing when other messages are mixed in.
+// Expected sequence headers are not used as the server would store them with the messages. With parallel set above 1
+// that many publishes await acknowledgement, acknowledgements are still checked in the order messages were published
+func (c *streamCmd) storageUpgradePublish(path string, total uint64) (published uint64, renumbered uint64, err error) {
+	if total == 0 {
+		return 0, 0, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	parallel := max(c.storageParallel, 1)
+
+	js, err := c.nc.JetStream(append(jsOpts(), nats.PublishAsyncMaxPending(parallel))...)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	bar, stop := c.storageUpgradeProgress(total)
+	defer stop()
+
+	var last uint64
+	record := func(ack *nats.PubAck, seq uint64) error {
+		switch {
+		case ack.Duplicate:
+			return fmt.Errorf("message %d was rejected as a duplicate of a message with the same Nats-Msg-Id", seq)
+		case last > 0 && ack.Sequence != last+1:
+			return fmt.Errorf("message %d was stored as %d after %d, another publisher is adding messages", seq, ack.Sequence, last)
+		}
+
+		if ack.Sequence != seq {
+			renumbered++
+		}
+		last = ack.Sequence
+		published++
+
+		if bar != nil {
+			bar.Incr()
+		}
+
+		return nil
+	}
+
+	type pendingPublish struct {
+		seq    uint64
+		future nats.PubAckFuture
+	}
+	var pending []pendingPublish
+
+	wait := func() error {
+		p := pending[0]
+		pending = pending[1:]
+
+		select {
+		case ack := <-p.future.Ok():
+			return record(ack, p.seq)
+		case err := <-p.future.Err():
+			return err
+		case <-time.After(opts().Timeout):
+			return fmt.Errorf("timeout waiting for the acknowledgement of message %d", p.seq)
+		}
+	}
+
+	dec := json.NewDecoder(file)
+	for {
+		var msg capturedMsg
+		err = dec.Decode(&msg)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return published, renumbered, fmt.Errorf("invalid snapshot: %w", err)
+		}
+
+		out := &nats.Msg{Subject: msg.Subject, Header: msg.Header, Data: msg.Data}
+
+		if parallel == 1 {
+			pctx, cancel := context.WithTimeout(ctx, opts().Timeout)
+			ack, err := js.PublishMsg(out, nats.Context(pctx))
+			cancel()
+			if err != nil {
+				return published, renumbered, err
+			}
+
+			err = record(ack, msg.Sequence)
+			if err != nil {
+				return published, renumbered, err
+			}
+
+			continue
+		}
+
+		future, err := js.PublishMsgAsync(out)
+		if err != nil {
+			return published, renumbered, err
+		}
+		pending = append(pending, pendingPublish{seq: msg.Sequence, future: future})
+
+		if len(pending) >= parallel {
+			err = wait()
+			if err != nil {
+				return published, renumbered, err
+			}
+		}
+	}
+
+	for len(pending) > 0 {
+		err = wait()
+		if err != nil {
+			return published, renumbered, err
+		}
+	}
+
+	return published, renumbered, nil
+}
+
+// storageUpgradeProgress shows a progress bar when enabled, stop has to be called once done
+func (c *streamCmd) storageUpgradeProgress(total uint64) (bar *uiprogress.Bar, stop func()) {
+	if !c.showProgress || total == 0 {
+		return nil, func() {}
+	}
+
+	progress := uiprogress.New()
+	progress.SetOut(os.Stderr)
+	bar = progress.AddBar(int(total)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+		return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+	})
+	progress.Start()
+
+	return bar, func() {
+		time.Sleep(250 * time.Millisecond) // let it draw
+		progress.Stop()
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+// streamSubjectCount is the number of messages a Stream holds for one subject, bytes are only known after scanning
+type streamSubjectCount struct {
+	Subject  string  `json:"subject"`
+	Messages uint64  `json:"messages"`
+	Percent  float64 `json:"percent"`
+	Bytes    *uint64 `json:"bytes,omitempty"`
+}
+
+type streamSubjectCountsReport struct {
+	Stream   string                `json:"stream"`
+	Filter   string                `json:"filter"`
+	Messages uint64                `json:"messages"`
+	Subjects []*streamSubjectCount `json:"subjects"`
+}
+
+func (c *streamCmd) subjectCountsAction(_ *fisk.ParseContext) error {
+	if c.subjectCountsTop < 0 {
+		return fmt.Errorf("top can not be negative")
+	}
+
+	asked := c.connectAndAskStream()
+
+	subs, err := c.mgr.StreamContainedSubjects(c.stream, c.filterSubject)
+	if err != nil {
+		return err
+	}
+
+	report := &streamSubjectCountsReport{Stream: c.stream, Filter: c.filterSubject}
+	for subject, cnt := range subs {
+		report.Subjects = append(report.Subjects, &streamSubjectCount{Subject: subject, Messages: cnt})
+		report.Messages += cnt
+	}
+
+	sort.Slice(report.Subjects, func(i, j int) bool {
+		if report.Subjects[i].Messages == report.Subjects[j].Messages {
+			return report.Subjects[i].Subject < report.Subjects[j].Subject
+		}
+		return report.Subjects[i].Messages > report.Subjects[j].Messages
+	})
+
+	for _, s := range report.Subjects {
+		s.Percent = float64(s.Messages) / float64(report.Messages) * 100
+	}
+
+	if c.subjectCountsBytes && report.Messages > 0 {
+		err = c.subjectCountsScanBytes(report)
+		if err != nil {
+			return fmt.Errorf("could not measure subject sizes: %w", err)
+		}
+	}
+
+	if c.subjectCountsCSV != "" {
+		err = c.subjectCountsWriteCSV(report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		iu.PrintJSON(report)
+		return nil
+	}
+
+	if asked {
+		fmt.Println()
+	}
+
+	c.subjectCountsRender(report)
+
+	if c.subjectCountsCSV != "" {
+		fmt.Printf("Saved the counts of %s subjects in csv file %s\n", f(len(report.Subjects)), c.subjectCountsCSV)
+	}
+
+	return nil
+}
+
+func (c *streamCmd) subjectCountsRender(report *streamSubjectCountsReport) {
+	if len(report.Subjects) == 0 {
+		fmt.Printf("No subjects found matching %s\n", report.Filter)
+		return
+	}
+
+	shown := report.Subjects
+	if c.subjectCountsTop > 0 && len(shown) > c.subjectCountsTop {
+		shown = shown[:c.subjectCountsTop]
+	}
+
+	title := fmt.Sprintf("%s Subjects in Stream %s", f(len(report.Subjects)), report.Stream)
+	if len(shown) < len(report.Subjects) {
+		title = fmt.Sprintf("Top %s of %s", f(len(shown)), title)
+	}
+
+	table := newTableWriter(title)
+	if c.subjectCountsBytes {
+		table.AddHeaders("Subject", "Messages", "Percent", "Bytes")
+	} else {
+		table.AddHeaders("Subject", "Messages", "Percent")
+	}
+
+	for _, s := range shown {
+		row := []any{s.Subject, f(s.Messages), fmt.Sprintf("%.1f%%", s.Percent)}
+		if s.Bytes != nil {
+			row = append(row, fiBytes(*s.Bytes))
+		}
+		table.AddRow(row...)
+	}
+
+	fmt.Println(table.Render())
+
+	// a single subject is always even, anything else shows how far the busiest subject is from an even spread
+	if len(report.Subjects) > 1 {
+		average := float64(report.Messages) / float64(len(report.Subjects))
+		fmt.Printf("Subjects average %s messages, the busiest subject holds %.1fx the average\n", f(uint64(average+0.5)), float64(report.Subjects[0].Messages)/average)
+		fmt.Println()
+	}
+}
+
+// subjectCountsScanBytes reads the headers of every matching message to add up the payload sizes per subject
+func (c *streamCmd) subjectCountsScanBytes(report *streamSubjectCountsReport) error {
+	js, err := c.nc.JetStream(jsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	filter := report.Filter
+	if filter == ">" {
+		filter = ""
+	}
+
+	sub, err := js.SubscribeSync(filter, nats.BindStream(report.Stream), nats.OrderedConsumer(), nats.DeliverAll(), nats.HeadersOnly())
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	sizes := map[string]uint64{}
+	for {
+		msg, err := sub.NextMsg(opts().Timeout)
+		if err != nil {
+			return err
+		}
+
+		size, err := strconv.ParseUint(msg.Header.Get(nats.MsgSize), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s header on message: %w", nats.MsgSize, err)
+		}
+		sizes[msg.Subject] += size
+
+		meta, err := msg.Metadata()
+		if err != nil {
+			return err
+		}
+		if meta.NumPending == 0 {
+			break
+		}
+	}
+
+	for _, s := range report.Subjects {
+		size := sizes[s.Subject]
+		s.Bytes = &size
+	}
+
+	return nil
+}
+
+func (c *streamCmd) subjectCountsWriteCSV(report *streamSubjectCountsReport) error {
+	out, err := os.Create(c.subjectCountsCSV)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	header := []string{"subject", "messages", "percent"}
+	if c.subjectCountsBytes {
+		header = append(header, "bytes")
+	}
+	w.Write(header)
+
+	for _, s := range report.Subjects {
+		row := []string{s.Subject, strconv.FormatUint(s.Messages, 10), strconv.FormatFloat(s.Percent, 'f', 2, 64)}
+		if s.Bytes != nil {
+			row = append(row, strconv.FormatUint(*s.Bytes, 10))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+
+	if w.Error() != nil {
+		return w.Error()
+	}
+
+	return out.Close()
+}
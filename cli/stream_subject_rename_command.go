@@ -0,0 +1,178 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/choria-io/fisk"
+	"github.com/nats-io/jsm.go/api"
+)
+
+// streamSubjectRename renames subject when it is old, when old and new both end in > subjects old covers are
+// renamed keeping their tokens after the prefix
+func streamSubjectRename(subject string, old string, new string) (string, bool) {
+	if subject == old {
+		return new, true
+	}
+
+	if !strings.HasSuffix(old, ">") || !strings.HasSuffix(new, ">") {
+		return subject, false
+	}
+
+	prefix := strings.TrimSuffix(old, ">")
+	if !strings.HasPrefix(subject, prefix) {
+		return subject, false
+	}
+
+	return strings.TrimSuffix(new, ">") + strings.TrimPrefix(subject, prefix), true
+}
+
+// streamSubjectsRename renames all subjects using streamSubjectRename removing any duplicates it creates
+func streamSubjectsRename(subjects []string, old string, new string) ([]string, bool) {
+	var renamed []string
+	var changed bool
+
+	for _, subject := range subjects {
+		subject, ok := streamSubjectRename(subject, old, new)
+		changed = changed || ok
+		if !slices.Contains(renamed, subject) {
+			renamed = append(renamed, subject)
+		}
+	}
+
+	return renamed, changed
+}
+
+// consumerFilterRename is a Consumer with its filters renamed
+type consumerFilterRename struct {
+	name string
+	cfg  api.ConsumerConfig
+	old  []string
+	new  []string
+}
+
+func (c *streamCmd) subjectRenameAction(_ *fisk.ParseContext) error {
+	for _, subject := range []string{c.subjectRenameOld, c.subjectRenameNew} {
+		err := validateSubjectSyntax(subject)
+		if err != nil {
+			return err
+		}
+	}
+	if c.subjectRenameOld == c.subjectRenameNew {
+		return fmt.Errorf("the old and new subjects are the same")
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	cfg := stream.Configuration()
+	if cfg.Mirror != nil {
+		return fmt.Errorf("stream %s is a mirror, mirrors can not have subjects", c.stream)
+	}
+
+	subjects, streamChanged := streamSubjectsRename(cfg.Subjects, c.subjectRenameOld, c.subjectRenameNew)
+
+	consumers, _, err := c.mgr.Consumers(c.stream)
+	if err != nil {
+		return err
+	}
+
+	var renames []*consumerFilterRename
+	for _, cons := range consumers {
+		ccfg := cons.Configuration()
+
+		old := ccfg.FilterSubjects
+		if ccfg.FilterSubject != "" {
+			old = []string{ccfg.FilterSubject}
+		}
+
+		filters, changed := streamSubjectsRename(old, c.subjectRenameOld, c.subjectRenameNew)
+		if !changed {
+			continue
+		}
+
+		if !cons.IsDurable() {
+			logWarnf("Consumer %s is ephemeral and can not be updated, it will keep filtering %s", cons.Name(), strings.Join(old, ", "))
+			continue
+		}
+
+		if len(filters) == 1 {
+			ccfg.FilterSubject = filters[0]
+			ccfg.FilterSubjects = nil
+		} else {
+			ccfg.FilterSubject = ""
+			ccfg.FilterSubjects = filters
+		}
+
+		renames = append(renames, &consumerFilterRename{name: cons.Name(), cfg: ccfg, old: old, new: filters})
+	}
+
+	if !streamChanged && len(renames) == 0 {
+		return fmt.Errorf("no subjects of Stream %s or filters of its Consumers match %s", c.stream, c.subjectRenameOld)
+	}
+
+	table := newTableWriter(fmt.Sprintf("Renaming %s to %s", c.subjectRenameOld, c.subjectRenameNew))
+	table.AddHeaders("Changing", "Old Subjects", "New Subjects")
+	if streamChanged {
+		table.AddRow(fmt.Sprintf("Stream %s", c.stream), strings.Join(cfg.Subjects, ", "), strings.Join(subjects, ", "))
+	}
+	for _, rename := range renames {
+		table.AddRow(fmt.Sprintf("Consumer %s", rename.name), strings.Join(rename.old, ", "), strings.Join(rename.new, ", "))
+	}
+	fmt.Println(table.Render())
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really rename subjects on Stream %s and %d Consumers", c.stream, len(renames)), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	// the stream is updated first as consumers can only filter subjects the stream holds
+	if streamChanged {
+		cfg.Subjects = subjects
+		err = stream.UpdateConfiguration(cfg)
+		if err != nil {
+			return fmt.Errorf("could not update subjects of Stream %s: %w", c.stream, err)
+		}
+		fmt.Printf("Updated Stream %s subjects to %s\n", c.stream, strings.Join(subjects, ", "))
+	}
+
+	var errs []error
+	for _, rename := range renames {
+		_, err = c.mgr.NewConsumerFromDefault(c.stream, rename.cfg)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not update filters of Consumer %s: %w", rename.name, err))
+			continue
+		}
+		fmt.Printf("Updated Consumer %s filters to %s\n", rename.name, strings.Join(rename.new, ", "))
+	}
+
+	if streamChanged {
+		fmt.Println()
+		fmt.Println("Messages already stored keep their subjects, Consumers filtering the new subjects will not receive them")
+	}
+
+	return errors.Join(errs...)
+}
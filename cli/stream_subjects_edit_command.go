@@ -0,0 +1,130 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/choria-io/fisk"
+)
+
+// streamSubjectsAdd appends the subjects in add that are not already in current
+func streamSubjectsAdd(current []string, add []string) (updated []string, added []string) {
+	updated = slices.Clone(current)
+	for _, subject := range add {
+		if !slices.Contains(updated, subject) {
+			updated = append(updated, subject)
+			added = append(added, subject)
+		}
+	}
+
+	return updated, added
+}
+
+// streamSubjectsRemove removes the subjects in remove from current, every subject has to be in current and at least
+// one subject has to remain
+func streamSubjectsRemove(current []string, remove []string) ([]string, error) {
+	var updated []string
+	for _, subject := range remove {
+		if !slices.Contains(current, subject) {
+			return nil, fmt.Errorf("subject %s is not one of the subjects %s", subject, strings.Join(current, ", "))
+		}
+	}
+
+	for _, subject := range current {
+		if !slices.Contains(remove, subject) {
+			updated = append(updated, subject)
+		}
+	}
+
+	if len(updated) == 0 {
+		return nil, fmt.Errorf("removing %s would leave the Stream without subjects", strings.Join(remove, ", "))
+	}
+
+	return updated, nil
+}
+
+func (c *streamCmd) addSubjectAction(_ *fisk.ParseContext) error {
+	for _, subject := range c.subjectChanges {
+		err := validateSubjectSyntax(subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	cfg := stream.Configuration()
+	if cfg.Mirror != nil {
+		return fmt.Errorf("stream %s is a mirror, mirrors can not have subjects", c.stream)
+	}
+
+	updated, added := streamSubjectsAdd(cfg.Subjects, c.subjectChanges)
+	if len(added) == 0 {
+		fmt.Printf("Stream %s already has subjects %s\n", c.stream, strings.Join(c.subjectChanges, ", "))
+		return nil
+	}
+
+	cfg.Subjects = updated
+	err = stream.UpdateConfiguration(cfg)
+	if err != nil {
+		return fmt.Errorf("could not add subjects %s to Stream %s: %w", strings.Join(added, ", "), c.stream, err)
+	}
+
+	fmt.Printf("Added subjects %s to Stream %s, it now has subjects %s\n", strings.Join(added, ", "), c.stream, strings.Join(updated, ", "))
+
+	return nil
+}
+
+func (c *streamCmd) removeSubjectAction(_ *fisk.ParseContext) error {
+	c.connectAndAskStream()
+
+	stream, err := c.loadStream(c.stream)
+	if err != nil {
+		return err
+	}
+
+	cfg := stream.Configuration()
+	updated, err := streamSubjectsRemove(cfg.Subjects, c.subjectChanges)
+	if err != nil {
+		return err
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Really stop Stream %s storing messages published to %s", c.stream, strings.Join(c.subjectChanges, ", ")), false)
+		fisk.FatalIfError(err, "could not obtain confirmation")
+
+		if !ok {
+			return nil
+		}
+	}
+
+	cfg.Subjects = updated
+	err = stream.UpdateConfiguration(cfg)
+	if err != nil {
+		return fmt.Errorf("could not remove subjects %s from Stream %s: %w", strings.Join(c.subjectChanges, ", "), c.stream, err)
+	}
+
+	fmt.Printf("Removed subjects %s from Stream %s, it now has subjects %s\n", strings.Join(c.subjectChanges, ", "), c.stream, strings.Join(updated, ", "))
+	fmt.Println("Messages already stored on the removed subjects are kept")
+
+	return nil
+}
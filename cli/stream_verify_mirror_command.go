@@ -0,0 +1,293 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/gosuri/uiprogress"
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+type streamVerifyMirrorState struct {
+	Stream   string `json:"stream"`
+	Messages uint64 `json:"messages"`
+	FirstSeq uint64 `json:"first_seq"`
+	LastSeq  uint64 `json:"last_seq"`
+}
+
+type streamVerifyMirrorMismatch struct {
+	Sequence uint64 `json:"seq"`
+	Reason   string `json:"reason"`
+}
+
+// streamVerifyMirrorReport is the result of comparing a mirror to its origin over the sequences both hold
+type streamVerifyMirrorReport struct {
+	Origin     streamVerifyMirrorState       `json:"origin"`
+	Mirror     streamVerifyMirrorState       `json:"mirror"`
+	Full       bool                          `json:"full"`
+	FirstSeq   uint64                        `json:"compared_first_seq"`
+	LastSeq    uint64                        `json:"compared_last_seq"`
+	Checked    int                           `json:"checked"`
+	Mismatches []*streamVerifyMirrorMismatch `json:"mismatches"`
+}
+
+func (c *streamCmd) verifyMirrorAction(_ *fisk.ParseContext) error {
+	if c.verifySamples < 1 && !c.verifyFull {
+		return fmt.Errorf("samples must be at least 1")
+	}
+
+	var err error
+	c.nc, c.mgr, err = prepareHelper("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+
+	mirrorMgr := c.mgr
+	if c.verifyContext != "" {
+		nc, mgr, err := prepareHelperForContext(c.verifyContext)
+		if err != nil {
+			return fmt.Errorf("could not connect using context %s: %w", c.verifyContext, err)
+		}
+		defer nc.Close()
+
+		mirrorMgr = mgr
+	}
+
+	origin, err := c.mgr.LoadStream(c.stream)
+	if err != nil {
+		return fmt.Errorf("could not load origin %s: %w", c.stream, err)
+	}
+
+	mirror, err := mirrorMgr.LoadStream(c.verifyMirror)
+	if err != nil {
+		return fmt.Errorf("could not load mirror %s: %w", c.verifyMirror, err)
+	}
+
+	mcfg := mirror.Configuration()
+	switch {
+	case mcfg.Mirror == nil:
+		return fmt.Errorf("stream %s is not a mirror", mirror.Name())
+	case mcfg.Mirror.Name != origin.Name():
+		return fmt.Errorf("stream %s mirrors %s not %s", mirror.Name(), mcfg.Mirror.Name, origin.Name())
+	}
+
+	report := &streamVerifyMirrorReport{Full: c.verifyFull, Mismatches: []*streamVerifyMirrorMismatch{}}
+
+	report.Origin, err = c.verifyMirrorState(origin)
+	if err != nil {
+		return err
+	}
+
+	report.Mirror, err = c.verifyMirrorState(mirror)
+	if err != nil {
+		return err
+	}
+
+	report.FirstSeq = report.Origin.FirstSeq
+	if report.Mirror.FirstSeq > report.FirstSeq {
+		report.FirstSeq = report.Mirror.FirstSeq
+	}
+	report.LastSeq = report.Origin.LastSeq
+	if report.Mirror.LastSeq < report.LastSeq {
+		report.LastSeq = report.Mirror.LastSeq
+	}
+
+	var seqs []uint64
+	if report.FirstSeq > 0 && report.FirstSeq <= report.LastSeq {
+		seqs = c.verifyMirrorSequences(report.FirstSeq, report.LastSeq)
+	}
+
+	if c.json {
+		c.showProgress = false
+	}
+
+	var progress *uiprogress.Bar
+	if c.showProgress && len(seqs) > 0 {
+		progress = uiprogress.AddBar(len(seqs)).AppendCompleted().PrependFunc(func(b *uiprogress.Bar) string {
+			return fmt.Sprintf("%s / %s", f(b.Current()), f(b.Total))
+		})
+		uiprogress.Start()
+	}
+
+	for _, seq := range seqs {
+		if ctx.Err() != nil {
+			break
+		}
+
+		reason, err := c.verifyMirrorMessage(origin, mirror, seq)
+		if err != nil {
+			if progress != nil {
+				uiprogress.Stop()
+			}
+			return err
+		}
+
+		report.Checked++
+		if reason != "" {
+			report.Mismatches = append(report.Mismatches, &streamVerifyMirrorMismatch{Sequence: seq, Reason: reason})
+		}
+
+		if progress != nil {
+			progress.Incr()
+		}
+	}
+
+	if progress != nil {
+		time.Sleep(250 * time.Millisecond) // let it draw
+		uiprogress.Stop()
+		fmt.Println()
+	}
+
+	if c.json {
+		iu.PrintJSON(report)
+	} else {
+		c.renderVerifyMirror(report)
+	}
+
+	if len(report.Mismatches) > 0 {
+		return fmt.Errorf("found %s mismatched messages", f(len(report.Mismatches)))
+	}
+
+	return nil
+}
+
+func (c *streamCmd) verifyMirrorState(stream *jsm.Stream) (streamVerifyMirrorState, error) {
+	state, err := stream.State()
+	if err != nil {
+		return streamVerifyMirrorState{}, err
+	}
+
+	return streamVerifyMirrorState{
+		Stream:   stream.Name(),
+		Messages: state.Msgs,
+		FirstSeq: state.FirstSeq,
+		LastSeq:  state.LastSeq,
+	}, nil
+}
+
+// verifyMirrorSequences picks the sequences to compare, all of them for full verification or when there are fewer than the samples
+func (c *streamCmd) verifyMirrorSequences(first uint64, last uint64) []uint64 {
+	count := last - first + 1
+
+	if c.verifyFull || count <= uint64(c.verifySamples) {
+		seqs := make([]uint64, 0, count)
+		for seq := first; seq <= last; seq++ {
+			seqs = append(seqs, seq)
+		}
+		return seqs
+	}
+
+	picked := map[uint64]bool{}
+	for len(picked) < c.verifySamples {
+		picked[first+uint64(rand.Int63n(int64(count)))] = true
+	}
+
+	seqs := make([]uint64, 0, len(picked))
+	for seq := range picked {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	return seqs
+}
+
+// verifyMirrorMessage compares the message at seq in both streams, returning why they differ or empty when they match
+func (c *streamCmd) verifyMirrorMessage(origin *jsm.Stream, mirror *jsm.Stream, seq uint64) (string, error) {
+	om, err := verifyMirrorRead(origin, seq)
+	if err != nil {
+		return "", err
+	}
+
+	mm, err := verifyMirrorRead(mirror, seq)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case om == nil && mm == nil:
+		return "", nil
+	case om == nil:
+		return "missing from origin", nil
+	case mm == nil:
+		return "missing from mirror", nil
+	case om.Subject != mm.Subject:
+		return fmt.Sprintf("subject %s differs from %s", mm.Subject, om.Subject), nil
+	case !bytes.Equal(verifyMirrorHash(om), verifyMirrorHash(mm)):
+		return "payload differs", nil
+	default:
+		return "", nil
+	}
+}
+
+// verifyMirrorRead loads a message by sequence, deleted messages are nil
+func verifyMirrorRead(stream *jsm.Stream, seq uint64) (*api.StoredMsg, error) {
+	msg, err := stream.ReadMessage(seq)
+	if jsm.IsNatsError(err, 10037) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read message %d from %s: %w", seq, stream.Name(), err)
+	}
+
+	return msg, nil
+}
+
+func verifyMirrorHash(msg *api.StoredMsg) []byte {
+	h := sha256.New()
+	h.Write([]byte(msg.Subject))
+	h.Write([]byte{0})
+	h.Write(msg.Data)
+
+	return h.Sum(nil)
+}
+
+func (c *streamCmd) renderVerifyMirror(report *streamVerifyMirrorReport) {
+	table := newTableWriter(fmt.Sprintf("Mirror %s of Stream %s", report.Mirror.Stream, report.Origin.Stream))
+	table.AddHeaders("", "Origin", "Mirror")
+	table.AddRow("Messages", f(report.Origin.Messages), f(report.Mirror.Messages))
+	table.AddRow("First Sequence", f(report.Origin.FirstSeq), f(report.Mirror.FirstSeq))
+	table.AddRow("Last Sequence", f(report.Origin.LastSeq), f(report.Mirror.LastSeq))
+	fmt.Println(table.Render())
+
+	if report.Origin.LastSeq > report.Mirror.LastSeq {
+		fmt.Printf("The mirror is %s messages behind the origin\n\n", f(report.Origin.LastSeq-report.Mirror.LastSeq))
+	}
+
+	if report.Checked == 0 {
+		fmt.Println("No messages are held in both streams, nothing was compared")
+		return
+	}
+
+	if len(report.Mismatches) == 0 {
+		fmt.Printf("Compared %s messages between sequence %s and %s, all matched\n", f(report.Checked), f(report.FirstSeq), f(report.LastSeq))
+		return
+	}
+
+	table = newTableWriter(fmt.Sprintf("%s mismatched messages", f(len(report.Mismatches))))
+	table.AddHeaders("Sequence", "Reason")
+	for _, m := range report.Mismatches {
+		table.AddRow(f(m.Sequence), m.Reason)
+	}
+	fmt.Println(table.Render())
+	fmt.Printf("%s of %s compared messages differ\n", f(len(report.Mismatches)), f(report.Checked))
+}
@@ -14,23 +14,36 @@
 package cli
 
 import (
+	"container/list"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/jsm.go/api"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	terminal "golang.org/x/term"
 )
 
 type subCmd struct {
@@ -39,6 +52,8 @@ type subCmd struct {
 	durable               string
 	raw                   bool
 	translate             string
+	transformIn           []string
+	transform             *payloadTransformPipeline
 	jsAck                 bool
 	inbox                 bool
 	match                 bool
@@ -60,8 +75,47 @@ type subCmd struct {
 	timeStamps            bool
 	deltaTimeStamps       bool
 	subjectsOnly          bool
+	dedupHeader           string
+	tagHeader             string
+	dedupPayload          bool
+	dedupWindow           time.Duration
+	dedupReport           bool
+	prometheusListen      string
+	prometheusTokens      int
+	httpForward           string
+	httpRetries           int
+	interactiveAck        bool
+	verifyOrder           string
+	failOnDisorder        bool
+	validateOrdering      bool
+	sequenceCheck         bool
+	skipAckEvery          uint64
+	skipMode              string
+	logFormat             string
+	measureTTFM           bool
+	connectionReport      bool
+	sizeHistogram         bool
+	gapStats              bool
+	holStats              bool
+	countPerSubject       bool
+	sizeBuckets           []string
+	sizeStored            bool
+	reportInterval        time.Duration
+	countStatus           bool
+	extractField          string
+	maxMessageSizeString  string
+	maxMessageSize        int64
+	extract               subFieldExtractor
+	exitOnErrorField      string
+	errorMatcher          subMessageMatcher
+
+	// the connection each subscription was made on when subscribing on multiple servers
+	subServers map[*nats.Subscription]*nats.Conn
 }
 
+// subDedupCacheSize is the maximum number of message identities tracked when de-duplicating
+const subDedupCacheSize = 100000
+
 func configureSubCommand(app commandHost) {
 	c := &subCmd{}
 
@@ -79,6 +133,26 @@ func configureSubCommand(app commandHost) {
 	Caution: Be careful when subscribing to streams with WorkQueue policy. Messages will be acked and deleted when a durable consumer is being used.
 
 	Use nats stream view <stream> for inspecting messages.	
+
+	Passing --server multiple times subscribes on every server using a separate connection, showing
+	which server each message arrived from.
+
+		E.g. nats sub orders.> --server nats://a:4222 --server nats://b:4222
+
+	Messages can be forwarded to webhook style HTTP endpoints, each is posted with its headers as HTTP
+	headers and the subject in the Nats-Subject header. Failed posts are logged and retried.
+
+		E.g. nats sub events.orders --http-forward http://localhost:8080/ingest
+
+	Using --extract-field only the value of a jq path in JSON payloads is shown, one line
+	per message, <nil> is shown for payloads that are not JSON or lack the field.
+
+		E.g. nats sub events.orders --extract-field .customer.email
+
+	CI pipelines can wait for failures reported as JSON events, the first message for which the jq
+	expression is neither false nor null is shown and the subscriber exits with code 1.
+
+		E.g. nats sub deployment.events --exit-on-error-field '.status == "error"'
 		
 	`
 
@@ -91,6 +165,7 @@ func configureSubCommand(app commandHost) {
 	act.Flag("durable", "Use a durable consumer (requires JetStream)").StringVar(&c.durable)
 	act.Flag("raw", "Show the raw data received").Short('r').UnNegatableBoolVar(&c.raw)
 	act.Flag("translate", "Translate the message data by running it through the given command before output").StringVar(&c.translate)
+	act.Flag("transform-in", payloadTransformHelp("received message bodies before output")).PlaceHolder("TRANSFORM").StringsVar(&c.transformIn)
 	act.Flag("ack", "Acknowledge JetStream message that have the correct metadata").BoolVar(&c.jsAck)
 	// We do not support (explicit) ackPolicy right now. The only situation where it is useful would be WorkQueue policy right now.
 	// Deleting from a stream with WorkQueue through ack could be unexpected behavior in the sub command.
@@ -99,6 +174,7 @@ func configureSubCommand(app commandHost) {
 	act.Flag("match-replies", "Match replies to requests").UnNegatableBoolVar(&c.match)
 	act.Flag("inbox", "Subscribes to a generate inbox").Short('i').UnNegatableBoolVar(&c.inbox)
 	act.Flag("count", "Quit after receiving this many messages").UintVar(&c.limit)
+	act.Flag("count-status", "Count status messages like 503 No Responders towards --count").UnNegatableBoolVar(&c.countStatus)
 	act.Flag("dump", "Dump received messages to files, 1 file per message. Specify - for null terminated STDOUT for use with xargs -0").PlaceHolder("DIRECTORY").StringVar(&c.dump)
 	act.Flag("headers-only", "Do not render any data, shows only headers").UnNegatableBoolVar(&c.headersOnly)
 	act.Flag("subjects-only", "Prints only the messages' subjects").UnNegatableBoolVar(&c.subjectsOnly)
@@ -115,6 +191,35 @@ func configureSubCommand(app commandHost) {
 	act.Flag("report-top", "Number of subjects to show when doing 'report-subjects'. Default is 10.").Default("10").IntVar(&c.reportSubjectsCount)
 	act.Flag("timestamp", "Show timestamps in output").Short('t').UnNegatableBoolVar(&c.timeStamps)
 	act.Flag("delta-time", "Show time since start in output").Short('d').UnNegatableBoolVar(&c.deltaTimeStamps)
+	act.Flag("dedup-header", "Suppress messages with a value for this header that was already seen").PlaceHolder("HEADER").StringVar(&c.dedupHeader)
+	act.Flag("dedup-payload", "Suppress messages with a payload that was already seen").UnNegatableBoolVar(&c.dedupPayload)
+	act.Flag("dedup-window", "Only consider messages duplicates when seen again within this duration").PlaceHolder("DURATION").DurationVar(&c.dedupWindow)
+	act.Flag("dedup-report", "Report how many duplicate messages were suppressed on exit").UnNegatableBoolVar(&c.dedupReport)
+	act.Flag("prometheus", "Expose Prometheus metrics about received messages on this address instead of showing messages").PlaceHolder("ADDRESS").StringVar(&c.prometheusListen)
+	act.Flag("prometheus-tokens", "Number of leading subject tokens to use as the subject label in Prometheus metrics, 0 for the full subject").Default("1").IntVar(&c.prometheusTokens)
+	act.Flag("http-forward", "POST every message received to this HTTP endpoint with NATS headers as HTTP headers").PlaceHolder("URL").StringVar(&c.httpForward)
+	act.Flag("http-retries", "How many times to retry posts to the http-forward endpoint that fail or do not respond with 2xx").Default("3").IntVar(&c.httpRetries)
+	act.Flag("verify-order", "Verify messages are in order using a numeric header, or a JSON body field when starting with . like .meta.seq").PlaceHolder("HEADER|PATH").StringVar(&c.verifyOrder)
+	act.Flag("fail-on-disorder", "Exit with an error when messages were found out of order").UnNegatableBoolVar(&c.failOnDisorder)
+	act.Flag("validate-ordering", "Verify JetStream messages arrive in stream order without any being missed, exiting with an error otherwise (requires JetStream)").UnNegatableBoolVar(&c.validateOrdering)
+	act.Flag("sequence-check", "Verify every sequence number in message bodies, as published using {{Count}}, is received once and in order, exiting with an error otherwise").UnNegatableBoolVar(&c.sequenceCheck)
+	act.Flag("interactive-ack", "Fetch JetStream messages one at a time and prompt to ack, nak, term or skip each (requires JetStream)").UnNegatableBoolVar(&c.interactiveAck)
+	act.Flag("skip-ack-every", "Do not acknowledge every Nth newly delivered JetStream message to provoke redeliveries").PlaceHolder("N").Uint64Var(&c.skipAckEvery)
+	act.Flag("skip-mode", "How to handle messages that are not acknowledged (ignore, nak)").Default("ignore").EnumVar(&c.skipMode, "ignore", "nak")
+	act.Flag("log-format", "Show every message as a single structured log line (logfmt, json)").EnumVar(&c.logFormat, "logfmt", "json")
+	act.Flag("tag-header", "Prepend the value of this header to the output line of every message").PlaceHolder("HEADER").StringVar(&c.tagHeader)
+	act.Flag("connection-report", "Show statistics about the connections used when exiting").UnNegatableBoolVar(&c.connectionReport)
+	act.Flag("size-histogram", "Show the distribution of message sizes when exiting").UnNegatableBoolVar(&c.sizeHistogram)
+	act.Flag("size-buckets", "Upper bounds of the message size buckets like 1KB,64KB,1MB").PlaceHolder("SIZES").StringsVar(&c.sizeBuckets)
+	act.Flag("size-stored", "Use the stored payload size of JetStream messages rather than the size on the wire including headers").UnNegatableBoolVar(&c.sizeStored)
+	act.Flag("report-interval", "Also show the distribution of message sizes at this interval").PlaceHolder("DURATION").DurationVar(&c.reportInterval)
+	act.Flag("gap-stats", "Show statistics and the distribution of the time between consecutive messages when exiting").UnNegatableBoolVar(&c.gapStats)
+	act.Flag("count-per-subject", "Show how many messages were received on every subject when exiting").UnNegatableBoolVar(&c.countPerSubject)
+	act.Flag("hol-stats", "Show how long messages waited in the client before the handler started when exiting").UnNegatableBoolVar(&c.holStats)
+	act.Flag("max-message-size", "Skip messages with payloads larger than this, reporting how many were skipped on exit").PlaceHolder("BYTES").StringVar(&c.maxMessageSizeString)
+	act.Flag("extract-field", "Show only the value of this jq path in JSON payloads, <nil> when missing or not JSON").PlaceHolder("PATH").StringVar(&c.extractField)
+	act.Flag("exit-on-error-field", "Exit with code 1 after the first JSON message matching this jq expression").PlaceHolder("JQ").StringVar(&c.exitOnErrorField)
+	act.Flag("measure-ttfm", "Measure the time from subscribing until the first message arrives, failing when none arrives within the timeout").UnNegatableBoolVar(&c.measureTTFM)
 }
 
 func init() {
@@ -155,7 +260,7 @@ func startSubjectReporting(ctx context.Context, subjMu *sync.Mutex, subjectRepor
 
 				for count, k := range keys {
 
-					subjectRows = append(subjectRows, []any{k, f(subjectReportMap[k]), humanize.IBytes(uint64(subjectBytesReportMap[k]))})
+					subjectRows = append(subjectRows, []any{k, f(subjectReportMap[k]), fiBytes(uint64(subjectBytesReportMap[k]))})
 					totalCount += subjectReportMap[k]
 					totalBytes += subjectBytesReportMap[k]
 					if (count + 1) == subjCount {
@@ -172,7 +277,7 @@ func startSubjectReporting(ctx context.Context, subjMu *sync.Mutex, subjectRepor
 				}
 				table := newTableWriter(tableHeaderString)
 				table.AddHeaders("Subject", "Message Count", "Bytes")
-				table.AddFooter("Totals", f(totalCount), humanize.IBytes(uint64(totalBytes)))
+				table.AddFooter("Totals", f(totalCount), fiBytes(uint64(totalBytes)))
 				for i := range subjectRows {
 					table.AddRow(subjectRows[i]...)
 				}
@@ -183,13 +288,45 @@ func startSubjectReporting(ctx context.Context, subjMu *sync.Mutex, subjectRepor
 }
 
 func (c *subCmd) subscribe(p *fisk.ParseContext) error {
-	nc, err := newNatsConn("", natsOpts()...)
+	subscribeStart := time.Now()
+
+	c.jetStream = c.sseq > 0 || len(c.durable) > 0 || c.deliverAll || c.deliverNew || c.deliverLast || c.deliverSince != "" || c.deliverLastPerSubject || c.stream != ""
+
+	var err error
+	c.transform, err = newPayloadTransformPipeline(c.transformIn, c.translate)
 	if err != nil {
 		return err
 	}
-	defer nc.Close()
 
-	c.jetStream = c.sseq > 0 || len(c.durable) > 0 || c.deliverAll || c.deliverNew || c.deliverLast || c.deliverSince != "" || c.deliverLastPerSubject || c.stream != ""
+	multiServer := len(opts().ServerURLs) > 1
+	if multiServer && (c.jetStream || c.jsAck || c.inbox || c.match) {
+		return fmt.Errorf("subscribing on multiple servers is not compatible with JetStream, ack, inbox or match-replies")
+	}
+
+	manager := newConnManager()
+	defer manager.shutdown(false, c.connectionReport)
+
+	var conns []*nats.Conn
+	if multiServer {
+		c.subServers = make(map[*nats.Subscription]*nats.Conn)
+
+		// separate connections that reconnect to the same server first should they disconnect
+		for i, server := range opts().ServerURLs {
+			conn, err := manager.connect(fmt.Sprintf("server %d", i+1), server, nats.DontRandomize())
+			if err != nil {
+				return err
+			}
+			conns = append(conns, conn)
+		}
+	} else {
+		nc, err := manager.shared("default")
+		if err != nil {
+			return err
+		}
+		conns = []*nats.Conn{nc}
+	}
+
+	nc := conns[0]
 
 	switch {
 	case len(c.subjects) == 0 && c.inbox:
@@ -200,6 +337,13 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		return fmt.Errorf("streams subscribe support only 1 subject")
 	}
 
+	for _, subject := range c.subjects {
+		err := validateSubscribeSubject(subject, c.queue)
+		if err != nil {
+			return err
+		}
+	}
+
 	if c.inbox && c.jetStream {
 		return fmt.Errorf("generating inboxes is not compatible with JetStream subscriptions")
 	}
@@ -215,6 +359,89 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 	if c.timeStamps && c.deltaTimeStamps {
 		return fmt.Errorf("timestamp and delta-time flags are mutually exclusive")
 	}
+	if c.dedupHeader != "" && c.dedupPayload {
+		return fmt.Errorf("dedup-header and dedup-payload flags are mutually exclusive")
+	}
+	if (c.dedupWindow > 0 || c.dedupReport) && c.dedupHeader == "" && !c.dedupPayload {
+		return fmt.Errorf("dedup-window and dedup-report require dedup-header or dedup-payload")
+	}
+	if c.prometheusListen != "" && (c.reportSubjects || c.match || c.dump != "") {
+		return fmt.Errorf("prometheus is not compatible with report-subjects, match-replies or dump")
+	}
+	if c.httpForward != "" && (c.interactiveAck || c.match) {
+		return fmt.Errorf("http-forward is not compatible with interactive-ack or match-replies")
+	}
+	if c.failOnDisorder && c.verifyOrder == "" {
+		return fmt.Errorf("fail-on-disorder requires verify-order")
+	}
+	if c.validateOrdering {
+		switch {
+		case !c.jetStream:
+			return fmt.Errorf("validate-ordering requires a JetStream subscription")
+		case c.verifyOrder != "" || c.interactiveAck:
+			return fmt.Errorf("validate-ordering is not compatible with verify-order or interactive-ack")
+		}
+	}
+	if c.skipAckEvery > 0 && c.interactiveAck {
+		return fmt.Errorf("skip-ack-every is not compatible with interactive-ack")
+	}
+	if c.skipAckEvery > 0 && !c.jetStream && !c.jsAck {
+		return fmt.Errorf("skip-ack-every requires a JetStream subscription or ack")
+	}
+	if c.logFormat != "" && (c.raw || c.dump != "" || c.match || c.reportSubjects || c.prometheusListen != "" || c.interactiveAck) {
+		return fmt.Errorf("log-format is not compatible with raw, dump, match-replies, report-subjects, prometheus or interactive-ack")
+	}
+	if c.maxMessageSizeString != "" {
+		c.maxMessageSize, err = parseStringAsBytes(c.maxMessageSizeString)
+		if err != nil || c.maxMessageSize <= 0 {
+			return fmt.Errorf("invalid max message size %q", c.maxMessageSizeString)
+		}
+	}
+	if c.extractField != "" {
+		if c.raw || c.dump != "" || c.logFormat != "" || c.headersOnly || c.subjectsOnly || c.match || c.reportSubjects || c.prometheusListen != "" || c.interactiveAck {
+			return fmt.Errorf("extract-field is not compatible with raw, dump, log-format, headers-only, subjects-only, match-replies, report-subjects, prometheus or interactive-ack")
+		}
+
+		c.extract, err = newSubFieldExtractor(c.extractField)
+		if err != nil {
+			return err
+		}
+	}
+	if c.exitOnErrorField != "" {
+		if c.match || c.measureTTFM || c.interactiveAck {
+			return fmt.Errorf("exit-on-error-field is not compatible with match-replies, measure-ttfm or interactive-ack")
+		}
+
+		c.errorMatcher, err = newSubMessageMatcher(c.exitOnErrorField)
+		if err != nil {
+			return err
+		}
+	}
+	if c.measureTTFM {
+		switch {
+		case c.limit > 1:
+			return fmt.Errorf("measure-ttfm exits after the first message and can not be used with count")
+		case c.match || c.reportSubjects || c.prometheusListen != "" || c.interactiveAck:
+			return fmt.Errorf("measure-ttfm is not compatible with match-replies, report-subjects, prometheus or interactive-ack")
+		}
+		c.limit = 1
+	}
+	if c.holStats && (c.jetStream || c.interactiveAck) {
+		return fmt.Errorf("hol-stats is not compatible with JetStream subscriptions or interactive-ack")
+	}
+	if !c.sizeHistogram && (len(c.sizeBuckets) > 0 || c.sizeStored || c.reportInterval > 0) {
+		return fmt.Errorf("size-buckets, size-stored and report-interval require size-histogram")
+	}
+	if c.interactiveAck {
+		switch {
+		case !c.jetStream:
+			return fmt.Errorf("interactive-ack requires a JetStream subscription")
+		case c.jsAck || c.reportSubjects || c.match || c.dump != "" || c.prometheusListen != "" || c.verifyOrder != "":
+			return fmt.Errorf("interactive-ack is not compatible with ack, report-subjects, match-replies, dump, prometheus or verify-order")
+		case !iu.IsTerminal():
+			return fmt.Errorf("interactive-ack requires a terminal")
+		}
+	}
 
 	if c.dump != "" && c.dump != "-" {
 		err = os.MkdirAll(c.dump, 0700)
@@ -227,21 +454,111 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		subs           []*nats.Subscription
 		mu             = sync.Mutex{}
 		subjMu         = sync.Mutex{}
-		dump           = c.dump != ""
 		ctr            = uint(0)
+		uncounted      = uint(0)
 		ignoreSubjects = splitCLISubjects(c.ignoreSubjects)
-		ctx, cancel    = context.WithCancel(ctx)
+		ctx, cancel    = signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+		dedup          *subDeduplicator
+		metrics        *subMetrics
+		order          *subOrderTracker
+		streamOrder    *subStreamOrderTracker
+		sequences      *subSequenceChecker
+		sizes          *sizeHistogram
+		gaps           *gapStats
+		hol            *subHOLStats
+		subjectCounts  *subjectCounter
+		forwarder      *subHTTPForwarder
+
+		// messages skipped for being larger than max-message-size and the largest of those
+		oversized    uint64
+		oversizedMax int
+
+		// messages deliberately left unacknowledged and redelivered messages seen with skip-ack-every
+		firstDeliveries uint64
+		provoked        uint64
+		redelivered     uint64
 
 		replySub *nats.Subscription
 		matchMap map[string]*nats.Msg
 
+		// the first message matching exit-on-error-field and its number
+		errorMsg *nats.Msg
+		errorCtr uint
+
 		subjectReportMap      map[string]int64
 		subjectBytesReportMap map[string]int64
 
 		startTime = time.Now()
+
+		// when the subscription was completed and the first message arrived with measure-ttfm
+		subscribedTime   time.Time
+		firstMessageTime time.Time
 	)
 	defer cancel()
 
+	if c.dedupHeader != "" || c.dedupPayload {
+		dedup = newSubDeduplicator(subDedupCacheSize, c.dedupWindow)
+	}
+
+	if c.verifyOrder != "" {
+		order, err = newSubOrderTracker(c.verifyOrder)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.validateOrdering {
+		streamOrder = newSubStreamOrderTracker()
+	}
+
+	if c.sequenceCheck {
+		sequences = newSubSequenceChecker()
+	}
+
+	if c.sizeHistogram {
+		bounds, err := parseSizeHistogramBuckets(c.sizeBuckets)
+		if err != nil {
+			return err
+		}
+
+		sizes, err = newSizeHistogram(bounds)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.gapStats {
+		gaps = newGapStats()
+	}
+
+	if c.holStats {
+		hol = newSubHOLStats()
+	}
+
+	if c.countPerSubject {
+		subjectCounts = newSubjectCounter()
+	}
+
+	if c.httpForward != "" {
+		forwarder, err = newSubHTTPForwarder(c.httpForward, c.httpRetries, opts().Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.prometheusListen != "" {
+		metrics = newSubMetrics(c.prometheusTokens)
+		err = metrics.start(c.prometheusListen)
+		if err != nil {
+			return err
+		}
+		defer metrics.stop()
+
+		if !opts().Quiet {
+			log.Printf("Serving Prometheus metrics on http://%s/metrics", metrics.addr())
+		}
+	}
+
 	// If the wait timeout is set, then we will cancel after the timer fires.
 	var t *time.Timer
 	if c.wait > 0 {
@@ -255,16 +572,40 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		mu.Lock()
 		defer mu.Unlock()
 
+		// messages queued before unsubscribing are left unhandled and unacknowledged once an error was seen
+		if errorMsg != nil {
+			return
+		}
+
 		var info *jsm.MsgInfo
 		if m.Reply != "" {
 			info, _ = jsm.ParseJSMsgMetadata(m)
 		}
 
 		if c.jsAck && info != nil {
+			skip := false
+			if c.skipAckEvery > 0 {
+				if info.Delivered() > 1 {
+					redelivered++
+				} else {
+					firstDeliveries++
+					skip = firstDeliveries%c.skipAckEvery == 0
+				}
+			}
+
 			defer func() {
-				err = m.Respond(nil)
-				if err != nil && !dump && !c.raw {
-					log.Printf("Acknowledging message via subject %s failed: %s\n", m.Reply, err)
+				switch {
+				case skip && c.skipMode == "nak":
+					err = m.Respond(api.AckNak)
+					provoked++
+				case skip:
+					provoked++
+					return
+				default:
+					err = m.Respond(nil)
+				}
+				if err != nil {
+					logErrorf("Acknowledging message via subject %s failed: %s", m.Reply, err)
 				}
 			}()
 		}
@@ -273,10 +614,12 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		if c.jetStream && len(m.Data) == 0 && m.Header.Get("Status") == "100" {
 			if m.Reply != "" {
 				m.Respond(nil)
-				log.Printf("Responding to Flow Control message")
+				logDebugf("Responding to Flow Control message")
 			} else if stalled := m.Header.Get("Nats-Consumer-Stalled"); stalled != "" {
 				nc.Publish(stalled, nil)
-				log.Printf("Resuming stalled consumer")
+				if !opts().Quiet {
+					log.Printf("Resuming stalled consumer")
+				}
 			}
 			return
 		}
@@ -287,24 +630,100 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			}
 		}
 
+		if dedup != nil && dedup.isDuplicate(c.dedupKey(m), time.Now()) {
+			return
+		}
+
+		if c.maxMessageSize > 0 && int64(len(m.Data)) > c.maxMessageSize {
+			oversized++
+			oversizedMax = max(oversizedMax, len(m.Data))
+			return
+		}
+
+		// status messages like 503 No Responders are shown but not counted or observed unless asked for
+		status, _ := msgStatus(m)
+		counted := status == "" || c.countStatus
+
 		ctr++
-		if c.reportSubjects {
-			subjMu.Lock()
-			subjectReportMap[m.Subject]++
-			subjectBytesReportMap[m.Subject] += int64(len(m.Data))
-			subjMu.Unlock()
+		if !counted {
+			uncounted++
 		}
 
-		// if we're not reporting on subjects, then print the message
-		if !c.reportSubjects {
+		if counted {
+			if c.measureTTFM && ctr-uncounted == 1 {
+				firstMessageTime = time.Now()
+			}
+			if sizes != nil {
+				sizes.observe(c.msgSize(m, info))
+			}
+			if gaps != nil {
+				gaps.observe(time.Now())
+			}
+			if subjectCounts != nil {
+				subjectCounts.observe(m.Subject)
+			}
+			if c.reportSubjects {
+				subjMu.Lock()
+				subjectReportMap[m.Subject]++
+				subjectBytesReportMap[m.Subject] += int64(len(m.Data))
+				subjMu.Unlock()
+			}
+
+			if metrics != nil {
+				metrics.observe(m, info, time.Now())
+			}
+
+			if order != nil {
+				for _, warning := range order.observe(m) {
+					logWarnf("Order: %s", warning)
+				}
+			}
+
+			if streamOrder != nil {
+				for _, warning := range streamOrder.observe(m.Subject, info) {
+					logWarnf("Order: %s", warning)
+				}
+			}
+
+			if sequences != nil {
+				for _, warning := range sequences.observe(m) {
+					logWarnf("Sequence: %s", warning)
+				}
+			}
+		}
+
+		// a failed post is logged and the subscriber keeps running
+		if forwarder != nil && status == "" {
+			err := forwarder.forward(ctx, m)
+			if err != nil {
+				logErrorf("Could not forward message on %s to %s: %s", m.Subject, c.httpForward, err)
+			}
+		}
+
+		isError := c.errorMatcher != nil && errorMsg == nil && status == "" && c.errorMatcher(m.Data)
+
+		// if we're not reporting on subjects or metrics, then print the message, errors are always shown
+		if !c.reportSubjects && metrics == nil {
 			if c.match && m.Reply != "" {
 				matchMap[m.Reply] = m
 			} else {
 				c.printMsg(m, nil, ctr, startTime)
 			}
+		} else if isError {
+			c.printMsg(m, nil, ctr, startTime)
 		}
 
-		if ctr == c.limit {
+		if isError {
+			errorMsg = m
+			errorCtr = ctr
+			for _, sub := range subs {
+				sub.Unsubscribe()
+			}
+			cancel()
+			return
+		}
+
+		if counted && ctr-uncounted == c.limit {
 			for _, sub := range subs {
 				sub.Unsubscribe()
 			}
@@ -336,7 +755,7 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		delete(matchMap, reply.Subject)
 
 		// if reached limit and matched all requests
-		if ctr == c.limit && len(matchMap) == 0 {
+		if ctr-uncounted == c.limit && len(matchMap) == 0 {
 			replySub.Unsubscribe()
 			cancel()
 		}
@@ -369,25 +788,52 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		ignoredSubjInfo = fmt.Sprintf("\nIgnored subjects: %s", f(ignoreSubjects))
 	}
 
-	if (!c.raw && c.dump == "") || c.inbox {
+	if (!c.raw && c.dump == "" && !opts().Quiet && c.logFormat == "") || c.inbox {
 		switch {
 		case c.jetStream:
 			// logs later depending on settings
 		case c.jsAck:
 			log.Printf("Subscribing on %s with acknowledgement of JetStream messages %s", c.firstSubject(), ignoredSubjInfo)
+		case multiServer:
+			log.Printf("Subscribing on %s using %d servers %s", strings.Join(c.subjects, ", "), len(conns), ignoredSubjInfo)
 		default:
 			log.Printf("Subscribing on %s %s", strings.Join(c.subjects, ", "), ignoredSubjInfo)
 		}
 	}
 
+	// subscribes on every connection, holding the lock so messages are only handled once their server is known
+	subscribeAll := func(subj string, queue string) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, conn := range conns {
+			var sub *nats.Subscription
+			var err error
+			if hol != nil {
+				sub, err = hol.queueSubscribe(ctx, conn, subj, queue, handler)
+			} else {
+				sub, err = conn.QueueSubscribe(subj, queue, handler)
+			}
+			if err != nil {
+				return err
+			}
+			subs = append(subs, sub)
+
+			if multiServer {
+				c.subServers[sub] = conn
+			}
+		}
+
+		return nil
+	}
+
 	switch {
 	case c.reportSubjects:
 		for _, subj := range c.subjects {
-			sub, err := nc.Subscribe(subj, handler)
+			err = subscribeAll(subj, "")
 			if err != nil {
 				return err
 			}
-			subs = append(subs, sub)
 		}
 
 		startSubjectReporting(ctx, &subjMu, subjectReportMap, subjectBytesReportMap, c.reportSubjectsCount)
@@ -399,10 +845,15 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			return err
 		}
 
-		opts := []nats.SubOpt{
-			nats.EnableFlowControl(),
-			nats.IdleHeartbeat(5 * time.Second),
-			nats.AckNone(),
+		var opts []nats.SubOpt
+		switch {
+		case c.interactiveAck:
+			// decisions are made per message so the consumer needs acknowledgement
+			opts = append(opts, nats.AckExplicit())
+		case c.skipAckEvery > 0:
+			opts = append(opts, nats.EnableFlowControl(), nats.IdleHeartbeat(5*time.Second), nats.AckExplicit(), nats.ManualAck())
+		default:
+			opts = append(opts, nats.EnableFlowControl(), nats.IdleHeartbeat(5*time.Second), nats.AckNone())
 		}
 
 		if c.headersOnly || c.subjectsOnly {
@@ -416,6 +867,9 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			if err == nil {
 				bindDurable = true
 				c.jsAck = con.Config.AckPolicy != nats.AckNonePolicy
+				if c.skipAckEvery > 0 && !c.jsAck {
+					return fmt.Errorf("skip-ack-every requires a consumer that acknowledges messages")
+				}
 				log.Printf("Subscribing to JetStream Stream %q using existing durable %q", c.stream, c.durable)
 			} else if errors.Is(err, nats.ErrConsumerNotFound) {
 				opts = append(opts, nats.Durable(c.durable))
@@ -467,14 +921,29 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 			opts = append(opts, nats.DeliverLastPerSubject())
 		}
 
+		if c.interactiveAck {
+			var sub *nats.Subscription
+			if bindDurable {
+				sub, err = js.PullSubscribe("", c.durable, nats.Bind(c.stream, c.durable))
+			} else {
+				sub, err = js.PullSubscribe(c.firstSubject(), "", opts...)
+			}
+			if err != nil {
+				return err
+			}
+
+			return c.interactiveAckMessages(ctx, nc, sub, startTime)
+		}
+
 		if bindDurable {
-			sub, err := js.Subscribe("", handler, nats.Bind(c.stream, c.durable))
+			// the handler acknowledges messages itself
+			sub, err := js.Subscribe("", handler, nats.Bind(c.stream, c.durable), nats.ManualAck())
 			if err != nil {
 				return err
 			}
 			subs = append(subs, sub)
 		} else {
-			c.jsAck = false
+			c.jsAck = c.skipAckEvery > 0
 			sub, err := js.Subscribe(c.firstSubject(), handler, opts...)
 			if err != nil {
 				return err
@@ -483,19 +952,17 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		}
 
 	case c.queue != "":
-		sub, err := nc.QueueSubscribe(c.firstSubject(), c.queue, handler)
+		err = subscribeAll(c.firstSubject(), c.queue)
 		if err != nil {
 			return err
 		}
-		subs = append(subs, sub)
 
 	default:
 		for _, subj := range c.subjects {
-			sub, err := nc.Subscribe(subj, handler)
+			err = subscribeAll(subj, "")
 			if err != nil {
 				return err
 			}
-			subs = append(subs, sub)
 		}
 
 	}
@@ -503,18 +970,439 @@ func (c *subCmd) subscribe(p *fisk.ParseContext) error {
 		return err
 	}
 
-	nc.Flush()
+	for _, conn := range conns {
+		conn.Flush()
 
-	err = nc.LastError()
-	if err != nil {
-		return err
+		err = conn.LastError()
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.measureTTFM {
+		mu.Lock()
+		subscribedTime = time.Now()
+		mu.Unlock()
+
+		ttfmTimer := time.AfterFunc(opts().Timeout, cancel)
+		defer ttfmTimer.Stop()
+	}
+
+	if sizes != nil && c.reportInterval > 0 {
+		go func() {
+			ticker := time.NewTicker(c.reportInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					// holding the lock keeps the report from being mixed with messages being shown
+					mu.Lock()
+					c.printSizeHistogram(sizes.report())
+					mu.Unlock()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
 	}
 
 	<-ctx.Done()
 
+	if c.measureTTFM {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if firstMessageTime.IsZero() {
+			return fmt.Errorf("no message received within %v", opts().Timeout)
+		}
+
+		// the first message can arrive before the server confirmed the subscription
+		ttfm := firstMessageTime.Sub(subscribedTime)
+		if ttfm < 0 {
+			ttfm = 0
+		}
+
+		log.Printf("Connected and subscribed in %v, received the first message %v later, %v in total", subscribedTime.Sub(subscribeStart).Round(time.Microsecond), ttfm.Round(time.Microsecond), firstMessageTime.Sub(subscribeStart).Round(time.Microsecond))
+
+		return nil
+	}
+
+	if sizes != nil {
+		mu.Lock()
+		c.printSizeHistogram(sizes.report())
+		mu.Unlock()
+	}
+
+	if gaps != nil {
+		mu.Lock()
+		c.printGapStats(gaps.report())
+		mu.Unlock()
+	}
+
+	if hol != nil {
+		mu.Lock()
+		c.printHOLStats(hol.report())
+		mu.Unlock()
+	}
+
+	if subjectCounts != nil {
+		mu.Lock()
+		c.printSubjectCounts(subjectCounts.report())
+		mu.Unlock()
+	}
+
+	if forwarder != nil {
+		mu.Lock()
+		log.Print(forwarder.summary())
+		mu.Unlock()
+	}
+
+	if c.maxMessageSize > 0 {
+		mu.Lock()
+		if oversized > 0 {
+			log.Printf("Skipped %s messages larger than %s, the largest was %s", f(oversized), fiBytes(uint64(c.maxMessageSize)), fiBytes(uint64(oversizedMax)))
+		} else {
+			log.Printf("Skipped 0 messages larger than %s", fiBytes(uint64(c.maxMessageSize)))
+		}
+		mu.Unlock()
+	}
+
+	if c.dedupReport {
+		mu.Lock()
+		log.Printf("Suppressed %s duplicate messages", f(dedup.suppressed))
+		mu.Unlock()
+	}
+
+	if c.skipAckEvery > 0 {
+		mu.Lock()
+		action := "not acknowledging"
+		if c.skipMode == "nak" {
+			action = "negatively acknowledging"
+		}
+		log.Printf("Provoked %s redeliveries by %s every %s messages, observed %s redelivered messages", f(provoked), action, f(c.skipAckEvery), f(redelivered))
+		mu.Unlock()
+	}
+
+	mu.Lock()
+	matched, matchedCtr := errorMsg, errorCtr
+	mu.Unlock()
+	if matched != nil {
+		return fmt.Errorf("message %s received on %s matched %s", f(matchedCtr), matched.Subject, c.exitOnErrorField)
+	}
+
+	if streamOrder != nil {
+		mu.Lock()
+		log.Print(streamOrder.summary())
+		violations := streamOrder.violations()
+		mu.Unlock()
+
+		if violations > 0 {
+			return fmt.Errorf("found %s stream ordering violations", f(violations))
+		}
+	}
+
+	if sequences != nil {
+		mu.Lock()
+		log.Print(sequences.summary())
+		problems := sequences.problems()
+		mu.Unlock()
+
+		if problems > 0 {
+			return fmt.Errorf("found %s sequence problems", f(problems))
+		}
+	}
+
+	if order != nil {
+		mu.Lock()
+		defer mu.Unlock()
+
+		log.Print(order.summary())
+
+		if c.failOnDisorder && order.disordered() > 0 {
+			return fmt.Errorf("found %s ordering problems", f(order.disordered()))
+		}
+	}
+
+	return nil
+}
+
+// interactiveAckMessages fetches messages one at a time, showing each and acting on the choice made for it before
+// fetching the next, quitting leaves the current message unacknowledged for later redelivery
+func (c *subCmd) interactiveAckMessages(ctx context.Context, nc *nats.Conn, sub *nats.Subscription, startTime time.Time) error {
+	defer func() {
+		err := sub.Unsubscribe()
+		if err != nil {
+			logErrorf("Could not unsubscribe: %v", err)
+		}
+		nc.Flush()
+	}()
+
+	if !c.raw {
+		log.Printf("Fetching messages one at a time, choose [a]ck, [n]ak, [t]erm, [s]kip or [q]uit after each")
+	}
+
+	for ctr := uint(1); c.limit == 0 || ctr <= c.limit; ctr++ {
+		msg, err := c.fetchOne(ctx, sub)
+		if err != nil {
+			return err
+		}
+		if msg == nil {
+			return nil
+		}
+
+		c.printMsg(msg, nil, ctr, startTime)
+
+		choice, err := c.askAckChoice()
+		if err != nil {
+			return err
+		}
+
+		switch choice {
+		case 'a':
+			err = msg.AckSync()
+		case 'n':
+			err = msg.Nak()
+		case 't':
+			err = msg.Term()
+		case 's':
+			// left unacknowledged, the server will redeliver it after the ack wait
+		case 'q':
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not respond to message: %w", err)
+		}
+	}
+
 	return nil
 }
 
+// fetchOne waits for the next message, nil when interrupted
+func (c *subCmd) fetchOne(ctx context.Context, sub *nats.Subscription) (*nats.Msg, error) {
+	for {
+		fetchCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		msgs, err := sub.Fetch(1, nats.Context(fetchCtx))
+		cancel()
+
+		switch {
+		case ctx.Err() != nil:
+			return nil, nil
+		case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, nats.ErrTimeout):
+			continue
+		case err != nil:
+			return nil, err
+		case len(msgs) > 0:
+			return msgs[0], nil
+		}
+	}
+}
+
+// askAckChoice reads a single key press, Ctrl-C and Ctrl-D quit
+func (c *subCmd) askAckChoice() (byte, error) {
+	fd := int(os.Stdin.Fd())
+
+	for {
+		fmt.Print("[a]ck, [n]ak, [t]erm, [s]kip or [q]uit? ")
+
+		state, err := terminal.MakeRaw(fd)
+		if err != nil {
+			return 0, err
+		}
+
+		key := make([]byte, 1)
+		_, err = os.Stdin.Read(key)
+		terminal.Restore(fd, state)
+		if err != nil {
+			return 0, err
+		}
+
+		choice := key[0]
+		if choice >= 'A' && choice <= 'Z' {
+			choice += 'a' - 'A'
+		}
+
+		switch choice {
+		case 3, 4:
+			choice = 'q'
+		}
+
+		if word, ok := subAckChoices[choice]; ok {
+			fmt.Println(word)
+			return choice, nil
+		}
+
+		fmt.Println()
+	}
+}
+
+var subAckChoices = map[byte]string{
+	'a': "ack",
+	'n': "nak",
+	't': "term",
+	's': "skip",
+	'q': "quit",
+}
+
+// dedupKey determines the identity of a message used for de-duplication, empty when it has none
+func (c *subCmd) dedupKey(m *nats.Msg) string {
+	if c.dedupPayload {
+		sum := sha256.Sum256(m.Data)
+		return hex.EncodeToString(sum[:])
+	}
+
+	return m.Header.Get(c.dedupHeader)
+}
+
+// writeRawBody writes the transformed body of msg to stdout without adding anything to it
+func (c *subCmd) writeRawBody(msg *nats.Msg) {
+	data, err := c.transform.apply(msg.Data, msg.Subject, "")
+	if err != nil {
+		logErrorf("Error while translating msg body: %s", err)
+		return
+	}
+
+	os.Stdout.Write(data)
+}
+
+// msgSize is the size a message is counted as in the size histogram, headers only consumers report the stored size
+// in a header
+func (c *subCmd) msgSize(m *nats.Msg, info *jsm.MsgInfo) int {
+	if !c.sizeStored || info == nil {
+		return m.Size()
+	}
+
+	if stored := m.Header.Get(nats.MsgSize); stored != "" {
+		size, err := strconv.Atoi(stored)
+		if err == nil {
+			return size
+		}
+	}
+
+	return len(m.Data)
+}
+
+// printSizeHistogram shows the size distribution as a table, or as a JSON line when logging in JSON format
+func (c *subCmd) printSizeHistogram(report *sizeHistogramReport) {
+	if c.logFormat == "json" {
+		j, err := json.Marshal(map[string]any{"ts": time.Now().UTC(), "sizes": report})
+		if err != nil {
+			logErrorf("Could not JSON encode the size histogram: %s", err)
+			return
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	fmt.Println(report.render("Message sizes"))
+}
+
+// printGapStats shows the gaps between messages, or a JSON line when logging in JSON format
+func (c *subCmd) printGapStats(report *gapStatsReport) {
+	if c.logFormat == "json" {
+		j, err := json.Marshal(map[string]any{"ts": time.Now().UTC(), "gaps": report})
+		if err != nil {
+			logErrorf("Could not JSON encode the gap statistics: %s", err)
+			return
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	fmt.Println(report.render())
+}
+
+// printHOLStats shows how long messages waited for the handler, or a JSON line when logging in JSON format
+func (c *subCmd) printHOLStats(report *subHOLStatsReport) {
+	if c.logFormat == "json" {
+		j, err := json.Marshal(map[string]any{"ts": time.Now().UTC(), "hol": report})
+		if err != nil {
+			logErrorf("Could not JSON encode the head-of-line statistics: %s", err)
+			return
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	fmt.Println(report.render())
+}
+
+// printSubjectCounts shows the messages received per subject, or a JSON line when logging in JSON format
+func (c *subCmd) printSubjectCounts(report *subjectCounterReport) {
+	if c.logFormat == "json" {
+		j, err := json.Marshal(map[string]any{"ts": time.Now().UTC(), "subjects": report})
+		if err != nil {
+			logErrorf("Could not JSON encode the subject counts: %s", err)
+			return
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	fmt.Println(report.render())
+}
+
+// subDeduplicator tracks recently seen message identities in a size bound LRU cache
+type subDeduplicator struct {
+	size       int
+	window     time.Duration
+	seen       map[string]*list.Element
+	order      *list.List
+	suppressed uint64
+}
+
+type subDedupEntry struct {
+	key  string
+	seen time.Time
+}
+
+func newSubDeduplicator(size int, window time.Duration) *subDeduplicator {
+	return &subDeduplicator{
+		size:   size,
+		window: window,
+		seen:   make(map[string]*list.Element),
+		order:  list.New(),
+	}
+}
+
+// isDuplicate records key as seen at now unless already seen and reports if it was, empty keys are never duplicates
+func (d *subDeduplicator) isDuplicate(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+
+	if d.window > 0 {
+		for e := d.order.Back(); e != nil; e = d.order.Back() {
+			entry := e.Value.(*subDedupEntry)
+			if now.Sub(entry.seen) <= d.window {
+				break
+			}
+			d.order.Remove(e)
+			delete(d.seen, entry.key)
+		}
+	}
+
+	// with a window the first sighting is kept, keeping the list in the order keys were first seen, so a message
+	// repeated more often than the window is still shown once per window
+	if e, ok := d.seen[key]; ok {
+		if d.window == 0 {
+			d.order.MoveToFront(e)
+		}
+		d.suppressed++
+		return true
+	}
+
+	d.seen[key] = d.order.PushFront(&subDedupEntry{key: key, seen: now})
+
+	if d.order.Len() > d.size {
+		e := d.order.Back()
+		d.order.Remove(e)
+		delete(d.seen, e.Value.(*subDedupEntry).key)
+	}
+
+	return false
+}
+
 func (c *subCmd) firstSubject() string {
 	if len(c.subjects) == 0 {
 		return ""
@@ -541,7 +1429,7 @@ func (c *subCmd) printMsg(msg *nats.Msg, reply *nats.Msg, ctr uint, startTime ti
 	}
 
 	if c.dump != "" {
-		// Output format 1/3: dumping, to stdout or files
+		// Output format 1/5: dumping, to stdout or files
 
 		var (
 			stdout      = c.dump == "-"
@@ -563,65 +1451,285 @@ func (c *subCmd) printMsg(msg *nats.Msg, reply *nats.Msg, ctr uint, startTime ti
 			dumpMsg(reply, stdout, replyFile, ctr)
 		}
 
+	} else if c.logFormat != "" {
+		// Output format 2/5: structured log lines
+		fmt.Println(c.logLine(c.newLogRecord(msg, info, ctr, time.Now())))
+
+	} else if c.extract != nil {
+		// Output format 3/5: only a field extracted from the payload, status messages are described on stderr
+		if line := statusMsgLine(msg, msg.Subject); line != "" {
+			log.Print(line)
+			return
+		}
+
+		data, err := c.transform.apply(msg.Data, msg.Subject, "")
+		if err != nil {
+			logErrorf("%v", err)
+			return
+		}
+
+		fmt.Println(c.extract(data))
+
+	} else if c.raw && opts().Quiet {
+		// Output format 4/5: raw, exactly as received without any separators so scripts get the payload as is
+		c.writeRawBody(msg)
+		if reply != nil {
+			c.writeRawBody(reply)
+		}
+
 	} else if c.raw {
-		// Output format 2/3: raw
-		outPutMSGBodyCompact(msg.Data, c.translate, "", "")
+		// Output format 4/5: raw, status messages have no body so they are described on stderr
+		if line := statusMsgLine(msg, msg.Subject); line != "" {
+			log.Print(line)
+		} else {
+			outPutMSGBodyCompact(msg.Data, c.transform, "", "")
+		}
 		if reply != nil {
-			fmt.Println(string(reply.Data))
+			if line := statusMsgLine(reply, msg.Subject); line != "" {
+				log.Print(line)
+			} else {
+				fmt.Println(string(reply.Data))
+			}
 		}
 
 	} else {
-		// Output format 3/3: pretty
+		// Output format 5/5: pretty
+
+		tag := c.headerTag(msg)
+
+		var from string
+		if server := c.msgServer(msg); server != "" {
+			from = fmt.Sprintf(" from %s", server)
+		}
+
+		if line := statusMsgLine(msg, msg.Subject); line != "" {
+			fmt.Printf("%s[#%d]%s %s%s\n", tag, ctr, timeStamp, line, from)
+			fmt.Println()
+			return
+		}
 
 		if info == nil {
 			if msg.Reply != "" {
-				fmt.Printf("[#%d]%s Received on %q with reply %q\n", ctr, timeStamp, msg.Subject, msg.Reply)
+				fmt.Printf("%s[#%d]%s Received on %q with reply %q%s\n", tag, ctr, timeStamp, msg.Subject, msg.Reply, from)
 			} else {
-				fmt.Printf("[#%d]%s Received on %q\n", ctr, timeStamp, msg.Subject)
+				fmt.Printf("%s[#%d]%s Received on %q%s\n", tag, ctr, timeStamp, msg.Subject, from)
 			}
 		} else if c.jetStream {
-			fmt.Printf("[#%d] Received JetStream message: stream: %s seq %d / subject: %s / time: %v\n", ctr, info.Stream(), info.StreamSequence(), msg.Subject, info.TimeStamp().Format(time.RFC3339))
+			fmt.Printf("%s[#%d] Received JetStream message: stream: %s seq %d / subject: %s / time: %v%s\n", tag, ctr, info.Stream(), info.StreamSequence(), msg.Subject, info.TimeStamp().Format(time.RFC3339), c.redeliveryTag(info))
 		} else {
-			fmt.Printf("[#%d] Received JetStream message: consumer: %s > %s / subject: %s / delivered: %d / consumer seq: %d / stream seq: %d\n", ctr, info.Stream(), info.Consumer(), msg.Subject, info.Delivered(), info.ConsumerSequence(), info.StreamSequence())
+			fmt.Printf("%s[#%d] Received JetStream message: consumer: %s > %s / subject: %s / delivered: %d / consumer seq: %d / stream seq: %d%s%s\n", tag, ctr, info.Stream(), info.Consumer(), msg.Subject, info.Delivered(), info.ConsumerSequence(), info.StreamSequence(), c.redeliveryTag(info), from)
 		}
 
 		if c.subjectsOnly {
 			return
 		}
 
-		prettyPrintMsg(msg, c.headersOnly, c.translate)
+		prettyPrintMsg(msg, c.headersOnly, c.transform)
 
 		if reply != nil {
 			if info == nil {
-				fmt.Printf("[#%d]%s Matched reply on %q\n", ctr, timeStamp, reply.Subject)
+				fmt.Printf("%s[#%d]%s Matched reply on %q\n", tag, ctr, timeStamp, reply.Subject)
 			} else if c.jetStream {
-				fmt.Printf("[#%d] Matched reply JetStream message: stream: %s seq %d / subject: %s / time: %v\n", ctr, info.Stream(), info.StreamSequence(), reply.Subject, info.TimeStamp().Format(time.RFC3339))
+				fmt.Printf("%s[#%d] Matched reply JetStream message: stream: %s seq %d / subject: %s / time: %v\n", tag, ctr, info.Stream(), info.StreamSequence(), reply.Subject, info.TimeStamp().Format(time.RFC3339))
 			} else {
-				fmt.Printf("[#%d] Matched reply JetStream message: consumer: %s > %s / subject: %s / delivered: %d / consumer seq: %d / stream seq: %d\n", ctr, info.Stream(), info.Consumer(), reply.Subject, info.Delivered(), info.ConsumerSequence(), info.StreamSequence())
+				fmt.Printf("%s[#%d] Matched reply JetStream message: consumer: %s > %s / subject: %s / delivered: %d / consumer seq: %d / stream seq: %d\n", tag, ctr, info.Stream(), info.Consumer(), reply.Subject, info.Delivered(), info.ConsumerSequence(), info.StreamSequence())
 			}
 
-			prettyPrintMsg(reply, c.headersOnly, c.translate)
+			if line := statusMsgLine(reply, msg.Subject); line != "" {
+				fmt.Println(line)
+				fmt.Println()
+			} else {
+				prettyPrintMsg(reply, c.headersOnly, c.transform)
+			}
 
 		}
 	} // output format type dispatch
 }
 
-func dumpMsg(msg *nats.Msg, stdout bool, filepath string, ctr uint) {
-	// dont want sub etc
-	serMsg := nats.NewMsg(msg.Subject)
-	serMsg.Header = msg.Header
-	serMsg.Data = msg.Data
-	serMsg.Reply = msg.Reply
+// msgServer is the server a message arrived from when subscribing on multiple servers
+func (c *subCmd) msgServer(msg *nats.Msg) string {
+	if c.subServers == nil || msg.Sub == nil {
+		return ""
+	}
+
+	conn, ok := c.subServers[msg.Sub]
+	if !ok {
+		return ""
+	}
+
+	return conn.ConnectedUrlRedacted()
+}
+
+// headerTag is the value of the tag header prepended to output lines, [-] when the message does not have it
+func (c *subCmd) headerTag(msg *nats.Msg) string {
+	if c.tagHeader == "" {
+		return ""
+	}
+
+	val := msg.Header.Get(c.tagHeader)
+	if val == "" {
+		val = "-"
+	}
+
+	return fmt.Sprintf("[%s] ", val)
+}
+
+// redeliveryTag marks redelivered messages while provoking redeliveries
+func (c *subCmd) redeliveryTag(info *jsm.MsgInfo) string {
+	if c.skipAckEvery == 0 || info.Delivered() < 2 {
+		return ""
+	}
+
+	return fmt.Sprintf(" [REDELIVERY %d]", info.Delivered())
+}
+
+// subLogRecord is a message shown as a structured log line by --log-format
+type subLogRecord struct {
+	Time             time.Time   `json:"ts"`
+	Subject          string      `json:"subject"`
+	Reply            string      `json:"reply,omitempty"`
+	Server           string      `json:"server,omitempty"`
+	Stream           string      `json:"stream,omitempty"`
+	Consumer         string      `json:"consumer,omitempty"`
+	Sequence         uint64      `json:"seq"`
+	ConsumerSequence uint64      `json:"consumer_seq,omitempty"`
+	Delivered        int         `json:"delivered,omitempty"`
+	Bytes            int         `json:"bytes"`
+	Header           nats.Header `json:"headers,omitempty"`
+	Data             string      `json:"data,omitempty"`
+}
+
+// newLogRecord creates the log record for a message, JetStream messages are logged with the time they were stored and
+// their stream sequence while other messages use the time they were received and their position in the output
+func (c *subCmd) newLogRecord(msg *nats.Msg, info *jsm.MsgInfo, ctr uint, received time.Time) *subLogRecord {
+	rec := &subLogRecord{
+		Time:     received.UTC(),
+		Subject:  msg.Subject,
+		Server:   c.msgServer(msg),
+		Sequence: uint64(ctr),
+		Bytes:    len(msg.Data),
+	}
+
+	if info != nil {
+		rec.Time = info.TimeStamp().UTC()
+		rec.Stream = info.Stream()
+		rec.Consumer = info.Consumer()
+		rec.Sequence = info.StreamSequence()
+		rec.ConsumerSequence = info.ConsumerSequence()
+		rec.Delivered = info.Delivered()
+	} else {
+		rec.Reply = msg.Reply
+	}
+
+	if c.subjectsOnly {
+		return rec
+	}
+
+	if len(msg.Header) > 0 {
+		rec.Header = msg.Header
+	}
+
+	if !c.headersOnly {
+		data, err := c.transform.apply(msg.Data, msg.Subject, rec.Stream)
+		if err != nil {
+			logErrorf("Error while translating msg body: %s", err)
+			data = msg.Data
+		}
+		rec.Data = strings.TrimSuffix(string(data), "\n")
+	}
+
+	return rec
+}
+
+func (c *subCmd) logLine(rec *subLogRecord) string {
+	if c.logFormat == "json" {
+		j, err := json.Marshal(rec)
+		if err != nil {
+			logErrorf("Could not JSON encode message: %s", err)
+			return ""
+		}
+		return string(j)
+	}
+
+	return rec.logfmt()
+}
+
+// logfmt renders the record as space separated key=value pairs, quoting values where needed
+func (r *subLogRecord) logfmt() string {
+	var b strings.Builder
+
+	add := func(key string, val string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(val))
+	}
+
+	add("ts", r.Time.Format(time.RFC3339Nano))
+	add("subject", r.Subject)
+	if r.Reply != "" {
+		add("reply", r.Reply)
+	}
+	if r.Server != "" {
+		add("server", r.Server)
+	}
+	if r.Stream != "" {
+		add("stream", r.Stream)
+	}
+	if r.Consumer != "" {
+		add("consumer", r.Consumer)
+	}
+	add("seq", strconv.FormatUint(r.Sequence, 10))
+	if r.ConsumerSequence > 0 {
+		add("consumer_seq", strconv.FormatUint(r.ConsumerSequence, 10))
+	}
+	if r.Delivered > 0 {
+		add("delivered", strconv.Itoa(r.Delivered))
+	}
+	add("bytes", strconv.Itoa(r.Bytes))
+
+	hdrs := make([]string, 0, len(r.Header))
+	for h := range r.Header {
+		hdrs = append(hdrs, h)
+	}
+	sort.Strings(hdrs)
+	for _, h := range hdrs {
+		add("header."+h, strings.Join(r.Header.Values(h), ","))
+	}
+
+	if r.Data != "" {
+		add("data", r.Data)
+	}
+
+	return b.String()
+}
 
-	jm, err := json.Marshal(serMsg)
+func logfmtValue(val string) string {
+	if val == "" {
+		return `""`
+	}
+
+	for _, r := range val {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || !strconv.IsPrint(r) {
+			return strconv.Quote(val)
+		}
+	}
+
+	return val
+}
+
+func dumpMsg(msg *nats.Msg, stdout bool, filepath string, ctr uint) {
+	jm, err := json.Marshal(newCapturedMsg(msg, time.Now()))
 	if err != nil {
-		log.Printf("Could not JSON encode message: %s", err)
+		logErrorf("Could not JSON encode message: %s", err)
 	} else if stdout {
 		os.Stdout.WriteString(fmt.Sprintf("%s\000", jm))
 	} else {
 		err = os.WriteFile(filepath, jm, 0600)
 		if err != nil {
-			log.Printf("Could not save message: %s", err)
+			logErrorf("Could not save message: %s", err)
 		}
 
 		if ctr%100 == 0 {
@@ -630,7 +1738,7 @@ func dumpMsg(msg *nats.Msg, stdout bool, filepath string, ctr uint) {
 	}
 }
 
-func prettyPrintMsg(msg *nats.Msg, headersOnly bool, filter string) {
+func prettyPrintMsg(msg *nats.Msg, headersOnly bool, transform *payloadTransformPipeline) {
 	if len(msg.Header) > 0 {
 		for h, vals := range msg.Header {
 			for _, val := range vals {
@@ -642,6 +1750,113 @@ func prettyPrintMsg(msg *nats.Msg, headersOnly bool, filter string) {
 	}
 
 	if !headersOnly {
-		outPutMSGBody(msg.Data, filter, msg.Subject, "")
+		outPutMSGBody(msg.Data, transform, msg.Subject, "")
+	}
+}
+
+// subMetrics holds Prometheus metrics about received messages in a registry private to the sub command
+type subMetrics struct {
+	tokens      int
+	registry    *prometheus.Registry
+	messages    *prometheus.CounterVec
+	bytes       *prometheus.CounterVec
+	redelivered *prometheus.CounterVec
+	last        atomic.Int64
+	listener    net.Listener
+	server      *http.Server
+}
+
+func newSubMetrics(tokens int) *subMetrics {
+	m := &subMetrics{
+		tokens:   tokens,
+		registry: prometheus.NewRegistry(),
+		messages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nats_sub_messages_total",
+			Help: "Number of messages received",
+		}, []string{"subject"}),
+		bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nats_sub_bytes_total",
+			Help: "Number of payload bytes received",
+		}, []string{"subject"}),
+		redelivered: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "nats_sub_jetstream_redeliveries_total",
+			Help: "Number of JetStream messages received that were delivered more than once",
+		}, []string{"subject"}),
+	}
+
+	age := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "nats_sub_last_message_age_seconds",
+		Help: "Seconds since the last message was received, 0 when no messages were received",
+	}, func() float64 {
+		last := m.last.Load()
+		if last == 0 {
+			return 0
+		}
+		return time.Since(time.Unix(0, last)).Seconds()
+	})
+
+	m.registry.MustRegister(m.messages, m.bytes, m.redelivered, age)
+
+	return m
+}
+
+// subjectLabel reduces subject to its first tokens to keep label cardinality bounded
+func (m *subMetrics) subjectLabel(subject string) string {
+	if m.tokens <= 0 {
+		return subject
+	}
+
+	parts := strings.SplitN(subject, ".", m.tokens+1)
+	if len(parts) <= m.tokens {
+		return subject
+	}
+
+	return strings.Join(parts[:m.tokens], ".")
+}
+
+func (m *subMetrics) observe(msg *nats.Msg, info *jsm.MsgInfo, now time.Time) {
+	label := m.subjectLabel(msg.Subject)
+
+	m.messages.WithLabelValues(label).Inc()
+	m.bytes.WithLabelValues(label).Add(float64(len(msg.Data)))
+	if info != nil && info.Delivered() > 1 {
+		m.redelivered.WithLabelValues(label).Inc()
 	}
+
+	m.last.Store(now.UnixNano())
+}
+
+// start listens on listen and serves the metrics in the background
+func (m *subMetrics) start(listen string) error {
+	var err error
+
+	m.listener, err = net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("could not listen for prometheus requests: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	m.server = &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		err := m.server.Serve(m.listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Prometheus listener failed: %v", err)
+		}
+	}()
+
+	return nil
+}
+
+func (m *subMetrics) addr() string {
+	return m.listener.Addr().String()
+}
+
+// stop shuts down the HTTP server allowing in flight scrapes to complete
+func (m *subMetrics) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	m.server.Shutdown(ctx)
 }
@@ -0,0 +1,370 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/options"
+)
+
+func TestSubDeduplicator(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		d := newSubDeduplicator(2, 0)
+		now := time.Now()
+
+		if d.isDuplicate("a", now) || d.isDuplicate("b", now) {
+			t.Fatalf("new keys reported as duplicate")
+		}
+		if !d.isDuplicate("a", now) {
+			t.Fatalf("expected a to be a duplicate")
+		}
+
+		// b is the least recently used and should be evicted
+		d.isDuplicate("c", now)
+		if d.isDuplicate("b", now) {
+			t.Fatalf("expected b to have been evicted")
+		}
+		if d.suppressed != 1 {
+			t.Fatalf("expected 1 suppressed got %d", d.suppressed)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		d := newSubDeduplicator(10, time.Minute)
+		now := time.Now()
+
+		d.isDuplicate("a", now)
+		if !d.isDuplicate("a", now.Add(30*time.Second)) {
+			t.Fatalf("expected a to be a duplicate within the window")
+		}
+		if d.isDuplicate("a", now.Add(2*time.Minute)) {
+			t.Fatalf("expected a to have expired from the window")
+		}
+	})
+
+	t.Run("window from first seen", func(t *testing.T) {
+		d := newSubDeduplicator(10, time.Minute)
+		now := time.Now()
+
+		d.isDuplicate("a", now)
+		for _, offset := range []time.Duration{20 * time.Second, 40 * time.Second, 60 * time.Second} {
+			if !d.isDuplicate("a", now.Add(offset)) {
+				t.Fatalf("expected a to be a duplicate after %v", offset)
+			}
+		}
+		if d.isDuplicate("a", now.Add(80*time.Second)) {
+			t.Fatalf("expected a repeated more often than the window to expire a window after it was first seen")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		d := newSubDeduplicator(10, 0)
+		if d.isDuplicate("", time.Now()) || d.isDuplicate("", time.Now()) {
+			t.Fatalf("empty keys should never be duplicates")
+		}
+	})
+}
+
+func TestSubMetrics(t *testing.T) {
+	t.Run("labels", func(t *testing.T) {
+		m := newSubMetrics(2)
+		for subj, expect := range map[string]string{"a": "a", "a.b": "a.b", "a.b.c.d": "a.b"} {
+			if label := m.subjectLabel(subj); label != expect {
+				t.Fatalf("expected %q for %q got %q", expect, subj, label)
+			}
+		}
+
+		m = newSubMetrics(0)
+		if label := m.subjectLabel("a.b.c"); label != "a.b.c" {
+			t.Fatalf("expected the full subject got %q", label)
+		}
+	})
+
+	t.Run("serve", func(t *testing.T) {
+		m := newSubMetrics(1)
+		err := m.start("127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("start failed: %v", err)
+		}
+		defer m.stop()
+
+		m.observe(&nats.Msg{Subject: "metrics.one", Data: []byte("hello")}, nil, time.Now())
+		m.observe(&nats.Msg{Subject: "metrics.two", Data: []byte("world")}, nil, time.Now())
+
+		resp, err := http.Get(fmt.Sprintf("http://%s/metrics", m.addr()))
+		if err != nil {
+			t.Fatalf("scrape failed: %v", err)
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+
+		for _, expect := range []string{`nats_sub_messages_total{subject="metrics"} 2`, `nats_sub_bytes_total{subject="metrics"} 10`, "nats_sub_last_message_age_seconds"} {
+			if !strings.Contains(string(body), expect) {
+				t.Fatalf("missing %q in metrics: %s", expect, body)
+			}
+		}
+	})
+}
+
+func TestSubLogRecord(t *testing.T) {
+	received := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	msg := nats.NewMsg("orders.new")
+	msg.Data = []byte("hello world")
+	msg.Header.Add("Order-Id", "1")
+
+	t.Run("logfmt", func(t *testing.T) {
+		c := &subCmd{logFormat: "logfmt"}
+
+		line := c.logLine(c.newLogRecord(msg, nil, 1, received))
+		expected := `ts=2024-01-01T00:00:00Z subject=orders.new seq=1 bytes=11 header.Order-Id=1 data="hello world"`
+		if line != expected {
+			t.Fatalf("expected %q got %q", expected, line)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		c := &subCmd{logFormat: "json", headersOnly: true}
+
+		line := c.logLine(c.newLogRecord(msg, nil, 2, received))
+		expected := `{"ts":"2024-01-01T00:00:00Z","subject":"orders.new","seq":2,"bytes":11,"headers":{"Order-Id":["1"]}}`
+		if line != expected {
+			t.Fatalf("expected %q got %q", expected, line)
+		}
+	})
+
+	t.Run("quoting", func(t *testing.T) {
+		for val, expected := range map[string]string{"": `""`, "a.b": "a.b", "a=b": `"a=b"`, "a\nb": `"a\nb"`, `a"b`: `"a\"b"`} {
+			if actual := logfmtValue(val); actual != expected {
+				t.Fatalf("expected %q for %q got %q", expected, val, actual)
+			}
+		}
+	})
+}
+
+func TestSubHeaderTag(t *testing.T) {
+	msg := nats.NewMsg("events.order")
+	msg.Header.Set("X-Source", "serviceA")
+
+	if tag := (&subCmd{}).headerTag(msg); tag != "" {
+		t.Fatalf("expected no tag without a tag header got %q", tag)
+	}
+
+	c := &subCmd{tagHeader: "X-Source"}
+	if tag := c.headerTag(msg); tag != "[serviceA] " {
+		t.Fatalf("expected the header value as tag got %q", tag)
+	}
+	if tag := c.headerTag(nats.NewMsg("events.order")); tag != "[-] " {
+		t.Fatalf("expected [-] when the header is absent got %q", tag)
+	}
+}
+
+func TestSubFieldExtractor(t *testing.T) {
+	extract, err := newSubFieldExtractor(".customer.email")
+	if err != nil {
+		t.Fatalf("could not compile path: %v", err)
+	}
+
+	for data, expected := range map[string]string{
+		`{"customer":{"email":"a@example.net"}}`: "a@example.net",
+		`{"customer":{"email":["a","b"]}}`:       `["a","b"]`,
+		`{"customer":{"name":"a"}}`:              "<nil>",
+		`{"customer":"a"}`:                       "<nil>",
+		`not json`:                               "<nil>",
+	} {
+		if actual := extract([]byte(data)); actual != expected {
+			t.Fatalf("expected %q for %s got %q", expected, data, actual)
+		}
+	}
+
+	extract, err = newSubFieldExtractor(".items[].id")
+	if err != nil {
+		t.Fatalf("could not compile path: %v", err)
+	}
+	if actual := extract([]byte(`{"items":[{"id":1},{"id":2}]}`)); actual != "1\n2" {
+		t.Fatalf("expected a line per value got %q", actual)
+	}
+
+	_, err = newSubFieldExtractor(".customer[")
+	if err == nil {
+		t.Fatalf("expected an invalid path to fail")
+	}
+}
+
+func TestSubMessageMatcher(t *testing.T) {
+	matcher, err := newSubMessageMatcher(`.status == "error"`)
+	if err != nil {
+		t.Fatalf("could not compile expression: %v", err)
+	}
+
+	for data, expected := range map[string]bool{
+		`{"status":"error"}`: true,
+		`{"status":"ok"}`:    false,
+		`{}`:                 false,
+		`not json`:           false,
+	} {
+		if actual := matcher([]byte(data)); actual != expected {
+			t.Fatalf("expected %v for %s got %v", expected, data, actual)
+		}
+	}
+
+	// values other than false and null match like jq select does
+	matcher, err = newSubMessageMatcher(".error")
+	if err != nil {
+		t.Fatalf("could not compile expression: %v", err)
+	}
+	for data, expected := range map[string]bool{
+		`{"error":"disk full"}`: true,
+		`{"error":null}`:        false,
+		`{"error":false}`:       false,
+		`{"status":"ok"}`:       false,
+	} {
+		if actual := matcher([]byte(data)); actual != expected {
+			t.Fatalf("expected %v for %s got %v", expected, data, actual)
+		}
+	}
+
+	_, err = newSubMessageMatcher(".status ==")
+	if err == nil {
+		t.Fatalf("expected an invalid expression to fail")
+	}
+}
+
+func TestSubMultiServer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	srv, err := server.NewServer(&server.Options{Port: -1})
+	checkErr(t, err, "could not start server: %v", err)
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatalf("nats server did not start")
+	}
+	defer srv.Shutdown()
+
+	options.DefaultOptions = &options.Options{}
+	flag := &options.ServersFlag{Options: options.DefaultOptions}
+	alt := strings.Replace(srv.ClientURL(), "127.0.0.1", "localhost", 1)
+	for _, url := range []string{srv.ClientURL(), alt} {
+		err = flag.Set(url)
+		checkErr(t, err, "set failed: %v", err)
+	}
+	if opts().Servers != srv.ClientURL()+","+alt {
+		t.Fatalf("expected servers to be joined got %q", opts().Servers)
+	}
+
+	manager := newConnManager()
+	defer manager.shutdown(false, false)
+
+	var conns []*nats.Conn
+	for _, url := range opts().ServerURLs {
+		conn, err := manager.connect(url, url)
+		checkErr(t, err, "connect failed: %v", err)
+		conns = append(conns, conn)
+	}
+
+	c := &subCmd{logFormat: "json", subServers: map[*nats.Subscription]*nats.Conn{}}
+	msgs := make(chan *nats.Msg, 2)
+	for _, conn := range conns {
+		sub, err := conn.ChanSubscribe("test", msgs)
+		checkErr(t, err, "subscribe failed: %v", err)
+		c.subServers[sub] = conn
+
+		err = conn.Flush()
+		checkErr(t, err, "flush failed: %v", err)
+	}
+
+	err = conns[0].Publish("test", []byte("hello"))
+	checkErr(t, err, "publish failed: %v", err)
+
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-msgs:
+			rec := c.newLogRecord(msg, nil, uint(i+1), time.Now())
+			seen[rec.Server] = true
+		case <-time.After(time.Second):
+			t.Fatalf("expected a message from every server, got %v", seen)
+		}
+	}
+
+	if !seen[srv.ClientURL()] || !seen[alt] {
+		t.Fatalf("expected a message from every server, got %v", seen)
+	}
+
+	if c.msgServer(nats.NewMsg("test")) != "" {
+		t.Fatalf("expected no server for a message not received on a subscription")
+	}
+}
+
+func TestSubHOLStats(t *testing.T) {
+	srv, err := server.NewServer(&server.Options{Port: -1})
+	checkErr(t, err, "could not start server: %v", err)
+	go srv.Start()
+	if !srv.ReadyForConnections(10 * time.Second) {
+		t.Fatalf("nats server did not start")
+	}
+	defer srv.Shutdown()
+
+	nc, err := nats.Connect(srv.ClientURL())
+	checkErr(t, err, "connect failed: %v", err)
+	defer nc.Close()
+
+	hctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	handled := make(chan string, 5)
+	hol := newSubHOLStats()
+	_, err = hol.queueSubscribe(hctx, nc, "hol.test", "", func(m *nats.Msg) {
+		time.Sleep(20 * time.Millisecond)
+		handled <- string(m.Data)
+	})
+	checkErr(t, err, "subscribe failed: %v", err)
+
+	for i := 1; i <= 5; i++ {
+		checkErr(t, nc.Publish("hol.test", []byte(fmt.Sprintf("%d", i))), "publish failed")
+	}
+	checkErr(t, nc.Flush(), "flush failed")
+
+	for i := 1; i <= 5; i++ {
+		select {
+		case body := <-handled:
+			if body != fmt.Sprintf("%d", i) {
+				t.Fatalf("expected message %d got %s", i, body)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("message %d was not handled", i)
+		}
+	}
+
+	// the last message waited for the four handled before it
+	report := hol.report()
+	if report.Messages != 5 || report.Max < 70*time.Millisecond || report.P50 > report.Max {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if !strings.Contains(report.render(), "Handler delay of 5 messages") {
+		t.Fatalf("unexpected render: %s", report.render())
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/itchyny/gojq"
+)
+
+// subNilField is shown when a payload is not JSON or does not have the field being extracted
+const subNilField = "<nil>"
+
+// subFieldExtractor extracts a field from a JSON payload for display
+type subFieldExtractor func(data []byte) string
+
+// newSubFieldExtractor compiles a jq path, strings are extracted as is while other values are shown as JSON, a line
+// per value when the path produces many
+func newSubFieldExtractor(path string) (subFieldExtractor, error) {
+	query, err := gojq.Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field path %q: %w", path, err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field path %q: %w", path, err)
+	}
+
+	return func(data []byte) string {
+		var doc any
+		err := json.Unmarshal(data, &doc)
+		if err != nil {
+			return subNilField
+		}
+
+		var results []string
+		iter := code.Run(doc)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				break
+			}
+
+			switch r := v.(type) {
+			case error:
+				return subNilField
+			case nil:
+				results = append(results, subNilField)
+			case string:
+				results = append(results, r)
+			default:
+				j, err := json.Marshal(r)
+				if err != nil {
+					return subNilField
+				}
+				results = append(results, string(j))
+			}
+		}
+
+		if len(results) == 0 {
+			return subNilField
+		}
+
+		return strings.Join(results, "\n")
+	}, nil
+}
+
+// subMessageMatcher reports if a JSON payload matches a jq expression
+type subMessageMatcher func(data []byte) bool
+
+// newSubMessageMatcher compiles a jq expression that matches payloads when any value it produces is not false or
+// null, payloads that are not JSON never match
+func newSubMessageMatcher(expr string) (subMessageMatcher, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+
+	code, err := gojq.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+
+	return func(data []byte) bool {
+		var doc any
+		err := json.Unmarshal(data, &doc)
+		if err != nil {
+			return false
+		}
+
+		iter := code.Run(doc)
+		for {
+			v, ok := iter.Next()
+			if !ok {
+				return false
+			}
+
+			switch r := v.(type) {
+			case error, nil:
+				continue
+			case bool:
+				if r {
+					return true
+				}
+			default:
+				return true
+			}
+		}
+	}, nil
+}
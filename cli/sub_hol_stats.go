@@ -0,0 +1,133 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// subHOLBuffer is how many messages may wait for the handler while measuring head-of-line blocking
+const subHOLBuffer = 64 * 1024
+
+// subHOLStats measures how long messages wait in the client before the handler starts, long waits show a slow
+// handler is blocking the messages queued behind it
+type subHOLStats struct {
+	delays []time.Duration
+	mu     sync.Mutex
+}
+
+// subHOLMsg is a message along with the time it arrived in the client
+type subHOLMsg struct {
+	msg     *nats.Msg
+	arrived time.Time
+}
+
+type subHOLStatsReport struct {
+	Messages int           `json:"messages"`
+	Mean     time.Duration `json:"mean"`
+	P50      time.Duration `json:"p50"`
+	P95      time.Duration `json:"p95"`
+	P99      time.Duration `json:"p99"`
+	Max      time.Duration `json:"max"`
+}
+
+func newSubHOLStats() *subHOLStats {
+	return &subHOLStats{}
+}
+
+// queueSubscribe subscribes using a channel as nats.go does not record when messages arrive, one goroutine notes the
+// arrival time of every message the client delivers and another runs handler for each in order until ctx is done
+func (h *subHOLStats) queueSubscribe(ctx context.Context, nc *nats.Conn, subj string, queue string, handler nats.MsgHandler) (*nats.Subscription, error) {
+	arrivals := make(chan *nats.Msg, subHOLBuffer)
+	queued := make(chan subHOLMsg, subHOLBuffer)
+
+	sub, err := nc.ChanQueueSubscribe(subj, queue, arrivals)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		for {
+			select {
+			case m := <-arrivals:
+				select {
+				case queued <- subHOLMsg{msg: m, arrived: time.Now()}:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		for {
+			select {
+			case q := <-queued:
+				h.observe(time.Since(q.arrived))
+				handler(q.msg)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return sub, nil
+}
+
+// observe records the time a message waited before its handler started
+func (h *subHOLStats) observe(delay time.Duration) {
+	h.mu.Lock()
+	h.delays = append(h.delays, delay)
+	h.mu.Unlock()
+}
+
+// report calculates the statistics of all delays seen so far
+func (h *subHOLStats) report() *subHOLStatsReport {
+	h.mu.Lock()
+	delays := append([]time.Duration{}, h.delays...)
+	h.mu.Unlock()
+
+	report := &subHOLStatsReport{Messages: len(delays)}
+	if len(delays) == 0 {
+		return report
+	}
+
+	report.Mean = benchKVAverage(delays)
+	report.P50 = benchKVPercentile(delays, 50)
+	report.P95 = benchKVPercentile(delays, 95)
+	report.P99 = benchKVPercentile(delays, 99)
+	// calculating percentiles sorted the delays in place
+	report.Max = delays[len(delays)-1]
+
+	return report
+}
+
+func (r *subHOLStatsReport) render() string {
+	if r.Messages == 0 {
+		return "No messages were received to measure head-of-line blocking"
+	}
+
+	table := newTableWriter(fmt.Sprintf("Handler delay of %s messages", f(r.Messages)))
+	table.AddHeaders("Mean", "p50", "p95", "p99", "Maximum")
+	table.AddRow(f(r.Mean), f(r.P50), f(r.P95), f(r.P99), f(r.Max))
+
+	return table.Render()
+}
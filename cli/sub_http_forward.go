@@ -0,0 +1,143 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	// subHTTPSubjectHeader is the HTTP header holding the subject a forwarded message was received on
+	subHTTPSubjectHeader = "Nats-Subject"
+
+	// subHTTPReplyHeader is the HTTP header holding the reply subject of a forwarded message
+	subHTTPReplyHeader = "Nats-Reply"
+
+	// subHTTPRetryDelay is how long to wait before the first retry, every further retry waits longer
+	subHTTPRetryDelay = 250 * time.Millisecond
+)
+
+// subHTTPForwarder posts received messages to an HTTP endpoint
+type subHTTPForwarder struct {
+	url     string
+	retries int
+	client  *http.Client
+
+	forwarded uint64
+	failed    uint64
+}
+
+func newSubHTTPForwarder(target string, retries int, timeout time.Duration) (*subHTTPForwarder, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http-forward url %q: %w", target, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid http-forward url %q: only http and https urls are supported", target)
+	}
+	if retries < 0 {
+		return nil, fmt.Errorf("http-retries can not be negative")
+	}
+
+	return &subHTTPForwarder{
+		url:     target,
+		retries: retries,
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// forward posts m to the endpoint, retrying failed posts and responses other than 2xx
+func (h *subHTTPForwarder) forward(ctx context.Context, m *nats.Msg) error {
+	var err error
+
+	for attempt := 0; attempt <= h.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(time.Duration(attempt) * subHTTPRetryDelay):
+			case <-ctx.Done():
+				h.failed++
+				return ctx.Err()
+			}
+		}
+
+		err = h.post(ctx, m)
+		if err == nil {
+			h.forwarded++
+			return nil
+		}
+
+		logDebugf("HTTP forward attempt %d of message on %s failed: %s", attempt+1, m.Subject, err)
+	}
+
+	h.failed++
+
+	if h.retries > 0 {
+		return fmt.Errorf("failed after %d attempts: %w", h.retries+1, err)
+	}
+
+	return err
+}
+
+func (h *subHTTPForwarder) post(ctx context.Context, m *nats.Msg) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(m.Data))
+	if err != nil {
+		return err
+	}
+
+	for k, vals := range m.Header {
+		for _, v := range vals {
+			req.Header.Add(k, v)
+		}
+	}
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+	req.Header.Set(subHTTPSubjectHeader, m.Subject)
+	if m.Reply != "" {
+		req.Header.Set(subHTTPReplyHeader, m.Reply)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if len(bytes.TrimSpace(body)) == 0 {
+			return fmt.Errorf("%s returned %s", h.url, resp.Status)
+		}
+
+		return fmt.Errorf("%s returned %s: %s", h.url, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	// reading the body lets the connection be reused
+	io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
+
+func (h *subHTTPForwarder) summary() string {
+	return fmt.Sprintf("Forwarded %s messages to %s, %s could not be forwarded", f(h.forwarded), h.url, f(h.failed))
+}
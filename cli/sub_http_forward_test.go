@@ -0,0 +1,93 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSubHTTPForwarder(t *testing.T) {
+	var calls atomic.Int32
+	var failures atomic.Int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+
+		if failures.Load() > 0 {
+			failures.Add(-1)
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || string(body) != "order" {
+			t.Errorf("unexpected request %s %q", r.Method, body)
+		}
+		if r.Header.Get("X-Tenant") != "acme" || r.Header.Get("Nats-Subject") != "events.orders" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+		if r.Header.Get("Content-Type") != "application/octet-stream" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+	}))
+	defer srv.Close()
+
+	msg := nats.NewMsg("events.orders")
+	msg.Data = []byte("order")
+	msg.Header.Set("X-Tenant", "acme")
+
+	h, err := newSubHTTPForwarder(srv.URL, 2, time.Second)
+	if err != nil {
+		t.Fatalf("forwarder failed: %v", err)
+	}
+
+	failures.Store(2)
+	err = h.forward(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("expected the message to be forwarded after retrying: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts got %d", calls.Load())
+	}
+
+	calls.Store(0)
+	failures.Store(3)
+	err = h.forward(context.Background(), msg)
+	if err == nil || !strings.Contains(err.Error(), "failed after 3 attempts") || !strings.Contains(err.Error(), "503 Service Unavailable: try again") {
+		t.Fatalf("expected the message to fail after 3 attempts: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts got %d", calls.Load())
+	}
+
+	if h.forwarded != 1 || h.failed != 1 {
+		t.Fatalf("expected 1 forwarded and 1 failed message got %d and %d", h.forwarded, h.failed)
+	}
+
+	for _, target := range []string{"ftp://localhost/ingest", "localhost:8080", "http://"} {
+		_, err = newSubHTTPForwarder(target, 3, time.Second)
+		if err == nil {
+			t.Fatalf("expected %q to be rejected", target)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+var errSubOrderMissing = errors.New("no ordering value found")
+
+// subOrderExtractor finds the ordering value of a message in a header or, for sources starting with . or $., a JSON body field
+type subOrderExtractor struct {
+	header string
+	path   []string
+}
+
+func newSubOrderExtractor(source string) (*subOrderExtractor, error) {
+	source = strings.TrimSpace(source)
+
+	if !strings.HasPrefix(source, ".") && !strings.HasPrefix(source, "$.") {
+		if source == "" {
+			return nil, fmt.Errorf("an ordering header or JSON path is required")
+		}
+
+		return &subOrderExtractor{header: source}, nil
+	}
+
+	path := strings.Split(strings.TrimPrefix(strings.TrimPrefix(source, "$"), "."), ".")
+	for _, p := range path {
+		if p == "" {
+			return nil, fmt.Errorf("invalid JSON path %q", source)
+		}
+	}
+
+	return &subOrderExtractor{path: path}, nil
+}
+
+func (e *subOrderExtractor) value(m *nats.Msg) (uint64, error) {
+	var raw string
+
+	if e.header != "" {
+		raw = m.Header.Get(e.header)
+		if raw == "" {
+			return 0, errSubOrderMissing
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(m.Data))
+		dec.UseNumber()
+
+		var doc any
+		err := dec.Decode(&doc)
+		if err != nil {
+			return 0, fmt.Errorf("invalid JSON body: %w", err)
+		}
+
+		for _, p := range e.path {
+			switch v := doc.(type) {
+			case map[string]any:
+				doc = v[p]
+			case []any:
+				idx, err := strconv.Atoi(p)
+				if err != nil || idx < 0 || idx >= len(v) {
+					return 0, errSubOrderMissing
+				}
+				doc = v[idx]
+			default:
+				return 0, errSubOrderMissing
+			}
+		}
+
+		switch v := doc.(type) {
+		case nil:
+			return 0, errSubOrderMissing
+		case json.Number:
+			raw = v.String()
+		case string:
+			raw = v
+		default:
+			return 0, fmt.Errorf("ordering value %v is not a number", v)
+		}
+	}
+
+	val, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("ordering value %q is not a positive integer", raw)
+	}
+
+	return val, nil
+}
+
+type subOrderState struct {
+	last uint64
+	seen bool
+}
+
+// subOrderTracker verifies that ordering values increase by one at a time over all subjects, and never decrease within
+// a subject. A flow split over many subjects is expected to skip values on each subject so skips are only detected globally.
+// Values are compared modulo 2^64 so a counter wrapping from the largest value to 0 is considered in order.
+type subOrderTracker struct {
+	extractor *subOrderExtractor
+	global    subOrderState
+	subjects  map[string]*subOrderState
+
+	checked    uint64
+	backwards  uint64
+	duplicates uint64
+	skips      uint64
+	skipped    uint64
+	missing    uint64
+}
+
+func newSubOrderTracker(source string) (*subOrderTracker, error) {
+	extractor, err := newSubOrderExtractor(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &subOrderTracker{extractor: extractor, subjects: map[string]*subOrderState{}}, nil
+}
+
+// observe records the ordering value of m and returns a warning for every problem found
+func (t *subOrderTracker) observe(m *nats.Msg) []string {
+	val, err := t.extractor.value(m)
+	if err != nil {
+		t.missing++
+		return []string{fmt.Sprintf("Message on %s has no usable ordering value: %v", m.Subject, err)}
+	}
+
+	t.checked++
+
+	var warnings []string
+	globalOrdered := true
+
+	if t.global.seen {
+		switch dist := val - t.global.last; {
+		case dist == 0:
+			t.duplicates++
+			globalOrdered = false
+			warnings = append(warnings, fmt.Sprintf("Ordering value %d on %s was repeated", val, m.Subject))
+		case dist > math.MaxUint64/2:
+			t.backwards++
+			globalOrdered = false
+			warnings = append(warnings, fmt.Sprintf("Ordering value %d on %s went backwards from %d", val, m.Subject, t.global.last))
+		case dist > 1:
+			t.skips++
+			t.skipped += dist - 1
+			warnings = append(warnings, fmt.Sprintf("Ordering value %d on %s skipped %d values after %d", val, m.Subject, dist-1, t.global.last))
+		}
+	}
+
+	subj, ok := t.subjects[m.Subject]
+	if !ok {
+		subj = &subOrderState{}
+		t.subjects[m.Subject] = subj
+	}
+
+	// after an earlier misplaced message the global order can look fine while a subject goes backwards
+	if globalOrdered && subj.seen && val-subj.last > math.MaxUint64/2 {
+		t.backwards++
+		warnings = append(warnings, fmt.Sprintf("Ordering value %d on %s went backwards from %d on the same subject", val, m.Subject, subj.last))
+	}
+
+	// the latest value is always kept so checking resumes from the most recent message
+	subj.last, subj.seen = val, true
+	t.global.last, t.global.seen = val, true
+
+	return warnings
+}
+
+func (t *subOrderTracker) disordered() uint64 {
+	return t.backwards + t.duplicates + t.skips + t.missing
+}
+
+func (t *subOrderTracker) summary() string {
+	return fmt.Sprintf("Verified ordering of %s messages on %s subjects: %s went backwards, %s were repeated, %s skipped %s values and %s had no ordering value",
+		f(t.checked), f(len(t.subjects)), f(t.backwards), f(t.duplicates), f(t.skips), f(t.skipped), f(t.missing))
+}
+
+// subStreamOrderTracker verifies that JetStream messages are delivered in stream order without any being missed.
+// Stream sequences have to increase, they skip values for messages that were deleted or not matched by the consumer
+// filter so missed deliveries are detected using the consumer sequence that increases by one for every delivery.
+// Redeliveries are not checked as they are expected to arrive after later messages.
+type subStreamOrderTracker struct {
+	streamSeq   uint64
+	consumerSeq uint64
+	seen        bool
+
+	checked     uint64
+	redelivered uint64
+	backwards   uint64
+	duplicates  uint64
+	gaps        uint64
+	missed      uint64
+}
+
+func newSubStreamOrderTracker() *subStreamOrderTracker {
+	return &subStreamOrderTracker{}
+}
+
+// observe records the sequences of a message described by info and returns a warning for every problem found
+func (t *subStreamOrderTracker) observe(subject string, info *jsm.MsgInfo) []string {
+	if info == nil {
+		return nil
+	}
+
+	if info.Delivered() > 1 {
+		t.redelivered++
+
+		// redeliveries use consumer sequences too
+		if info.ConsumerSequence() > t.consumerSeq {
+			t.consumerSeq = info.ConsumerSequence()
+		}
+
+		return nil
+	}
+
+	t.checked++
+
+	sseq, cseq := info.StreamSequence(), info.ConsumerSequence()
+
+	var warnings []string
+	if t.seen {
+		switch {
+		case sseq == t.streamSeq:
+			t.duplicates++
+			warnings = append(warnings, fmt.Sprintf("Stream sequence %d on %s was delivered again", sseq, subject))
+		case sseq < t.streamSeq:
+			t.backwards++
+			warnings = append(warnings, fmt.Sprintf("Stream sequence %d on %s went backwards from %d", sseq, subject, t.streamSeq))
+		}
+
+		if cseq > t.consumerSeq+1 {
+			t.gaps++
+			t.missed += cseq - t.consumerSeq - 1
+			warnings = append(warnings, fmt.Sprintf("Missed %d messages between stream sequences %d and %d, consumer sequence %d followed %d", cseq-t.consumerSeq-1, t.streamSeq, sseq, cseq, t.consumerSeq))
+		}
+	}
+
+	t.streamSeq, t.consumerSeq, t.seen = sseq, cseq, true
+
+	return warnings
+}
+
+func (t *subStreamOrderTracker) violations() uint64 {
+	return t.backwards + t.duplicates + t.gaps
+}
+
+func (t *subStreamOrderTracker) summary() string {
+	return fmt.Sprintf("Validated stream ordering of %s messages: %s went backwards, %s were delivered again and %s gaps missed %s messages, %s redeliveries were not checked",
+		f(t.checked), f(t.backwards), f(t.duplicates), f(t.gaps), f(t.missed), f(t.redelivered))
+}
+
+// subSequenceChecker verifies messages whose body is a sequence number, like those published using "{{Count}}", are
+// all received exactly once. The first message received sets the start, values received are kept until every
+// value up to them arrived so gaps filled by late messages are only counted as out of order.
+type subSequenceChecker struct {
+	first      uint64
+	highest    uint64
+	contiguous uint64
+	seen       bool
+	pending    map[uint64]struct{}
+
+	received   uint64
+	duplicates uint64
+	outOfOrder uint64
+	invalid    uint64
+}
+
+func newSubSequenceChecker() *subSequenceChecker {
+	return &subSequenceChecker{pending: map[uint64]struct{}{}}
+}
+
+// observe records the sequence in the body of m and returns a warning for every problem found
+func (c *subSequenceChecker) observe(m *nats.Msg) []string {
+	val, err := strconv.ParseUint(string(bytes.TrimSpace(m.Data)), 10, 64)
+	if err != nil {
+		c.invalid++
+		return []string{fmt.Sprintf("Message on %s does not hold a sequence number", m.Subject)}
+	}
+
+	c.received++
+
+	if !c.seen {
+		c.first, c.highest, c.contiguous, c.seen = val, val, val, true
+		return nil
+	}
+
+	_, received := c.pending[val]
+	if received || (val >= c.first && val <= c.contiguous) {
+		c.duplicates++
+		return []string{fmt.Sprintf("Sequence %d on %s was received again", val, m.Subject)}
+	}
+
+	var warnings []string
+	if val < c.highest {
+		c.outOfOrder++
+		warnings = append(warnings, fmt.Sprintf("Sequence %d on %s arrived after %d", val, m.Subject, c.highest))
+	} else {
+		c.highest = val
+	}
+
+	c.pending[val] = struct{}{}
+	for {
+		_, ok := c.pending[c.contiguous+1]
+		if !ok {
+			break
+		}
+		delete(c.pending, c.contiguous+1)
+		c.contiguous++
+	}
+
+	return warnings
+}
+
+// missing calculates how many sequences between the first and the highest received were not received, and in how many gaps
+func (c *subSequenceChecker) missing() (missing uint64, gaps uint64) {
+	var later []uint64
+	for v := range c.pending {
+		if v > c.contiguous {
+			later = append(later, v)
+		}
+	}
+	sort.Slice(later, func(i, j int) bool { return later[i] < later[j] })
+
+	last := c.contiguous
+	for _, v := range later {
+		if v > last+1 {
+			gaps++
+			missing += v - last - 1
+		}
+		last = v
+	}
+
+	return missing, gaps
+}
+
+func (c *subSequenceChecker) problems() uint64 {
+	missing, _ := c.missing()
+	return missing + c.duplicates + c.outOfOrder + c.invalid
+}
+
+func (c *subSequenceChecker) summary() string {
+	if !c.seen {
+		return fmt.Sprintf("Checked sequences of 0 messages, %s had no sequence number", f(c.invalid))
+	}
+
+	missing, gaps := c.missing()
+
+	return fmt.Sprintf("Checked sequences %d to %d in %s messages: %s were missing in %s gaps, %s were duplicates, %s arrived out of order and %s had no sequence number",
+		c.first, c.highest, f(c.received), f(missing), f(gaps), f(c.duplicates), f(c.outOfOrder), f(c.invalid))
+}
@@ -0,0 +1,275 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/nats-io/jsm.go"
+	"github.com/nats-io/nats.go"
+)
+
+func orderHeaderMsg(subject string, seq uint64) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Header.Set("Seq", fmt.Sprintf("%d", seq))
+
+	return msg
+}
+
+func TestSubOrderExtractor(t *testing.T) {
+	t.Run("header", func(t *testing.T) {
+		e, err := newSubOrderExtractor("Seq")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		val, err := e.value(orderHeaderMsg("x", 10))
+		if err != nil || val != 10 {
+			t.Fatalf("expected 10 got %d: %v", val, err)
+		}
+
+		_, err = e.value(nats.NewMsg("x"))
+		if !errors.Is(err, errSubOrderMissing) {
+			t.Fatalf("expected missing value error, got %v", err)
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		for _, path := range []string{".meta.seq", "$.meta.seq"} {
+			e, err := newSubOrderExtractor(path)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			val, err := e.value(&nats.Msg{Data: []byte(`{"meta":{"seq":18446744073709551615}}`)})
+			if err != nil || val != math.MaxUint64 {
+				t.Fatalf("expected max uint64 got %d: %v", val, err)
+			}
+
+			val, err = e.value(&nats.Msg{Data: []byte(`{"meta":{"seq":"12"}}`)})
+			if err != nil || val != 12 {
+				t.Fatalf("expected 12 got %d: %v", val, err)
+			}
+
+			for _, body := range []string{`{"meta":{}}`, `{"meta":1}`, `{}`} {
+				_, err = e.value(&nats.Msg{Data: []byte(body)})
+				if !errors.Is(err, errSubOrderMissing) {
+					t.Fatalf("expected missing value error for %s, got %v", body, err)
+				}
+			}
+
+			for _, body := range []string{`{"meta":{"seq":-1}}`, `{"meta":{"seq":1.5}}`, `{"meta":{"seq":true}}`, `not json`} {
+				_, err = e.value(&nats.Msg{Data: []byte(body)})
+				if err == nil || errors.Is(err, errSubOrderMissing) {
+					t.Fatalf("expected invalid value error for %s, got %v", body, err)
+				}
+			}
+		}
+
+		e, err := newSubOrderExtractor(".items.1.seq")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		val, err := e.value(&nats.Msg{Data: []byte(`{"items":[{"seq":1},{"seq":2}]}`)})
+		if err != nil || val != 2 {
+			t.Fatalf("expected 2 got %d: %v", val, err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, source := range []string{"", ".", ".meta..seq"} {
+			_, err := newSubOrderExtractor(source)
+			if err == nil {
+				t.Fatalf("expected an error for %q", source)
+			}
+		}
+	})
+}
+
+func TestSubOrderTracker(t *testing.T) {
+	observe := func(tracker *subOrderTracker, msgs ...*nats.Msg) int {
+		warnings := 0
+		for _, msg := range msgs {
+			warnings += len(tracker.observe(msg))
+		}
+
+		return warnings
+	}
+
+	t.Run("split flow", func(t *testing.T) {
+		tracker, _ := newSubOrderTracker("Seq")
+
+		warnings := observe(tracker,
+			orderHeaderMsg("orders.a.events", 1),
+			orderHeaderMsg("orders.b.events", 2),
+			orderHeaderMsg("orders.a.events", 3),
+			orderHeaderMsg("orders.c.events", 4),
+			orderHeaderMsg("orders.b.events", 5),
+		)
+		if warnings != 0 || tracker.disordered() != 0 || tracker.checked != 5 || len(tracker.subjects) != 3 {
+			t.Fatalf("expected an ordered flow: %s", tracker.summary())
+		}
+	})
+
+	t.Run("disorder", func(t *testing.T) {
+		tracker, _ := newSubOrderTracker("Seq")
+
+		warnings := observe(tracker,
+			orderHeaderMsg("a", 1),
+			orderHeaderMsg("a", 2),
+			orderHeaderMsg("b", 5),
+			orderHeaderMsg("a", 3),
+			orderHeaderMsg("a", 3),
+			nats.NewMsg("a"),
+		)
+		if warnings != 4 {
+			t.Fatalf("expected 4 warnings got %d", warnings)
+		}
+		if tracker.skips != 1 || tracker.skipped != 2 || tracker.backwards != 1 || tracker.duplicates != 1 || tracker.missing != 1 {
+			t.Fatalf("unexpected counts: %s", tracker.summary())
+		}
+	})
+
+	t.Run("subject backwards", func(t *testing.T) {
+		tracker, _ := newSubOrderTracker("Seq")
+
+		// b going backwards is reported globally, a then follows b in order globally but went backwards on a
+		observe(tracker,
+			orderHeaderMsg("a", 10),
+			orderHeaderMsg("b", 3),
+			orderHeaderMsg("a", 4),
+		)
+		if tracker.backwards != 2 {
+			t.Fatalf("expected 2 backwards got %d", tracker.backwards)
+		}
+	})
+
+	t.Run("wraparound", func(t *testing.T) {
+		tracker, _ := newSubOrderTracker("Seq")
+
+		warnings := observe(tracker,
+			orderHeaderMsg("a", math.MaxUint64-1),
+			orderHeaderMsg("a", math.MaxUint64),
+			orderHeaderMsg("a", 0),
+			orderHeaderMsg("a", 1),
+		)
+		if warnings != 0 || tracker.disordered() != 0 {
+			t.Fatalf("expected wrapping to be in order: %s", tracker.summary())
+		}
+
+		observe(tracker, orderHeaderMsg("a", math.MaxUint64))
+		if tracker.backwards != 1 {
+			t.Fatalf("expected going back over the wrap to be backwards: %s", tracker.summary())
+		}
+	})
+}
+
+func orderStreamInfo(t *testing.T, delivered int, sseq uint64, cseq uint64) *jsm.MsgInfo {
+	t.Helper()
+
+	msg := nats.NewMsg("orders.new")
+	msg.Reply = fmt.Sprintf("$JS.ACK.ORDERS.C.%d.%d.%d.1700000000000000000.0", delivered, sseq, cseq)
+
+	info, err := jsm.ParseJSMsgMetadata(msg)
+	if err != nil {
+		t.Fatalf("invalid metadata: %v", err)
+	}
+
+	return info
+}
+
+func TestSubStreamOrderTracker(t *testing.T) {
+	tr := newSubStreamOrderTracker()
+
+	check := func(info *jsm.MsgInfo, expected int) {
+		t.Helper()
+
+		warnings := tr.observe("orders.new", info)
+		if len(warnings) != expected {
+			t.Fatalf("expected %d warnings got %v", expected, warnings)
+		}
+	}
+
+	// stream sequences skipping values for filtered or deleted messages are in order
+	check(orderStreamInfo(t, 1, 10, 1), 0)
+	check(orderStreamInfo(t, 1, 11, 2), 0)
+	check(orderStreamInfo(t, 1, 15, 3), 0)
+	if tr.violations() != 0 {
+		t.Fatalf("unexpected violations: %s", tr.summary())
+	}
+
+	// a missed delivery
+	check(orderStreamInfo(t, 1, 20, 6), 1)
+	if tr.gaps != 1 || tr.missed != 2 {
+		t.Fatalf("expected 2 missed messages: %s", tr.summary())
+	}
+
+	// redeliveries are not checked
+	check(orderStreamInfo(t, 2, 11, 7), 0)
+
+	check(orderStreamInfo(t, 1, 20, 8), 1)
+	check(orderStreamInfo(t, 1, 18, 9), 1)
+	check(nil, 0)
+
+	if tr.duplicates != 1 || tr.backwards != 1 || tr.redelivered != 1 || tr.checked != 6 || tr.violations() != 3 {
+		t.Fatalf("unexpected totals: %s", tr.summary())
+	}
+}
+
+func TestSubSequenceChecker(t *testing.T) {
+	c := newSubSequenceChecker()
+
+	check := func(body string, expected int) {
+		t.Helper()
+
+		warnings := c.observe(&nats.Msg{Subject: "integrity.check", Data: []byte(body)})
+		if len(warnings) != expected {
+			t.Fatalf("expected %d warnings for %q got %v", expected, body, warnings)
+		}
+	}
+
+	check("5", 0)
+	check("6\n", 0)
+	check("8", 0)
+	check("11", 0)
+	if missing, gaps := c.missing(); missing != 3 || gaps != 2 {
+		t.Fatalf("expected 3 missing in 2 gaps got %d in %d", missing, gaps)
+	}
+
+	// late messages fill the gaps
+	check("7", 1)
+	check("10", 1)
+	if missing, gaps := c.missing(); missing != 1 || gaps != 1 {
+		t.Fatalf("expected 1 missing in 1 gap got %d in %d", missing, gaps)
+	}
+
+	// duplicates of values both before and after the contiguous range
+	check("6", 1)
+	check("10", 1)
+	check("4", 1)
+	check("4", 1)
+	check("x", 1)
+
+	if c.duplicates != 3 || c.outOfOrder != 3 || c.invalid != 1 || c.received != 10 || c.problems() != 8 {
+		t.Fatalf("unexpected totals: %s", c.summary())
+	}
+
+	expected := "Checked sequences 5 to 11 in 10 messages: 1 were missing in 1 gaps, 3 were duplicates, 3 arrived out of order and 1 had no sequence number"
+	if c.summary() != expected {
+		t.Fatalf("unexpected summary: %s", c.summary())
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// subjectCounter counts the messages received on every subject
+type subjectCounter struct {
+	counts   map[string]uint64
+	messages uint64
+	mu       sync.Mutex
+}
+
+type subjectCount struct {
+	Subject  string `json:"subject"`
+	Messages uint64 `json:"messages"`
+}
+
+type subjectCounterReport struct {
+	Messages uint64          `json:"messages"`
+	Subjects []*subjectCount `json:"subjects"`
+}
+
+func newSubjectCounter() *subjectCounter {
+	return &subjectCounter{counts: map[string]uint64{}}
+}
+
+func (s *subjectCounter) observe(subject string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[subject]++
+	s.messages++
+}
+
+// report lists the subjects with the most messages first, subjects with equal counts are sorted by name
+func (s *subjectCounter) report() *subjectCounterReport {
+	s.mu.Lock()
+	report := &subjectCounterReport{Messages: s.messages, Subjects: []*subjectCount{}}
+	for subject, cnt := range s.counts {
+		report.Subjects = append(report.Subjects, &subjectCount{Subject: subject, Messages: cnt})
+	}
+	s.mu.Unlock()
+
+	sort.Slice(report.Subjects, func(i, j int) bool {
+		if report.Subjects[i].Messages == report.Subjects[j].Messages {
+			return report.Subjects[i].Subject < report.Subjects[j].Subject
+		}
+		return report.Subjects[i].Messages > report.Subjects[j].Messages
+	})
+
+	return report
+}
+
+func (r *subjectCounterReport) render() string {
+	if r.Messages == 0 {
+		return "No messages were received"
+	}
+
+	table := newTableWriter("Messages per subject")
+	table.AddHeaders("Subject", "Messages", "Percent")
+
+	for _, s := range r.Subjects {
+		table.AddRow(s.Subject, f(s.Messages), fmt.Sprintf("%.1f%%", float64(s.Messages)/float64(r.Messages)*100))
+	}
+	table.AddFooter("Total", f(r.Messages), "")
+
+	return table.Render()
+}
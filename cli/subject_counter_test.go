@@ -0,0 +1,47 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSubjectCounter(t *testing.T) {
+	s := newSubjectCounter()
+	if out := s.report().render(); out != "No messages were received" {
+		t.Fatalf("unexpected render: %s", out)
+	}
+
+	for _, subject := range []string{"orders.b", "orders.a", "orders.c", "orders.c", "orders.a", "orders.c"} {
+		s.observe(subject)
+	}
+
+	report := s.report()
+	if report.Messages != 6 || len(report.Subjects) != 3 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	expected := []subjectCount{{"orders.c", 3}, {"orders.a", 2}, {"orders.b", 1}}
+	for i, e := range expected {
+		if *report.Subjects[i] != e {
+			t.Fatalf("expected %+v at %d got %+v", e, i, report.Subjects[i])
+		}
+	}
+
+	out := report.render()
+	if !strings.Contains(out, "Messages per subject") || !strings.Contains(out, "50.0%") || strings.Index(out, "orders.c") > strings.Index(out, "orders.b") {
+		t.Fatalf("unexpected render:\n%s", out)
+	}
+}
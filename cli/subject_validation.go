@@ -0,0 +1,120 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// validateSubjectSyntax checks that subject is made up of non empty tokens without white space, wildcards are allowed
+func validateSubjectSyntax(subject string) error {
+	if subject == "" {
+		return fmt.Errorf("invalid subject: it is empty")
+	}
+
+	if pos := strings.IndexFunc(subject, unicode.IsSpace); pos != -1 {
+		return fmt.Errorf("invalid subject %q: white space at position %d", subject, pos+1)
+	}
+
+	for i, token := range strings.Split(subject, ".") {
+		if token == "" {
+			return fmt.Errorf("invalid subject %q: token %d is empty", subject, i+1)
+		}
+	}
+
+	return nil
+}
+
+// validatePublishSubject checks that messages can be published to subject, which can not contain wildcards
+func validatePublishSubject(subject string) error {
+	err := validateSubjectSyntax(subject)
+	if err != nil {
+		return err
+	}
+
+	for i, token := range strings.Split(subject, ".") {
+		if token == "*" || token == ">" {
+			return fmt.Errorf("invalid subject %q: can not publish to the wildcard %s in token %d", subject, token, i+1)
+		}
+	}
+
+	return nil
+}
+
+// validateFilterSubject checks subjects Streams listen on and Consumers filter on, where > has to be the last token
+func validateFilterSubject(subject string) error {
+	err := validateSubjectSyntax(subject)
+	if err != nil {
+		return err
+	}
+
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		if token == ">" && i != len(tokens)-1 {
+			return fmt.Errorf("invalid subject %q: the wildcard > in token %d has to be the last token", subject, i+1)
+		}
+	}
+
+	return nil
+}
+
+// validateFilterSubjects checks every subject using validateFilterSubject, empty subjects mean no filter is set
+func validateFilterSubjects(subjects []string) error {
+	for _, subject := range subjects {
+		if subject == "" {
+			continue
+		}
+
+		err := validateFilterSubject(subject)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSubscribeSubject checks the syntax of a subject to subscribe to, logging warnings about likely mistakes
+func validateSubscribeSubject(subject string, queue string) error {
+	err := validateSubjectSyntax(subject)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range subscribeSubjectWarnings(subject, queue) {
+		logWarnf("%s", warning)
+	}
+
+	return nil
+}
+
+// subscribeSubjectWarnings finds usually unintended subscriptions, subject is assumed to have a valid syntax
+func subscribeSubjectWarnings(subject string, queue string) []string {
+	var warnings []string
+
+	tokens := strings.Split(subject, ".")
+	for i, token := range tokens {
+		if token == ">" && i != len(tokens)-1 {
+			warnings = append(warnings, fmt.Sprintf("Subject %q has > in token %d, it is only a wildcard as the last token and the subscription will not receive messages", subject, i+1))
+		}
+	}
+
+	if queue != "" && tokens[len(tokens)-1] == ">" {
+		warnings = append(warnings, fmt.Sprintf("Subscribing to %q in queue group %q, every message matching > is handled by only one member of the group", subject, queue))
+	}
+
+	return warnings
+}
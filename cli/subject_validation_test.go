@@ -0,0 +1,104 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSubjects(t *testing.T) {
+	type test struct {
+		subject   string
+		syntax    string
+		publish   string
+		filter    string
+		warnings  int
+		queueWarn bool
+	}
+
+	for _, tc := range []test{
+		{subject: "orders"},
+		{subject: "orders.new"},
+		{subject: "$JS.API.INFO"},
+		{subject: "_INBOX.abc123"},
+		{subject: "orders.ord*rs"},
+		{subject: "orders.>x"},
+		{subject: "orders-new.eu_1"},
+		{subject: "orders.*", publish: "can not publish to the wildcard * in token 2"},
+		{subject: "*", publish: "can not publish to the wildcard * in token 1"},
+		{subject: "*.orders.>", publish: "can not publish to the wildcard * in token 1", queueWarn: true},
+		{subject: ">", publish: "can not publish to the wildcard > in token 1", queueWarn: true},
+		{subject: "orders.>", publish: "can not publish to the wildcard > in token 2", queueWarn: true},
+		{subject: "orders.>.new", publish: "can not publish to the wildcard > in token 2", filter: "the wildcard > in token 2 has to be the last token", warnings: 1},
+		{subject: ">.>", publish: "can not publish to the wildcard > in token 1", filter: "the wildcard > in token 1 has to be the last token", warnings: 1, queueWarn: true},
+		{subject: "", syntax: "it is empty"},
+		{subject: "orders..new", syntax: "token 2 is empty"},
+		{subject: ".orders", syntax: "token 1 is empty"},
+		{subject: "orders.", syntax: "token 2 is empty"},
+		{subject: ".", syntax: "token 1 is empty"},
+		{subject: "orders new", syntax: "white space at position 7"},
+		{subject: " orders", syntax: "white space at position 1"},
+		{subject: "orders\t.new", syntax: "white space at position 7"},
+		{subject: "orders.new\n", syntax: "white space at position 11"},
+	} {
+		check := func(kind string, err error, expected string) {
+			t.Helper()
+
+			if expected == "" {
+				if err != nil {
+					t.Fatalf("expected %q to be a valid %s subject: %v", tc.subject, kind, err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), expected) {
+				t.Fatalf("expected %s validation of %q to fail with %q got %v", kind, tc.subject, expected, err)
+			}
+		}
+
+		check("syntax", validateSubjectSyntax(tc.subject), tc.syntax)
+
+		if tc.syntax != "" {
+			check("publish", validatePublishSubject(tc.subject), tc.syntax)
+			check("filter", validateFilterSubject(tc.subject), tc.syntax)
+			continue
+		}
+
+		check("publish", validatePublishSubject(tc.subject), tc.publish)
+		check("filter", validateFilterSubject(tc.subject), tc.filter)
+
+		if w := subscribeSubjectWarnings(tc.subject, ""); len(w) != tc.warnings {
+			t.Fatalf("expected %d warnings for %q got %v", tc.warnings, tc.subject, w)
+		}
+
+		expected := tc.warnings
+		if tc.queueWarn {
+			expected++
+		}
+		if w := subscribeSubjectWarnings(tc.subject, "workers"); len(w) != expected {
+			t.Fatalf("expected %d warnings for %q in a queue group got %v", expected, tc.subject, w)
+		}
+	}
+
+	err := validateFilterSubjects([]string{""})
+	if err != nil {
+		t.Fatalf("expected an empty filter to be accepted: %v", err)
+	}
+
+	err = validateFilterSubjects([]string{"orders.>", "invoices..new"})
+	if err == nil || !strings.Contains(err.Error(), `"invoices..new": token 2 is empty`) {
+		t.Fatalf("expected the invalid subject to be named got %v", err)
+	}
+}
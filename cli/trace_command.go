@@ -82,6 +82,11 @@ func (c *traceCmd) traceAction(_ *fisk.ParseContext) error {
 		return err
 	}
 
+	// older servers ignore the trace headers and would deliver the message even in trace only mode
+	if !serverMinVersion(nc.ConnectedServerVersion(), 2, 11, 0) {
+		return fmt.Errorf("server %s version %s does not support message tracing, version 2.11.0 or newer is required", nc.ConnectedServerName(), nc.ConnectedServerVersion())
+	}
+
 	msg := nats.NewMsg(c.subject)
 	for k, v := range c.header {
 		msg.Header.Set(k, v)
@@ -122,7 +127,10 @@ func (c *traceCmd) traceAction(_ *fisk.ParseContext) error {
 	}
 
 	event, err := tracing.TraceMsg(nc, msg, c.deliver, opts().Timeout, traces)
-	if (event == nil || !errors.Is(err, nats.ErrTimeout)) && err != nil {
+	if event == nil && (err == nil || errors.Is(err, nats.ErrTimeout)) {
+		return fmt.Errorf("no trace events received within %v, servers handling the subject might not support message tracing", opts().Timeout)
+	}
+	if !errors.Is(err, nats.ErrTimeout) && err != nil {
 		return err
 	}
 
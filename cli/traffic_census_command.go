@@ -0,0 +1,374 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/choria-io/fisk"
+	"github.com/mattn/go-isatty"
+	"github.com/nats-io/nats.go"
+	iu "github.com/nats-io/natscli/internal/util"
+)
+
+const (
+	trafficCensusPendingMsgs  = 1_000_000
+	trafficCensusPendingBytes = 1024 * 1024 * 1024
+)
+
+type trafficCensusCmd struct {
+	subject  string
+	depth    int
+	duration time.Duration
+	csvFile  string
+	json     bool
+	top      int
+	sort     string
+	buckets  []string
+
+	mu       sync.Mutex
+	subjects map[string]*trafficCensusEntry
+	sizes    *sizeHistogram
+	started  time.Time
+}
+
+type trafficCensusEntry struct {
+	Subject      string  `json:"subject"`
+	Messages     int64   `json:"messages"`
+	Bytes        int64   `json:"bytes"`
+	MessageRate  float64 `json:"messages_per_second"`
+	BytesRate    float64 `json:"bytes_per_second"`
+	prevMessages int64
+	prevBytes    int64
+}
+
+type trafficCensusReport struct {
+	Subject  string                `json:"subject"`
+	Depth    int                   `json:"depth,omitempty"`
+	Start    time.Time             `json:"start"`
+	Duration time.Duration         `json:"duration"`
+	Messages int64                 `json:"messages"`
+	Bytes    int64                 `json:"bytes"`
+	Dropped  int                   `json:"dropped"`
+	Subjects []*trafficCensusEntry `json:"subjects"`
+	Sizes    *sizeHistogramReport  `json:"sizes,omitempty"`
+}
+
+func configureTrafficCensusCommand(traffic *fisk.CmdClause) {
+	c := &trafficCensusCmd{}
+
+	census := traffic.Command("census", "Counts messages and bytes per subject without showing payloads").Default().Action(c.censusAction)
+	census.Arg("subject", "The subject to observe").Default(">").StringVar(&c.subject)
+	census.Flag("depth", "Rollup subjects to their first N tokens").PlaceHolder("N").IntVar(&c.depth)
+	census.Flag("duration", "Stop after this long and show the final report").DurationVar(&c.duration)
+	census.Flag("csv", "Save the final report to a CSV file").PlaceHolder("FILE").StringVar(&c.csvFile)
+	census.Flag("json", "Produce the final report in JSON format").Short('j').UnNegatableBoolVar(&c.json)
+	census.Flag("top", "Number of subjects to show in the refreshing table").Default("20").IntVar(&c.top)
+	census.Flag("sort", "Sort subjects by messages, bytes or subject").Default("messages").EnumVar(&c.sort, "messages", "bytes", "subject")
+	census.Flag("size-buckets", "Upper bounds of the message size buckets shown in the final report like 1KB,64KB,1MB").PlaceHolder("SIZES").StringsVar(&c.buckets)
+}
+
+func (c *trafficCensusCmd) censusAction(_ *fisk.ParseContext) error {
+	if c.depth < 0 {
+		return fmt.Errorf("depth can not be negative")
+	}
+
+	bounds, err := parseSizeHistogramBuckets(c.buckets)
+	if err != nil {
+		return err
+	}
+
+	c.sizes, err = newSizeHistogram(bounds)
+	if err != nil {
+		return err
+	}
+
+	nc, err := newNatsConn("", natsOpts()...)
+	if err != nil {
+		return err
+	}
+	defer nc.Close()
+
+	c.subjects = make(map[string]*trafficCensusEntry)
+
+	sub, err := nc.Subscribe(c.subject, func(m *nats.Msg) {
+		c.observe(m.Subject, len(m.Data))
+	})
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// the census is only honest when it keeps up with the traffic, give the
+	// client a lot of room to buffer and report what it had to drop anyway
+	err = sub.SetPendingLimits(trafficCensusPendingMsgs, trafficCensusPendingBytes)
+	if err != nil {
+		return err
+	}
+
+	c.started = time.Now()
+
+	interactive := !c.json && isatty.IsTerminal(os.Stdout.Fd())
+	if !interactive && !c.json {
+		log.Printf("Observing traffic on %s", c.subject)
+	}
+
+	cctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var done <-chan time.Time
+	if c.duration > 0 {
+		done = time.After(c.duration)
+	}
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last := time.Now()
+
+loop:
+	for {
+		select {
+		case now := <-ticker.C:
+			entries := c.snapshot(now.Sub(last))
+			last = now
+
+			if interactive {
+				dropped, _ := sub.Dropped()
+				clearScreen()
+				c.renderTable(entries, dropped, c.top, true)
+			}
+
+		case <-done:
+			break loop
+
+		case <-cctx.Done():
+			break loop
+		}
+	}
+
+	// drops are only available while the subscription is active
+	dropped, _ := sub.Dropped()
+
+	err = sub.Unsubscribe()
+	if err != nil {
+		logErrorf("Could not unsubscribe: %v", err)
+	}
+
+	report := c.report(dropped)
+
+	if c.csvFile != "" {
+		err = c.writeCSV(report)
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.json {
+		return iu.PrintJSON(report)
+	}
+
+	if interactive {
+		clearScreen()
+	}
+	c.renderTable(report.Subjects, dropped, 0, false)
+
+	if report.Sizes != nil && report.Sizes.Messages > 0 {
+		fmt.Println()
+		fmt.Println(report.Sizes.render("Message sizes"))
+	}
+
+	if c.csvFile != "" {
+		fmt.Printf("Saved the report in csv file %s\n", c.csvFile)
+	}
+
+	return nil
+}
+
+// rollup reduces subject to the configured number of tokens
+func (c *trafficCensusCmd) rollup(subject string) string {
+	if c.depth == 0 {
+		return subject
+	}
+
+	tokens := strings.SplitN(subject, ".", c.depth+1)
+	if len(tokens) <= c.depth {
+		return subject
+	}
+
+	return strings.Join(tokens[:c.depth], ".") + ".>"
+}
+
+func (c *trafficCensusCmd) observe(subject string, size int) {
+	key := c.rollup(subject)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.subjects[key]
+	if !ok {
+		entry = &trafficCensusEntry{Subject: key}
+		c.subjects[key] = entry
+	}
+
+	entry.Messages++
+	entry.Bytes += int64(size)
+
+	if c.sizes != nil {
+		c.sizes.observe(size)
+	}
+}
+
+// snapshot copies the current counters, calculating rates over the interval since the previous snapshot
+func (c *trafficCensusCmd) snapshot(interval time.Duration) []*trafficCensusEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]*trafficCensusEntry, 0, len(c.subjects))
+	for _, e := range c.subjects {
+		s := &trafficCensusEntry{Subject: e.Subject, Messages: e.Messages, Bytes: e.Bytes}
+		if interval > 0 {
+			s.MessageRate = float64(e.Messages-e.prevMessages) / interval.Seconds()
+			s.BytesRate = float64(e.Bytes-e.prevBytes) / interval.Seconds()
+		}
+		e.prevMessages = e.Messages
+		e.prevBytes = e.Bytes
+
+		entries = append(entries, s)
+	}
+
+	c.sortEntries(entries)
+
+	return entries
+}
+
+// report produces the final census with rates averaged over the entire run
+func (c *trafficCensusCmd) report(dropped int) *trafficCensusReport {
+	elapsed := time.Since(c.started)
+
+	report := &trafficCensusReport{
+		Subject:  c.subject,
+		Depth:    c.depth,
+		Start:    c.started,
+		Duration: elapsed,
+		Dropped:  dropped,
+		Subjects: []*trafficCensusEntry{},
+	}
+
+	c.mu.Lock()
+	for _, e := range c.subjects {
+		entry := &trafficCensusEntry{Subject: e.Subject, Messages: e.Messages, Bytes: e.Bytes}
+		if elapsed > 0 {
+			entry.MessageRate = float64(e.Messages) / elapsed.Seconds()
+			entry.BytesRate = float64(e.Bytes) / elapsed.Seconds()
+		}
+
+		report.Messages += e.Messages
+		report.Bytes += e.Bytes
+		report.Subjects = append(report.Subjects, entry)
+	}
+	c.mu.Unlock()
+
+	if c.sizes != nil {
+		report.Sizes = c.sizes.report()
+	}
+
+	c.sortEntries(report.Subjects)
+
+	return report
+}
+
+func (c *trafficCensusCmd) sortEntries(entries []*trafficCensusEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		switch c.sort {
+		case "bytes":
+			if entries[i].Bytes != entries[j].Bytes {
+				return entries[i].Bytes > entries[j].Bytes
+			}
+		case "messages":
+			if entries[i].Messages != entries[j].Messages {
+				return entries[i].Messages > entries[j].Messages
+			}
+		}
+
+		return entries[i].Subject < entries[j].Subject
+	})
+}
+
+// renderTable shows up to limit entries, all entries when limit is 0
+func (c *trafficCensusCmd) renderTable(entries []*trafficCensusEntry, dropped int, limit int, live bool) {
+	title := fmt.Sprintf("Traffic census for %s over %s", c.subject, f(time.Since(c.started).Round(time.Second)))
+	if len(entries) > limit && limit > 0 {
+		title = fmt.Sprintf("%s, top %d of %d subjects", title, limit, len(entries))
+	}
+
+	rateHeading := "Average"
+	if live {
+		rateHeading = "Current"
+	}
+
+	table := newTableWriter(title)
+	table.AddHeaders("Subject", "Messages", "Bytes", rateHeading+" Msgs/s", rateHeading+" Bytes/s")
+	for i, e := range entries {
+		if limit > 0 && i == limit {
+			break
+		}
+
+		table.AddRow(e.Subject, f(e.Messages), fiBytes(uint64(e.Bytes)), f(e.MessageRate), fiBytes(uint64(e.BytesRate)))
+	}
+
+	fmt.Println(table.Render())
+
+	if dropped > 0 {
+		fmt.Printf("WARNING: %s messages were dropped by the client, the census is incomplete\n", f(dropped))
+	} else {
+		fmt.Println("No messages were dropped")
+	}
+}
+
+func (c *trafficCensusCmd) writeCSV(report *trafficCensusReport) error {
+	out, err := os.Create(c.csvFile)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := csv.NewWriter(out)
+	w.Write([]string{"subject", "messages", "bytes", "messages_per_second", "bytes_per_second"})
+	for _, e := range report.Subjects {
+		w.Write([]string{
+			e.Subject,
+			strconv.FormatInt(e.Messages, 10),
+			strconv.FormatInt(e.Bytes, 10),
+			strconv.FormatFloat(e.MessageRate, 'f', 2, 64),
+			strconv.FormatFloat(e.BytesRate, 'f', 2, 64),
+		})
+	}
+	w.Flush()
+
+	if w.Error() != nil {
+		return w.Error()
+	}
+
+	return out.Close()
+}
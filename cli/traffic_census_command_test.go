@@ -0,0 +1,89 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrafficCensusRollup(t *testing.T) {
+	cases := []struct {
+		depth   int
+		subject string
+		expect  string
+	}{
+		{0, "orders.eu.new", "orders.eu.new"},
+		{1, "orders.eu.new", "orders.>"},
+		{2, "orders.eu.new", "orders.eu.>"},
+		{2, "orders.eu", "orders.eu"},
+		{3, "orders", "orders"},
+	}
+
+	for _, tc := range cases {
+		c := &trafficCensusCmd{depth: tc.depth}
+		res := c.rollup(tc.subject)
+		if res != tc.expect {
+			t.Fatalf("depth %d of %s expected %s got %s", tc.depth, tc.subject, tc.expect, res)
+		}
+	}
+}
+
+func TestTrafficCensusReport(t *testing.T) {
+	sizes, err := newSizeHistogram([]int64{16, 64})
+	checkErr(t, err, "histogram failed: %v", err)
+
+	c := &trafficCensusCmd{subject: ">", depth: 1, sort: "messages", subjects: map[string]*trafficCensusEntry{}, sizes: sizes, started: time.Now().Add(-2 * time.Second)}
+
+	c.observe("orders.new", 10)
+	c.observe("orders.cancel", 20)
+	c.observe("billing.new", 100)
+
+	report := c.report(3)
+	if report.Messages != 3 || report.Bytes != 130 || report.Dropped != 3 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+
+	if report.Sizes == nil || report.Sizes.Buckets[0].Count != 1 || report.Sizes.Buckets[1].Count != 1 || report.Sizes.Buckets[2].Count != 1 {
+		t.Fatalf("unexpected sizes: %+v", report.Sizes)
+	}
+
+	if len(report.Subjects) != 2 {
+		t.Fatalf("expected 2 subjects got %d", len(report.Subjects))
+	}
+
+	if report.Subjects[0].Subject != "orders.>" || report.Subjects[0].Messages != 2 || report.Subjects[0].Bytes != 30 {
+		t.Fatalf("unexpected first entry: %+v", report.Subjects[0])
+	}
+
+	if report.Subjects[0].MessageRate <= 0 || report.Subjects[0].MessageRate > 1 {
+		t.Fatalf("unexpected rate %v", report.Subjects[0].MessageRate)
+	}
+
+	c.sort = "bytes"
+	report = c.report(0)
+	if report.Subjects[0].Subject != "billing.>" {
+		t.Fatalf("expected billing.> first when sorting by bytes got %s", report.Subjects[0].Subject)
+	}
+
+	entries := c.snapshot(time.Second)
+	if entries[0].MessageRate != 1 {
+		t.Fatalf("expected a rate of 1 got %v", entries[0].MessageRate)
+	}
+
+	entries = c.snapshot(time.Second)
+	if entries[0].MessageRate != 0 {
+		t.Fatalf("expected a rate of 0 got %v", entries[0].MessageRate)
+	}
+}
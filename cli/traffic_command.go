@@ -21,7 +21,6 @@ import (
 	"time"
 
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/nats.go"
 )
 
@@ -59,7 +58,7 @@ type rateTrackInt struct {
 }
 
 func (r *rateTrackInt) Comma() string  { return f(r.Rate()) }
-func (r *rateTrackInt) IBytes() string { return humanize.IBytes(uint64(r.Rate())) }
+func (r *rateTrackInt) IBytes() string { return fiBytes(uint64(r.Rate())) }
 func (r *rateTrackInt) Inc()           { r.IncN(1) }
 
 func (r *rateTrackInt) Value() int64 {
@@ -87,8 +86,13 @@ func (r *rateTrackInt) Rate() int64 {
 func configureTrafficCommand(app commandHost) {
 	c := &trafficCmd{}
 
-	traffic := app.Command("traffic", "Monitor NATS network traffic").Hidden().Action(c.monitor)
-	traffic.Arg("subjects", "Subjects to monitor, defaults to all").Default(">").StringVar(&c.subjects)
+	traffic := app.Command("traffic", "Monitor NATS network traffic")
+	addCheat("traffic", traffic)
+
+	configureTrafficCensusCommand(traffic)
+
+	classes := traffic.Command("classes", "Monitor internal NATS traffic by class").Hidden().Action(c.monitor)
+	classes.Arg("subjects", "Subjects to monitor, defaults to all").Default(">").StringVar(&c.subjects)
 }
 
 func init() {
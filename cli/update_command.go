@@ -0,0 +1,412 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/choria-io/fisk"
+)
+
+// updateReleasesURL is the GitHub API endpoint listing CLI releases
+var updateReleasesURL = "https://api.github.com/repos/nats-io/natscli/releases"
+
+const updateChecksumsFile = "SHA256SUMS"
+
+type updateCmd struct {
+	check      bool
+	draft      bool
+	prerelease bool
+	force      bool
+}
+
+type updateRelease struct {
+	Tag        string               `json:"tag_name"`
+	Draft      bool                 `json:"draft"`
+	Prerelease bool                 `json:"prerelease"`
+	Assets     []updateReleaseAsset `json:"assets"`
+}
+
+type updateReleaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+func configureUpdateCommand(app commandHost) {
+	c := &updateCmd{}
+
+	version := app.Command("version", "Shows the CLI version").Action(c.versionAction)
+	version.Flag("check", "Checks if a newer release is available").UnNegatableBoolVar(&c.check)
+	version.Flag("prerelease", "Consider pre-releases when checking").UnNegatableBoolVar(&c.prerelease)
+
+	update := app.Command("update", "Updates the CLI to the latest release").Action(c.updateAction)
+	update.Flag("prerelease", "Consider pre-releases when updating").UnNegatableBoolVar(&c.prerelease)
+	update.Flag("draft", "Consider draft releases when updating, requires a GITHUB_TOKEN with access").UnNegatableBoolVar(&c.draft)
+	update.Flag("force", "Update without prompting, even when already on the latest release").Short('f').UnNegatableBoolVar(&c.force)
+}
+
+func init() {
+	registerCommand("update", 20, configureUpdateCommand)
+}
+
+// currentCLIVersion is the version injected at build time, falling back to the module version when installed using go install
+func currentCLIVersion() string {
+	if Version != "development" {
+		return Version
+	}
+
+	nfo, ok := debug.ReadBuildInfo()
+	if !ok || nfo.Main.Version == "" || nfo.Main.Version == "(devel)" {
+		return Version
+	}
+
+	return nfo.Main.Version
+}
+
+func (c *updateCmd) versionAction(_ *fisk.ParseContext) error {
+	current := currentCLIVersion()
+	fmt.Println(current)
+
+	if !c.check {
+		return nil
+	}
+
+	release, err := c.latestRelease()
+	if err != nil {
+		logWarnf("Could not check for updates: %v", err)
+		return nil
+	}
+
+	switch {
+	case current == "development":
+		fmt.Printf("Development build, the latest release is %s\n", release.Tag)
+	case compareVersions(release.Tag, current) > 0:
+		fmt.Printf("Update available: %s, use 'nats update' to install it\n", release.Tag)
+	default:
+		fmt.Println("Up to date")
+	}
+
+	return nil
+}
+
+func (c *updateCmd) updateAction(_ *fisk.ParseContext) error {
+	current := currentCLIVersion()
+
+	release, err := c.latestRelease()
+	if err != nil {
+		return fmt.Errorf("could not determine the latest release: %w", err)
+	}
+
+	if !c.force && current != "development" && compareVersions(release.Tag, current) <= 0 {
+		fmt.Printf("Already on the latest release %s\n", current)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+
+	if !c.force {
+		ok, err := askConfirmation(fmt.Sprintf("Update %s from %s to %s", exe, current, release.Tag), false)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return nil
+		}
+	}
+
+	assetName := updateAssetName(strings.TrimPrefix(release.Tag, "v"), runtime.GOOS, runtime.GOARCH, updateGoArm())
+	asset := release.asset(assetName)
+	if asset == nil {
+		return fmt.Errorf("release %s has no asset %s for this platform", release.Tag, assetName)
+	}
+
+	sums := release.asset(updateChecksumsFile)
+	if sums == nil {
+		return fmt.Errorf("release %s does not publish a %s file", release.Tag, updateChecksumsFile)
+	}
+
+	sumsData, err := c.download(sums.URL)
+	if err != nil {
+		return err
+	}
+
+	expected, err := updateChecksum(sumsData, assetName)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Downloading %s", asset.URL)
+	archive, err := c.download(asset.URL)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(archive)
+	if hex.EncodeToString(sum[:]) != expected {
+		return fmt.Errorf("checksum mismatch for %s", assetName)
+	}
+
+	binary, err := updateExtractBinary(archive)
+	if err != nil {
+		return err
+	}
+
+	err = updateReplaceExecutable(exe, binary)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %s to %s\n", exe, release.Tag)
+
+	return nil
+}
+
+func (c *updateCmd) get(url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+
+	return resp, nil
+}
+
+func (c *updateCmd) download(url string) ([]byte, error) {
+	resp, err := c.get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// latestRelease finds the newest release honoring the draft and prerelease settings
+func (c *updateCmd) latestRelease() (*updateRelease, error) {
+	resp, err := c.get(updateReleasesURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var releases []*updateRelease
+	err = json.NewDecoder(resp.Body).Decode(&releases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid releases response: %w", err)
+	}
+
+	return selectRelease(releases, c.draft, c.prerelease)
+}
+
+func selectRelease(releases []*updateRelease, draft bool, prerelease bool) (*updateRelease, error) {
+	var latest *updateRelease
+
+	for _, r := range releases {
+		if (r.Draft && !draft) || (r.Prerelease && !prerelease) {
+			continue
+		}
+
+		if latest == nil || compareVersions(r.Tag, latest.Tag) > 0 {
+			latest = r
+		}
+	}
+
+	if latest == nil {
+		return nil, fmt.Errorf("no releases found")
+	}
+
+	return latest, nil
+}
+
+func (r *updateRelease) asset(name string) *updateReleaseAsset {
+	for i, a := range r.Assets {
+		if a.Name == name {
+			return &r.Assets[i]
+		}
+	}
+
+	return nil
+}
+
+// compareVersions compares semantic versions a and b, a release sorts after its pre-releases
+func compareVersions(a string, b string) int {
+	a = strings.TrimPrefix(a, "v")
+	b = strings.TrimPrefix(b, "v")
+
+	amaj, amin, apatch, _ := versionComponents(a)
+	bmaj, bmin, bpatch, _ := versionComponents(b)
+
+	for _, d := range []int{amaj - bmaj, amin - bmin, apatch - bpatch} {
+		if d != 0 {
+			return d
+		}
+	}
+
+	apre := strings.Contains(a, "-")
+	bpre := strings.Contains(b, "-")
+	switch {
+	case apre && !bpre:
+		return -1
+	case !apre && bpre:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// updateGoArm determines the ARM version this binary was built for
+func updateGoArm() string {
+	if runtime.GOARCH != "arm" {
+		return ""
+	}
+
+	nfo, ok := debug.ReadBuildInfo()
+	if ok {
+		for _, s := range nfo.Settings {
+			if s.Key == "GOARM" && s.Value != "" {
+				return s.Value
+			}
+		}
+	}
+
+	return "7"
+}
+
+// updateAssetName is the release archive name as produced by the release tooling
+func updateAssetName(version string, goos string, goarch string, goarm string) string {
+	return fmt.Sprintf("nats-%s-%s-%s%s.zip", version, goos, goarch, goarm)
+}
+
+// updateChecksum finds the checksum for file in a sha256sum formatted list
+func updateChecksum(sums []byte, file string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 2 && strings.TrimPrefix(parts[1], "*") == file {
+			return strings.ToLower(parts[0]), nil
+		}
+	}
+
+	return "", fmt.Errorf("no checksum found for %s", file)
+}
+
+// updateExtractBinary finds the nats executable inside a release archive
+func updateExtractBinary(archive []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range zr.File {
+		name := filepath.Base(f.Name)
+		if f.FileInfo().IsDir() || (name != "nats" && name != "nats.exe") {
+			continue
+		}
+
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		return io.ReadAll(r)
+	}
+
+	return nil, fmt.Errorf("no nats executable found in the release archive")
+}
+
+// updateReplaceExecutable atomically replaces target with binary by writing a temporary file next to it and renaming it into place
+func updateReplaceExecutable(target string, binary []byte) error {
+	dir := filepath.Dir(target)
+
+	tmp, err := os.CreateTemp(dir, ".nats-update-*")
+	if err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return fmt.Errorf("cannot write to %s, re-run the update as a user that can modify %s: %w", dir, target, err)
+		}
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(binary)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), 0755)
+	if err != nil {
+		return err
+	}
+
+	// windows does not allow replacing a running executable but does allow renaming it
+	if runtime.GOOS == "windows" {
+		old := target + ".old"
+		os.Remove(old)
+		err = os.Rename(target, old)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = os.Rename(tmp.Name(), target)
+	if errors.Is(err, os.ErrPermission) {
+		return fmt.Errorf("cannot replace %s, re-run the update as a user that can modify it: %w", target, err)
+	}
+
+	return err
+}
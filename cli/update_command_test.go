@@ -0,0 +1,149 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b   string
+		expect int
+	}{
+		{"v0.1.5", "v0.1.4", 1},
+		{"v0.1.4", "0.1.4", 0},
+		{"v0.2.0", "v0.10.0", -1},
+		{"v1.0.0", "v1.0.0-beta.1", 1},
+		{"v1.0.0-beta.1", "v1.0.0", -1},
+		{"v1.0.0-beta.2", "v1.0.0-beta.1", 1},
+	}
+
+	for _, tc := range cases {
+		res := compareVersions(tc.a, tc.b)
+		if (res > 0 && tc.expect <= 0) || (res < 0 && tc.expect >= 0) || (res == 0 && tc.expect != 0) {
+			t.Fatalf("compare %s to %s got %d expected sign of %d", tc.a, tc.b, res, tc.expect)
+		}
+	}
+}
+
+func TestSelectRelease(t *testing.T) {
+	releases := []*updateRelease{
+		{Tag: "v0.1.4"},
+		{Tag: "v0.1.6", Draft: true},
+		{Tag: "v0.1.5"},
+		{Tag: "v0.2.0-beta.1", Prerelease: true},
+	}
+
+	cases := []struct {
+		draft, pre bool
+		expect     string
+	}{
+		{false, false, "v0.1.5"},
+		{false, true, "v0.2.0-beta.1"},
+		{true, false, "v0.1.6"},
+	}
+
+	for _, tc := range cases {
+		r, err := selectRelease(releases, tc.draft, tc.pre)
+		if err != nil {
+			t.Fatalf("select failed: %v", err)
+		}
+		if r.Tag != tc.expect {
+			t.Fatalf("expected %s got %s", tc.expect, r.Tag)
+		}
+	}
+
+	_, err := selectRelease([]*updateRelease{{Tag: "v1.0.0", Draft: true}}, false, false)
+	if err == nil {
+		t.Fatalf("expected an error when no releases match")
+	}
+}
+
+func TestUpdateChecksum(t *testing.T) {
+	sums := []byte("abc123  nats-0.1.5-linux-amd64.zip\nDEF456 *nats-0.1.5-linux-arm7.zip\n")
+
+	sum, err := updateChecksum(sums, "nats-0.1.5-linux-arm7.zip")
+	if err != nil {
+		t.Fatalf("checksum failed: %v", err)
+	}
+	if sum != "def456" {
+		t.Fatalf("expected def456 got %s", sum)
+	}
+
+	_, err = updateChecksum(sums, "nats-0.1.5-darwin-arm64.zip")
+	if err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+
+	name := updateAssetName("0.1.5", "linux", "arm", "7")
+	if name != "nats-0.1.5-linux-arm7.zip" {
+		t.Fatalf("unexpected asset name %s", name)
+	}
+}
+
+func TestUpdateExtractAndReplace(t *testing.T) {
+	buf := bytes.Buffer{}
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("nats-0.1.5-linux-amd64/nats")
+	if err != nil {
+		t.Fatalf("zip failed: %v", err)
+	}
+	w.Write([]byte("new binary"))
+	zw.Close()
+
+	binary, err := updateExtractBinary(buf.Bytes())
+	if err != nil {
+		t.Fatalf("extract failed: %v", err)
+	}
+
+	target := filepath.Join(t.TempDir(), "nats")
+	err = os.WriteFile(target, []byte("old binary"), 0755)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	err = updateReplaceExecutable(target, binary)
+	if err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if string(data) != "new binary" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	if runtime.GOOS == "windows" || os.Getuid() == 0 {
+		return
+	}
+
+	dir := t.TempDir()
+	target = filepath.Join(dir, "nats")
+	os.WriteFile(target, []byte("old binary"), 0755)
+	os.Chmod(dir, 0555)
+	defer os.Chmod(dir, 0755)
+
+	err = updateReplaceExecutable(target, binary)
+	if err == nil || !bytes.Contains([]byte(err.Error()), []byte("cannot write to")) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
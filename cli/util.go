@@ -38,11 +38,11 @@ import (
 
 	"github.com/nats-io/natscli/options"
 
+	"github.com/nats-io/natscli/internal/subjectmap"
 	iu "github.com/nats-io/natscli/internal/util"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/choria-io/fisk"
-	"github.com/dustin/go-humanize"
 	"github.com/google/shlex"
 	"github.com/gosuri/uiprogress"
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -294,6 +294,17 @@ func splitCLISubjects(subjects []string) []string {
 	return new
 }
 
+// showSubjectMapTest shows what subject maps to for the --subject-map-test dry run
+func showSubjectMapTest(maps *subjectmap.Mapper, subject string) {
+	mapped, used := maps.Map(subject)
+	if used == nil {
+		fmt.Printf("%s does not match any subject map\n", subject)
+		return
+	}
+
+	fmt.Printf("%s maps to %s using %s\n", subject, mapped, used)
+}
+
 func natsOpts() []nats.Option {
 	if opts().Config == nil {
 		return []nats.Option{}
@@ -313,37 +324,61 @@ func natsOpts() []nats.Option {
 		nats.ConnectHandler(func(conn *nats.Conn) {
 			if opts().Trace {
 				log.Printf(">>> Connected to %s", conn.ConnectedUrlRedacted())
+			} else {
+				logDebugf("Connected to %s", conn.ConnectedUrlRedacted())
 			}
 		}),
 		nats.DiscoveredServersHandler(func(conn *nats.Conn) {
 			if opts().Trace {
 				log.Printf(">>> Discovered new servers, known servers are now %s", strings.Join(conn.Servers(), ", "))
+			} else {
+				logDebugf("Discovered new servers, known servers are now %s", strings.Join(conn.Servers(), ", "))
 			}
 		}),
 		nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
 			if err != nil {
-				log.Printf("Disconnected due to: %s, will attempt reconnect", err)
+				logWarnf("Disconnected due to: %s, will attempt reconnect", err)
 			}
 		}),
 		nats.ReconnectHandler(func(nc *nats.Conn) {
-			log.Printf("Reconnected [%s]", nc.ConnectedUrl())
+			log.Printf("Reconnected [%s]", nc.ConnectedUrlRedacted())
 		}),
 		nats.ErrorHandler(func(nc *nats.Conn, _ *nats.Subscription, err error) {
 			url := nc.ConnectedUrl()
 			if url == "" {
-				log.Printf("Unexpected NATS error: %s", err)
+				logErrorf("Unexpected NATS error: %s", err)
 			} else {
-				log.Printf("Unexpected NATS error from server %s: %s", nc.ConnectedUrlRedacted(), err)
+				logErrorf("Unexpected NATS error from server %s: %s", nc.ConnectedUrlRedacted(), err)
 			}
 		}),
 	}...)
 }
 
+// jsTarget is the JetStream domain and API prefix to use, from the loaded context when there is one else from the flags
+func jsTarget() (domain string, prefix string) {
+	opts := opts()
+	if opts.Config != nil {
+		return opts.Config.JSDomain(), opts.Config.JSAPIPrefix()
+	}
+
+	return opts.JsDomain, opts.JsApiPrefix
+}
+
+// validateJSTarget ensures that only one of a JetStream domain or API prefix is set
+func validateJSTarget(domain string, prefix string) error {
+	if domain == "" || prefix == "" {
+		return nil
+	}
+
+	return fmt.Errorf("cannot use both JetStream domain %q and API prefix %q: a domain accesses the JetStream API using the prefix $JS.%s.API while an API prefix is for JetStream APIs imported from another account", domain, prefix, domain)
+}
+
 func jsOpts() []nats.JSOpt {
 	opts := opts()
+	domain, prefix := jsTarget()
 	jso := []nats.JSOpt{
-		nats.Domain(opts.JsDomain),
-		nats.APIPrefix(opts.JsApiPrefix),
+		nats.Domain(domain),
+		nats.APIPrefix(prefix),
 		nats.MaxWait(opts.Timeout),
 	}
 
@@ -390,7 +425,9 @@ func newNatsConnUnlocked(servers string, copts ...nats.Option) (*nats.Conn, erro
 
 	var err error
 
-	opts.Conn, err = nats.Connect(servers, copts...)
+	trace := newConnectTrace(opts.ConnectDebug)
+	opts.Conn, err = nats.Connect(servers, trace.options(servers, copts)...)
+	trace.finish(opts.Conn, err)
 
 	return opts.Conn, err
 }
@@ -435,6 +472,50 @@ func prepareHelper(servers string, copts ...nats.Option) (*nats.Conn, *jsm.Manag
 	return prepareHelperUnlocked(servers, copts...)
 }
 
+// prepareHelperForContext connects using a named context rather than the one selected for the command, the caller
+// should close the connection, copts are added to the options of the context
+func prepareHelperForContext(name string, copts ...nats.Option) (*nats.Conn, *jsm.Manager, error) {
+	cfg, err := natscontext.New(name, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctxopts, err := cfg.NATSOptions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctxopts = append(ctxopts, nats.Name("NATS CLI Version "+Version))
+	trace := newConnectTrace(opts().ConnectDebug)
+	nc, err := nats.Connect(cfg.ServerURL(), trace.options(cfg.ServerURL(), append(ctxopts, copts...))...)
+	trace.finish(nc, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jsopts := []jsm.Option{
+		jsm.WithAPIPrefix(cfg.JSAPIPrefix()),
+		jsm.WithEventPrefix(cfg.JSEventPrefix()),
+		jsm.WithDomain(cfg.JSDomain()),
+	}
+
+	if opts().Timeout != 0 {
+		jsopts = append(jsopts, jsm.WithTimeout(opts().Timeout))
+	}
+
+	if opts().Trace || logDebugEnabled() {
+		jsopts = append(jsopts, jsm.WithTrace())
+	}
+
+	mgr, err := jsm.New(nc, jsopts...)
+	if err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+
+	return nc, mgr, nil
+}
+
 func validator() *SchemaValidator {
 	if os.Getenv("NOVALIDATE") == "" {
 		return new(SchemaValidator)
@@ -484,7 +565,7 @@ func prepareHelperUnlocked(servers string, copts ...nats.Option) (*nats.Conn, *j
 		jsopts = append(jsopts, jsm.WithTimeout(opts.Timeout))
 	}
 
-	if opts.Trace {
+	if opts.Trace || logDebugEnabled() {
 		jsopts = append(jsopts, jsm.WithTrace())
 	}
 
@@ -568,6 +649,7 @@ func readMIMEHeader(tp *textproto.Reader) (textproto.MIMEHeader, error) {
 type pubData struct {
 	Cnt       int
 	Count     int
+	Seq       int
 	Unix      int64
 	UnixNano  int64
 	TimeStamp string
@@ -580,11 +662,17 @@ func (p *pubData) ID() string {
 }
 
 func pubReplyBodyTemplate(body string, request string, ctr int) ([]byte, error) {
+	return pubReplyBodyTemplateWithData(body, request, ctr, nil)
+}
+
+// pubReplyBodyTemplateWithData renders body like pubReplyBodyTemplate using data as the template data instead of the message details
+func pubReplyBodyTemplateWithData(body string, request string, ctr int, data any) ([]byte, error) {
 	now := time.Now()
 	funcMap := template.FuncMap{
 		"Random":    randomString,
 		"Count":     func() int { return ctr },
 		"Cnt":       func() int { return ctr },
+		"Seq":       func() int { return ctr },
 		"Unix":      func() int64 { return now.Unix() },
 		"UnixNano":  func() int64 { return now.UnixNano() },
 		"TimeStamp": func() string { return now.Format(time.RFC3339) },
@@ -601,16 +689,21 @@ func pubReplyBodyTemplate(body string, request string, ctr int) ([]byte, error)
 		return []byte(body), err
 	}
 
+	if data == nil {
+		data = &pubData{
+			Cnt:       ctr,
+			Count:     ctr,
+			Seq:       ctr,
+			Unix:      now.Unix(),
+			UnixNano:  now.UnixNano(),
+			TimeStamp: now.Format(time.RFC3339),
+			Time:      now.Format(time.Kitchen),
+			Request:   request,
+		}
+	}
+
 	var b bytes.Buffer
-	err = templ.Execute(&b, &pubData{
-		Cnt:       ctr,
-		Count:     ctr,
-		Unix:      now.Unix(),
-		UnixNano:  now.UnixNano(),
-		TimeStamp: now.Format(time.RFC3339),
-		Time:      now.Format(time.Kitchen),
-		Request:   request,
-	})
+	err = templ.Execute(&b, data)
 	if err != nil {
 		return []byte(body), err
 	}
@@ -683,7 +776,7 @@ func parseStringsToMsgHeader(hdrs []string, seq int, msg *nats.Msg) error {
 
 		val, err := pubReplyBodyTemplate(strings.TrimSpace(parts[1]), "", seq)
 		if err != nil {
-			log.Printf("Failed to parse Header template for %s: %s", parts[0], err)
+			logErrorf("Failed to parse Header template for %s: %s", parts[0], err)
 			continue
 		}
 
@@ -739,6 +832,10 @@ func loadContext(softFail bool) error {
 		opts.Config, err = natscontext.New(opts.CfgCtx, false, ctxOpts...)
 	}
 
+	if err == nil {
+		err = validateJSTarget(opts.Config.JSDomain(), opts.Config.JSAPIPrefix())
+	}
+
 	return err
 }
 
@@ -945,13 +1042,42 @@ func doReq(req any, subj string, waitFor int, nc *nats.Conn) ([][]byte, error) {
 	return res, err
 }
 
+// pubSubRoundTrip publishes a message to subj and waits for it to be received on the same connection
+func pubSubRoundTrip(nc *nats.Conn, subj string, timeout time.Duration) error {
+	sub, err := nc.SubscribeSync(subj)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	err = nc.Publish(subj, []byte("round trip check"))
+	if err != nil {
+		return err
+	}
+
+	err = nc.FlushTimeout(timeout)
+	if err != nil {
+		return err
+	}
+
+	_, err = sub.NextMsg(timeout)
+	if err != nil {
+		if lerr := nc.LastError(); lerr != nil {
+			return lerr
+		}
+		return err
+	}
+
+	return nil
+}
+
 type raftLeader struct {
 	name    string
 	cluster string
 	groups  int
 }
 
-func renderRaftLeaders(leaders map[string]*raftLeader, grpTitle string) {
+func renderRaftLeaders(out io.Writer, leaders map[string]*raftLeader, grpTitle string) {
 	table := newTableWriter("RAFT Leader Report")
 	table.AddHeaders("Server", "Cluster", grpTitle, "Distribution")
 
@@ -992,7 +1118,7 @@ func renderRaftLeaders(leaders map[string]*raftLeader, grpTitle string) {
 		}
 		table.AddRow(l.name, l.cluster, f(l.groups), strings.Repeat("*", dots))
 	}
-	fmt.Println(table.Render())
+	fmt.Fprintln(out, table.Render())
 }
 
 func compactStrings(source []string) []string {
@@ -1151,6 +1277,76 @@ func parseStringAsBytes(s string) (int64, error) {
 	return num, nil
 }
 
+// timeZoneAbbreviations are the commonly used zone abbreviations, Go only knows the abbreviations of the local zone
+var timeZoneAbbreviations = map[string]int{
+	"UTC": 0, "GMT": 0, "Z": 0, "WET": 0, "WEST": 1, "BST": 1, "CET": 1, "CEST": 2, "EET": 2, "EEST": 3, "MSK": 3,
+	"JST": 9, "AEST": 10, "AEDT": 11, "EST": -5, "EDT": -4, "CST": -6, "CDT": -5, "MST": -7, "MDT": -6, "PST": -8, "PDT": -7,
+}
+
+var timeStringLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// parseTimeString parses RFC3339 times, dates like 2006-01-02 15:04 optionally followed by a zone abbreviation, offset
+// or location name, and durations which are taken to be that long before now. Times without a zone are local time.
+func parseTimeString(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("time not given")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err == nil {
+		return t, nil
+	}
+
+	dur, err := fisk.ParseDuration(s)
+	if err == nil {
+		return now.Add(-dur), nil
+	}
+
+	loc := time.Local
+	value := s
+	if i := strings.LastIndex(s, " "); i > 0 {
+		zl, ok := parseTimeZone(s[i+1:])
+		if ok {
+			loc = zl
+			value = strings.TrimSpace(s[:i])
+		}
+	}
+
+	for _, layout := range timeStringLayouts {
+		t, err = time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, use RFC3339, a date like 2006-01-02 15:04 optionally followed by a time zone or a duration", s)
+}
+
+func parseTimeZone(zone string) (*time.Location, bool) {
+	offset, ok := timeZoneAbbreviations[strings.ToUpper(zone)]
+	if ok {
+		return time.FixedZone(strings.ToUpper(zone), offset*60*60), true
+	}
+
+	for _, layout := range []string{"-07:00", "-0700", "-07"} {
+		t, err := time.Parse(layout, zone)
+		if err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(zone, offset), true
+		}
+	}
+
+	if strings.Contains(zone, "/") {
+		loc, err := time.LoadLocation(zone)
+		if err == nil {
+			return loc, true
+		}
+	}
+
+	return nil, false
+}
+
 var semVerRe = regexp.MustCompile(`\Av?([0-9]+)\.?([0-9]+)?\.?([0-9]+)?`)
 
 func versionComponents(version string) (major, minor, patch int, err error) {
@@ -1182,19 +1378,19 @@ func serverMinVersion(version string, major, minor, patch int) bool {
 	return true
 }
 
-func outPutMSGBodyCompact(data []byte, filter string, subject string, stream string) (string, error) {
+func outPutMSGBodyCompact(data []byte, transform *payloadTransformPipeline, subject string, stream string) (string, error) {
 	if len(data) == 0 {
 		fmt.Println("nil body")
 		return "", nil
 	}
 
-	data, err := filterDataThroughCmd(data, filter, subject, stream)
+	out, err := transform.apply(data, subject, stream)
 	if err != nil {
 		// using q here so raw binary data will be escaped
 		fmt.Printf("%q\nError while translating msg body: %s\n\n", data, err.Error())
 		return "", err
 	}
-	output := string(data)
+	output := string(out)
 	if strings.HasSuffix(output, "\n") {
 		fmt.Print(output)
 	} else {
@@ -1204,8 +1400,8 @@ func outPutMSGBodyCompact(data []byte, filter string, subject string, stream str
 	return output, nil
 }
 
-func outPutMSGBody(data []byte, filter string, subject string, stream string) {
-	output, err := outPutMSGBodyCompact(data, filter, subject, stream)
+func outPutMSGBody(data []byte, transform *payloadTransformPipeline, subject string, stream string) {
+	output, err := outPutMSGBodyCompact(data, transform, subject, stream)
 	if err != nil {
 		return
 	}
@@ -1217,6 +1413,64 @@ func outPutMSGBody(data []byte, filter string, subject string, stream string) {
 	}
 }
 
+// statusDescriptions describe the codes of status messages that arrive without a Description header
+var statusDescriptions = map[string]string{
+	"100": "Idle Heartbeat",
+	"404": "No Messages",
+	"408": "Request Timeout",
+	"409": "Conflict",
+	"503": "No Responders",
+}
+
+// msgStatus is the code and description of a status message sent by the server, status messages have a Status header
+// and no body, the code is empty for all other messages
+func msgStatus(msg *nats.Msg) (string, string) {
+	if msg == nil || len(msg.Data) > 0 || msg.Header == nil {
+		return "", ""
+	}
+
+	code := msg.Header.Get("Status")
+	if code == "" {
+		return "", ""
+	}
+
+	desc := msg.Header.Get("Description")
+	if desc == "" {
+		desc = statusDescriptions[code]
+	}
+	if desc == "" {
+		desc = "Unknown Status"
+	}
+
+	return code, desc
+}
+
+// statusMsgLine describes a status message received as a reply to subject
+func statusMsgLine(msg *nats.Msg, subject string) string {
+	code, desc := msgStatus(msg)
+	if code == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("Status %s: %s on subject %s", code, desc, subject)
+}
+
+// statusMsgError is the error a status message received as a reply to subject represents, nil for other messages
+func statusMsgError(msg *nats.Msg, subject string) error {
+	code, desc := msgStatus(msg)
+
+	switch code {
+	case "":
+		return nil
+	case "503":
+		return fmt.Errorf("no responders available for subject %s: %w", subject, nats.ErrNoResponders)
+	case "408":
+		return fmt.Errorf("%s on subject %s: %w", strings.ToLower(desc), subject, nats.ErrTimeout)
+	default:
+		return fmt.Errorf("status %s: %s on subject %s", code, desc, subject)
+	}
+}
+
 func filterDataThroughCmd(data []byte, filter, subject, stream string) ([]byte, error) {
 	if filter == "" {
 		return data, nil
@@ -1308,9 +1562,9 @@ func barGraph(w io.Writer, data map[string]float64, caption string, width int, b
 
 		var h string
 		if bytes {
-			h = humanize.IBytes(uint64(v))
+			h = fiBytes(uint64(v))
 		} else {
-			h = humanize.Commaf(v)
+			h = f(v)
 		}
 
 		bar := strings.Repeat("█", blocks)
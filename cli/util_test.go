@@ -16,10 +16,14 @@ package cli
 import (
 	"errors"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/nats-io/jsm.go/api"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/natscli/options"
 )
 
 func checkErr(t *testing.T, err error, format string, a ...any) {
@@ -108,6 +112,48 @@ func TestParseStringAsBytes(t *testing.T) {
 	}
 }
 
+func TestParseTimeString(t *testing.T) {
+	now := time.Date(2023, 4, 4, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		input  string
+		expect time.Time
+		error  bool
+	}{
+		{input: "2023-04-01T02:00:00Z", expect: time.Date(2023, 4, 1, 2, 0, 0, 0, time.UTC)},
+		{input: "2023-04-01T02:00:00.5+02:00", expect: time.Date(2023, 4, 1, 0, 0, 0, 500000000, time.UTC)},
+		{input: "2023-04-01 02:00 CET", expect: time.Date(2023, 4, 1, 1, 0, 0, 0, time.UTC)},
+		{input: "2023-04-01 02:00:30 cest", expect: time.Date(2023, 4, 1, 0, 0, 30, 0, time.UTC)},
+		{input: "2023-04-01 02:00 PDT", expect: time.Date(2023, 4, 1, 9, 0, 0, 0, time.UTC)},
+		{input: "2023-04-01 02:00 -0500", expect: time.Date(2023, 4, 1, 7, 0, 0, 0, time.UTC)},
+		{input: "2023-04-01T02:00 +05:30", expect: time.Date(2023, 3, 31, 20, 30, 0, 0, time.UTC)},
+		{input: "2023-04-01 UTC", expect: time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2023-04-01 02:00", expect: time.Date(2023, 4, 1, 2, 0, 0, 0, time.Local)},
+		{input: "2h", expect: now.Add(-2 * time.Hour)},
+		{input: "1d", expect: now.Add(-24 * time.Hour)},
+		{input: "", error: true},
+		{input: "yesterday", error: true},
+		{input: "2023-04-01 02:00 XYZ", error: true},
+	}
+
+	for _, c := range cases {
+		ts, err := parseTimeString(c.input, now)
+		if c.error {
+			if err == nil {
+				t.Fatalf("expected an error parsing %q got %v", c.input, ts)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("did not expect an error parsing %q: %v", c.input, err)
+		}
+		if !ts.Equal(c.expect) {
+			t.Fatalf("expected %q to parse as %v got %v", c.input, c.expect, ts)
+		}
+	}
+}
+
 func TestSplitString(t *testing.T) {
 	for _, s := range []string{"x y", "x	y", "x  y", "x,y", "x, y"} {
 		parts := splitString(s)
@@ -221,3 +267,74 @@ func TestMultipleSort(t *testing.T) {
 		t.Fatalf("expected true")
 	}
 }
+
+func TestJSTarget(t *testing.T) {
+	SkipContexts = true
+	defer func() { SkipContexts = false }()
+
+	err := validateJSTarget("hub", "")
+	checkErr(t, err, "domain only failed: %v", err)
+	err = validateJSTarget("", "$JS.other.API")
+	checkErr(t, err, "prefix only failed: %v", err)
+
+	err = validateJSTarget("hub", "$JS.other.API")
+	if err == nil || !strings.Contains(err.Error(), "$JS.hub.API") {
+		t.Fatalf("expected an explanatory error got %v", err)
+	}
+
+	options.DefaultOptions = &options.Options{JsDomain: "hub"}
+	err = loadContext(false)
+	checkErr(t, err, "load failed: %v", err)
+
+	domain, prefix := jsTarget()
+	if domain != "hub" || prefix != "" {
+		t.Fatalf("unexpected target %q %q", domain, prefix)
+	}
+
+	options.DefaultOptions = &options.Options{JsDomain: "hub", JsApiPrefix: "$JS.other.API"}
+	err = loadContext(false)
+	if err == nil || !strings.Contains(err.Error(), "cannot use both") {
+		t.Fatalf("expected an error got %v", err)
+	}
+}
+
+func TestStatusMsgError(t *testing.T) {
+	msg := func(code string, desc string, body string) *nats.Msg {
+		m := nats.NewMsg("reply")
+		m.Data = []byte(body)
+		if code != "" {
+			m.Header.Set("Status", code)
+		}
+		if desc != "" {
+			m.Header.Set("Description", desc)
+		}
+		return m
+	}
+
+	if err := statusMsgError(msg("", "", "hello"), "x.y"); err != nil {
+		t.Fatalf("expected no error for a normal message: %v", err)
+	}
+	if err := statusMsgError(msg("408", "", "hello"), "x.y"); err != nil {
+		t.Fatalf("expected no error for a message with a body: %v", err)
+	}
+
+	err := statusMsgError(msg("503", "", ""), "x.y")
+	if !errors.Is(err, nats.ErrNoResponders) || err.Error() != "no responders available for subject x.y: nats: no responders available for request" {
+		t.Fatalf("unexpected 503 error: %v", err)
+	}
+
+	err = statusMsgError(msg("408", "", ""), "x.y")
+	if !errors.Is(err, nats.ErrTimeout) || !strings.HasPrefix(err.Error(), "request timeout on subject x.y") {
+		t.Fatalf("unexpected 408 error: %v", err)
+	}
+
+	err = statusMsgError(msg("409", "Exceeded MaxWaiting", ""), "x.y")
+	if err == nil || err.Error() != "status 409: Exceeded MaxWaiting on subject x.y" {
+		t.Fatalf("unexpected 409 error: %v", err)
+	}
+
+	line := statusMsgLine(msg("503", "", ""), "x.y")
+	if line != "Status 503: No Responders on subject x.y" {
+		t.Fatalf("unexpected status line: %q", line)
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"math"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -377,39 +378,62 @@ func utf8StringLen(s string) int {
 	return c
 }
 
+// F formats v for display, numbers get thousands separators and durations are humanized unless SetRawNumbers is enabled
 func F(v any) string {
+	raw := RawNumbers()
+
 	switch x := v.(type) {
 	case []string:
 		return strings.Join(x, ", ")
 	case time.Duration:
-		return HumanizeDuration(x)
+		return Duration(x)
 	case time.Time:
+		if raw {
+			return x.UTC().Format(time.RFC3339Nano)
+		}
 		return x.Local().Format("2006-01-02 15:04:05")
 	case bool:
 		return fmt.Sprintf("%t", x)
 	case uint:
-		return humanize.Comma(int64(x))
+		return formatInt(int64(x), raw)
 	case uint32:
-		return humanize.Comma(int64(x))
+		return formatInt(int64(x), raw)
 	case uint16:
-		return humanize.Comma(int64(x))
+		return formatInt(int64(x), raw)
 	case uint64:
+		if raw {
+			return strconv.FormatUint(x, 10)
+		}
 		return humanize.Comma(int64(x))
 	case int:
-		return humanize.Comma(int64(x))
+		return formatInt(int64(x), raw)
 	case int32:
-		return humanize.Comma(int64(x))
+		return formatInt(int64(x), raw)
 	case int64:
-		return humanize.Comma(x)
+		return formatInt(x, raw)
 	case float32:
+		if raw {
+			return strconv.FormatFloat(float64(x), 'f', -1, 32)
+		}
 		return humanize.CommafWithDigits(float64(x), 3)
 	case float64:
+		if raw {
+			return strconv.FormatFloat(x, 'f', -1, 64)
+		}
 		return humanize.CommafWithDigits(x, 3)
 	default:
 		return fmt.Sprintf("%v", x)
 	}
 }
 
+func formatInt(v int64, raw bool) string {
+	if raw {
+		return strconv.FormatInt(v, 10)
+	}
+
+	return humanize.Comma(v)
+}
+
 func HumanizeDuration(d time.Duration) string {
 	if d < time.Millisecond {
 		return d.Round(time.Microsecond).String()
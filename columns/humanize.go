@@ -0,0 +1,223 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package columns
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+var rawNumbers atomic.Bool
+
+// countUnits are the suffixes used by Count for every power of 1000
+var countUnits = []string{"K", "M", "G", "T", "P", "E"}
+
+// SetRawNumbers disables humanizing in all formatting functions, exact values are shown instead
+func SetRawNumbers(raw bool) {
+	rawNumbers.Store(raw)
+}
+
+// RawNumbers indicates if exact values are shown instead of humanized ones
+func RawNumbers() bool {
+	return rawNumbers.Load()
+}
+
+// IBytes formats a size in bytes using IEC units like 1.2 KiB
+func IBytes(v uint64) string {
+	if RawNumbers() {
+		return strconv.FormatUint(v, 10)
+	}
+
+	return humanize.IBytes(v)
+}
+
+// Count formats integers compactly like 1.2M, other values are formatted using F
+func Count(v any) string {
+	switch x := v.(type) {
+	case uint:
+		return countUnsigned(uint64(x))
+	case uint16:
+		return countUnsigned(uint64(x))
+	case uint32:
+		return countUnsigned(uint64(x))
+	case uint64:
+		return countUnsigned(x)
+	case int:
+		return countSigned(int64(x))
+	case int32:
+		return countSigned(int64(x))
+	case int64:
+		return countSigned(x)
+	default:
+		return F(v)
+	}
+}
+
+func countSigned(v int64) string {
+	if v >= 0 {
+		return countUnsigned(uint64(v))
+	}
+
+	// negating math.MinInt64 overflows, the cast to uint64 keeps its magnitude
+	return "-" + countUnsigned(uint64(-(v+1))+1)
+}
+
+func countUnsigned(v uint64) string {
+	if RawNumbers() {
+		return strconv.FormatUint(v, 10)
+	}
+
+	if v < 1000 {
+		return strconv.FormatUint(v, 10)
+	}
+
+	unit := uint64(1000)
+	for _, suffix := range countUnits {
+		// rounds half up to one decimal, calculated in parts to not overflow for large values
+		tenths := (v/unit)*10 + ((v%unit)*10+unit/2)/unit
+
+		if tenths < 10000 || suffix == countUnits[len(countUnits)-1] {
+			if tenths%10 == 0 {
+				return fmt.Sprintf("%d%s", tenths/10, suffix)
+			}
+
+			return fmt.Sprintf("%d.%d%s", tenths/10, tenths%10, suffix)
+		}
+
+		unit *= 1000
+	}
+
+	return strconv.FormatUint(v, 10)
+}
+
+// Duration formats d like 2d3h or 1.5s, units after the last non zero one are left out
+func Duration(d time.Duration) string {
+	if RawNumbers() {
+		return d.String()
+	}
+
+	switch {
+	case d == math.MaxInt64:
+		return "never"
+	case d == math.MinInt64:
+		return "-" + Duration(-(d + 1))
+	case d < 0:
+		return "-" + Duration(-d)
+	case d == 0:
+		return "0s"
+	case d < time.Microsecond:
+		return d.String()
+	case d < time.Millisecond:
+		return d.Round(time.Microsecond).String()
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	}
+
+	// rounding to 10ms can reach a full minute which is shown in minutes instead
+	d = d.Round(10 * time.Millisecond)
+	if d < time.Minute {
+		// formatted from whole hundredths as float seconds are not exact, 2.47s would show as 2.4699999999999998s
+		secs, hundredths := d/time.Second, d%time.Second/(10*time.Millisecond)
+		if hundredths == 0 {
+			return fmt.Sprintf("%ds", secs)
+		}
+
+		return strings.TrimRight(fmt.Sprintf("%d.%02d", secs, hundredths), "0") + "s"
+	}
+
+	secs := int64(d / time.Second)
+	parts := []struct {
+		value int64
+		unit  string
+	}{
+		{secs / (365 * 24 * 3600), "y"},
+		{secs / (24 * 3600) % 365, "d"},
+		{secs / 3600 % 24, "h"},
+		{secs / 60 % 60, "m"},
+		{secs % 60, "s"},
+	}
+
+	last := len(parts) - 1
+	for last > 0 && parts[last].value == 0 {
+		last--
+	}
+
+	var out strings.Builder
+	for _, p := range parts[:last+1] {
+		if out.Len() == 0 && p.value == 0 {
+			continue
+		}
+		fmt.Fprintf(&out, "%d%s", p.value, p.unit)
+	}
+
+	return out.String()
+}
+
+// Ago formats how long ago something happened given the time since, like 3m ago or 2d3h ago
+func Ago(d time.Duration) string {
+	if RawNumbers() {
+		return d.String() + " ago"
+	}
+
+	switch {
+	case d < 0:
+		return "in " + coarseDuration(-d)
+	case d < time.Second:
+		return "just now"
+	default:
+		return coarseDuration(d) + " ago"
+	}
+}
+
+// coarseDuration formats d using at most its two most significant whole units
+func coarseDuration(d time.Duration) string {
+	if d < time.Second {
+		return Duration(d)
+	}
+
+	units := []struct {
+		size time.Duration
+		unit string
+	}{
+		{365 * 24 * time.Hour, "y"},
+		{24 * time.Hour, "d"},
+		{time.Hour, "h"},
+		{time.Minute, "m"},
+		{time.Second, "s"},
+	}
+
+	for i, u := range units {
+		if d < u.size {
+			continue
+		}
+
+		out := fmt.Sprintf("%d%s", d/u.size, u.unit)
+		if i < len(units)-1 {
+			next := units[i+1]
+			if rest := (d % u.size) / next.size; rest > 0 {
+				out += fmt.Sprintf("%d%s", rest, next.unit)
+			}
+		}
+
+		return out
+	}
+
+	return Duration(d)
+}
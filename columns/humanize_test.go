@@ -0,0 +1,161 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package columns
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestIBytes(t *testing.T) {
+	for v, expected := range map[uint64]string{
+		0:                      "0 B",
+		1023:                   "1023 B",
+		1024:                   "1.0 KiB",
+		1536:                   "1.5 KiB",
+		1024*1024 - 1:          "1024 KiB",
+		1024 * 1024:            "1.0 MiB",
+		5 * 1024 * 1024 * 1024: "5.0 GiB",
+		math.MaxUint64:         "16 EiB",
+	} {
+		if got := IBytes(v); got != expected {
+			t.Fatalf("expected %d to be %q got %q", v, expected, got)
+		}
+	}
+}
+
+func TestCount(t *testing.T) {
+	for _, tc := range []struct {
+		v        any
+		expected string
+	}{
+		{0, "0"},
+		{999, "999"},
+		{1000, "1K"},
+		{1049, "1K"},
+		{1050, "1.1K"},
+		{1234, "1.2K"},
+		{999_949, "999.9K"},
+		{999_950, "1M"},
+		{1_234_567, "1.2M"},
+		{uint64(1_000_000_000), "1G"},
+		{uint32(4_294_967_295), "4.3G"},
+		{int64(-1500), "-1.5K"},
+		{int64(math.MinInt64), "-9.2E"},
+		{uint64(math.MaxUint64), "18.4E"},
+		{"text", "text"},
+	} {
+		if got := Count(tc.v); got != tc.expected {
+			t.Fatalf("expected %v to be %q got %q", tc.v, tc.expected, got)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	day := 24 * time.Hour
+
+	for _, tc := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "0s"},
+		{999 * time.Nanosecond, "999ns"},
+		{1499 * time.Nanosecond, "1µs"},
+		{999_499 * time.Nanosecond, "999µs"},
+		{time.Millisecond, "1ms"},
+		{1499 * time.Microsecond, "1ms"},
+		{999 * time.Millisecond, "999ms"},
+		{time.Second, "1s"},
+		{1500 * time.Millisecond, "1.5s"},
+		{1504 * time.Millisecond, "1.5s"},
+		{1505 * time.Millisecond, "1.51s"},
+		{2470 * time.Millisecond, "2.47s"},
+		{2500 * time.Millisecond, "2.5s"},
+		{10050 * time.Millisecond, "10.05s"},
+		{59_994 * time.Millisecond, "59.99s"},
+		{59_995 * time.Millisecond, "1m"},
+		{time.Minute, "1m"},
+		{61 * time.Second, "1m1s"},
+		{time.Hour, "1h"},
+		{time.Hour + 5*time.Second, "1h0m5s"},
+		{2*day + 3*time.Hour, "2d3h"},
+		{2*day + 3*time.Hour + 4*time.Minute + 5*time.Second, "2d3h4m5s"},
+		{365 * day, "1y"},
+		{365*day + 20*day, "1y20d"},
+		{-90 * time.Second, "-1m30s"},
+		{math.MaxInt64, "never"},
+	} {
+		if got := Duration(tc.d); got != tc.expected {
+			t.Fatalf("expected %d to be %q got %q", tc.d, tc.expected, got)
+		}
+	}
+}
+
+func TestAgo(t *testing.T) {
+	for _, tc := range []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "just now"},
+		{999 * time.Millisecond, "just now"},
+		{time.Second, "1s ago"},
+		{59 * time.Second, "59s ago"},
+		{3 * time.Minute, "3m ago"},
+		{3*time.Minute + 12*time.Second + 500*time.Millisecond, "3m12s ago"},
+		{time.Hour + 59*time.Second, "1h ago"},
+		{51*time.Hour + 30*time.Minute, "2d3h ago"},
+		{400 * 24 * time.Hour, "1y35d ago"},
+		{-5 * time.Minute, "in 5m"},
+		{-500 * time.Millisecond, "in 500ms"},
+	} {
+		if got := Ago(tc.d); got != tc.expected {
+			t.Fatalf("expected %v to be %q got %q", tc.d, tc.expected, got)
+		}
+	}
+}
+
+func TestRawNumbers(t *testing.T) {
+	SetRawNumbers(true)
+	defer SetRawNumbers(false)
+
+	ts := time.Date(2024, 6, 1, 10, 30, 0, 5, time.UTC)
+
+	for _, tc := range []struct {
+		got      string
+		expected string
+	}{
+		{IBytes(1536), "1536"},
+		{Count(1_234_567), "1234567"},
+		{Count(int64(-1500)), "-1500"},
+		{Duration(2*24*time.Hour + 3*time.Hour), "51h0m0s"},
+		{Duration(1500 * time.Millisecond), "1.5s"},
+		{Ago(3 * time.Minute), "3m0s ago"},
+		{F(uint64(1_234_567)), "1234567"},
+		{F(-1234), "-1234"},
+		{F(1234.5678), "1234.5678"},
+		{F(90 * time.Second), "1m30s"},
+		{F(ts), "2024-06-01T10:30:00.000000005Z"},
+	} {
+		if tc.got != tc.expected {
+			t.Fatalf("expected %q got %q", tc.expected, tc.got)
+		}
+	}
+
+	SetRawNumbers(false)
+
+	if got := F(uint64(1_234_567)); got != "1,234,567" {
+		t.Fatalf("expected humanized numbers after disabling raw numbers got %q", got)
+	}
+}
@@ -16,8 +16,8 @@ package audit
 import (
 	"errors"
 	"fmt"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/natscli/columns"
 	"github.com/nats-io/natscli/internal/archive"
 	"reflect"
 	"sort"
@@ -65,9 +65,9 @@ func createCheckClusterMemoryUsageOutliers(outlierThreshold float64) checkFunc {
 					examples.add(
 						"Cluster %s avg: %s, server %s: %s",
 						clusterName,
-						humanize.IBytes(uint64(clusterMemoryUsageMean)),
+						columns.IBytes(uint64(clusterMemoryUsageMean)),
 						serverName,
-						humanize.IBytes(uint64(serverMemoryUsage)),
+						columns.IBytes(uint64(serverMemoryUsage)),
 					)
 					clustersWithIssuesMap[clusterName] = nil
 				}
@@ -16,8 +16,8 @@ package audit
 import (
 	"errors"
 	"fmt"
-	"github.com/dustin/go-humanize"
 	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/natscli/columns"
 	"github.com/nats-io/natscli/internal/archive"
 )
 
@@ -202,8 +202,8 @@ func makeCheckServerResourceLimits(memoryUsageThreshold, storeUsageThreshold flo
 						examples.add(
 							"%s memory usage: %s of %s",
 							serverName,
-							humanize.IBytes(serverJSInfo.Memory),
-							humanize.IBytes(serverJSInfo.ReservedMemory),
+							columns.IBytes(serverJSInfo.Memory),
+							columns.IBytes(serverJSInfo.ReservedMemory),
 						)
 					}
 				}
@@ -214,8 +214,8 @@ func makeCheckServerResourceLimits(memoryUsageThreshold, storeUsageThreshold flo
 						examples.add(
 							"%s store usage: %s of %s",
 							serverName,
-							humanize.IBytes(serverJSInfo.Store),
-							humanize.IBytes(serverJSInfo.ReservedStore),
+							columns.IBytes(serverJSInfo.Store),
+							columns.IBytes(serverJSInfo.ReservedStore),
 						)
 					}
 				}
@@ -0,0 +1,222 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package subjectmap rewrites subjects using maps given as PATTERN=REPLACEMENT.
+//
+// Patterns are subjects where a * token captures a single token and a trailing > captures all remaining tokens, the
+// replacement refers to the captures in order as $1, $2 or ${1}:
+//
+//	orders.*.created=archive.orders.$1.created
+//	orders.>=archive.$1
+//
+// Patterns starting with ~ are regular expressions matching the entire subject, the replacement can refer to their
+// groups the same way:
+//
+//	~orders\.(eu|us)\.(.+)=regional.$1.$2
+package subjectmap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Map is a single subject map
+type Map struct {
+	spec        string
+	tokens      []string
+	re          *regexp.Regexp
+	replacement string
+	captures    int
+}
+
+// Mapper rewrites subjects using the first matching Map
+type Mapper struct {
+	maps []*Map
+}
+
+var replacementRef = regexp.MustCompile(`\$(\d+)|\$\{(\d+)\}`)
+
+// New parses all specs, the error names the first spec that could not be parsed
+func New(specs []string) (*Mapper, error) {
+	m := &Mapper{}
+
+	for _, spec := range specs {
+		sm, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		m.maps = append(m.maps, sm)
+	}
+
+	return m, nil
+}
+
+// Parse parses a single map given as PATTERN=REPLACEMENT
+func Parse(spec string) (*Map, error) {
+	pattern, replacement, ok := strings.Cut(spec, "=")
+	if !ok || pattern == "" || pattern == "~" || replacement == "" {
+		return nil, fmt.Errorf("invalid subject map %q, expected PATTERN=REPLACEMENT", spec)
+	}
+
+	m := &Map{spec: spec, replacement: replacement}
+
+	if expr, isRegex := strings.CutPrefix(pattern, "~"); isRegex {
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid subject map %q: %w", spec, err)
+		}
+
+		m.re = re
+		m.captures = re.NumSubexp()
+	} else {
+		m.tokens = strings.Split(pattern, ".")
+
+		for i, token := range m.tokens {
+			switch {
+			case token == "":
+				return nil, fmt.Errorf("invalid subject map %q: the pattern has an empty token", spec)
+			case strings.ContainsAny(token, " \t\r\n"):
+				return nil, fmt.Errorf("invalid subject map %q: the pattern can not contain white space", spec)
+			case token == ">" && i != len(m.tokens)-1:
+				return nil, fmt.Errorf("invalid subject map %q: > has to be the last token of the pattern", spec)
+			case token == "*" || token == ">":
+				m.captures++
+			}
+		}
+	}
+
+	err := m.validateReplacement()
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject map %q: %w", spec, err)
+	}
+
+	return m, nil
+}
+
+// validateReplacement makes sure the replacement only refers to existing captures and expands to a valid subject
+func (m *Map) validateReplacement() error {
+	for _, ref := range replacementRef.FindAllStringSubmatch(m.replacement, -1) {
+		idx := ref[1]
+		if idx == "" {
+			idx = ref[2]
+		}
+
+		n, _ := strconv.Atoi(idx)
+		if n < 1 || n > m.captures {
+			return fmt.Errorf("the replacement refers to $%d but the pattern has %d captures", n, m.captures)
+		}
+	}
+
+	for _, token := range strings.Split(m.replacement, ".") {
+		switch {
+		case token == "":
+			return fmt.Errorf("the replacement has an empty token")
+		case token == "*" || token == ">":
+			return fmt.Errorf("the replacement can not contain wildcards")
+		case strings.ContainsAny(token, " \t\r\n"):
+			return fmt.Errorf("the replacement can not contain white space")
+		}
+	}
+
+	return nil
+}
+
+// String is the map as it was given
+func (m *Map) String() string {
+	return m.spec
+}
+
+// Apply maps subject, it is false when the subject does not match the pattern
+func (m *Map) Apply(subject string) (string, bool) {
+	captures, ok := m.match(subject)
+	if !ok {
+		return "", false
+	}
+
+	return replacementRef.ReplaceAllStringFunc(m.replacement, func(ref string) string {
+		n, _ := strconv.Atoi(strings.Trim(ref, "${}"))
+		return captures[n-1]
+	}), true
+}
+
+func (m *Map) match(subject string) ([]string, bool) {
+	if m.re != nil {
+		groups := m.re.FindStringSubmatch(subject)
+		if groups == nil {
+			return nil, false
+		}
+
+		return groups[1:], true
+	}
+
+	tokens := strings.Split(subject, ".")
+	captures := make([]string, 0, m.captures)
+
+	for i, pt := range m.tokens {
+		if i >= len(tokens) || tokens[i] == "" {
+			return nil, false
+		}
+
+		switch pt {
+		case ">":
+			rest := tokens[i:]
+			for _, t := range rest {
+				if t == "" {
+					return nil, false
+				}
+			}
+			return append(captures, strings.Join(rest, ".")), true
+		case "*":
+			captures = append(captures, tokens[i])
+		default:
+			if pt != tokens[i] {
+				return nil, false
+			}
+		}
+	}
+
+	if len(tokens) != len(m.tokens) {
+		return nil, false
+	}
+
+	return captures, true
+}
+
+// Map rewrites subject using the first matching map, returning the map that was used or nil when none matched in
+// which case the subject is returned unchanged
+func (m *Mapper) Map(subject string) (string, *Map) {
+	if m == nil {
+		return subject, nil
+	}
+
+	for _, sm := range m.maps {
+		mapped, ok := sm.Apply(subject)
+		if ok {
+			return mapped, sm
+		}
+	}
+
+	return subject, nil
+}
+
+// Len is the number of maps
+func (m *Mapper) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.maps)
+}
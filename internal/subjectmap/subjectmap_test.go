@@ -0,0 +1,177 @@
+// Copyright 2024 The NATS Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package subjectmap
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{
+		"orders=archive.orders",
+		"orders.*.created=archive.orders.$1.created",
+		"orders.*.*=archive.$2.$1",
+		"orders.>=archive.$1",
+		"orders.*.>=archive.${1}x.$2",
+		"*=$1",
+		"ord*.created=archive.created",
+		`~orders\.(.*)=neworders.$1`,
+		`~a=b`,
+	} {
+		if _, err := Parse(spec); err != nil {
+			t.Fatalf("expected %q to parse: %v", spec, err)
+		}
+	}
+
+	for spec, expected := range map[string]string{
+		"orders":                        "expected PATTERN=REPLACEMENT",
+		"=archive":                      "expected PATTERN=REPLACEMENT",
+		"orders=":                       "expected PATTERN=REPLACEMENT",
+		"~=archive":                     "expected PATTERN=REPLACEMENT",
+		"orders..created=archive":       "empty token",
+		".orders=archive":               "empty token",
+		"orders.=archive":               "empty token",
+		"orders.>.created=archive":      "> has to be the last token",
+		"or ders=archive":               "white space",
+		"orders.*=archive.$2":           "refers to $2 but the pattern has 1 captures",
+		"orders=archive.$1":             "refers to $1 but the pattern has 0 captures",
+		"orders.*=archive.$0":           "refers to $0",
+		"orders.*=archive.${3}":         "refers to $3",
+		"orders.*=archive..$1":          "empty token",
+		"orders.*=archive.*":            "wildcards",
+		"orders.>=archive.>":            "wildcards",
+		"orders.*=archive.$1 x":         "white space",
+		`~orders\.(.*=archive.$1`:       "missing closing )",
+		`~orders\.(.*)=archive.$2`:      "refers to $2 but the pattern has 1 captures",
+		`~orders\.(a)(b)=archive.$1.$3`: "refers to $3 but the pattern has 2 captures",
+	} {
+		_, err := Parse(spec)
+		if err == nil {
+			t.Fatalf("expected %q to fail", spec)
+		}
+		if !strings.Contains(err.Error(), expected) || !strings.Contains(err.Error(), strconv.Quote(spec)) {
+			t.Fatalf("expected the error for %q to contain %q and the map got: %v", spec, expected, err)
+		}
+	}
+}
+
+func TestMapApply(t *testing.T) {
+	type test struct {
+		spec     string
+		subject  string
+		expected string
+		match    bool
+	}
+
+	for _, tc := range []test{
+		// literal tokens
+		{"orders=archive.orders", "orders", "archive.orders", true},
+		{"orders=archive.orders", "orders.new", "", false},
+		{"orders.new=archive", "orders", "", false},
+
+		// single token wildcards
+		{"orders.*.created=archive.orders.$1.created", "orders.eu.created", "archive.orders.eu.created", true},
+		{"orders.*.created=archive.orders.$1.created", "orders.eu.deleted", "", false},
+		{"orders.*.created=archive.orders.$1.created", "orders.created", "", false},
+		{"orders.*.created=archive.orders.$1.created", "orders.eu.x.created", "", false},
+		{"orders.*.created=archive.orders.$1.created", "orders.eu.created.x", "", false},
+		{"orders.*.*=archive.$2.$1", "orders.eu.created", "archive.created.eu", true},
+		{"orders.*.*=archive.$1.$1", "orders.eu.created", "archive.eu.eu", true},
+		{"*=prefix.$1", "orders", "prefix.orders", true},
+		{"*=prefix.$1", "orders.eu", "", false},
+		{"*.*=$2.$1", "a.b", "b.a", true},
+
+		// multi token wildcards need at least one token
+		{"orders.>=archive.$1", "orders.eu.created", "archive.eu.created", true},
+		{"orders.>=archive.$1", "orders.eu", "archive.eu", true},
+		{"orders.>=archive.$1", "orders", "", false},
+		{"orders.*.>=archive.$2.$1", "orders.eu.a.b", "archive.a.b.eu", true},
+		{"orders.*.>=archive.$2.$1", "orders.eu", "", false},
+		{">=all.$1", "a.b.c", "all.a.b.c", true},
+
+		// partial wildcards are literal tokens like in NATS
+		{"ord*.created=archive", "ord*.created", "archive", true},
+		{"ord*.created=archive", "orders.created", "", false},
+
+		// captures can be embedded in tokens
+		{"orders.*=archive.${1}x", "orders.eu", "archive.eux", true},
+		{"orders.*=archive.$1-v2", "orders.eu", "archive.eu-v2", true},
+		{"orders.*=archive.$1$1", "orders.eu", "archive.eueu", true},
+
+		// malformed subjects never match
+		{"orders.*=archive.$1", "orders.", "", false},
+		{"orders.>=archive.$1", "orders.eu..x", "", false},
+		{"orders.*=archive.$1", "", "", false},
+
+		// regular expressions match the whole subject
+		{`~orders\.(.*)=neworders.$1`, "orders.eu.created", "neworders.eu.created", true},
+		{`~orders\.(.*)=neworders.$1`, "x.orders.eu", "", false},
+		{`~orders\.(eu|us)\.(.+)=regional.$1.$2`, "orders.us.created", "regional.us.created", true},
+		{`~orders\.(eu|us)\.(.+)=regional.$1.$2`, "orders.asia.created", "", false},
+		{`~orders|invoices=all`, "invoices", "all", true},
+		{`~orders|invoices=all`, "orders.x", "", false},
+	} {
+		m, err := Parse(tc.spec)
+		if err != nil {
+			t.Fatalf("parse of %q failed: %v", tc.spec, err)
+		}
+
+		mapped, ok := m.Apply(tc.subject)
+		if ok != tc.match || mapped != tc.expected {
+			t.Fatalf("expected %q using %q to map to %q (%v) got %q (%v)", tc.subject, tc.spec, tc.expected, tc.match, mapped, ok)
+		}
+	}
+}
+
+func TestMapper(t *testing.T) {
+	m, err := New([]string{"orders.*.created=archive.$1", "orders.>=all.$1", `~.*=other`})
+	if err != nil {
+		t.Fatalf("new failed: %v", err)
+	}
+
+	if m.Len() != 3 {
+		t.Fatalf("expected 3 maps got %d", m.Len())
+	}
+
+	for subject, expected := range map[string][2]string{
+		"orders.eu.created": {"archive.eu", "orders.*.created=archive.$1"},
+		"orders.eu.deleted": {"all.eu.deleted", "orders.>=all.$1"},
+		"invoices":          {"other", `~.*=other`},
+	} {
+		mapped, used := m.Map(subject)
+		if mapped != expected[0] || used == nil || used.String() != expected[1] {
+			t.Fatalf("expected %q to map to %q using %q got %q using %v", subject, expected[0], expected[1], mapped, used)
+		}
+	}
+
+	m, err = New([]string{"orders.*=archive.$1"})
+	if err != nil {
+		t.Fatalf("new failed: %v", err)
+	}
+	if mapped, used := m.Map("invoices.new"); mapped != "invoices.new" || used != nil {
+		t.Fatalf("expected subjects without a matching map to be unchanged got %q %v", mapped, used)
+	}
+
+	var empty *Mapper
+	if mapped, used := empty.Map("orders"); mapped != "orders" || used != nil || empty.Len() != 0 {
+		t.Fatalf("expected a nil mapper to keep subjects")
+	}
+
+	_, err = New([]string{"orders.*=archive.$1", "orders.>.x=y"})
+	if err == nil || !strings.Contains(err.Error(), `"orders.>.x=y"`) {
+		t.Fatalf("expected the invalid map to be named: %v", err)
+	}
+}
@@ -15,8 +15,11 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"github.com/nats-io/natscli/cli"
+	"os/exec"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -70,6 +73,106 @@ func TestCLIKVGet(t *testing.T) {
 	if strings.TrimSpace(string(out)) != "Y" {
 		t.Fatalf("get failed: %s != Y", string(out))
 	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv get T X.Y --quiet", srv.ClientURL()))
+	if string(out) != "Y\n" {
+		t.Fatalf("expected only the value when quiet got %q", out)
+	}
+}
+
+func TestCLIKVCopy(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	source := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "SRC"})
+	dest := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "DST"})
+	mustPut(t, source, "app.db", "postgres")
+	mustPut(t, source, "users.a", "A")
+	mustPut(t, source, "users.b.c", "BC")
+	mustPut(t, source, "users.d", "D")
+	checkErr(t, source.Delete("users.d"), "delete failed")
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' kv copy SRC app.db DST config.db", srv.ClientURL()))
+	if !strings.Contains(string(out), "Copied SRC > app.db to DST > config.db") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	entry, err := dest.Get("config.db")
+	checkErr(t, err, "get failed: %v", err)
+	if string(entry.Value()) != "postgres" {
+		t.Fatalf("expected the value to be copied got %q", entry.Value())
+	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv copy SRC 'users.>' DST archive --recursive --move", srv.ClientURL()))
+	if !strings.Contains(string(out), "Moved 2 keys from SRC > users.> to DST > archive") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	for key, expected := range map[string]string{"archive.a": "A", "archive.b.c": "BC"} {
+		entry, err = dest.Get(key)
+		checkErr(t, err, "get of %s failed: %v", key, err)
+		if string(entry.Value()) != expected {
+			t.Fatalf("expected %q for %s got %q", expected, key, entry.Value())
+		}
+	}
+	_, err = dest.Get("archive.d")
+	if err != nats.ErrKeyNotFound {
+		t.Fatalf("expected deleted keys to not be copied: %v", err)
+	}
+	_, err = source.Get("users.a")
+	if err != nats.ErrKeyNotFound {
+		t.Fatalf("expected moved keys to be deleted: %v", err)
+	}
+
+	out = runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv copy SRC 'users.>' DST archive", srv.ClientURL()))
+	if !strings.Contains(string(out), "use --recursive to copy many keys") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	for _, target := range []string{"archive", "'archive.>'"} {
+		out = runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv copy DST 'archive.>' DST %s --recursive --move", srv.ClientURL(), target))
+		if !strings.Contains(string(out), "can not copy DST > archive.> onto itself") {
+			t.Fatalf("unexpected output: %s", out)
+		}
+	}
+	_, err = dest.Get("archive.a")
+	checkErr(t, err, "expected keys moved onto themselves to be kept: %v", err)
+}
+
+func TestCLIKVGetRevision(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	store := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "T", History: 5})
+	first := mustPut(t, store, "X", "first")
+	time.Sleep(10 * time.Millisecond)
+	before := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	second := mustPut(t, store, "X", "second")
+	err := store.Delete("X")
+	if err != nil {
+		t.Fatalf("delete failed: %s", err)
+	}
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' kv get-revision T X %d --raw", srv.ClientURL(), first))
+	if string(out) != "first" {
+		t.Fatalf("get revision failed: %s", out)
+	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv get-revision T X --before %s --raw", srv.ClientURL(), before.Format(time.RFC3339Nano)))
+	if string(out) != "first" {
+		t.Fatalf("get revision before failed: %s", out)
+	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv get-revision T X %d", srv.ClientURL(), second))
+	if !regexp.MustCompile(`Operation: +PUT`).Match(out) || !strings.Contains(string(out), "second") {
+		t.Fatalf("expected the put operation and value: %s", out)
+	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv get-revision T X %d", srv.ClientURL(), second+1))
+	if !regexp.MustCompile(`Operation: +DELETE`).Match(out) {
+		t.Fatalf("expected the delete operation: %s", out)
+	}
+
+	runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv get-revision T X --before 1h", srv.ClientURL()))
 }
 
 func TestCLIKVCreate(t *testing.T) {
@@ -289,3 +392,214 @@ func TestCLIRM(t *testing.T) {
 		t.Fatalf("stream was not deleted")
 	}
 }
+
+func TestCLIKVDiff(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	a := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "A"})
+	b := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "B"})
+
+	mustPut(t, a, "cfg", `{"timeout":10,"name":"x"}`)
+	mustPut(t, a, "cfg2", `{"timeout":20,"name":"x"}`)
+	mustPut(t, a, "only.a", "1")
+	mustPut(t, b, "cfg", `{ "name": "x", "timeout": 10 }`)
+	mustPut(t, b, "cfg2", `{"timeout":30,"name":"x"}`)
+	mustPut(t, b, "only.b", "1")
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' kv diff A cfg cfg2 --json", srv.ClientURL()))
+	if !strings.Contains(string(out), `"path": ".timeout"`) || strings.Contains(string(out), ".name") {
+		t.Fatalf("expected only timeout to differ: %s", out)
+	}
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv diff-buckets A B", srv.ClientURL()))
+	for _, expect := range []string{"only in A", "only in B", "cfg2", "3 keys differ, 1 keys are identical"} {
+		if !strings.Contains(string(out), expect) {
+			t.Fatalf("expected %q in output: %s", expect, out)
+		}
+	}
+}
+
+func TestCLIKVLock(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	store := createTestBucket(t, nc, nil)
+	lockCmd := fmt.Sprintf("--server='%s' kv lock T job", srv.ClientURL())
+
+	t.Run("failing command", func(t *testing.T) {
+		out := runNatsCliFailing(t, fmt.Sprintf("%s -- sh -c 'echo locked; exit 3'", lockCmd))
+		if !strings.Contains(string(out), "locked") {
+			t.Fatalf("expected the command to run: %s", out)
+		}
+	})
+
+	_, err := store.Get("job")
+	if err != nats.ErrKeyNotFound {
+		t.Fatalf("expected the lock to be released: %v", err)
+	}
+
+	t.Run("held", func(t *testing.T) {
+		held := exec.Command("bash", "-c", natsCliCommand(lockCmd, "-- sleep 3"))
+		err := held.Start()
+		checkErr(t, err, "could not start lock: %v", err)
+		defer held.Wait()
+
+		var entry nats.KeyValueEntry
+		for i := 0; i < 200 && entry == nil; i++ {
+			entry, _ = store.Get("job")
+			time.Sleep(50 * time.Millisecond)
+		}
+		if entry == nil {
+			t.Fatalf("lock was not acquired")
+		}
+
+		out := runNatsCli(t, fmt.Sprintf("--server='%s' kv lock-status T job --json", srv.ClientURL()))
+		var status struct {
+			Held   bool `json:"held"`
+			Stale  bool `json:"stale"`
+			Holder struct {
+				Command string `json:"command"`
+				PID     int    `json:"pid"`
+			} `json:"holder"`
+		}
+		err = json.Unmarshal(out, &status)
+		checkErr(t, err, "invalid json: %v: %s", err, out)
+		if !status.Held || status.Stale || status.Holder.Command != "sleep 3" || status.Holder.PID == 0 {
+			t.Fatalf("unexpected status: %s", out)
+		}
+
+		out = runNatsCliFailing(t, fmt.Sprintf("%s --no-wait -- true", lockCmd))
+		if !strings.Contains(string(out), "lock T > job is held by pid") {
+			t.Fatalf("expected the lock to be held: %s", out)
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		// a holder that crashed and stopped refreshing the lock
+		mustPut(t, store, "job", `{"id":"crashed","host":"gone","pid":1,"acquired":"2024-01-01T00:00:00Z","ttl":1000000000}`)
+
+		out := runNatsCliFailing(t, fmt.Sprintf("%s --wait 100ms -- true", lockCmd))
+		if !strings.Contains(string(out), "timeout waiting for lock T > job held by pid 1 on gone") {
+			t.Fatalf("expected the lock to be held: %s", out)
+		}
+
+		time.Sleep(1100 * time.Millisecond)
+
+		out = runNatsCli(t, fmt.Sprintf("--server='%s' kv lock-status T job --json", srv.ClientURL()))
+		if !strings.Contains(string(out), `"stale": true`) {
+			t.Fatalf("expected the lock to be stale: %s", out)
+		}
+
+		out = runNatsCli(t, fmt.Sprintf("%s --no-wait -- echo running", lockCmd))
+		if !strings.Contains(string(out), "Took over stale lock T > job from pid 1 on gone") || !strings.Contains(string(out), "running") {
+			t.Fatalf("expected the stale lock to be taken over: %s", out)
+		}
+
+		_, err := store.Get("job")
+		if err != nats.ErrKeyNotFound {
+			t.Fatalf("expected the lock to be released: %v", err)
+		}
+	})
+}
+
+func TestCLIKVExpire(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	store := createTestBucket(t, nc, nil)
+	rev := mustPut(t, store, "X", "VAL")
+
+	out := runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv expire T X --ttl 500ms", srv.ClientURL()))
+	if !strings.Contains(string(out), "TTL must be at least 1s") {
+		t.Fatalf("expected a short TTL to be rejected: %s", out)
+	}
+
+	out = runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv expire T X --ttl 1h", srv.ClientURL()))
+	if !strings.Contains(string(out), "bucket T does not support per-key TTLs") {
+		t.Fatalf("expected the bucket to not support per-key TTLs: %s", out)
+	}
+
+	entry, err := store.Get("X")
+	checkErr(t, err, "get failed: %v", err)
+	if entry.Revision() != rev {
+		t.Fatalf("expected the key to be unchanged, revision %d != %d", entry.Revision(), rev)
+	}
+}
+
+func TestCLIKVClone(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	store := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "T", History: 5, Description: "source"})
+	mustPut(t, store, "X", "1")
+	mustPut(t, store, "X", "2")
+	mustPut(t, store, "Y", "Y")
+	mustPut(t, store, "Z", "Z")
+	checkErr(t, store.Delete("Z"), "delete failed")
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' kv clone T C --no-progress", srv.ClientURL()))
+	if !strings.Contains(string(out), "Created bucket C using the configuration of T") || !strings.Contains(string(out), "Copied 2 keys from T to C") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	js, err := nc.JetStream()
+	checkErr(t, err, "js failed: %v", err)
+	clone, err := js.KeyValue("C")
+	checkErr(t, err, "could not load clone: %v", err)
+
+	status, err := clone.Status()
+	checkErr(t, err, "status failed: %v", err)
+	if status.History() != 5 {
+		t.Fatalf("expected history 5 got %d", status.History())
+	}
+
+	entry, err := clone.Get("X")
+	checkErr(t, err, "get failed: %v", err)
+	if string(entry.Value()) != "2" {
+		t.Fatalf("expected the latest value to be copied got %q", entry.Value())
+	}
+	_, err = clone.Get("Z")
+	if err != nats.ErrKeyNotFound {
+		t.Fatalf("expected deleted keys to not be copied: %v", err)
+	}
+
+	mustPut(t, store, "Y", "NEW")
+
+	out = runNatsCli(t, fmt.Sprintf("--server='%s' kv clone T C --no-progress", srv.ClientURL()))
+	if !strings.Contains(string(out), "Copied 0 keys") || !strings.Contains(string(out), "Skipped 2 keys already in C") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+
+	runNatsCli(t, fmt.Sprintf("--server='%s' kv clone T C --overwrite --no-progress", srv.ClientURL()))
+	entry, err = clone.Get("Y")
+	checkErr(t, err, "get failed: %v", err)
+	if string(entry.Value()) != "NEW" {
+		t.Fatalf("expected the key to be overwritten got %q", entry.Value())
+	}
+
+	out = runNatsCliFailing(t, fmt.Sprintf("--server='%s' kv clone T T", srv.ClientURL()))
+	if !strings.Contains(string(out), "can not clone bucket T into itself") {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestCLIKVStatus(t *testing.T) {
+	srv, nc, _ := setupJStreamTest(t)
+	defer srv.Shutdown()
+
+	store := createTestBucket(t, nc, &nats.KeyValueConfig{Bucket: "T", History: 5})
+	mustPut(t, store, "X", "1")
+	mustPut(t, store, "X", "2")
+	mustPut(t, store, "Y", "1")
+	mustPut(t, store, "Z", "1")
+	err := store.Delete("Z")
+	checkErr(t, err, "delete failed: %v", err)
+
+	out := runNatsCli(t, fmt.Sprintf("--server='%s' kv status T", srv.ClientURL()))
+	for _, expected := range []string{"Subjects incl. deleted: 3", "Values Stored: 5", "History Kept: 5", "Health: healthy"} {
+		if !strings.Contains(string(out), expected) {
+			t.Fatalf("expected %q in output: %s", expected, out)
+		}
+	}
+}
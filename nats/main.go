@@ -23,6 +23,7 @@ import (
 	"github.com/nats-io/natscli/plugins"
 
 	"github.com/nats-io/natscli/cli"
+	"github.com/nats-io/natscli/options"
 )
 
 var version = "development"
@@ -32,7 +33,13 @@ func main() {
 
 NATS Server and JetStream administration.
 
-See 'nats cheat' for a quick cheatsheet of commands`
+See 'nats cheat' for a quick cheatsheet of commands
+
+Failures exit with status 2 when a resource was not found, 3 on
+permission errors, 4 on timeouts and 1 otherwise
+
+Long reports are shown using the pager set in NATS_PAGER or PAGER,
+defaulting to 'less -FRX', pass --no-pager to disable`
 
 	ncli := fisk.New("nats", help)
 	ncli.Author("NATS Authors <info@nats.io>")
@@ -40,6 +47,7 @@ See 'nats cheat' for a quick cheatsheet of commands`
 	ncli.Version(getVersion())
 	ncli.HelpFlag.Short('h')
 	ncli.WithCheats().CheatCommand.Hidden()
+	cli.ConfigureErrorRendering(ncli)
 
 	opts, err := cli.ConfigureInApp(ncli, nil, true)
 	if err != nil {
@@ -47,7 +55,7 @@ See 'nats cheat' for a quick cheatsheet of commands`
 	}
 	cli.SetVersion(version)
 
-	ncli.Flag("server", "NATS server urls").Short('s').Envar("NATS_URL").PlaceHolder("URL").StringVar(&opts.Servers)
+	ncli.Flag("server", "NATS server urls").Short('s').Envar("NATS_URL").PlaceHolder("URL").SetValue(&options.ServersFlag{Options: opts})
 	ncli.Flag("user", "Username or Token").Envar("NATS_USER").PlaceHolder("USER").StringVar(&opts.Username)
 	ncli.Flag("password", "Password").Envar("NATS_PASSWORD").PlaceHolder("PASSWORD").StringVar(&opts.Password)
 	ncli.Flag("connection-name", "Nickname to use for the underlying NATS Connection").Default("NATS CLI Version " + version).PlaceHolder("NAME").StringVar(&opts.ConnectionName)
@@ -65,15 +73,22 @@ See 'nats cheat' for a quick cheatsheet of commands`
 	}
 	ncli.Flag("timeout", "Time to wait on responses from NATS").Default("5s").Envar("NATS_TIMEOUT").PlaceHolder("DURATION").DurationVar(&opts.Timeout)
 	ncli.Flag("socks-proxy", "SOCKS5 proxy for connecting to NATS server").Envar("NATS_SOCKS_PROXY").PlaceHolder("PROXY").StringVar(&opts.SocksProxy)
-	ncli.Flag("js-api-prefix", "Subject prefix for access to JetStream API").PlaceHolder("PREFIX").StringVar(&opts.JsApiPrefix)
+	ncli.Flag("js-api-prefix", "Subject prefix for access to JetStream API imported from another account, cannot be used with --js-domain").PlaceHolder("PREFIX").StringVar(&opts.JsApiPrefix)
 	ncli.Flag("js-event-prefix", "Subject prefix for access to JetStream Advisories").PlaceHolder("PREFIX").StringVar(&opts.JsEventPrefix)
-	ncli.Flag("js-domain", "JetStream domain to access").PlaceHolder("DOMAIN").StringVar(&opts.JsDomain)
+	ncli.Flag("js-domain", "JetStream domain to access, cannot be used with --js-api-prefix").PlaceHolder("DOMAIN").StringVar(&opts.JsDomain)
 	ncli.Flag("inbox-prefix", "Custom inbox prefix to use for inboxes").PlaceHolder("PREFIX").StringVar(&opts.InboxPrefix)
 	ncli.Flag("domain", "JetStream domain to access").PlaceHolder("DOMAIN").Hidden().StringVar(&opts.JsDomain)
 	ncli.Flag("colors", "Sets a color scheme to use").PlaceHolder("SCHEME").Envar("NATS_COLOR").EnumVar(&opts.ColorScheme, cli.ValidStyles()...)
 	ncli.Flag("context", "Configuration context").Envar("NATS_CONTEXT").PlaceHolder("NAME").StringVar(&opts.CfgCtx)
 	ncli.Flag("trace", "Trace API interactions").UnNegatableBoolVar(&opts.Trace)
+	ncli.Flag("connect-debug", "Log every step taken while connecting to NATS").UnNegatableBoolVar(&opts.ConnectDebug)
 	ncli.Flag("no-context", "Disable the selected context").UnNegatableBoolVar(&cli.SkipContexts)
+	ncli.Flag("quiet", "Suppress informational output, showing only data and errors").UnNegatableBoolVar(&opts.Quiet)
+	ncli.Flag("log-level", "Level of diagnostics to log (debug, info, warn, error)").Default("info").EnumVar(&opts.LogLevel, "debug", "info", "warn", "error")
+	ncli.Flag("log-json", "Log diagnostics to stderr as JSON").UnNegatableBoolVar(&opts.LogJSON)
+	ncli.Flag("no-pager", "Do not send long report output through a pager").UnNegatableBoolVar(&opts.NoPager)
+	ncli.Flag("raw-numbers", "Show exact values rather than humanized sizes, counts, durations and times").UnNegatableBoolVar(&opts.RawNumbers)
+	ncli.Flag("no-humanize", "Show exact values rather than humanized sizes, counts, durations and times").Hidden().UnNegatableBoolVar(&opts.RawNumbers)
 
 	log.SetFlags(log.Ltime)
 
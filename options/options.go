@@ -14,10 +14,12 @@
 package options
 
 import (
+	"strings"
+	"time"
+
 	"github.com/nats-io/jsm.go"
 	"github.com/nats-io/jsm.go/natscontext"
 	"github.com/nats-io/nats.go"
-	"time"
 )
 
 var DefaultOptions *Options
@@ -28,6 +30,8 @@ type Options struct {
 	Config *natscontext.Context
 	// Servers is the list of servers to connect to
 	Servers string
+	// ServerURLs holds the servers when the server flag was given multiple times
+	ServerURLs []string
 	// Creds is nats credentials to authenticate with
 	Creds string
 	// TlsCert is the TLS Public Certificate
@@ -82,4 +86,37 @@ type Options struct {
 	WinCertStoreMatch string
 	// WinCertCaStoreMatch is the queries for CAs to use
 	WinCertCaStoreMatch []string
+	// NoPager disables sending long report output through a pager
+	NoPager bool
+	// Quiet suppresses all informational output, leaving only data on stdout
+	Quiet bool
+	// LogLevel is the level of diagnostics to log, one of debug, info, warn or error
+	LogLevel string
+	// LogJSON logs diagnostics as JSON documents
+	LogJSON bool
+	// RawNumbers shows exact values instead of humanized sizes, counts, durations and times
+	RawNumbers bool
+	// ConnectDebug logs every step taken while connecting to NATS
+	ConnectDebug bool
+}
+
+// ServersFlag is a flag value that can be repeated, every server given is kept in ServerURLs while Servers holds all
+// of them as a comma separated list
+type ServersFlag struct {
+	Options *Options
+}
+
+func (f *ServersFlag) Set(v string) error {
+	f.Options.ServerURLs = append(f.Options.ServerURLs, v)
+	f.Options.Servers = strings.Join(f.Options.ServerURLs, ",")
+
+	return nil
+}
+
+func (f *ServersFlag) String() string {
+	return f.Options.Servers
+}
+
+func (f *ServersFlag) IsCumulative() bool {
+	return true
 }